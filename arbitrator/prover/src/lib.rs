@@ -245,6 +245,12 @@ pub unsafe extern "C" fn arbitrator_get_num_steps(mach: *const Machine) -> u64 {
     (*mach).get_steps()
 }
 
+/// Returns the bytes of wasm memory held by the machine's modules
+#[no_mangle]
+pub unsafe extern "C" fn arbitrator_memory_size(mach: *const Machine) -> u64 {
+    (*mach).memory_size()
+}
+
 pub const ARBITRATOR_MACHINE_STATUS_RUNNING: u8 = 0;
 pub const ARBITRATOR_MACHINE_STATUS_FINISHED: u8 = 1;
 pub const ARBITRATOR_MACHINE_STATUS_ERRORED: u8 = 2;
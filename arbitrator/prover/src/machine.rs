@@ -1369,6 +1369,11 @@ impl Machine {
         self.steps
     }
 
+    /// The bytes of wasm memory held by the machine's modules
+    pub fn memory_size(&self) -> u64 {
+        self.modules.iter().map(|m| m.memory.size()).sum()
+    }
+
     pub fn step_n(&mut self, n: u64) -> Result<()> {
         if self.is_halted() {
             return Ok(());
@@ -1,28 +1,131 @@
 package arbnode
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"sort"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution/gethexec"
 	"github.com/offchainlabs/nitro/staker"
 	"github.com/offchainlabs/nitro/validator"
 )
 
 type BlockValidatorAPI struct {
-	val *staker.BlockValidator
+	val             *staker.BlockValidator
+	inboxTracker    *InboxTracker
+	txStreamer      *TransactionStreamer
+	genesisBlockNum uint64
 }
 
-func (a *BlockValidatorAPI) LatestValidated(ctx context.Context) (*staker.GlobalStateValidatedInfo, error) {
-	return a.val.ReadLastValidatedInfo()
+// LatestValidatedInfo is the last validated global state, along with the block it's at
+type LatestValidatedInfo struct {
+	staker.GlobalStateValidatedInfo
+	// BlockNumber is nil if this node doesn't have the validated block, which may happen just after a reorg
+	BlockNumber *hexutil.Uint64 `json:"blockNumber,omitempty"`
+	BlockHash   common.Hash     `json:"blockHash"`
+}
+
+func (a *BlockValidatorAPI) LatestValidated(ctx context.Context) (*LatestValidatedInfo, error) {
+	validated, err := a.val.ReadLastValidatedInfo()
+	if err != nil || validated == nil {
+		return nil, err
+	}
+	info := &LatestValidatedInfo{
+		GlobalStateValidatedInfo: *validated,
+		BlockHash:                validated.GlobalState.BlockHash,
+	}
+	caughtUp, count, err := staker.GlobalStateToMsgCount(a.inboxTracker, a.txStreamer, validated.GlobalState)
+	if err == nil && caughtUp && count > 0 {
+		blockNumber := hexutil.Uint64(arbutil.MessageCountToBlockNumber(count, a.genesisBlockNum))
+		info.BlockNumber = &blockNumber
+	}
+	return info, nil
+}
+
+type WatchtowerAPI struct {
+	staker          *staker.Staker
+	genesisBlockNum uint64
+}
+
+// AssertionStatus is an assertion on the parent chain, and the block it asserts
+type AssertionStatus struct {
+	Node                     hexutil.Uint64          `json:"node"`
+	NodeHash                 common.Hash             `json:"nodeHash"`
+	ParentChainBlockProposed hexutil.Uint64          `json:"parentChainBlockProposed"`
+	WasmModuleRoot           common.Hash             `json:"wasmModuleRoot"`
+	GlobalState              validator.GoGlobalState `json:"globalState"`
+	// BlockNumber is nil if this node hasn't processed the asserted block yet
+	BlockNumber *hexutil.Uint64 `json:"blockNumber,omitempty"`
+	BlockHash   common.Hash     `json:"blockHash"`
+}
+
+func (a *WatchtowerAPI) assertionStatus(progress *staker.AssertionProgress) *AssertionStatus {
+	if progress == nil || progress.Node == nil {
+		return nil
+	}
+	node := progress.Node
+	status := &AssertionStatus{
+		Node:                     hexutil.Uint64(node.NodeNum),
+		NodeHash:                 node.NodeHash,
+		ParentChainBlockProposed: hexutil.Uint64(node.ParentChainBlockProposed),
+		WasmModuleRoot:           node.WasmModuleRoot,
+		GlobalState:              node.AfterState().GlobalState,
+		BlockHash:                node.AfterState().GlobalState.BlockHash,
+	}
+	if progress.MessageCount > 0 {
+		blockNumber := hexutil.Uint64(arbutil.MessageCountToBlockNumber(progress.MessageCount, a.genesisBlockNum))
+		status.BlockNumber = &blockNumber
+	}
+	return status
+}
+
+// LatestStaked returns the latest assertion the staker is staked on, or the latest confirmed one if it has no stake.
+// Returns null until the staker first checks.
+func (a *WatchtowerAPI) LatestStaked(ctx context.Context) *AssertionStatus {
+	return a.assertionStatus(a.staker.LatestStakedAssertion())
+}
+
+// LatestConfirmed returns the latest confirmed assertion, or null until the staker first checks
+func (a *WatchtowerAPI) LatestConfirmed(ctx context.Context) *AssertionStatus {
+	return a.assertionStatus(a.staker.LatestConfirmedAssertion())
+}
+
+// AssertionDivergences returns the assertions on the parent chain found to disagree with this node
+func (a *WatchtowerAPI) AssertionDivergences(ctx context.Context) []*staker.AssertionDivergence {
+	return a.staker.AssertionDivergences()
+}
+
+type StakerAPI struct {
+	staker *staker.Staker
+}
+
+// WithdrawStake returns and withdraws the staker's deposit once the node it's staked on is confirmed.
+// Returns the hashes of the transactions sent.
+func (a *StakerAPI) WithdrawStake(ctx context.Context) ([]common.Hash, error) {
+	return a.staker.WithdrawStake(ctx)
+}
+
+// RefundOldStakers returns the deposits of stakers staked on confirmed nodes, or of all such stakers if none are given
+func (a *StakerAPI) RefundOldStakers(ctx context.Context, stakers []common.Address) ([]common.Hash, error) {
+	return a.staker.RefundOldStakers(ctx, stakers)
+}
+
+// AddToStake tops up the staker's deposit to the current required stake
+func (a *StakerAPI) AddToStake(ctx context.Context) ([]common.Hash, error) {
+	return a.staker.AddToStake(ctx)
 }
 
 type BlockValidatorDebugAPI struct {
-	val *staker.StatelessBlockValidator
+	val             *staker.StatelessBlockValidator
+	genesisBlockNum uint64
 }
 
 type ValidateBlockResult struct {
@@ -31,20 +134,25 @@ type ValidateBlockResult struct {
 	GlobalState validator.GoGlobalState `json:"globalstate"`
 }
 
+func (a *BlockValidatorDebugAPI) moduleRoot(moduleRootOptional *common.Hash) (common.Hash, error) {
+	if moduleRootOptional != nil {
+		return *moduleRootOptional, nil
+	}
+	moduleRoots := a.val.GetModuleRootsToValidate()
+	if len(moduleRoots) == 0 {
+		return common.Hash{}, errors.New("no current WasmModuleRoot configured, must provide parameter")
+	}
+	return moduleRoots[0], nil
+}
+
 func (a *BlockValidatorDebugAPI) ValidateMessageNumber(
 	ctx context.Context, msgNum hexutil.Uint64, full bool, moduleRootOptional *common.Hash,
 ) (ValidateBlockResult, error) {
 	result := ValidateBlockResult{}
 
-	var moduleRoot common.Hash
-	if moduleRootOptional != nil {
-		moduleRoot = *moduleRootOptional
-	} else {
-		moduleRoots := a.val.GetModuleRootsToValidate()
-		if len(moduleRoots) == 0 {
-			return result, errors.New("no current WasmModuleRoot configured, must provide parameter")
-		}
-		moduleRoot = moduleRoots[0]
+	moduleRoot, err := a.moduleRoot(moduleRootOptional)
+	if err != nil {
+		return result, err
 	}
 	start_time := time.Now()
 	valid, gs, err := a.val.ValidateResult(ctx, arbutil.MessageIndex(msgNum), full, moduleRoot)
@@ -55,3 +163,361 @@ func (a *BlockValidatorDebugAPI) ValidateMessageNumber(
 	result.Valid = valid
 	return result, err
 }
+
+// maxValidateBlockRange bounds the blocks ValidateBlockRange validates in a call, so a call finishes in reasonable time
+const maxValidateBlockRange = 1024
+
+type BlockRangeValidationResult struct {
+	Block         hexutil.Uint64 `json:"block"`
+	MessageNumber hexutil.Uint64 `json:"messageNumber"`
+	// Valid is false if validation failed or produced a different global state than this node's.
+	// Error is set if the block couldn't be validated at all.
+	Valid       bool                     `json:"valid"`
+	Latency     string                   `json:"latency"`
+	Expected    validator.GoGlobalState  `json:"expected"`
+	GlobalState *validator.GoGlobalState `json:"globalstate,omitempty"`
+	Error       string                   `json:"error,omitempty"`
+}
+
+// ValidateBlockRange re-validates the blocks from fromBlock to toBlock inclusive against the module root, or the
+// current one if none is given, and reports on each block. A block failing doesn't stop the rest being validated.
+func (a *BlockValidatorDebugAPI) ValidateBlockRange(
+	ctx context.Context, fromBlock, toBlock hexutil.Uint64, full bool, moduleRootOptional *common.Hash,
+) ([]BlockRangeValidationResult, error) {
+	if uint64(fromBlock) < a.genesisBlockNum {
+		return nil, fmt.Errorf("block %v is before the genesis block %v", fromBlock, a.genesisBlockNum)
+	}
+	if toBlock < fromBlock {
+		return nil, fmt.Errorf("to block %v is before from block %v", toBlock, fromBlock)
+	}
+	if toBlock-fromBlock >= maxValidateBlockRange {
+		return nil, fmt.Errorf("can validate at most %v blocks at a time", maxValidateBlockRange)
+	}
+	moduleRoot, err := a.moduleRoot(moduleRootOptional)
+	if err != nil {
+		return nil, err
+	}
+	results := []BlockRangeValidationResult{}
+	for block := uint64(fromBlock); block <= uint64(toBlock); block++ {
+		msgNum := arbutil.BlockNumberToMessageCount(block, a.genesisBlockNum) - 1
+		result := BlockRangeValidationResult{
+			Block:         hexutil.Uint64(block),
+			MessageNumber: hexutil.Uint64(msgNum),
+		}
+		startTime := time.Now()
+		valid, expected, gs, err := a.val.ValidateMessage(ctx, msgNum, full, moduleRoot)
+		result.Latency = fmt.Sprintf("%vms", time.Since(startTime).Milliseconds())
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		result.Valid = valid
+		result.GlobalState = gs
+		if expected != nil {
+			result.Expected = *expected
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+type RecordedPreimage struct {
+	Type arbutil.PreimageType `json:"type"`
+	Hash common.Hash          `json:"hash"`
+	Data hexutil.Bytes        `json:"data"`
+}
+
+// Preimage returns the preimage of the hash resolved when recording the message, for external provers and
+// auditing tools. The preimage type is optional.
+func (a *BlockValidatorDebugAPI) Preimage(
+	ctx context.Context, msgNum hexutil.Uint64, hash common.Hash, preimageType *uint8,
+) (*RecordedPreimage, error) {
+	var ty *arbutil.PreimageType
+	if preimageType != nil {
+		typed := arbutil.PreimageType(*preimageType)
+		ty = &typed
+	}
+	resolvedType, data, err := a.val.RecordedPreimage(ctx, arbutil.MessageIndex(msgNum), hash, ty)
+	if err != nil {
+		return nil, err
+	}
+	return &RecordedPreimage{
+		Type: resolvedType,
+		Hash: hash,
+		Data: data,
+	}, nil
+}
+
+// RecordedPreimages returns every preimage resolved when recording the message
+func (a *BlockValidatorDebugAPI) RecordedPreimages(ctx context.Context, msgNum hexutil.Uint64) ([]RecordedPreimage, error) {
+	preimages, err := a.val.RecordedPreimages(ctx, arbutil.MessageIndex(msgNum))
+	if err != nil {
+		return nil, err
+	}
+	result := []RecordedPreimage{}
+	for ty, typePreimages := range preimages {
+		for hash, data := range typePreimages {
+			result = append(result, RecordedPreimage{
+				Type: ty,
+				Hash: hash,
+				Data: data,
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		return bytes.Compare(result[i].Hash[:], result[j].Hash[:]) < 0
+	})
+	return result, nil
+}
+
+type BatchPosterAPI struct {
+	batchPoster *BatchPoster
+}
+
+// Drain stops posting new batches, so that the batch poster's key can be rotated.
+// It doesn't survive a restart of the node.
+func (a *BatchPosterAPI) Drain(ctx context.Context) error {
+	a.batchPoster.Drain()
+	return nil
+}
+
+func (a *BatchPosterAPI) Resume(ctx context.Context) error {
+	a.batchPoster.Resume()
+	return nil
+}
+
+func (a *BatchPosterAPI) DrainStatus(ctx context.Context) (*BatchPosterDrainStatus, error) {
+	return a.batchPoster.DrainStatus(ctx)
+}
+
+// ChainFreezeAPI reports on a chain frozen with the sequencer's freeze-at-block for a migration,
+// and exports its final state
+type ChainFreezeAPI struct {
+	node            *Node
+	genesisBlockNum uint64
+}
+
+type ChainFreezeStatus struct {
+	Block        hexutil.Uint64 `json:"block"`
+	MessageCount hexutil.Uint64 `json:"messageCount"`
+	// Reached is whether the chain has reached the block, and Frozen whether it hasn't gone past it
+	Reached   bool        `json:"reached"`
+	Frozen    bool        `json:"frozen"`
+	BlockHash common.Hash `json:"blockHash"`
+	SendRoot  common.Hash `json:"sendRoot"`
+	// Posted is whether all messages up to the block have been posted to the parent chain,
+	// and the batch and delayed message counts and accumulators are those of the batch posting the last one
+	Posted              bool           `json:"posted"`
+	BatchCount          hexutil.Uint64 `json:"batchCount"`
+	InboxAccumulator    common.Hash    `json:"inboxAccumulator"`
+	DelayedMessageCount hexutil.Uint64 `json:"delayedMessageCount"`
+	DelayedAccumulator  common.Hash    `json:"delayedAccumulator"`
+	// Validated is whether this node's block validator has validated all messages up to the block
+	Validated bool `json:"validated"`
+	// Final is whether the chain is frozen at the block and everything up to it is posted and validated
+	Final bool `json:"final"`
+}
+
+func (a *ChainFreezeAPI) Status(ctx context.Context, block hexutil.Uint64) (*ChainFreezeStatus, error) {
+	if uint64(block) < a.genesisBlockNum {
+		return nil, fmt.Errorf("block %v is before the genesis block %v", block, a.genesisBlockNum)
+	}
+	msgCount := arbutil.BlockNumberToMessageCount(uint64(block), a.genesisBlockNum)
+	status := &ChainFreezeStatus{
+		Block:        block,
+		MessageCount: hexutil.Uint64(msgCount),
+	}
+	headCount, err := a.node.TxStreamer.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	if headCount < msgCount {
+		return status, nil
+	}
+	status.Reached = true
+	status.Frozen = headCount == msgCount
+	result, err := a.node.Execution.ResultAtPos(msgCount - 1)
+	if err != nil {
+		return nil, err
+	}
+	status.BlockHash = result.BlockHash
+	status.SendRoot = result.SendRoot
+
+	tracker := a.node.InboxTracker
+	batchCount, err := tracker.GetBatchCount()
+	if err != nil {
+		return nil, err
+	}
+	if batchCount > 0 {
+		postedCount, err := tracker.GetBatchMessageCount(batchCount - 1)
+		if err != nil {
+			return nil, err
+		}
+		status.Posted = postedCount >= msgCount
+	}
+	if status.Posted {
+		batch, err := staker.FindBatchContainingMessageIndex(tracker, msgCount-1, batchCount-1)
+		if err != nil {
+			return nil, err
+		}
+		metadata, err := tracker.GetBatchMetadata(batch)
+		if err != nil {
+			return nil, err
+		}
+		status.BatchCount = hexutil.Uint64(batch + 1)
+		status.InboxAccumulator = metadata.Accumulator
+		status.DelayedMessageCount = hexutil.Uint64(metadata.DelayedMessageCount)
+		if metadata.DelayedMessageCount > 0 {
+			status.DelayedAccumulator, err = tracker.GetDelayedAcc(metadata.DelayedMessageCount - 1)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if a.node.BlockValidator != nil {
+		status.Validated = a.node.BlockValidator.GetValidated() >= msgCount
+	}
+	status.Final = status.Frozen && status.Posted && status.Validated
+	return status, nil
+}
+
+type ChainFreezeExport struct {
+	File      string      `json:"file"`
+	BlockHash common.Hash `json:"blockHash"`
+	StateRoot common.Hash `json:"stateRoot"`
+}
+
+// ExportState writes the state at the block to a new file in the temporary directory, and returns its name
+func (a *ChainFreezeAPI) ExportState(ctx context.Context, block hexutil.Uint64) (*ChainFreezeExport, error) {
+	execNode, ok := a.node.Execution.(*gethexec.ExecutionNode)
+	if !ok {
+		return nil, errors.New("exporting state requires a local execution node")
+	}
+	file, err := os.CreateTemp("", fmt.Sprintf("state-export-%d-*.jsonl", block))
+	if err != nil {
+		return nil, err
+	}
+	header, err := execNode.ExportState(uint64(block), file)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return nil, err
+	}
+	return &ChainFreezeExport{
+		File:      file.Name(),
+		BlockHash: header.Hash(),
+		StateRoot: header.Root,
+	}, nil
+}
+
+// AttestationAPI reports how a block is anchored to the parent chain, for operators to attest to
+type AttestationAPI struct {
+	node            *Node
+	genesisBlockNum uint64
+}
+
+type BlockAnchor struct {
+	Block     hexutil.Uint64 `json:"block"`
+	BlockHash common.Hash    `json:"blockHash"`
+	SendRoot  common.Hash    `json:"sendRoot"`
+	// Posted is whether the block's last message has been posted to the parent chain, in the batch given
+	Posted                bool           `json:"posted"`
+	Batch                 hexutil.Uint64 `json:"batch"`
+	BatchAccumulator      common.Hash    `json:"batchAccumulator"`
+	BatchParentChainBlock hexutil.Uint64 `json:"batchParentChainBlock"`
+	// Confirmed is whether the latest confirmed assertion includes the block. Its block hash is that of its
+	// last block, which commits to this block through the chain of block hashes.
+	Confirmed                 bool           `json:"confirmed"`
+	AssertionNode             hexutil.Uint64 `json:"assertionNode,omitempty"`
+	AssertionNodeHash         common.Hash    `json:"assertionNodeHash,omitempty"`
+	AssertionBlockHash        common.Hash    `json:"assertionBlockHash,omitempty"`
+	AssertionParentChainBlock hexutil.Uint64 `json:"assertionParentChainBlock,omitempty"`
+}
+
+func (a *AttestationAPI) Anchor(ctx context.Context, block hexutil.Uint64) (*BlockAnchor, error) {
+	if uint64(block) < a.genesisBlockNum {
+		return nil, fmt.Errorf("block %v is before the genesis block %v", block, a.genesisBlockNum)
+	}
+	msgCount := arbutil.BlockNumberToMessageCount(uint64(block), a.genesisBlockNum)
+	headCount, err := a.node.TxStreamer.GetProcessedMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	if headCount < msgCount {
+		return nil, fmt.Errorf("block %v hasn't been processed yet", block)
+	}
+	result, err := a.node.Execution.ResultAtPos(msgCount - 1)
+	if err != nil {
+		return nil, err
+	}
+	anchor := &BlockAnchor{
+		Block:     block,
+		BlockHash: result.BlockHash,
+		SendRoot:  result.SendRoot,
+	}
+
+	tracker := a.node.InboxTracker
+	batchCount, err := tracker.GetBatchCount()
+	if err != nil {
+		return nil, err
+	}
+	if batchCount == 0 {
+		return anchor, nil
+	}
+	postedCount, err := tracker.GetBatchMessageCount(batchCount - 1)
+	if err != nil {
+		return nil, err
+	}
+	if postedCount < msgCount {
+		return anchor, nil
+	}
+	batch, err := staker.FindBatchContainingMessageIndex(tracker, msgCount-1, batchCount-1)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := tracker.GetBatchMetadata(batch)
+	if err != nil {
+		return nil, err
+	}
+	anchor.Posted = true
+	anchor.Batch = hexutil.Uint64(batch)
+	anchor.BatchAccumulator = metadata.Accumulator
+	anchor.BatchParentChainBlock = hexutil.Uint64(metadata.ParentChainBlock)
+
+	if a.node.L1Reader == nil || a.node.DeployInfo == nil {
+		return anchor, nil
+	}
+	rollup, err := staker.NewRollupWatcher(a.node.DeployInfo.Rollup, a.node.L1Reader.Client(), bind.CallOpts{})
+	if err != nil {
+		return nil, err
+	}
+	latestConfirmed, err := rollup.LatestConfirmed(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("error getting latest confirmed assertion: %w", err)
+	}
+	assertion, err := rollup.LookupNode(ctx, latestConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up assertion %v: %w", latestConfirmed, err)
+	}
+	afterState := assertion.AfterState().GlobalState
+	found, confirmedCount, err := staker.GlobalStateToMsgCount(tracker, a.node.TxStreamer, afterState)
+	if err != nil {
+		return nil, err
+	}
+	if found && confirmedCount >= msgCount {
+		anchor.Confirmed = true
+		anchor.AssertionNode = hexutil.Uint64(assertion.NodeNum)
+		anchor.AssertionNodeHash = assertion.NodeHash
+		anchor.AssertionBlockHash = afterState.BlockHash
+		anchor.AssertionParentChainBlock = hexutil.Uint64(assertion.ParentChainBlockProposed)
+	}
+	return anchor, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+var archiveRequestsCounter = metrics.NewRegisteredCounter("arb/parentchain/archive/requests", nil)
+
+// archiveClient follows the head of the parent chain with one endpoint, and sends log queries and
+// contract calls for blocks more than recentBlocks behind the head to a separate archive endpoint,
+// so an expensive archive provider is only used while backfilling history, such as during initial sync.
+type archiveClient struct {
+	arbutil.L1Interface
+	archive      arbutil.L1Interface
+	recentBlocks func() uint64
+	// head is the latest parent chain block number seen, only refreshed when a request might be recent
+	head atomic.Uint64
+}
+
+func NewArchiveClient(client arbutil.L1Interface, archive arbutil.L1Interface, recentBlocks func() uint64) arbutil.L1Interface {
+	return &archiveClient{
+		L1Interface:  client,
+		archive:      archive,
+		recentBlocks: recentBlocks,
+	}
+}
+
+// isHistorical returns whether the block is more than recentBlocks behind the head
+func (c *archiveClient) isHistorical(ctx context.Context, block *big.Int) bool {
+	if block == nil || block.Sign() < 0 || !block.IsUint64() {
+		// latest, pending, safe, and finalized are all recent
+		return false
+	}
+	recentBlocks := c.recentBlocks()
+	if block.Uint64()+recentBlocks < c.head.Load() {
+		return true
+	}
+	head, err := c.L1Interface.BlockNumber(ctx)
+	if err != nil {
+		// the request itself will most likely fail and be retried
+		return false
+	}
+	for {
+		current := c.head.Load()
+		if head <= current || c.head.CompareAndSwap(current, head) {
+			break
+		}
+	}
+	return block.Uint64()+recentBlocks < c.head.Load()
+}
+
+func (c *archiveClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	if query.BlockHash == nil && query.FromBlock != nil && c.isHistorical(ctx, query.FromBlock) {
+		archiveRequestsCounter.Inc(1)
+		return c.archive.FilterLogs(ctx, query)
+	}
+	return c.L1Interface.FilterLogs(ctx, query)
+}
+
+func (c *archiveClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if c.isHistorical(ctx, blockNumber) {
+		archiveRequestsCounter.Inc(1)
+		return c.archive.CallContract(ctx, msg, blockNumber)
+	}
+	return c.L1Interface.CallContract(ctx, msg, blockNumber)
+}
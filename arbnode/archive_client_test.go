@@ -0,0 +1,58 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+type fakeHeadClient struct {
+	fakeLogsClient
+	head uint64
+}
+
+func (c *fakeHeadClient) BlockNumber(ctx context.Context) (uint64, error) {
+	return c.head, nil
+}
+
+func TestArchiveClient(t *testing.T) {
+	ctx := context.Background()
+	head := &fakeHeadClient{head: 1000}
+	archive := &fakeLogsClient{}
+	client := NewArchiveClient(head, archive, func() uint64 { return 100 })
+
+	query := func(from, to int64) {
+		t.Helper()
+		_, err := client.FilterLogs(ctx, ethereum.FilterQuery{FromBlock: big.NewInt(from), ToBlock: big.NewInt(to)})
+		Require(t, err)
+	}
+	expectCalls := func(headCalls, archiveCalls int64) {
+		t.Helper()
+		if calls := head.calls.Load(); calls != headCalls {
+			Fail(t, "made", calls, "requests to the head endpoint but expected", headCalls)
+		}
+		if calls := archive.calls.Load(); calls != archiveCalls {
+			Fail(t, "made", calls, "requests to the archive endpoint but expected", archiveCalls)
+		}
+	}
+
+	query(0, 99)
+	expectCalls(0, 1)
+	query(850, 999)
+	expectCalls(1, 1)
+	// A range starting in history goes to the archive even if it reaches the head
+	query(800, 1000)
+	expectCalls(1, 2)
+
+	// Once the head advances, the same blocks are historical
+	head.head = 2000
+	query(1000, 1100)
+	expectCalls(1, 3)
+	query(1950, 2000)
+	expectCalls(2, 3)
+}
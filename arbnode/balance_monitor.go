@@ -0,0 +1,294 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/arbmath"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+const (
+	balanceRoleBatchPoster           = "batchposter"
+	balanceRoleValidator             = "validator"
+	balanceRoleBatchPosterGasRefund  = "batchposter-gasrefunder"
+	balanceRoleValidatorGasRefund    = "validator-gasrefunder"
+	balanceMonitorWebhookPayloadKind = "balance-alert"
+)
+
+type BalanceMonitorConfig struct {
+	Enable               bool          `koanf:"enable"`
+	Interval             time.Duration `koanf:"interval" reload:"hot"`
+	BatchPosterThreshold float64       `koanf:"batch-poster-threshold" reload:"hot"`
+	ValidatorThreshold   float64       `koanf:"validator-threshold" reload:"hot"`
+	GasRefunderThreshold float64       `koanf:"gas-refunder-threshold" reload:"hot"`
+	SpendRateWindow      time.Duration `koanf:"spend-rate-window" reload:"hot"`
+	WebhookURL           string        `koanf:"webhook-url" reload:"hot"`
+	WebhookTimeout       time.Duration `koanf:"webhook-timeout" reload:"hot"`
+	RepeatAlertInterval  time.Duration `koanf:"repeat-alert-interval" reload:"hot"`
+}
+
+type BalanceMonitorConfigFetcher func() *BalanceMonitorConfig
+
+var DefaultBalanceMonitorConfig = BalanceMonitorConfig{
+	Enable:               false,
+	Interval:             time.Minute,
+	BatchPosterThreshold: 0,
+	ValidatorThreshold:   0,
+	GasRefunderThreshold: 0,
+	SpendRateWindow:      time.Hour * 24,
+	WebhookURL:           "",
+	WebhookTimeout:       time.Second * 10,
+	RepeatAlertInterval:  time.Hour,
+}
+
+func BalanceMonitorConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultBalanceMonitorConfig.Enable, "enable monitoring the parent chain balances of the batch poster, validator, and gas refunder accounts")
+	f.Duration(prefix+".interval", DefaultBalanceMonitorConfig.Interval, "how often to check account balances")
+	f.Float64(prefix+".batch-poster-threshold", DefaultBalanceMonitorConfig.BatchPosterThreshold, "alert when the batch poster's balance falls below this many ether (0 to disable)")
+	f.Float64(prefix+".validator-threshold", DefaultBalanceMonitorConfig.ValidatorThreshold, "alert when the validator's balance falls below this many ether (0 to disable)")
+	f.Float64(prefix+".gas-refunder-threshold", DefaultBalanceMonitorConfig.GasRefunderThreshold, "alert when a gas refunder contract's balance falls below this many ether (0 to disable)")
+	f.Duration(prefix+".spend-rate-window", DefaultBalanceMonitorConfig.SpendRateWindow, "how far back to look when estimating an account's spend rate and time until empty")
+	f.String(prefix+".webhook-url", DefaultBalanceMonitorConfig.WebhookURL, "URL to POST a JSON alert to when an account's balance falls below, or recovers above, its threshold (optional)")
+	f.Duration(prefix+".webhook-timeout", DefaultBalanceMonitorConfig.WebhookTimeout, "timeout for requests to the alert webhook")
+	f.Duration(prefix+".repeat-alert-interval", DefaultBalanceMonitorConfig.RepeatAlertInterval, "how often to repeat an alert while an account's balance stays below its threshold (0 to only alert once)")
+}
+
+func (c *BalanceMonitorConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.Interval <= 0 {
+		return errors.New("balance monitor interval must be positive")
+	}
+	if c.BatchPosterThreshold < 0 || c.ValidatorThreshold < 0 || c.GasRefunderThreshold < 0 {
+		return errors.New("balance monitor thresholds can't be negative")
+	}
+	if c.WebhookURL != "" {
+		if _, err := url.ParseRequestURI(c.WebhookURL); err != nil {
+			return fmt.Errorf("invalid balance monitor webhook URL: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *BalanceMonitorConfig) threshold(role string) float64 {
+	switch role {
+	case balanceRoleBatchPoster:
+		return c.BatchPosterThreshold
+	case balanceRoleValidator:
+		return c.ValidatorThreshold
+	default:
+		return c.GasRefunderThreshold
+	}
+}
+
+type balanceSample struct {
+	time    time.Time
+	balance float64
+}
+
+type monitoredAccount struct {
+	role    string
+	address common.Address
+
+	samples   []balanceSample
+	alerting  bool
+	lastAlert time.Time
+}
+
+// spendRate returns the ether spent per second over the samples, ignoring any top-ups in between
+func (a *monitoredAccount) spendRate() float64 {
+	if len(a.samples) < 2 {
+		return 0
+	}
+	var spent float64
+	for i := 1; i < len(a.samples); i++ {
+		if drop := a.samples[i-1].balance - a.samples[i].balance; drop > 0 {
+			spent += drop
+		}
+	}
+	elapsed := a.samples[len(a.samples)-1].time.Sub(a.samples[0].time).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return spent / elapsed
+}
+
+// BalanceAlert is the JSON body POSTed to the webhook
+type BalanceAlert struct {
+	Kind      string         `json:"kind"`
+	Role      string         `json:"role"`
+	Address   common.Address `json:"address"`
+	Balance   float64        `json:"balanceEther"`
+	Threshold float64        `json:"thresholdEther"`
+	// SpendRate is in ether per hour, and TimeToEmpty in seconds, omitted if nothing was spent recently
+	SpendRate   float64 `json:"spendRateEtherPerHour"`
+	TimeToEmpty *uint64 `json:"timeToEmptySeconds,omitempty"`
+	Recovered   bool    `json:"recovered"`
+}
+
+// BalanceMonitor tracks the parent chain balances of the node's operational accounts, and alerts through
+// metrics and an optional webhook when one falls below its threshold, with an estimate of the time left
+// until it's empty at the account's recent spend rate.
+type BalanceMonitor struct {
+	stopwaiter.StopWaiter
+	config BalanceMonitorConfigFetcher
+	client arbutil.L1Interface
+	http   *http.Client
+
+	accountsMutex sync.Mutex
+	accounts      []*monitoredAccount
+}
+
+func NewBalanceMonitor(client arbutil.L1Interface, config BalanceMonitorConfigFetcher) *BalanceMonitor {
+	return &BalanceMonitor{
+		config: config,
+		client: client,
+		http:   &http.Client{},
+	}
+}
+
+// AddAccount adds an account to monitor, and must be called before Start
+func (m *BalanceMonitor) AddAccount(role string, address common.Address) {
+	if m.Started() {
+		panic("trying to add a monitored account after start")
+	}
+	if address == (common.Address{}) {
+		return
+	}
+	m.accountsMutex.Lock()
+	defer m.accountsMutex.Unlock()
+	m.accounts = append(m.accounts, &monitoredAccount{role: role, address: address})
+}
+
+func (m *BalanceMonitor) Start(ctxIn context.Context) {
+	m.StopWaiter.Start(ctxIn, m)
+	m.CallIteratively(m.checkBalances)
+}
+
+func (m *BalanceMonitor) checkBalances(ctx context.Context) time.Duration {
+	config := m.config()
+	m.accountsMutex.Lock()
+	defer m.accountsMutex.Unlock()
+	now := time.Now()
+	for _, account := range m.accounts {
+		balanceWei, err := m.client.BalanceAt(ctx, account.address, nil)
+		if err != nil {
+			log.Warn("error getting account balance", "role", account.role, "address", account.address, "err", err)
+			continue
+		}
+		m.recordBalance(ctx, config, account, now, arbmath.BalancePerEther(balanceWei))
+	}
+	return config.Interval
+}
+
+func (m *BalanceMonitor) recordBalance(ctx context.Context, config *BalanceMonitorConfig, account *monitoredAccount, now time.Time, balance float64) {
+	account.samples = append(account.samples, balanceSample{time: now, balance: balance})
+	for len(account.samples) > 2 && now.Sub(account.samples[1].time) >= config.SpendRateWindow {
+		account.samples = account.samples[1:]
+	}
+	spendRate := account.spendRate()
+	var timeToEmpty *uint64
+	if spendRate > 0 {
+		seconds := uint64(balance / spendRate)
+		timeToEmpty = &seconds
+	}
+
+	prefix := "arb/balancemonitor/" + account.role
+	metrics.GetOrRegisterGaugeFloat64(prefix+"/balanceether", nil).Update(balance)
+	if timeToEmpty != nil {
+		metrics.GetOrRegisterGauge(prefix+"/timetoempty", nil).Update(int64(*timeToEmpty))
+	} else {
+		// nothing was spent recently, so the account won't run out at this rate
+		metrics.GetOrRegisterGauge(prefix+"/timetoempty", nil).Update(-1)
+	}
+
+	threshold := config.threshold(account.role)
+	below := threshold > 0 && balance < threshold
+	var belowGauge int64
+	if below {
+		belowGauge = 1
+	}
+	metrics.GetOrRegisterGauge(prefix+"/belowthreshold", nil).Update(belowGauge)
+	alert := &BalanceAlert{
+		Kind:        balanceMonitorWebhookPayloadKind,
+		Role:        account.role,
+		Address:     account.address,
+		Balance:     balance,
+		Threshold:   threshold,
+		SpendRate:   spendRate * time.Hour.Seconds(),
+		TimeToEmpty: timeToEmpty,
+	}
+	if !below {
+		if account.alerting {
+			account.alerting = false
+			alert.Recovered = true
+			log.Info("account balance recovered above threshold", "role", account.role, "address", account.address, "balance", balance, "threshold", threshold)
+			m.sendAlert(ctx, config, alert)
+		}
+		return
+	}
+	if account.alerting && (config.RepeatAlertInterval <= 0 || now.Sub(account.lastAlert) < config.RepeatAlertInterval) {
+		return
+	}
+	account.alerting = true
+	account.lastAlert = now
+	metrics.GetOrRegisterCounter(prefix+"/alerts", nil).Inc(1)
+	logCtx := []interface{}{"role", account.role, "address", account.address, "balance", balance, "threshold", threshold}
+	if timeToEmpty != nil {
+		logCtx = append(logCtx, "timeToEmpty", time.Duration(*timeToEmpty)*time.Second)
+	}
+	log.Error("account balance below threshold", logCtx...)
+	m.sendAlert(ctx, config, alert)
+}
+
+func (m *BalanceMonitor) sendAlert(ctx context.Context, config *BalanceMonitorConfig, alert *BalanceAlert) {
+	if config.WebhookURL == "" {
+		return
+	}
+	err := m.postAlert(ctx, config, alert)
+	if err != nil {
+		log.Warn("error sending balance alert to webhook", "role", alert.Role, "err", err)
+		metrics.GetOrRegisterCounter("arb/balancemonitor/webhook/errors", nil).Inc(1)
+	}
+}
+
+func (m *BalanceMonitor) postAlert(ctx context.Context, config *BalanceMonitorConfig, alert *BalanceAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, config.WebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := m.http.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %v", resp.StatusCode)
+	}
+	return nil
+}
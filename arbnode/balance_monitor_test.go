@@ -0,0 +1,84 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestBalanceMonitorAlerts(t *testing.T) {
+	ctx := context.Background()
+	var alertsMutex sync.Mutex
+	var alerts []BalanceAlert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert BalanceAlert
+		Require(t, json.NewDecoder(r.Body).Decode(&alert))
+		alertsMutex.Lock()
+		alerts = append(alerts, alert)
+		alertsMutex.Unlock()
+	}))
+	defer server.Close()
+
+	config := DefaultBalanceMonitorConfig
+	config.Enable = true
+	config.BatchPosterThreshold = 5
+	config.WebhookURL = server.URL
+	config.SpendRateWindow = time.Hour * 3
+	Require(t, config.Validate())
+	monitor := NewBalanceMonitor(nil, func() *BalanceMonitorConfig { return &config })
+	account := &monitoredAccount{role: balanceRoleBatchPoster, address: common.HexToAddress("0x1234")}
+
+	expectAlerts := func(count int) *BalanceAlert {
+		t.Helper()
+		alertsMutex.Lock()
+		defer alertsMutex.Unlock()
+		if len(alerts) != count {
+			Fail(t, "sent", len(alerts), "alerts but expected", count)
+		}
+		if count == 0 {
+			return nil
+		}
+		return &alerts[count-1]
+	}
+
+	start := time.Now()
+	monitor.recordBalance(ctx, &config, account, start, 8)
+	// A top-up doesn't count against the spend rate
+	monitor.recordBalance(ctx, &config, account, start.Add(time.Hour), 10)
+	expectAlerts(0)
+	monitor.recordBalance(ctx, &config, account, start.Add(time.Hour*2), 4)
+	alert := expectAlerts(1)
+	if alert.Recovered || alert.Balance != 4 || alert.Threshold != 5 {
+		Fail(t, "unexpected alert", alert)
+	}
+	// 6 ether were spent over 2 hours, so the remaining 4 last 80 minutes
+	if math.Abs(alert.SpendRate-3) > 1e-9 || alert.TimeToEmpty == nil || *alert.TimeToEmpty+1 < 4800 || *alert.TimeToEmpty > 4800 {
+		Fail(t, "unexpected spend rate", alert.SpendRate, "or time to empty", alert.TimeToEmpty)
+	}
+
+	// The alert is only repeated after the repeat interval
+	monitor.recordBalance(ctx, &config, account, start.Add(time.Hour*2+time.Minute), 4)
+	expectAlerts(1)
+	monitor.recordBalance(ctx, &config, account, start.Add(time.Hour*3+time.Minute), 4)
+	expectAlerts(2)
+
+	monitor.recordBalance(ctx, &config, account, start.Add(time.Hour*4), 20)
+	if oldest := account.samples[0].time; !oldest.Equal(start.Add(time.Hour)) {
+		Fail(t, "oldest sample from", oldest.Sub(start), "but expected only those in the spend rate window to be kept")
+	}
+	if alert := expectAlerts(3); !alert.Recovered {
+		Fail(t, "expected a recovery alert but got", alert)
+	}
+	monitor.recordBalance(ctx, &config, account, start.Add(time.Hour*5), 20)
+	expectAlerts(3)
+}
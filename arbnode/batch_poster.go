@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/andybalholm/brotli"
+	"github.com/go-redis/redis/v8"
 	"github.com/spf13/pflag"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -33,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/offchainlabs/nitro/arbnode/dataposter"
+	redisstorage "github.com/offchainlabs/nitro/arbnode/dataposter/redis"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/storage"
 	"github.com/offchainlabs/nitro/arbnode/redislock"
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
@@ -47,6 +49,7 @@ import (
 	"github.com/offchainlabs/nitro/util/arbmath"
 	"github.com/offchainlabs/nitro/util/blobs"
 	"github.com/offchainlabs/nitro/util/headerreader"
+	"github.com/offchainlabs/nitro/util/latencytracker"
 	"github.com/offchainlabs/nitro/util/redisutil"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
 )
@@ -54,6 +57,33 @@ import (
 var (
 	batchPosterWalletBalance      = metrics.NewRegisteredGaugeFloat64("arb/batchposter/wallet/balanceether", nil)
 	batchPosterGasRefunderBalance = metrics.NewRegisteredGaugeFloat64("arb/batchposter/gasrefunder/balanceether", nil)
+	batchPosterCompressionLevel   = metrics.NewRegisteredGauge("arb/batchposter/compression/level", nil)
+	batchPosterCompressionRatio   = metrics.NewRegisteredGaugeFloat64("arb/batchposter/compression/ratio", nil)
+	batchPosterCompressionTime    = metrics.NewRegisteredHistogram("arb/batchposter/compression/duration", nil, metrics.NewBoundedHistogramSample())
+	batchPosterBlobFeePerByte     = metrics.NewRegisteredGauge("arb/batchposter/blobfeeperbyte", nil)
+	batchPosterCalldataFeePerByte = metrics.NewRegisteredGauge("arb/batchposter/calldatafeeperbyte", nil)
+	batchPosterBlobFallback       = metrics.NewRegisteredCounter("arb/batchposter/blobfallback", nil)
+	batchPosterFeeCeilingWaits    = metrics.NewRegisteredCounter("arb/batchposter/feeceiling/waits", nil)
+	batchPosterFeeCeilingBackoff  = metrics.NewRegisteredGauge("arb/batchposter/feeceiling/backoff", nil)
+	batchPosterFeeCeilingUnposted = metrics.NewRegisteredGauge("arb/batchposter/feeceiling/unposted", nil)
+	batchPosterLeader             = metrics.NewRegisteredGauge("arb/batchposter/leader", nil)
+	batchPosterPostedBatches      = metrics.NewRegisteredCounter("arb/batchposter/posted/batches", nil)
+	batchPosterPostedMessages     = metrics.NewRegisteredCounter("arb/batchposter/posted/messages", nil)
+	batchPosterPostedBytes        = metrics.NewRegisteredCounter("arb/batchposter/posted/bytes", nil)
+	batchPosterPostedBlobs        = metrics.NewRegisteredCounter("arb/batchposter/posted/blobs", nil)
+	batchPosterLastBatchAge       = metrics.NewRegisteredGauge("arb/batchposter/lastbatch/age", nil)
+	batchPosterBacklogMessages    = metrics.NewRegisteredGauge("arb/batchposter/backlog/messages", nil)
+	batchPosterBacklogBatches     = metrics.NewRegisteredGauge("arb/batchposter/backlog/batches", nil)
+	batchPosterNonceLag           = metrics.NewRegisteredGauge("arb/batchposter/l1/noncelag", nil)
+	batchPosterSpentGwei          = metrics.NewRegisteredCounter("arb/batchposter/l1/spentgwei", nil)
+	batchPosterIncludedTxs        = metrics.NewRegisteredCounter("arb/batchposter/l1/included", nil)
+	batchPosterLeaderChanges      = metrics.NewRegisteredCounter("arb/batchposter/leader/changes", nil)
+	batchPosterSimulationReverted = metrics.NewRegisteredCounter("arb/batchposter/simulation/reverted", nil)
+	batchPosterRecoveryMessages   = metrics.NewRegisteredGauge("arb/batchposter/recovery/messages", nil)
+	batchPosterDASFallbackActive  = metrics.NewRegisteredGauge("arb/batchposter/dasfallback/active", nil)
+	batchPosterDASFallbackBatches = metrics.NewRegisteredCounter("arb/batchposter/dasfallback/batches", nil)
+	batchPosterDASFallbackBytes   = metrics.NewRegisteredCounter("arb/batchposter/dasfallback/bytes", nil)
+	batchPosterDASFailures        = metrics.NewRegisteredCounter("arb/batchposter/dasfallback/failures", nil)
 
 	usableBytesInBlob    = big.NewInt(int64(len(kzg4844.Blob{}) * 31 / 32))
 	blobTxBlobGasPerBlob = big.NewInt(params.BlobTxBlobGasPerBlob)
@@ -88,6 +118,8 @@ type BatchPoster struct {
 	gasRefunderAddr    common.Address
 	building           *buildingBatch
 	daWriter           das.DataAvailabilityServiceWriter
+	dapWriter          das.DAProviderWriter
+	dasRenewer         *DASCertRenewer
 	dataPoster         *dataposter.DataPoster
 	redisLock          *redislock.Simple
 	messagesPerBatch   *arbmath.MovingAverage[uint64]
@@ -96,11 +128,29 @@ type BatchPoster struct {
 	// An estimate of the number of batches we want to post but haven't yet.
 	// This doesn't include batches which we don't want to post yet due to the L1 bounds.
 	backlog         uint64
-	lastHitL1Bounds time.Time // The last time we wanted to post a message but hit the L1 bounds
+	lastHitL1Bounds time.Time     // The last time we wanted to post a message but hit the L1 bounds
+	feeBackoff      time.Duration // How long we last waited for L1 fees to fall below the configured maximums
+	lastBatchPosted time.Time     // When this batch poster last posted a batch
+	nextNonce       atomic.Uint64 // The nonce after that of the last batch this batch poster posted
 
 	batchReverted        atomic.Bool // indicates whether data poster batch was reverted
+	draining             atomic.Bool // stop posting new batches so that the key can be rotated
+	drained              atomic.Bool // draining and no posted batches remain unconfirmed
 	nextRevertCheckBlock int64       // the last parent block scanned for reverting batches
 
+	// With exclusive posting, whether this batch poster holds the lock, and whether it's finished taking over from the previous holder
+	leading      bool
+	leaderSince  time.Time
+	handoverDone bool
+
+	// Whether the batch poster has reconciled its queue with the sequencer inbox since it started
+	recovered     bool
+	recoveryStart time.Time
+
+	// After the DAS fails, batch data is posted on chain until this time, then the DAS is tried again
+	dasFallbackUntil   time.Time
+	dasFallbackBatches uint64 // batches posted on chain since the DAS last succeeded
+
 	accessList func(SequencerInboxAccs, AfterDelayedMessagesRead int) types.AccessList
 }
 
@@ -119,12 +169,18 @@ const (
 type BatchPosterConfig struct {
 	Enable                             bool `koanf:"enable"`
 	DisableDasFallbackStoreDataOnChain bool `koanf:"disable-das-fallback-store-data-on-chain" reload:"hot"`
+	// How long to post batch data on chain after the DAS fails before trying the DAS again.
+	DasFallbackRetryInterval time.Duration `koanf:"das-fallback-retry-interval" reload:"hot"`
 	// Max batch size.
 	MaxSize int `koanf:"max-size" reload:"hot"`
 	// Maximum 4844 blob enabled batch size.
 	Max4844BatchSize int `koanf:"max-4844-batch-size" reload:"hot"`
+	// Maximum batch size when storing batches with a data availability service.
+	MaxDASBatchSize int `koanf:"max-das-batch-size" reload:"hot"`
 	// Max batch post delay.
 	MaxDelay time.Duration `koanf:"max-delay" reload:"hot"`
+	// Max number of messages in a batch.
+	MaxMessagesPerBatch uint64 `koanf:"max-messages-per-batch" reload:"hot"`
 	// Wait for max BatchPost delay.
 	WaitForMaxDelay bool `koanf:"wait-for-max-delay" reload:"hot"`
 	// Batch post polling interval.
@@ -132,14 +188,24 @@ type BatchPosterConfig struct {
 	// Batch posting error delay.
 	ErrorDelay                     time.Duration               `koanf:"error-delay" reload:"hot"`
 	CompressionLevel               int                         `koanf:"compression-level" reload:"hot"`
+	AdaptiveCompression            bool                        `koanf:"adaptive-compression" reload:"hot"`
+	AdaptiveCompressionBacklog     uint64                      `koanf:"adaptive-compression-backlog" reload:"hot"`
 	DASRetentionPeriod             time.Duration               `koanf:"das-retention-period" reload:"hot"`
+	DASRenewal                     DASRenewalConfig            `koanf:"das-renewal" reload:"hot"`
 	GasRefunderAddress             string                      `koanf:"gas-refunder-address" reload:"hot"`
 	DataPoster                     dataposter.DataPosterConfig `koanf:"data-poster" reload:"hot"`
 	RedisUrl                       string                      `koanf:"redis-url"`
 	RedisLock                      redislock.SimpleCfg         `koanf:"redis-lock" reload:"hot"`
+	ExclusivePosting               bool                        `koanf:"exclusive-posting"`
+	HandoverTimeout                time.Duration               `koanf:"handover-timeout" reload:"hot"`
 	ExtraBatchGas                  uint64                      `koanf:"extra-batch-gas" reload:"hot"`
 	Post4844Blobs                  bool                        `koanf:"post-4844-blobs" reload:"hot"`
 	IgnoreBlobPrice                bool                        `koanf:"ignore-blob-price" reload:"hot"`
+	MaxBlobFeeGwei                 float64                     `koanf:"max-blob-fee-gwei" reload:"hot"`
+	MaxL1BaseFeeGwei               float64                     `koanf:"max-l1-base-fee-gwei" reload:"hot"`
+	MaxL1TipCapGwei                float64                     `koanf:"max-l1-tip-cap-gwei" reload:"hot"`
+	MaxFeeBackoff                  time.Duration               `koanf:"max-fee-backoff" reload:"hot"`
+	FeeCeilingBypass               time.Duration               `koanf:"fee-ceiling-bypass" reload:"hot"`
 	ParentChainWallet              genericconf.WalletConfig    `koanf:"parent-chain-wallet"`
 	L1BlockBound                   string                      `koanf:"l1-block-bound" reload:"hot"`
 	L1BlockBoundBypass             time.Duration               `koanf:"l1-block-bound-bypass" reload:"hot"`
@@ -158,6 +224,30 @@ func (c *BatchPosterConfig) Validate() error {
 	if c.MaxSize <= 40 {
 		return errors.New("MaxBatchSize too small")
 	}
+	if c.MaxDASBatchSize != 0 && c.MaxDASBatchSize <= 40 {
+		return errors.New("MaxDASBatchSize too small")
+	}
+	if c.DasFallbackRetryInterval < 0 {
+		return errors.New("das-fallback-retry-interval must not be negative")
+	}
+	if c.CompressionLevel < brotli.BestSpeed || c.CompressionLevel > brotli.BestCompression {
+		return fmt.Errorf("invalid compression level %v (must be between %v and %v)", c.CompressionLevel, brotli.BestSpeed, brotli.BestCompression)
+	}
+	if c.AdaptiveCompression && c.AdaptiveCompressionBacklog == 0 {
+		return errors.New("adaptive compression backlog must be positive")
+	}
+	if err := c.DASRenewal.Validate(); err != nil {
+		return err
+	}
+	if err := c.DataPoster.Validate(); err != nil {
+		return err
+	}
+	if c.ExclusivePosting && (c.RedisUrl == "" || !c.RedisLock.Enable) {
+		return errors.New("exclusive batch posting requires a redis url and the redis lock to be enabled")
+	}
+	if c.MaxL1BaseFeeGwei < 0 || c.MaxL1TipCapGwei < 0 {
+		return errors.New("maximum parent chain fees can't be negative")
+	}
 	if c.L1BlockBound == "" {
 		c.l1BlockBound = l1BlockBoundDefault
 	} else if c.L1BlockBound == "safe" {
@@ -179,24 +269,37 @@ type BatchPosterConfigFetcher func() *BatchPosterConfig
 func BatchPosterConfigAddOptions(prefix string, f *pflag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultBatchPosterConfig.Enable, "enable posting batches to l1")
 	f.Bool(prefix+".disable-das-fallback-store-data-on-chain", DefaultBatchPosterConfig.DisableDasFallbackStoreDataOnChain, "If unable to batch to DAS, disable fallback storing data on chain")
-	f.Int(prefix+".max-size", DefaultBatchPosterConfig.MaxSize, "maximum batch size")
+	f.Duration(prefix+".das-fallback-retry-interval", DefaultBatchPosterConfig.DasFallbackRetryInterval, "after failing to batch to DAS, how long to keep storing data on chain before trying the DAS again")
+	f.Int(prefix+".max-size", DefaultBatchPosterConfig.MaxSize, "maximum batch size in bytes")
 	f.Int(prefix+".max-4844-batch-size", DefaultBatchPosterConfig.Max4844BatchSize, "maximum 4844 blob enabled batch size")
-	f.Duration(prefix+".max-delay", DefaultBatchPosterConfig.MaxDelay, "maximum batch posting delay")
+	f.Int(prefix+".max-das-batch-size", DefaultBatchPosterConfig.MaxDASBatchSize, "maximum batch size in bytes when storing batches with a data availability service (0 = use max-size)")
+	f.Duration(prefix+".max-delay", DefaultBatchPosterConfig.MaxDelay, "maximum batch posting delay (how old the oldest message in a batch may get before the batch is posted)")
+	f.Uint64(prefix+".max-messages-per-batch", DefaultBatchPosterConfig.MaxMessagesPerBatch, "maximum number of messages in a batch, after which the batch is considered full (0 for no limit)")
 	f.Bool(prefix+".wait-for-max-delay", DefaultBatchPosterConfig.WaitForMaxDelay, "wait for the max batch delay, even if the batch is full")
 	f.Duration(prefix+".poll-interval", DefaultBatchPosterConfig.PollInterval, "how long to wait after no batches are ready to be posted before checking again")
 	f.Duration(prefix+".error-delay", DefaultBatchPosterConfig.ErrorDelay, "how long to delay after error posting batch")
 	f.Int(prefix+".compression-level", DefaultBatchPosterConfig.CompressionLevel, "batch compression level")
+	f.Bool(prefix+".adaptive-compression", DefaultBatchPosterConfig.AdaptiveCompression, "lower the compression level to compress faster when the batch poster is falling behind")
+	f.Uint64(prefix+".adaptive-compression-backlog", DefaultBatchPosterConfig.AdaptiveCompressionBacklog, "when using adaptive compression, how many batches of backlog before the compression level is lowered (it's lowered further at twice and three times this backlog)")
 	f.Duration(prefix+".das-retention-period", DefaultBatchPosterConfig.DASRetentionPeriod, "In AnyTrust mode, the period which DASes are requested to retain the stored batches.")
+	DASRenewalConfigAddOptions(prefix+".das-renewal", f)
 	f.String(prefix+".gas-refunder-address", DefaultBatchPosterConfig.GasRefunderAddress, "The gas refunder contract address (optional)")
 	f.Uint64(prefix+".extra-batch-gas", DefaultBatchPosterConfig.ExtraBatchGas, "use this much more gas than estimation says is necessary to post batches")
 	f.Bool(prefix+".post-4844-blobs", DefaultBatchPosterConfig.Post4844Blobs, "if the parent chain supports 4844 blobs and they're well priced, post EIP-4844 blobs")
 	f.Bool(prefix+".ignore-blob-price", DefaultBatchPosterConfig.IgnoreBlobPrice, "if the parent chain supports 4844 blobs and ignore-blob-price is true, post 4844 blobs even if it's not price efficient")
+	f.Float64(prefix+".max-blob-fee-gwei", DefaultBatchPosterConfig.MaxBlobFeeGwei, "fall back to posting batches as calldata while the parent chain's blob base fee is above this, even if ignore-blob-price is set (0 to disable)")
+	f.Float64(prefix+".max-l1-base-fee-gwei", DefaultBatchPosterConfig.MaxL1BaseFeeGwei, "wait to post batches while the parent chain's base fee is above this (0 to disable)")
+	f.Float64(prefix+".max-l1-tip-cap-gwei", DefaultBatchPosterConfig.MaxL1TipCapGwei, "wait to post batches while the parent chain's suggested priority fee is above this (0 to disable)")
+	f.Duration(prefix+".max-fee-backoff", DefaultBatchPosterConfig.MaxFeeBackoff, "when waiting for parent chain fees to fall, the maximum time to wait between checks (the wait starts at the poll interval and doubles each time)")
+	f.Duration(prefix+".fee-ceiling-bypass", DefaultBatchPosterConfig.FeeCeilingBypass, "post batches regardless of the parent chain fee maximums once the oldest unposted message is within this margin of the sequencer inbox's max delay, so that delayed messages can't be force included ahead of it")
 	f.String(prefix+".redis-url", DefaultBatchPosterConfig.RedisUrl, "if non-empty, the Redis URL to store queued transactions in")
 	f.String(prefix+".l1-block-bound", DefaultBatchPosterConfig.L1BlockBound, "only post messages to batches when they're within the max future block/timestamp as of this L1 block tag (\"safe\", \"finalized\", \"latest\", or \"ignore\" to ignore this check)")
 	f.Duration(prefix+".l1-block-bound-bypass", DefaultBatchPosterConfig.L1BlockBoundBypass, "post batches even if not within the layer 1 future bounds if we're within this margin of the max delay")
 	f.Bool(prefix+".use-access-lists", DefaultBatchPosterConfig.UseAccessLists, "post batches with access lists to reduce gas usage (disabled for L3s)")
 	f.Uint64(prefix+".gas-estimate-base-fee-multiple-bips", uint64(DefaultBatchPosterConfig.GasEstimateBaseFeeMultipleBips), "for gas estimation, use this multiple of the basefee (measured in basis points) as the max fee per gas")
 	redislock.AddConfigOptions(prefix+".redis-lock", f)
+	f.Bool(prefix+".exclusive-posting", DefaultBatchPosterConfig.ExclusivePosting, "only post batches while holding the redis lock, so that redundant batch posters, possibly with different keys, take turns with one posting at a time")
+	f.Duration(prefix+".handover-timeout", DefaultBatchPosterConfig.HandoverTimeout, "with exclusive posting, how long a batch poster that has just taken the lock waits for batches posted by the previous holder to leave the parent chain's mempool, and how long a restarted batch poster without queued transactions waits for the batches it posted before restarting")
 	dataposter.DataPosterConfigAddOptions(prefix+".data-poster", f, dataposter.DefaultDataPosterConfig)
 	genericconf.WalletConfigAddOptions(prefix+".parent-chain-wallet", f, DefaultBatchPosterConfig.ParentChainWallet.Pathname)
 }
@@ -204,26 +307,39 @@ func BatchPosterConfigAddOptions(prefix string, f *pflag.FlagSet) {
 var DefaultBatchPosterConfig = BatchPosterConfig{
 	Enable:                             false,
 	DisableDasFallbackStoreDataOnChain: false,
+	DasFallbackRetryInterval:           time.Minute * 5,
 	// This default is overridden for L3 chains in applyChainParameters in cmd/nitro/nitro.go
 	MaxSize: 100000,
 	// TODO: is 1000 bytes an appropriate margin for error vs blob space efficiency?
 	Max4844BatchSize:               blobs.BlobEncodableData*(params.MaxBlobGasPerBlock/params.BlobTxBlobGasPerBlob) - 1000,
+	MaxDASBatchSize:                0,
 	PollInterval:                   time.Second * 10,
 	ErrorDelay:                     time.Second * 10,
 	MaxDelay:                       time.Hour,
+	MaxMessagesPerBatch:            0,
 	WaitForMaxDelay:                false,
 	CompressionLevel:               brotli.BestCompression,
+	AdaptiveCompression:            true,
+	AdaptiveCompressionBacklog:     20,
 	DASRetentionPeriod:             time.Hour * 24 * 15,
+	DASRenewal:                     DefaultDASRenewalConfig,
 	GasRefunderAddress:             "",
 	ExtraBatchGas:                  50_000,
 	Post4844Blobs:                  false,
 	IgnoreBlobPrice:                false,
+	MaxBlobFeeGwei:                 0,
+	MaxL1BaseFeeGwei:               0,
+	MaxL1TipCapGwei:                0,
+	MaxFeeBackoff:                  time.Minute * 5,
+	FeeCeilingBypass:               time.Hour * 4,
 	DataPoster:                     dataposter.DefaultDataPosterConfig,
 	ParentChainWallet:              DefaultBatchPosterL1WalletConfig,
 	L1BlockBound:                   "",
 	L1BlockBoundBypass:             time.Hour,
 	UseAccessLists:                 true,
 	RedisLock:                      redislock.DefaultCfg,
+	ExclusivePosting:               false,
+	HandoverTimeout:                time.Minute * 5,
 	GasEstimateBaseFeeMultipleBips: arbmath.OneInBips * 3 / 2,
 }
 
@@ -239,16 +355,26 @@ var TestBatchPosterConfig = BatchPosterConfig{
 	Enable:                         true,
 	MaxSize:                        100000,
 	Max4844BatchSize:               DefaultBatchPosterConfig.Max4844BatchSize,
+	MaxDASBatchSize:                0,
 	PollInterval:                   time.Millisecond * 10,
 	ErrorDelay:                     time.Millisecond * 10,
 	MaxDelay:                       0,
+	MaxMessagesPerBatch:            0,
 	WaitForMaxDelay:                false,
 	CompressionLevel:               2,
+	AdaptiveCompression:            true,
+	AdaptiveCompressionBacklog:     20,
 	DASRetentionPeriod:             time.Hour * 24 * 15,
+	DASRenewal:                     DefaultDASRenewalConfig,
 	GasRefunderAddress:             "",
 	ExtraBatchGas:                  10_000,
 	Post4844Blobs:                  true,
 	IgnoreBlobPrice:                false,
+	MaxBlobFeeGwei:                 0,
+	MaxL1BaseFeeGwei:               0,
+	MaxL1TipCapGwei:                0,
+	MaxFeeBackoff:                  time.Minute * 5,
+	FeeCeilingBypass:               time.Hour * 4,
 	DataPoster:                     dataposter.TestDataPosterConfig,
 	ParentChainWallet:              DefaultBatchPosterL1WalletConfig,
 	L1BlockBound:                   "",
@@ -268,6 +394,8 @@ type BatchPosterOpts struct {
 	DeployInfo    *chaininfo.RollupAddresses
 	TransactOpts  *bind.TransactOpts
 	DAWriter      das.DataAvailabilityServiceWriter
+	DAReader      das.DataAvailabilityServiceReader
+	DAPWriter     das.DAProviderWriter
 	ParentChainID *big.Int
 }
 
@@ -296,11 +424,12 @@ func NewBatchPoster(ctx context.Context, opts *BatchPosterOpts) (*BatchPoster, e
 		simpleRedisLockConfig.Key = batchPosterSimpleRedisLockKey
 		return &simpleRedisLockConfig
 	}
-	redisLock, err := redislock.NewSimple(redisClient, redisLockConfigFetcher, func() bool { return opts.SyncMonitor.Synced() })
+	var b *BatchPoster
+	redisLock, err := redislock.NewSimple(redisClient, redisLockConfigFetcher, func() bool { return opts.SyncMonitor.Synced() && !b.drained.Load() })
 	if err != nil {
 		return nil, err
 	}
-	b := &BatchPoster{
+	b = &BatchPoster{
 		l1Reader:           opts.L1Reader,
 		inbox:              opts.Inbox,
 		streamer:           opts.Streamer,
@@ -314,15 +443,28 @@ func NewBatchPoster(ctx context.Context, opts *BatchPosterOpts) (*BatchPoster, e
 		gasRefunderAddr:    opts.Config().gasRefunder,
 		bridgeAddr:         opts.DeployInfo.Bridge,
 		daWriter:           opts.DAWriter,
+		dapWriter:          opts.DAPWriter,
 		redisLock:          redisLock,
 	}
 	b.messagesPerBatch, err = arbmath.NewMovingAverage[uint64](20)
 	if err != nil {
 		return nil, err
 	}
+	if opts.Config().DASRenewal.Enable && opts.DAWriter != nil && opts.DAReader != nil {
+		confirmedBatchFetcher, err := RollupConfirmedBatchFetcher(opts.DeployInfo.Rollup, opts.L1Reader.Client())
+		if err != nil {
+			return nil, err
+		}
+		b.dasRenewer = NewDASCertRenewer(opts.DAWriter, opts.DAReader, confirmedBatchFetcher, opts.Config)
+	}
 	dataPosterConfigFetcher := func() *dataposter.DataPosterConfig {
 		return &(opts.Config().DataPoster)
 	}
+	if redisClient != nil && !opts.Config().DataPoster.UseNoOpStorage && !opts.L1Reader.IsParentChainArbitrum() {
+		if err := migrateDataPosterQueue(ctx, opts, redisClient); err != nil {
+			return nil, fmt.Errorf("error migrating the data poster queue in redis: %w", err)
+		}
+	}
 	b.dataPoster, err = dataposter.NewDataPoster(ctx,
 		&dataposter.DataPosterOpts{
 			Database:          opts.DataPosterDB,
@@ -332,7 +474,7 @@ func NewBatchPoster(ctx context.Context, opts *BatchPosterOpts) (*BatchPoster, e
 			Config:            dataPosterConfigFetcher,
 			MetadataRetriever: b.getBatchPosterPosition,
 			ExtraBacklog:      b.GetBacklogEstimate,
-			RedisKey:          "data-poster.queue",
+			RedisKey:          dataPosterRedisKey(opts),
 			ParentChainID:     opts.ParentChainID,
 		})
 	if err != nil {
@@ -358,6 +500,79 @@ func NewBatchPoster(ctx context.Context, opts *BatchPosterOpts) (*BatchPoster, e
 	return b, nil
 }
 
+const sharedDataPosterRedisKey = "data-poster.queue"
+
+// dataPosterRedisKey returns where the data poster keeps its queue in redis.
+// Batch posters sharing a key share the queue, but with exclusive posting, redundant batch posters
+// may use different keys, and each needs its own queue since they have separate nonces.
+func dataPosterRedisKey(opts *BatchPosterOpts) string {
+	if !opts.Config().ExclusivePosting {
+		return sharedDataPosterRedisKey
+	}
+	return senderDataPosterRedisKey(batchPosterSender(opts))
+}
+
+func senderDataPosterRedisKey(sender common.Address) string {
+	return sharedDataPosterRedisKey + "." + strings.ToLower(sender.Hex())
+}
+
+func batchPosterSender(opts *BatchPosterOpts) common.Address {
+	if opts.TransactOpts != nil {
+		return opts.TransactOpts.From
+	}
+	return common.HexToAddress(opts.Config().DataPoster.ExternalSigner.Address)
+}
+
+// migrateDataPosterQueue moves the data poster's queue in redis when exclusive posting has been turned on or off,
+// so that the transactions queued under the old key aren't orphaned. The shared queue is only moved to the
+// batch poster whose key queued it, and a queue is never moved over one that already exists.
+func migrateDataPosterQueue(ctx context.Context, opts *BatchPosterOpts, redisClient redis.UniversalClient) error {
+	config := opts.Config()
+	sender := batchPosterSender(opts)
+	from, to := senderDataPosterRedisKey(sender), sharedDataPosterRedisKey
+	if config.ExclusivePosting {
+		from, to = to, from
+	}
+	exists, err := redisClient.Exists(ctx, from).Result()
+	if err != nil || exists == 0 {
+		return err
+	}
+	if config.ExclusivePosting {
+		queue, err := redisstorage.NewStorage(redisClient, from, &config.DataPoster.RedisSigner, func() storage.EncoderDecoderInterface { return &storage.EncoderDecoder{} })
+		if err != nil {
+			return err
+		}
+		last, err := queue.FetchLast(ctx)
+		if err != nil {
+			return err
+		}
+		if last == nil || last.FullTx == nil {
+			return nil
+		}
+		queuedBy, err := types.Sender(types.LatestSignerForChainID(opts.ParentChainID), last.FullTx)
+		if err != nil {
+			return err
+		}
+		if queuedBy != sender {
+			return nil
+		}
+	}
+	moved, err := redisClient.RenameNX(ctx, from, to).Result()
+	if err != nil {
+		if exists, existsErr := redisClient.Exists(ctx, from).Result(); existsErr == nil && exists == 0 {
+			// another batch poster with the same key moved it first
+			return nil
+		}
+		return err
+	}
+	if moved {
+		log.Info("moved data poster queue in redis", "from", from, "to", to, "exclusivePosting", config.ExclusivePosting)
+	} else {
+		log.Warn("not moving data poster queue in redis, as the destination already has one", "from", from, "to", to, "exclusivePosting", config.ExclusivePosting)
+	}
+	return nil
+}
+
 type AccessListOpts struct {
 	SequencerInboxAddr       common.Address
 	BridgeAddr               common.Address
@@ -455,6 +670,12 @@ func (b *BatchPoster) checkReverts(ctx context.Context, to int64) (bool, error)
 				if err != nil {
 					return false, fmt.Errorf("getting a receipt for transaction: %v, %w", tx.Hash(), err)
 				}
+				// Only batch posts are counted, not other transactions sent from the batch poster's wallet.
+				// If the parent chain reorgs, a batch included again is counted again.
+				if to := tx.To(); to != nil && *to == b.seqInboxAddr {
+					batchPosterIncludedTxs.Inc(1)
+					batchPosterSpentGwei.Inc(receiptCostGwei(r))
+				}
 				if r.Status == types.ReceiptStatusFailed {
 					shouldHalt := !b.config().DataPoster.UseNoOpStorage
 					logLevel := log.Warn
@@ -471,6 +692,18 @@ func (b *BatchPoster) checkReverts(ctx context.Context, to int64) (bool, error)
 	return false, nil
 }
 
+// receiptCostGwei returns how much a transaction cost its sender in execution and blob fees, in gwei
+func receiptCostGwei(r *types.Receipt) int64 {
+	cost := new(big.Int)
+	if r.EffectiveGasPrice != nil {
+		cost.Mul(arbmath.UintToBig(r.GasUsed), r.EffectiveGasPrice)
+	}
+	if r.BlobGasPrice != nil {
+		cost.Add(cost, arbmath.BigMulByUint(r.BlobGasPrice, r.BlobGasUsed))
+	}
+	return saturatingGaugeValue(cost.Div(cost, big.NewInt(params.GWei)))
+}
+
 // pollForReverts runs a gouroutine that listens to l1 block headers, checks
 // if any transaction made by batch poster was reverted.
 func (b *BatchPoster) pollForReverts(ctx context.Context) {
@@ -555,6 +788,7 @@ type batchSegments struct {
 	delayedMsg            uint64
 	sizeLimit             int
 	recompressionLevel    int
+	compressionTime       time.Duration
 	newUncompressedSize   int
 	totalUncompressedSize int
 	lastCompressedSize    int
@@ -568,10 +802,14 @@ type buildingBatch struct {
 	msgCount          arbutil.MessageIndex
 	haveUsefulMessage bool
 	use4844           bool
+	useDAS            bool
 }
 
-func newBatchSegments(firstDelayed uint64, config *BatchPosterConfig, backlog uint64, use4844 bool) *batchSegments {
+func newBatchSegments(firstDelayed uint64, config *BatchPosterConfig, backlog uint64, use4844 bool, useDAS bool) *batchSegments {
 	maxSize := config.MaxSize
+	if useDAS && config.MaxDASBatchSize > 0 {
+		maxSize = config.MaxDASBatchSize
+	}
 	if use4844 {
 		maxSize = config.Max4844BatchSize
 	} else {
@@ -581,15 +819,34 @@ func newBatchSegments(firstDelayed uint64, config *BatchPosterConfig, backlog ui
 		maxSize -= 40
 	}
 	compressedBuffer := bytes.NewBuffer(make([]byte, 0, maxSize*2))
+	compressionLevel, recompressionLevel := compressionLevels(config, backlog)
+	batchPosterCompressionLevel.Update(int64(recompressionLevel))
+	return &batchSegments{
+		compressedBuffer:   compressedBuffer,
+		compressedWriter:   brotli.NewWriterLevel(compressedBuffer, compressionLevel),
+		sizeLimit:          maxSize,
+		recompressionLevel: recompressionLevel,
+		rawSegments:        make([][]byte, 0, 128),
+		delayedMsg:         firstDelayed,
+	}
+}
+
+// compressionLevels picks the levels used to build and then recompress a batch.
+// With adaptive compression, the levels are lowered as the backlog grows so that we can catch up.
+func compressionLevels(config *BatchPosterConfig, backlog uint64) (int, int) {
 	compressionLevel := config.CompressionLevel
 	recompressionLevel := config.CompressionLevel
-	if backlog > 20 {
+	if !config.AdaptiveCompression {
+		return compressionLevel, recompressionLevel
+	}
+	threshold := config.AdaptiveCompressionBacklog
+	if backlog > threshold {
 		compressionLevel = arbmath.MinInt(compressionLevel, brotli.DefaultCompression)
 	}
-	if backlog > 40 {
+	if backlog > threshold*2 {
 		recompressionLevel = arbmath.MinInt(recompressionLevel, brotli.DefaultCompression)
 	}
-	if backlog > 60 {
+	if backlog > threshold*3 {
 		compressionLevel = arbmath.MinInt(compressionLevel, 4)
 	}
 	if recompressionLevel < compressionLevel {
@@ -601,14 +858,7 @@ func newBatchSegments(firstDelayed uint64, config *BatchPosterConfig, backlog ui
 		)
 		recompressionLevel = compressionLevel
 	}
-	return &batchSegments{
-		compressedBuffer:   compressedBuffer,
-		compressedWriter:   brotli.NewWriterLevel(compressedBuffer, compressionLevel),
-		sizeLimit:          maxSize,
-		recompressionLevel: recompressionLevel,
-		rawSegments:        make([][]byte, 0, 128),
-		delayedMsg:         firstDelayed,
-	}
+	return compressionLevel, recompressionLevel
 }
 
 func (s *batchSegments) recompressAll() error {
@@ -648,7 +898,9 @@ func (s *batchSegments) testForOverflow(isHeader bool) (bool, error) {
 	if isHeader || len(s.rawSegments) == s.trailingHeaders {
 		return false, nil
 	}
+	start := time.Now()
 	err := s.compressedWriter.Flush()
+	s.compressionTime += time.Since(start)
 	if err != nil {
 		return true, err
 	}
@@ -676,7 +928,9 @@ func (s *batchSegments) addSegmentToCompressed(segment []byte) error {
 	if err != nil {
 		return err
 	}
+	start := time.Now()
 	lenWritten, err := s.compressedWriter.Write(encoded)
+	s.compressionTime += time.Since(start)
 	s.newUncompressedSize += lenWritten
 	s.totalUncompressedSize += lenWritten
 	return err
@@ -786,11 +1040,17 @@ func (s *batchSegments) CloseAndGetBytes() ([]byte, error) {
 	if len(s.rawSegments) == 0 {
 		return nil, nil
 	}
+	start := time.Now()
 	err := s.compressedWriter.Close()
+	s.compressionTime += time.Since(start)
 	if err != nil {
 		return nil, err
 	}
 	compressedBytes := s.compressedBuffer.Bytes()
+	batchPosterCompressionTime.Update(s.compressionTime.Milliseconds())
+	if len(compressedBytes) > 0 {
+		batchPosterCompressionRatio.Update(float64(s.totalUncompressedSize) / float64(len(compressedBytes)))
+	}
 	fullMsg := make([]byte, 1, len(compressedBytes)+1)
 	fullMsg[0] = arbstate.BrotliMessageHeaderByte
 	fullMsg = append(fullMsg, compressedBytes...)
@@ -864,6 +1124,86 @@ func estimateGas(client rpc.ClientInterface, ctx context.Context, params estimat
 	return uint64(gas), err
 }
 
+// BatchRevertError is returned when simulating a batch shows that the sequencer inbox would revert it
+type BatchRevertError struct {
+	// Reason is the name of the sequencer inbox error, or empty if the revert data couldn't be decoded
+	Reason string
+	Args   []interface{}
+	err    error
+}
+
+func (e *BatchRevertError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("sequencer inbox would revert batch: %v", e.err)
+	}
+	return fmt.Sprintf("sequencer inbox would revert batch with %v%v: %v", e.Reason, e.Args, e.err)
+}
+
+func (e *BatchRevertError) Unwrap() error {
+	return e.err
+}
+
+// StaleDelayedCount returns whether the batch was built against a delayed message count the inbox no longer agrees with
+func (e *BatchRevertError) StaleDelayedCount() bool {
+	return e.Reason == "DelayedBackwards" || e.Reason == "DelayedTooFar"
+}
+
+func revertData(err error) []byte {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil
+	}
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil
+	}
+	data, err := hexutil.Decode(hexData)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func (b *BatchPoster) decodeSeqInboxRevert(data []byte) (string, []interface{}, bool) {
+	if len(data) < 4 {
+		return "", nil, false
+	}
+	for _, abiErr := range b.seqInboxABI.Errors {
+		if !bytes.Equal(data[:4], abiErr.ID[:4]) {
+			continue
+		}
+		args, err := abiErr.Inputs.Unpack(data[4:])
+		if err != nil {
+			return "", nil, false
+		}
+		return abiErr.Name, args, true
+	}
+	return "", nil, false
+}
+
+// simulationError turns a failed gas estimation that reverted into a BatchRevertError with the sequencer
+// inbox's revert reason, so batches which would revert aren't posted and the reason is visible.
+func (b *BatchPoster) simulationError(ctx context.Context, client rpc.ClientInterface, params estimateGasParams, err error) error {
+	if !headerreader.ExecutionRevertedRegexp.MatchString(err.Error()) {
+		return err
+	}
+	batchPosterSimulationReverted.Inc(1)
+	data := revertData(err)
+	if data == nil {
+		// Not every parent chain client includes the revert data in gas estimation errors, but eth_call returns it
+		var result hexutil.Bytes
+		if callErr := client.CallContext(ctx, &result, "eth_call", params, "latest"); callErr != nil {
+			data = revertData(callErr)
+		}
+	}
+	revertErr := &BatchRevertError{err: err}
+	if reason, args, ok := b.decodeSeqInboxRevert(data); ok {
+		revertErr.Reason = reason
+		revertErr.Args = args
+	}
+	return revertErr
+}
+
 func (b *BatchPoster) estimateGas(ctx context.Context, sequencerMessage []byte, delayedMessages uint64, realData []byte, realBlobs []kzg4844.Blob, realNonce uint64, realAccessList types.AccessList) (uint64, error) {
 	config := b.config()
 	rpcClient := b.l1Reader.Client()
@@ -887,17 +1227,19 @@ func (b *BatchPoster) estimateGas(ctx context.Context, sequencerMessage []byte,
 		if err != nil {
 			return 0, fmt.Errorf("failed to compute real blob commitments: %w", err)
 		}
-		// If we're at the latest nonce, we can skip the special future tx estimate stuff
-		gas, err := estimateGas(rawRpcClient, ctx, estimateGasParams{
+		// If we're at the latest nonce, we can skip the special future tx estimate stuff,
+		// and the estimate simulates the real batch against the sequencer inbox's current state.
+		params := estimateGasParams{
 			From:         b.dataPoster.Sender(),
 			To:           &b.seqInboxAddr,
 			Data:         realData,
 			MaxFeePerGas: (*hexutil.Big)(maxFeePerGas),
 			BlobHashes:   realBlobHashes,
 			AccessList:   realAccessList,
-		})
+		}
+		gas, err := estimateGas(rawRpcClient, ctx, params)
 		if err != nil {
-			return 0, fmt.Errorf("%w: %w", ErrNormalGasEstimationFailed, err)
+			return 0, fmt.Errorf("%w: %w", ErrNormalGasEstimationFailed, b.simulationError(ctx, rawRpcClient, params, err))
 		}
 		return gas + config.ExtraBatchGas, nil
 	}
@@ -914,7 +1256,7 @@ func (b *BatchPoster) estimateGas(ctx context.Context, sequencerMessage []byte,
 	if err != nil {
 		return 0, fmt.Errorf("failed to compute blob commitments: %w", err)
 	}
-	gas, err := estimateGas(rawRpcClient, ctx, estimateGasParams{
+	params := estimateGasParams{
 		From:         b.dataPoster.Sender(),
 		To:           &b.seqInboxAddr,
 		Data:         data,
@@ -923,8 +1265,10 @@ func (b *BatchPoster) estimateGas(ctx context.Context, sequencerMessage []byte,
 		// This isn't perfect because we're probably estimating the batch at a different sequence number,
 		// but it should overestimate rather than underestimate which is fine.
 		AccessList: realAccessList,
-	})
+	}
+	gas, err := estimateGas(rawRpcClient, ctx, params)
 	if err != nil {
+		err = b.simulationError(ctx, rawRpcClient, params, err)
 		sequencerMessageHeader := sequencerMessage
 		if len(sequencerMessageHeader) > 33 {
 			sequencerMessageHeader = sequencerMessageHeader[:33]
@@ -941,10 +1285,42 @@ func (b *BatchPoster) estimateGas(ctx context.Context, sequencerMessage []byte,
 	return gas + config.ExtraBatchGas, nil
 }
 
+// The sequencer inbox's time bounds are in L1 blocks even when the parent chain is an Arbitrum chain,
+// so padding is converted to blocks at Ethereum's block time whatever the parent chain's own block time is.
 const ethPosBlockTime = 12 * time.Second
 
 var errAttemptLockFailed = errors.New("failed to acquire lock; either another batch poster posted a batch or this node fell behind")
 
+// saturatingGaugeValue converts a fee to a gauge value, saturating rather than overflowing for huge fees
+func saturatingGaugeValue(value *big.Int) int64 {
+	return arbmath.SaturatingCast(arbmath.BigToUintSaturating(value))
+}
+
+// chooseBlobPosting decides whether to post the next batch in blobs given the parent chain's fees, and whether it's
+// posted as calldata because of the blob price
+func chooseBlobPosting(config *BatchPosterConfig, blobFee, blobFeePerByte, calldataFeePerByte *big.Int, backlog uint64, non4844BatchCount int) (use4844 bool, fellBack bool) {
+	if config.MaxBlobFeeGwei > 0 && arbmath.BigGreaterThan(blobFee, arbmath.FloatToBig(config.MaxBlobFeeGwei*params.GWei)) {
+		// Blob fees have spiked; post as calldata until they come back down
+		log.Info("blob base fee above maximum, posting batch as calldata", "blobFee", blobFee, "maxBlobFeeGwei", config.MaxBlobFeeGwei)
+		return false, true
+	}
+	if config.IgnoreBlobPrice {
+		return true, false
+	}
+	// Logic to prevent switching from non-4844 batches to 4844 batches too often,
+	// so that blocks can be filled efficiently. The geth txpool rejects txs for
+	// accounts that already have the other type of txs in the pool with
+	// "address already reserved". This logic makes sure that, if there is a backlog,
+	// that enough non-4844 batches have been posted to fill a block before switching.
+	if backlog == 0 ||
+		non4844BatchCount == 0 ||
+		non4844BatchCount > 16 {
+		use4844 = arbmath.BigLessThan(blobFeePerByte, calldataFeePerByte)
+		return use4844, !use4844
+	}
+	return false, false
+}
+
 func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error) {
 	if b.batchReverted.Load() {
 		return false, fmt.Errorf("batch was reverted, not posting any more batches")
@@ -972,40 +1348,42 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		}
 		var use4844 bool
 		config := b.config()
-		if config.Post4844Blobs && b.daWriter == nil && latestHeader.ExcessBlobGas != nil && latestHeader.BlobGasUsed != nil {
+		if config.Post4844Blobs && b.daWriter == nil && b.dapWriter == nil && latestHeader.ExcessBlobGas != nil && latestHeader.BlobGasUsed != nil {
 			arbOSVersion, err := b.arbOSVersionGetter.ArbOSVersionForMessageNumber(arbutil.MessageIndex(arbmath.SaturatingUSub(uint64(batchPosition.MessageCount), 1)))
 			if err != nil {
 				return false, err
 			}
 			if arbOSVersion >= 20 {
-				if config.IgnoreBlobPrice {
-					use4844 = true
-				} else {
-					backlog := atomic.LoadUint64(&b.backlog)
-					// Logic to prevent switching from non-4844 batches to 4844 batches too often,
-					// so that blocks can be filled efficiently. The geth txpool rejects txs for
-					// accounts that already have the other type of txs in the pool with
-					// "address already reserved". This logic makes sure that, if there is a backlog,
-					// that enough non-4844 batches have been posted to fill a block before switching.
-					if backlog == 0 ||
-						b.non4844BatchCount == 0 ||
-						b.non4844BatchCount > 16 {
-						blobFeePerByte := eip4844.CalcBlobFee(eip4844.CalcExcessBlobGas(*latestHeader.ExcessBlobGas, *latestHeader.BlobGasUsed))
-						blobFeePerByte.Mul(blobFeePerByte, blobTxBlobGasPerBlob)
-						blobFeePerByte.Div(blobFeePerByte, usableBytesInBlob)
-
-						calldataFeePerByte := arbmath.BigMulByUint(latestHeader.BaseFee, 16)
-						use4844 = arbmath.BigLessThan(blobFeePerByte, calldataFeePerByte)
-					}
+				blobFee := eip4844.CalcBlobFee(eip4844.CalcExcessBlobGas(*latestHeader.ExcessBlobGas, *latestHeader.BlobGasUsed))
+				blobFeePerByte := arbmath.BigMul(blobFee, blobTxBlobGasPerBlob)
+				blobFeePerByte.Div(blobFeePerByte, usableBytesInBlob)
+				calldataFeePerByte := arbmath.BigMulByUint(latestHeader.BaseFee, 16)
+				batchPosterBlobFeePerByte.Update(saturatingGaugeValue(blobFeePerByte))
+				batchPosterCalldataFeePerByte.Update(saturatingGaugeValue(calldataFeePerByte))
+
+				var fellBack bool
+				use4844, fellBack = chooseBlobPosting(config, blobFee, blobFeePerByte, calldataFeePerByte, atomic.LoadUint64(&b.backlog), b.non4844BatchCount)
+				if fellBack {
+					batchPosterBlobFallback.Inc(1)
 				}
 			}
 		}
 
+		useDAS := (b.daWriter != nil || b.dapWriter != nil) && !time.Now().Before(b.dasFallbackUntil)
+		if useDAS && b.daWriter == nil {
+			// Celestia batches are only read once the chain has upgraded, until then they're posted on chain
+			arbOSVersion, err := b.arbOSVersionGetter.ArbOSVersionForMessageNumber(arbutil.MessageIndex(arbmath.SaturatingUSub(uint64(batchPosition.MessageCount), 1)))
+			if err != nil {
+				return false, err
+			}
+			useDAS = arbOSVersion >= arbostypes.ArbosVersion_Celestia
+		}
 		b.building = &buildingBatch{
-			segments:      newBatchSegments(batchPosition.DelayedMessageCount, b.config(), b.GetBacklogEstimate(), use4844),
+			segments:      newBatchSegments(batchPosition.DelayedMessageCount, b.config(), b.GetBacklogEstimate(), use4844, useDAS),
 			msgCount:      batchPosition.MessageCount,
 			startMsgCount: batchPosition.MessageCount,
 			use4844:       use4844,
+			useDAS:        useDAS,
 		}
 	}
 	msgCount, err := b.streamer.GetMessageCount()
@@ -1120,6 +1498,14 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 			)
 			break
 		}
+		if config.MaxMessagesPerBatch > 0 && uint64(b.building.msgCount-b.building.startMsgCount) >= config.MaxMessagesPerBatch {
+			// this batch has as many messages as it's allowed
+			if !config.WaitForMaxDelay {
+				forcePostBatch = true
+			}
+			b.building.haveUsefulMessage = true
+			break
+		}
 		success, err := b.building.segments.AddMessage(msg)
 		if err != nil {
 			// Clear our cache
@@ -1155,7 +1541,8 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		return false, nil
 	}
 
-	if b.daWriter != nil {
+	postedOnChainAsFallback := (b.daWriter != nil || b.dapWriter != nil) && !b.building.useDAS
+	if b.building.useDAS {
 		if !b.redisLock.AttemptLock(ctx) {
 			return false, errAttemptLockFailed
 		}
@@ -1168,16 +1555,42 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 			return false, fmt.Errorf("%w: nonce changed from %d to %d while creating batch", storage.ErrStorageRace, nonce, gotNonce)
 		}
 
-		cert, err := b.daWriter.Store(ctx, sequencerMsg, uint64(time.Now().Add(config.DASRetentionPeriod).Unix()), []byte{}) // b.daWriter will append signature if enabled
+		var cert *arbstate.DataAvailabilityCertificate
+		var dapMsg []byte
+		if b.dapWriter != nil {
+			dapMsg, err = b.dapWriter.Store(ctx, sequencerMsg)
+		} else {
+			cert, err = b.daWriter.Store(ctx, sequencerMsg, uint64(time.Now().Add(config.DASRetentionPeriod).Unix()), []byte{}) // b.daWriter will append signature if enabled
+		}
 		if errors.Is(err, das.BatchToDasFailed) {
+			batchPosterDASFailures.Inc(1)
 			if config.DisableDasFallbackStoreDataOnChain {
 				return false, errors.New("unable to batch to DAS and fallback storing data on chain is disabled")
 			}
-			log.Warn("Falling back to storing data on chain", "err", err)
+			b.dasFallbackUntil = time.Now().Add(config.DasFallbackRetryInterval)
+			batchPosterDASFallbackActive.Update(1)
+			log.Error("Unable to batch to DAS, falling back to storing data on chain", "err", err, "batch", batchPosition.NextSeqNum, "retryDASAfter", config.DasFallbackRetryInterval)
+			if len(sequencerMsg) > config.MaxSize {
+				// The batch will be rebuilt without the DAS size limit
+				return false, fmt.Errorf("the %v byte DAS batch is too large to store on chain (max-size %v), rebuilding it: %w", len(sequencerMsg), config.MaxSize, err)
+			}
+			postedOnChainAsFallback = true
 		} else if err != nil {
 			return false, err
 		} else {
-			sequencerMsg = das.Serialize(cert)
+			if cert != nil {
+				sequencerMsg = das.Serialize(cert)
+				if b.dasRenewer != nil {
+					b.dasRenewer.Track(batchPosition.NextSeqNum, cert)
+				}
+			} else {
+				sequencerMsg = dapMsg
+			}
+			if b.dasFallbackBatches > 0 {
+				log.Info("Batched to DAS again, no longer storing data on chain", "batchesStoredOnChain", b.dasFallbackBatches)
+			}
+			b.dasFallbackBatches = 0
+			batchPosterDASFallbackActive.Update(0)
 		}
 	}
 
@@ -1198,6 +1611,11 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 	// posts a new delayed message that we didn't see while gas estimating.
 	gasLimit, err := b.estimateGas(ctx, sequencerMsg, lastPotentialMsg.DelayedMessagesRead, data, kzgBlobs, nonce, accessList)
 	if err != nil {
+		var revertErr *BatchRevertError
+		if errors.As(err, &revertErr) && revertErr.StaleDelayedCount() {
+			// The batch is rebuilt from scratch after an error, picking up the inbox's current delayed message count
+			log.Warn("not posting batch built against a stale delayed message count", "reason", revertErr.Reason, "delayedMessages", b.building.segments.delayedMsg)
+		}
 		return false, err
 	}
 	newMeta, err := rlp.EncodeToBytes(batchPosterPosition{
@@ -1233,6 +1651,20 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		"numBlobs", len(kzgBlobs),
 	)
 
+	latencytracker.MessagesPosted(b.building.msgCount)
+	b.lastBatchPosted = time.Now()
+	b.nextNonce.Store(nonce + 1)
+	batchPosterPostedBatches.Inc(1)
+	batchPosterPostedMessages.Inc(int64(b.building.msgCount - batchPosition.MessageCount))
+	batchPosterPostedBytes.Inc(int64(len(sequencerMsg)))
+	batchPosterPostedBlobs.Inc(int64(len(kzgBlobs)))
+	if postedOnChainAsFallback {
+		b.dasFallbackBatches++
+		batchPosterDASFallbackBatches.Inc(1)
+		batchPosterDASFallbackBytes.Inc(int64(len(sequencerMsg)))
+		log.Warn("Stored batch data on chain instead of in the DAS", "batch", batchPosition.NextSeqNum, "size", len(sequencerMsg), "batchesStoredOnChain", b.dasFallbackBatches)
+	}
+
 	recentlyHitL1Bounds := time.Since(b.lastHitL1Bounds) < config.PollInterval*3
 	postedMessages := b.building.msgCount - batchPosition.MessageCount
 	b.messagesPerBatch.Update(uint64(postedMessages))
@@ -1255,6 +1687,8 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		messagesPerBatch = 1
 	}
 	backlog := uint64(unpostedMessages) / messagesPerBatch
+	batchPosterBacklogMessages.Update(int64(unpostedMessages))
+	batchPosterBacklogBatches.Update(int64(backlog))
 	if backlog > 10 {
 		logLevel := log.Warn
 		if recentlyHitL1Bounds {
@@ -1293,6 +1727,326 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 	return true, nil
 }
 
+// updateNonceLag records how many of the batch poster's transactions have been sent but not yet included on the parent chain
+func (b *BatchPoster) updateNonceLag(ctx context.Context) {
+	sender := b.dataPoster.Sender()
+	latestNonce, err := b.l1Reader.Client().NonceAt(ctx, sender, nil)
+	if err != nil {
+		log.Warn("error fetching batch poster nonce", "err", err)
+		return
+	}
+	pendingNonce, err := b.l1Reader.Client().PendingNonceAt(ctx, sender)
+	if err != nil {
+		log.Warn("error fetching batch poster pending nonce", "err", err)
+		return
+	}
+	// Transactions the data poster is holding back aren't in the parent chain's mempool yet
+	nextNonce := arbmath.MaxInt(pendingNonce, b.nextNonce.Load())
+	batchPosterNonceLag.Update(int64(arbmath.SaturatingUSub(nextNonce, latestNonce)))
+}
+
+// feeCeilingBackoff returns how long to wait before trying again if the parent chain's fees are above
+// the configured maximums, or zero if batches can be posted now.
+// The wait doubles each time fees are still too high, up to the configured maximum backoff.
+func (b *BatchPoster) feeCeilingBackoff(ctx context.Context) time.Duration {
+	config := b.config()
+	if config.MaxL1BaseFeeGwei <= 0 && config.MaxL1TipCapGwei <= 0 {
+		b.feeBackoff = 0
+		return 0
+	}
+	var reasons []interface{}
+	if config.MaxL1BaseFeeGwei > 0 {
+		latestHeader, err := b.l1Reader.LastHeader(ctx)
+		if err != nil {
+			log.Warn("error getting parent chain header to check base fee", "err", err)
+		} else if latestHeader.BaseFee != nil && arbmath.BigGreaterThan(latestHeader.BaseFee, arbmath.FloatToBig(config.MaxL1BaseFeeGwei*params.GWei)) {
+			reasons = append(reasons, "baseFee", latestHeader.BaseFee, "maxL1BaseFeeGwei", config.MaxL1BaseFeeGwei)
+		}
+	}
+	if config.MaxL1TipCapGwei > 0 {
+		tipCap, err := b.l1Reader.Client().SuggestGasTipCap(ctx)
+		if err != nil {
+			log.Warn("error getting parent chain suggested tip cap", "err", err)
+		} else if arbmath.BigGreaterThan(tipCap, arbmath.FloatToBig(config.MaxL1TipCapGwei*params.GWei)) {
+			reasons = append(reasons, "tipCap", tipCap, "maxL1TipCapGwei", config.MaxL1TipCapGwei)
+		}
+	}
+	if len(reasons) == 0 {
+		if b.feeBackoff > 0 {
+			log.Info("parent chain fees are back below the configured maximums, resuming batch posting")
+		}
+		b.resetFeeBackoff()
+		return 0
+	}
+
+	oldest, unposted, err := b.unpostedMessages(ctx)
+	if err != nil {
+		log.Warn("error getting batch poster position", "err", err)
+	} else if unposted == 0 {
+		// there's nothing waiting to be posted, so there's nothing to hold back
+		b.resetFeeBackoff()
+		return 0
+	} else if b.feeCeilingBypassed(ctx, oldest) {
+		logArgs := append(reasons, "unpostedMessages", unposted)
+		log.Warn("posting batches despite parent chain fees being above the configured maximums, as messages are nearing the sequencer inbox's max delay", logArgs...)
+		b.resetFeeBackoff()
+		return 0
+	}
+	if b.feeBackoff == 0 {
+		b.feeBackoff = config.PollInterval
+	} else {
+		b.feeBackoff *= 2
+	}
+	if config.MaxFeeBackoff > 0 && b.feeBackoff > config.MaxFeeBackoff {
+		b.feeBackoff = config.MaxFeeBackoff
+	}
+	batchPosterFeeCeilingWaits.Inc(1)
+	batchPosterFeeCeilingBackoff.Update(b.feeBackoff.Milliseconds())
+	batchPosterFeeCeilingUnposted.Update(int64(unposted))
+	logArgs := append(reasons, "unpostedMessages", unposted, "backoff", b.feeBackoff)
+	log.Warn("parent chain fees are above the configured maximums, waiting to post batches", logArgs...)
+	return b.feeBackoff
+}
+
+func (b *BatchPoster) resetFeeBackoff() {
+	b.feeBackoff = 0
+	batchPosterFeeCeilingBackoff.Update(0)
+	batchPosterFeeCeilingUnposted.Update(0)
+}
+
+// unpostedMessages returns the first message not yet posted in a batch, and how many messages are waiting to be posted
+func (b *BatchPoster) unpostedMessages(ctx context.Context) (arbutil.MessageIndex, arbutil.MessageIndex, error) {
+	_, batchPositionBytes, err := b.dataPoster.GetNextNonceAndMeta(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	var batchPosition batchPosterPosition
+	if err := rlp.DecodeBytes(batchPositionBytes, &batchPosition); err != nil {
+		return 0, 0, fmt.Errorf("decoding batch position: %w", err)
+	}
+	msgCount, err := b.streamer.GetMessageCount()
+	if err != nil {
+		return 0, 0, err
+	}
+	if msgCount <= batchPosition.MessageCount {
+		return batchPosition.MessageCount, 0, nil
+	}
+	return batchPosition.MessageCount, msgCount - batchPosition.MessageCount, nil
+}
+
+// feeCeilingBypassed returns whether the oldest unposted message is so close to the sequencer inbox's max delay
+// that it must be posted regardless of fees, as past it delayed messages could be force included ahead of it
+// and the messages the sequencer has queued would no longer be postable
+func (b *BatchPoster) feeCeilingBypassed(ctx context.Context, oldest arbutil.MessageIndex) bool {
+	msg, err := b.streamer.GetMessage(oldest)
+	if err != nil {
+		log.Warn("error getting oldest unposted message, posting regardless of fees", "err", err)
+		return true
+	}
+	_, _, delaySeconds, _, err := b.seqInbox.MaxTimeVariation(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		log.Warn("error getting max time variation, posting regardless of fees", "err", err)
+		return true
+	}
+	return nearMaxDelay(msg.Message.Header.Timestamp, arbmath.BigToUintSaturating(delaySeconds), b.config().FeeCeilingBypass, time.Now())
+}
+
+// nearMaxDelay returns whether a message with the timestamp is within the margin of the max delay
+func nearMaxDelay(timestamp uint64, maxDelaySeconds uint64, margin time.Duration, now time.Time) bool {
+	deadline := arbmath.SaturatingUAdd(timestamp, maxDelaySeconds)
+	return arbmath.SaturatingUAdd(uint64(now.Unix()), uint64(margin/time.Second)) >= deadline
+}
+
+// lead returns whether this batch poster should post batches when exclusive posting is enabled.
+// It must hold the redis lock, and after taking the lock from another batch poster, it waits for
+// the batches that one posted to leave the parent chain's mempool so that it doesn't post conflicting ones.
+func (b *BatchPoster) lead(ctx context.Context) bool {
+	if !b.redisLock.AttemptLock(ctx) {
+		if b.leading {
+			log.Warn("batch poster lost the lock, standing by", "sender", b.dataPoster.Sender())
+			b.leading = false
+			batchPosterLeader.Update(0)
+			batchPosterLeaderChanges.Inc(1)
+		}
+		return false
+	}
+	if !b.leading {
+		log.Info("batch poster acquired the lock, taking over posting", "sender", b.dataPoster.Sender())
+		b.leading = true
+		b.leaderSince = time.Now()
+		b.handoverDone = false
+		batchPosterLeader.Update(1)
+		batchPosterLeaderChanges.Inc(1)
+	}
+	if b.handoverDone {
+		return true
+	}
+	latestCount, err := b.seqInbox.BatchCount(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		log.Warn("error getting latest batch count during handover", "err", err)
+		return false
+	}
+	pendingCount, err := b.seqInbox.BatchCount(&bind.CallOpts{Context: ctx, Pending: true})
+	if err != nil {
+		log.Warn("error getting pending batch count during handover", "err", err)
+		return false
+	}
+	if latestCount.Cmp(pendingCount) == 0 {
+		log.Info("batch poster took over posting", "batchCount", latestCount, "sender", b.dataPoster.Sender())
+		b.handoverDone = true
+		return true
+	}
+	if time.Since(b.leaderSince) >= b.config().HandoverTimeout {
+		log.Warn("timed out waiting for previous batch poster's batches to be included, posting anyways", "latestBatchCount", latestCount, "pendingBatchCount", pendingCount)
+		b.handoverDone = true
+		return true
+	}
+	log.Info("waiting for previous batch poster's batches to be included before posting", "latestBatchCount", latestCount, "pendingBatchCount", pendingCount)
+	return false
+}
+
+// recoverAfterRestart reconciles the batch poster's queue with the sequencer inbox before the first batch is posted,
+// and returns whether posting can begin. If the node stopped after posting batches but before they were confirmed,
+// a persistent queue still holds them and the data poster re-sends them, so posting continues after them. Otherwise
+// the batches are rebuilt from the messages after the sequencer inbox's position, but only once any batches still
+// in the parent chain's mempool from before the restart are included, so that the rebuilt ones don't conflict.
+func (b *BatchPoster) recoverAfterRestart(ctx context.Context) bool {
+	if b.recoveryStart.IsZero() {
+		b.recoveryStart = time.Now()
+	}
+	onChainPositionBytes, err := b.getBatchPosterPosition(ctx, nil)
+	if err != nil {
+		log.Warn("error getting sequencer inbox position to recover batch poster", "err", err)
+		return false
+	}
+	var onChainPosition batchPosterPosition
+	if err := rlp.DecodeBytes(onChainPositionBytes, &onChainPosition); err != nil {
+		log.Warn("error decoding sequencer inbox position to recover batch poster", "err", err)
+		return false
+	}
+	_, queuedPositionBytes, err := b.dataPoster.GetNextNonceAndMeta(ctx)
+	if err != nil {
+		log.Warn("error getting queued batch position to recover batch poster", "err", err)
+		return false
+	}
+	var queuedPosition batchPosterPosition
+	if err := rlp.DecodeBytes(queuedPositionBytes, &queuedPosition); err != nil {
+		log.Warn("error decoding queued batch position to recover batch poster", "err", err)
+		return false
+	}
+	pending, err := b.dataPoster.PendingTransactions(ctx)
+	if err != nil {
+		log.Warn("error getting pending transactions to recover batch poster", "err", err)
+		return false
+	}
+	msgCount, err := b.streamer.GetMessageCount()
+	if err != nil {
+		log.Warn("error getting message count to recover batch poster", "err", err)
+		return false
+	}
+	missing := arbmath.SaturatingUSub(uint64(msgCount), uint64(onChainPosition.MessageCount))
+	batchPosterRecoveryMessages.Update(int64(missing))
+
+	if queuedPosition.NextSeqNum > onChainPosition.NextSeqNum {
+		log.Info(
+			"batch poster resuming with queued batches not yet in the sequencer inbox",
+			"sequencerInboxBatches", onChainPosition.NextSeqNum,
+			"queuedBatches", queuedPosition.NextSeqNum-onChainPosition.NextSeqNum,
+			"pendingTransactions", pending,
+			"messagesAfterQueued", arbmath.SaturatingUSub(uint64(msgCount), uint64(queuedPosition.MessageCount)),
+		)
+		b.recovered = true
+		return true
+	}
+	if pending > 0 && queuedPosition.NextSeqNum < onChainPosition.NextSeqNum {
+		log.Error(
+			"batch poster's queue is behind the sequencer inbox, another batch poster may be using the same key",
+			"sequencerInboxBatches", onChainPosition.NextSeqNum,
+			"queuedBatches", queuedPosition.NextSeqNum,
+		)
+	}
+	pendingCount, err := b.seqInbox.BatchCount(&bind.CallOpts{Context: ctx, Pending: true})
+	if err != nil {
+		log.Warn("error getting pending batch count to recover batch poster", "err", err)
+		return false
+	}
+	if pendingCount.Uint64() > onChainPosition.NextSeqNum {
+		if time.Since(b.recoveryStart) < b.config().HandoverTimeout {
+			log.Info("waiting for batches posted before restarting to be included before rebuilding", "batchCount", onChainPosition.NextSeqNum, "pendingBatchCount", pendingCount)
+			return false
+		}
+		log.Warn("timed out waiting for batches posted before restarting to be included, rebuilding anyways", "batchCount", onChainPosition.NextSeqNum, "pendingBatchCount", pendingCount)
+	}
+	if missing > 0 {
+		log.Info(
+			"batch poster rebuilding batches for messages missing from the sequencer inbox",
+			"sequencerInboxBatches", onChainPosition.NextSeqNum,
+			"fromMessage", onChainPosition.MessageCount,
+			"toMessage", msgCount,
+		)
+	}
+	b.recovered = true
+	return true
+}
+
+// Drain stops the batch poster from posting new batches, while it waits for the ones it's posted to be confirmed.
+// Once none remain, it releases the redis lock for another batch poster to take over, see key_rotation.go.
+// Draining is only held in memory, so a batch poster that's restarted posts new batches again until it's drained again.
+func (b *BatchPoster) Drain() {
+	if !b.draining.Swap(true) {
+		log.Info("draining batch poster, no new batches will be posted", "sender", b.dataPoster.Sender())
+	}
+}
+
+// Resume undoes Drain
+func (b *BatchPoster) Resume() {
+	if b.draining.Swap(false) {
+		b.drained.Store(false)
+		log.Info("resuming batch posting", "sender", b.dataPoster.Sender())
+	}
+}
+
+// checkDrained keeps hold of the redis lock while the batch poster still has transactions in flight,
+// so that another batch poster doesn't post conflicting batches, then releases it.
+func (b *BatchPoster) checkDrained(ctx context.Context) {
+	if b.drained.Load() {
+		return
+	}
+	pending, err := b.dataPoster.PendingTransactions(ctx)
+	if err != nil {
+		log.Warn("error checking batch poster's pending transactions", "err", err)
+		return
+	}
+	if pending > 0 {
+		b.redisLock.AttemptLock(ctx)
+		log.Info("waiting for batch poster transactions to be confirmed before releasing lock", "pending", pending)
+		return
+	}
+	b.drained.Store(true)
+	b.redisLock.Release(ctx)
+	log.Info("batch poster drained", "sender", b.dataPoster.Sender())
+}
+
+type BatchPosterDrainStatus struct {
+	Sender              common.Address `json:"sender"`
+	Draining            bool           `json:"draining"`
+	Drained             bool           `json:"drained"`
+	PendingTransactions int            `json:"pendingTransactions"`
+}
+
+func (b *BatchPoster) DrainStatus(ctx context.Context) (*BatchPosterDrainStatus, error) {
+	pending, err := b.dataPoster.PendingTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &BatchPosterDrainStatus{
+		Sender:              b.dataPoster.Sender(),
+		Draining:            b.draining.Load(),
+		Drained:             b.drained.Load(),
+		PendingTransactions: pending,
+	}, nil
+}
+
 func (b *BatchPoster) GetBacklogEstimate() uint64 {
 	return atomic.LoadUint64(&b.backlog)
 }
@@ -1300,6 +2054,9 @@ func (b *BatchPoster) GetBacklogEstimate() uint64 {
 func (b *BatchPoster) Start(ctxIn context.Context) {
 	b.dataPoster.Start(ctxIn)
 	b.redisLock.Start(ctxIn)
+	if b.dasRenewer != nil {
+		b.dasRenewer.Start(ctxIn)
+	}
 	b.StopWaiter.Start(ctxIn, b)
 	b.LaunchThread(b.pollForReverts)
 	commonEphemeralErrorHandler := util.NewEphemeralErrorHandler(time.Minute, "", 0)
@@ -1331,6 +2088,16 @@ func (b *BatchPoster) Start(ctxIn context.Context) {
 			} else {
 				batchPosterWalletBalance.Update(arbmath.BalancePerEther(walletBalance))
 			}
+			b.updateNonceLag(ctx)
+		}
+		if !b.lastBatchPosted.IsZero() {
+			batchPosterLastBatchAge.Update(int64(time.Since(b.lastBatchPosted).Seconds()))
+		}
+		if b.draining.Load() {
+			b.building = nil
+			resetAllEphemeralErrs()
+			b.checkDrained(ctx)
+			return b.config().PollInterval
 		}
 		couldLock, err := b.redisLock.CouldAcquireLock(ctx)
 		if err != nil {
@@ -1344,6 +2111,17 @@ func (b *BatchPoster) Start(ctxIn context.Context) {
 			resetAllEphemeralErrs()
 			return b.config().PollInterval
 		}
+		if backoff := b.feeCeilingBackoff(ctx); backoff > 0 {
+			return backoff
+		}
+		if b.config().ExclusivePosting && !b.lead(ctx) {
+			b.building = nil
+			resetAllEphemeralErrs()
+			return b.config().PollInterval
+		}
+		if !b.recovered && !b.recoverAfterRestart(ctx) {
+			return b.config().PollInterval
+		}
 		posted, err := b.maybePostSequencerBatch(ctx)
 		if err == nil {
 			resetAllEphemeralErrs()
@@ -1379,6 +2157,9 @@ func (b *BatchPoster) StopAndWait() {
 	b.StopWaiter.StopAndWait()
 	b.dataPoster.StopAndWait()
 	b.redisLock.StopAndWait()
+	if b.dasRenewer != nil && b.dasRenewer.Started() {
+		b.dasRenewer.StopAndWait()
+	}
 }
 
 type BoolRing struct {
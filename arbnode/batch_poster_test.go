@@ -0,0 +1,260 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+
+	redisstorage "github.com/offchainlabs/nitro/arbnode/dataposter/redis"
+	"github.com/offchainlabs/nitro/arbnode/dataposter/storage"
+	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
+	"github.com/offchainlabs/nitro/util/redisutil"
+)
+
+func TestAdaptiveCompressionLevels(t *testing.T) {
+	config := DefaultBatchPosterConfig
+	config.CompressionLevel = brotli.BestCompression
+	config.AdaptiveCompressionBacklog = 10
+
+	for _, test := range []struct {
+		backlog       uint64
+		compression   int
+		recompression int
+	}{
+		{0, brotli.BestCompression, brotli.BestCompression},
+		{10, brotli.BestCompression, brotli.BestCompression},
+		{11, brotli.DefaultCompression, brotli.BestCompression},
+		{21, brotli.DefaultCompression, brotli.DefaultCompression},
+		{31, 4, brotli.DefaultCompression},
+	} {
+		compression, recompression := compressionLevels(&config, test.backlog)
+		if compression != test.compression || recompression != test.recompression {
+			t.Errorf("backlog %v: got levels %v/%v but expected %v/%v", test.backlog, compression, recompression, test.compression, test.recompression)
+		}
+	}
+
+	config.AdaptiveCompression = false
+	compression, recompression := compressionLevels(&config, 100)
+	if compression != brotli.BestCompression || recompression != brotli.BestCompression {
+		t.Errorf("adaptive compression disabled but got levels %v/%v", compression, recompression)
+	}
+}
+
+func TestReceiptCostGwei(t *testing.T) {
+	receipt := &types.Receipt{
+		GasUsed:           100_000,
+		EffectiveGasPrice: big.NewInt(30 * params.GWei),
+	}
+	if cost := receiptCostGwei(receipt); cost != 3_000_000 {
+		t.Errorf("calldata batch cost %v gwei but expected 3000000", cost)
+	}
+	receipt.BlobGasUsed = params.BlobTxBlobGasPerBlob
+	receipt.BlobGasPrice = big.NewInt(params.GWei)
+	if cost := receiptCostGwei(receipt); cost != 3_000_000+params.BlobTxBlobGasPerBlob {
+		t.Errorf("blob batch cost %v gwei but expected %v", cost, 3_000_000+params.BlobTxBlobGasPerBlob)
+	}
+	if cost := receiptCostGwei(&types.Receipt{GasUsed: 21000}); cost != 0 {
+		t.Errorf("receipt without a gas price cost %v gwei", cost)
+	}
+	huge := &types.Receipt{GasUsed: math.MaxUint64, EffectiveGasPrice: new(big.Int).Lsh(big.NewInt(1), 100)}
+	if cost := receiptCostGwei(huge); cost != math.MaxInt64 {
+		t.Errorf("receipt with a huge cost saturated to %v gwei", cost)
+	}
+}
+
+func TestChooseBlobPosting(t *testing.T) {
+	config := DefaultBatchPosterConfig
+	gwei := big.NewInt(params.GWei)
+	cheap, dear := big.NewInt(10), big.NewInt(1000)
+
+	if use4844, fellBack := chooseBlobPosting(&config, gwei, cheap, dear, 0, 0); !use4844 || fellBack {
+		t.Error("didn't post cheaper blobs")
+	}
+	if use4844, fellBack := chooseBlobPosting(&config, gwei, dear, cheap, 0, 0); use4844 || !fellBack {
+		t.Error("posted blobs dearer than calldata")
+	}
+	// with a backlog, keep filling blocks with calldata batches rather than switching back right away
+	if use4844, fellBack := chooseBlobPosting(&config, gwei, cheap, dear, 10, 5); use4844 || fellBack {
+		t.Error("switched to blobs mid-backlog")
+	}
+
+	config.IgnoreBlobPrice = true
+	if use4844, _ := chooseBlobPosting(&config, gwei, dear, cheap, 0, 0); !use4844 {
+		t.Error("ignore-blob-price didn't post blobs")
+	}
+	config.MaxBlobFeeGwei = 2
+	if use4844, _ := chooseBlobPosting(&config, gwei, dear, cheap, 0, 0); !use4844 {
+		t.Error("fell back to calldata below the maximum blob fee")
+	}
+	spiked := big.NewInt(3 * params.GWei)
+	if use4844, fellBack := chooseBlobPosting(&config, spiked, cheap, dear, 0, 0); use4844 || !fellBack {
+		t.Error("posted blobs above the maximum blob fee despite ignore-blob-price")
+	}
+	config.IgnoreBlobPrice = false
+	if use4844, fellBack := chooseBlobPosting(&config, spiked, cheap, dear, 0, 0); use4844 || !fellBack {
+		t.Error("posted blobs above the maximum blob fee")
+	}
+}
+
+func TestSaturatingGaugeValue(t *testing.T) {
+	if value := saturatingGaugeValue(big.NewInt(42)); value != 42 {
+		t.Errorf("gauge value %v but expected 42", value)
+	}
+	huge := new(big.Int).Lsh(big.NewInt(1), 100)
+	if value := saturatingGaugeValue(huge); value != math.MaxInt64 {
+		t.Errorf("huge fee gave gauge value %v", value)
+	}
+	if value := saturatingGaugeValue(big.NewInt(-1)); value != 0 {
+		t.Errorf("negative fee gave gauge value %v", value)
+	}
+}
+
+func TestNearMaxDelay(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	day := uint64(60 * 60 * 24)
+	margin := time.Hour * 4
+	if nearMaxDelay(uint64(now.Unix())-3600, day, margin, now) {
+		t.Error("an hour old message treated as near a day's max delay")
+	}
+	if !nearMaxDelay(uint64(now.Unix())-day+3*3600, day, margin, now) {
+		t.Error("message three hours from the max delay not treated as near it")
+	}
+	if !nearMaxDelay(uint64(now.Unix())-2*day, day, margin, now) {
+		t.Error("message past the max delay not treated as near it")
+	}
+	if nearMaxDelay(uint64(now.Unix()), math.MaxUint64, margin, now) {
+		t.Error("overflowing max delay treated as near")
+	}
+}
+
+type revertDataError struct {
+	data string
+}
+
+func (e revertDataError) Error() string          { return "execution reverted" }
+func (e revertDataError) ErrorData() interface{} { return e.data }
+
+func TestBatchRevertReason(t *testing.T) {
+	seqInboxABI, err := bridgegen.SequencerInboxMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &BatchPoster{seqInboxABI: seqInboxABI}
+
+	delayedTooFar := seqInboxABI.Errors["DelayedTooFar"].ID[:4]
+	err = fmt.Errorf("estimating gas: %w", revertDataError{hexutil.Encode(delayedTooFar)})
+	reason, _, ok := b.decodeSeqInboxRevert(revertData(err))
+	if !ok || reason != "DelayedTooFar" {
+		t.Errorf("decoded revert reason %q (ok %v) but expected DelayedTooFar", reason, ok)
+	}
+
+	badSeqNum := seqInboxABI.Errors["BadSequencerNumber"]
+	args, err := badSeqNum.Inputs.Pack(big.NewInt(5), big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reason, decodedArgs, ok := b.decodeSeqInboxRevert(append(badSeqNum.ID[:4:4], args...))
+	if !ok || reason != "BadSequencerNumber" || len(decodedArgs) != 2 || decodedArgs[1].(*big.Int).Int64() != 7 {
+		t.Errorf("decoded revert %q %v (ok %v) but expected BadSequencerNumber [5 7]", reason, decodedArgs, ok)
+	}
+
+	if _, _, ok := b.decodeSeqInboxRevert([]byte{1, 2, 3, 4}); ok {
+		t.Error("decoded unknown revert data")
+	}
+	if revertData(errors.New("execution reverted")) != nil {
+		t.Error("got revert data from an error without any")
+	}
+
+	revertErr := &BatchRevertError{Reason: "DelayedBackwards", err: errors.New("execution reverted")}
+	if !revertErr.StaleDelayedCount() {
+		t.Error("DelayedBackwards revert isn't reported as a stale delayed message count")
+	}
+}
+
+func TestMigrateDataPosterQueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	redisClient, err := redisutil.RedisClientFromURL(redisutil.CreateTestRedis(ctx, t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chainID := big.NewInt(1337)
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	tx := types.MustSignNewTx(key, types.LatestSignerForChainID(chainID), &types.DynamicFeeTx{ChainID: chainID, Nonce: 1})
+	queue, err := redisstorage.NewStorage(redisClient, sharedDataPosterRedisKey, &TestBatchPosterConfig.DataPoster.RedisSigner, func() storage.EncoderDecoderInterface { return &storage.EncoderDecoder{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Put(ctx, 1, nil, &storage.QueuedTransaction{FullTx: tx}); err != nil {
+		t.Fatal(err)
+	}
+
+	migrate := func(from common.Address, exclusivePosting bool) {
+		t.Helper()
+		config := TestBatchPosterConfig
+		config.ExclusivePosting = exclusivePosting
+		opts := &BatchPosterOpts{
+			Config:        func() *BatchPosterConfig { return &config },
+			TransactOpts:  &bind.TransactOpts{From: from},
+			ParentChainID: chainID,
+		}
+		if err := migrateDataPosterQueue(ctx, opts, redisClient); err != nil {
+			t.Fatal(err)
+		}
+	}
+	requireQueue := func(key string, expected bool) {
+		t.Helper()
+		exists, err := redisClient.Exists(ctx, key).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (exists != 0) != expected {
+			t.Fatalf("queue %v exists: %v, expected %v", key, exists != 0, expected)
+		}
+	}
+
+	// turning on exclusive posting, a batch poster with another key leaves the shared queue alone
+	other := common.HexToAddress("0x0123")
+	migrate(other, true)
+	requireQueue(sharedDataPosterRedisKey, true)
+	requireQueue(senderDataPosterRedisKey(other), false)
+
+	// while the batch poster whose key queued the transactions takes it over
+	migrate(sender, true)
+	requireQueue(sharedDataPosterRedisKey, false)
+	requireQueue(senderDataPosterRedisKey(sender), true)
+	migrate(sender, true)
+	requireQueue(senderDataPosterRedisKey(sender), true)
+
+	// and moves it back when exclusive posting is turned off again
+	migrate(sender, false)
+	requireQueue(sharedDataPosterRedisKey, true)
+	requireQueue(senderDataPosterRedisKey(sender), false)
+	queued, err := queue.FetchLast(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queued == nil || queued.FullTx.Hash() != tx.Hash() {
+		t.Fatal("the queued transaction didn't survive the migrations")
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+// DAAuditor periodically re-fetches randomly chosen historical batches from wherever their data lives
+// (the parent chain's calldata, blobs, or a DAS) and checks that the data is still available and matches
+// what was committed to the sequencer inbox. It only reports on what it finds, and never affects the node's state.
+type DAAuditor struct {
+	stopwaiter.StopWaiter
+	config     DAAuditorConfigFetcher
+	reader     *InboxReader
+	tracker    *InboxTracker
+	das        arbstate.DataAvailabilityReader
+	blobReader arbstate.BlobReader
+
+	statsMutex sync.Mutex
+	stats      map[string]*DAAuditStats
+}
+
+type DAAuditorConfig struct {
+	Enable          bool          `koanf:"enable"`
+	Interval        time.Duration `koanf:"interval" reload:"hot"`
+	BatchesPerAudit uint64        `koanf:"batches-per-audit" reload:"hot"`
+	LookbackBatches uint64        `koanf:"lookback-batches" reload:"hot"`
+	Timeout         time.Duration `koanf:"timeout" reload:"hot"`
+}
+
+type DAAuditorConfigFetcher func() *DAAuditorConfig
+
+var DefaultDAAuditorConfig = DAAuditorConfig{
+	Enable:          false,
+	Interval:        time.Minute * 10,
+	BatchesPerAudit: 4,
+	LookbackBatches: 0,
+	Timeout:         time.Minute,
+}
+
+func DAAuditorConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultDAAuditorConfig.Enable, "enable periodically re-fetching historical batch data to check it's still available")
+	f.Duration(prefix+".interval", DefaultDAAuditorConfig.Interval, "how often to audit batch data availability")
+	f.Uint64(prefix+".batches-per-audit", DefaultDAAuditorConfig.BatchesPerAudit, "how many randomly chosen batches to re-fetch in each audit")
+	f.Uint64(prefix+".lookback-batches", DefaultDAAuditorConfig.LookbackBatches, "only audit batches among this many of the most recent (0 to audit all batches)")
+	f.Duration(prefix+".timeout", DefaultDAAuditorConfig.Timeout, "how long to wait when fetching a batch's data before considering it unavailable")
+}
+
+func (c *DAAuditorConfig) Validate() error {
+	if c.Enable && c.Interval <= 0 {
+		return errors.New("data availability audit interval must be positive")
+	}
+	if c.Enable && c.Timeout <= 0 {
+		return errors.New("data availability audit timeout must be positive")
+	}
+	return nil
+}
+
+// DAAuditStats counts the outcomes of auditing batches with data in one location
+type DAAuditStats struct {
+	Audited     uint64    `json:"audited"`
+	Available   uint64    `json:"available"`
+	Unavailable uint64    `json:"unavailable"`
+	Mismatched  uint64    `json:"mismatched"`
+	LastFailure uint64    `json:"lastFailure,omitempty"` // the sequence number of the last batch that failed its audit
+	LastAudit   time.Time `json:"lastAudit"`
+}
+
+const (
+	daSourceCalldata = "calldata"
+	daSourceBlobs    = "blobs"
+	daSourceDAS      = "das"
+)
+
+var errBatchAccumulatorMismatch = errors.New("batch data doesn't match the sequencer inbox accumulator")
+
+func NewDAAuditor(reader *InboxReader, tracker *InboxTracker, das arbstate.DataAvailabilityReader, blobReader arbstate.BlobReader, config DAAuditorConfigFetcher) *DAAuditor {
+	return &DAAuditor{
+		config:     config,
+		reader:     reader,
+		tracker:    tracker,
+		das:        das,
+		blobReader: blobReader,
+		stats:      make(map[string]*DAAuditStats),
+	}
+}
+
+func (a *DAAuditor) Start(ctxIn context.Context) {
+	a.StopWaiter.Start(ctxIn, a)
+	a.CallIteratively(a.audit)
+}
+
+// Stats returns the audit results so far for each data location
+func (a *DAAuditor) Stats() map[string]DAAuditStats {
+	a.statsMutex.Lock()
+	defer a.statsMutex.Unlock()
+	stats := make(map[string]DAAuditStats, len(a.stats))
+	for source, sourceStats := range a.stats {
+		stats[source] = *sourceStats
+	}
+	return stats
+}
+
+func (a *DAAuditor) audit(ctx context.Context) time.Duration {
+	config := a.config()
+	batchCount, err := a.tracker.GetBatchCount()
+	if err != nil {
+		log.Warn("data availability auditor failed to get batch count", "err", err)
+		return config.Interval
+	}
+	if batchCount <= 1 {
+		return config.Interval
+	}
+	// batch 0 is the chain's initialization message and has no data to audit
+	first := uint64(1)
+	if config.LookbackBatches > 0 && batchCount-first > config.LookbackBatches {
+		first = batchCount - config.LookbackBatches
+	}
+	for i := uint64(0); i < config.BatchesPerAudit && ctx.Err() == nil; i++ {
+		seqNum := first + uint64(rand.Int63n(int64(batchCount-first)))
+		auditCtx, cancel := context.WithTimeout(ctx, config.Timeout)
+		source, err := a.auditBatch(auditCtx, seqNum)
+		cancel()
+		if ctx.Err() != nil {
+			break
+		}
+		if source == "" {
+			log.Warn("data availability auditor failed to audit batch", "batch", seqNum, "err", err)
+			continue
+		}
+		a.record(source, seqNum, err)
+	}
+	return config.Interval
+}
+
+// auditBatch returns the location of the batch's data, or an empty string if the audit couldn't be performed
+func (a *DAAuditor) auditBatch(ctx context.Context, seqNum uint64) (string, error) {
+	data, blockHash, err := a.reader.GetSequencerMessageBytes(ctx, seqNum)
+	if err != nil {
+		return "", err
+	}
+	source := daSourceCalldata
+	if len(data) > 40 {
+		if arbstate.IsDASMessageHeaderByte(data[40]) {
+			source = daSourceDAS
+		} else if arbstate.IsBlobHashesHeaderByte(data[40]) {
+			source = daSourceBlobs
+		}
+	}
+
+	if err := a.checkAccumulator(seqNum, data); err != nil {
+		if errors.Is(err, errBatchAccumulatorMismatch) {
+			return source, err
+		}
+		// our own database couldn't tell us what to expect, so this says nothing about availability
+		return "", err
+	}
+
+	var provider arbstate.DataAvailabilityProvider
+	switch source {
+	case daSourceDAS:
+		if a.das == nil {
+			return "", errors.New("batch data is in a DAS but no DAS reader is configured")
+		}
+		provider = arbstate.NewDAProviderDAS(a.das)
+	case daSourceBlobs:
+		if a.blobReader == nil {
+			return "", arbstate.ErrNoBlobReader
+		}
+		provider = arbstate.NewDAProviderBlobReader(a.blobReader)
+	default:
+		// calldata was fully checked against the accumulator
+		return source, nil
+	}
+	// The providers verify what they fetch against the hashes committed to in the batch
+	payload, err := provider.RecoverPayloadFromBatch(ctx, seqNum, blockHash, data, nil, arbstate.KeysetDontValidate)
+	if err != nil {
+		return source, err
+	}
+	if payload == nil {
+		return source, fmt.Errorf("%w: batch data failed validation", errBatchAccumulatorMismatch)
+	}
+	return source, nil
+}
+
+// checkAccumulator recomputes the sequencer inbox accumulator from the fetched batch data
+func (a *DAAuditor) checkAccumulator(seqNum uint64, data []byte) error {
+	metadata, err := a.tracker.GetBatchMetadata(seqNum)
+	if err != nil {
+		return err
+	}
+	var beforeAcc common.Hash
+	if seqNum > 0 {
+		beforeAcc, err = a.tracker.GetBatchAcc(seqNum - 1)
+		if err != nil {
+			return err
+		}
+	}
+	var delayedAcc common.Hash
+	if metadata.DelayedMessageCount > 0 {
+		delayedAcc, err = a.tracker.GetDelayedAcc(metadata.DelayedMessageCount - 1)
+		if err != nil {
+			return err
+		}
+	}
+	acc := crypto.Keccak256Hash(beforeAcc[:], crypto.Keccak256(data), delayedAcc[:])
+	if acc != metadata.Accumulator {
+		return fmt.Errorf("%w: computed %v but have %v", errBatchAccumulatorMismatch, acc, metadata.Accumulator)
+	}
+	return nil
+}
+
+func (a *DAAuditor) record(source string, seqNum uint64, err error) {
+	a.statsMutex.Lock()
+	defer a.statsMutex.Unlock()
+	stats, ok := a.stats[source]
+	if !ok {
+		stats = &DAAuditStats{}
+		a.stats[source] = stats
+	}
+	stats.Audited++
+	stats.LastAudit = time.Now()
+	metrics.GetOrRegisterCounter("arb/daauditor/"+source+"/audited", nil).Inc(1)
+
+	if err == nil {
+		stats.Available++
+		metrics.GetOrRegisterCounter("arb/daauditor/"+source+"/available", nil).Inc(1)
+	} else if errors.Is(err, errBatchAccumulatorMismatch) || errors.Is(err, arbstate.ErrHashMismatch) {
+		stats.Mismatched++
+		stats.LastFailure = seqNum
+		metrics.GetOrRegisterCounter("arb/daauditor/"+source+"/mismatched", nil).Inc(1)
+		log.Error("audited batch data doesn't match what was committed", "batch", seqNum, "source", source, "err", err)
+	} else {
+		stats.Unavailable++
+		stats.LastFailure = seqNum
+		metrics.GetOrRegisterCounter("arb/daauditor/"+source+"/unavailable", nil).Inc(1)
+		log.Warn("audited batch data is unavailable", "batch", seqNum, "source", source, "err", err)
+	}
+	availability := float64(stats.Available) / float64(stats.Audited)
+	metrics.GetOrRegisterGaugeFloat64("arb/daauditor/"+source+"/availability", nil).Update(availability)
+}
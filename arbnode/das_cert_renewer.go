@@ -0,0 +1,234 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/das"
+	"github.com/offchainlabs/nitro/staker"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	dasCertTrackedGauge   = metrics.NewRegisteredGauge("arb/batchposter/das/renewal/tracked", nil)
+	dasCertRenewedCounter = metrics.NewRegisteredCounter("arb/batchposter/das/renewal/renewed", nil)
+	dasCertFailedCounter  = metrics.NewRegisteredCounter("arb/batchposter/das/renewal/failed", nil)
+	dasCertExpiredCounter = metrics.NewRegisteredCounter("arb/batchposter/das/renewal/expired", nil)
+)
+
+type DASRenewalConfig struct {
+	Enable        bool          `koanf:"enable"`
+	RenewBefore   time.Duration `koanf:"renew-before" reload:"hot"`
+	CheckInterval time.Duration `koanf:"check-interval" reload:"hot"`
+	MaxTracked    int           `koanf:"max-tracked" reload:"hot"`
+}
+
+var DefaultDASRenewalConfig = DASRenewalConfig{
+	Enable:        false,
+	RenewBefore:   time.Hour * 24 * 3,
+	CheckInterval: time.Minute * 10,
+	MaxTracked:    1 << 16,
+}
+
+func DASRenewalConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultDASRenewalConfig.Enable, "re-store the data of posted AnyTrust batches with the committee if their certificates would expire before the batches are confirmed (only batches posted since the node started, and failed renewals aren't posted on chain instead)")
+	f.Duration(prefix+".renew-before", DefaultDASRenewalConfig.RenewBefore, "renew a batch's data once its certificate is due to expire within this period")
+	f.Duration(prefix+".check-interval", DefaultDASRenewalConfig.CheckInterval, "how often to check for certificates that need renewing")
+	f.Int(prefix+".max-tracked", DefaultDASRenewalConfig.MaxTracked, "the maximum number of unconfirmed batches to track, forgetting the oldest beyond that")
+}
+
+func (c *DASRenewalConfig) Validate() error {
+	if c.Enable && c.CheckInterval <= 0 {
+		return errors.New("das renewal check interval must be positive")
+	}
+	return nil
+}
+
+type trackedDASCert struct {
+	seqNum   uint64
+	dataHash common.Hash
+	timeout  uint64
+}
+
+// DASCertRenewer keeps the data of AnyTrust batches posted by this node retrievable until the batches are confirmed.
+// A committee only promises to store data until its certificate's timeout, so if the rollup hasn't confirmed an
+// assertion covering a batch by the time that approaches, the data is fetched back and stored again with a later timeout.
+// The certificate posted on-chain can't change, but it refers to the data by hash, which the committee keeps serving.
+// Certificates are only tracked in memory, so batches posted before the node last started aren't renewed.
+// There's no on-chain fallback for a failed renewal either: the batch is already in the sequencer inbox as its
+// certificate, so posting its data again on chain would make a new batch rather than replace the old one's data.
+// Failures are only logged and counted under arb/batchposter/das/renewal, for the operator to step in.
+type DASCertRenewer struct {
+	stopwaiter.StopWaiter
+	config          func() *BatchPosterConfig
+	writer          das.DataAvailabilityServiceWriter
+	reader          arbstate.DataAvailabilityReader
+	confirmedBatchF func(context.Context) (uint64, error)
+
+	mutex   sync.Mutex
+	tracked []*trackedDASCert // ordered by sequence number
+}
+
+func NewDASCertRenewer(
+	writer das.DataAvailabilityServiceWriter,
+	reader arbstate.DataAvailabilityReader,
+	confirmedBatchF func(context.Context) (uint64, error),
+	config func() *BatchPosterConfig,
+) *DASCertRenewer {
+	return &DASCertRenewer{
+		config:          config,
+		writer:          writer,
+		reader:          reader,
+		confirmedBatchF: confirmedBatchF,
+	}
+}
+
+// RollupConfirmedBatchFetcher returns the number of batches fully covered by the rollup's latest confirmed assertion,
+// as of the parent chain's latest finalized block.
+func RollupConfirmedBatchFetcher(rollupAddress common.Address, client arbutil.L1Interface) (func(context.Context) (uint64, error), error) {
+	callOpts := bind.CallOpts{
+		BlockNumber: big.NewInt(int64(rpc.FinalizedBlockNumber)),
+	}
+	rollup, err := staker.NewRollupWatcher(rollupAddress, client, callOpts)
+	if err != nil {
+		return nil, err
+	}
+	var lastNode uint64
+	var lastBatch uint64
+	return func(ctx context.Context) (uint64, error) {
+		opts := callOpts
+		opts.Context = ctx
+		latestConfirmed, err := rollup.LatestConfirmed(&opts)
+		if err != nil {
+			return 0, err
+		}
+		if latestConfirmed == lastNode {
+			return lastBatch, nil
+		}
+		node, err := rollup.LookupNode(ctx, latestConfirmed)
+		if err != nil {
+			return 0, err
+		}
+		lastNode = latestConfirmed
+		lastBatch = node.Assertion.AfterState.GlobalState.Batch
+		return lastBatch, nil
+	}, nil
+}
+
+func (r *DASCertRenewer) Start(ctxIn context.Context) {
+	r.StopWaiter.Start(ctxIn, r)
+	r.CallIteratively(r.check)
+}
+
+// Track starts watching the certificate of a batch about to be posted
+func (r *DASCertRenewer) Track(seqNum uint64, cert *arbstate.DataAvailabilityCertificate) {
+	config := r.config().DASRenewal
+	if !config.Enable {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	// a batch may be stored again after a failed attempt to post it
+	for len(r.tracked) > 0 && r.tracked[len(r.tracked)-1].seqNum >= seqNum {
+		r.tracked = r.tracked[:len(r.tracked)-1]
+	}
+	r.tracked = append(r.tracked, &trackedDASCert{
+		seqNum:   seqNum,
+		dataHash: cert.DataHash,
+		timeout:  cert.Timeout,
+	})
+	if config.MaxTracked > 0 && len(r.tracked) > config.MaxTracked {
+		dropped := r.tracked[0]
+		log.Warn("too many unconfirmed AnyTrust batches, no longer renewing oldest", "batch", dropped.seqNum, "timeout", time.Unix(int64(dropped.timeout), 0))
+		r.tracked = r.tracked[len(r.tracked)-config.MaxTracked:]
+	}
+	dasCertTrackedGauge.Update(int64(len(r.tracked)))
+}
+
+func (r *DASCertRenewer) check(ctx context.Context) time.Duration {
+	batchConfig := r.config()
+	config := &batchConfig.DASRenewal
+	if !config.Enable {
+		return config.CheckInterval
+	}
+	confirmedBatch, err := r.confirmedBatchF(ctx)
+	if err != nil {
+		log.Warn("error getting confirmed batch count for das certificate renewal", "err", err)
+		return config.CheckInterval
+	}
+	due := r.forgetConfirmedAndGetDue(confirmedBatch, time.Now().Add(config.RenewBefore))
+	for _, cert := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		newTimeout := uint64(time.Now().Add(batchConfig.DASRetentionPeriod).Unix())
+		if err := r.renew(ctx, cert, newTimeout); err != nil {
+			dasCertFailedCounter.Inc(1)
+			if time.Now().Unix() >= int64(cert.timeout) {
+				dasCertExpiredCounter.Inc(1)
+				log.Error("failed to renew expired das certificate, batch data may no longer be available", "batch", cert.seqNum, "dataHash", cert.dataHash, "timeout", time.Unix(int64(cert.timeout), 0), "err", err)
+			} else {
+				log.Warn("failed to renew das certificate", "batch", cert.seqNum, "dataHash", cert.dataHash, "timeout", time.Unix(int64(cert.timeout), 0), "err", err)
+			}
+			continue
+		}
+		log.Info("renewed das certificate for unconfirmed batch", "batch", cert.seqNum, "dataHash", cert.dataHash, "oldTimeout", time.Unix(int64(cert.timeout), 0), "newTimeout", time.Unix(int64(newTimeout), 0))
+		dasCertRenewedCounter.Inc(1)
+		r.mutex.Lock()
+		cert.timeout = newTimeout
+		r.mutex.Unlock()
+	}
+	return config.CheckInterval
+}
+
+// forgetConfirmedAndGetDue stops tracking batches the rollup has confirmed,
+// and returns the remaining ones whose certificates expire before the deadline.
+func (r *DASCertRenewer) forgetConfirmedAndGetDue(confirmedBatch uint64, deadline time.Time) []*trackedDASCert {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	firstUnconfirmed := sort.Search(len(r.tracked), func(i int) bool {
+		return r.tracked[i].seqNum >= confirmedBatch
+	})
+	r.tracked = r.tracked[firstUnconfirmed:]
+	dasCertTrackedGauge.Update(int64(len(r.tracked)))
+
+	var due []*trackedDASCert
+	for _, cert := range r.tracked {
+		if int64(cert.timeout) < deadline.Unix() {
+			due = append(due, cert)
+		}
+	}
+	return due
+}
+
+func (r *DASCertRenewer) renew(ctx context.Context, cert *trackedDASCert, timeout uint64) error {
+	data, err := r.reader.GetByHash(ctx, cert.dataHash)
+	if err != nil {
+		return fmt.Errorf("fetching batch data: %w", err)
+	}
+	newCert, err := r.writer.Store(ctx, data, timeout, []byte{})
+	if err != nil {
+		return fmt.Errorf("storing batch data: %w", err)
+	}
+	if newCert.DataHash != cert.dataHash {
+		return fmt.Errorf("re-stored batch data has hash %v but expected %v", common.Hash(newCert.DataHash), cert.dataHash)
+	}
+	return nil
+}
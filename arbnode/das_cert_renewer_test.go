@@ -0,0 +1,120 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/das/dastree"
+)
+
+type mockRenewalDAS struct {
+	data     map[common.Hash][]byte
+	timeouts map[common.Hash]uint64
+	failGet  bool
+}
+
+func (d *mockRenewalDAS) GetByHash(_ context.Context, hash common.Hash) ([]byte, error) {
+	if d.failGet {
+		return nil, errors.New("not found")
+	}
+	data, ok := d.data[hash]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+func (d *mockRenewalDAS) ExpirationPolicy(context.Context) (arbstate.ExpirationPolicy, error) {
+	return arbstate.DiscardAfterDataTimeout, nil
+}
+
+func (d *mockRenewalDAS) Store(_ context.Context, message []byte, timeout uint64, _ []byte) (*arbstate.DataAvailabilityCertificate, error) {
+	hash := dastree.Hash(message)
+	d.data[hash] = message
+	d.timeouts[hash] = timeout
+	return &arbstate.DataAvailabilityCertificate{DataHash: hash, Timeout: timeout}, nil
+}
+
+func (d *mockRenewalDAS) String() string {
+	return "mockRenewalDAS"
+}
+
+func TestDASCertRenewal(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mock := &mockRenewalDAS{
+		data:     make(map[common.Hash][]byte),
+		timeouts: make(map[common.Hash]uint64),
+	}
+	config := DefaultBatchPosterConfig
+	config.DASRenewal.Enable = true
+	config.DASRenewal.RenewBefore = time.Hour
+	confirmedBatch := uint64(0)
+	renewer := NewDASCertRenewer(
+		mock,
+		mock,
+		func(context.Context) (uint64, error) { return confirmedBatch, nil },
+		func() *BatchPosterConfig { return &config },
+	)
+
+	now := time.Now()
+	timeouts := []time.Duration{time.Minute, time.Minute, time.Hour * 2}
+	var certs []*arbstate.DataAvailabilityCertificate
+	for i, timeout := range timeouts {
+		cert, err := mock.Store(ctx, []byte{byte(i), 1, 2, 3}, uint64(now.Add(timeout).Unix()), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		renewer.Track(uint64(i+1), cert)
+		certs = append(certs, cert)
+	}
+
+	// batch 1 is confirmed so its certificate shouldn't be renewed, while batch 2's is due and batch 3's isn't yet
+	confirmedBatch = 2
+	renewer.check(ctx)
+	if mock.timeouts[certs[0].DataHash] != certs[0].Timeout {
+		t.Error("renewed the certificate of a confirmed batch")
+	}
+	renewed := mock.timeouts[certs[1].DataHash]
+	if renewed < uint64(now.Add(config.DASRetentionPeriod).Unix()) {
+		t.Error("didn't renew certificate about to expire, timeout", renewed)
+	}
+	if mock.timeouts[certs[2].DataHash] != certs[2].Timeout {
+		t.Error("renewed a certificate that wasn't due")
+	}
+	if len(renewer.tracked) != 2 {
+		t.Fatal("expected 2 tracked batches but have", len(renewer.tracked))
+	}
+	if renewer.tracked[0].timeout != renewed {
+		t.Error("tracked timeout", renewer.tracked[0].timeout, "doesn't match renewed timeout", renewed)
+	}
+
+	// a failed renewal is retried on the next check
+	config.DASRenewal.RenewBefore = time.Hour * 3
+	mock.failGet = true
+	renewer.check(ctx)
+	if mock.timeouts[certs[2].DataHash] != certs[2].Timeout {
+		t.Error("renewed a certificate whose data couldn't be fetched")
+	}
+	mock.failGet = false
+	renewer.check(ctx)
+	if mock.timeouts[certs[2].DataHash] == certs[2].Timeout {
+		t.Error("didn't retry renewing a certificate after a failure")
+	}
+
+	// once everything is confirmed, nothing is left to track
+	confirmedBatch = 4
+	renewer.check(ctx)
+	if len(renewer.tracked) != 0 {
+		t.Error("still tracking", len(renewer.tracked), "batches after all were confirmed")
+	}
+}
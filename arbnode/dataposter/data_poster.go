@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -50,6 +51,13 @@ import (
 	redisstorage "github.com/offchainlabs/nitro/arbnode/dataposter/redis"
 )
 
+var (
+	replacedTxCounter            = metrics.NewRegisteredCounter("arb/dataposter/replaced", nil)
+	unconfirmedTxsGauge          = metrics.NewRegisteredGauge("arb/dataposter/unconfirmed", nil)
+	oldestUnconfirmedTxAgeGauge  = metrics.NewRegisteredGauge("arb/dataposter/unconfirmed/oldestage", nil)
+	oldestUnconfirmedFeeCapGauge = metrics.NewRegisteredGauge("arb/dataposter/unconfirmed/oldestfeecap", nil)
+)
+
 // Dataposter implements functionality to post transactions on the chain. It
 // is initialized with specified sender/signer and keeps nonce of that address
 // as it posts transactions.
@@ -429,6 +437,13 @@ func (p *DataPoster) GetNextNonceAndMeta(ctx context.Context) (uint64, []byte, e
 	return nonce, meta, err
 }
 
+// PendingTransactions returns how many transactions have been posted but not yet confirmed.
+func (p *DataPoster) PendingTransactions(ctx context.Context) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.queue.Length(ctx)
+}
+
 const minNonBlobRbfIncrease = arbmath.OneInBips * 11 / 10
 const minBlobRbfIncrease = arbmath.OneInBips * 2
 
@@ -468,6 +483,47 @@ func (p *DataPoster) evalMaxFeeCapExpr(backlogOfBatches uint64, elapsed time.Dur
 
 var big4 = big.NewInt(4)
 
+const (
+	TipStrategySuggested  = "suggested"
+	TipStrategyFeeHistory = "fee-history"
+	TipStrategyMin        = "min"
+)
+
+// suggestTipCap proposes a priority fee according to the configured tip strategy, before it's clamped to the configured bounds
+func (p *DataPoster) suggestTipCap(ctx context.Context) (*big.Int, error) {
+	config := p.config()
+	switch config.TipStrategy {
+	case TipStrategySuggested, "":
+		return p.client.SuggestGasTipCap(ctx)
+	case TipStrategyMin:
+		return big.NewInt(0), nil
+	case TipStrategyFeeHistory:
+		var history struct {
+			Reward [][]*hexutil.Big `json:"reward"`
+		}
+		err := p.client.Client().CallContext(ctx, &history, "eth_feeHistory", hexutil.Uint64(config.FeeHistoryBlocks), "latest", []float64{config.TipPercentile})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent chain fee history: %w", err)
+		}
+		total := new(big.Int)
+		var count uint64
+		for _, rewards := range history.Reward {
+			if len(rewards) == 0 || rewards[0] == nil {
+				continue
+			}
+			total.Add(total, rewards[0].ToInt())
+			count++
+		}
+		if count == 0 {
+			// no recent transactions to learn from
+			return p.client.SuggestGasTipCap(ctx)
+		}
+		return arbmath.BigDivByUint(total, count), nil
+	default:
+		return nil, fmt.Errorf("unknown tip strategy \"%v\"", config.TipStrategy)
+	}
+}
+
 // The dataPosterBacklog argument should *not* include extraBacklog (it's added in in this function)
 func (p *DataPoster) feeAndTipCaps(ctx context.Context, nonce uint64, gasLimit uint64, numBlobs uint64, lastTx *types.Transaction, dataCreatedAt time.Time, dataPosterBacklog uint64, latestHeader *types.Header) (*big.Int, *big.Int, *big.Int, error) {
 	config := p.config()
@@ -492,7 +548,7 @@ func (p *DataPoster) feeAndTipCaps(ctx context.Context, nonce uint64, gasLimit u
 		return nil, nil, nil, fmt.Errorf("failed to get latest nonce %v blocks ago (block %v): %w", config.NonceRbfSoftConfs, softConfBlock, err)
 	}
 
-	suggestedTip, err := p.client.SuggestGasTipCap(ctx)
+	suggestedTip, err := p.suggestTipCap(ctx)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -598,6 +654,14 @@ func (p *DataPoster) feeAndTipCaps(ctx context.Context, nonce uint64, gasLimit u
 		newBlobFeeCap = arbmath.BigDivByUint(newBlobCost, blobGasUsed)
 	}
 
+	if config.MinBaseFeeMultipleBips > 0 && gasLimit > 0 {
+		// Keep enough headroom over the current base fee for the transaction to stay includable while the base fee rises,
+		// as long as the balance set aside for this transaction can cover it.
+		minBaseFeeCap := arbmath.BigAdd(arbmath.BigMulByBips(latestHeader.BaseFee, config.MinBaseFeeMultipleBips), newTipCap)
+		affordableBaseFeeCap := arbmath.BigDivByUint(arbmath.BigSub(balanceForTx, targetBlobCost), gasLimit)
+		newBaseFeeCap = arbmath.BigMax(newBaseFeeCap, arbmath.BigMin(minBaseFeeCap, affordableBaseFeeCap))
+	}
+
 	if config.MaxFeeBidMultipleBips > 0 {
 		// Limit the fee caps to be no greater than max(MaxFeeBidMultipleBips, minRbf)
 		maxNonBlobFee := arbmath.BigMulByBips(currentNonBlobFee, config.MaxFeeBidMultipleBips)
@@ -935,6 +999,7 @@ func (p *DataPoster) replaceTx(ctx context.Context, prevTx *storage.QueuedTransa
 	if err != nil {
 		return err
 	}
+	replacedTxCounter.Inc(1)
 
 	return p.sendTx(ctx, prevTx, &newTx)
 }
@@ -1067,6 +1132,16 @@ func (p *DataPoster) Start(ctxIn context.Context) {
 			latestCumulativeWeight = latestQueued.CumulativeWeight()
 			latestNonce = latestQueued.FullTx.Nonce()
 		}
+		unconfirmedTxsGauge.Update(int64(len(queueContents)))
+		if len(queueContents) > 0 {
+			// Transactions stuck behind a gas spike show up as a growing age and fee cap here
+			oldestUnconfirmedTxAgeGauge.Update(time.Since(queueContents[0].Created).Milliseconds())
+			// Saturating, as a fee cap may not fit in a gauge
+			oldestUnconfirmedFeeCapGauge.Update(arbmath.SaturatingCast(arbmath.BigToUintSaturating(queueContents[0].FullTx.GasFeeCap())))
+		} else {
+			oldestUnconfirmedTxAgeGauge.Update(0)
+			oldestUnconfirmedFeeCapGauge.Update(0)
+		}
 		for _, tx := range queueContents {
 			replacing := false
 			if now.After(tx.NextReplacement) {
@@ -1139,6 +1214,10 @@ type DataPosterConfig struct {
 	MaxTipCapGwei          float64           `koanf:"max-tip-cap-gwei" reload:"hot"`
 	MaxBlobTxTipCapGwei    float64           `koanf:"max-blob-tx-tip-cap-gwei" reload:"hot"`
 	MaxFeeBidMultipleBips  arbmath.Bips      `koanf:"max-fee-bid-multiple-bips" reload:"hot"`
+	MinBaseFeeMultipleBips arbmath.Bips      `koanf:"min-base-fee-multiple-bips" reload:"hot"`
+	TipStrategy            string            `koanf:"tip-strategy" reload:"hot"`
+	TipPercentile          float64           `koanf:"tip-percentile" reload:"hot"`
+	FeeHistoryBlocks       uint64            `koanf:"fee-history-blocks" reload:"hot"`
 	NonceRbfSoftConfs      uint64            `koanf:"nonce-rbf-soft-confs" reload:"hot"`
 	AllocateMempoolBalance bool              `koanf:"allocate-mempool-balance" reload:"hot"`
 	UseDBStorage           bool              `koanf:"use-db-storage"`
@@ -1176,6 +1255,18 @@ type DangerousConfig struct {
 	ClearDBStorage bool `koanf:"clear-dbstorage"`
 }
 
+func (c *DataPosterConfig) Validate() error {
+	switch c.TipStrategy {
+	case TipStrategySuggested, TipStrategyFeeHistory, TipStrategyMin:
+	default:
+		return fmt.Errorf("invalid tip strategy \"%v\" (must be %v, %v or %v)", c.TipStrategy, TipStrategySuggested, TipStrategyFeeHistory, TipStrategyMin)
+	}
+	if c.TipStrategy == TipStrategyFeeHistory && (c.FeeHistoryBlocks == 0 || c.TipPercentile < 0 || c.TipPercentile > 100) {
+		return errors.New("the fee-history tip strategy requires at least one fee history block and a tip percentile between 0 and 100")
+	}
+	return nil
+}
+
 // ConfigFetcher function type is used instead of directly passing config so
 // that flags can be reloaded dynamically.
 type ConfigFetcher func() *DataPosterConfig
@@ -1194,6 +1285,10 @@ func DataPosterConfigAddOptions(prefix string, f *pflag.FlagSet, defaultDataPost
 	f.Float64(prefix+".max-tip-cap-gwei", defaultDataPosterConfig.MaxTipCapGwei, "the maximum tip cap to post transactions at")
 	f.Float64(prefix+".max-blob-tx-tip-cap-gwei", defaultDataPosterConfig.MaxBlobTxTipCapGwei, "the maximum tip cap to post EIP-4844 blob carrying transactions at")
 	f.Uint64(prefix+".max-fee-bid-multiple-bips", uint64(defaultDataPosterConfig.MaxFeeBidMultipleBips), "the maximum multiple of the current price to bid for a transaction's fees (may be exceeded due to min rbf increase, 0 = unlimited)")
+	f.Uint64(prefix+".min-base-fee-multiple-bips", uint64(defaultDataPosterConfig.MinBaseFeeMultipleBips), "the minimum multiple of the current base fee to bid as the fee cap, if the balance allows it, so transactions stay includable as the base fee rises (0 = disabled)")
+	f.String(prefix+".tip-strategy", defaultDataPosterConfig.TipStrategy, "how to pick the priority fee before applying the min and max tip caps, one of \"suggested\" (the parent chain node's suggestion), \"fee-history\" (a percentile of recent blocks' priority fees) or \"min\" (always the min tip cap)")
+	f.Float64(prefix+".tip-percentile", defaultDataPosterConfig.TipPercentile, "the percentile of recent priority fees to use with the fee-history tip strategy")
+	f.Uint64(prefix+".fee-history-blocks", defaultDataPosterConfig.FeeHistoryBlocks, "the number of recent blocks to average priority fees over with the fee-history tip strategy")
 	f.Uint64(prefix+".nonce-rbf-soft-confs", defaultDataPosterConfig.NonceRbfSoftConfs, "the maximum probable reorg depth, used to determine when a transaction will no longer likely need replaced-by-fee")
 	f.Bool(prefix+".allocate-mempool-balance", defaultDataPosterConfig.AllocateMempoolBalance, "if true, don't put transactions in the mempool that spend a total greater than the batch poster's balance")
 	f.Bool(prefix+".use-db-storage", defaultDataPosterConfig.UseDBStorage, "uses database storage when enabled")
@@ -1236,6 +1331,10 @@ var DefaultDataPosterConfig = DataPosterConfig{
 	MaxTipCapGwei:          5,
 	MaxBlobTxTipCapGwei:    1, // lower than normal because 4844 rbf is a minimum of a 2x
 	MaxFeeBidMultipleBips:  arbmath.OneInBips * 10,
+	MinBaseFeeMultipleBips: 0,
+	TipStrategy:            TipStrategySuggested,
+	TipPercentile:          60,
+	FeeHistoryBlocks:       10,
 	NonceRbfSoftConfs:      1,
 	AllocateMempoolBalance: true,
 	UseDBStorage:           true,
@@ -1270,6 +1369,10 @@ var TestDataPosterConfig = DataPosterConfig{
 	MaxTipCapGwei:          5,
 	MaxBlobTxTipCapGwei:    1,
 	MaxFeeBidMultipleBips:  arbmath.OneInBips * 10,
+	MinBaseFeeMultipleBips: 0,
+	TipStrategy:            TipStrategySuggested,
+	TipPercentile:          60,
+	FeeHistoryBlocks:       10,
 	NonceRbfSoftConfs:      1,
 	AllocateMempoolBalance: true,
 	UseDBStorage:           false,
@@ -482,3 +482,70 @@ func TestFeeAndTipCaps_RBF_RisingBlobFee_FallingBaseFee(t *testing.T) {
 	}
 
 }
+
+func TestFeeAndTipCaps_TipStrategyAndMinBaseFeeMultiple(t *testing.T) {
+	config := &DataPosterConfig{
+		MaxMempoolTransactions: 18,
+		MaxMempoolWeight:       18,
+		MinTipCapGwei:          0.05,
+		MaxTipCapGwei:          5,
+		AllocateMempoolBalance: true,
+		TipStrategy:            TipStrategyMin,
+
+		UrgencyGwei:           2.,
+		ElapsedTimeBase:       10 * time.Minute,
+		ElapsedTimeImportance: 10,
+		TargetPriceGwei:       2.,
+	}
+	expression, err := govaluate.NewEvaluableExpression(DefaultDataPosterConfig.MaxFeeCapFormula)
+	if err != nil {
+		t.Fatalf("error creating govaluate evaluable expression: %v", err)
+	}
+	p := DataPoster{
+		config:       func() *DataPosterConfig { return config },
+		extraBacklog: func() uint64 { return 0 },
+		balance:      big.NewInt(0).Mul(big.NewInt(params.Ether), big.NewInt(10)),
+		client: &stubL1Client{
+			senderNonce:        1,
+			suggestedGasTipCap: big.NewInt(2 * params.GWei),
+		},
+		auth: &bind.TransactOpts{
+			From: common.Address{},
+		},
+		maxFeeCapExpression: expression,
+	}
+	latestHeader := types.Header{
+		Number:  big.NewInt(1),
+		BaseFee: big.NewInt(params.GWei),
+	}
+
+	ctx := context.Background()
+	gasFeeCap, tipCap, _, err := p.feeAndTipCaps(ctx, 1, 100_000, 0, nil, time.Now(), 0, &latestHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the min tip strategy ignores the parent chain's suggestion
+	expectedTipCap := big.NewInt(params.GWei / 20)
+	if !arbmath.BigEquals(tipCap, expectedTipCap) {
+		t.Fatalf("feeAndTipCaps returned tip cap %v but expected %v", tipCap, expectedTipCap)
+	}
+	if gasFeeCap.Cmp(big.NewInt(3*params.GWei)) >= 0 {
+		t.Fatalf("fee cap %v exceeds the target price without a base fee multiple", gasFeeCap)
+	}
+
+	// a base fee multiple raises the fee cap above the target price
+	config.MinBaseFeeMultipleBips = arbmath.OneInBips * 3
+	gasFeeCap, _, _, err = p.feeAndTipCaps(ctx, 1, 100_000, 0, nil, time.Now(), 0, &latestHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFeeCap := arbmath.BigAdd(big.NewInt(3*params.GWei), expectedTipCap)
+	if !arbmath.BigEquals(gasFeeCap, expectedFeeCap) {
+		t.Fatalf("feeAndTipCaps returned fee cap %v but expected %v", gasFeeCap, expectedFeeCap)
+	}
+
+	config.TipStrategy = "bogus"
+	if err := config.Validate(); err == nil {
+		t.Fatal("invalid tip strategy passed validation")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"math/big"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -29,17 +30,40 @@ type DelayedSequencer struct {
 	exec                     execution.ExecutionSequencer
 	coordinator              *SeqCoordinator
 	waitingForFinalizedBlock uint64
+	lastSequenced            time.Time
 	mutex                    sync.Mutex
 	config                   DelayedSequencerConfigFetcher
 }
 
 type DelayedSequencerConfig struct {
-	Enable              bool  `koanf:"enable" reload:"hot"`
-	FinalizeDistance    int64 `koanf:"finalize-distance" reload:"hot"`
-	RequireFullFinality bool  `koanf:"require-full-finality" reload:"hot"`
-	UseMergeFinality    bool  `koanf:"use-merge-finality" reload:"hot"`
+	Enable              bool          `koanf:"enable" reload:"hot"`
+	FinalizeDistance    int64         `koanf:"finalize-distance" reload:"hot"`
+	RequireFullFinality bool          `koanf:"require-full-finality" reload:"hot"`
+	UseMergeFinality    bool          `koanf:"use-merge-finality" reload:"hot"`
+	L1Confirmations     int64         `koanf:"l1-confirmations" reload:"hot"`
+	AggregationMode     string        `koanf:"aggregation-mode" reload:"hot"`
+	AggregationInterval time.Duration `koanf:"aggregation-interval" reload:"hot"`
+	MaxDelayBlocks      int64         `koanf:"max-delay-blocks" reload:"hot"`
 }
 
+// How eagerly finalized delayed messages are sequenced
+const (
+	// As soon as they're finalized, checking every parent chain block
+	DelayedAggregationEveryBlock = "every-block"
+	// At most once per aggregation interval, so that messages arriving close together go into the same block
+	DelayedAggregationInterval = "interval"
+	// Only when the sequencer coordinator forces it, or once the oldest message has waited max-delay-blocks
+	DelayedAggregationForced = "forced"
+)
+
+// Bounds on how long delayed messages may be held back. The upper bounds keep well clear of the sequencer
+// inbox's force inclusion delay (a day by default), after which anyone could include the messages themselves.
+const (
+	minDelayedAggregationInterval = time.Second
+	maxDelayedAggregationInterval = time.Hour
+	maxDelayedMaxDelayBlocks      = 3600
+)
+
 type DelayedSequencerConfigFetcher func() *DelayedSequencerConfig
 
 func DelayedSequencerConfigAddOptions(prefix string, f *flag.FlagSet) {
@@ -47,6 +71,30 @@ func DelayedSequencerConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Int64(prefix+".finalize-distance", DefaultDelayedSequencerConfig.FinalizeDistance, "how many blocks in the past L1 block is considered final (ignored when using Merge finality)")
 	f.Bool(prefix+".require-full-finality", DefaultDelayedSequencerConfig.RequireFullFinality, "whether to wait for full finality before sequencing delayed messages")
 	f.Bool(prefix+".use-merge-finality", DefaultDelayedSequencerConfig.UseMergeFinality, "whether to use The Merge's notion of finality before sequencing delayed messages")
+	f.Int64(prefix+".l1-confirmations", DefaultDelayedSequencerConfig.L1Confirmations, "minimum number of L1 confirmations a delayed message must have before it's sequenced (0 to disable); the stricter of this and the finality setting applies, so with finalize-distance it only matters if larger")
+	f.String(prefix+".aggregation-mode", DefaultDelayedSequencerConfig.AggregationMode, "when to sequence finalized delayed messages, either every-block, interval (at most once per aggregation-interval), or forced (only when the sequencer coordinator forces it or after max-delay-blocks)")
+	f.Duration(prefix+".aggregation-interval", DefaultDelayedSequencerConfig.AggregationInterval, "with the interval aggregation mode, minimum time between sequencing delayed messages (at most 1h)")
+	f.Int64(prefix+".max-delay-blocks", DefaultDelayedSequencerConfig.MaxDelayBlocks, "with the forced aggregation mode, sequence delayed messages once the oldest was posted this many parent chain blocks ago (at most 3600)")
+}
+
+func (c *DelayedSequencerConfig) Validate() error {
+	if c.L1Confirmations < 0 {
+		return fmt.Errorf("delayed sequencer l1 confirmations %v must not be negative", c.L1Confirmations)
+	}
+	switch c.AggregationMode {
+	case DelayedAggregationEveryBlock:
+	case DelayedAggregationInterval:
+		if c.AggregationInterval < minDelayedAggregationInterval || c.AggregationInterval > maxDelayedAggregationInterval {
+			return fmt.Errorf("delayed sequencer aggregation interval %v must be between %v and %v", c.AggregationInterval, minDelayedAggregationInterval, maxDelayedAggregationInterval)
+		}
+	case DelayedAggregationForced:
+		if c.MaxDelayBlocks < 1 || c.MaxDelayBlocks > maxDelayedMaxDelayBlocks {
+			return fmt.Errorf("delayed sequencer max delay blocks %v must be between 1 and %v", c.MaxDelayBlocks, maxDelayedMaxDelayBlocks)
+		}
+	default:
+		return fmt.Errorf("unknown delayed sequencer aggregation mode \"%v\"", c.AggregationMode)
+	}
+	return nil
 }
 
 var DefaultDelayedSequencerConfig = DelayedSequencerConfig{
@@ -54,6 +102,10 @@ var DefaultDelayedSequencerConfig = DelayedSequencerConfig{
 	FinalizeDistance:    20,
 	RequireFullFinality: false,
 	UseMergeFinality:    true,
+	L1Confirmations:     0,
+	AggregationMode:     DelayedAggregationEveryBlock,
+	AggregationInterval: time.Minute,
+	MaxDelayBlocks:      300,
 }
 
 var TestDelayedSequencerConfig = DelayedSequencerConfig{
@@ -61,6 +113,10 @@ var TestDelayedSequencerConfig = DelayedSequencerConfig{
 	FinalizeDistance:    20,
 	RequireFullFinality: false,
 	UseMergeFinality:    false,
+	L1Confirmations:     0,
+	AggregationMode:     DelayedAggregationEveryBlock,
+	AggregationInterval: time.Minute,
+	MaxDelayBlocks:      300,
 }
 
 func NewDelayedSequencer(l1Reader *headerreader.HeaderReader, reader *InboxReader, exec execution.ExecutionSequencer, coordinator *SeqCoordinator, config DelayedSequencerConfigFetcher) (*DelayedSequencer, error) {
@@ -87,10 +143,39 @@ func (d *DelayedSequencer) trySequence(ctx context.Context, lastBlockHeader *typ
 		return nil
 	}
 
-	return d.sequenceWithoutLockout(ctx, lastBlockHeader)
+	return d.sequenceWithoutLockout(ctx, lastBlockHeader, false)
+}
+
+// holdBack returns whether to leave finalized delayed messages for later, so more can be sequenced together
+func (d *DelayedSequencer) holdBack(config *DelayedSequencerConfig, currentBlock uint64, oldestMessageBlock uint64) bool {
+	switch config.AggregationMode {
+	case DelayedAggregationInterval:
+		return time.Since(d.lastSequenced) < config.AggregationInterval
+	case DelayedAggregationForced:
+		return currentBlock < oldestMessageBlock+uint64(config.MaxDelayBlocks)
+	default:
+		return false
+	}
 }
 
-func (d *DelayedSequencer) sequenceWithoutLockout(ctx context.Context, lastBlockHeader *types.Header) error {
+// applyL1Confirmations lowers the block up to which delayed messages are sequenced, as determined by the finality
+// settings, to the latest with the required confirmations, whichever is stricter. With merge finality that's usually
+// the finalized or safe block, while with finalize-distance it only has an effect if it's larger than the distance.
+// Returns false if no block has enough confirmations yet.
+func applyL1Confirmations(finalized uint64, headNum uint64, confirmations int64) (uint64, bool) {
+	if confirmations <= 0 {
+		return finalized, true
+	}
+	if headNum < uint64(confirmations) {
+		return 0, false
+	}
+	if confirmed := headNum - uint64(confirmations); confirmed < finalized {
+		return confirmed, true
+	}
+	return finalized, true
+}
+
+func (d *DelayedSequencer) sequenceWithoutLockout(ctx context.Context, lastBlockHeader *types.Header, force bool) error {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
@@ -122,6 +207,16 @@ func (d *DelayedSequencer) sequenceWithoutLockout(ctx context.Context, lastBlock
 		finalized = uint64(currentNum - config.FinalizeDistance)
 	}
 
+	confirmed, ok := applyL1Confirmations(finalized, lastBlockHeader.Number.Uint64(), config.L1Confirmations)
+	if !ok {
+		return nil
+	}
+	if confirmed < finalized {
+		finalized = confirmed
+		// the finalized header is now past what we're reading up to
+		finalizedHash = common.Hash{}
+	}
+
 	if d.waitingForFinalizedBlock > finalized {
 		return nil
 	}
@@ -143,6 +238,7 @@ func (d *DelayedSequencer) sequenceWithoutLockout(ctx context.Context, lastBlock
 	pos := startPos
 	var lastDelayedAcc common.Hash
 	var messages []*arbostypes.L1IncomingMessage
+	var oldestMessageBlock uint64
 	for pos < dbDelayedCount {
 		msg, acc, parentChainBlockNumber, err := d.inbox.GetDelayedMessageAccumulatorAndParentChainBlockNumber(pos)
 		if err != nil {
@@ -164,11 +260,21 @@ func (d *DelayedSequencer) sequenceWithoutLockout(ctx context.Context, lastBlock
 				return errors.New("delayed message accumulator mismatch while sequencing")
 			}
 		}
+		if len(messages) == 0 {
+			oldestMessageBlock = parentChainBlockNumber
+		}
 		lastDelayedAcc = acc
 		messages = append(messages, msg)
 		pos++
 	}
 
+	if len(messages) > 0 && !force && d.holdBack(config, lastBlockHeader.Number.Uint64(), oldestMessageBlock) {
+		log.Debug("DelayedSequencer: holding back finalized messages", "msgnum", len(messages), "mode", config.AggregationMode)
+		// Check again at the next parent chain block, rather than waiting for a new message to be finalized
+		d.waitingForFinalizedBlock = finalized
+		return nil
+	}
+
 	// Sequence the delayed messages, if any
 	if len(messages) > 0 {
 		delayedBridgeAcc, err := d.bridge.GetAccumulator(ctx, pos-1, new(big.Int).SetUint64(finalized), finalizedHash)
@@ -185,6 +291,7 @@ func (d *DelayedSequencer) sequenceWithoutLockout(ctx context.Context, lastBlock
 				return err
 			}
 		}
+		d.lastSequenced = time.Now()
 		log.Info("DelayedSequencer: Sequenced", "msgnum", len(messages), "startpos", startPos)
 	}
 
@@ -192,12 +299,13 @@ func (d *DelayedSequencer) sequenceWithoutLockout(ctx context.Context, lastBlock
 }
 
 // Dangerous: bypasses lockout check!
+// Also sequences finalized messages regardless of the aggregation mode.
 func (d *DelayedSequencer) ForceSequenceDelayed(ctx context.Context) error {
 	lastBlockHeader, err := d.l1Reader.LastHeader(ctx)
 	if err != nil {
 		return err
 	}
-	return d.sequenceWithoutLockout(ctx, lastBlockHeader)
+	return d.sequenceWithoutLockout(ctx, lastBlockHeader, true)
 }
 
 func (d *DelayedSequencer) run(ctx context.Context) {
@@ -211,7 +319,10 @@ func (d *DelayedSequencer) run(ctx context.Context) {
 				log.Info("delayed sequencer: header channel close")
 				return
 			}
-			if err := d.trySequence(ctx, nextHeader); err != nil {
+			if err := d.trySequence(ctx, nextHeader); errors.Is(err, execution.ErrChainFrozen) {
+				// delayed messages are left for after the migration
+				log.Debug("delayed sequencer: chain frozen, not sequencing delayed messages")
+			} else if err != nil {
 				log.Error("Delayed sequencer error", "err", err)
 			}
 		case <-ctx.Done():
@@ -0,0 +1,80 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelayedSequencerAggregation(t *testing.T) {
+	config := DefaultDelayedSequencerConfig
+	Require(t, config.Validate())
+	d := &DelayedSequencer{}
+	if d.holdBack(&config, 100, 99) {
+		Fail(t, "every-block mode held back delayed messages")
+	}
+
+	config.AggregationMode = DelayedAggregationInterval
+	Require(t, config.Validate())
+	if d.holdBack(&config, 100, 99) {
+		Fail(t, "interval mode held back delayed messages when none were sequenced yet")
+	}
+	d.lastSequenced = time.Now()
+	if !d.holdBack(&config, 100, 99) {
+		Fail(t, "interval mode didn't hold back delayed messages within the interval")
+	}
+	d.lastSequenced = time.Now().Add(-config.AggregationInterval)
+	if d.holdBack(&config, 100, 99) {
+		Fail(t, "interval mode held back delayed messages after the interval")
+	}
+	config.AggregationInterval = 2 * time.Hour
+	if config.Validate() == nil {
+		Fail(t, "accepted an aggregation interval past the bound")
+	}
+
+	config.AggregationMode = DelayedAggregationForced
+	Require(t, config.Validate())
+	if !d.holdBack(&config, 100, 99) {
+		Fail(t, "forced mode didn't hold back recent delayed messages")
+	}
+	if d.holdBack(&config, 99+uint64(config.MaxDelayBlocks), 99) {
+		Fail(t, "forced mode held back delayed messages past the max delay")
+	}
+	config.MaxDelayBlocks = 0
+	if config.Validate() == nil {
+		Fail(t, "accepted a max delay of zero blocks")
+	}
+
+	config.AggregationMode = "sometimes"
+	if config.Validate() == nil {
+		Fail(t, "accepted an unknown aggregation mode")
+	}
+}
+
+func TestDelayedSequencerL1Confirmations(t *testing.T) {
+	if bound, ok := applyL1Confirmations(90, 100, 0); !ok || bound != 90 {
+		Fail(t, "confirmations applied when disabled", bound)
+	}
+	// stricter than the finality setting
+	if bound, ok := applyL1Confirmations(90, 100, 30); !ok || bound != 70 {
+		Fail(t, "confirmations didn't lower the bound", bound)
+	}
+	// the finality setting is stricter, e.g. a finalize distance larger than the confirmations
+	if bound, ok := applyL1Confirmations(70, 100, 20); !ok || bound != 70 {
+		Fail(t, "confirmations raised the bound past finality", bound)
+	}
+	if _, ok := applyL1Confirmations(0, 10, 20); ok {
+		Fail(t, "found a confirmed block before the parent chain had enough blocks")
+	}
+	if bound, ok := applyL1Confirmations(0, 20, 20); !ok || bound != 0 {
+		Fail(t, "genesis not confirmed with exactly enough blocks", bound)
+	}
+
+	config := DefaultDelayedSequencerConfig
+	config.L1Confirmations = -1
+	if config.Validate() == nil {
+		Fail(t, "accepted negative l1 confirmations")
+	}
+}
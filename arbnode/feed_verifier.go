@@ -0,0 +1,209 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+var (
+	feedVerifierVerifiedCounter    = metrics.NewRegisteredCounter("arb/feed/verifier/verified", nil)
+	feedVerifierDivergenceCounter  = metrics.NewRegisteredCounter("arb/feed/verifier/divergences", nil)
+	feedVerifierQuarantinedGauge   = metrics.NewRegisteredGauge("arb/feed/verifier/quarantined", nil)
+	feedVerifierDroppedFeedCounter = metrics.NewRegisteredCounter("arb/feed/verifier/quarantine/dropped", nil)
+)
+
+type FeedVerifierConfig struct {
+	Enable             bool          `koanf:"enable"`
+	MaxTrackedMessages uint64        `koanf:"max-tracked-messages" reload:"hot"`
+	QuarantineDuration time.Duration `koanf:"quarantine-duration" reload:"hot"`
+}
+
+type FeedVerifierConfigFetcher func() *FeedVerifierConfig
+
+var DefaultFeedVerifierConfig = FeedVerifierConfig{
+	Enable:             false,
+	MaxTrackedMessages: 1_000_000,
+	QuarantineDuration: time.Hour,
+}
+
+func FeedVerifierConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultFeedVerifierConfig.Enable, "enable checking messages received over the feed against the batches later posted to the parent chain, and quarantining the feed if they diverge")
+	f.Uint64(prefix+".max-tracked-messages", DefaultFeedVerifierConfig.MaxTrackedMessages, "maximum number of feed messages awaiting a batch to remember, after which the oldest are forgotten unverified")
+	f.Duration(prefix+".quarantine-duration", DefaultFeedVerifierConfig.QuarantineDuration, "how long to ignore the feed after it diverges from the parent chain (0 to ignore it until restarted)")
+}
+
+func (c *FeedVerifierConfig) Validate() error {
+	if c.Enable && c.MaxTrackedMessages == 0 {
+		return errors.New("feed verifier max tracked messages must be positive")
+	}
+	return nil
+}
+
+// FeedDivergence records a message that the feed and the parent chain disagree on
+type FeedDivergence struct {
+	SequenceNumber arbutil.MessageIndex `json:"sequenceNumber"`
+	FeedHash       common.Hash          `json:"feedHash"`
+	BatchHash      common.Hash          `json:"batchHash"`
+	DetectedAt     time.Time            `json:"detectedAt"`
+}
+
+// maxRecordedFeedDivergences bounds how many divergences are kept for Divergences
+const maxRecordedFeedDivergences = 100
+
+// FeedVerifier remembers the hash of every message received over the feed, and when the batch containing
+// it is read from the parent chain, checks the batch's message has the same hash. A mismatch means the feed
+// promised something the sequencer didn't post, so the feed is quarantined: its messages are dropped and the
+// node only follows the parent chain until the quarantine ends.
+type FeedVerifier struct {
+	config  FeedVerifierConfigFetcher
+	chainId uint64
+
+	mutex            sync.Mutex
+	feedHashes       map[arbutil.MessageIndex]common.Hash
+	oldestTracked    arbutil.MessageIndex
+	quarantined      bool
+	quarantinedUntil time.Time
+	divergences      []FeedDivergence
+}
+
+func NewFeedVerifier(chainId uint64, config FeedVerifierConfigFetcher) *FeedVerifier {
+	return &FeedVerifier{
+		config:     config,
+		chainId:    chainId,
+		feedHashes: make(map[arbutil.MessageIndex]common.Hash),
+	}
+}
+
+// feedVerifierHash hashes the message without its batch gas cost, which the feed doesn't include
+func (v *FeedVerifier) feedVerifierHash(pos arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata) (common.Hash, error) {
+	if msg.Message != nil && msg.Message.BatchGasCost != nil {
+		msgCopy := *msg
+		incomingCopy := *msg.Message
+		incomingCopy.BatchGasCost = nil
+		msgCopy.Message = &incomingCopy
+		msg = &msgCopy
+	}
+	return msg.Hash(pos, v.chainId)
+}
+
+// Quarantined returns whether feed messages should currently be ignored
+func (v *FeedVerifier) Quarantined() bool {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return v.quarantinedLocked()
+}
+
+func (v *FeedVerifier) quarantinedLocked() bool {
+	if v.quarantined && !v.quarantinedUntil.IsZero() && time.Now().After(v.quarantinedUntil) {
+		log.Info("feed quarantine ended, following the feed again")
+		v.quarantined = false
+		feedVerifierQuarantinedGauge.Update(0)
+	}
+	return v.quarantined
+}
+
+// DropFeedMessages counts feed messages ignored because of the quarantine
+func (v *FeedVerifier) DropFeedMessages(count int) {
+	feedVerifierDroppedFeedCounter.Inc(int64(count))
+}
+
+// RecordFeedMessages remembers the hashes of messages received over the feed, replacing any earlier
+// messages the feed sent at the same positions
+func (v *FeedVerifier) RecordFeedMessages(pos arbutil.MessageIndex, messages []arbostypes.MessageWithMetadata) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	for i := range messages {
+		msgPos := pos + arbutil.MessageIndex(i)
+		hash, err := v.feedVerifierHash(msgPos, &messages[i])
+		if err != nil {
+			return err
+		}
+		if len(v.feedHashes) == 0 || msgPos < v.oldestTracked {
+			v.oldestTracked = msgPos
+		}
+		v.feedHashes[msgPos] = hash
+	}
+	maxTracked := v.config().MaxTrackedMessages
+	for uint64(len(v.feedHashes)) > maxTracked {
+		delete(v.feedHashes, v.oldestTracked)
+		v.oldestTracked++
+	}
+	return nil
+}
+
+// VerifyConfirmedMessages checks messages read from a parent chain batch against those received over the
+// feed at the same positions, quarantining the feed if any differ, and returns the divergences found
+func (v *FeedVerifier) VerifyConfirmedMessages(pos arbutil.MessageIndex, messages []arbostypes.MessageWithMetadata) ([]FeedDivergence, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	var found []FeedDivergence
+	for i := range messages {
+		msgPos := pos + arbutil.MessageIndex(i)
+		feedHash, ok := v.feedHashes[msgPos]
+		if !ok {
+			continue
+		}
+		delete(v.feedHashes, msgPos)
+		batchHash, err := v.feedVerifierHash(msgPos, &messages[i])
+		if err != nil {
+			return nil, err
+		}
+		if feedHash == batchHash {
+			feedVerifierVerifiedCounter.Inc(1)
+			continue
+		}
+		found = append(found, FeedDivergence{
+			SequenceNumber: msgPos,
+			FeedHash:       feedHash,
+			BatchHash:      batchHash,
+			DetectedAt:     time.Now(),
+		})
+	}
+	if end := pos + arbutil.MessageIndex(len(messages)); pos <= v.oldestTracked && v.oldestTracked < end {
+		v.oldestTracked = end
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	feedVerifierDivergenceCounter.Inc(int64(len(found)))
+	sequenceNumbers := make([]arbutil.MessageIndex, 0, len(found))
+	for _, divergence := range found {
+		sequenceNumbers = append(sequenceNumbers, divergence.SequenceNumber)
+	}
+	duration := v.config().QuarantineDuration
+	log.Error("feed diverged from the parent chain, quarantining the feed", "sequenceNumbers", sequenceNumbers, "firstFeedHash", found[0].FeedHash, "firstBatchHash", found[0].BatchHash, "quarantine", duration)
+	v.quarantined = true
+	v.quarantinedUntil = time.Time{}
+	if duration > 0 {
+		v.quarantinedUntil = time.Now().Add(duration)
+	}
+	feedVerifierQuarantinedGauge.Update(1)
+	// Everything else the feed sent is suspect too, and will be read from the parent chain instead
+	v.feedHashes = make(map[arbutil.MessageIndex]common.Hash)
+
+	v.divergences = append(v.divergences, found...)
+	if len(v.divergences) > maxRecordedFeedDivergences {
+		v.divergences = v.divergences[len(v.divergences)-maxRecordedFeedDivergences:]
+	}
+	return found, nil
+}
+
+// Divergences returns the most recent divergences found between the feed and the parent chain
+func (v *FeedVerifier) Divergences() []FeedDivergence {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return append([]FeedDivergence(nil), v.divergences...)
+}
@@ -0,0 +1,77 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+)
+
+func feedVerifierTestMessages(count int, l2msg byte) []arbostypes.MessageWithMetadata {
+	var messages []arbostypes.MessageWithMetadata
+	for i := 0; i < count; i++ {
+		messages = append(messages, arbostypes.MessageWithMetadata{
+			Message: &arbostypes.L1IncomingMessage{
+				Header: &arbostypes.L1IncomingMessageHeader{},
+				L2msg:  []byte{l2msg, byte(i)},
+			},
+		})
+	}
+	return messages
+}
+
+func TestFeedVerifier(t *testing.T) {
+	config := DefaultFeedVerifierConfig
+	config.Enable = true
+	config.MaxTrackedMessages = 8
+	verifier := NewFeedVerifier(412346, func() *FeedVerifierConfig { return &config })
+
+	feed := feedVerifierTestMessages(6, 0)
+	Require(t, verifier.RecordFeedMessages(10, feed))
+
+	// Batch messages carry their batch gas cost, which the feed doesn't
+	confirmed := feedVerifierTestMessages(4, 0)
+	gasCost := uint64(100)
+	confirmed[0].Message.BatchGasCost = &gasCost
+	divergences, err := verifier.VerifyConfirmedMessages(10, confirmed)
+	Require(t, err)
+	if len(divergences) != 0 || verifier.Quarantined() {
+		Fail(t, "unexpected divergences", divergences)
+	}
+
+	// Only the oldest messages are forgotten once too many are tracked
+	Require(t, verifier.RecordFeedMessages(16, feedVerifierTestMessages(7, 0)))
+	if len(verifier.feedHashes) != 8 {
+		Fail(t, "tracking", len(verifier.feedHashes), "messages but expected 8")
+	}
+	if _, ok := verifier.feedHashes[14]; ok {
+		Fail(t, "oldest feed message not forgotten")
+	}
+	if _, ok := verifier.feedHashes[22]; !ok {
+		Fail(t, "newest feed message forgotten")
+	}
+
+	diverging := feedVerifierTestMessages(6, 0)[4:]
+	diverging[1].Message.L2msg = []byte{1}
+	divergences, err = verifier.VerifyConfirmedMessages(14, diverging)
+	Require(t, err)
+	if len(divergences) != 1 || divergences[0].SequenceNumber != 15 {
+		Fail(t, "expected a divergence at message 15 but got", divergences)
+	}
+	if !verifier.Quarantined() || len(verifier.Divergences()) != 1 {
+		Fail(t, "expected the feed to be quarantined after diverging")
+	}
+	if len(verifier.feedHashes) != 0 {
+		Fail(t, "expected the quarantined feed's messages to be forgotten")
+	}
+
+	verifier.mutex.Lock()
+	verifier.quarantinedUntil = time.Now().Add(-time.Second)
+	verifier.mutex.Unlock()
+	if verifier.Quarantined() {
+		Fail(t, "expected the quarantine to end")
+	}
+}
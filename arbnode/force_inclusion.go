@@ -0,0 +1,130 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
+)
+
+// ForceIncluder finds delayed messages the sequencer has left out of the inbox for longer than the
+// sequencer inbox's delay window, and forces their inclusion, so any node operator can get them
+// included if the sequencer censors them.
+type ForceIncluder struct {
+	client         arbutil.L1Interface
+	bridge         *DelayedBridge
+	seqInbox       *bridgegen.SequencerInbox
+	blocksPerQuery uint64
+}
+
+func NewForceIncluder(client arbutil.L1Interface, bridge *DelayedBridge, seqInbox *bridgegen.SequencerInbox, blocksPerQuery uint64) (*ForceIncluder, error) {
+	if blocksPerQuery == 0 {
+		return nil, errors.New("blocks per query must be positive")
+	}
+	return &ForceIncluder{
+		client:         client,
+		bridge:         bridge,
+		seqInbox:       seqInbox,
+		blocksPerQuery: blocksPerQuery,
+	}, nil
+}
+
+// lastForceIncludable returns the index in messages of the last message old enough to be force included
+// at the given parent chain block, or -1 if there's none. Messages must be in delayed inbox order.
+func lastForceIncludable(messages []*DelayedInboxMessage, l1BlockNumber, timestamp, delayBlocks, delaySeconds uint64) int {
+	last := -1
+	for i, msg := range messages {
+		header := msg.Message.Header
+		if header.BlockNumber+delayBlocks >= l1BlockNumber || header.Timestamp+delaySeconds >= timestamp {
+			break
+		}
+		last = i
+	}
+	return last
+}
+
+// FindMessageToForceInclude returns the latest delayed message that could be force included into the
+// sequencer inbox now, with every delayed message before it, or nil if there's none. The delayed
+// messages the sequencer inbox hasn't read are looked up in the parent chain's logs, starting from the
+// delayed bridge's first block.
+func (f *ForceIncluder) FindMessageToForceInclude(ctx context.Context) (*DelayedInboxMessage, error) {
+	latest, err := f.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	callOpts := &bind.CallOpts{Context: ctx, BlockNumber: latest.Number}
+	delayedRead, err := f.seqInbox.TotalDelayedMessagesRead(callOpts)
+	if err != nil {
+		return nil, fmt.Errorf("error getting delayed messages read: %w", err)
+	}
+	delayedCount, err := f.bridge.GetMessageCount(ctx, latest.Number)
+	if err != nil {
+		return nil, err
+	}
+	if delayedRead.Uint64() >= delayedCount {
+		return nil, nil
+	}
+	delayBlocks, _, delaySeconds, _, err := f.seqInbox.MaxTimeVariation(callOpts)
+	if err != nil {
+		return nil, fmt.Errorf("error getting max time variation: %w", err)
+	}
+
+	// The sequencer inbox compares against the parent chain's block.number, which is the L1 block number on an Arbitrum chain
+	l1BlockNumber := arbutil.ParentHeaderToL1BlockNumber(latest)
+	var unread []*DelayedInboxMessage
+	for from := f.bridge.FirstBlock().Uint64(); from <= latest.Number.Uint64(); from += f.blocksPerQuery {
+		to := from + f.blocksPerQuery - 1
+		if to > latest.Number.Uint64() {
+			to = latest.Number.Uint64()
+		}
+		messages, err := f.bridge.LookupMessagesInRange(ctx, new(big.Int).SetUint64(from), new(big.Int).SetUint64(to), nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range messages {
+			if msg.Message.Header.RequestId.Big().Uint64() >= delayedRead.Uint64() {
+				unread = append(unread, msg)
+			}
+		}
+		if len(unread) > 0 && lastForceIncludable(unread, l1BlockNumber, latest.Time, delayBlocks.Uint64(), delaySeconds.Uint64()) < len(unread)-1 {
+			// Later messages are younger still
+			break
+		}
+	}
+	if len(unread) == 0 || unread[0].Message.Header.RequestId.Big().Uint64() != delayedRead.Uint64() {
+		return nil, fmt.Errorf("delayed message %v not found after parent chain block %v", delayedRead, f.bridge.FirstBlock())
+	}
+	last := lastForceIncludable(unread, l1BlockNumber, latest.Time, delayBlocks.Uint64(), delaySeconds.Uint64())
+	if last < 0 {
+		log.Info("no delayed messages old enough to force include", "delayedRead", delayedRead, "delayedCount", delayedCount)
+		return nil, nil
+	}
+	return unread[last], nil
+}
+
+// ForceInclude sends the sequencer inbox transaction forcing the inclusion of delayed messages up to and including msg
+func (f *ForceIncluder) ForceInclude(opts *bind.TransactOpts, msg *DelayedInboxMessage) (*types.Transaction, error) {
+	header := msg.Message.Header
+	totalDelayedMessagesRead := new(big.Int).Add(header.RequestId.Big(), common.Big1)
+	return f.seqInbox.ForceInclusion(
+		opts,
+		totalDelayedMessagesRead,
+		header.Kind,
+		[2]uint64{header.BlockNumber, header.Timestamp},
+		header.L1BaseFee,
+		header.Poster,
+		crypto.Keccak256Hash(msg.Message.L2msg),
+	)
+}
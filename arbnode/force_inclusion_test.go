@@ -0,0 +1,46 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"testing"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+)
+
+func TestLastForceIncludable(t *testing.T) {
+	delayedMessage := func(blockNumber, timestamp uint64) *DelayedInboxMessage {
+		return &DelayedInboxMessage{
+			Message: &arbostypes.L1IncomingMessage{
+				Header: &arbostypes.L1IncomingMessageHeader{
+					BlockNumber: blockNumber,
+					Timestamp:   timestamp,
+				},
+			},
+		}
+	}
+	messages := []*DelayedInboxMessage{
+		delayedMessage(100, 1000),
+		delayedMessage(105, 1060),
+		delayedMessage(110, 1120),
+	}
+	cases := []struct {
+		l1BlockNumber uint64
+		timestamp     uint64
+		expected      int
+	}{
+		// Both the delay blocks and delay seconds must have passed
+		{l1BlockNumber: 110, timestamp: 2000, expected: -1},
+		{l1BlockNumber: 111, timestamp: 1100, expected: -1},
+		{l1BlockNumber: 111, timestamp: 1101, expected: 0},
+		{l1BlockNumber: 116, timestamp: 1161, expected: 1},
+		{l1BlockNumber: 1000, timestamp: 10000, expected: 2},
+	}
+	for _, c := range cases {
+		last := lastForceIncludable(messages, c.l1BlockNumber, c.timestamp, 10, 100)
+		if last != c.expected {
+			Fail(t, "at block", c.l1BlockNumber, "and time", c.timestamp, "got last force includable", last, "but expected", c.expected)
+		}
+	}
+}
@@ -14,8 +14,11 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	flag "github.com/spf13/pflag"
 
 	"github.com/offchainlabs/nitro/arbutil"
@@ -24,15 +27,26 @@ import (
 	"github.com/offchainlabs/nitro/util/stopwaiter"
 )
 
+var (
+	parentChainReorgsCounter = metrics.NewRegisteredCounter("arb/inboxreader/parentchain/reorgs", nil)
+	batchCacheHitCounter     = metrics.NewRegisteredCounter("arb/inboxreader/batchcache/hit", nil)
+	batchCacheMissCounter    = metrics.NewRegisteredCounter("arb/inboxreader/batchcache/miss", nil)
+)
+
 type InboxReaderConfig struct {
-	DelayBlocks         uint64        `koanf:"delay-blocks" reload:"hot"`
-	CheckDelay          time.Duration `koanf:"check-delay" reload:"hot"`
-	HardReorg           bool          `koanf:"hard-reorg" reload:"hot"`
-	MinBlocksToRead     uint64        `koanf:"min-blocks-to-read" reload:"hot"`
-	DefaultBlocksToRead uint64        `koanf:"default-blocks-to-read" reload:"hot"`
-	TargetMessagesRead  uint64        `koanf:"target-messages-read" reload:"hot"`
-	MaxBlocksToRead     uint64        `koanf:"max-blocks-to-read" reload:"hot"`
-	ReadMode            string        `koanf:"read-mode" reload:"hot"`
+	DelayBlocks              uint64        `koanf:"delay-blocks" reload:"hot"`
+	CheckDelay               time.Duration `koanf:"check-delay" reload:"hot"`
+	HardReorg                bool          `koanf:"hard-reorg" reload:"hot"`
+	MinBlocksToRead          uint64        `koanf:"min-blocks-to-read" reload:"hot"`
+	DefaultBlocksToRead      uint64        `koanf:"default-blocks-to-read" reload:"hot"`
+	TargetMessagesRead       uint64        `koanf:"target-messages-read" reload:"hot"`
+	MaxBlocksToRead          uint64        `koanf:"max-blocks-to-read" reload:"hot"`
+	ReadMode                 string        `koanf:"read-mode" reload:"hot"`
+	LogsParallelism          uint64        `koanf:"logs-parallelism" reload:"hot"`
+	MaxLogsBlockRange        uint64        `koanf:"max-logs-block-range" reload:"hot"`
+	ReorgCheckBatches        uint64        `koanf:"reorg-check-batches" reload:"hot"`
+	TagFallbackConfirmations uint64        `koanf:"tag-fallback-confirmations" reload:"hot"`
+	BatchCacheSize           int           `koanf:"batch-cache-size"`
 }
 
 type InboxReaderConfigFetcher func() *InboxReaderConfig
@@ -45,6 +59,12 @@ func (c *InboxReaderConfig) Validate() error {
 	if c.ReadMode != "latest" && c.ReadMode != "safe" && c.ReadMode != "finalized" {
 		return fmt.Errorf("inbox reader read-mode is invalid, want: latest or safe or finalized, got: %s", c.ReadMode)
 	}
+	if c.LogsParallelism == 0 {
+		return errors.New("inbox reader logs-parallelism cannot be zero")
+	}
+	if c.BatchCacheSize < 0 {
+		return errors.New("inbox reader batch-cache-size cannot be negative")
+	}
 	return nil
 }
 
@@ -57,28 +77,56 @@ func InboxReaderConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Uint64(prefix+".target-messages-read", DefaultInboxReaderConfig.TargetMessagesRead, "if adjust-blocks-to-read is enabled, the target number of messages to read at once")
 	f.Uint64(prefix+".max-blocks-to-read", DefaultInboxReaderConfig.MaxBlocksToRead, "if adjust-blocks-to-read is enabled, the maximum number of blocks to read at once")
 	f.String(prefix+".read-mode", DefaultInboxReaderConfig.ReadMode, "mode to only read latest or safe or finalized L1 blocks. Enabling safe or finalized disables feed input and output. Defaults to latest. Takes string input, valid strings- latest, safe, finalized")
+	f.Uint64(prefix+".logs-parallelism", DefaultInboxReaderConfig.LogsParallelism, "the number of eth_getLogs requests to make concurrently when reading a range of blocks")
+	f.Uint64(prefix+".max-logs-block-range", DefaultInboxReaderConfig.MaxLogsBlockRange, "the maximum number of blocks to query in a single eth_getLogs request, for providers that limit it (0 = no limit)")
+	f.Uint64(prefix+".tag-fallback-confirmations", DefaultInboxReaderConfig.TagFallbackConfirmations, "in safe or finalized read mode, if the parent chain doesn't support the safe or finalized block tag, only read blocks with this many confirmations instead (0 = fail)")
+	f.Uint64(prefix+".reorg-check-batches", DefaultInboxReaderConfig.ReorgCheckBatches, "the number of recently read batches whose parent chain block hashes are checked to detect and roll back parent chain reorgs (0 = disabled)")
+	f.Int(prefix+".batch-cache-size", DefaultInboxReaderConfig.BatchCacheSize, "the number of recent sequencer batches to keep in memory, so the block validator and other readers of batch data don't fetch them from the parent chain again (0 = disabled)")
 }
 
 var DefaultInboxReaderConfig = InboxReaderConfig{
-	DelayBlocks:         0,
-	CheckDelay:          time.Minute,
-	HardReorg:           false,
-	MinBlocksToRead:     1,
-	DefaultBlocksToRead: 100,
-	TargetMessagesRead:  500,
-	MaxBlocksToRead:     2000,
-	ReadMode:            "latest",
+	DelayBlocks:              0,
+	CheckDelay:               time.Minute,
+	HardReorg:                false,
+	MinBlocksToRead:          1,
+	DefaultBlocksToRead:      100,
+	TargetMessagesRead:       500,
+	MaxBlocksToRead:          2000,
+	ReadMode:                 "latest",
+	LogsParallelism:          4,
+	MaxLogsBlockRange:        0,
+	ReorgCheckBatches:        64,
+	TagFallbackConfirmations: 0,
+	BatchCacheSize:           64,
 }
 
 var TestInboxReaderConfig = InboxReaderConfig{
-	DelayBlocks:         0,
-	CheckDelay:          time.Millisecond * 10,
-	HardReorg:           false,
-	MinBlocksToRead:     1,
-	DefaultBlocksToRead: 100,
-	TargetMessagesRead:  500,
-	MaxBlocksToRead:     2000,
-	ReadMode:            "latest",
+	DelayBlocks:              0,
+	CheckDelay:               time.Millisecond * 10,
+	HardReorg:                false,
+	MinBlocksToRead:          1,
+	DefaultBlocksToRead:      100,
+	TargetMessagesRead:       500,
+	MaxBlocksToRead:          2000,
+	ReadMode:                 "latest",
+	LogsParallelism:          4,
+	MaxLogsBlockRange:        0,
+	ReorgCheckBatches:        64,
+	TagFallbackConfirmations: 0,
+	BatchCacheSize:           64,
+}
+
+// cachedBatch is a serialized sequencer batch, cached by the inbox accumulator after it so it can't be stale after a reorg
+type cachedBatch struct {
+	data      []byte
+	blockHash common.Hash
+}
+
+// batchParentChainBlock is the parent chain block a batch was read from
+type batchParentChainBlock struct {
+	batch  uint64
+	number uint64
+	hash   common.Hash
 }
 
 type InboxReader struct {
@@ -88,6 +136,8 @@ type InboxReader struct {
 	caughtUp          bool
 	firstMessageBlock *big.Int
 	config            InboxReaderConfigFetcher
+	recentBatchBlocks []batchParentChainBlock
+	loggedTagFallback bool
 
 	// Thread safe
 	tracker        *InboxTracker
@@ -96,6 +146,7 @@ type InboxReader struct {
 	caughtUpChan   chan struct{}
 	client         arbutil.L1Interface
 	l1Reader       *headerreader.HeaderReader
+	batchCache     *lru.Cache[common.Hash, cachedBatch]
 
 	// Atomic
 	lastSeenBatchCount uint64
@@ -111,7 +162,12 @@ func NewInboxReader(tracker *InboxTracker, client arbutil.L1Interface, l1Reader
 	if err != nil {
 		return nil, err
 	}
+	var batchCache *lru.Cache[common.Hash, cachedBatch]
+	if size := config().BatchCacheSize; size > 0 {
+		batchCache = lru.NewCache[common.Hash, cachedBatch](size)
+	}
 	return &InboxReader{
+		batchCache:        batchCache,
 		tracker:           tracker,
 		delayedBridge:     delayedBridge,
 		sequencerInbox:    sequencerInbox,
@@ -253,11 +309,7 @@ func (r *InboxReader) run(ctx context.Context, hadError bool) error {
 		if readMode != "latest" {
 			var blockNum uint64
 			fetchLatestSafeOrFinalized := func() {
-				if readMode == "safe" {
-					blockNum, err = r.l1Reader.LatestSafeBlockNr(ctx)
-				} else {
-					blockNum, err = r.l1Reader.LatestFinalizedBlockNr(ctx)
-				}
+				blockNum, err = r.latestTrustedBlockNr(ctx, readMode)
 			}
 			fetchLatestSafeOrFinalized()
 			if err != nil || blockNum == 0 {
@@ -316,6 +368,14 @@ func (r *InboxReader) run(ctx context.Context, hadError bool) error {
 			}
 		}
 
+		reorgFrom, err := r.rollBackParentChainReorg(ctx)
+		if err != nil {
+			return err
+		}
+		if reorgFrom != nil && reorgFrom.Cmp(from) < 0 {
+			from = reorgFrom
+		}
+
 		reorgingDelayed := false
 		reorgingSequencer := false
 		missingDelayed := false
@@ -440,7 +500,7 @@ func (r *InboxReader) run(ctx context.Context, hadError bool) error {
 				if len(sequencerBatches) > 0 && batchNum >= sequencerBatches[0].SequenceNumber {
 					idx := int(batchNum - sequencerBatches[0].SequenceNumber)
 					if idx < len(sequencerBatches) {
-						return sequencerBatches[idx].Serialize(ctx, r.l1Reader.Client())
+						return sequencerBatches[idx].Serialize(ctx, r.client)
 					}
 					log.Warn("missing mentioned batch in L1 message lookup", "batch", batchNum)
 				}
@@ -531,6 +591,7 @@ func (r *InboxReader) run(ctx context.Context, hadError bool) error {
 				}
 				if len(sequencerBatches) > 0 {
 					readAnyBatches = true
+					r.trackBatchBlocks(sequencerBatches)
 					r.lastReadMutex.Lock()
 					r.lastReadBlock = to.Uint64()
 					r.lastReadBatchCount = sequencerBatches[len(sequencerBatches)-1].SequenceNumber + 1
@@ -570,6 +631,123 @@ func (r *InboxReader) run(ctx context.Context, hadError bool) error {
 	}
 }
 
+// latestTrustedBlockNr returns the latest safe or finalized parent chain block, according to the read mode.
+// If the parent chain doesn't support the block tag, it falls back to trusting blocks with enough confirmations.
+func (r *InboxReader) latestTrustedBlockNr(ctx context.Context, readMode string) (uint64, error) {
+	var blockNum uint64
+	var err error
+	if readMode == "safe" {
+		blockNum, err = r.l1Reader.LatestSafeBlockNr(ctx)
+	} else {
+		blockNum, err = r.l1Reader.LatestFinalizedBlockNr(ctx)
+	}
+	confirmations := r.config().TagFallbackConfirmations
+	if !errors.Is(err, headerreader.ErrBlockNumberNotSupported) || confirmations == 0 {
+		return blockNum, err
+	}
+	if !r.loggedTagFallback {
+		log.Warn("parent chain doesn't support the read mode's block tag, falling back to confirmations", "readMode", readMode, "confirmations", confirmations, "err", err)
+		r.loggedTagFallback = true
+	}
+	header, err := r.l1Reader.LastHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return arbmath.SaturatingUSub(header.Number.Uint64(), confirmations), nil
+}
+
+// trackBatchBlocks remembers the parent chain blocks of the most recently read batches, so they can be checked for reorgs
+func (r *InboxReader) trackBatchBlocks(batches []*SequencerInboxBatch) {
+	limit := r.config().ReorgCheckBatches
+	if limit == 0 {
+		r.recentBatchBlocks = nil
+		return
+	}
+	for _, batch := range batches {
+		r.recentBatchBlocks = append(r.recentBatchBlocks, batchParentChainBlock{
+			batch:  batch.SequenceNumber,
+			number: batch.ParentChainBlockNumber,
+			hash:   batch.BlockHash,
+		})
+	}
+	if uint64(len(r.recentBatchBlocks)) > limit {
+		r.recentBatchBlocks = r.recentBatchBlocks[uint64(len(r.recentBatchBlocks))-limit:]
+	}
+}
+
+// rollBackParentChainReorg checks whether the parent chain blocks of recently read batches are still canonical.
+// If not, it rolls the inbox tracker and transaction streamer back to before the first reorged batch and
+// any delayed messages read from its block or later, returning the block to re-read from.
+func (r *InboxReader) rollBackParentChainReorg(ctx context.Context) (*big.Int, error) {
+	if r.config().ReorgCheckBatches == 0 || len(r.recentBatchBlocks) == 0 {
+		return nil, nil
+	}
+	// The parent chain's blocks are linked, so if a block is still canonical so are those before it.
+	// Walk back from the most recent batch to find the first one which was reorged out.
+	reorged := -1
+	checkedNumber, checkedCanonical := uint64(math.MaxUint64), false
+	for i := len(r.recentBatchBlocks) - 1; i >= 0; i-- {
+		recent := r.recentBatchBlocks[i]
+		if recent.number != checkedNumber {
+			header, err := r.client.HeaderByNumber(ctx, new(big.Int).SetUint64(recent.number))
+			if err != nil && !errors.Is(err, ethereum.NotFound) {
+				return nil, err
+			}
+			checkedNumber = recent.number
+			checkedCanonical = err == nil && header.Hash() == recent.hash
+		}
+		if checkedCanonical {
+			break
+		}
+		reorged = i
+	}
+	if reorged < 0 {
+		return nil, nil
+	}
+	divergence := r.recentBatchBlocks[reorged]
+	r.recentBatchBlocks = r.recentBatchBlocks[:reorged]
+	parentChainReorgsCounter.Inc(1)
+
+	batchCount, err := r.tracker.GetBatchCount()
+	if err != nil {
+		return nil, err
+	}
+	if batchCount > divergence.batch {
+		log.Warn("parent chain reorg detected, rolling back sequencer batches", "block", divergence.number, "hash", divergence.hash, "batchCount", batchCount, "newBatchCount", divergence.batch)
+		err = r.tracker.ReorgBatchesTo(divergence.batch)
+		if err != nil {
+			return nil, err
+		}
+	}
+	delayedCount, err := r.tracker.GetDelayedCount()
+	if err != nil {
+		return nil, err
+	}
+	newDelayedCount := delayedCount
+	for newDelayedCount > 0 {
+		_, _, parentChainBlock, err := r.tracker.GetDelayedMessageAccumulatorAndParentChainBlockNumber(newDelayedCount - 1)
+		if err != nil {
+			return nil, err
+		}
+		if parentChainBlock < divergence.number {
+			break
+		}
+		newDelayedCount--
+	}
+	if newDelayedCount < delayedCount {
+		log.Warn("parent chain reorg detected, rolling back delayed messages", "block", divergence.number, "hash", divergence.hash, "delayedCount", delayedCount, "newDelayedCount", newDelayedCount)
+		err = r.tracker.ReorgDelayedTo(newDelayedCount, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	from := new(big.Int).SetUint64(divergence.number)
+	if from.Cmp(r.firstMessageBlock) < 0 {
+		from.Set(r.firstMessageBlock)
+	}
+	return from, nil
+}
+
 func (r *InboxReader) addMessages(ctx context.Context, sequencerBatches []*SequencerInboxBatch, delayedMessages []*DelayedInboxMessage) (bool, error) {
 	err := r.tracker.AddDelayedMessages(delayedMessages, r.config().HardReorg)
 	if err != nil {
@@ -581,6 +759,16 @@ func (r *InboxReader) addMessages(ctx context.Context, sequencerBatches []*Seque
 	} else if err != nil {
 		return false, err
 	}
+	if r.batchCache != nil {
+		for _, batch := range sequencerBatches {
+			// Already serialized by the tracker, so this doesn't fetch anything
+			data, err := batch.Serialize(ctx, r.client)
+			if err != nil {
+				return false, err
+			}
+			r.batchCache.Add(batch.AfterInboxAcc, cachedBatch{data, batch.BlockHash})
+		}
+	}
 	return false, nil
 }
 
@@ -619,6 +807,13 @@ func (r *InboxReader) GetSequencerMessageBytes(ctx context.Context, seqNum uint6
 	if err != nil {
 		return nil, common.Hash{}, err
 	}
+	if r.batchCache != nil {
+		if cached, ok := r.batchCache.Get(metadata.Accumulator); ok {
+			batchCacheHitCounter.Inc(1)
+			return cached.data, cached.blockHash, nil
+		}
+		batchCacheMissCounter.Inc(1)
+	}
 	blockNum := arbmath.UintToBig(metadata.ParentChainBlock)
 	seqBatches, err := r.sequencerInbox.LookupBatchesInRange(ctx, blockNum, blockNum)
 	if err != nil {
@@ -628,6 +823,9 @@ func (r *InboxReader) GetSequencerMessageBytes(ctx context.Context, seqNum uint6
 	for _, batch := range seqBatches {
 		if batch.SequenceNumber == seqNum {
 			data, err := batch.Serialize(ctx, r.client)
+			if err == nil && r.batchCache != nil && batch.AfterInboxAcc == metadata.Accumulator {
+				r.batchCache.Add(batch.AfterInboxAcc, cachedBatch{data, batch.BlockHash})
+			}
 			return data, batch.BlockHash, err
 		}
 		seenBatches = append(seenBatches, batch.SequenceNumber)
@@ -40,6 +40,9 @@ type InboxTracker struct {
 	das        arbstate.DataAvailabilityReader
 	blobReader arbstate.BlobReader
 
+	keysetValidator arbstate.KeysetHashValidator
+	celestiaReader  arbstate.CelestiaReader
+
 	batchMetaMutex sync.Mutex
 	batchMeta      *containers.LruCache[uint64, BatchMetadata]
 }
@@ -63,6 +66,14 @@ func (t *InboxTracker) SetBlockValidator(validator *staker.BlockValidator) {
 	t.validator = validator
 }
 
+func (t *InboxTracker) SetKeysetValidator(keysetValidator arbstate.KeysetHashValidator) {
+	t.keysetValidator = keysetValidator
+}
+
+func (t *InboxTracker) SetCelestiaReader(celestiaReader arbstate.CelestiaReader) {
+	t.celestiaReader = celestiaReader
+}
+
 func (t *InboxTracker) Initialize() error {
 	batch := t.db.NewBatch()
 
@@ -210,6 +221,33 @@ func (t *InboxTracker) GetBatchAcc(seqNum uint64) (common.Hash, error) {
 	return metadata.Accumulator, err
 }
 
+// GetFirstRetainedBatch returns the first batch whose metadata hasn't been pruned.
+// The metadata of earlier batches, except possibly the first, is no longer available.
+func (t *InboxTracker) GetFirstRetainedBatch() (uint64, error) {
+	hasKey, err := t.db.Has(firstRetainedBatchKey)
+	if err != nil || !hasKey {
+		return 0, err
+	}
+	data, err := t.db.Get(firstRetainedBatchKey)
+	if err != nil {
+		return 0, err
+	}
+	var batch uint64
+	err = rlp.DecodeBytes(data, &batch)
+	if err != nil {
+		return 0, err
+	}
+	return batch, nil
+}
+
+func (t *InboxTracker) setFirstRetainedBatch(batch uint64) error {
+	data, err := rlp.EncodeToBytes(batch)
+	if err != nil {
+		return err
+	}
+	return t.db.Put(firstRetainedBatchKey, data)
+}
+
 func (t *InboxTracker) GetBatchCount() (uint64, error) {
 	data, err := t.db.Get(sequencerBatchCountKey)
 	if err != nil {
@@ -608,11 +646,14 @@ func (t *InboxTracker) AddSequencerBatches(ctx context.Context, client arbutil.L
 	}
 	var daProviders []arbstate.DataAvailabilityProvider
 	if t.das != nil {
-		daProviders = append(daProviders, arbstate.NewDAProviderDAS(t.das))
+		daProviders = append(daProviders, arbstate.NewDAProviderDASWithKeysetValidator(t.das, t.keysetValidator))
 	}
 	if t.blobReader != nil {
 		daProviders = append(daProviders, arbstate.NewDAProviderBlobReader(t.blobReader))
 	}
+	if t.celestiaReader != nil {
+		daProviders = append(daProviders, arbstate.NewDAProviderCelestia(t.celestiaReader))
+	}
 	multiplexer := arbstate.NewInboxMultiplexer(backend, prevbatchmeta.DelayedMessageCount, daProviders, arbstate.KeysetValidate)
 	batchMessageCounts := make(map[uint64]arbutil.MessageIndex)
 	currentpos := prevbatchmeta.MessageCount + 1
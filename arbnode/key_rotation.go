@@ -0,0 +1,170 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/cmd/chaininfo"
+	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
+	"github.com/offchainlabs/nitro/solgen/go/rollupgen"
+	"github.com/offchainlabs/nitro/solgen/go/upgrade_executorgen"
+)
+
+// Rotating a batch poster or validator key without downtime takes these steps, which cmd/rotate-key walks through:
+//  1. Whitelist the new key, by having the rollup owner execute the calldata from KeyRotationCalldata.
+//  2. Drain the old key: call arbbatchposter_drain on the batch poster's authenticated RPC so it stops posting new batches
+//     (validators can simply be stopped, as they don't need to act continuously).
+//  3. Wait for the old key to be drained: arbbatchposter_drainStatus reports once the batch poster's posted
+//     batches are all confirmed, at which point it also releases its redis lock, and CheckKeyRotation
+//     reports once the old key has no transactions left in the parent chain's mempool.
+//  4. Switch over to the new key, by starting a node with it (a standby batch poster sharing the
+//     redis lock takes over immediately), and stop the node using the old key.
+//  5. Remove the old key from the whitelist, again with calldata from KeyRotationCalldata.
+
+type KeyRotationRole string
+
+const (
+	KeyRotationBatchPoster KeyRotationRole = "batch-poster"
+	KeyRotationValidator   KeyRotationRole = "validator"
+)
+
+func ParseKeyRotationRole(role string) (KeyRotationRole, error) {
+	switch KeyRotationRole(role) {
+	case KeyRotationBatchPoster, KeyRotationValidator:
+		return KeyRotationRole(role), nil
+	default:
+		return "", fmt.Errorf("invalid key rotation role \"%v\" (must be %v or %v)", role, KeyRotationBatchPoster, KeyRotationValidator)
+	}
+}
+
+// KeyRotationCalldata returns the contract to call and the calldata to whitelist or un-whitelist a key,
+// along with the calldata that the rollup owner should send to the upgrade executor to make that call.
+func KeyRotationCalldata(role KeyRotationRole, deployInfo *chaininfo.RollupAddresses, key common.Address, whitelisted bool) (common.Address, []byte, []byte, error) {
+	var target common.Address
+	var calldata []byte
+	switch role {
+	case KeyRotationBatchPoster:
+		seqInboxABI, err := abi.JSON(strings.NewReader(bridgegen.SequencerInboxABI))
+		if err != nil {
+			return common.Address{}, nil, nil, err
+		}
+		target = deployInfo.SequencerInbox
+		calldata, err = seqInboxABI.Pack("setIsBatchPoster", key, whitelisted)
+		if err != nil {
+			return common.Address{}, nil, nil, err
+		}
+	case KeyRotationValidator:
+		rollupABI, err := abi.JSON(strings.NewReader(rollupgen.RollupAdminLogicABI))
+		if err != nil {
+			return common.Address{}, nil, nil, err
+		}
+		target = deployInfo.Rollup
+		calldata, err = rollupABI.Pack("setValidator", []common.Address{key}, []bool{whitelisted})
+		if err != nil {
+			return common.Address{}, nil, nil, err
+		}
+	default:
+		return common.Address{}, nil, nil, fmt.Errorf("unknown key rotation role %v", role)
+	}
+	executorABI, err := abi.JSON(strings.NewReader(upgrade_executorgen.UpgradeExecutorABI))
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	executorCalldata, err := executorABI.Pack("executeCall", target, calldata)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	return target, calldata, executorCalldata, nil
+}
+
+type KeyStatus struct {
+	Address     common.Address `json:"address"`
+	Whitelisted bool           `json:"whitelisted"`
+	Nonce       uint64         `json:"nonce"`
+	// PendingNonce is above Nonce while the key has transactions that haven't been included yet
+	PendingNonce uint64   `json:"pendingNonce"`
+	Balance      *big.Int `json:"balance"`
+}
+
+func (s *KeyStatus) Drained() bool {
+	return s.PendingNonce <= s.Nonce
+}
+
+type KeyRotationStatus struct {
+	Role KeyRotationRole `json:"role"`
+	Old  KeyStatus       `json:"old"`
+	New  KeyStatus       `json:"new"`
+}
+
+// ReadyToSwitch returns whether the new key can take over: it must be whitelisted and the old key must have
+// no transactions in flight, or the two could post conflicting transactions.
+func (s *KeyRotationStatus) ReadyToSwitch() bool {
+	return s.New.Whitelisted && s.Old.Drained()
+}
+
+// CheckKeyRotation reads the parent chain's view of the old and new keys
+func CheckKeyRotation(ctx context.Context, client arbutil.L1Interface, role KeyRotationRole, deployInfo *chaininfo.RollupAddresses, oldKey common.Address, newKey common.Address) (*KeyRotationStatus, error) {
+	var isWhitelisted func(common.Address) (bool, error)
+	callOpts := &bind.CallOpts{Context: ctx}
+	switch role {
+	case KeyRotationBatchPoster:
+		seqInbox, err := bridgegen.NewSequencerInboxCaller(deployInfo.SequencerInbox, client)
+		if err != nil {
+			return nil, err
+		}
+		isWhitelisted = func(addr common.Address) (bool, error) {
+			return seqInbox.IsBatchPoster(callOpts, addr)
+		}
+	case KeyRotationValidator:
+		rollup, err := rollupgen.NewRollupUserLogicCaller(deployInfo.Rollup, client)
+		if err != nil {
+			return nil, err
+		}
+		isWhitelisted = func(addr common.Address) (bool, error) {
+			return rollup.IsValidator(callOpts, addr)
+		}
+	default:
+		return nil, fmt.Errorf("unknown key rotation role %v", role)
+	}
+	keyStatus := func(addr common.Address) (KeyStatus, error) {
+		status := KeyStatus{Address: addr}
+		var err error
+		status.Whitelisted, err = isWhitelisted(addr)
+		if err != nil {
+			return status, err
+		}
+		status.Nonce, err = client.NonceAt(ctx, addr, nil)
+		if err != nil {
+			return status, err
+		}
+		status.PendingNonce, err = client.PendingNonceAt(ctx, addr)
+		if err != nil {
+			return status, err
+		}
+		status.Balance, err = client.BalanceAt(ctx, addr, nil)
+		return status, err
+	}
+	oldStatus, err := keyStatus(oldKey)
+	if err != nil {
+		return nil, fmt.Errorf("checking old key %v: %w", oldKey, err)
+	}
+	newStatus, err := keyStatus(newKey)
+	if err != nil {
+		return nil, fmt.Errorf("checking new key %v: %w", newKey, err)
+	}
+	return &KeyRotationStatus{
+		Role: role,
+		Old:  oldStatus,
+		New:  newStatus,
+	}, nil
+}
@@ -0,0 +1,337 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/signature"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	auditedMessageCountGauge = metrics.NewRegisteredGauge("arb/messageaudit/count", nil)
+	messageAuditErrorCounter = metrics.NewRegisteredCounter("arb/messageaudit/errors", nil)
+)
+
+type MessageAuditConfig struct {
+	Enable         bool          `koanf:"enable"`
+	KafkaRestURL   string        `koanf:"kafka-rest-url"`
+	Topic          string        `koanf:"topic"`
+	BatchSize      uint64        `koanf:"batch-size" reload:"hot"`
+	PollInterval   time.Duration `koanf:"poll-interval" reload:"hot"`
+	RequestTimeout time.Duration `koanf:"request-timeout" reload:"hot"`
+}
+
+type MessageAuditConfigFetcher func() *MessageAuditConfig
+
+var DefaultMessageAuditConfig = MessageAuditConfig{
+	Enable:         false,
+	KafkaRestURL:   "",
+	Topic:          "",
+	BatchSize:      100,
+	PollInterval:   time.Second,
+	RequestTimeout: 30 * time.Second,
+}
+
+func MessageAuditConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultMessageAuditConfig.Enable, "enable mirroring every sequenced message into a Kafka topic for an independent audit trail")
+	f.String(prefix+".kafka-rest-url", DefaultMessageAuditConfig.KafkaRestURL, "URL of the Kafka REST proxy to produce audit records through")
+	f.String(prefix+".topic", DefaultMessageAuditConfig.Topic, "Kafka topic to produce audit records to")
+	f.Uint64(prefix+".batch-size", DefaultMessageAuditConfig.BatchSize, "maximum number of messages to produce in a single request")
+	f.Duration(prefix+".poll-interval", DefaultMessageAuditConfig.PollInterval, "how often to check for new messages to produce once caught up")
+	f.Duration(prefix+".request-timeout", DefaultMessageAuditConfig.RequestTimeout, "timeout for requests to the Kafka REST proxy")
+}
+
+func (c *MessageAuditConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.KafkaRestURL == "" || c.Topic == "" {
+		return errors.New("message audit requires a Kafka REST proxy URL and topic")
+	}
+	if _, err := url.Parse(c.KafkaRestURL); err != nil {
+		return fmt.Errorf("invalid message audit Kafka REST proxy URL: %w", err)
+	}
+	if c.BatchSize == 0 {
+		return errors.New("message audit batch size must be positive")
+	}
+	return nil
+}
+
+// AuditedMessage is the record produced for each sequenced message. Signature is made by the node's
+// data signer over Hash, the same way as for feed messages, so it can be checked against the feed's key.
+type AuditedMessage struct {
+	ChainId        uint64                         `json:"chainId"`
+	SequenceNumber arbutil.MessageIndex           `json:"sequenceNumber"`
+	Hash           common.Hash                    `json:"hash"`
+	Message        arbostypes.MessageWithMetadata `json:"message"`
+	Signature      hexutil.Bytes                  `json:"signature,omitempty"`
+}
+
+// Key identifies the record, so that producing it again after a failure or restart is idempotent,
+// while a different message at the same position after a reorg gets a record of its own.
+func (m *AuditedMessage) Key() string {
+	return fmt.Sprintf("%d:%d:%v", m.ChainId, m.SequenceNumber, m.Hash)
+}
+
+type MessageAuditSink interface {
+	// Publish must only return once every message is durably stored, in order
+	Publish(ctx context.Context, messages []*AuditedMessage) error
+}
+
+// MessageAuditor mirrors every message in the transaction streamer, in order, into a MessageAuditSink.
+// Its progress is only persisted once a sink accepts the messages, so each message is delivered at least
+// once, and records' keys let consumers or compacted topics drop the duplicates for exactly-once results.
+// Reorged messages aren't retracted, as the audit trail is append-only, but their replacements are produced.
+type MessageAuditor struct {
+	stopwaiter.StopWaiter
+	config     MessageAuditConfigFetcher
+	db         ethdb.Database
+	streamer   *TransactionStreamer
+	sink       MessageAuditSink
+	chainId    uint64
+	dataSigner signature.DataSignerFunc
+
+	// countMutex guards the persisted audited message count against reorgs while messages are published
+	countMutex sync.Mutex
+	reorgs     uint64
+}
+
+func NewMessageAuditor(db ethdb.Database, streamer *TransactionStreamer, sink MessageAuditSink, chainId uint64, dataSigner signature.DataSignerFunc, config MessageAuditConfigFetcher) *MessageAuditor {
+	return &MessageAuditor{
+		config:     config,
+		db:         db,
+		streamer:   streamer,
+		sink:       sink,
+		chainId:    chainId,
+		dataSigner: dataSigner,
+	}
+}
+
+func (a *MessageAuditor) Start(ctxIn context.Context) {
+	a.StopWaiter.Start(ctxIn, a)
+	a.CallIteratively(a.publishMessages)
+}
+
+func (a *MessageAuditor) getAuditedMessageCount() (arbutil.MessageIndex, error) {
+	has, err := a.db.Has(auditedMessageCountKey)
+	if err != nil || !has {
+		return 0, err
+	}
+	countBytes, err := a.db.Get(auditedMessageCountKey)
+	if err != nil {
+		return 0, err
+	}
+	var count uint64
+	if err := rlp.DecodeBytes(countBytes, &count); err != nil {
+		return 0, err
+	}
+	return arbutil.MessageIndex(count), nil
+}
+
+func (a *MessageAuditor) setAuditedMessageCount(count arbutil.MessageIndex) error {
+	countBytes, err := rlp.EncodeToBytes(uint64(count))
+	if err != nil {
+		return err
+	}
+	if err := a.db.Put(auditedMessageCountKey, countBytes); err != nil {
+		return err
+	}
+	auditedMessageCountGauge.Update(int64(count))
+	return nil
+}
+
+// Reorg makes the auditor produce messages again from count, as the ones after it are being replaced
+func (a *MessageAuditor) Reorg(count arbutil.MessageIndex) error {
+	a.countMutex.Lock()
+	defer a.countMutex.Unlock()
+	a.reorgs++
+	audited, err := a.getAuditedMessageCount()
+	if err != nil {
+		return err
+	}
+	if audited <= count {
+		return nil
+	}
+	log.Info("message auditor producing reorged messages again", "from", count, "audited", audited)
+	return a.setAuditedMessageCount(count)
+}
+
+func (a *MessageAuditor) auditedMessage(pos arbutil.MessageIndex) (*AuditedMessage, error) {
+	msg, err := a.streamer.GetMessage(pos)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := msg.Hash(pos, a.chainId)
+	if err != nil {
+		return nil, err
+	}
+	audited := &AuditedMessage{
+		ChainId:        a.chainId,
+		SequenceNumber: pos,
+		Hash:           hash,
+		Message:        *msg,
+	}
+	if a.dataSigner != nil {
+		audited.Signature, err = a.dataSigner(hash.Bytes())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return audited, nil
+}
+
+func (a *MessageAuditor) publishMessages(ctx context.Context) time.Duration {
+	config := a.config()
+	a.countMutex.Lock()
+	reorgs := a.reorgs
+	audited, err := a.getAuditedMessageCount()
+	a.countMutex.Unlock()
+	if err != nil {
+		log.Error("message auditor failed to get audited message count", "err", err)
+		return config.PollInterval
+	}
+	count, err := a.streamer.GetMessageCount()
+	if err != nil {
+		log.Error("message auditor failed to get message count", "err", err)
+		return config.PollInterval
+	}
+	if audited >= count {
+		return config.PollInterval
+	}
+	end := count
+	if end-audited > arbutil.MessageIndex(config.BatchSize) {
+		end = audited + arbutil.MessageIndex(config.BatchSize)
+	}
+	messages := make([]*AuditedMessage, 0, end-audited)
+	for pos := audited; pos < end; pos++ {
+		msg, err := a.auditedMessage(pos)
+		if err != nil {
+			// the message may have been reorged out, or pruned if the auditor has fallen far behind
+			log.Error("message auditor failed to read message", "pos", pos, "err", err)
+			messageAuditErrorCounter.Inc(1)
+			return config.PollInterval
+		}
+		messages = append(messages, msg)
+	}
+	publishCtx, cancel := context.WithTimeout(ctx, config.RequestTimeout)
+	err = a.sink.Publish(publishCtx, messages)
+	cancel()
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Warn("message auditor failed to publish messages", "from", audited, "to", end, "err", err)
+			messageAuditErrorCounter.Inc(1)
+		}
+		return config.PollInterval
+	}
+
+	a.countMutex.Lock()
+	defer a.countMutex.Unlock()
+	if a.reorgs != reorgs {
+		// some of the messages may have been replaced while being published
+		return 0
+	}
+	if err := a.setAuditedMessageCount(end); err != nil {
+		log.Error("message auditor failed to persist audited message count", "count", end, "err", err)
+		return config.PollInterval
+	}
+	if end < count {
+		return 0
+	}
+	return config.PollInterval
+}
+
+// KafkaRestSink produces audit records through a Kafka REST proxy, keyed by AuditedMessage.Key
+type KafkaRestSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewKafkaRestSink(restURL string, topic string) *KafkaRestSink {
+	return &KafkaRestSink{
+		url:    strings.TrimSuffix(restURL, "/") + "/topics/" + url.PathEscape(topic),
+		client: &http.Client{},
+	}
+}
+
+type kafkaRestRecord struct {
+	Key   string          `json:"key"`
+	Value *AuditedMessage `json:"value"`
+}
+
+type kafkaRestResponse struct {
+	Offsets []struct {
+		Partition *int32  `json:"partition"`
+		Offset    *int64  `json:"offset"`
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+}
+
+func (s *KafkaRestSink) Publish(ctx context.Context, messages []*AuditedMessage) error {
+	records := make([]kafkaRestRecord, 0, len(messages))
+	for _, msg := range messages {
+		records = append(records, kafkaRestRecord{Key: msg.Key(), Value: msg})
+	}
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("kafka REST proxy returned status %v: %v", resp.StatusCode, string(respBody))
+	}
+	var result kafkaRestResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("invalid kafka REST proxy response: %w", err)
+	}
+	if len(result.Offsets) != len(records) {
+		return fmt.Errorf("kafka REST proxy acknowledged %v records but %v were produced", len(result.Offsets), len(records))
+	}
+	for i, offset := range result.Offsets {
+		if offset.Error != nil || offset.ErrorCode != nil {
+			var errMsg string
+			if offset.Error != nil {
+				errMsg = *offset.Error
+			}
+			return fmt.Errorf("kafka REST proxy failed to produce record %v: %v", records[i].Key, errMsg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+type recordingAuditSink struct {
+	published []*AuditedMessage
+	fail      bool
+}
+
+func (s *recordingAuditSink) Publish(ctx context.Context, messages []*AuditedMessage) error {
+	if s.fail {
+		return errors.New("sink unavailable")
+	}
+	s.published = append(s.published, messages...)
+	return nil
+}
+
+func writeAuditTestMessages(t *testing.T, db ethdb.Database, from, to uint64, l2msg byte) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		msg := arbostypes.MessageWithMetadata{
+			Message: &arbostypes.L1IncomingMessage{
+				Header: &arbostypes.L1IncomingMessageHeader{},
+				L2msg:  []byte{l2msg, byte(i)},
+			},
+		}
+		data, err := rlp.EncodeToBytes(msg)
+		Require(t, err)
+		Require(t, db.Put(dbKey(messagePrefix, i), data))
+	}
+	Require(t, setMessageCount(db, arbutil.MessageIndex(to)))
+}
+
+func TestMessageAuditor(t *testing.T) {
+	ctx := context.Background()
+	db := rawdb.NewMemoryDatabase()
+	writeAuditTestMessages(t, db, 0, 5, 0)
+	sink := &recordingAuditSink{}
+	config := DefaultMessageAuditConfig
+	config.BatchSize = 3
+	auditor := NewMessageAuditor(db, &TransactionStreamer{db: db}, sink, 412346, nil, func() *MessageAuditConfig { return &config })
+
+	expectAudited := func(count arbutil.MessageIndex, published int) {
+		t.Helper()
+		audited, err := auditor.getAuditedMessageCount()
+		Require(t, err)
+		if audited != count {
+			Fail(t, "audited", audited, "messages but expected", count)
+		}
+		if len(sink.published) != published {
+			Fail(t, "published", len(sink.published), "records but expected", published)
+		}
+	}
+
+	sink.fail = true
+	auditor.publishMessages(ctx)
+	expectAudited(0, 0)
+	sink.fail = false
+
+	if delay := auditor.publishMessages(ctx); delay != 0 {
+		Fail(t, "expected to continue immediately with messages remaining but got delay", delay)
+	}
+	expectAudited(3, 3)
+	auditor.publishMessages(ctx)
+	expectAudited(5, 5)
+	auditor.publishMessages(ctx)
+	expectAudited(5, 5)
+	for i, msg := range sink.published {
+		if msg.SequenceNumber != arbutil.MessageIndex(i) {
+			Fail(t, "record", i, "has sequence number", msg.SequenceNumber)
+		}
+		hash, err := msg.Message.Hash(msg.SequenceNumber, 412346)
+		Require(t, err)
+		if msg.Hash != hash || msg.Key() != fmt.Sprintf("412346:%d:%v", i, hash) {
+			Fail(t, "record", i, "has unexpected hash", msg.Hash, "or key", msg.Key())
+		}
+	}
+
+	// Reorged messages are produced again with new keys
+	Require(t, auditor.Reorg(3))
+	writeAuditTestMessages(t, db, 3, 6, 1)
+	expectAudited(3, 5)
+	auditor.publishMessages(ctx)
+	expectAudited(6, 8)
+	if sink.published[5].SequenceNumber != 3 || sink.published[5].Key() == sink.published[3].Key() {
+		Fail(t, "reorged message produced with key", sink.published[5].Key())
+	}
+
+	// A reorg beyond the audited messages leaves the count alone
+	Require(t, auditor.Reorg(10))
+	expectAudited(6, 8)
+}
+
+func TestKafkaRestSink(t *testing.T) {
+	var requests int
+	var failRecord bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Method != http.MethodPost || r.URL.Path != "/topics/audit" {
+			Fail(t, "unexpected request", r.Method, r.URL.Path)
+		}
+		if contentType := r.Header.Get("Content-Type"); contentType != "application/vnd.kafka.json.v2+json" {
+			Fail(t, "unexpected content type", contentType)
+		}
+		var body struct {
+			Records []struct {
+				Key   string         `json:"key"`
+				Value AuditedMessage `json:"value"`
+			} `json:"records"`
+		}
+		Require(t, json.NewDecoder(r.Body).Decode(&body))
+		offsets := []map[string]interface{}{}
+		for i, record := range body.Records {
+			if record.Key != record.Value.Key() {
+				Fail(t, "record key", record.Key, "doesn't match value", record.Value.Key())
+			}
+			offset := map[string]interface{}{"partition": 0, "offset": i}
+			if failRecord && i == len(body.Records)-1 {
+				offset = map[string]interface{}{"error_code": 50003, "error": "timed out"}
+			}
+			offsets = append(offsets, offset)
+		}
+		Require(t, json.NewEncoder(w).Encode(map[string]interface{}{"offsets": offsets}))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	sink := NewKafkaRestSink(server.URL+"/", "audit")
+	messages := []*AuditedMessage{
+		{ChainId: 1, SequenceNumber: 0, Message: arbostypes.MessageWithMetadata{Message: &arbostypes.EmptyTestIncomingMessage}},
+		{ChainId: 1, SequenceNumber: 1, Message: arbostypes.MessageWithMetadata{Message: &arbostypes.EmptyTestIncomingMessage}},
+	}
+	Require(t, sink.Publish(ctx, messages))
+	failRecord = true
+	if err := sink.Publish(ctx, messages); err == nil {
+		Fail(t, "expected a record failing to be produced to fail the publish")
+	}
+	if requests != 2 {
+		Fail(t, "made", requests, "requests but expected 2")
+	}
+}
@@ -23,34 +23,41 @@ import (
 
 type MessagePruner struct {
 	stopwaiter.StopWaiter
-	transactionStreamer         *TransactionStreamer
-	inboxTracker                *InboxTracker
-	config                      MessagePrunerConfigFetcher
-	pruningLock                 sync.Mutex
-	lastPruneDone               time.Time
-	cachedPrunedMessages        uint64
-	cachedPrunedDelayedMessages uint64
+	transactionStreamer                 *TransactionStreamer
+	inboxTracker                        *InboxTracker
+	config                              MessagePrunerConfigFetcher
+	pruningLock                         sync.Mutex
+	lastPruneDone                       time.Time
+	cachedPrunedMessages                uint64
+	cachedPrunedDelayedMessages         uint64
+	cachedPrunedBatchMetadata           uint64
+	cachedPrunedParentChainBlockNumbers uint64
+	cachedPrunedLegacyDelayedMessages   uint64
+	cachedPrunedDelayedSequenced        uint64
 }
 
 type MessagePrunerConfig struct {
 	Enable bool `koanf:"enable"`
 	// Message pruning interval.
-	PruneInterval  time.Duration `koanf:"prune-interval" reload:"hot"`
-	MinBatchesLeft uint64        `koanf:"min-batches-left" reload:"hot"`
+	PruneInterval      time.Duration `koanf:"prune-interval" reload:"hot"`
+	MinBatchesLeft     uint64        `koanf:"min-batches-left" reload:"hot"`
+	PruneInboxMetadata bool          `koanf:"prune-inbox-metadata" reload:"hot"`
 }
 
 type MessagePrunerConfigFetcher func() *MessagePrunerConfig
 
 var DefaultMessagePrunerConfig = MessagePrunerConfig{
-	Enable:         true,
-	PruneInterval:  time.Minute,
-	MinBatchesLeft: 2,
+	Enable:             true,
+	PruneInterval:      time.Minute,
+	MinBatchesLeft:     2,
+	PruneInboxMetadata: false,
 }
 
 func MessagePrunerConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultMessagePrunerConfig.Enable, "enable message pruning")
 	f.Duration(prefix+".prune-interval", DefaultMessagePrunerConfig.PruneInterval, "interval for running message pruner")
 	f.Uint64(prefix+".min-batches-left", DefaultMessagePrunerConfig.MinBatchesLeft, "min number of batches not pruned")
+	f.Bool(prefix+".prune-inbox-metadata", DefaultMessagePrunerConfig.PruneInboxMetadata, "also prune the batch and delayed message metadata of pruned messages (looking up the batch of a pruned message won't be possible)")
 }
 
 func NewMessagePruner(transactionStreamer *TransactionStreamer, inboxTracker *InboxTracker, config MessagePrunerConfigFetcher) *MessagePruner {
@@ -111,7 +118,14 @@ func (m *MessagePruner) prune(ctx context.Context, count arbutil.MessageIndex, g
 	msgCount := endBatchMetadata.MessageCount
 	delayedCount := endBatchMetadata.DelayedMessageCount
 
-	return m.deleteOldMessagesFromDB(ctx, msgCount, delayedCount)
+	err = m.deleteOldMessagesFromDB(ctx, msgCount, delayedCount)
+	if err != nil {
+		return err
+	}
+	if !m.config().PruneInboxMetadata {
+		return nil
+	}
+	return m.deleteOldInboxMetadataFromDB(ctx, trimBatchCount, delayedCount)
 }
 
 func (m *MessagePruner) deleteOldMessagesFromDB(ctx context.Context, messageCount arbutil.MessageIndex, delayedMessageCount uint64) error {
@@ -133,6 +147,51 @@ func (m *MessagePruner) deleteOldMessagesFromDB(ctx context.Context, messageCoun
 	return nil
 }
 
+// deleteOldInboxMetadataFromDB deletes the metadata of batches before the last one in batchCount, which is
+// kept as the first retained batch, and the metadata of delayed messages deleted with delayedMessageCount
+func (m *MessagePruner) deleteOldInboxMetadataFromDB(ctx context.Context, batchCount uint64, delayedMessageCount uint64) error {
+	if batchCount < 1 {
+		return nil
+	}
+	// Record the first retained batch before deleting, so the batch search never reads deleted metadata
+	firstRetained, err := m.inboxTracker.GetFirstRetainedBatch()
+	if err != nil {
+		return err
+	}
+	if batchCount-1 > firstRetained {
+		err = m.inboxTracker.setFirstRetainedBatch(batchCount - 1)
+		if err != nil {
+			return err
+		}
+	}
+	prunedKeysRange, err := deleteFromLastPrunedUptoEndKey(ctx, m.inboxTracker.db, sequencerBatchMetaPrefix, &m.cachedPrunedBatchMetadata, batchCount)
+	if err != nil {
+		return fmt.Errorf("error deleting batch metadata: %w", err)
+	}
+	if len(prunedKeysRange) > 0 {
+		log.Info("Pruned batch metadata:", "first pruned key", prunedKeysRange[0], "last pruned key", prunedKeysRange[len(prunedKeysRange)-1])
+	}
+
+	delayedMetadata := []struct {
+		prefix []byte
+		cached *uint64
+	}{
+		{parentChainBlockNumberPrefix, &m.cachedPrunedParentChainBlockNumbers},
+		{legacyDelayedMessagePrefix, &m.cachedPrunedLegacyDelayedMessages},
+		{delayedSequencedPrefix, &m.cachedPrunedDelayedSequenced},
+	}
+	for _, metadata := range delayedMetadata {
+		prunedKeysRange, err = deleteFromLastPrunedUptoEndKey(ctx, m.inboxTracker.db, metadata.prefix, metadata.cached, delayedMessageCount)
+		if err != nil {
+			return fmt.Errorf("error deleting delayed message metadata with prefix %q: %w", metadata.prefix, err)
+		}
+		if len(prunedKeysRange) > 0 {
+			log.Info("Pruned delayed message metadata:", "prefix", string(metadata.prefix), "first pruned key", prunedKeysRange[0], "last pruned key", prunedKeysRange[len(prunedKeysRange)-1])
+		}
+	}
+	return nil
+}
+
 // deleteFromLastPrunedUptoEndKey is similar to deleteFromRange but automatically populates the start key
 // cachedStartMinKey must not be nil. It's set to the new start key at the end of this function if successful.
 func deleteFromLastPrunedUptoEndKey(ctx context.Context, db ethdb.Database, prefix []byte, cachedStartMinKey *uint64, endMinKey uint64) ([]uint64, error) {
@@ -5,11 +5,14 @@ package arbnode
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/staker"
 )
 
 func TestMessagePrunerWithPruningEligibleMessagePresent(t *testing.T) {
@@ -75,6 +78,49 @@ func TestMessagePrunerWithNoPruningEligibleMessagePresent(t *testing.T) {
 
 }
 
+func TestMessagePrunerInboxMetadata(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	batchCount := uint64(10)
+	delayedCount := uint64(20)
+	inboxTrackerDb := rawdb.NewMemoryDatabase()
+	for i := uint64(0); i < batchCount; i++ {
+		// Each batch has 3 messages
+		metadata, err := rlp.EncodeToBytes(BatchMetadata{MessageCount: arbutil.MessageIndex(3 * (i + 1))})
+		Require(t, err)
+		Require(t, inboxTrackerDb.Put(dbKey(sequencerBatchMetaPrefix, i), metadata))
+	}
+	for i := uint64(0); i < delayedCount; i++ {
+		Require(t, inboxTrackerDb.Put(dbKey(parentChainBlockNumberPrefix, i), []byte{}))
+		Require(t, inboxTrackerDb.Put(dbKey(delayedSequencedPrefix, i), []byte{}))
+	}
+	tracker, err := NewInboxTracker(inboxTrackerDb, nil, nil, nil)
+	Require(t, err)
+	pruner := &MessagePruner{inboxTracker: tracker}
+
+	Require(t, pruner.deleteOldInboxMetadataFromDB(ctx, batchCount/2, delayedCount/2))
+	checkDbKeys(t, batchCount/2, inboxTrackerDb, sequencerBatchMetaPrefix)
+	checkDbKeys(t, delayedCount/2, inboxTrackerDb, parentChainBlockNumberPrefix)
+	checkDbKeys(t, delayedCount/2, inboxTrackerDb, delayedSequencedPrefix)
+	firstRetained, err := tracker.GetFirstRetainedBatch()
+	Require(t, err)
+	if firstRetained != batchCount/2-1 {
+		Fail(t, "first retained batch", firstRetained, "but expected", batchCount/2-1)
+	}
+
+	// Batches after the first retained one can still be found
+	batch, err := staker.FindBatchContainingMessageIndex(tracker, 20, batchCount)
+	Require(t, err)
+	if batch != 6 {
+		Fail(t, "message 20 found in batch", batch, "but expected batch 6")
+	}
+	_, err = staker.FindBatchContainingMessageIndex(tracker, 5, batchCount)
+	if !errors.Is(err, staker.ErrBatchMetadataPruned) {
+		Fail(t, "expected pruned batch metadata error but got", err)
+	}
+}
+
 func setupDatabase(t *testing.T, messageCount, delayedMessageCount uint64) (ethdb.Database, ethdb.Database, *MessagePruner) {
 
 	transactionStreamerDb := rawdb.NewMemoryDatabase()
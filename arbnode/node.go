@@ -87,6 +87,11 @@ type Config struct {
 	Staker              staker.L1ValidatorConfig    `koanf:"staker" reload:"hot"`
 	SeqCoordinator      SeqCoordinatorConfig        `koanf:"seq-coordinator"`
 	DataAvailability    das.DataAvailabilityConfig  `koanf:"data-availability"`
+	DAAuditor           DAAuditorConfig             `koanf:"da-auditor" reload:"hot"`
+	MessageAudit        MessageAuditConfig          `koanf:"message-audit" reload:"hot"`
+	BalanceMonitor      BalanceMonitorConfig        `koanf:"balance-monitor" reload:"hot"`
+	FeedVerifier        FeedVerifierConfig          `koanf:"feed-verifier" reload:"hot"`
+	UpgradeWatcher      RollupUpgradeWatcherConfig  `koanf:"rollup-upgrade-watcher" reload:"hot"`
 	SyncMonitor         SyncMonitorConfig           `koanf:"sync-monitor"`
 	Dangerous           DangerousConfig             `koanf:"dangerous"`
 	TransactionStreamer TransactionStreamerConfig   `koanf:"transaction-streamer" reload:"hot"`
@@ -108,6 +113,9 @@ func (c *Config) Validate() error {
 		c.Feed.Output.Enable = false
 		c.Feed.Input.URL = []string{}
 	}
+	if err := c.DelayedSequencer.Validate(); err != nil {
+		return err
+	}
 	if err := c.BlockValidator.Validate(); err != nil {
 		return err
 	}
@@ -126,6 +134,27 @@ func (c *Config) Validate() error {
 	if err := c.Staker.Validate(); err != nil {
 		return err
 	}
+	if err := c.DAAuditor.Validate(); err != nil {
+		return err
+	}
+	if err := c.MessageAudit.Validate(); err != nil {
+		return err
+	}
+	if err := c.BalanceMonitor.Validate(); err != nil {
+		return err
+	}
+	if err := c.FeedVerifier.Validate(); err != nil {
+		return err
+	}
+	if err := c.UpgradeWatcher.Validate(); err != nil {
+		return err
+	}
+	if err := c.ResourceMgmt.Validate(); err != nil {
+		return err
+	}
+	if err := c.DataAvailability.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -151,10 +180,16 @@ func ConfigAddOptions(prefix string, f *flag.FlagSet, feedInputEnable bool, feed
 	staker.L1ValidatorConfigAddOptions(prefix+".staker", f)
 	SeqCoordinatorConfigAddOptions(prefix+".seq-coordinator", f)
 	das.DataAvailabilityConfigAddNodeOptions(prefix+".data-availability", f)
+	DAAuditorConfigAddOptions(prefix+".da-auditor", f)
+	MessageAuditConfigAddOptions(prefix+".message-audit", f)
+	BalanceMonitorConfigAddOptions(prefix+".balance-monitor", f)
+	FeedVerifierConfigAddOptions(prefix+".feed-verifier", f)
+	RollupUpgradeWatcherConfigAddOptions(prefix+".rollup-upgrade-watcher", f)
 	SyncMonitorConfigAddOptions(prefix+".sync-monitor", f)
 	DangerousConfigAddOptions(prefix+".dangerous", f)
 	TransactionStreamerConfigAddOptions(prefix+".transaction-streamer", f)
 	MaintenanceConfigAddOptions(prefix+".maintenance", f)
+	resourcemanager.ConfigAddOptions(prefix+".resource-mgmt", f)
 }
 
 var ConfigDefault = Config{
@@ -169,6 +204,11 @@ var ConfigDefault = Config{
 	Staker:              staker.DefaultL1ValidatorConfig,
 	SeqCoordinator:      DefaultSeqCoordinatorConfig,
 	DataAvailability:    das.DefaultDataAvailabilityConfig,
+	DAAuditor:           DefaultDAAuditorConfig,
+	MessageAudit:        DefaultMessageAuditConfig,
+	BalanceMonitor:      DefaultBalanceMonitorConfig,
+	FeedVerifier:        DefaultFeedVerifierConfig,
+	UpgradeWatcher:      DefaultRollupUpgradeWatcherConfig,
 	SyncMonitor:         DefaultSyncMonitorConfig,
 	Dangerous:           DefaultDangerousConfig,
 	TransactionStreamer: DefaultTransactionStreamerConfig,
@@ -265,6 +305,11 @@ type Node struct {
 	SeqCoordinator          *SeqCoordinator
 	MaintenanceRunner       *MaintenanceRunner
 	DASLifecycleManager     *das.LifecycleManager
+	DAAuditor               *DAAuditor
+	MessageAuditor          *MessageAuditor
+	BalanceMonitor          *BalanceMonitor
+	FeedVerifier            *FeedVerifier
+	RollupUpgradeWatcher    *RollupUpgradeWatcher
 	ClassicOutboxRetriever  *ClassicOutboxRetriever
 	SyncMonitor             *SyncMonitor
 	configFetcher           ConfigFetcher
@@ -419,6 +464,17 @@ func createNodeImpl(
 	if err != nil {
 		return nil, err
 	}
+	var messageAuditor *MessageAuditor
+	if config.MessageAudit.Enable {
+		sink := NewKafkaRestSink(config.MessageAudit.KafkaRestURL, config.MessageAudit.Topic)
+		messageAuditor = NewMessageAuditor(arbDb, txStreamer, sink, l2ChainId, dataSigner, func() *MessageAuditConfig { return &configFetcher.Get().MessageAudit })
+		txStreamer.SetMessageAuditor(messageAuditor)
+	}
+	var feedVerifier *FeedVerifier
+	if config.FeedVerifier.Enable {
+		feedVerifier = NewFeedVerifier(l2ChainId, func() *FeedVerifierConfig { return &configFetcher.Get().FeedVerifier })
+		txStreamer.SetFeedVerifier(feedVerifier)
+	}
 	var coordinator *SeqCoordinator
 	var bpVerifier *contracts.AddressVerifier
 	if deployInfo != nil && l1client != nil {
@@ -488,6 +544,11 @@ func createNodeImpl(
 			SeqCoordinator:          coordinator,
 			MaintenanceRunner:       maintenanceRunner,
 			DASLifecycleManager:     nil,
+			DAAuditor:               nil,
+			MessageAuditor:          messageAuditor,
+			BalanceMonitor:          nil,
+			FeedVerifier:            feedVerifier,
+			RollupUpgradeWatcher:    nil,
 			ClassicOutboxRetriever:  classicOutbox,
 			SyncMonitor:             syncMonitor,
 			configFetcher:           configFetcher,
@@ -498,11 +559,12 @@ func createNodeImpl(
 	if deployInfo == nil {
 		return nil, errors.New("deployinfo is nil")
 	}
-	delayedBridge, err := NewDelayedBridge(l1client, deployInfo.Bridge, deployInfo.DeployedAt)
+	logsClient := NewParallelLogsClient(l1client, func() *InboxReaderConfig { return &configFetcher.Get().InboxReader })
+	delayedBridge, err := NewDelayedBridge(logsClient, deployInfo.Bridge, deployInfo.DeployedAt)
 	if err != nil {
 		return nil, err
 	}
-	sequencerInbox, err := NewSequencerInbox(l1client, deployInfo.SequencerInbox, int64(deployInfo.DeployedAt))
+	sequencerInbox, err := NewSequencerInbox(logsClient, deployInfo.SequencerInbox, int64(deployInfo.DeployedAt))
 	if err != nil {
 		return nil, err
 	}
@@ -510,7 +572,20 @@ func createNodeImpl(
 	var daWriter das.DataAvailabilityServiceWriter
 	var daReader das.DataAvailabilityServiceReader
 	var dasLifecycleManager *das.LifecycleManager
-	if config.DataAvailability.Enable {
+	var dapWriter das.DAProviderWriter
+	var celestiaReader arbstate.CelestiaReader
+	if config.DataAvailability.Enable && config.DataAvailability.Mode == das.DataAvailabilityModeCelestia {
+		celestiaDA, err := das.NewCelestiaDA(ctx, config.DataAvailability.Celestia)
+		if err != nil {
+			return nil, err
+		}
+		dasLifecycleManager = &das.LifecycleManager{}
+		dasLifecycleManager.Register(celestiaDA)
+		if config.BatchPoster.Enable {
+			dapWriter = celestiaDA
+		}
+		celestiaReader = celestiaDA
+	} else if config.DataAvailability.Enable {
 		if config.BatchPoster.Enable {
 			daWriter, daReader, dasLifecycleManager, err = das.CreateBatchPosterDAS(ctx, &config.DataAvailability, dataSigner, l1client, deployInfo.SequencerInbox)
 			if err != nil {
@@ -531,6 +606,14 @@ func createNodeImpl(
 			}
 			daReader = das.NewReaderPanicWrapper(daReader)
 		}
+
+		if config.DataAvailability.RestServer.Enable {
+			restServer, err := das.NewRestfulDasServerForReader(&config.DataAvailability.RestServer, daReader)
+			if err != nil {
+				return nil, err
+			}
+			dasLifecycleManager.Register(restServer)
+		}
 	} else if l2Config.ArbitrumChainParams.DataAvailabilityCommittee {
 		return nil, errors.New("a data availability service is required for this chain, but it was not configured")
 	}
@@ -539,12 +622,35 @@ func createNodeImpl(
 	if err != nil {
 		return nil, err
 	}
+	if daReader != nil && config.DataAvailability.VerifyKeysetsOnChain {
+		keysetValidator, err := das.NewChainKeysetValidator(l1client, deployInfo.SequencerInbox)
+		if err != nil {
+			return nil, err
+		}
+		inboxTracker.SetKeysetValidator(keysetValidator)
+	}
+	if celestiaReader != nil {
+		inboxTracker.SetCelestiaReader(celestiaReader)
+	}
 	inboxReader, err := NewInboxReader(inboxTracker, l1client, l1Reader, new(big.Int).SetUint64(deployInfo.DeployedAt), delayedBridge, sequencerInbox, func() *InboxReaderConfig { return &configFetcher.Get().InboxReader })
 	if err != nil {
 		return nil, err
 	}
 	txStreamer.SetInboxReaders(inboxReader, delayedBridge)
 
+	var daAuditor *DAAuditor
+	if config.DAAuditor.Enable {
+		daAuditor = NewDAAuditor(inboxReader, inboxTracker, daReader, blobReader, func() *DAAuditorConfig { return &configFetcher.Get().DAAuditor })
+	}
+
+	var rollupUpgradeWatcher *RollupUpgradeWatcher
+	if config.UpgradeWatcher.Enable {
+		rollupUpgradeWatcher, err = NewRollupUpgradeWatcher(l1client, deployInfo, fatalErrChan, func() *RollupUpgradeWatcherConfig { return &configFetcher.Get().UpgradeWatcher })
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var statelessBlockValidator *staker.StatelessBlockValidator
 	if config.BlockValidator.ValidationServerConfigs[0].URL != "" {
 		statelessBlockValidator, err = staker.NewStatelessBlockValidator(
@@ -568,6 +674,9 @@ func createNodeImpl(
 		log.Warn("validation not supported", "err", err)
 		statelessBlockValidator = nil
 	}
+	if statelessBlockValidator != nil && celestiaReader != nil {
+		statelessBlockValidator.SetCelestiaReader(celestiaReader)
+	}
 
 	var blockValidator *staker.BlockValidator
 	if config.ValidatorRequired() {
@@ -584,6 +693,7 @@ func createNodeImpl(
 	}
 
 	var stakerObj *staker.Staker
+	var validatorTxSender *common.Address
 	var messagePruner *MessagePruner
 
 	if config.Staker.Enable {
@@ -642,6 +752,7 @@ func createNodeImpl(
 		if err := wallet.Initialize(ctx); err != nil {
 			return nil, err
 		}
+		validatorTxSender = wallet.TxSenderAddress()
 		var validatorAddr string
 		if txOptsValidator != nil {
 			validatorAddr = txOptsValidator.From.String()
@@ -672,6 +783,8 @@ func createNodeImpl(
 			DeployInfo:    deployInfo,
 			TransactOpts:  txOptsBatchPoster,
 			DAWriter:      daWriter,
+			DAReader:      daReader,
+			DAPWriter:     dapWriter,
 			ParentChainID: parentChainID,
 		})
 		if err != nil {
@@ -679,6 +792,23 @@ func createNodeImpl(
 		}
 	}
 
+	var balanceMonitor *BalanceMonitor
+	if config.BalanceMonitor.Enable {
+		balanceMonitor = NewBalanceMonitor(l1client, func() *BalanceMonitorConfig { return &configFetcher.Get().BalanceMonitor })
+		if batchPoster != nil {
+			balanceMonitor.AddAccount(balanceRoleBatchPoster, batchPoster.dataPoster.Sender())
+			balanceMonitor.AddAccount(balanceRoleBatchPosterGasRefund, config.BatchPoster.gasRefunder)
+		}
+		if stakerObj != nil {
+			if validatorTxSender != nil {
+				balanceMonitor.AddAccount(balanceRoleValidator, *validatorTxSender)
+			}
+			if config.Staker.GasRefunderAddress != "" {
+				balanceMonitor.AddAccount(balanceRoleValidatorGasRefund, common.HexToAddress(config.Staker.GasRefunderAddress))
+			}
+		}
+	}
+
 	// always create DelayedSequencer, it won't do anything if it is disabled
 	delayedSequencer, err = NewDelayedSequencer(l1Reader, inboxReader, exec, coordinator, func() *DelayedSequencerConfig { return &configFetcher.Get().DelayedSequencer })
 	if err != nil {
@@ -706,6 +836,11 @@ func createNodeImpl(
 		SeqCoordinator:          coordinator,
 		MaintenanceRunner:       maintenanceRunner,
 		DASLifecycleManager:     dasLifecycleManager,
+		DAAuditor:               daAuditor,
+		MessageAuditor:          messageAuditor,
+		BalanceMonitor:          balanceMonitor,
+		FeedVerifier:            feedVerifier,
+		RollupUpgradeWatcher:    rollupUpgradeWatcher,
 		ClassicOutboxRetriever:  classicOutbox,
 		SyncMonitor:             syncMonitor,
 		configFetcher:           configFetcher,
@@ -743,21 +878,78 @@ func CreateNode(
 		apis = append(apis, rpc.API{
 			Namespace: "arb",
 			Version:   "1.0",
-			Service:   &BlockValidatorAPI{val: currentNode.BlockValidator},
-			Public:    false,
+			Service: &BlockValidatorAPI{
+				val:             currentNode.BlockValidator,
+				inboxTracker:    currentNode.InboxTracker,
+				txStreamer:      currentNode.TxStreamer,
+				genesisBlockNum: l2Config.ArbitrumChainParams.GenesisBlockNum,
+			},
+			Public: false,
+		})
+	}
+	if currentNode.Staker != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service: &WatchtowerAPI{
+				staker:          currentNode.Staker,
+				genesisBlockNum: l2Config.ArbitrumChainParams.GenesisBlockNum,
+			},
+			Public: false,
 		})
+		if currentNode.Staker.Strategy() != staker.WatchtowerStrategy {
+			// only served over the authenticated RPC endpoint, see the auth.api option
+			apis = append(apis, rpc.API{
+				Namespace:     "arbstaker",
+				Version:       "1.0",
+				Service:       &StakerAPI{staker: currentNode.Staker},
+				Public:        false,
+				Authenticated: true,
+			})
+		}
 	}
 	if currentNode.StatelessBlockValidator != nil {
 		apis = append(apis, rpc.API{
 			Namespace: "arbvalidator",
 			Version:   "1.0",
 			Service: &BlockValidatorDebugAPI{
-				val: currentNode.StatelessBlockValidator,
+				val:             currentNode.StatelessBlockValidator,
+				genesisBlockNum: l2Config.ArbitrumChainParams.GenesisBlockNum,
 			},
 			Public: false,
 		})
 	}
 
+	if currentNode.BatchPoster != nil {
+		// only served over the authenticated RPC endpoint, see the auth.api option
+		apis = append(apis, rpc.API{
+			Namespace:     "arbbatchposter",
+			Version:       "1.0",
+			Service:       &BatchPosterAPI{batchPoster: currentNode.BatchPoster},
+			Public:        false,
+			Authenticated: true,
+		})
+	}
+
+	apis = append(apis, rpc.API{
+		Namespace: "arbfreeze",
+		Version:   "1.0",
+		Service: &ChainFreezeAPI{
+			node:            currentNode,
+			genesisBlockNum: l2Config.ArbitrumChainParams.GenesisBlockNum,
+		},
+		Public: false,
+	})
+	apis = append(apis, rpc.API{
+		Namespace: "arbattest",
+		Version:   "1.0",
+		Service: &AttestationAPI{
+			node:            currentNode,
+			genesisBlockNum: l2Config.ArbitrumChainParams.GenesisBlockNum,
+		},
+		Public: false,
+	})
+
 	stack.RegisterAPIs(apis)
 
 	return currentNode, nil
@@ -843,6 +1035,18 @@ func (n *Node) Start(ctx context.Context) error {
 	if n.MessagePruner != nil {
 		n.MessagePruner.Start(ctx)
 	}
+	if n.DAAuditor != nil {
+		n.DAAuditor.Start(ctx)
+	}
+	if n.MessageAuditor != nil {
+		n.MessageAuditor.Start(ctx)
+	}
+	if n.BalanceMonitor != nil {
+		n.BalanceMonitor.Start(ctx)
+	}
+	if n.RollupUpgradeWatcher != nil {
+		n.RollupUpgradeWatcher.Start(ctx)
+	}
 	if n.Staker != nil {
 		err = n.Staker.Initialize(ctx)
 		if err != nil {
@@ -916,6 +1120,18 @@ func (n *Node) StopAndWait() {
 	if n.MessagePruner != nil && n.MessagePruner.Started() {
 		n.MessagePruner.StopAndWait()
 	}
+	if n.DAAuditor != nil && n.DAAuditor.Started() {
+		n.DAAuditor.StopAndWait()
+	}
+	if n.MessageAuditor != nil && n.MessageAuditor.Started() {
+		n.MessageAuditor.StopAndWait()
+	}
+	if n.BalanceMonitor != nil && n.BalanceMonitor.Started() {
+		n.BalanceMonitor.StopAndWait()
+	}
+	if n.RollupUpgradeWatcher != nil && n.RollupUpgradeWatcher.Started() {
+		n.RollupUpgradeWatcher.StopAndWait()
+	}
 	if n.BroadcastServer != nil && n.BroadcastServer.Started() {
 		n.BroadcastServer.StopAndWait()
 	}
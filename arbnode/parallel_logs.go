@@ -0,0 +1,119 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+var parallelLogsChunksCounter = metrics.NewRegisteredCounter("arb/inboxreader/logs/chunks", nil)
+
+// parallelLogsClient splits log queries over a range of parent chain blocks into chunks which are fetched
+// concurrently, so that catching up from an old block isn't bound by the latency of sequential eth_getLogs
+// calls, and no single call exceeds the provider's block range limit. The chunks' logs are returned in order.
+type parallelLogsClient struct {
+	arbutil.L1Interface
+	config InboxReaderConfigFetcher
+}
+
+func NewParallelLogsClient(client arbutil.L1Interface, config InboxReaderConfigFetcher) arbutil.L1Interface {
+	return &parallelLogsClient{
+		L1Interface: client,
+		config:      config,
+	}
+}
+
+// logChunks splits the inclusive block range [from, to] into at most parallelism chunks,
+// or more if needed to keep each one within maxRange blocks
+func logChunks(from, to uint64, parallelism, maxRange uint64) [][2]uint64 {
+	blocks := to - from + 1
+	if parallelism == 0 {
+		parallelism = 1
+	}
+	chunkSize := (blocks + parallelism - 1) / parallelism
+	if maxRange > 0 && chunkSize > maxRange {
+		chunkSize = maxRange
+	}
+	var chunks [][2]uint64
+	for start := from; start <= to; start += chunkSize {
+		end := to
+		if to-start >= chunkSize {
+			end = start + chunkSize - 1
+		}
+		chunks = append(chunks, [2]uint64{start, end})
+		if end == to {
+			break
+		}
+	}
+	return chunks
+}
+
+func (c *parallelLogsClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	if query.BlockHash != nil || query.FromBlock == nil || query.ToBlock == nil ||
+		!query.FromBlock.IsUint64() || !query.ToBlock.IsUint64() || query.FromBlock.Cmp(query.ToBlock) > 0 {
+		return c.L1Interface.FilterLogs(ctx, query)
+	}
+	config := c.config()
+	chunks := logChunks(query.FromBlock.Uint64(), query.ToBlock.Uint64(), config.LogsParallelism, config.MaxLogsBlockRange)
+	if len(chunks) == 1 {
+		return c.L1Interface.FilterLogs(ctx, query)
+	}
+	parallelLogsChunksCounter.Inc(int64(len(chunks)))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([][]types.Log, len(chunks))
+	var errMutex sync.Mutex
+	var firstErr error
+	semaphore := make(chan struct{}, arbmath.MaxInt(config.LogsParallelism, 1))
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		chunkQuery := query
+		chunkQuery.FromBlock = new(big.Int).SetUint64(chunk[0])
+		chunkQuery.ToBlock = new(big.Int).SetUint64(chunk[1])
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-semaphore }()
+			var err error
+			results[i], err = c.L1Interface.FilterLogs(ctx, chunkQuery)
+			if err != nil {
+				errMutex.Lock()
+				if firstErr == nil {
+					// the other chunks' errors are only from canceling them
+					firstErr = fmt.Errorf("fetching logs for blocks %v to %v: %w", chunkQuery.FromBlock, chunkQuery.ToBlock, err)
+				}
+				errMutex.Unlock()
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var logs []types.Log
+	for _, chunkLogs := range results {
+		logs = append(logs, chunkLogs...)
+	}
+	return logs, nil
+}
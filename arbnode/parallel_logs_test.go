@@ -0,0 +1,95 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+// fakeLogsClient returns one log per block, and fails queries over more than maxRange blocks
+type fakeLogsClient struct {
+	arbutil.L1Interface
+	maxRange uint64
+	failAt   uint64
+	calls    atomic.Int64
+}
+
+func (c *fakeLogsClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	c.calls.Add(1)
+	from, to := query.FromBlock.Uint64(), query.ToBlock.Uint64()
+	if c.maxRange > 0 && to-from+1 > c.maxRange {
+		return nil, errors.New("block range too large")
+	}
+	var logs []types.Log
+	for block := from; block <= to; block++ {
+		if c.failAt != 0 && block == c.failAt {
+			return nil, errors.New("provider error")
+		}
+		logs = append(logs, types.Log{BlockNumber: block})
+	}
+	return logs, nil
+}
+
+func TestLogChunks(t *testing.T) {
+	for _, test := range []struct {
+		from, to, parallelism, maxRange uint64
+		expected                        [][2]uint64
+	}{
+		{10, 10, 4, 0, [][2]uint64{{10, 10}}},
+		{0, 99, 4, 0, [][2]uint64{{0, 24}, {25, 49}, {50, 74}, {75, 99}}},
+		{0, 9, 3, 0, [][2]uint64{{0, 3}, {4, 7}, {8, 9}}},
+		{0, 99, 2, 30, [][2]uint64{{0, 29}, {30, 59}, {60, 89}, {90, 99}}},
+		{5, 7, 10, 0, [][2]uint64{{5, 5}, {6, 6}, {7, 7}}},
+	} {
+		chunks := logChunks(test.from, test.to, test.parallelism, test.maxRange)
+		if len(chunks) != len(test.expected) {
+			t.Errorf("chunks of %v-%v (parallelism %v, max range %v) are %v but expected %v", test.from, test.to, test.parallelism, test.maxRange, chunks, test.expected)
+			continue
+		}
+		for i := range chunks {
+			if chunks[i] != test.expected[i] {
+				t.Errorf("chunks of %v-%v (parallelism %v, max range %v) are %v but expected %v", test.from, test.to, test.parallelism, test.maxRange, chunks, test.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestParallelLogsClient(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultInboxReaderConfig
+	config.LogsParallelism = 3
+	config.MaxLogsBlockRange = 50
+	inner := &fakeLogsClient{maxRange: 50}
+	client := NewParallelLogsClient(inner, func() *InboxReaderConfig { return &config })
+
+	logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{FromBlock: big.NewInt(100), ToBlock: big.NewInt(399)})
+	Require(t, err)
+	if len(logs) != 300 {
+		Fail(t, "got", len(logs), "logs but expected 300")
+	}
+	for i, log := range logs {
+		if log.BlockNumber != uint64(100+i) {
+			Fail(t, "log", i, "is from block", log.BlockNumber, "so logs are out of order")
+		}
+	}
+	if calls := inner.calls.Load(); calls != 6 {
+		Fail(t, "made", calls, "requests but expected 6")
+	}
+
+	inner.failAt = 250
+	_, err = client.FilterLogs(ctx, ethereum.FilterQuery{FromBlock: big.NewInt(100), ToBlock: big.NewInt(399)})
+	if err == nil {
+		Fail(t, "expected an error when a chunk fails")
+	}
+}
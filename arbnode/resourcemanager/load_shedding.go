@@ -0,0 +1,320 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package resourcemanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/spf13/pflag"
+)
+
+var (
+	loadShedLevelGauge       = metrics.NewRegisteredGauge("arb/rpc/loadshed/level", nil)
+	loadShedCPUGauge         = metrics.NewRegisteredGaugeFloat64("arb/rpc/loadshed/cpu", nil)
+	loadShedMemGauge         = metrics.NewRegisteredGaugeFloat64("arb/rpc/loadshed/memory", nil)
+	loadShedStateReadCounter = metrics.NewRegisteredCounter("arb/rpc/loadshed/shed/stateread", nil)
+	loadShedLogsCounter      = metrics.NewRegisteredCounter("arb/rpc/loadshed/shed/logs", nil)
+)
+
+// MethodClass is the priority of an RPC method when shedding load,
+// lower classes are more important and are shed last.
+type MethodClass int
+
+const (
+	// ClassSubmission is transaction submission and the calls wallets need
+	// to build a transaction. It's never shed.
+	ClassSubmission MethodClass = iota
+	// ClassStateRead is everything not otherwise classified, mostly reads of chain state.
+	ClassStateRead
+	// ClassLogsTracing is log queries and tracing, which are the most expensive to serve.
+	ClassLogsTracing
+)
+
+func (c MethodClass) String() string {
+	switch c {
+	case ClassSubmission:
+		return "submission"
+	case ClassStateRead:
+		return "state-read"
+	case ClassLogsTracing:
+		return "logs-tracing"
+	default:
+		return "unknown"
+	}
+}
+
+var submissionMethods = map[string]bool{
+	"eth_sendRawTransaction":            true,
+	"eth_sendRawTransactionConditional": true,
+	"eth_sendTransaction":               true,
+	"eth_chainId":                       true,
+	"net_version":                       true,
+	"eth_getTransactionCount":           true,
+	"eth_estimateGas":                   true,
+	"eth_gasPrice":                      true,
+	"eth_maxPriorityFeePerGas":          true,
+}
+
+var logsTracingMethods = map[string]bool{
+	"eth_getLogs":          true,
+	"eth_newFilter":        true,
+	"eth_getFilterLogs":    true,
+	"eth_getFilterChanges": true,
+}
+
+var logsTracingPrefixes = []string{"debug_", "trace_", "arbtrace_"}
+
+// ClassifyMethod returns the priority class of an RPC method
+func ClassifyMethod(method string) MethodClass {
+	if submissionMethods[method] {
+		return ClassSubmission
+	}
+	if logsTracingMethods[method] {
+		return ClassLogsTracing
+	}
+	for _, prefix := range logsTracingPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return ClassLogsTracing
+		}
+	}
+	return ClassStateRead
+}
+
+// The level of pressure the node is under, the classes above a level's
+// highest served class are shed.
+const (
+	pressureNone int32 = iota
+	pressureElevated
+	pressureCritical
+)
+
+type LoadSheddingConfig struct {
+	Enable               bool          `koanf:"enable"`
+	CPUThreshold         float64       `koanf:"cpu-threshold" reload:"hot"`
+	MemThreshold         float64       `koanf:"mem-threshold" reload:"hot"`
+	CriticalCPUThreshold float64       `koanf:"critical-cpu-threshold" reload:"hot"`
+	CriticalMemThreshold float64       `koanf:"critical-mem-threshold" reload:"hot"`
+	SampleInterval       time.Duration `koanf:"sample-interval" reload:"hot"`
+}
+
+var DefaultLoadSheddingConfig = LoadSheddingConfig{
+	Enable:               false,
+	CPUThreshold:         0.8,
+	MemThreshold:         0.85,
+	CriticalCPUThreshold: 0.95,
+	CriticalMemThreshold: 0.95,
+	SampleInterval:       time.Second,
+}
+
+func LoadSheddingConfigAddOptions(prefix string, f *pflag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultLoadSheddingConfig.Enable, "shed the lowest priority RPC calls with HTTP 429 errors when the node is overloaded, so transaction submission stays available")
+	f.Float64(prefix+".cpu-threshold", DefaultLoadSheddingConfig.CPUThreshold, "fraction of the available CPU in use above which log queries and tracing are shed")
+	f.Float64(prefix+".mem-threshold", DefaultLoadSheddingConfig.MemThreshold, "fraction of the cgroup memory limit in use, excluding the page cache, above which log queries and tracing are shed")
+	f.Float64(prefix+".critical-cpu-threshold", DefaultLoadSheddingConfig.CriticalCPUThreshold, "fraction of the available CPU in use above which all calls except transaction submission are shed")
+	f.Float64(prefix+".critical-mem-threshold", DefaultLoadSheddingConfig.CriticalMemThreshold, "fraction of the cgroup memory limit in use, excluding the page cache, above which all calls except transaction submission are shed")
+	f.Duration(prefix+".sample-interval", DefaultLoadSheddingConfig.SampleInterval, "how often to sample CPU and memory usage")
+}
+
+func (c *LoadSheddingConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	for _, threshold := range []float64{c.CPUThreshold, c.MemThreshold, c.CriticalCPUThreshold, c.CriticalMemThreshold} {
+		if threshold <= 0 || threshold > 1 {
+			return errors.New("load shedding thresholds must be fractions between 0 and 1")
+		}
+	}
+	if c.CriticalCPUThreshold < c.CPUThreshold || c.CriticalMemThreshold < c.MemThreshold {
+		return errors.New("load shedding critical thresholds must not be below the regular thresholds")
+	}
+	if c.SampleInterval <= 0 {
+		return errors.New("load shedding sample interval must be positive")
+	}
+	return nil
+}
+
+// PressureSampler reports the fraction of the available CPU and memory in use.
+type PressureSampler interface {
+	Sample() (cpu float64, mem float64, err error)
+}
+
+// systemPressureSampler measures the process' CPU time against the available
+// cores, and memory usage against the cgroups limit if there is one.
+type systemPressureSampler struct {
+	memFiles    *cgroupsMemoryFiles
+	lastWall    time.Time
+	lastCPUTime time.Duration
+}
+
+func newSystemPressureSampler() *systemPressureSampler {
+	s := &systemPressureSampler{lastWall: time.Now()}
+	for _, files := range []cgroupsMemoryFiles{cgroupsV1MemoryFiles, cgroupsV2MemoryFiles} {
+		files := files
+		if _, _, err := readCgroupsMemory(files); err == nil {
+			s.memFiles = &files
+			break
+		}
+	}
+	if s.memFiles == nil {
+		log.Warn("No method for determining memory usage and limits was discovered, load shedding will only consider CPU usage")
+	}
+	if cpuTime, err := processCPUTime(); err == nil {
+		s.lastCPUTime = cpuTime
+	}
+	return s
+}
+
+func processCPUTime() (time.Duration, error) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, err
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), nil
+}
+
+func (s *systemPressureSampler) Sample() (float64, float64, error) {
+	now := time.Now()
+	cpuTime, err := processCPUTime()
+	if err != nil {
+		return 0, 0, err
+	}
+	var cpu float64
+	if wall := now.Sub(s.lastWall); wall > 0 {
+		cpu = float64(cpuTime-s.lastCPUTime) / float64(wall) / float64(runtime.NumCPU())
+	}
+	s.lastWall = now
+	s.lastCPUTime = cpuTime
+	var mem float64
+	if s.memFiles != nil {
+		limit, usage, err := readCgroupsMemory(*s.memFiles)
+		if err != nil {
+			return 0, 0, err
+		}
+		if limit > 0 {
+			mem = float64(usage) / float64(limit)
+		}
+	}
+	return cpu, mem, nil
+}
+
+// loadShedder implements http.Handler and sheds RPC calls by priority class
+// when the sampled CPU or memory pressure crosses the configured thresholds.
+type loadShedder struct {
+	inner   http.Handler
+	config  *LoadSheddingConfig
+	sampler PressureSampler
+
+	level      atomic.Int32
+	sampleLock sync.Mutex
+	lastSample time.Time
+}
+
+func newLoadShedder(inner http.Handler, config *LoadSheddingConfig, sampler PressureSampler) *loadShedder {
+	return &loadShedder{inner: inner, config: config, sampler: sampler}
+}
+
+// pressureLevel returns the current pressure level, resampling it if the
+// last sample is older than the sample interval.
+func (s *loadShedder) pressureLevel() int32 {
+	if !s.sampleLock.TryLock() {
+		// another request is already sampling
+		return s.level.Load()
+	}
+	defer s.sampleLock.Unlock()
+	if time.Since(s.lastSample) < s.config.SampleInterval {
+		return s.level.Load()
+	}
+	s.lastSample = time.Now()
+	cpu, mem, err := s.sampler.Sample()
+	if err != nil {
+		log.Error("Error sampling resource usage for load shedding", "err", err)
+		return s.level.Load()
+	}
+	loadShedCPUGauge.Update(cpu)
+	loadShedMemGauge.Update(mem)
+	level := pressureNone
+	if cpu >= s.config.CriticalCPUThreshold || mem >= s.config.CriticalMemThreshold {
+		level = pressureCritical
+	} else if cpu >= s.config.CPUThreshold || mem >= s.config.MemThreshold {
+		level = pressureElevated
+	}
+	if previous := s.level.Swap(level); previous != level {
+		log.Warn("RPC load shedding level changed", "level", level, "previous", previous, "cpu", cpu, "memory", mem)
+	}
+	loadShedLevelGauge.Update(int64(level))
+	return level
+}
+
+// The maximum amount of a request body read to classify it, geth rejects
+// bodies larger than 5MB anyway.
+const maxClassifiedBodySize = 5 * 1024 * 1024
+
+type rpcMethod struct {
+	Method string `json:"method"`
+}
+
+// classifyRequest returns the lowest priority class of the calls in a request,
+// so that a batch can't bring sheddable calls along with a submission.
+func classifyRequest(body []byte) MethodClass {
+	body = bytes.TrimLeft(body, " \t\r\n")
+	var calls []rpcMethod
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &calls); err != nil {
+			return ClassStateRead
+		}
+	} else {
+		var call rpcMethod
+		if err := json.Unmarshal(body, &call); err != nil {
+			return ClassStateRead
+		}
+		calls = append(calls, call)
+	}
+	class := ClassSubmission
+	for _, call := range calls {
+		if callClass := ClassifyMethod(call.Method); callClass > class {
+			class = callClass
+		}
+	}
+	return class
+}
+
+// ServeHTTP passes req to inner unless its calls' priority class is being
+// shed at the current pressure level, in which case it returns a HTTP 429 error.
+func (s *loadShedder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	level := s.pressureLevel()
+	if level == pressureNone || req.Method != http.MethodPost || req.Body == nil {
+		s.inner.ServeHTTP(w, req)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxClassifiedBodySize))
+	if err != nil {
+		http.Error(w, "Failed to read request", http.StatusBadRequest)
+		return
+	}
+	req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+	class := classifyRequest(body)
+	switch {
+	case class == ClassLogsTracing:
+		loadShedLogsCounter.Inc(1)
+	case class == ClassStateRead && level >= pressureCritical:
+		loadShedStateReadCounter.Inc(1)
+	default:
+		s.inner.ServeHTTP(w, req)
+		return
+	}
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, "Too many requests", http.StatusTooManyRequests)
+}
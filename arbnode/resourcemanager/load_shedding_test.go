@@ -0,0 +1,96 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package resourcemanager
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakePressureSampler struct {
+	cpu, mem float64
+}
+
+func (s *fakePressureSampler) Sample() (float64, float64, error) {
+	return s.cpu, s.mem, nil
+}
+
+func TestClassifyRequest(t *testing.T) {
+	for _, tc := range []struct {
+		body string
+		want MethodClass
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["0x00"]}`, ClassSubmission},
+		{`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":[]}`, ClassStateRead},
+		{`{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[]}`, ClassLogsTracing},
+		{`{"jsonrpc":"2.0","id":1,"method":"debug_traceTransaction","params":[]}`, ClassLogsTracing},
+		{` [{"method":"eth_chainId"},{"method":"eth_getTransactionCount"}]`, ClassSubmission},
+		{`[{"method":"eth_sendRawTransaction"},{"method":"arbtrace_block"}]`, ClassLogsTracing},
+		{`not json`, ClassStateRead},
+	} {
+		if got := classifyRequest([]byte(tc.body)); got != tc.want {
+			t.Errorf("classifyRequest(%s) = %v, want %v", tc.body, got, tc.want)
+		}
+	}
+}
+
+func TestLoadShedding(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write(body)
+	})
+	config := DefaultLoadSheddingConfig
+	config.Enable = true
+	config.SampleInterval = 0
+	sampler := &fakePressureSampler{}
+	shedder := newLoadShedder(inner, &config, sampler)
+
+	serve := func(method string) int {
+		body := `{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":[]}`
+		recorder := httptest.NewRecorder()
+		shedder.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		if recorder.Code == http.StatusOK && recorder.Body.String() != body {
+			t.Errorf("inner handler got body %q, want %q", recorder.Body.String(), body)
+		}
+		return recorder.Code
+	}
+
+	for _, tc := range []struct {
+		desc     string
+		cpu, mem float64
+		want     map[string]int
+	}{
+		{
+			desc: "no pressure",
+			cpu:  0.5,
+			mem:  0.5,
+			want: map[string]int{"eth_sendRawTransaction": http.StatusOK, "eth_call": http.StatusOK, "eth_getLogs": http.StatusOK},
+		},
+		{
+			desc: "elevated cpu",
+			cpu:  0.85,
+			mem:  0.5,
+			want: map[string]int{"eth_sendRawTransaction": http.StatusOK, "eth_call": http.StatusOK, "eth_getLogs": http.StatusTooManyRequests},
+		},
+		{
+			desc: "critical memory",
+			cpu:  0.5,
+			mem:  0.97,
+			want: map[string]int{"eth_sendRawTransaction": http.StatusOK, "eth_call": http.StatusTooManyRequests, "debug_traceCall": http.StatusTooManyRequests},
+		},
+	} {
+		sampler.cpu, sampler.mem = tc.cpu, tc.mem
+		for method, want := range tc.want {
+			if got := serve(method); got != want {
+				t.Errorf("%v: %v got status %v, want %v", tc.desc, method, got, want)
+			}
+		}
+	}
+}
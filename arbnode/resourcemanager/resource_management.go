@@ -35,16 +35,27 @@ var (
 //
 // Must be run before the go-ethereum stack is set up (ethereum/go-ethereum/node.New).
 func Init(conf *Config) error {
-	if conf.MemFreeLimit == "" {
+	if conf.MemFreeLimit == "" && !conf.LoadShedding.Enable {
 		return nil
 	}
 
-	limit, err := ParseMemLimit(conf.MemFreeLimit)
-	if err != nil {
-		return err
+	var limit int
+	if conf.MemFreeLimit != "" {
+		var err error
+		limit, err = ParseMemLimit(conf.MemFreeLimit)
+		if err != nil {
+			return err
+		}
 	}
 
 	node.WrapHTTPHandler = func(srv http.Handler) (http.Handler, error) {
+		if conf.LoadShedding.Enable {
+			srv = newLoadShedder(srv, &conf.LoadShedding, newSystemPressureSampler())
+		}
+		if conf.MemFreeLimit == "" {
+			return srv, nil
+		}
+
 		var c LimitChecker
 		c, err := NewCgroupsMemoryLimitCheckerIfSupported(limit)
 		if errors.Is(err, errNotSupported) {
@@ -83,22 +94,34 @@ func ParseMemLimit(limitStr string) (int, error) {
 	return limit, nil
 }
 
-// Config contains the configuration for resourcemanager functionality.
-// Currently only a memory limit is supported, other limits may be added
-// in the future.
+// Config contains the configuration for resourcemanager functionality:
+// a memory limit, and shedding low priority RPC calls under CPU or
+// memory pressure.
 type Config struct {
-	MemFreeLimit string `koanf:"mem-free-limit" reload:"hot"`
+	MemFreeLimit string             `koanf:"mem-free-limit" reload:"hot"`
+	LoadShedding LoadSheddingConfig `koanf:"load-shedding"`
 }
 
 // DefaultConfig has the defaul resourcemanager configuration,
 // all limits are disabled.
 var DefaultConfig = Config{
 	MemFreeLimit: "",
+	LoadShedding: DefaultLoadSheddingConfig,
 }
 
 // ConfigAddOptions adds the configuration options for resourcemanager.
 func ConfigAddOptions(prefix string, f *pflag.FlagSet) {
 	f.String(prefix+".mem-free-limit", DefaultConfig.MemFreeLimit, "RPC calls are throttled if free system memory excluding the page cache is below this amount, expressed in bytes or multiples of bytes with suffix B, K, M, G. The limit should be set such that sufficient free memory is left for the page cache in order for the system to be performant")
+	LoadSheddingConfigAddOptions(prefix+".load-shedding", f)
+}
+
+func (c *Config) Validate() error {
+	if c.MemFreeLimit != "" {
+		if _, err := ParseMemLimit(c.MemFreeLimit); err != nil {
+			return err
+		}
+	}
+	return c.LoadShedding.Validate()
 }
 
 // httpServer implements http.Handler and wraps calls to inner with a resource
@@ -224,27 +247,36 @@ func newCgroupsMemoryLimitChecker(files cgroupsMemoryFiles, memLimitBytes int) *
 // access. How much "reasonable" is will depend on access patterns, state
 // size, and your application's tolerance for latency.
 func (c *cgroupsMemoryLimitChecker) IsLimitExceeded() (bool, error) {
-	var limit, usage, active, inactive int
-	var err error
-	if limit, err = readIntFromFile(c.files.limitFile); err != nil {
-		return false, err
-	}
-	if usage, err = readIntFromFile(c.files.usageFile); err != nil {
-		return false, err
-	}
-	if active, err = readFromMemStats(c.files.statsFile, c.files.activeRe); err != nil {
-		return false, err
-	}
-	if inactive, err = readFromMemStats(c.files.statsFile, c.files.inactiveRe); err != nil {
+	limit, usage, err := readCgroupsMemory(c.files)
+	if err != nil {
 		return false, err
 	}
 
 	memLimit := limit - c.memLimitBytes
-	memUsage := usage - (active + inactive)
 	nitroMemLimit.Update(int64(memLimit))
-	nitroMemUsage.Update(int64(memUsage))
+	nitroMemUsage.Update(int64(usage))
+
+	return usage >= memLimit, nil
+}
 
-	return memUsage >= memLimit, nil
+// readCgroupsMemory returns the cgroup's memory limit, and its memory usage
+// excluding the active and inactive page cache.
+func readCgroupsMemory(files cgroupsMemoryFiles) (int, int, error) {
+	var limit, usage, active, inactive int
+	var err error
+	if limit, err = readIntFromFile(files.limitFile); err != nil {
+		return 0, 0, err
+	}
+	if usage, err = readIntFromFile(files.usageFile); err != nil {
+		return 0, 0, err
+	}
+	if active, err = readFromMemStats(files.statsFile, files.activeRe); err != nil {
+		return 0, 0, err
+	}
+	if inactive, err = readFromMemStats(files.statsFile, files.inactiveRe); err != nil {
+		return 0, 0, err
+	}
+	return limit, usage - (active + inactive), nil
 }
 
 func (c cgroupsMemoryLimitChecker) String() string {
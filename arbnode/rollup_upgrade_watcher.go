@@ -0,0 +1,262 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/cmd/chaininfo"
+	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
+	"github.com/offchainlabs/nitro/solgen/go/rollupgen"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	rollupUpgradeChangesCounter = metrics.NewRegisteredCounter("arb/rollupwatcher/changes", nil)
+	rollupRestartRequiredGauge  = metrics.NewRegisteredGauge("arb/rollupwatcher/restartrequired", nil)
+)
+
+// The EIP-1967 storage slot holding a proxy's implementation address
+var eip1967ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+
+type RollupUpgradeWatcherConfig struct {
+	Enable                    bool          `koanf:"enable"`
+	PollInterval              time.Duration `koanf:"poll-interval" reload:"hot"`
+	ExitOnIncompatibleUpgrade bool          `koanf:"exit-on-incompatible-upgrade" reload:"hot"`
+}
+
+var DefaultRollupUpgradeWatcherConfig = RollupUpgradeWatcherConfig{
+	Enable:                    false,
+	PollInterval:              time.Minute,
+	ExitOnIncompatibleUpgrade: false,
+}
+
+func RollupUpgradeWatcherConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultRollupUpgradeWatcherConfig.Enable, "watch the parent chain for upgrades to the rollup contracts and their parameters")
+	f.Duration(prefix+".poll-interval", DefaultRollupUpgradeWatcherConfig.PollInterval, "how often to check the rollup contracts for upgrades")
+	f.Bool(prefix+".exit-on-incompatible-upgrade", DefaultRollupUpgradeWatcherConfig.ExitOnIncompatibleUpgrade, "shut the node down if the rollup is upgraded in a way that requires a restart, instead of only logging an error")
+}
+
+func (c *RollupUpgradeWatcherConfig) Validate() error {
+	if c.Enable && c.PollInterval <= 0 {
+		return errors.New("rollup upgrade watcher poll interval must be positive")
+	}
+	return nil
+}
+
+// RollupParameters is the node's view of the rollup's configuration on the parent chain
+type RollupParameters struct {
+	Bridge                   common.Address `json:"bridge"`
+	SequencerInbox           common.Address `json:"sequencerInbox"`
+	Outbox                   common.Address `json:"outbox"`
+	ChallengeManager         common.Address `json:"challengeManager"`
+	WasmModuleRoot           common.Hash    `json:"wasmModuleRoot"`
+	ConfirmPeriodBlocks      uint64         `json:"confirmPeriodBlocks"`
+	ExtraChallengeTimeBlocks uint64         `json:"extraChallengeTimeBlocks"`
+	BaseStake                *big.Int       `json:"baseStake"`
+	RollupImplementation     common.Address `json:"rollupImplementation"`
+	SequencerInboxImpl       common.Address `json:"sequencerInboxImplementation"`
+	MaxTimeVariation         [4]*big.Int    `json:"maxTimeVariation"` // delay blocks, future blocks, delay seconds, future seconds
+}
+
+// RollupUpgradeWatcher periodically reads the rollup's configuration from the parent chain and reports changes.
+// It doesn't refresh anything itself: the parameters it watches are read from the parent chain whenever they're
+// used, so a change only needs to be logged. The node can't switch over to a new bridge or sequencer inbox while
+// running though, as derivation would silently stop finding new messages, so that's reported as requiring a restart.
+type RollupUpgradeWatcher struct {
+	stopwaiter.StopWaiter
+	config       func() *RollupUpgradeWatcherConfig
+	client       arbutil.L1Interface
+	deployInfo   *chaininfo.RollupAddresses
+	rollup       *rollupgen.RollupUserLogicCaller
+	seqInbox     *bridgegen.SequencerInboxCaller
+	fatalErrChan chan error
+
+	mutex           sync.Mutex
+	current         *RollupParameters
+	restartRequired bool
+	handlers        []func(old, new *RollupParameters)
+}
+
+func NewRollupUpgradeWatcher(client arbutil.L1Interface, deployInfo *chaininfo.RollupAddresses, fatalErrChan chan error, config func() *RollupUpgradeWatcherConfig) (*RollupUpgradeWatcher, error) {
+	rollup, err := rollupgen.NewRollupUserLogicCaller(deployInfo.Rollup, client)
+	if err != nil {
+		return nil, err
+	}
+	seqInbox, err := bridgegen.NewSequencerInboxCaller(deployInfo.SequencerInbox, client)
+	if err != nil {
+		return nil, err
+	}
+	return &RollupUpgradeWatcher{
+		config:       config,
+		client:       client,
+		deployInfo:   deployInfo,
+		rollup:       rollup,
+		seqInbox:     seqInbox,
+		fatalErrChan: fatalErrChan,
+	}, nil
+}
+
+// OnChange registers a handler to be called with the old and new parameters whenever they change.
+// Nothing in the node registers one, this is for components that start caching these parameters.
+func (w *RollupUpgradeWatcher) OnChange(handler func(old, new *RollupParameters)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.handlers = append(w.handlers, handler)
+}
+
+// Parameters returns the rollup's configuration as of the last check, or nil before the first check
+func (w *RollupUpgradeWatcher) Parameters() *RollupParameters {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.current
+}
+
+func (w *RollupUpgradeWatcher) RestartRequired() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.restartRequired
+}
+
+func (w *RollupUpgradeWatcher) Start(ctxIn context.Context) {
+	w.StopWaiter.Start(ctxIn, w)
+	w.CallIteratively(func(ctx context.Context) time.Duration {
+		if err := w.check(ctx); err != nil {
+			log.Warn("error checking rollup contracts for upgrades", "err", err)
+		}
+		return w.config().PollInterval
+	})
+}
+
+func (w *RollupUpgradeWatcher) readParameters(ctx context.Context) (*RollupParameters, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	var params RollupParameters
+	var err error
+	if params.Bridge, err = w.rollup.Bridge(opts); err != nil {
+		return nil, err
+	}
+	if params.SequencerInbox, err = w.rollup.SequencerInbox(opts); err != nil {
+		return nil, err
+	}
+	if params.Outbox, err = w.rollup.Outbox(opts); err != nil {
+		return nil, err
+	}
+	if params.ChallengeManager, err = w.rollup.ChallengeManager(opts); err != nil {
+		return nil, err
+	}
+	if params.WasmModuleRoot, err = w.rollup.WasmModuleRoot(opts); err != nil {
+		return nil, err
+	}
+	if params.ConfirmPeriodBlocks, err = w.rollup.ConfirmPeriodBlocks(opts); err != nil {
+		return nil, err
+	}
+	if params.ExtraChallengeTimeBlocks, err = w.rollup.ExtraChallengeTimeBlocks(opts); err != nil {
+		return nil, err
+	}
+	if params.BaseStake, err = w.rollup.BaseStake(opts); err != nil {
+		return nil, err
+	}
+	delayBlocks, futureBlocks, delaySeconds, futureSeconds, err := w.seqInbox.MaxTimeVariation(opts)
+	if err != nil {
+		return nil, err
+	}
+	params.MaxTimeVariation = [4]*big.Int{delayBlocks, futureBlocks, delaySeconds, futureSeconds}
+	rollupImpl, err := w.client.StorageAt(ctx, w.deployInfo.Rollup, eip1967ImplementationSlot, nil)
+	if err != nil {
+		return nil, err
+	}
+	params.RollupImplementation = common.BytesToAddress(rollupImpl)
+	seqInboxImpl, err := w.client.StorageAt(ctx, w.deployInfo.SequencerInbox, eip1967ImplementationSlot, nil)
+	if err != nil {
+		return nil, err
+	}
+	params.SequencerInboxImpl = common.BytesToAddress(seqInboxImpl)
+	return &params, nil
+}
+
+func (w *RollupUpgradeWatcher) check(ctx context.Context) error {
+	params, err := w.readParameters(ctx)
+	if err != nil {
+		return err
+	}
+	w.mutex.Lock()
+	old := w.current
+	w.current = params
+	handlers := w.handlers
+	w.mutex.Unlock()
+
+	var incompatible []string
+	if params.Bridge != w.deployInfo.Bridge {
+		incompatible = append(incompatible, fmt.Sprintf("bridge changed from %v to %v", w.deployInfo.Bridge, params.Bridge))
+	}
+	if params.SequencerInbox != w.deployInfo.SequencerInbox {
+		incompatible = append(incompatible, fmt.Sprintf("sequencer inbox changed from %v to %v", w.deployInfo.SequencerInbox, params.SequencerInbox))
+	}
+	if len(incompatible) > 0 {
+		w.requireRestart(incompatible)
+	}
+	if old == nil {
+		return nil
+	}
+	changes := diffRollupParameters(old, params)
+	if len(changes) == 0 {
+		return nil
+	}
+	rollupUpgradeChangesCounter.Inc(int64(len(changes)))
+	for _, change := range changes {
+		log.Warn("rollup configuration changed on the parent chain", "change", change)
+	}
+	for _, handler := range handlers {
+		handler(old, params)
+	}
+	return nil
+}
+
+func (w *RollupUpgradeWatcher) requireRestart(reasons []string) {
+	w.mutex.Lock()
+	alreadyRequired := w.restartRequired
+	w.restartRequired = true
+	w.mutex.Unlock()
+	rollupRestartRequiredGauge.Update(1)
+	err := fmt.Errorf("rollup was upgraded in a way that requires updating the node's chain info and restarting: %v", reasons)
+	if w.config().ExitOnIncompatibleUpgrade && !alreadyRequired {
+		w.fatalErrChan <- err
+		return
+	}
+	log.Error(err.Error())
+}
+
+// diffRollupParameters describes each difference between two views of the rollup
+func diffRollupParameters(old, new *RollupParameters) []string {
+	var changes []string
+	diff := func(name string, before, after interface{}) {
+		if fmt.Sprint(before) != fmt.Sprint(after) {
+			changes = append(changes, fmt.Sprintf("%v changed from %v to %v", name, before, after))
+		}
+	}
+	diff("bridge", old.Bridge, new.Bridge)
+	diff("sequencer inbox", old.SequencerInbox, new.SequencerInbox)
+	diff("outbox", old.Outbox, new.Outbox)
+	diff("challenge manager", old.ChallengeManager, new.ChallengeManager)
+	diff("wasm module root", old.WasmModuleRoot, new.WasmModuleRoot)
+	diff("confirm period blocks", old.ConfirmPeriodBlocks, new.ConfirmPeriodBlocks)
+	diff("extra challenge time blocks", old.ExtraChallengeTimeBlocks, new.ExtraChallengeTimeBlocks)
+	diff("base stake", old.BaseStake, new.BaseStake)
+	diff("rollup implementation", old.RollupImplementation, new.RollupImplementation)
+	diff("sequencer inbox implementation", old.SequencerInboxImpl, new.SequencerInboxImpl)
+	diff("max time variation", old.MaxTimeVariation, new.MaxTimeVariation)
+	return changes
+}
@@ -14,6 +14,8 @@ var (
 	messageCountKey        []byte = []byte("_messageCount")        // contains the current message count
 	delayedMessageCountKey []byte = []byte("_delayedMessageCount") // contains the current delayed message count
 	sequencerBatchCountKey []byte = []byte("_sequencerBatchCount") // contains the current sequencer message count
+	firstRetainedBatchKey  []byte = []byte("_firstRetainedBatch")  // contains the first batch whose metadata hasn't been pruned
+	auditedMessageCountKey []byte = []byte("_auditedMessageCount") // contains the number of messages published to the message audit sink
 	dbSchemaVersion        []byte = []byte("_schemaVersion")       // contains a uint64 representing the database schema version
 )
 
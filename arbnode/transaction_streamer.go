@@ -36,6 +36,7 @@ import (
 	"github.com/offchainlabs/nitro/execution"
 	"github.com/offchainlabs/nitro/staker"
 	"github.com/offchainlabs/nitro/util/arbmath"
+	"github.com/offchainlabs/nitro/util/latencytracker"
 	"github.com/offchainlabs/nitro/util/sharedmetrics"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
 )
@@ -49,6 +50,8 @@ type TransactionStreamer struct {
 	exec             execution.ExecutionSequencer
 	execLastMsgCount arbutil.MessageIndex
 	validator        *staker.BlockValidator
+	auditor          *MessageAuditor
+	feedVerifier     *FeedVerifier
 
 	db           ethdb.Database
 	fatalErrChan chan<- error
@@ -140,6 +143,26 @@ func (s *TransactionStreamer) SetBlockValidator(validator *staker.BlockValidator
 	s.validator = validator
 }
 
+func (s *TransactionStreamer) SetMessageAuditor(auditor *MessageAuditor) {
+	if s.Started() {
+		panic("trying to set message auditor after start")
+	}
+	if s.auditor != nil {
+		panic("trying to set message auditor when already set")
+	}
+	s.auditor = auditor
+}
+
+func (s *TransactionStreamer) SetFeedVerifier(verifier *FeedVerifier) {
+	if s.Started() {
+		panic("trying to set feed verifier after start")
+	}
+	if s.feedVerifier != nil {
+		panic("trying to set feed verifier when already set")
+	}
+	s.feedVerifier = verifier
+}
+
 func (s *TransactionStreamer) SetSeqCoordinator(coordinator *SeqCoordinator) {
 	if s.Started() {
 		panic("trying to set coordinator after start")
@@ -349,6 +372,13 @@ func (s *TransactionStreamer) reorg(batch ethdb.Batch, count arbutil.MessageInde
 		}
 	}
 
+	if s.auditor != nil {
+		err = s.auditor.Reorg(count)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = deleteStartingAt(s.db, batch, messagePrefix, uint64ToKey(uint64(count)))
 	if err != nil {
 		return err
@@ -445,6 +475,16 @@ func (s *TransactionStreamer) AddBroadcastMessages(feedMessages []*m.BroadcastFe
 		broadcastAfterPos++
 	}
 
+	if s.feedVerifier != nil {
+		if s.feedVerifier.Quarantined() {
+			s.feedVerifier.DropFeedMessages(len(messages))
+			return nil
+		}
+		if err := s.feedVerifier.RecordFeedMessages(broadcastStartPos, messages); err != nil {
+			return err
+		}
+	}
+
 	s.insertionMutex.Lock()
 	defer s.insertionMutex.Unlock()
 
@@ -700,6 +740,19 @@ func (s *TransactionStreamer) addMessagesAndEndBatchImpl(messageStartPos arbutil
 	var hasNewConfirmedMessages bool
 	var cacheClearLen int
 
+	if messagesAreConfirmed && s.feedVerifier != nil {
+		divergences, err := s.feedVerifier.VerifyConfirmedMessages(messageStartPos, messages)
+		if err != nil {
+			return err
+		}
+		if len(divergences) > 0 {
+			// Don't use any queued feed messages, as the feed is quarantined
+			s.broadcasterQueuedMessages = s.broadcasterQueuedMessages[:0]
+			atomic.StoreUint64(&s.broadcasterQueuedMessagesPos, 0)
+			s.broadcasterQueuedMessagesActiveReorg = false
+		}
+	}
+
 	messagesAfterPos := messageStartPos + arbutil.MessageIndex(len(messages))
 	broadcastStartPos := arbutil.MessageIndex(atomic.LoadUint64(&s.broadcasterQueuedMessagesPos))
 
@@ -916,6 +969,8 @@ func (s *TransactionStreamer) writeMessages(pos arbutil.MessageIndex, messages [
 		return err
 	}
 
+	latencytracker.MessagesSequenced(pos, len(messages))
+
 	select {
 	case s.newMessageNotifier <- struct{}{}:
 	default:
@@ -924,6 +979,8 @@ func (s *TransactionStreamer) writeMessages(pos arbutil.MessageIndex, messages [
 	if s.broadcastServer != nil {
 		if err := s.broadcastServer.BroadcastMessages(messages, pos); err != nil {
 			log.Error("failed broadcasting message", "pos", pos, "err", err)
+		} else {
+			latencytracker.MessagesBroadcast(pos, len(messages))
 		}
 	}
 
@@ -968,16 +1025,17 @@ func (s *TransactionStreamer) executeNextMsg(ctx context.Context, exec execution
 		log.Error("feedOneMsg failed to readMessage", "err", err, "pos", pos)
 		return false
 	}
-	var msgForPrefetch *arbostypes.MessageWithMetadata
-	if pos+1 < msgCount {
-		msg, err := s.GetMessage(pos + 1)
+	var msgsForPrefetch []*arbostypes.MessageWithMetadata
+	lookahead := arbutil.MessageIndex(s.exec.CatchUpLookahead())
+	for next := pos + 1; next < msgCount && next <= pos+lookahead; next++ {
+		msg, err := s.GetMessage(next)
 		if err != nil {
-			log.Error("feedOneMsg failed to readMessage", "err", err, "pos", pos+1)
+			log.Error("feedOneMsg failed to readMessage", "err", err, "pos", next)
 			return false
 		}
-		msgForPrefetch = msg
+		msgsForPrefetch = append(msgsForPrefetch, msg)
 	}
-	if err = s.exec.DigestMessage(pos, msg, msgForPrefetch); err != nil {
+	if err = s.exec.DigestMessage(pos, msg, msgsForPrefetch); err != nil {
 		logger := log.Warn
 		if prevMessageCount < msgCount {
 			logger = log.Debug
@@ -0,0 +1,264 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/trie"
+
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+var (
+	verifiedBlocksCounter       = metrics.NewRegisteredCounter("arb/parentchain/verified/blocks", nil)
+	verificationFailuresCounter = metrics.NewRegisteredCounter("arb/parentchain/verified/failures", nil)
+)
+
+const verifiedBlocksCacheSize = 64
+
+// verifiedClient reads the parent chain data the inbox is derived from without trusting the RPC provider.
+// Headers come from a light client, such as a Helios node following the beacon chain's sync committee,
+// which only serves headers it has verified. Logs and transactions are then taken from the provider's
+// full blocks and receipts, but only once they're proven to match the verified header's transactions
+// and receipts roots, so a provider can neither forge nor omit them.
+//
+// Contract calls and other requests are still answered by the provider without being verified.
+type verifiedClient struct {
+	arbutil.L1Interface
+	lightClient arbutil.L1Interface
+	blocks      *lru.Cache[common.Hash, *verifiedBlock]
+}
+
+type verifiedBlock struct {
+	header   *types.Header
+	txs      types.Transactions
+	receipts types.Receipts
+}
+
+func NewVerifiedClient(client arbutil.L1Interface, lightClient arbutil.L1Interface) arbutil.L1Interface {
+	return &verifiedClient{
+		L1Interface: client,
+		lightClient: lightClient,
+		blocks:      lru.NewCache[common.Hash, *verifiedBlock](verifiedBlocksCacheSize),
+	}
+}
+
+func (c *verifiedClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return c.lightClient.HeaderByNumber(ctx, number)
+}
+
+func (c *verifiedClient) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return c.lightClient.HeaderByHash(ctx, hash)
+}
+
+func (c *verifiedClient) blockReceipts(ctx context.Context, block *types.Block) (types.Receipts, error) {
+	if rpcClient := c.L1Interface.Client(); rpcClient != nil {
+		var receipts types.Receipts
+		err := rpcClient.CallContext(ctx, &receipts, "eth_getBlockReceipts", block.Hash())
+		if err == nil {
+			return receipts, nil
+		}
+		log.Debug("parent chain provider can't get block receipts, getting them by transaction", "block", block.Hash(), "err", err)
+	}
+	receipts := make(types.Receipts, 0, len(block.Transactions()))
+	for _, tx := range block.Transactions() {
+		receipt, err := c.L1Interface.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
+
+// loadBlock gets the block's transactions and receipts from the provider, and checks them against the header
+func (c *verifiedClient) loadBlock(ctx context.Context, header *types.Header) (*verifiedBlock, error) {
+	hash := header.Hash()
+	if block, ok := c.blocks.Get(hash); ok {
+		return block, nil
+	}
+	block, err := c.L1Interface.BlockByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if block.Hash() != hash || types.DeriveSha(txs, trie.NewStackTrie(nil)) != header.TxHash {
+		verificationFailuresCounter.Inc(1)
+		return nil, fmt.Errorf("parent chain provider returned transactions for block %v that don't match its verified header", hash)
+	}
+	receipts, err := c.blockReceipts(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) != len(txs) || types.DeriveSha(receipts, trie.NewStackTrie(nil)) != header.ReceiptHash {
+		verificationFailuresCounter.Inc(1)
+		return nil, fmt.Errorf("parent chain provider returned receipts for block %v that don't match its verified header", hash)
+	}
+	verified := &verifiedBlock{
+		header:   header,
+		txs:      txs,
+		receipts: receipts,
+	}
+	verifiedBlocksCounter.Inc(1)
+	c.blocks.Add(hash, verified)
+	return verified, nil
+}
+
+func bloomMatchesQuery(bloom types.Bloom, query ethereum.FilterQuery) bool {
+	if len(query.Addresses) > 0 {
+		found := false
+		for _, addr := range query.Addresses {
+			if types.BloomLookup(bloom, addr) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, position := range query.Topics {
+		if len(position) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range position {
+			if types.BloomLookup(bloom, topic) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func logMatchesQuery(log *types.Log, query ethereum.FilterQuery) bool {
+	if len(query.Addresses) > 0 {
+		found := false
+		for _, addr := range query.Addresses {
+			if log.Address == addr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(query.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, position := range query.Topics {
+		if len(position) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range position {
+			if log.Topics[i] == topic {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// logs returns the logs in the verified block matching the query, with their positions filled in
+func (block *verifiedBlock) logs(query ethereum.FilterQuery) []types.Log {
+	var logs []types.Log
+	index := uint(0)
+	for i, receipt := range block.receipts {
+		for _, receiptLog := range receipt.Logs {
+			if logMatchesQuery(receiptLog, query) {
+				found := *receiptLog
+				found.BlockNumber = block.header.Number.Uint64()
+				found.BlockHash = block.header.Hash()
+				found.TxHash = block.txs[i].Hash()
+				found.TxIndex = uint(i)
+				found.Index = index
+				found.Removed = false
+				logs = append(logs, found)
+			}
+			index++
+		}
+	}
+	return logs
+}
+
+func (c *verifiedClient) filterBlockLogs(ctx context.Context, header *types.Header, query ethereum.FilterQuery) ([]types.Log, error) {
+	if !bloomMatchesQuery(header.Bloom, query) {
+		return nil, nil
+	}
+	block, err := c.loadBlock(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	return block.logs(query), nil
+}
+
+// FilterLogs finds logs in the verified receipts of each block whose verified bloom might contain them
+func (c *verifiedClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	if query.BlockHash != nil {
+		header, err := c.lightClient.HeaderByHash(ctx, *query.BlockHash)
+		if err != nil {
+			return nil, err
+		}
+		return c.filterBlockLogs(ctx, header, query)
+	}
+	if query.FromBlock == nil || query.FromBlock.Sign() < 0 {
+		return nil, errors.New("verified parent chain log queries need a starting block number")
+	}
+	toHeader, err := c.lightClient.HeaderByNumber(ctx, query.ToBlock)
+	if err != nil {
+		return nil, err
+	}
+	to := toHeader.Number.Uint64()
+	logs := []types.Log{}
+	for number := query.FromBlock.Uint64(); number <= to; number++ {
+		header := toHeader
+		if number != to {
+			header, err = c.lightClient.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+			if err != nil {
+				return nil, err
+			}
+		}
+		blockLogs, err := c.filterBlockLogs(ctx, header, query)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, blockLogs...)
+	}
+	return logs, nil
+}
+
+func (c *verifiedClient) TransactionInBlock(ctx context.Context, blockHash common.Hash, index uint) (*types.Transaction, error) {
+	header, err := c.lightClient.HeaderByHash(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	block, err := c.loadBlock(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	if index >= uint(len(block.txs)) {
+		return nil, ethereum.NotFound
+	}
+	return block.txs[index], nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+// fakeLightClient serves verified headers
+type fakeLightClient struct {
+	arbutil.L1Interface
+	headers []*types.Header
+}
+
+func (c *fakeLightClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		return c.headers[len(c.headers)-1], nil
+	}
+	if number.Uint64() >= uint64(len(c.headers)) {
+		return nil, ethereum.NotFound
+	}
+	return c.headers[number.Uint64()], nil
+}
+
+func (c *fakeLightClient) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	for _, header := range c.headers {
+		if header.Hash() == hash {
+			return header, nil
+		}
+	}
+	return nil, ethereum.NotFound
+}
+
+// fakeProvider serves full blocks and receipts, which it may tamper with
+type fakeProvider struct {
+	arbutil.L1Interface
+	blocks   map[common.Hash]*types.Block
+	receipts map[common.Hash]*types.Receipt
+}
+
+func (c *fakeProvider) Client() rpc.ClientInterface {
+	return nil
+}
+
+func (c *fakeProvider) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	block, ok := c.blocks[hash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return block, nil
+}
+
+func (c *fakeProvider) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	receipt, ok := c.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func TestVerifiedClient(t *testing.T) {
+	ctx := context.Background()
+	inbox := common.HexToAddress("0x1234")
+	other := common.HexToAddress("0x5678")
+	event := common.HexToHash("0xe7e7")
+
+	lightClient := &fakeLightClient{}
+	provider := &fakeProvider{
+		blocks:   make(map[common.Hash]*types.Block),
+		receipts: make(map[common.Hash]*types.Receipt),
+	}
+	// Block 1 has an inbox log, block 2 only an unrelated one, and blocks 0 and 3 none
+	for number := uint64(0); number < 4; number++ {
+		var txs types.Transactions
+		var receipts types.Receipts
+		var emitter *common.Address
+		if number == 1 {
+			emitter = &inbox
+		} else if number == 2 {
+			emitter = &other
+		}
+		if emitter != nil {
+			tx := types.NewTransaction(number, *emitter, common.Big0, 100000, common.Big1, []byte{byte(number)})
+			receipt := &types.Receipt{
+				Status:            types.ReceiptStatusSuccessful,
+				CumulativeGasUsed: 50000,
+				TxHash:            tx.Hash(),
+				Logs:              []*types.Log{{Address: *emitter, Topics: []common.Hash{event}, Data: []byte{byte(number)}}},
+			}
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			txs = append(txs, tx)
+			receipts = append(receipts, receipt)
+			provider.receipts[tx.Hash()] = receipt
+		}
+		header := &types.Header{
+			Number:      new(big.Int).SetUint64(number),
+			Difficulty:  common.Big0,
+			TxHash:      types.DeriveSha(txs, trie.NewStackTrie(nil)),
+			ReceiptHash: types.DeriveSha(receipts, trie.NewStackTrie(nil)),
+			Bloom:       types.CreateBloom(receipts),
+		}
+		lightClient.headers = append(lightClient.headers, header)
+		provider.blocks[header.Hash()] = types.NewBlockWithHeader(header).WithBody(txs, nil)
+	}
+	client := NewVerifiedClient(provider, lightClient)
+
+	query := ethereum.FilterQuery{
+		FromBlock: big.NewInt(0),
+		ToBlock:   big.NewInt(3),
+		Addresses: []common.Address{inbox},
+	}
+	logs, err := client.FilterLogs(ctx, query)
+	Require(t, err)
+	block1 := lightClient.headers[1].Hash()
+	if len(logs) != 1 || logs[0].BlockHash != block1 || logs[0].BlockNumber != 1 || logs[0].Data[0] != 1 {
+		Fail(t, "unexpected verified logs", logs)
+	}
+	tx, err := arbutil.GetLogTransaction(ctx, client, logs[0])
+	Require(t, err)
+	if tx.Data()[0] != 1 {
+		Fail(t, "unexpected log transaction", tx)
+	}
+
+	// A provider can't change a log without it failing to match the verified receipts root
+	provider.receipts[logs[0].TxHash].Logs[0].Data = []byte{2}
+	client = NewVerifiedClient(provider, lightClient)
+	if _, err := client.FilterLogs(ctx, query); err == nil {
+		Fail(t, "expected a tampered receipt to fail verification")
+	}
+	provider.receipts[logs[0].TxHash].Logs[0].Data = []byte{1}
+
+	// Or change a transaction without it failing to match the verified transactions root
+	original := provider.blocks[block1]
+	forged := types.NewTransaction(1, inbox, common.Big0, 100000, common.Big1, []byte{9})
+	provider.blocks[block1] = types.NewBlockWithHeader(original.Header()).WithBody(types.Transactions{forged}, nil)
+	if _, err := NewVerifiedClient(provider, lightClient).TransactionInBlock(ctx, block1, 0); err == nil {
+		Fail(t, "expected a tampered transaction to fail verification")
+	}
+}
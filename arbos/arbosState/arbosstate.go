@@ -68,6 +68,9 @@ func OpenArbosState(stateDB vm.StateDB, burner burn.Burner) (*ArbosState, error)
 	if arbosVersion == 0 {
 		return nil, ErrUninitializedArbOS
 	}
+	if arbosVersion >= arbostypes.ArbosVersion_RefinedStorageGas {
+		backingStorage = backingStorage.WithRefinedGas()
+	}
 	return &ArbosState{
 		arbosVersion,
 		backingStorage.OpenStorageBackedUint64(uint64(upgradeVersionOffset)),
@@ -156,6 +159,9 @@ var (
 	sendMerkleSubspace   SubspaceID = []byte{5}
 	blockhashesSubspace  SubspaceID = []byte{6}
 	chainConfigSubspace  SubspaceID = []byte{7}
+
+	// reserved for precompiles registered by chain forks, see precompiles.RegisterCustomPrecompile
+	customPrecompilesSubspace SubspaceID = []byte{255}
 )
 
 // Returns a list of precompiles that only appear in Arbitrum chains (i.e. ArbOS precompiles) at the genesis block
@@ -320,6 +326,12 @@ func (state *ArbosState) UpgradeArbosVersion(
 		case 20:
 			// Update Brotli compression level for fast compression from 0 to 1
 			ensure(state.SetBrotliCompressionLevel(1))
+		case 21:
+			// no state changes needed, the new behavior is gated on the version:
+			// - storage writes are priced like SSTOREs from the next time the state is opened (ArbosVersion_RefinedStorageGas)
+			// - this and later upgrades emit an ArbOSUpgraded event (ArbosVersion_UpgradeEvents)
+			// - the chain owner can configure retryable auto-redeems (ArbosVersion_AutoRedeemConfig)
+			// - the batch poster may post Celestia batches (ArbosVersion_Celestia)
 		default:
 			if nextArbosVersion >= 12 && nextArbosVersion <= 19 {
 				// ArbOS versions 12 through 19 are left to Orbit chains for custom upgrades.
@@ -372,6 +384,12 @@ func (state *ArbosState) BackingStorage() *storage.Storage {
 	return state.backingStorage
 }
 
+// CustomPrecompileStorage returns the storage sub-space belonging to the custom precompile at the given address.
+// Each custom precompile gets its own sub-space so forks can't collide with ArbOS or one another.
+func (state *ArbosState) CustomPrecompileStorage(address common.Address) *storage.Storage {
+	return state.backingStorage.OpenSubStorage(customPrecompilesSubspace).OpenSubStorage(address.Bytes())
+}
+
 func (state *ArbosState) Restrict(err error) {
 	state.Burner.Restrict(err)
 }
@@ -36,6 +36,13 @@ const MaxL2MessageSize = 256 * 1024
 
 const ArbosVersion_FixRedeemGas = uint64(11)
 
+// ArbOS 21 prices storage writes like SSTOREs, emits an event on upgrades, makes retryable
+// auto-redeems configurable, and reads Celestia batches
+const ArbosVersion_RefinedStorageGas = uint64(21)
+const ArbosVersion_UpgradeEvents = uint64(21)
+const ArbosVersion_AutoRedeemConfig = uint64(21)
+const ArbosVersion_Celestia = uint64(21)
+
 type L1IncomingMessageHeader struct {
 	Kind        uint8          `json:"kind"`
 	Poster      common.Address `json:"sender"`
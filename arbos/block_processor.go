@@ -13,6 +13,7 @@ import (
 	"github.com/offchainlabs/nitro/arbos/arbosState"
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/arbos/l2pricing"
+	"github.com/offchainlabs/nitro/arbos/storage"
 	"github.com/offchainlabs/nitro/arbos/util"
 	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
 	"github.com/offchainlabs/nitro/util/arbmath"
@@ -40,6 +41,7 @@ var L2ToL1TransactionEventID common.Hash
 var L2ToL1TxEventID common.Hash
 var EmitReedeemScheduledEvent func(*vm.EVM, uint64, uint64, [32]byte, [32]byte, common.Address, *big.Int, *big.Int) error
 var EmitTicketCreatedEvent func(*vm.EVM, [32]byte) error
+var EmitArbOSUpgradedEvent func(*vm.EVM, uint64, uint64) error
 var gasUsedSinceStartupCounter = metrics.NewRegisteredCounter("arb/gas_used", nil)
 
 // A helper struct that implements String() by marshalling to JSON.
@@ -188,6 +190,9 @@ func ProduceBlockAdvanced(
 	chainConfig *params.ChainConfig,
 	sequencingHooks *SequencingHooks,
 ) (*types.Block, types.Receipts, error) {
+	// Must be enabled before ArbOS state is opened, so that every write in the block invalidates the cache
+	disableSlotCache := storage.EnableSlotCache(statedb)
+	defer disableSlotCache()
 
 	state, err := arbosState.OpenSystemArbosState(statedb, nil, true)
 	if err != nil {
@@ -19,6 +19,12 @@ type Burner interface {
 	TracingInfo() *util.TracingInfo
 }
 
+// Refunder is implemented by burners which may be credited storage refunds, as the EVM credits
+// clearing and restoring storage slots. Only burners metering gas paid for by a transaction should be.
+type Refunder interface {
+	Refundable() bool
+}
+
 type SystemBurner struct {
 	gasBurnt    uint64
 	tracingInfo *util.TracingInfo
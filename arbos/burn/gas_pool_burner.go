@@ -0,0 +1,69 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package burn
+
+import (
+	"github.com/ethereum/go-ethereum/core/vm"
+	glog "github.com/ethereum/go-ethereum/log"
+	"github.com/offchainlabs/nitro/arbos/util"
+)
+
+// GasPoolBurner meters work against a fixed gas budget, such as the gas supplied to a precompile call,
+// failing with vm.ErrOutOfGas once the budget is exhausted. Unlike the SystemBurner, work done with it
+// is bounded by what the caller paid for.
+type GasPoolBurner struct {
+	gasSupplied uint64
+	gasLeft     uint64
+	tracingInfo *util.TracingInfo
+	readOnly    bool
+}
+
+func NewGasPoolBurner(gas uint64, tracingInfo *util.TracingInfo, readOnly bool) *GasPoolBurner {
+	return &GasPoolBurner{
+		gasSupplied: gas,
+		gasLeft:     gas,
+		tracingInfo: tracingInfo,
+		readOnly:    readOnly,
+	}
+}
+
+// Burn takes the amount from the budget, or exhausts it and returns vm.ErrOutOfGas if it's more than what's left
+func (burner *GasPoolBurner) Burn(amount uint64) error {
+	if burner.gasLeft < amount {
+		burner.gasLeft = 0
+		return vm.ErrOutOfGas
+	}
+	burner.gasLeft -= amount
+	return nil
+}
+
+func (burner *GasPoolBurner) Burned() uint64 {
+	return burner.gasSupplied - burner.gasLeft
+}
+
+func (burner *GasPoolBurner) GasLeft() uint64 {
+	return burner.gasLeft
+}
+
+func (burner *GasPoolBurner) Restrict(err error) {
+	glog.Crit("A metered burner was used for access-controlled work", "error", err)
+}
+
+func (burner *GasPoolBurner) HandleError(err error) error {
+	return err
+}
+
+func (burner *GasPoolBurner) ReadOnly() bool {
+	return burner.readOnly
+}
+
+// Refundable lets ArbOS storage credit the transaction refunds for clearing and restoring slots, like the EVM does,
+// as the budget is gas paid for by the transaction
+func (burner *GasPoolBurner) Refundable() bool {
+	return true
+}
+
+func (burner *GasPoolBurner) TracingInfo() *util.TracingInfo {
+	return burner.tracingInfo
+}
@@ -0,0 +1,33 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package burn
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+func TestGasPoolBurner(t *testing.T) {
+	burner := NewGasPoolBurner(1000, nil, false)
+	if err := burner.Burn(600); err != nil {
+		t.Fatal(err)
+	}
+	if burner.GasLeft() != 400 || burner.Burned() != 600 {
+		t.Fatal("wrong gas left", burner.GasLeft(), "or burnt", burner.Burned())
+	}
+	if err := burner.Burn(401); !errors.Is(err, vm.ErrOutOfGas) {
+		t.Fatal("expected out of gas but got", err)
+	}
+	if burner.GasLeft() != 0 || burner.Burned() != 1000 {
+		t.Fatal("running out of gas should exhaust the budget, but left", burner.GasLeft())
+	}
+	if err := burner.Burn(1); !errors.Is(err, vm.ErrOutOfGas) {
+		t.Fatal("expected out of gas but got", err)
+	}
+	if err := burner.Burn(0); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package burn
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/offchainlabs/nitro/arbos/util"
+)
+
+var ErrInjectedBurnFailure = errors.New("injected burn failure")
+
+// BurnRecord describes a single call to TestBurner.Burn
+type BurnRecord struct {
+	Amount uint64
+	Tag    string // the tag set via SetTag when the burn occurred
+	Caller string // the function that called Burn, e.g. "storage.(*Storage).Set"
+	Failed bool   // whether the burn returned an error
+}
+
+// TestingT is the part of testing.TB that TestBurner's assertions use, so that this package doesn't link the
+// testing package into the node
+type TestingT interface {
+	Helper()
+	Fatalf(format string, args ...any)
+}
+
+// TestBurner is a Burner for unit tests that records every burn, allowing precise gas-accounting assertions.
+// A failure can be injected to make the Nth call to Burn return an error, as a metered burner would when out of gas.
+type TestBurner struct {
+	records     []BurnRecord
+	gasBurnt    uint64
+	tag         string
+	failAt      int
+	failErr     error
+	restricted  []error
+	readOnly    bool
+	tracingInfo *util.TracingInfo
+}
+
+func NewTestBurner(readOnly bool) *TestBurner {
+	return &TestBurner{readOnly: readOnly}
+}
+
+// SetTag labels all subsequent burns until the tag is changed again
+func (burner *TestBurner) SetTag(tag string) {
+	burner.tag = tag
+}
+
+// FailAt makes the nth call to Burn (counting from 1) fail with the given error, or ErrInjectedBurnFailure if nil
+func (burner *TestBurner) FailAt(n int, err error) {
+	if err == nil {
+		err = ErrInjectedBurnFailure
+	}
+	burner.failAt = n
+	burner.failErr = err
+}
+
+func (burner *TestBurner) Burn(amount uint64) error {
+	record := BurnRecord{
+		Amount: amount,
+		Tag:    burner.tag,
+		Caller: burnCaller(),
+		Failed: len(burner.records)+1 == burner.failAt,
+	}
+	burner.records = append(burner.records, record)
+	if record.Failed {
+		return burner.failErr
+	}
+	burner.gasBurnt += amount
+	return nil
+}
+
+func (burner *TestBurner) Burned() uint64 {
+	return burner.gasBurnt
+}
+
+// Restrict records the error rather than logging it, see Restricted
+func (burner *TestBurner) Restrict(err error) {
+	if err != nil {
+		burner.restricted = append(burner.restricted, err)
+	}
+}
+
+func (burner *TestBurner) HandleError(err error) error {
+	return err
+}
+
+func (burner *TestBurner) ReadOnly() bool {
+	return burner.readOnly
+}
+
+func (burner *TestBurner) TracingInfo() *util.TracingInfo {
+	return burner.tracingInfo
+}
+
+// Records returns every call to Burn in order, including one that was made to fail
+func (burner *TestBurner) Records() []BurnRecord {
+	return burner.records
+}
+
+// Restricted returns the errors passed to Restrict
+func (burner *TestBurner) Restricted() []error {
+	return burner.restricted
+}
+
+// BurnedWithTag sums the successful burns made under the given tag
+func (burner *TestBurner) BurnedWithTag(tag string) uint64 {
+	total := uint64(0)
+	for _, record := range burner.records {
+		if record.Tag == tag && !record.Failed {
+			total += record.Amount
+		}
+	}
+	return total
+}
+
+// Reset clears the recorded burns and any injected failure, keeping the current tag
+func (burner *TestBurner) Reset() {
+	burner.records = nil
+	burner.gasBurnt = 0
+	burner.failAt = 0
+	burner.failErr = nil
+	burner.restricted = nil
+}
+
+// RequireBurned fails the test if the total gas burnt doesn't match what's expected
+func (burner *TestBurner) RequireBurned(t TestingT, expected uint64) {
+	t.Helper()
+	if burner.gasBurnt != expected {
+		t.Fatalf("burnt %v gas but expected %v\n%v", burner.gasBurnt, expected, burner.describe())
+	}
+}
+
+// RequireBurnedWithTag fails the test if the gas burnt under the tag doesn't match what's expected
+func (burner *TestBurner) RequireBurnedWithTag(t TestingT, tag string, expected uint64) {
+	t.Helper()
+	if burnt := burner.BurnedWithTag(tag); burnt != expected {
+		t.Fatalf("burnt %v gas with tag %v but expected %v\n%v", burnt, tag, expected, burner.describe())
+	}
+}
+
+func (burner *TestBurner) describe() string {
+	var builder strings.Builder
+	for _, record := range burner.records {
+		builder.WriteString("\t")
+		builder.WriteString(record.Caller)
+		if record.Tag != "" {
+			builder.WriteString(" [" + record.Tag + "]")
+		}
+		builder.WriteString(": ")
+		builder.WriteString(strconv.FormatUint(record.Amount, 10))
+		if record.Failed {
+			builder.WriteString(" (failed)")
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// burnCaller finds the name of the function that called Burn, trimmed to its package
+func burnCaller() string {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return "unknown"
+	}
+	name := runtime.FuncForPC(pc).Name()
+	if slash := strings.LastIndex(name, "/"); slash >= 0 {
+		name = name[slash+1:]
+	}
+	return name
+}
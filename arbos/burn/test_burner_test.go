@@ -0,0 +1,46 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package burn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTestBurner(t *testing.T) {
+	burner := NewTestBurner(false)
+	burner.SetTag("first")
+	if err := burner.Burn(100); err != nil {
+		t.Fatal(err)
+	}
+	burner.SetTag("second")
+	if err := burner.Burn(20); err != nil {
+		t.Fatal(err)
+	}
+	burner.FailAt(3, nil)
+	if err := burner.Burn(5); !errors.Is(err, ErrInjectedBurnFailure) {
+		t.Fatal("expected injected failure but got", err)
+	}
+	if err := burner.Burn(1); err != nil {
+		t.Fatal(err)
+	}
+
+	burner.RequireBurned(t, 121)
+	burner.RequireBurnedWithTag(t, "first", 100)
+	burner.RequireBurnedWithTag(t, "second", 21)
+
+	records := burner.Records()
+	if len(records) != 4 {
+		t.Fatal("wrong number of records", len(records))
+	}
+	if records[0].Caller != "burn.TestTestBurner" {
+		t.Fatal("wrong caller", records[0].Caller)
+	}
+
+	burner.Reset()
+	burner.RequireBurned(t, 0)
+	if len(burner.Records()) != 0 {
+		t.Fatal("records weren't reset")
+	}
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/offchainlabs/nitro/arbos/arbosState"
 )
 
-// extraPreTxFilter should be modified by chain operators to enforce additional pre-transaction validity rules
+// extraPreTxFilter enforces additional pre-transaction validity rules registered by chain operators, see TxHooks
 func extraPreTxFilter(
 	chainConfig *params.ChainConfig,
 	currentBlockHeader *types.Header,
@@ -21,11 +21,15 @@ func extraPreTxFilter(
 	sender common.Address,
 	l1Info *L1Info,
 ) error {
-	// TODO: implement additional pre-transaction checks
+	for _, hooks := range registeredTxHooks() {
+		if err := hooks.PreTxFilter(chainConfig, currentBlockHeader, statedb, state, tx, options, sender, l1Info); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// extraPostTxFilter should be modified by chain operators to enforce additional post-transaction validity rules
+// extraPostTxFilter enforces additional post-transaction validity rules registered by chain operators, see TxHooks
 func extraPostTxFilter(
 	chainConfig *params.ChainConfig,
 	currentBlockHeader *types.Header,
@@ -37,6 +41,10 @@ func extraPostTxFilter(
 	l1Info *L1Info,
 	result *core.ExecutionResult,
 ) error {
-	// TODO: implement additional post-transaction checks
+	for _, hooks := range registeredTxHooks() {
+		if err := hooks.PostTxFilter(chainConfig, currentBlockHeader, statedb, state, tx, options, sender, l1Info, result); err != nil {
+			return err
+		}
+	}
 	return nil
 }
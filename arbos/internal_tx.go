@@ -16,6 +16,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/offchainlabs/nitro/arbos/arbosState"
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/arbos/util"
 )
 
@@ -88,7 +89,14 @@ func ApplyInternalTxUpdate(tx *types.ArbitrumInternalTx, state *arbosState.Arbos
 
 		state.L2PricingState().UpdatePricingModel(l2BaseFee, timePassed, false)
 
-		return state.UpgradeArbosVersionIfNecessary(currentTime, evm.StateDB, evm.ChainConfig())
+		oldVersion := state.ArbOSVersion()
+		if err := state.UpgradeArbosVersionIfNecessary(currentTime, evm.StateDB, evm.ChainConfig()); err != nil {
+			return err
+		}
+		if newVersion := state.ArbOSVersion(); newVersion != oldVersion && newVersion >= arbostypes.ArbosVersion_UpgradeEvents {
+			return EmitArbOSUpgradedEvent(evm, oldVersion, newVersion)
+		}
+		return nil
 	case InternalTxBatchPostingReportMethodID:
 		inputs, err := util.UnpackInternalTxDataBatchPostingReport(tx.Data)
 		if err != nil {
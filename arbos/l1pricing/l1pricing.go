@@ -26,6 +26,11 @@ import (
 	"github.com/offchainlabs/nitro/arbos/util"
 )
 
+// L1PricingState prices L1 data. Fees for it are collected into the L1PricerFundsPoolAddress pool, and
+// l1FeesAvailable records the part of the pool's balance that's been recognized. Each batch posting report
+// pays the batch posters what they spent and the rewards recipient its reward out of the recognized fees,
+// and then adjusts the price per unit so the surplus, which is negative when in deficit, trends to zero.
+// Any balance sent to the pool directly isn't recognized until the chain owner calls ReleaseL1PricerSurplusFunds.
 type L1PricingState struct {
 	storage *storage.Storage
 
@@ -282,6 +287,24 @@ func (ps *L1PricingState) TransferFromL1FeesAvailable(
 	return updated, nil
 }
 
+// Surplus is the fees available less what's owed to the batch posters and the rewards recipient,
+// which is negative when the pool is in deficit
+func (ps *L1PricingState) Surplus() (*big.Int, error) {
+	totalFundsDue, err := ps.BatchPosterTable().TotalFundsDue()
+	if err != nil {
+		return nil, err
+	}
+	fundsDueForRewards, err := ps.FundsDueForRewards()
+	if err != nil {
+		return nil, err
+	}
+	l1FeesAvailable, err := ps.L1FeesAvailable()
+	if err != nil {
+		return nil, err
+	}
+	return am.BigSub(l1FeesAvailable, am.BigAdd(totalFundsDue, fundsDueForRewards)), nil
+}
+
 // UpdateForBatchPosterSpending updates the pricing model based on a payment by a batch poster
 func (ps *L1PricingState) UpdateForBatchPosterSpending(
 	statedb vm.StateDB,
@@ -432,15 +455,10 @@ func (ps *L1PricingState) UpdateForBatchPosterSpending(
 
 	// adjust the price
 	if unitsAllocated > 0 {
-		totalFundsDue, err := batchPosterTable.TotalFundsDue()
-		if err != nil {
-			return err
-		}
-		fundsDueForRewards, err = ps.FundsDueForRewards()
+		surplus, err := ps.Surplus()
 		if err != nil {
 			return err
 		}
-		surplus := am.BigSub(l1FeesAvailable, am.BigAdd(totalFundsDue, fundsDueForRewards))
 
 		inertia, err := ps.Inertia()
 		if err != nil {
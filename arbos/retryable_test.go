@@ -4,6 +4,7 @@
 package arbos
 
 import (
+	"errors"
 	"math/big"
 	"math/rand"
 	"testing"
@@ -214,6 +215,37 @@ func TestRetryableCreate(t *testing.T) {
 	}
 }
 
+func TestRetryableAutoRedeemConfig(t *testing.T) {
+	state, _ := arbosState.NewArbosMemoryBackedArbOSState()
+	rstate := state.RetryableState()
+	checkConfig := func(attempts uint64, gasCeiling uint64, refundToBeneficiary bool) {
+		t.Helper()
+		haveAttempts, haveGasCeiling, haveRefundToBeneficiary, err := rstate.AutoRedeemConfig()
+		Require(t, err)
+		if haveAttempts != attempts || haveGasCeiling != gasCeiling || haveRefundToBeneficiary != refundToBeneficiary {
+			Fail(t, "auto-redeem config is", haveAttempts, haveGasCeiling, haveRefundToBeneficiary)
+		}
+	}
+	checkConfig(1, 0, false)
+
+	Require(t, rstate.SetAutoRedeemConfig(retryables.MaxAutoRedeemAttempts, params.TxGas, true))
+	checkConfig(retryables.MaxAutoRedeemAttempts, params.TxGas, true)
+	Require(t, rstate.SetAutoRedeemConfig(2, 0, false))
+	checkConfig(2, 0, false)
+
+	for _, attempts := range []uint64{0, retryables.MaxAutoRedeemAttempts + 1} {
+		if err := rstate.SetAutoRedeemConfig(attempts, 0, false); !errors.Is(err, retryables.ErrBadAutoRedeemAttempts) {
+			Fail(t, "expected", attempts, "auto-redeem attempts to be rejected, got", err)
+		}
+	}
+	for _, gasCeiling := range []uint64{1, params.TxGas - 1} {
+		if err := rstate.SetAutoRedeemConfig(1, gasCeiling, false); !errors.Is(err, retryables.ErrBadAutoRedeemGasCeiling) {
+			Fail(t, "expected an auto-redeem gas ceiling of", gasCeiling, "to be rejected, got", err)
+		}
+	}
+	checkConfig(2, 0, false)
+}
+
 func stateCheck(t *testing.T, statedb *state.StateDB, change bool, message string, scope func()) {
 	stateBefore := statedb.IntermediateRoot(true)
 	dumpBefore := string(statedb.Dump(&state.DumpConfig{}))
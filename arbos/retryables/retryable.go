@@ -12,6 +12,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/offchainlabs/nitro/arbos/storage"
 	"github.com/offchainlabs/nitro/arbos/util"
 	"github.com/offchainlabs/nitro/util/arbmath"
@@ -20,14 +21,32 @@ import (
 const RetryableLifetimeSeconds = 7 * 24 * 60 * 60 // one week
 const RetryableReapPrice = 58000
 
+// MaxAutoRedeemAttempts bounds how many times the chain owner may have a retryable's auto-redeem attempted
+const MaxAutoRedeemAttempts = 8
+
 type RetryableState struct {
 	retryables   *storage.Storage
 	TimeoutQueue *storage.Queue
+
+	// the auto-redeem of new retryables, as configured by the chain owner
+	autoRedeemAttempts            storage.StorageBackedUint64 // 0 means the default of 1
+	autoRedeemGasCeiling          storage.StorageBackedUint64 // 0 means no ceiling
+	autoRedeemRefundToBeneficiary storage.StorageBackedUint64 // 1 if refunding the beneficiary
 }
 
 var (
-	timeoutQueueKey = []byte{0}
-	calldataKey     = []byte{1}
+	timeoutQueueKey     = []byte{0}
+	calldataKey         = []byte{1}
+	autoRedeemConfigKey = []byte{2}
+)
+
+var ErrBadAutoRedeemAttempts = errors.New("auto-redeem attempts must be between 1 and the maximum")
+var ErrBadAutoRedeemGasCeiling = errors.New("auto-redeem gas ceiling must be 0 or at least the intrinsic gas of a tx")
+
+const (
+	autoRedeemAttemptsOffset uint64 = iota
+	autoRedeemGasCeilingOffset
+	autoRedeemRefundToBeneficiaryOffset
 )
 
 func InitializeRetryableState(sto *storage.Storage) error {
@@ -35,10 +54,54 @@ func InitializeRetryableState(sto *storage.Storage) error {
 }
 
 func OpenRetryableState(sto *storage.Storage, statedb vm.StateDB) *RetryableState {
+	autoRedeemConfig := sto.OpenCachedSubStorage(autoRedeemConfigKey)
 	return &RetryableState{
-		sto,
-		storage.OpenQueue(sto.OpenCachedSubStorage(timeoutQueueKey)),
+		retryables:                    sto,
+		TimeoutQueue:                  storage.OpenQueue(sto.OpenCachedSubStorage(timeoutQueueKey)),
+		autoRedeemAttempts:            autoRedeemConfig.OpenStorageBackedUint64(autoRedeemAttemptsOffset),
+		autoRedeemGasCeiling:          autoRedeemConfig.OpenStorageBackedUint64(autoRedeemGasCeilingOffset),
+		autoRedeemRefundToBeneficiary: autoRedeemConfig.OpenStorageBackedUint64(autoRedeemRefundToBeneficiaryOffset),
+	}
+}
+
+// AutoRedeemConfig returns how many times a new retryable's auto-redeem is attempted while it fails with gas left,
+// the most gas it's given (0 for no ceiling), and whether its unused gas is refunded to the beneficiary rather than
+// the fee refund address
+func (rs *RetryableState) AutoRedeemConfig() (uint64, uint64, bool, error) {
+	attempts, err := rs.autoRedeemAttempts.Get()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if attempts == 0 {
+		attempts = 1
+	}
+	gasCeiling, err := rs.autoRedeemGasCeiling.Get()
+	if err != nil {
+		return 0, 0, false, err
 	}
+	refundToBeneficiary, err := rs.autoRedeemRefundToBeneficiary.Get()
+	return attempts, gasCeiling, refundToBeneficiary != 0, err
+}
+
+func (rs *RetryableState) SetAutoRedeemConfig(attempts uint64, gasCeiling uint64, refundToBeneficiary bool) error {
+	if attempts == 0 || attempts > MaxAutoRedeemAttempts {
+		return ErrBadAutoRedeemAttempts
+	}
+	if gasCeiling != 0 && gasCeiling < params.TxGas {
+		// every auto-redeem would be skipped
+		return ErrBadAutoRedeemGasCeiling
+	}
+	if err := rs.autoRedeemAttempts.Set(attempts); err != nil {
+		return err
+	}
+	if err := rs.autoRedeemGasCeiling.Set(gasCeiling); err != nil {
+		return err
+	}
+	var refundTo uint64
+	if refundToBeneficiary {
+		refundTo = 1
+	}
+	return rs.autoRedeemRefundToBeneficiary.Set(refundTo)
 }
 
 type Retryable struct {
@@ -52,6 +115,7 @@ type Retryable struct {
 	calldata           storage.StorageBackedBytes
 	timeout            storage.StorageBackedUint64
 	timeoutWindowsLeft storage.StorageBackedUint64
+	autoRedeemsLeft    storage.StorageBackedUint64 // only non-zero while the auto-redeem is being attempted
 }
 
 const (
@@ -62,6 +126,7 @@ const (
 	beneficiaryOffset
 	timeoutOffset
 	timeoutWindowsLeftOffset
+	autoRedeemsLeftOffset
 )
 
 func (rs *RetryableState) CreateRetryable(
@@ -85,6 +150,7 @@ func (rs *RetryableState) CreateRetryable(
 		sto.OpenStorageBackedBytes(calldataKey),
 		sto.OpenStorageBackedUint64(timeoutOffset),
 		sto.OpenStorageBackedUint64(timeoutWindowsLeftOffset),
+		sto.OpenStorageBackedUint64(autoRedeemsLeftOffset),
 	}
 	_ = ret.numTries.Set(0)
 	_ = ret.from.Set(from)
@@ -120,6 +186,7 @@ func (rs *RetryableState) OpenRetryable(id common.Hash, currentTimestamp uint64)
 		calldata:           sto.OpenStorageBackedBytes(calldataKey),
 		timeout:            timeoutStorage,
 		timeoutWindowsLeft: sto.OpenStorageBackedUint64(timeoutWindowsLeftOffset),
+		autoRedeemsLeft:    sto.OpenStorageBackedUint64(autoRedeemsLeftOffset),
 	}, nil
 }
 
@@ -170,6 +237,16 @@ func (retryable *Retryable) IncrementNumTries() (uint64, error) {
 	return retryable.numTries.Increment()
 }
 
+// AutoRedeemsLeft returns how many more times the auto-redeem is to be attempted if the current attempt fails
+func (retryable *Retryable) AutoRedeemsLeft() (uint64, error) {
+	return retryable.autoRedeemsLeft.Get()
+}
+
+// SetAutoRedeemsLeft must be cleared once the auto-redeem is done being attempted, as DeleteRetryable doesn't
+func (retryable *Retryable) SetAutoRedeemsLeft(left uint64) error {
+	return retryable.autoRedeemsLeft.Set(left)
+}
+
 func (retryable *Retryable) Beneficiary() (common.Address, error) {
 	return retryable.beneficiary.Get()
 }
@@ -0,0 +1,62 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package storage
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// slotCaches maps each StateDB producing a block to the cache of ArbOS parameters read during the block
+var slotCaches sync.Map
+
+// slotCache memoizes the values of ArbOS parameters (the fixed slots behind StorageBacked types, such as the
+// pricing parameters) so that every transaction in a block doesn't have to read them from the StateDB again.
+//
+// A slot is only cached while it hasn't been written in the block. Once written it's read from the StateDB for
+// the rest of the block, as the write may yet be reverted along with the transaction or call that made it,
+// and the cache has no way to see that. Reads are still charged to the burner and traced exactly as uncached
+// reads are, so caching never changes gas used or recorded accesses.
+type slotCache struct {
+	values  map[common.Hash]common.Hash
+	written map[common.Hash]struct{}
+}
+
+// EnableSlotCache caches ArbOS parameters read from the StateDB until the returned function is called,
+// which must happen before the StateDB is used for anything besides producing its current block.
+func EnableSlotCache(db vm.StateDB) func() {
+	slotCaches.Store(db, &slotCache{
+		values:  make(map[common.Hash]common.Hash),
+		written: make(map[common.Hash]struct{}),
+	})
+	return func() {
+		slotCaches.Delete(db)
+	}
+}
+
+func lookupSlotCache(db vm.StateDB) *slotCache {
+	cache, ok := slotCaches.Load(db)
+	if !ok {
+		return nil
+	}
+	return cache.(*slotCache)
+}
+
+func (c *slotCache) get(db vm.StateDB, account common.Address, slot common.Hash) common.Hash {
+	if value, ok := c.values[slot]; ok {
+		return value
+	}
+	value := db.GetState(account, slot)
+	if _, ok := c.written[slot]; !ok {
+		c.values[slot] = value
+	}
+	return value
+}
+
+func (c *slotCache) invalidate(slot common.Hash) {
+	delete(c.values, slot)
+	c.written[slot] = struct{}{}
+}
@@ -47,6 +47,8 @@ type Storage struct {
 	storageKey []byte
 	burner     burn.Burner
 	hashCache  *lru.Cache[string, []byte]
+	refinedGas bool
+	slotCache  *slotCache // nil unless the StateDB is producing a block with the slot cache enabled
 }
 
 const StorageReadCost = params.SloadGasEIP2200
@@ -68,6 +70,7 @@ func NewGeth(statedb vm.StateDB, burner burn.Burner) *Storage {
 		storageKey: []byte{},
 		burner:     burner,
 		hashCache:  storageHashCache,
+		slotCache:  lookupSlotCache(statedb),
 	}
 }
 
@@ -107,6 +110,47 @@ func writeCost(value common.Hash) uint64 {
 	return StorageWriteCost
 }
 
+// refinedWriteCost prices a write the way the EVM prices an SSTORE under EIP-2200's net gas metering,
+// distinguishing setting a fresh slot, changing a set one, clearing it, and rewriting a slot already written
+// in this transaction. Like the EVM, clearing or restoring a slot earns a refund, but only if the burner
+// meters gas paid for by the transaction.
+func refinedWriteCost(db vm.StateDB, account common.Address, slot common.Hash, value common.Hash, burner burn.Burner) uint64 {
+	current := db.GetState(account, slot)
+	if current == value {
+		return StorageReadCost
+	}
+	refunder, refunds := burner.(burn.Refunder)
+	refunds = refunds && refunder.Refundable()
+	original := db.GetCommittedState(account, slot)
+	if original == current {
+		if original == (common.Hash{}) {
+			return StorageWriteCost
+		}
+		if value == (common.Hash{}) && refunds {
+			db.AddRefund(params.SstoreClearsScheduleRefundEIP3529)
+		}
+		return StorageWriteZeroCost
+	}
+	if !refunds {
+		return StorageReadCost
+	}
+	if original != (common.Hash{}) {
+		if current == (common.Hash{}) {
+			db.SubRefund(params.SstoreClearsScheduleRefundEIP3529)
+		} else if value == (common.Hash{}) {
+			db.AddRefund(params.SstoreClearsScheduleRefundEIP3529)
+		}
+	}
+	if original == value {
+		if original == (common.Hash{}) {
+			db.AddRefund(StorageWriteCost - StorageReadCost)
+		} else {
+			db.AddRefund(StorageWriteZeroCost - StorageReadCost)
+		}
+	}
+	return StorageReadCost
+}
+
 func (s *Storage) Account() common.Address {
 	return s.account
 }
@@ -144,14 +188,22 @@ func (s *Storage) Set(key common.Hash, value common.Hash) error {
 		log.Error("Read-only burner attempted to mutate state", "key", key, "value", value)
 		return vm.ErrWriteProtection
 	}
-	err := s.burner.Burn(writeCost(value))
+	mapped := s.mapAddress(key)
+	cost := writeCost(value)
+	if s.refinedGas {
+		cost = refinedWriteCost(s.db, s.account, mapped, value, s.burner)
+	}
+	err := s.burner.Burn(cost)
 	if err != nil {
 		return err
 	}
 	if info := s.burner.TracingInfo(); info != nil {
 		info.RecordStorageSet(key, value)
 	}
-	s.db.SetState(s.account, s.mapAddress(key), value)
+	if s.slotCache != nil {
+		s.slotCache.invalidate(mapped)
+	}
+	s.db.SetState(s.account, mapped, value)
 	return nil
 }
 
@@ -186,6 +238,8 @@ func (s *Storage) OpenCachedSubStorage(id []byte) *Storage {
 		storageKey: s.cachedKeccak(s.storageKey, id),
 		burner:     s.burner,
 		hashCache:  storageHashCache,
+		refinedGas: s.refinedGas,
+		slotCache:  s.slotCache,
 	}
 }
 func (s *Storage) OpenSubStorage(id []byte) *Storage {
@@ -195,6 +249,8 @@ func (s *Storage) OpenSubStorage(id []byte) *Storage {
 		storageKey: s.cachedKeccak(s.storageKey, id),
 		burner:     s.burner,
 		hashCache:  nil,
+		refinedGas: s.refinedGas,
+		slotCache:  s.slotCache,
 	}
 }
 
@@ -207,6 +263,22 @@ func (s *Storage) WithoutCache() *Storage {
 		storageKey: s.storageKey,
 		burner:     s.burner,
 		hashCache:  nil,
+		refinedGas: s.refinedGas,
+		slotCache:  s.slotCache,
+	}
+}
+
+// Returns shallow copy of Storage whose writes, including those of its substorages and slots,
+// are priced like SSTOREs in the EVM rather than by whether the value written is zero.
+func (s *Storage) WithRefinedGas() *Storage {
+	return &Storage{
+		account:    s.account,
+		db:         s.db,
+		storageKey: s.storageKey,
+		burner:     s.burner,
+		hashCache:  s.hashCache,
+		refinedGas: true,
+		slotCache:  s.slotCache,
 	}
 }
 
@@ -323,14 +395,16 @@ func (s *Storage) cachedKeccak(data ...[]byte) []byte {
 }
 
 type StorageSlot struct {
-	account common.Address
-	db      vm.StateDB
-	slot    common.Hash
-	burner  burn.Burner
+	account    common.Address
+	db         vm.StateDB
+	slot       common.Hash
+	burner     burn.Burner
+	refinedGas bool
+	cache      *slotCache
 }
 
 func (s *Storage) NewSlot(offset uint64) StorageSlot {
-	return StorageSlot{s.account, s.db, s.mapAddress(util.UintToHash(offset)), s.burner}
+	return StorageSlot{s.account, s.db, s.mapAddress(util.UintToHash(offset)), s.burner, s.refinedGas, s.slotCache}
 }
 
 func (ss *StorageSlot) Get() (common.Hash, error) {
@@ -341,6 +415,9 @@ func (ss *StorageSlot) Get() (common.Hash, error) {
 	if info := ss.burner.TracingInfo(); info != nil {
 		info.RecordStorageGet(ss.slot)
 	}
+	if ss.cache != nil {
+		return ss.cache.get(ss.db, ss.account, ss.slot), nil
+	}
 	return ss.db.GetState(ss.account, ss.slot), nil
 }
 
@@ -349,13 +426,20 @@ func (ss *StorageSlot) Set(value common.Hash) error {
 		log.Error("Read-only burner attempted to mutate state", "value", value)
 		return vm.ErrWriteProtection
 	}
-	err := ss.burner.Burn(writeCost(value))
+	cost := writeCost(value)
+	if ss.refinedGas {
+		cost = refinedWriteCost(ss.db, ss.account, ss.slot, value, ss.burner)
+	}
+	err := ss.burner.Burn(cost)
 	if err != nil {
 		return err
 	}
 	if info := ss.burner.TracingInfo(); info != nil {
 		info.RecordStorageSet(ss.slot, value)
 	}
+	if ss.cache != nil {
+		ss.cache.invalidate(ss.slot)
+	}
 	ss.db.SetState(ss.account, ss.slot, value)
 	return nil
 }
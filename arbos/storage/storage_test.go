@@ -10,8 +10,11 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/offchainlabs/nitro/arbos/burn"
+	"github.com/offchainlabs/nitro/arbos/util"
 	"github.com/offchainlabs/nitro/util/arbmath"
 )
 
@@ -166,3 +169,217 @@ func TestMapAddressCache(t *testing.T) {
 		t.Fatal(<-errs)
 	}
 }
+
+type refundingBurner struct {
+	*burn.SystemBurner
+}
+
+func (burner refundingBurner) Refundable() bool {
+	return true
+}
+
+func TestRefinedStorageGas(t *testing.T) {
+	statedb := NewMemoryBackedStateDB()
+	burner := refundingBurner{burn.NewSystemBurner(nil, false)}
+	sto := NewGeth(statedb, burner).WithRefinedGas()
+	commit := func() {
+		statedb.(*state.StateDB).IntermediateRoot(true)
+	}
+	one, two := common.HexToHash("1"), common.HexToHash("2")
+
+	type step struct {
+		value  common.Hash
+		cost   uint64
+		refund uint64
+		commit bool
+	}
+	run := func(key uint64, steps []step) {
+		t.Helper()
+		for i, step := range steps {
+			before := burner.Burned()
+			if err := sto.SetByUint64(key, step.value); err != nil {
+				t.Fatal(err)
+			}
+			if cost := burner.Burned() - before; cost != step.cost {
+				t.Fatal("step", i, "writing slot", key, "cost", cost, "but expected", step.cost)
+			}
+			if refund := statedb.GetRefund(); refund != step.refund {
+				t.Fatal("step", i, "writing slot", key, "left refund", refund, "but expected", step.refund)
+			}
+			if step.commit {
+				commit()
+			}
+		}
+	}
+
+	// committing ends the transaction, resetting the refund
+	// a fresh slot, set and changed before and after committing, then cleared
+	run(1, []step{
+		{one, StorageWriteCost, 0, false},
+		{two, StorageReadCost, 0, true},
+		{two, StorageReadCost, 0, false},
+		{one, StorageWriteZeroCost, 0, false},
+		{common.Hash{}, StorageReadCost, params.SstoreClearsScheduleRefundEIP3529, true},
+	})
+	// a slot set and cleared within the transaction is refunded all but the read
+	run(2, []step{
+		{one, StorageWriteCost, 0, false},
+		{common.Hash{}, StorageReadCost, StorageWriteCost - StorageReadCost, true},
+	})
+
+	statedb.SetState(sto.account, sto.mapAddress(util.UintToHash(3)), one)
+	commit()
+	// clearing a set slot and restoring it takes back the clearing refund
+	run(3, []step{
+		{common.Hash{}, StorageWriteZeroCost, params.SstoreClearsScheduleRefundEIP3529, false},
+		{one, StorageReadCost, StorageWriteZeroCost - StorageReadCost, false},
+	})
+	slot := sto.NewSlot(3)
+	before := burner.Burned()
+	if err := slot.Set(one); err != nil {
+		t.Fatal(err)
+	}
+	if cost := burner.Burned() - before; cost != StorageReadCost {
+		t.Fatal("rewriting a slot's value cost", cost)
+	}
+
+	// system burners pay the same costs but aren't refunded
+	systemBurner := burn.NewSystemBurner(nil, false)
+	systemSto := NewGeth(statedb, systemBurner).WithRefinedGas().OpenSubStorage([]byte{1})
+	refund := statedb.GetRefund()
+	if err := systemSto.SetByUint64(1, one); err != nil {
+		t.Fatal(err)
+	}
+	if err := systemSto.ClearByUint64(1); err != nil {
+		t.Fatal(err)
+	}
+	if systemBurner.Burned() != StorageWriteCost+StorageReadCost || statedb.GetRefund() != refund {
+		t.Fatal("system burner burnt", systemBurner.Burned(), "and changed the refund from", refund, "to", statedb.GetRefund())
+	}
+
+	// without refined gas, a write's cost depends only on the value
+	legacyBurner := burn.NewSystemBurner(nil, false)
+	legacySto := NewGeth(statedb, legacyBurner)
+	if err := legacySto.SetByUint64(1, two); err != nil {
+		t.Fatal(err)
+	}
+	if legacyBurner.Burned() != StorageWriteCost {
+		t.Fatal("legacy write cost", legacyBurner.Burned())
+	}
+}
+
+func TestSlotCache(t *testing.T) {
+	statedb := NewMemoryBackedStateDB()
+	disable := EnableSlotCache(statedb)
+	burner := burn.NewSystemBurner(nil, false)
+	sto := NewGeth(statedb, burner).OpenCachedSubStorage([]byte{1})
+	param := sto.OpenStorageBackedUint64(0)
+	one, two := common.HexToHash("1"), common.HexToHash("2")
+
+	// an unwritten parameter is read once and then served from the cache, at the same gas cost
+	statedb.SetState(sto.account, param.slot, one)
+	if value, err := param.Get(); err != nil || value != 1 {
+		t.Fatal("read", value, err)
+	}
+	statedb.SetState(sto.account, param.slot, two)
+	before := burner.Burned()
+	if value, err := sto.OpenStorageBackedUint64(0).Get(); err != nil || value != 1 {
+		t.Fatal("expected the cached value but read", value, err)
+	}
+	if cost := burner.Burned() - before; cost != StorageReadCost {
+		t.Fatal("cached read cost", cost)
+	}
+
+	// once written, reads go to the StateDB, so they see writes being reverted
+	snapshot := statedb.Snapshot()
+	if err := param.Set(3); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := param.Get(); err != nil || value != 3 {
+		t.Fatal("read", value, "after writing 3", err)
+	}
+	statedb.RevertToSnapshot(snapshot)
+	if value, err := param.Get(); err != nil || value != 2 {
+		t.Fatal("read", value, "after reverting the write", err)
+	}
+	// writes through the generic interface invalidate the cache too
+	other := sto.OpenStorageBackedUint64(1)
+	if _, err := other.Get(); err != nil {
+		t.Fatal(err)
+	}
+	snapshot = statedb.Snapshot()
+	if err := sto.SetUint64ByUint64(1, 5); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := other.Get(); err != nil || value != 5 {
+		t.Fatal("read", value, "after writing 5", err)
+	}
+	statedb.RevertToSnapshot(snapshot)
+	if value, err := other.Get(); err != nil || value != 0 {
+		t.Fatal("read", value, "after reverting the write", err)
+	}
+
+	// storage opened once the cache is disabled reads the StateDB directly
+	disable()
+	statedb.SetState(sto.account, other.slot, one)
+	if value, err := NewGeth(statedb, burner).OpenCachedSubStorage([]byte{1}).OpenStorageBackedUint64(1).Get(); err != nil || value != 1 {
+		t.Fatal("read", value, "with the cache disabled", err)
+	}
+}
+
+// BenchmarkSlotCache measures what the slot cache costs when opening storage, which looks the StateDB up in
+// the global slotCaches map, against what it saves on reading parameters.
+func BenchmarkSlotCache(b *testing.B) {
+	statedb := NewMemoryBackedStateDB()
+	burner := burn.NewSystemBurner(nil, false)
+
+	// other StateDBs producing blocks or serving calls at the same time share the map
+	for i := 0; i < 64; i++ {
+		defer EnableSlotCache(NewMemoryBackedStateDB())()
+	}
+
+	b.Run("open", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			NewGeth(statedb, burner)
+		}
+	})
+	b.Run("open-cached", func(b *testing.B) {
+		defer EnableSlotCache(statedb)()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			NewGeth(statedb, burner)
+		}
+	})
+	b.Run("open-cached-parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			// StateDBs aren't safe for concurrent use, so each goroutine has its own
+			db := NewMemoryBackedStateDB()
+			defer EnableSlotCache(db)()
+			for pb.Next() {
+				NewGeth(db, burner)
+			}
+		})
+	})
+
+	// written before the cache is enabled, as the cache doesn't serve parameters written in the block
+	if err := NewGeth(statedb, burner).OpenCachedSubStorage([]byte{1}).SetUint64ByUint64(0, 1); err != nil {
+		b.Fatal(err)
+	}
+	read := func(b *testing.B) {
+		param := NewGeth(statedb, burner).OpenCachedSubStorage([]byte{1}).OpenStorageBackedUint64(0)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := param.Get(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.Run("read", read)
+	b.Run("read-cached", func(b *testing.B) {
+		defer EnableSlotCache(statedb)()
+		read(b)
+	})
+}
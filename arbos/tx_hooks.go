@@ -0,0 +1,169 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbos
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/arbitrum_types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/offchainlabs/nitro/arbos/arbosState"
+)
+
+// TxHooks lets a chain add its own logic to ArbOS's transaction processing, such as extra validation or fee discounts,
+// without modifying ArbOS itself. Hooks are registered with RegisterTxHooks from an init function,
+// typically in a file of the chain's own package that's only compiled in with a build tag.
+//
+// Hooks run while executing blocks, so they must be deterministic and must behave identically in the replay binary.
+// Changing what a hook does changes the chain's state transition function and so must be coordinated like an upgrade.
+type TxHooks interface {
+	// PreTxFilter is called before a transaction is applied. An error drops the transaction from the block.
+	PreTxFilter(
+		chainConfig *params.ChainConfig,
+		header *types.Header,
+		statedb *state.StateDB,
+		state *arbosState.ArbosState,
+		tx *types.Transaction,
+		options *arbitrum_types.ConditionalOptions,
+		sender common.Address,
+		l1Info *L1Info,
+	) error
+
+	// StartTx is called as ArbOS begins processing a transaction signed by a user.
+	StartTx(p *TxProcessor)
+
+	// GasCharging is called once ArbOS has charged for the transaction's L1 calldata.
+	// It may charge more gas by lowering gasRemaining, but must never raise it.
+	// An error makes the transaction invalid.
+	GasCharging(p *TxProcessor, gasRemaining *uint64) error
+
+	// PrecompileCall is called before an ArbOS precompile runs. An error fails the call, consuming its gas.
+	PrecompileCall(p *TxProcessor, precompile common.Address, caller common.Address, input []byte) error
+
+	// EndTx is called once ArbOS has finished refunding gas and paying fees for the transaction.
+	EndTx(p *TxProcessor, gasUsed uint64, success bool)
+
+	// PostTxFilter is called after a transaction is applied. An error reverts the transaction and drops it from the block.
+	PostTxFilter(
+		chainConfig *params.ChainConfig,
+		header *types.Header,
+		statedb *state.StateDB,
+		state *arbosState.ArbosState,
+		tx *types.Transaction,
+		options *arbitrum_types.ConditionalOptions,
+		sender common.Address,
+		l1Info *L1Info,
+		result *core.ExecutionResult,
+	) error
+}
+
+// NoopTxHooks implements every hook by doing nothing, and can be embedded to implement only some of them
+type NoopTxHooks struct{}
+
+func (NoopTxHooks) PreTxFilter(
+	*params.ChainConfig, *types.Header, *state.StateDB, *arbosState.ArbosState,
+	*types.Transaction, *arbitrum_types.ConditionalOptions, common.Address, *L1Info,
+) error {
+	return nil
+}
+
+func (NoopTxHooks) StartTx(*TxProcessor) {}
+
+func (NoopTxHooks) GasCharging(*TxProcessor, *uint64) error {
+	return nil
+}
+
+func (NoopTxHooks) PrecompileCall(*TxProcessor, common.Address, common.Address, []byte) error {
+	return nil
+}
+
+func (NoopTxHooks) EndTx(*TxProcessor, uint64, bool) {}
+
+func (NoopTxHooks) PostTxFilter(
+	*params.ChainConfig, *types.Header, *state.StateDB, *arbosState.ArbosState,
+	*types.Transaction, *arbitrum_types.ConditionalOptions, common.Address, *L1Info, *core.ExecutionResult,
+) error {
+	return nil
+}
+
+var ErrTxHookRaisedGas = errors.New("tx hook raised the gas remaining")
+
+// registered hooks run in the order they were registered, and can't change once they've first been used
+var txHooks struct {
+	mutex      sync.Mutex
+	registered []TxHooks
+	frozen     atomic.Bool
+}
+
+// RegisterTxHooks adds hooks to ArbOS's transaction processing. It must be called from an init function, and panics
+// if any hooks have already been run, as a block processed without them would have a different result.
+func RegisterTxHooks(hooks TxHooks) {
+	txHooks.mutex.Lock()
+	defer txHooks.mutex.Unlock()
+	if txHooks.frozen.Load() {
+		panic("tx hooks registered after transaction processing began")
+	}
+	txHooks.registered = append(txHooks.registered, hooks)
+}
+
+// registeredTxHooks returns the registered hooks, preventing any more from being registered
+func registeredTxHooks() []TxHooks {
+	if !txHooks.frozen.Load() {
+		txHooks.mutex.Lock()
+		txHooks.frozen.Store(true)
+		txHooks.mutex.Unlock()
+	}
+	return txHooks.registered
+}
+
+func startTxHooks(p *TxProcessor) {
+	for _, hooks := range registeredTxHooks() {
+		hooks.StartTx(p)
+	}
+}
+
+func gasChargingHooks(p *TxProcessor, gasRemaining *uint64) error {
+	for _, hooks := range registeredTxHooks() {
+		before := *gasRemaining
+		if err := hooks.GasCharging(p, gasRemaining); err != nil {
+			return err
+		}
+		if *gasRemaining > before {
+			return ErrTxHookRaisedGas
+		}
+	}
+	return nil
+}
+
+func endTxHooks(p *TxProcessor, gasUsed uint64, success bool) {
+	for _, hooks := range registeredTxHooks() {
+		hooks.EndTx(p, gasUsed, success)
+	}
+}
+
+// PrecompileCallHook gives registered hooks the chance to fail a call to an ArbOS precompile
+func (p *TxProcessor) PrecompileCallHook(precompile common.Address, caller common.Address, input []byte) error {
+	for _, hooks := range registeredTxHooks() {
+		if err := hooks.PrecompileCall(p, precompile, caller, input); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Message returns the message being processed
+func (p *TxProcessor) Message() *core.Message {
+	return p.msg
+}
+
+// State returns ArbOS's state for the transaction being processed
+func (p *TxProcessor) State() *arbosState.ArbosState {
+	return p.state
+}
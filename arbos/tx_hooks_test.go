@@ -0,0 +1,128 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbos
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// withTxHooks runs the test with only the given hooks registered, restoring the previous registrations afterwards
+func withTxHooks(t *testing.T, hooks ...TxHooks) {
+	t.Helper()
+	txHooks.mutex.Lock()
+	previous, wasFrozen := txHooks.registered, txHooks.frozen.Load()
+	txHooks.registered = nil
+	txHooks.frozen.Store(false)
+	txHooks.mutex.Unlock()
+	t.Cleanup(func() {
+		txHooks.mutex.Lock()
+		txHooks.registered = previous
+		txHooks.frozen.Store(wasFrozen)
+		txHooks.mutex.Unlock()
+	})
+	for _, hook := range hooks {
+		RegisterTxHooks(hook)
+	}
+}
+
+type gasAdjustingHooks struct {
+	NoopTxHooks
+	adjustment int64
+}
+
+func (h gasAdjustingHooks) GasCharging(_ *TxProcessor, gasRemaining *uint64) error {
+	*gasRemaining = uint64(int64(*gasRemaining) + h.adjustment)
+	return nil
+}
+
+func TestGasChargingHooks(t *testing.T) {
+	withTxHooks(t, gasAdjustingHooks{adjustment: -100}, gasAdjustingHooks{adjustment: -20})
+	gas := uint64(1000)
+	Require(t, gasChargingHooks(nil, &gas))
+	if gas != 880 {
+		Fail(t, "hooks charged the wrong amount of gas, have", gas, "remaining")
+	}
+
+	withTxHooks(t, gasAdjustingHooks{adjustment: -100}, gasAdjustingHooks{adjustment: 1})
+	if err := gasChargingHooks(nil, &gas); !errors.Is(err, ErrTxHookRaisedGas) {
+		Fail(t, "expected a hook raising gas to be rejected, got", err)
+	}
+}
+
+var errBlockedPrecompile = errors.New("blocked precompile")
+
+type recordingHooks struct {
+	NoopTxHooks
+	name    string
+	calls   *[]string
+	blocked common.Address
+}
+
+func (h recordingHooks) StartTx(*TxProcessor) {
+	*h.calls = append(*h.calls, h.name+".StartTx")
+}
+
+func (h recordingHooks) PrecompileCall(_ *TxProcessor, precompile common.Address, _ common.Address, _ []byte) error {
+	*h.calls = append(*h.calls, h.name+".PrecompileCall")
+	if precompile == h.blocked {
+		return errBlockedPrecompile
+	}
+	return nil
+}
+
+func (h recordingHooks) EndTx(_ *TxProcessor, gasUsed uint64, success bool) {
+	if success {
+		*h.calls = append(*h.calls, h.name+".EndTx")
+	} else {
+		*h.calls = append(*h.calls, h.name+".EndTx(failed)")
+	}
+}
+
+func TestTxHooksOrder(t *testing.T) {
+	var calls []string
+	blocked := common.HexToAddress("0x6e")
+	withTxHooks(t,
+		recordingHooks{name: "a", calls: &calls},
+		recordingHooks{name: "b", calls: &calls, blocked: blocked},
+		recordingHooks{name: "c", calls: &calls},
+	)
+	var p *TxProcessor
+
+	startTxHooks(p)
+	Require(t, p.PrecompileCallHook(common.HexToAddress("0x64"), common.Address{}, nil))
+	if err := p.PrecompileCallHook(blocked, common.Address{}, nil); !errors.Is(err, errBlockedPrecompile) {
+		Fail(t, "expected the precompile call to be blocked, got", err)
+	}
+	endTxHooks(p, 21000, false)
+
+	expected := []string{
+		"a.StartTx", "b.StartTx", "c.StartTx",
+		"a.PrecompileCall", "b.PrecompileCall", "c.PrecompileCall",
+		// a failing hook stops later hooks from running
+		"a.PrecompileCall", "b.PrecompileCall",
+		"a.EndTx(failed)", "b.EndTx(failed)", "c.EndTx(failed)",
+	}
+	if len(calls) != len(expected) {
+		Fail(t, "hooks called", calls, "expected", expected)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			Fail(t, "hooks called", calls, "expected", expected)
+		}
+	}
+}
+
+func TestTxHooksRegistrationAfterUse(t *testing.T) {
+	withTxHooks(t)
+	startTxHooks(nil)
+	defer func() {
+		if recover() == nil {
+			Fail(t, "registering hooks after they were used didn't panic")
+		}
+	}()
+	RegisterTxHooks(NoopTxHooks{})
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/offchainlabs/nitro/arbos/retryables"
 
 	"github.com/offchainlabs/nitro/arbos/arbosState"
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
@@ -248,6 +249,22 @@ func (p *TxProcessor) StartTxHook() (endTxNow bool, gasUsed uint64, err error, r
 		effectiveBaseFee := evm.Context.BaseFee
 		usergas := p.msg.GasLimit
 
+		// the chain owner may have the auto-redeem attempted more than once, given less gas, or refund the beneficiary
+		autoRedeemAttempts := uint64(1)
+		redeemGas := usergas
+		refundTo := tx.FeeRefundAddr
+		if p.state.ArbOSVersion() >= arbostypes.ArbosVersion_AutoRedeemConfig {
+			attempts, gasCeiling, refundToBeneficiary, err := p.state.RetryableState().AutoRedeemConfig()
+			p.state.Restrict(err)
+			autoRedeemAttempts = attempts
+			if gasCeiling != 0 && redeemGas > gasCeiling {
+				redeemGas = gasCeiling
+			}
+			if refundToBeneficiary {
+				refundTo = tx.Beneficiary
+			}
+		}
+
 		if p.msg.TxRunMode != core.MessageCommitMode && p.msg.GasFeeCap.BitLen() == 0 {
 			// In gas estimation or eth_call mode, we permit a zero gas fee cap.
 			// This matches behavior with normal tx gas estimation and eth_call.
@@ -256,20 +273,29 @@ func (p *TxProcessor) StartTxHook() (endTxNow bool, gasUsed uint64, err error, r
 
 		maxGasCost := arbmath.BigMulByUint(tx.GasFeeCap, usergas)
 		maxFeePerGasTooLow := arbmath.BigLessThan(tx.GasFeeCap, effectiveBaseFee)
-		if arbmath.BigLessThan(balance, maxGasCost) || usergas < params.TxGas || maxFeePerGasTooLow {
+		if arbmath.BigLessThan(balance, maxGasCost) || redeemGas < params.TxGas || maxFeePerGasTooLow {
 			// User either specified too low of a gas fee cap, didn't have enough balance to pay for gas,
-			// or the specified gas limit is below the minimum transaction gas cost.
+			// or the gas given to the auto-redeem is below the minimum transaction gas cost.
+			// That's the specified gas limit unless the chain owner set a lower ceiling on it.
 			// Either way, attempt to refund the gas costs, since we're not doing the auto-redeem.
 			gasCostRefund := takeFunds(availableRefund, maxGasCost)
-			if err := transfer(&tx.From, &tx.FeeRefundAddr, gasCostRefund); err != nil {
+			if err := transfer(&tx.From, &refundTo, gasCostRefund); err != nil {
 				// should never happen as from's balance should be at least availableRefund at this point
 				glog.Error("failed to transfer gasCostRefund", "err", err)
 			}
 			return true, 0, nil, ticketId.Bytes()
 		}
 
+		// refund the gas above the ceiling
+		if redeemGas < usergas {
+			excessGasRefund := takeFunds(availableRefund, arbmath.BigMulByUint(effectiveBaseFee, usergas-redeemGas))
+			if err := transfer(&tx.From, &refundTo, excessGasRefund); err != nil {
+				glog.Error("failed to transfer excessGasRefund", "err", err)
+			}
+		}
+
 		// pay for the retryable's gas and update the pools
-		gascost := arbmath.BigMulByUint(effectiveBaseFee, usergas)
+		gascost := arbmath.BigMulByUint(effectiveBaseFee, redeemGas)
 		networkCost := gascost
 		if p.state.ArbOSVersion() >= 11 {
 			infraFeeAccount, err := p.state.InfraFeeAccount()
@@ -278,7 +304,7 @@ func (p *TxProcessor) StartTxHook() (endTxNow bool, gasUsed uint64, err error, r
 				minBaseFee, err := p.state.L2PricingState().MinBaseFeeWei()
 				p.state.Restrict(err)
 				infraFee := arbmath.BigMin(minBaseFee, effectiveBaseFee)
-				infraCost := arbmath.BigMulByUint(infraFee, usergas)
+				infraCost := arbmath.BigMulByUint(infraFee, redeemGas)
 				infraCost = takeFunds(networkCost, infraCost)
 				if err := transfer(&tx.From, &infraFeeAccount, infraCost); err != nil {
 					glog.Error("failed to transfer gas cost to infrastructure fee account", "err", err)
@@ -301,7 +327,7 @@ func (p *TxProcessor) StartTxHook() (endTxNow bool, gasUsed uint64, err error, r
 			gasPriceRefund.SetInt64(0)
 		}
 		gasPriceRefund = takeFunds(availableRefund, gasPriceRefund)
-		if err := transfer(&tx.From, &tx.FeeRefundAddr, gasPriceRefund); err != nil {
+		if err := transfer(&tx.From, &refundTo, gasPriceRefund); err != nil {
 			glog.Error("failed to transfer gasPriceRefund", "err", err)
 		}
 		availableRefund.Add(availableRefund, withheldGasFunds)
@@ -312,9 +338,9 @@ func (p *TxProcessor) StartTxHook() (endTxNow bool, gasUsed uint64, err error, r
 			underlyingTx.ChainId(),
 			0,
 			effectiveBaseFee,
-			usergas,
+			redeemGas,
 			ticketId,
-			tx.FeeRefundAddr,
+			refundTo,
 			availableRefund,
 			submissionFee,
 		)
@@ -322,14 +348,17 @@ func (p *TxProcessor) StartTxHook() (endTxNow bool, gasUsed uint64, err error, r
 
 		_, err = retryable.IncrementNumTries()
 		p.state.Restrict(err)
+		if autoRedeemAttempts > 1 {
+			p.state.Restrict(retryable.SetAutoRedeemsLeft(autoRedeemAttempts - 1))
+		}
 
 		err = EmitReedeemScheduledEvent(
 			evm,
-			usergas,
+			redeemGas,
 			retryTxInner.Nonce,
 			ticketId,
 			types.NewTx(retryTxInner).Hash(),
-			tx.FeeRefundAddr,
+			refundTo,
 			availableRefund,
 			submissionFee,
 		)
@@ -340,13 +369,13 @@ func (p *TxProcessor) StartTxHook() (endTxNow bool, gasUsed uint64, err error, r
 		if tracer := evm.Config.Tracer; tracer != nil {
 			redeem, err := util.PackArbRetryableTxRedeem(ticketId)
 			if err == nil {
-				tracingInfo.MockCall(redeem, usergas, from, types.ArbRetryableTxAddress, common.Big0)
+				tracingInfo.MockCall(redeem, redeemGas, from, types.ArbRetryableTxAddress, common.Big0)
 			} else {
 				glog.Error("failed to abi-encode auto-redeem", "err", err)
 			}
 		}
 
-		return true, usergas, nil, ticketId.Bytes()
+		return true, redeemGas, nil, ticketId.Bytes()
 	case *types.ArbitrumRetryTx:
 
 		// Transfer callvalue from escrow
@@ -364,6 +393,9 @@ func (p *TxProcessor) StartTxHook() (endTxNow bool, gasUsed uint64, err error, r
 		p.CurrentRetryable = &ticketId
 		p.CurrentRefundTo = &refundTo
 	}
+	if tipe < types.ArbitrumDepositTxType {
+		startTxHooks(p)
+	}
 	return false, 0, nil, nil
 }
 
@@ -430,6 +462,10 @@ func (p *TxProcessor) GasChargingHook(gasRemaining *uint64) (common.Address, err
 	}
 	*gasRemaining -= gasNeededToStartEVM
 
+	if err := gasChargingHooks(p, gasRemaining); err != nil {
+		return tipReceipient, err
+	}
+
 	if p.msg.TxRunMode != core.MessageEthcallMode {
 		// If this is a real tx, limit the amount of computed based on the gas pool.
 		// We do this by charging extra gas, and then refunding it later.
@@ -463,6 +499,7 @@ func (p *TxProcessor) EndTxHook(gasLeft uint64, success bool) {
 		panic("Tx somehow refunds gas after computation")
 	}
 	gasUsed := p.msg.GasLimit - gasLeft
+	defer endTxHooks(p, gasUsed, success)
 
 	if underlyingTx != nil && underlyingTx.Type() == types.ArbitrumRetryTxType {
 		inner, _ := underlyingTx.GetInner().(*types.ArbitrumRetryTx)
@@ -506,31 +543,56 @@ func (p *TxProcessor) EndTxHook(gasLeft uint64, success bool) {
 			}
 		}
 
+		// A failed auto-redeem the chain owner has attempted more than once is tried again with the gas it left
+		var reattempt *retryables.Retryable
+		if p.state.ArbOSVersion() >= arbostypes.ArbosVersion_AutoRedeemConfig {
+			retryable, err := p.state.RetryableState().OpenRetryable(inner.TicketId, p.evm.Context.Time)
+			p.state.Restrict(err)
+			if retryable != nil {
+				attemptsLeft, err := retryable.AutoRedeemsLeft()
+				p.state.Restrict(err)
+				if attemptsLeft > 0 {
+					if !success && gasLeft >= params.TxGas {
+						reattempt = retryable
+						attemptsLeft--
+					} else {
+						attemptsLeft = 0
+					}
+					p.state.Restrict(retryable.SetAutoRedeemsLeft(attemptsLeft))
+				}
+			}
+		}
+
 		if success {
 			// If successful, refund the submission fee.
 			refund(networkFeeAccount, inner.SubmissionFeeRefund)
-		} else {
+		} else if reattempt == nil {
 			// The submission fee is still taken from the L1 deposit earlier, even if it's not refunded.
 			takeFunds(maxRefund, inner.SubmissionFeeRefund)
 		}
 		// Conceptually, the gas charge is taken from the L1 deposit pool if possible.
 		takeFunds(maxRefund, arbmath.BigMulByUint(effectiveBaseFee, gasUsed))
-		// Refund any unused gas, without overdrafting the L1 deposit.
-		networkRefund := gasRefund
-		if p.state.ArbOSVersion() >= 11 {
-			infraFeeAccount, err := p.state.InfraFeeAccount()
-			p.state.Restrict(err)
-			if infraFeeAccount != (common.Address{}) {
-				minBaseFee, err := p.state.L2PricingState().MinBaseFeeWei()
+		if reattempt != nil {
+			// The unused gas stays paid for, and is given to the next attempt rather than refunded
+			p.reattemptAutoRedeem(reattempt, inner, gasLeft, maxRefund)
+		} else {
+			// Refund any unused gas, without overdrafting the L1 deposit.
+			networkRefund := gasRefund
+			if p.state.ArbOSVersion() >= 11 {
+				infraFeeAccount, err := p.state.InfraFeeAccount()
 				p.state.Restrict(err)
-				// TODO MinBaseFeeWei change during RetryTx execution may cause incorrect calculation of the part of the refund that should be taken from infraFeeAccount. Unless the balances of network and infra fee accounts are too low, the amount transferred to refund address should remain correct.
-				infraFee := arbmath.BigMin(minBaseFee, effectiveBaseFee)
-				infraRefund := arbmath.BigMulByUint(infraFee, gasLeft)
-				infraRefund = takeFunds(networkRefund, infraRefund)
-				refund(infraFeeAccount, infraRefund)
+				if infraFeeAccount != (common.Address{}) {
+					minBaseFee, err := p.state.L2PricingState().MinBaseFeeWei()
+					p.state.Restrict(err)
+					// TODO MinBaseFeeWei change during RetryTx execution may cause incorrect calculation of the part of the refund that should be taken from infraFeeAccount. Unless the balances of network and infra fee accounts are too low, the amount transferred to refund address should remain correct.
+					infraFee := arbmath.BigMin(minBaseFee, effectiveBaseFee)
+					infraRefund := arbmath.BigMulByUint(infraFee, gasLeft)
+					infraRefund = takeFunds(networkRefund, infraRefund)
+					refund(infraFeeAccount, infraRefund)
+				}
 			}
+			refund(networkFeeAccount, networkRefund)
 		}
-		refund(networkFeeAccount, networkRefund)
 
 		if success {
 			// we don't want to charge for this
@@ -611,6 +673,37 @@ func (p *TxProcessor) EndTxHook(gasLeft uint64, success bool) {
 	}
 }
 
+// reattemptAutoRedeem schedules another attempt at the failed auto-redeem, with the gas it left unused
+func (p *TxProcessor) reattemptAutoRedeem(retryable *retryables.Retryable, failed *types.ArbitrumRetryTx, gas uint64, maxRefund *big.Int) {
+	nextNonce, err := retryable.IncrementNumTries()
+	p.state.Restrict(err)
+	nonce := nextNonce - 1
+	retryTxInner, err := retryable.MakeTx(
+		p.evm.ChainConfig().ChainID,
+		nonce,
+		failed.GasFeeCap,
+		gas,
+		failed.TicketId,
+		failed.RefundTo,
+		maxRefund,
+		failed.SubmissionFeeRefund,
+	)
+	p.state.Restrict(err)
+	err = EmitReedeemScheduledEvent(
+		p.evm,
+		gas,
+		nonce,
+		failed.TicketId,
+		types.NewTx(retryTxInner).Hash(),
+		failed.RefundTo,
+		maxRefund,
+		failed.SubmissionFeeRefund,
+	)
+	if err != nil {
+		glog.Error("failed to emit RedeemScheduled event", "err", err)
+	}
+}
+
 func (p *TxProcessor) ScheduledTxes() types.Transactions {
 	scheduled := types.Transactions{}
 	time := p.evm.Context.Time
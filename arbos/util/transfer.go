@@ -15,6 +15,12 @@ import (
 	"github.com/offchainlabs/nitro/util/arbmath"
 )
 
+// BalanceTransferTracer is implemented by tracers that record every ArbOS balance transfer with its purpose,
+// including those made during EVM execution, which are otherwise only traced as calls.
+type BalanceTransferTracer interface {
+	CaptureArbOSBalanceTransfer(from, to *common.Address, amount *big.Int, scenario TracingScenario, purpose string)
+}
+
 // TransferBalance represents a balance change occurring aside from a call.
 // While most uses will be transfers, setting `from` or `to` to nil will mint or burn funds, respectively.
 func TransferBalance(
@@ -43,6 +49,9 @@ func TransferBalance(
 			log.Error("Tracing scenario mismatch", "scenario", scenario, "depth", evm.Depth())
 			return errors.New("tracing scenario mismatch")
 		}
+		if balanceTracer, ok := tracer.(BalanceTransferTracer); ok {
+			balanceTracer.CaptureArbOSBalanceTransfer(from, to, amount, scenario, purpose)
+		}
 
 		if scenario != TracingDuringEVM {
 			tracer.CaptureArbitrumTransfer(evm, from, to, amount, scenario == TracingBeforeEVM, purpose)
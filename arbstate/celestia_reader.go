@@ -0,0 +1,113 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbstate
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+var ErrNoCelestiaReader = errors.New("celestia batch payload was encountered but no CelestiaReader was configured")
+
+// CelestiaNamespaceSize is the size of a Celestia namespace: a version byte followed by the namespace ID
+const CelestiaNamespaceSize = 29
+
+// CelestiaBlobPointerSize is the size of a serialized CelestiaBlobPointer, including its header byte
+const CelestiaBlobPointerSize = 1 + 8 + CelestiaNamespaceSize + 32 + 32
+
+// CelestiaBlobPointer locates batch data posted as a blob to Celestia. The DataHash commits the batch to
+// the data itself, so a Celestia node serving it can withhold the data but can't forge it.
+type CelestiaBlobPointer struct {
+	BlockHeight uint64
+	Namespace   [CelestiaNamespaceSize]byte
+	Commitment  common.Hash
+	DataHash    common.Hash
+}
+
+// CelestiaReader fetches the batch data a CelestiaBlobPointer points to
+type CelestiaReader interface {
+	Read(ctx context.Context, pointer *CelestiaBlobPointer) ([]byte, error)
+}
+
+// Serialize returns the pointer with its header byte, as it's posted to the sequencer inbox
+func (p *CelestiaBlobPointer) Serialize() []byte {
+	buf := make([]byte, 0, CelestiaBlobPointerSize)
+	buf = append(buf, CelestiaMessageHeaderFlag)
+	buf = binary.BigEndian.AppendUint64(buf, p.BlockHeight)
+	buf = append(buf, p.Namespace[:]...)
+	buf = append(buf, p.Commitment[:]...)
+	return append(buf, p.DataHash[:]...)
+}
+
+func DeserializeCelestiaBlobPointer(data []byte) (*CelestiaBlobPointer, error) {
+	if len(data) != CelestiaBlobPointerSize {
+		return nil, fmt.Errorf("celestia blob pointer has length %v but expected %v", len(data), CelestiaBlobPointerSize)
+	}
+	if !IsCelestiaMessageHeaderByte(data[0]) {
+		return nil, errors.New("tried to deserialize a message that doesn't have the celestia header")
+	}
+	p := &CelestiaBlobPointer{}
+	data = data[1:]
+	p.BlockHeight = binary.BigEndian.Uint64(data[:8])
+	data = data[8:]
+	copy(p.Namespace[:], data[:CelestiaNamespaceSize])
+	data = data[CelestiaNamespaceSize:]
+	copy(p.Commitment[:], data[:32])
+	copy(p.DataHash[:], data[32:])
+	return p, nil
+}
+
+// NewDAProviderCelestia is generally meant to be only used by nitro.
+// DA Providers should implement methods in the DataAvailabilityProvider interface independently
+func NewDAProviderCelestia(reader CelestiaReader) *dAProviderForCelestia {
+	return &dAProviderForCelestia{
+		reader: reader,
+	}
+}
+
+type dAProviderForCelestia struct {
+	reader CelestiaReader
+}
+
+func (c *dAProviderForCelestia) IsValidHeaderByte(headerByte byte) bool {
+	return IsCelestiaMessageHeaderByte(headerByte)
+}
+
+func (c *dAProviderForCelestia) RecoverPayloadFromBatch(
+	ctx context.Context,
+	batchNum uint64,
+	batchBlockHash common.Hash,
+	sequencerMsg []byte,
+	preimages map[arbutil.PreimageType]map[common.Hash][]byte,
+	keysetValidationMode KeysetValidationMode,
+) ([]byte, error) {
+	pointer, err := DeserializeCelestiaBlobPointer(sequencerMsg[40:])
+	if err != nil {
+		// The sequencer inbox doesn't check the pointer, so a malformed one makes for an empty batch
+		log.Warn("Failed to deserialize celestia blob pointer", "batch", batchNum, "err", err)
+		return nil, nil
+	}
+	payload, err := c.reader.Read(ctx, pointer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read celestia blob at height %v: %w", pointer.BlockHeight, err)
+	}
+	if crypto.Keccak256Hash(payload) != pointer.DataHash {
+		return nil, fmt.Errorf("%w: celestia blob at height %v", ErrHashMismatch, pointer.BlockHeight)
+	}
+	if preimages != nil {
+		if preimages[arbutil.Keccak256PreimageType] == nil {
+			preimages[arbutil.Keccak256PreimageType] = make(map[common.Hash][]byte)
+		}
+		preimages[arbutil.Keccak256PreimageType][pointer.DataHash] = payload
+	}
+	return payload, nil
+}
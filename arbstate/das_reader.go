@@ -26,6 +26,15 @@ type DataAvailabilityReader interface {
 
 var ErrHashMismatch = errors.New("result does not match expected hash")
 
+var ErrUnregisteredKeyset = errors.New("keyset hash was never registered on the parent chain")
+
+// KeysetHashValidator checks a certificate's keyset hash against the parent chain's SequencerInbox,
+// which records every keyset that was ever valid, so the keyset's signatures can be trusted without
+// trusting the DAS that served it.
+type KeysetHashValidator interface {
+	IsKeysetHashRegistered(ctx context.Context, keysetHash common.Hash) (bool, error)
+}
+
 // DASMessageHeaderFlag indicates that this data is a certificate for the data availability service,
 // which will retrieve the full batch data.
 const DASMessageHeaderFlag byte = 0x80
@@ -43,6 +52,10 @@ const ZeroheavyMessageHeaderFlag byte = 0x20
 // BlobHashesHeaderFlag indicates that this message contains EIP 4844 versioned hashes of the committments calculated over the blob data for the batch data.
 const BlobHashesHeaderFlag byte = L1AuthenticatedMessageHeaderFlag | 0x10 // 0x50
 
+// CelestiaMessageHeaderFlag indicates that this data is a pointer to batch data posted to a Celestia namespace.
+// Like the blob header it must be authenticated by the sequencer inbox, and it's only valid as a whole header byte.
+const CelestiaMessageHeaderFlag byte = L1AuthenticatedMessageHeaderFlag | 0x04 // 0x44
+
 // BrotliMessageHeaderByte indicates that the message is brotli-compressed.
 const BrotliMessageHeaderByte byte = 0
 
@@ -74,13 +87,18 @@ func IsBlobHashesHeaderByte(header byte) bool {
 	return hasBits(header, BlobHashesHeaderFlag)
 }
 
+func IsCelestiaMessageHeaderByte(header byte) bool {
+	return header == CelestiaMessageHeaderFlag
+}
+
 func IsBrotliMessageHeaderByte(b uint8) bool {
 	return b == BrotliMessageHeaderByte
 }
 
-// IsKnownHeaderByte returns true if the supplied header byte has only known bits
+// IsKnownHeaderByte returns true if the supplied header byte has only known bits,
+// or is the Celestia header byte, whose bit isn't known in combination with any others
 func IsKnownHeaderByte(b uint8) bool {
-	return b&^KnownHeaderBits == 0
+	return b&^KnownHeaderBits == 0 || b == CelestiaMessageHeaderFlag
 }
 
 type DataAvailabilityCertificate struct {
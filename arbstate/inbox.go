@@ -16,6 +16,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rlp"
 
 	"github.com/offchainlabs/nitro/arbcompress"
@@ -68,6 +69,34 @@ var (
 	ErrInvalidBlobDataFormat = errors.New("blob batch data is not a list of hashes as expected")
 )
 
+// Malformed batch data is part of the state transition function, so it must be handled the same way by
+// every node and by the prover. Data the sequencer inbox authenticated but this node can't interpret, such
+// as an unknown authenticated header byte, halts the node with an error, as it must be out of date. Any
+// other malformed data is skipped:
+//   - a payload that fails zeroheavy or brotli decoding, or has an unknown format, has no segments
+//     (an empty payload also has none, but isn't malformed, as delayed-only batches have one)
+//   - zeroheavy output beyond maxZeroheavyDecompressedLen is truncated before brotli decoding
+//   - segments after one that isn't valid RLP, or after MaxSegmentsPerSequencerMessage, are dropped
+//   - an advancing segment that isn't a valid RLP integer is ignored
+//   - an empty segment, a segment of an unknown kind, or a compressed L2 message that fails to
+//     decompress within arbostypes.MaxL2MessageSize produces no message
+//   - reading past the batch's delayed message count produces an invalid message
+//
+// Each of these is counted under arb/inbox/malformed each time the batch is parsed, to alert on.
+var (
+	malformedZeroheavyCounter        = metrics.NewRegisteredCounter("arb/inbox/malformed/zeroheavy", nil)
+	oversizedZeroheavyCounter        = metrics.NewRegisteredCounter("arb/inbox/malformed/zeroheavy/oversized", nil)
+	malformedDecompressionCounter    = metrics.NewRegisteredCounter("arb/inbox/malformed/decompression", nil)
+	malformedFormatCounter           = metrics.NewRegisteredCounter("arb/inbox/malformed/format", nil)
+	malformedSegmentCounter          = metrics.NewRegisteredCounter("arb/inbox/malformed/segment", nil)
+	tooManySegmentsCounter           = metrics.NewRegisteredCounter("arb/inbox/malformed/segments/toomany", nil)
+	malformedAdvancingSegmentCounter = metrics.NewRegisteredCounter("arb/inbox/malformed/segment/advancing", nil)
+	emptySegmentCounter              = metrics.NewRegisteredCounter("arb/inbox/malformed/segment/empty", nil)
+	malformedSegmentKindCounter      = metrics.NewRegisteredCounter("arb/inbox/malformed/segment/kind", nil)
+	malformedL2MessageCounter        = metrics.NewRegisteredCounter("arb/inbox/malformed/segment/l2message", nil)
+	readPastDelayedMessagesCounter   = metrics.NewRegisteredCounter("arb/inbox/malformed/segment/pastdelayed", nil)
+)
+
 func parseSequencerMessage(ctx context.Context, batchNum uint64, batchBlockHash common.Hash, data []byte, daProviders []DataAvailabilityProvider, keysetValidationMode KeysetValidationMode) (*sequencerMessage, error) {
 	if len(data) < 40 {
 		return nil, errors.New("sequencer message missing L1 header")
@@ -116,6 +145,8 @@ func parseSequencerMessage(ctx context.Context, batchNum uint64, batchBlockHash
 				log.Error("No DAS Reader configured, but sequencer message found with DAS header")
 			} else if IsBlobHashesHeaderByte(payload[0]) {
 				return nil, ErrNoBlobReader
+			} else if IsCelestiaMessageHeaderByte(payload[0]) {
+				return nil, ErrNoCelestiaReader
 			}
 		}
 	}
@@ -127,9 +158,14 @@ func parseSequencerMessage(ctx context.Context, batchNum uint64, batchBlockHash
 	if len(payload) > 0 && IsZeroheavyEncodedHeaderByte(payload[0]) {
 		pl, err := io.ReadAll(io.LimitReader(zeroheavy.NewZeroheavyDecoder(bytes.NewReader(payload[1:])), int64(maxZeroheavyDecompressedLen)))
 		if err != nil {
-			log.Warn("error reading from zeroheavy decoder", err.Error())
+			log.Warn("error reading from zeroheavy decoder", "batch", batchNum, "err", err)
+			malformedZeroheavyCounter.Inc(1)
 			return parsedMsg, nil
 		}
+		if len(pl) >= maxZeroheavyDecompressedLen {
+			log.Warn("zeroheavy decoded sequencer message truncated", "batch", batchNum, "length", len(pl))
+			oversizedZeroheavyCounter.Inc(1)
+		}
 		payload = pl
 	}
 
@@ -144,27 +180,30 @@ func parseSequencerMessage(ctx context.Context, batchNum uint64, batchBlockHash
 				err := stream.Decode(&segment)
 				if err != nil {
 					if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
-						log.Warn("error parsing sequencer message segment", "err", err.Error())
+						log.Warn("error parsing sequencer message segment", "batch", batchNum, "err", err.Error())
+						malformedSegmentCounter.Inc(1)
 					}
 					break
 				}
 				if len(parsedMsg.segments) >= MaxSegmentsPerSequencerMessage {
-					log.Warn("too many segments in sequence batch")
+					log.Warn("too many segments in sequence batch", "batch", batchNum)
+					tooManySegmentsCounter.Inc(1)
 					break
 				}
 				parsedMsg.segments = append(parsedMsg.segments, segment)
 			}
 		} else {
-			log.Warn("sequencer msg decompression failed", "err", err)
+			log.Warn("sequencer msg decompression failed", "batch", batchNum, "err", err)
+			malformedDecompressionCounter.Inc(1)
 		}
 	} else {
 		length := len(payload)
 		if length == 0 {
-			log.Warn("empty sequencer message")
+			log.Warn("empty sequencer message", "batch", batchNum)
 		} else {
-			log.Warn("unknown sequencer message format", "length", length, "firstByte", payload[0])
+			log.Warn("unknown sequencer message format", "batch", batchNum, "length", length, "firstByte", payload[0])
+			malformedFormatCounter.Inc(1)
 		}
-
 	}
 
 	return parsedMsg, nil
@@ -177,6 +216,18 @@ func RecoverPayloadFromDasBatch(
 	dasReader DataAvailabilityReader,
 	preimages map[arbutil.PreimageType]map[common.Hash][]byte,
 	keysetValidationMode KeysetValidationMode,
+) ([]byte, error) {
+	return recoverPayloadFromDasBatch(ctx, batchNum, sequencerMsg, dasReader, nil, preimages, keysetValidationMode)
+}
+
+func recoverPayloadFromDasBatch(
+	ctx context.Context,
+	batchNum uint64,
+	sequencerMsg []byte,
+	dasReader DataAvailabilityReader,
+	keysetValidator KeysetHashValidator,
+	preimages map[arbutil.PreimageType]map[common.Hash][]byte,
+	keysetValidationMode KeysetValidationMode,
 ) ([]byte, error) {
 	var keccakPreimages map[common.Hash][]byte
 	if preimages != nil {
@@ -228,6 +279,21 @@ func RecoverPayloadFromDasBatch(
 		return preimage, nil
 	}
 
+	// Version 0 certificates predate keysets being registered by their tree hash, so only newer ones can be checked.
+	// The SequencerInbox refuses batches with unregistered keysets, so this only fails if the parent chain's view is
+	// inconsistent with ours. Returning an error retries later instead of diverging from the replay binary.
+	if keysetValidator != nil && keysetValidationMode != KeysetDontValidate && version == 1 {
+		registered, err := keysetValidator.IsKeysetHashRegistered(ctx, cert.KeysetHash)
+		if err != nil {
+			log.Error("Couldn't check keyset hash on the parent chain", "err", err, "keysetHash", cert.KeysetHash)
+			return nil, err
+		}
+		if !registered {
+			log.Error("DAS certificate keyset was never registered", "keysetHash", cert.KeysetHash, "batchNum", batchNum)
+			return nil, fmt.Errorf("%w: %v", ErrUnregisteredKeyset, common.Hash(cert.KeysetHash))
+		}
+	}
+
 	keysetPreimage, err := getByHash(ctx, cert.KeysetHash)
 	if err != nil {
 		log.Error("Couldn't get keyset", "err", err)
@@ -301,8 +367,18 @@ func NewDAProviderDAS(das DataAvailabilityReader) *dAProviderForDAS {
 	}
 }
 
+// NewDAProviderDASWithKeysetValidator additionally checks each certificate's keyset hash with keysetValidator
+// before trusting the keyset's signatures
+func NewDAProviderDASWithKeysetValidator(das DataAvailabilityReader, keysetValidator KeysetHashValidator) *dAProviderForDAS {
+	return &dAProviderForDAS{
+		das:             das,
+		keysetValidator: keysetValidator,
+	}
+}
+
 type dAProviderForDAS struct {
-	das DataAvailabilityReader
+	das             DataAvailabilityReader
+	keysetValidator KeysetHashValidator
 }
 
 func (d *dAProviderForDAS) IsValidHeaderByte(headerByte byte) bool {
@@ -317,7 +393,7 @@ func (d *dAProviderForDAS) RecoverPayloadFromBatch(
 	preimages map[arbutil.PreimageType]map[common.Hash][]byte,
 	keysetValidationMode KeysetValidationMode,
 ) ([]byte, error) {
-	return RecoverPayloadFromDasBatch(ctx, batchNum, sequencerMsg, d.das, preimages, keysetValidationMode)
+	return recoverPayloadFromDasBatch(ctx, batchNum, sequencerMsg, d.das, d.keysetValidator, preimages, keysetValidationMode)
 }
 
 // NewDAProviderBlobReader is generally meant to be only used by nitro.
@@ -496,6 +572,7 @@ func (r *inboxMultiplexer) getNextMsg() (*arbostypes.MessageWithMetadata, error)
 			advancing, err := rlp.NewStream(rd, 16).Uint64()
 			if err != nil {
 				log.Warn("error parsing sequencer advancing segment", "err", err)
+				malformedAdvancingSegmentCounter.Inc(1)
 				segmentNum++
 				continue
 			}
@@ -535,6 +612,7 @@ func (r *inboxMultiplexer) getNextMsg() (*arbostypes.MessageWithMetadata, error)
 	}
 	if len(segment) == 0 {
 		log.Error("empty sequencer message segment", "sequence", r.cachedSegmentNum, "segmentNum", segmentNum)
+		emptySegmentCounter.Inc(1)
 		return nil, nil
 	}
 	kind := segment[0]
@@ -546,6 +624,7 @@ func (r *inboxMultiplexer) getNextMsg() (*arbostypes.MessageWithMetadata, error)
 			decompressed, err := arbcompress.Decompress(segment, arbostypes.MaxL2MessageSize)
 			if err != nil {
 				log.Info("dropping compressed message", "err", err, "delayedMsg", r.delayedMessagesRead)
+				malformedL2MessageCounter.Inc(1)
 				return nil, nil
 			}
 			segment = decompressed
@@ -573,6 +652,7 @@ func (r *inboxMultiplexer) getNextMsg() (*arbostypes.MessageWithMetadata, error)
 					"delayedMessagesRead", r.delayedMessagesRead,
 					"batchAfterDelayedMessages", seqMsg.afterDelayedMessages,
 				)
+				readPastDelayedMessagesCounter.Inc(1)
 			}
 			msg = &arbostypes.MessageWithMetadata{
 				Message:             arbostypes.InvalidL1Message,
@@ -591,6 +671,7 @@ func (r *inboxMultiplexer) getNextMsg() (*arbostypes.MessageWithMetadata, error)
 		}
 	} else {
 		log.Error("bad sequencer message segment kind", "sequence", r.cachedSegmentNum, "segmentNum", segmentNum, "kind", kind)
+		malformedSegmentKindCounter.Inc(1)
 		return nil, nil
 	}
 	return msg, nil
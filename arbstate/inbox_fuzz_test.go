@@ -7,10 +7,16 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/nitro/arbcompress"
+	"github.com/offchainlabs/nitro/arbos/arbosState"
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/zeroheavy"
 )
 
 type multiplexerBackend struct {
@@ -74,3 +80,48 @@ func FuzzInboxMultiplexer(f *testing.F) {
 		}
 	})
 }
+
+func FuzzParseSequencerMessage(f *testing.F) {
+	var segments []byte
+	for _, segment := range [][]byte{
+		{BatchSegmentKindAdvanceTimestamp, 0x01},
+		{BatchSegmentKindDelayedMessages},
+		append([]byte{BatchSegmentKindL2Message}, arbostypes.TestIncomingMessageWithRequestId.L2msg...),
+	} {
+		encoded, err := rlp.EncodeToBytes(segment)
+		if err != nil {
+			f.Fatal(err)
+		}
+		segments = append(segments, encoded...)
+	}
+	compressed, err := arbcompress.CompressWell(segments)
+	if err != nil {
+		f.Fatal(err)
+	}
+	zeroheavyEncoded, err := io.ReadAll(zeroheavy.NewZeroheavyEncoder(bytes.NewReader(append([]byte{BrotliMessageHeaderByte}, compressed...))))
+	if err != nil {
+		f.Fatal(err)
+	}
+	header := make([]byte, 40)
+	f.Add(append(append([]byte{}, header...), append([]byte{BrotliMessageHeaderByte}, compressed...)...))
+	f.Add(append(append([]byte{}, header...), append([]byte{BrotliMessageHeaderByte}, segments...)...))
+	f.Add(append(append([]byte{}, header...), append([]byte{ZeroheavyMessageHeaderFlag}, zeroheavyEncoded...)...))
+	f.Add(header)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) < 40 {
+			return
+		}
+		msg, err := parseSequencerMessage(context.Background(), 0, common.Hash{}, data, nil, KeysetValidate)
+		if err != nil {
+			// Only data this node can't interpret may halt it, everything else must be skipped
+			if !errors.Is(err, arbosState.ErrFatalNodeOutOfDate) && !errors.Is(err, ErrNoBlobReader) && !errors.Is(err, ErrNoCelestiaReader) {
+				t.Fatal("unexpected error parsing sequencer message", err)
+			}
+			return
+		}
+		if len(msg.segments) > MaxSegmentsPerSequencerMessage {
+			t.Fatal("parsed", len(msg.segments), "segments but the limit is", MaxSegmentsPerSequencerMessage)
+		}
+	})
+}
@@ -0,0 +1,279 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcastclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	archivedMessagesCounter = metrics.NewRegisteredCounter("arb/feed/archive/messages", nil)
+	archiveErrorsCounter    = metrics.NewRegisteredCounter("arb/feed/archive/errors", nil)
+	archiveDroppedCounter   = metrics.NewRegisteredCounter("arb/feed/archive/dropped", nil)
+	archivePendingGauge     = metrics.NewRegisteredGauge("arb/feed/archive/pending", nil)
+)
+
+type ArchiveConfig struct {
+	Enable        bool          `koanf:"enable"`
+	Bucket        string        `koanf:"bucket"`
+	ObjectPrefix  string        `koanf:"object-prefix"`
+	Region        string        `koanf:"region"`
+	AccessKey     string        `koanf:"access-key"`
+	SecretKey     string        `koanf:"secret-key"`
+	MaxMessages   int           `koanf:"max-messages" reload:"hot"`
+	FlushInterval time.Duration `koanf:"flush-interval" reload:"hot"`
+	MaxPending    int           `koanf:"max-pending" reload:"hot"`
+}
+
+var DefaultArchiveConfig = ArchiveConfig{
+	Enable:        false,
+	MaxMessages:   1000,
+	FlushInterval: time.Minute,
+	MaxPending:    1000000,
+}
+
+var TestArchiveConfig = ArchiveConfig{
+	Enable:        false,
+	MaxMessages:   10,
+	FlushInterval: 100 * time.Millisecond,
+	MaxPending:    1000,
+}
+
+func ArchiveConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultArchiveConfig.Enable, "archive the raw feed messages consumed to an AWS S3 bucket")
+	f.String(prefix+".bucket", DefaultArchiveConfig.Bucket, "S3 bucket")
+	f.String(prefix+".object-prefix", DefaultArchiveConfig.ObjectPrefix, "prefix to add to S3 objects")
+	f.String(prefix+".region", DefaultArchiveConfig.Region, "S3 region")
+	f.String(prefix+".access-key", DefaultArchiveConfig.AccessKey, "S3 access key")
+	f.String(prefix+".secret-key", DefaultArchiveConfig.SecretKey, "S3 secret key")
+	f.Int(prefix+".max-messages", DefaultArchiveConfig.MaxMessages, "maximum number of feed messages per archive object")
+	f.Duration(prefix+".flush-interval", DefaultArchiveConfig.FlushInterval, "maximum time to wait before archiving feed messages which don't fill an archive object")
+	f.Int(prefix+".max-pending", DefaultArchiveConfig.MaxPending, "maximum number of feed messages waiting to be archived, the oldest are dropped beyond this if archiving fails")
+}
+
+func (c *ArchiveConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.Bucket == "" {
+		return errors.New("feed archive bucket must be set")
+	}
+	if c.MaxMessages <= 0 {
+		return errors.New("feed archive max-messages must be positive")
+	}
+	if c.FlushInterval <= 0 {
+		return errors.New("feed archive flush-interval must be positive")
+	}
+	if c.MaxPending < c.MaxMessages {
+		return errors.New("feed archive max-pending must be at least max-messages")
+	}
+	return nil
+}
+
+type ArchiveConfigFetcher func() *ArchiveConfig
+
+// ArchiveStorage is where archived feed messages are written to
+type ArchiveStorage interface {
+	PutObject(ctx context.Context, key string, value []byte) error
+}
+
+type s3ArchiveStorage struct {
+	client *s3.Client
+	bucket string
+}
+
+func NewS3ArchiveStorage(config *ArchiveConfig) (ArchiveStorage, error) {
+	cfg, err := awsConfig.LoadDefaultConfig(context.TODO(), awsConfig.WithRegion(config.Region), func(options *awsConfig.LoadOptions) error {
+		if config.AccessKey != "" && config.SecretKey != "" {
+			options.Credentials = credentials.NewStaticCredentialsProvider(config.AccessKey, config.SecretKey, "")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s3ArchiveStorage{
+		client: s3.NewFromConfig(cfg),
+		bucket: config.Bucket,
+	}, nil
+}
+
+func (s *s3ArchiveStorage) PutObject(ctx context.Context, key string, value []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(value),
+	})
+	return err
+}
+
+// ArchiveObjectKey is the key feed messages first through last are archived under, archived at the given time.
+// Sequence numbers are zero padded so listing the keys in order indexes the archive by sequence number, and
+// messages broadcast again after a feed reorg are kept alongside the originals.
+func ArchiveObjectKey(prefix string, first, last uint64, archivedAt time.Time) string {
+	return fmt.Sprintf("%s%020d-%020d-%d.json", prefix, first, last, archivedAt.UnixMilli())
+}
+
+// FeedArchiver passes consumed feed messages on to a TransactionStreamerInterface, and archives them in
+// the background, in the same format as they're broadcast, as an independent and replayable record of
+// what the sequencer broadcast.
+type FeedArchiver struct {
+	stopwaiter.StopWaiter
+	config  ArchiveConfigFetcher
+	storage ArchiveStorage
+	next    TransactionStreamerInterface
+
+	mutex   sync.Mutex
+	pending []*m.BroadcastFeedMessage
+	flushCh chan struct{}
+}
+
+func NewFeedArchiver(config ArchiveConfigFetcher, storage ArchiveStorage, next TransactionStreamerInterface) *FeedArchiver {
+	return &FeedArchiver{
+		config:  config,
+		storage: storage,
+		next:    next,
+		flushCh: make(chan struct{}, 1),
+	}
+}
+
+func (a *FeedArchiver) AddBroadcastMessages(feedMessages []*m.BroadcastFeedMessage) error {
+	if err := a.next.AddBroadcastMessages(feedMessages); err != nil {
+		return err
+	}
+	config := a.config()
+	a.mutex.Lock()
+	a.pending = append(a.pending, feedMessages...)
+	if excess := len(a.pending) - config.MaxPending; excess > 0 {
+		log.Error("too many feed messages waiting to be archived, dropping the oldest", "dropped", excess)
+		archiveDroppedCounter.Inc(int64(excess))
+		a.pending = a.pending[excess:]
+	}
+	full := len(a.pending) >= config.MaxMessages
+	archivePendingGauge.Update(int64(len(a.pending)))
+	a.mutex.Unlock()
+	if full {
+		select {
+		case a.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// nextChunk returns the pending messages to archive next: up to max-messages with consecutive sequence numbers
+func (a *FeedArchiver) nextChunk(maxMessages int) []*m.BroadcastFeedMessage {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	length := 1
+	for length < len(a.pending) && length < maxMessages {
+		if a.pending[length].SequenceNumber != a.pending[length-1].SequenceNumber+1 {
+			break
+		}
+		length++
+	}
+	if length > len(a.pending) {
+		length = len(a.pending)
+	}
+	return a.pending[:length]
+}
+
+func (a *FeedArchiver) removeChunk(chunk []*m.BroadcastFeedMessage) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	// The oldest messages may have been dropped while the chunk was being archived
+	for len(chunk) > 0 && len(a.pending) > 0 {
+		if a.pending[0] == chunk[0] {
+			a.pending = a.pending[1:]
+		}
+		chunk = chunk[1:]
+	}
+	archivePendingGauge.Update(int64(len(a.pending)))
+}
+
+// flush archives pending messages, stopping at the first error or once only a partial chunk is left, unless all is set
+func (a *FeedArchiver) flush(ctx context.Context, all bool) error {
+	for {
+		maxMessages := a.config().MaxMessages
+		chunk := a.nextChunk(maxMessages)
+		if len(chunk) == 0 || (!all && len(chunk) < maxMessages && len(chunk) == a.pendingCount()) {
+			return nil
+		}
+		data, err := json.Marshal(m.BroadcastMessage{
+			Version:  m.V1,
+			Messages: chunk,
+		})
+		if err != nil {
+			return err
+		}
+		first := uint64(chunk[0].SequenceNumber)
+		last := uint64(chunk[len(chunk)-1].SequenceNumber)
+		key := ArchiveObjectKey(a.config().ObjectPrefix, first, last, time.Now())
+		if err := a.storage.PutObject(ctx, key, data); err != nil {
+			archiveErrorsCounter.Inc(1)
+			return fmt.Errorf("error archiving feed messages %v to %v: %w", first, last, err)
+		}
+		archivedMessagesCounter.Inc(int64(len(chunk)))
+		log.Debug("archived feed messages", "first", first, "last", last, "key", key)
+		a.removeChunk(chunk)
+	}
+}
+
+func (a *FeedArchiver) pendingCount() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return len(a.pending)
+}
+
+func (a *FeedArchiver) Start(ctxIn context.Context) {
+	a.StopWaiter.Start(ctxIn, a)
+	a.LaunchThread(func(ctx context.Context) {
+		timer := time.NewTimer(a.config().FlushInterval)
+		defer timer.Stop()
+		for {
+			all := false
+			select {
+			case <-ctx.Done():
+				return
+			case <-a.flushCh:
+			case <-timer.C:
+				all = true
+			}
+			if err := a.flush(ctx, all); err != nil {
+				log.Warn("failed to archive feed messages", "err", err)
+			}
+			if all {
+				timer.Reset(a.config().FlushInterval)
+			}
+		}
+	})
+}
+
+// StopAndWait stops archiving, and makes a last attempt at archiving any pending messages
+func (a *FeedArchiver) StopAndWait() {
+	a.StopWaiter.StopAndWait()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := a.flush(ctx, true); err != nil {
+		log.Error("failed to archive feed messages on shutdown", "err", err, "pending", a.pendingCount())
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcastclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+)
+
+type memoryArchiveStorage struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+	failing bool
+}
+
+func (s *memoryArchiveStorage) PutObject(ctx context.Context, key string, value []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.failing {
+		return errors.New("storage unavailable")
+	}
+	s.objects[key] = value
+	return nil
+}
+
+// archivedSequenceNumbers returns the sequence numbers archived, in key order
+func (s *memoryArchiveStorage) archivedSequenceNumbers(t *testing.T) []arbutil.MessageIndex {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var keys []string
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var seqNums []arbutil.MessageIndex
+	for _, key := range keys {
+		var msg m.BroadcastMessage
+		Require(t, json.Unmarshal(s.objects[key], &msg))
+		for _, feedMessage := range msg.Messages {
+			seqNums = append(seqNums, feedMessage.SequenceNumber)
+		}
+	}
+	return seqNums
+}
+
+type nopTransactionStreamer struct{}
+
+func (nopTransactionStreamer) AddBroadcastMessages(feedMessages []*m.BroadcastFeedMessage) error {
+	return nil
+}
+
+func addTestFeedMessages(t *testing.T, archiver *FeedArchiver, first, count int) {
+	t.Helper()
+	for i := first; i < first+count; i++ {
+		Require(t, archiver.AddBroadcastMessages([]*m.BroadcastFeedMessage{{SequenceNumber: arbutil.MessageIndex(i)}}))
+	}
+}
+
+func waitForArchived(t *testing.T, storage *memoryArchiveStorage, expected []arbutil.MessageIndex) {
+	t.Helper()
+	var archived []arbutil.MessageIndex
+	for i := 0; i < 100; i++ {
+		archived = storage.archivedSequenceNumbers(t)
+		if len(archived) >= len(expected) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if len(archived) != len(expected) {
+		t.Fatal("archived", archived, "but expected", expected)
+	}
+	for i := range expected {
+		if archived[i] != expected[i] {
+			t.Fatal("archived", archived, "but expected", expected)
+		}
+	}
+}
+
+func seqNumRange(first, count int) []arbutil.MessageIndex {
+	var seqNums []arbutil.MessageIndex
+	for i := first; i < first+count; i++ {
+		seqNums = append(seqNums, arbutil.MessageIndex(i))
+	}
+	return seqNums
+}
+
+func TestFeedArchiver(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := TestArchiveConfig
+	config.FlushInterval = time.Hour
+	storage := &memoryArchiveStorage{objects: make(map[string][]byte)}
+	archiver := NewFeedArchiver(func() *ArchiveConfig { return &config }, storage, nopTransactionStreamer{})
+	archiver.Start(ctx)
+
+	// Only full chunks are archived before the flush interval
+	addTestFeedMessages(t, archiver, 0, 25)
+	waitForArchived(t, storage, seqNumRange(0, 20))
+	time.Sleep(50 * time.Millisecond)
+	if archived := storage.archivedSequenceNumbers(t); len(archived) != 20 {
+		t.Fatal("partial chunk archived before the flush interval", archived)
+	}
+
+	// Messages broadcast again after a feed reorg are archived separately from the originals
+	addTestFeedMessages(t, archiver, 23, 7)
+	archiver.StopAndWait()
+	expected := append(seqNumRange(0, 25), seqNumRange(23, 7)...)
+	waitForArchived(t, storage, expected)
+}
+
+func TestFeedArchiverStorageFailure(t *testing.T) {
+	config := TestArchiveConfig
+	config.MaxPending = 15
+	storage := &memoryArchiveStorage{objects: make(map[string][]byte), failing: true}
+	archiver := NewFeedArchiver(func() *ArchiveConfig { return &config }, storage, nopTransactionStreamer{})
+
+	ctx := context.Background()
+	addTestFeedMessages(t, archiver, 0, 20)
+	if err := archiver.flush(ctx, true); err == nil {
+		t.Fatal("expected archiving to fail")
+	}
+	// Failed messages are kept for the next attempt, but the oldest are dropped once too many are pending
+	if archiver.pendingCount() != 15 {
+		t.Fatal("expected 15 pending messages but got", archiver.pendingCount())
+	}
+
+	storage.mutex.Lock()
+	storage.failing = false
+	storage.mutex.Unlock()
+	Require(t, archiver.flush(ctx, true))
+	waitForArchived(t, storage, seqNumRange(5, 15))
+}
@@ -45,6 +45,9 @@ type FeedConfig struct {
 }
 
 func (fc *FeedConfig) Validate() error {
+	if err := fc.Input.Archive.Validate(); err != nil {
+		return err
+	}
 	return fc.Output.Validate()
 }
 
@@ -72,6 +75,7 @@ type Config struct {
 	SecondaryURL            []string                 `koanf:"secondary-url"`
 	Verify                  signature.VerifierConfig `koanf:"verify"`
 	EnableCompression       bool                     `koanf:"enable-compression" reload:"hot"`
+	Archive                 ArchiveConfig            `koanf:"archive" reload:"hot"`
 }
 
 func (c *Config) Enable() bool {
@@ -90,6 +94,7 @@ func ConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.StringSlice(prefix+".secondary-url", DefaultConfig.SecondaryURL, "list of secondary URLs of sequencer feed source. Would be started in the order they appear in the list when primary feeds fails")
 	signature.FeedVerifierConfigAddOptions(prefix+".verify", f)
 	f.Bool(prefix+".enable-compression", DefaultConfig.EnableCompression, "enable per message deflate compression support")
+	ArchiveConfigAddOptions(prefix+".archive", f)
 }
 
 var DefaultConfig = Config{
@@ -102,6 +107,7 @@ var DefaultConfig = Config{
 	SecondaryURL:            []string{},
 	Timeout:                 20 * time.Second,
 	EnableCompression:       true,
+	Archive:                 DefaultArchiveConfig,
 }
 
 var DefaultTestConfig = Config{
@@ -114,6 +120,7 @@ var DefaultTestConfig = Config{
 	SecondaryURL:            []string{},
 	Timeout:                 200 * time.Millisecond,
 	EnableCompression:       true,
+	Archive:                 TestArchiveConfig,
 }
 
 type TransactionStreamerInterface interface {
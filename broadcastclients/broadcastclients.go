@@ -47,6 +47,7 @@ type BroadcastClients struct {
 
 	primaryRouter   *Router
 	secondaryRouter *Router
+	archiver        *broadcastclient.FeedArchiver
 
 	// Use atomic access
 	connected int32
@@ -65,6 +66,15 @@ func NewBroadcastClients(
 	if len(config.URL) == 0 && len(config.SecondaryURL) == 0 {
 		return nil, nil
 	}
+	var archiver *broadcastclient.FeedArchiver
+	if config.Archive.Enable {
+		storage, err := broadcastclient.NewS3ArchiveStorage(&config.Archive)
+		if err != nil {
+			return nil, err
+		}
+		archiver = broadcastclient.NewFeedArchiver(func() *broadcastclient.ArchiveConfig { return &configFetcher().Archive }, storage, txStreamer)
+		txStreamer = archiver
+	}
 	newStandardRouter := func() *Router {
 		return &Router{
 			messageChan:                 make(chan m.BroadcastFeedMessage, ROUTER_QUEUE_SIZE),
@@ -79,6 +89,7 @@ func NewBroadcastClients(
 		primaryClients:   make([]*broadcastclient.BroadcastClient, 0, len(config.URL)),
 		secondaryClients: make([]*broadcastclient.BroadcastClient, 0, len(config.SecondaryURL)),
 		secondaryURL:     config.SecondaryURL,
+		archiver:         archiver,
 	}
 	clients.makeClient = func(url string, router *Router) (*broadcastclient.BroadcastClient, error) {
 		return broadcastclient.NewBroadcastClient(
@@ -132,6 +143,9 @@ func clearAndResetTicker(timer *time.Ticker, interval time.Duration) {
 }
 
 func (bcs *BroadcastClients) Start(ctx context.Context) {
+	if bcs.archiver != nil {
+		bcs.archiver.Start(ctx)
+	}
 	bcs.primaryRouter.StopWaiter.Start(ctx, bcs.primaryRouter)
 	bcs.secondaryRouter.StopWaiter.Start(ctx, bcs.secondaryRouter)
 
@@ -283,4 +297,7 @@ func (bcs *BroadcastClients) StopAndWait() {
 	for _, client := range bcs.secondaryClients {
 		client.StopAndWait()
 	}
+	if bcs.archiver != nil {
+		bcs.archiver.StopAndWait()
+	}
 }
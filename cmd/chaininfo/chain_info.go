@@ -31,6 +31,8 @@ type ChainInfo struct {
 	HasGenesisState           bool                `json:"has-genesis-state"`
 	ChainConfig               *params.ChainConfig `json:"chain-config"`
 	RollupAddresses           *RollupAddresses    `json:"rollup"`
+	// Confirmations to trust instead of the safe and finalized block tags, if the parent chain doesn't support them
+	ParentChainTagFallbackConfirmations uint64 `json:"parent-chain-tag-fallback-confirmations"`
 }
 
 func GetChainConfig(chainId *big.Int, chainName string, genesisBlockNum uint64, l2ChainInfoFiles []string, l2ChainInfoJson string) (*params.ChainConfig, error) {
@@ -13,10 +13,17 @@ import (
 )
 
 type ParentChainConfig struct {
-	ID         uint64                        `koanf:"id"`
-	Connection rpcclient.ClientConfig        `koanf:"connection" reload:"hot"`
-	Wallet     genericconf.WalletConfig      `koanf:"wallet"`
-	BlobClient headerreader.BlobClientConfig `koanf:"blob-client"`
+	ID                 uint64                        `koanf:"id"`
+	Connection         rpcclient.ClientConfig        `koanf:"connection" reload:"hot"`
+	ConnectionFailover rpcclient.FailoverConfig      `koanf:"connection-failover" reload:"hot"`
+	ArchiveConnection  rpcclient.ClientConfig        `koanf:"archive-connection" reload:"hot"`
+	ArchiveAfterBlocks uint64                        `koanf:"archive-after-blocks" reload:"hot"`
+	Wallet             genericconf.WalletConfig      `koanf:"wallet"`
+	BlobClient         headerreader.BlobClientConfig `koanf:"blob-client"`
+
+	// LightClient serves parent chain headers verified by a light client such as Helios. If its url is set, the
+	// logs and transactions the inbox is read from are checked against those headers rather than trusted.
+	LightClient rpcclient.ClientConfig `koanf:"light-client" reload:"hot"`
 }
 
 var L1ConnectionConfigDefault = rpcclient.ClientConfig{
@@ -28,10 +35,14 @@ var L1ConnectionConfigDefault = rpcclient.ClientConfig{
 }
 
 var L1ConfigDefault = ParentChainConfig{
-	ID:         0,
-	Connection: L1ConnectionConfigDefault,
-	Wallet:     DefaultL1WalletConfig,
-	BlobClient: headerreader.DefaultBlobClientConfig,
+	ID:                 0,
+	Connection:         L1ConnectionConfigDefault,
+	ConnectionFailover: rpcclient.DefaultFailoverConfig,
+	ArchiveConnection:  L1ConnectionConfigDefault,
+	ArchiveAfterBlocks: 1000,
+	LightClient:        L1ConnectionConfigDefault,
+	Wallet:             DefaultL1WalletConfig,
+	BlobClient:         headerreader.DefaultBlobClientConfig,
 }
 
 var DefaultL1WalletConfig = genericconf.WalletConfig{
@@ -45,6 +56,10 @@ var DefaultL1WalletConfig = genericconf.WalletConfig{
 func L1ConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Uint64(prefix+".id", L1ConfigDefault.ID, "if set other than 0, will be used to validate database and L1 connection")
 	rpcclient.RPCClientAddOptions(prefix+".connection", f, &L1ConfigDefault.Connection)
+	rpcclient.FailoverConfigAddOptions(prefix+".connection-failover", f)
+	rpcclient.RPCClientAddOptions(prefix+".archive-connection", f, &L1ConfigDefault.ArchiveConnection)
+	f.Uint64(prefix+".archive-after-blocks", L1ConfigDefault.ArchiveAfterBlocks, "send log queries and calls for blocks more than this many blocks behind the head to the archive connection, if its url is set")
+	rpcclient.RPCClientAddOptions(prefix+".light-client", f, &L1ConfigDefault.LightClient)
 	genericconf.WalletConfigAddOptions(prefix+".wallet", f, L1ConfigDefault.Wallet.Pathname)
 	headerreader.BlobClientAddOptions(prefix+".blob-client", f)
 }
@@ -54,7 +69,16 @@ func (c *ParentChainConfig) ResolveDirectoryNames(chain string) {
 }
 
 func (c *ParentChainConfig) Validate() error {
-	return c.Connection.Validate()
+	if err := c.Connection.Validate(); err != nil {
+		return err
+	}
+	if err := c.ArchiveConnection.Validate(); err != nil {
+		return err
+	}
+	if err := c.LightClient.Validate(); err != nil {
+		return err
+	}
+	return c.ConnectionFailover.Validate()
 }
 
 type L2Config struct {
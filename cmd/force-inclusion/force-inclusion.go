@@ -0,0 +1,121 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// force-inclusion forces delayed messages the sequencer has left out of the sequencer inbox for longer
+// than its delay window into the inbox, so users the sequencer censors can be served by anyone
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/cmd/util"
+	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
+)
+
+func main() {
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.LvlInfo)
+	log.Root().SetHandler(glogger)
+
+	if err := mainImpl(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func mainImpl() error {
+	l1conn := flag.String("l1conn", "", "parent chain connection")
+	bridgeAddr := flag.String("bridge", "", "bridge address")
+	seqInboxAddr := flag.String("sequencerInbox", "", "sequencer inbox address")
+	fromBlock := flag.Uint64("fromBlock", 0, "parent chain block to start looking for delayed messages from, at or before the first one the sequencer inbox hasn't read (usually the rollup's deployment block)")
+	blocksPerQuery := flag.Uint64("blocksPerQuery", 10000, "number of parent chain blocks to look for delayed messages in per log query")
+	l1keystore := flag.String("l1keystore", "", "parent chain private key store")
+	l1account := flag.String("l1account", "", "parent chain account to use (default is first account in keystore)")
+	l1passphrase := flag.String("l1passphrase", "passphrase", "parent chain private key file passphrase")
+	l1privatekey := flag.String("l1privatekey", "", "parent chain private key")
+	dryRun := flag.Bool("dryRun", false, "only report the delayed messages that could be force included")
+	txTimeout := flag.Duration("txTimeout", 10*time.Minute, "timeout when waiting for the force inclusion transaction to be included in a block")
+	flag.Parse()
+
+	if !common.IsHexAddress(*bridgeAddr) {
+		return fmt.Errorf("invalid bridge address %q", *bridgeAddr)
+	}
+	if !common.IsHexAddress(*seqInboxAddr) {
+		return fmt.Errorf("invalid sequencer inbox address %q", *seqInboxAddr)
+	}
+	ctx := context.Background()
+	client, err := ethclient.DialContext(ctx, *l1conn)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	bridge, err := arbnode.NewDelayedBridge(client, common.HexToAddress(*bridgeAddr), *fromBlock)
+	if err != nil {
+		return err
+	}
+	seqInbox, err := bridgegen.NewSequencerInbox(common.HexToAddress(*seqInboxAddr), client)
+	if err != nil {
+		return err
+	}
+	forceIncluder, err := arbnode.NewForceIncluder(client, bridge, seqInbox, *blocksPerQuery)
+	if err != nil {
+		return err
+	}
+	msg, err := forceIncluder.FindMessageToForceInclude(ctx)
+	if err != nil {
+		return err
+	}
+	if msg == nil {
+		fmt.Println("No delayed messages to force include")
+		return nil
+	}
+	header := msg.Message.Header
+	fmt.Printf("Delayed messages up to %v, from parent chain block %v at %v, can be force included\n", header.RequestId.Big(), msg.ParentChainBlockNumber, time.Unix(int64(header.Timestamp), 0).UTC())
+	if *dryRun {
+		return nil
+	}
+
+	chainId, err := client.ChainID(ctx)
+	if err != nil {
+		return err
+	}
+	wallet := genericconf.WalletConfig{
+		Pathname:   *l1keystore,
+		Account:    *l1account,
+		Password:   *l1passphrase,
+		PrivateKey: *l1privatekey,
+	}
+	txOpts, _, err := util.OpenWallet("l1", &wallet, chainId)
+	if err != nil {
+		return fmt.Errorf("error opening wallet: %w", err)
+	}
+	txOpts.Context = ctx
+	tx, err := forceIncluder.ForceInclude(txOpts, msg)
+	if err != nil {
+		return fmt.Errorf("error forcing inclusion: %w", err)
+	}
+	fmt.Println("Sent force inclusion transaction", tx.Hash())
+	waitCtx, cancel := context.WithTimeout(ctx, *txTimeout)
+	defer cancel()
+	receipt, err := bind.WaitMined(waitCtx, client, tx)
+	if err != nil {
+		return err
+	}
+	if receipt.Status != 1 {
+		return fmt.Errorf("force inclusion transaction %v failed", tx.Hash())
+	}
+	fmt.Println("Forced inclusion of delayed messages up to", header.RequestId.Big(), "in parent chain block", receipt.BlockNumber)
+	return nil
+}
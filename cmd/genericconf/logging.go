@@ -2,9 +2,11 @@ package genericconf
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/log"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -13,6 +15,8 @@ import (
 var globalFileHandlerFactory = fileHandlerFactory{}
 
 type fileHandlerFactory struct {
+	// mutex guards writer against RotateLog, the factory is otherwise only used by InitLog
+	mutex   sync.Mutex
 	writer  *lumberjack.Logger
 	records chan *log.Record
 	cancel  context.CancelFunc
@@ -83,6 +87,8 @@ func InitLog(logType string, logLevel log.Lvl, fileLoggingConfig *FileLoggingCon
 		return fmt.Errorf("error parsing log type: %w", err)
 	}
 	var glogger *log.GlogHandler
+	globalFileHandlerFactory.mutex.Lock()
+	defer globalFileHandlerFactory.mutex.Unlock()
 	// always close previous instance of file logger
 	if err := globalFileHandlerFactory.close(); err != nil {
 		return fmt.Errorf("failed to close file writer: %w", err)
@@ -101,3 +107,13 @@ func InitLog(logType string, logLevel log.Lvl, fileLoggingConfig *FileLoggingCon
 	log.Root().SetHandler(glogger)
 	return nil
 }
+
+// RotateLog closes the log file and starts a new one, keeping the old one as a backup
+func RotateLog() error {
+	globalFileHandlerFactory.mutex.Lock()
+	defer globalFileHandlerFactory.mutex.Unlock()
+	if globalFileHandlerFactory.writer == nil {
+		return errors.New("file logging is not enabled")
+	}
+	return globalFileHandlerFactory.writer.Rotate()
+}
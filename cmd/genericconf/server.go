@@ -278,3 +278,27 @@ func PProfAddOptions(prefix string, f *flag.FlagSet) {
 	f.String(prefix+".addr", PProfDefault.Addr, "pprof server address")
 	f.Int(prefix+".port", PProfDefault.Port, "pprof server port")
 }
+
+type OperatorAPIConfig struct {
+	Enable      bool   `koanf:"enable"`
+	Addr        string `koanf:"addr"`
+	Port        int    `koanf:"port"`
+	TokenFile   string `koanf:"token-file"`
+	SnapshotDir string `koanf:"snapshot-dir"`
+}
+
+var OperatorAPIConfigDefault = OperatorAPIConfig{
+	Enable:      false,
+	Addr:        "127.0.0.1",
+	Port:        8550,
+	TokenFile:   "",
+	SnapshotDir: "",
+}
+
+func OperatorAPIConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", OperatorAPIConfigDefault.Enable, "enable the REST API for operational tasks")
+	f.String(prefix+".addr", OperatorAPIConfigDefault.Addr, "operator REST API server listening interface")
+	f.Int(prefix+".port", OperatorAPIConfigDefault.Port, "operator REST API server listening port")
+	f.String(prefix+".token-file", OperatorAPIConfigDefault.TokenFile, "path to file holding the bearer token requests must authenticate with (default is operator-token in the global config directory, created if missing)")
+	f.String(prefix+".snapshot-dir", OperatorAPIConfigDefault.SnapshotDir, "directory to write state snapshots to (default is snapshots in the chain directory)")
+}
@@ -0,0 +1,126 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// inbox-timebounds reports how long a chain can keep sequencing through L1 stalls and
+// batch poster downtime before violating the sequencer inbox's time bounds
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/execution/gethexec"
+	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
+)
+
+func main() {
+	if err := mainImpl(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func mainImpl() error {
+	l1conn := flag.String("l1conn", "", "L1 connection, used to read the sequencer inbox's time bounds and the recent L1 block time")
+	seqInboxAddr := flag.String("sequencerInbox", "", "sequencer inbox address")
+	sampleBlocks := flag.Uint64("sampleBlocks", 1000, "number of recent L1 blocks to average the L1 block time over")
+	l1BlockTime := flag.Duration("l1BlockTime", 0, "L1 block time (default is measured from recent L1 blocks)")
+	delayBlocks := flag.Uint64("delayBlocks", 0, "max time variation delay blocks (default is read from the sequencer inbox)")
+	futureBlocks := flag.Uint64("futureBlocks", 0, "max time variation future blocks (default is read from the sequencer inbox)")
+	delaySeconds := flag.Uint64("delaySeconds", 0, "max time variation delay seconds (default is read from the sequencer inbox)")
+	futureSeconds := flag.Uint64("futureSeconds", 0, "max time variation future seconds (default is read from the sequencer inbox)")
+	maxDelay := flag.Duration("maxDelay", arbnode.DefaultBatchPosterConfig.MaxDelay, "the batch poster's max-delay")
+	postingLatency := flag.Duration("postingLatency", 5*time.Minute, "how long a batch posting transaction takes to be included, including retries")
+	maxTimestampDelta := flag.Duration("maxAcceptableTimestampDelta", gethexec.DefaultSequencerConfig.MaxAcceptableTimestampDelta, "the sequencer's max-acceptable-timestamp-delta")
+	targetDowntime := flag.Duration("targetDowntime", time.Hour, "batch poster downtime to tolerate when recommending a max-delay")
+	flag.Parse()
+
+	params := simulationParams{
+		Bounds: timeBounds{
+			DelayBlocks:   *delayBlocks,
+			FutureBlocks:  *futureBlocks,
+			DelaySeconds:  *delaySeconds,
+			FutureSeconds: *futureSeconds,
+		},
+		L1BlockTime:                 *l1BlockTime,
+		MaxDelay:                    *maxDelay,
+		PostingLatency:              *postingLatency,
+		MaxAcceptableTimestampDelta: *maxTimestampDelta,
+		TargetDowntime:              *targetDowntime,
+	}
+	needBounds := params.Bounds.DelayBlocks == 0 || params.Bounds.DelaySeconds == 0
+	if needBounds || params.L1BlockTime == 0 {
+		if *l1conn == "" {
+			return fmt.Errorf("must specify l1conn unless the time bounds and L1 block time are given")
+		}
+		ctx := context.Background()
+		client, err := ethclient.DialContext(ctx, *l1conn)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		if needBounds {
+			if !common.IsHexAddress(*seqInboxAddr) {
+				return fmt.Errorf("invalid sequencer inbox address %q", *seqInboxAddr)
+			}
+			seqInbox, err := bridgegen.NewSequencerInbox(common.HexToAddress(*seqInboxAddr), client)
+			if err != nil {
+				return err
+			}
+			bounds, err := readTimeBounds(ctx, seqInbox)
+			if err != nil {
+				return err
+			}
+			params.Bounds = bounds
+		}
+		if params.L1BlockTime == 0 {
+			params.L1BlockTime, err = measureBlockTime(ctx, client, *sampleBlocks)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	writeReport(os.Stdout, params, simulate(params))
+	return nil
+}
+
+func readTimeBounds(ctx context.Context, seqInbox *bridgegen.SequencerInbox) (timeBounds, error) {
+	delayBlocks, futureBlocks, delaySeconds, futureSeconds, err := seqInbox.MaxTimeVariation(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return timeBounds{}, fmt.Errorf("error getting max time variation: %w", err)
+	}
+	return timeBounds{
+		DelayBlocks:   delayBlocks.Uint64(),
+		FutureBlocks:  futureBlocks.Uint64(),
+		DelaySeconds:  delaySeconds.Uint64(),
+		FutureSeconds: futureSeconds.Uint64(),
+	}, nil
+}
+
+func measureBlockTime(ctx context.Context, client *ethclient.Client, sampleBlocks uint64) (time.Duration, error) {
+	latest, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	latestNumber := latest.Number.Uint64()
+	if sampleBlocks == 0 || sampleBlocks > latestNumber {
+		sampleBlocks = latestNumber
+	}
+	if sampleBlocks == 0 {
+		return 0, fmt.Errorf("not enough L1 blocks to measure the block time")
+	}
+	earliest, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(latestNumber-sampleBlocks))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(latest.Time-earliest.Time) * time.Second / time.Duration(sampleBlocks), nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// timeBounds mirrors the sequencer inbox's maxTimeVariation
+type timeBounds struct {
+	DelayBlocks   uint64
+	FutureBlocks  uint64
+	DelaySeconds  uint64
+	FutureSeconds uint64
+}
+
+type simulationParams struct {
+	Bounds      timeBounds
+	L1BlockTime time.Duration
+	// The batch poster's max-delay: how old the oldest message in a batch may get before it's posted
+	MaxDelay time.Duration
+	// How long a batch posting transaction takes to be included once sent
+	PostingLatency time.Duration
+	// The sequencer's max-acceptable-timestamp-delta: how stale the latest L1 block may get before it stops sequencing
+	MaxAcceptableTimestampDelta time.Duration
+	// The batch poster downtime the chain should survive, used to recommend a max-delay
+	TargetDowntime time.Duration
+}
+
+type simulationResult struct {
+	// The window after which a message can no longer be posted, while L1 is producing blocks
+	DelayWindow time.Duration
+	// How long past max-delay and the posting latency a message can wait, negative if normal operation violates the bounds
+	SteadyStateHeadroom time.Duration
+	// How long the batch poster can be down before queued messages violate the bounds
+	MaxDowntime time.Duration
+	// How long L1 can stop producing blocks before queued messages violate the bounds
+	MaxL1Stall time.Duration
+	// How long the sequencer keeps sequencing during an L1 stall before it halts by itself
+	SequencingDuringStall time.Duration
+	// Whether the sequencer keeps sequencing past the point the bounds are violated during an L1 stall
+	StallViolatesBounds bool
+	// The largest max-delay which survives TargetDowntime, negative if no max-delay does
+	RecommendedMaxDelay time.Duration
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func simulate(p simulationParams) simulationResult {
+	delaySeconds := time.Duration(p.Bounds.DelaySeconds) * time.Second
+	delayBlocks := time.Duration(p.Bounds.DelayBlocks) * p.L1BlockTime
+	var res simulationResult
+	// While L1 produces blocks both bounds advance, and whichever runs out first limits the window
+	res.DelayWindow = minDuration(delaySeconds, delayBlocks)
+	res.SteadyStateHeadroom = res.DelayWindow - p.MaxDelay - p.PostingLatency
+	// When the batch poster goes down the oldest queued message may already be max-delay old
+	res.MaxDowntime = res.SteadyStateHeadroom
+	// During an L1 stall the block number bound doesn't advance, but the timestamp of the first block after it does
+	res.MaxL1Stall = delaySeconds - p.MaxDelay - p.PostingLatency
+	res.SequencingDuringStall = p.MaxAcceptableTimestampDelta
+	if res.MaxL1Stall < res.SequencingDuringStall {
+		res.StallViolatesBounds = true
+	}
+	res.RecommendedMaxDelay = res.DelayWindow - p.PostingLatency - p.TargetDowntime
+	return res
+}
+
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		return fmt.Sprintf("none (short by %v)", -d)
+	}
+	return d.String()
+}
+
+func writeReport(w io.Writer, p simulationParams, res simulationResult) {
+	fmt.Fprintf(w, "sequencer inbox max time variation: delay blocks %v, future blocks %v, delay seconds %v, future seconds %v\n",
+		p.Bounds.DelayBlocks, p.Bounds.FutureBlocks, p.Bounds.DelaySeconds, p.Bounds.FutureSeconds)
+	fmt.Fprintf(w, "L1 block time %v, batch poster max-delay %v, posting latency %v, sequencer max-acceptable-timestamp-delta %v\n",
+		p.L1BlockTime, p.MaxDelay, p.PostingLatency, p.MaxAcceptableTimestampDelta)
+	fmt.Fprintf(w, "delay window: %v\n", res.DelayWindow)
+	fmt.Fprintf(w, "steady state headroom: %v\n", formatDuration(res.SteadyStateHeadroom))
+	fmt.Fprintf(w, "tolerated batch poster downtime: %v\n", formatDuration(res.MaxDowntime))
+	fmt.Fprintf(w, "tolerated L1 stall: %v\n", formatDuration(res.MaxL1Stall))
+	if res.StallViolatesBounds {
+		fmt.Fprintf(w, "WARNING: during an L1 stall the sequencer keeps sequencing for %v, past the point queued messages violate the bounds\n", res.SequencingDuringStall)
+	} else {
+		fmt.Fprintf(w, "during an L1 stall the sequencer halts after %v, before queued messages violate the bounds\n", res.SequencingDuringStall)
+	}
+	if res.RecommendedMaxDelay <= 0 {
+		fmt.Fprintf(w, "no max-delay tolerates %v of batch poster downtime\n", p.TargetDowntime)
+	} else {
+		fmt.Fprintf(w, "largest max-delay tolerating %v of batch poster downtime: %v\n", p.TargetDowntime, res.RecommendedMaxDelay)
+	}
+}
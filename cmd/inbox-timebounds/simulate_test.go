@@ -0,0 +1,64 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimulate(t *testing.T) {
+	params := simulationParams{
+		Bounds: timeBounds{
+			DelayBlocks:   7200,
+			FutureBlocks:  64,
+			DelaySeconds:  86400,
+			FutureSeconds: 3600,
+		},
+		L1BlockTime:                 12 * time.Second,
+		MaxDelay:                    time.Hour,
+		PostingLatency:              10 * time.Minute,
+		MaxAcceptableTimestampDelta: time.Hour,
+		TargetDowntime:              6 * time.Hour,
+	}
+	res := simulate(params)
+	if res.DelayWindow != 24*time.Hour {
+		t.Fatal("unexpected delay window", res.DelayWindow)
+	}
+	if res.MaxDowntime != 22*time.Hour+50*time.Minute {
+		t.Fatal("unexpected max downtime", res.MaxDowntime)
+	}
+	if res.StallViolatesBounds {
+		t.Fatal("sequencer should halt before an L1 stall violates the bounds")
+	}
+	if res.RecommendedMaxDelay != 17*time.Hour+50*time.Minute {
+		t.Fatal("unexpected recommended max delay", res.RecommendedMaxDelay)
+	}
+
+	// Faster L1 blocks make the delay blocks bound the limit
+	params.L1BlockTime = 6 * time.Second
+	res = simulate(params)
+	if res.DelayWindow != 12*time.Hour {
+		t.Fatal("unexpected delay window", res.DelayWindow)
+	}
+	if res.MaxL1Stall != 22*time.Hour+50*time.Minute {
+		t.Fatal("L1 stall shouldn't be limited by delay blocks", res.MaxL1Stall)
+	}
+
+	params.Bounds.DelaySeconds = 1800
+	res = simulate(params)
+	if res.SteadyStateHeadroom >= 0 {
+		t.Fatal("expected normal operation to violate the bounds")
+	}
+	if !res.StallViolatesBounds {
+		t.Fatal("expected the sequencer to keep sequencing past the bounds during an L1 stall")
+	}
+	var report bytes.Buffer
+	writeReport(&report, params, res)
+	if !strings.Contains(report.String(), "WARNING") || !strings.Contains(report.String(), "no max-delay tolerates") {
+		t.Fatal("report missing warnings:", report.String())
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/cmd/util"
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+)
+
+type AttestConfig struct {
+	Block   int64                    `koanf:"block"`
+	NodeURL string                   `koanf:"node-url"`
+	Output  string                   `koanf:"output"`
+	Timeout time.Duration            `koanf:"timeout"`
+	Wallet  genericconf.WalletConfig `koanf:"wallet"`
+}
+
+var DefaultAttestConfig = AttestConfig{
+	Block:   -1,
+	NodeURL: "http://127.0.0.1:8547",
+	Output:  "",
+	Timeout: time.Minute,
+	Wallet:  genericconf.WalletConfigDefault,
+}
+
+func AttestConfigAddOptions(f *flag.FlagSet) {
+	f.Int64("block", DefaultAttestConfig.Block, "number of the block to attest to")
+	f.String("node-url", DefaultAttestConfig.NodeURL, "RPC URL of the node whose view is attested to; it must serve the eth and arbattest APIs")
+	f.String("output", DefaultAttestConfig.Output, "file to write the signed attestation to (empty = stdout)")
+	f.Duration("timeout", DefaultAttestConfig.Timeout, "timeout for querying the node")
+	genericconf.WalletConfigAddOptions("wallet", f, "")
+}
+
+func (c *AttestConfig) Validate() error {
+	if c.Block < 0 {
+		return errors.New("attest requires a block")
+	}
+	if c.Wallet.PrivateKey == "" && c.Wallet.Pathname == "" {
+		return errors.New("attest requires a wallet to sign with")
+	}
+	return nil
+}
+
+// Attestation is an operator's statement of its node's view of a block and how it's anchored to the parent chain
+type Attestation struct {
+	ChainId    *hexutil.Big   `json:"chainId"`
+	AttestedAt hexutil.Uint64 `json:"attestedAt"`
+	StateRoot  common.Hash    `json:"stateRoot"`
+	arbnode.BlockAnchor
+}
+
+// SignedAttestation holds the attestation exactly as signed, so that the signature can be checked against it
+// regardless of how it's later re-encoded.
+type SignedAttestation struct {
+	Attestation json.RawMessage `json:"attestation"`
+	Signer      common.Address  `json:"signer"`
+	// An Ethereum signed message signature of the attestation, as made by personal_sign
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// Verify returns the attestation if it was signed by its claimed signer
+func (s *SignedAttestation) Verify() (*Attestation, error) {
+	if len(s.Signature) != crypto.SignatureLength {
+		return nil, errors.New("invalid attestation signature length")
+	}
+	sig := common.CopyBytes(s.Signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pubkey, err := crypto.SigToPub(accounts.TextHash(s.Attestation), sig)
+	if err != nil {
+		return nil, err
+	}
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != s.Signer {
+		return nil, fmt.Errorf("attestation signed by %v but claims to be signed by %v", signer, s.Signer)
+	}
+	var attestation Attestation
+	if err := json.Unmarshal(s.Attestation, &attestation); err != nil {
+		return nil, err
+	}
+	return &attestation, nil
+}
+
+func parseAttestConfig(args []string) (*AttestConfig, error) {
+	f := flag.NewFlagSet("attest", flag.ContinueOnError)
+	AttestConfigAddOptions(f)
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config AttestConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	return &config, config.Validate()
+}
+
+func printAttestUsage(name string) {
+	fmt.Printf("Sample usage: %s attest --block 1000 --node-url http://127.0.0.1:8547 --wallet.private-key <key>\n\n", name)
+	fmt.Printf("Signs a statement of the node's view of the block: its hash, state root and send root, and the batch and assertion anchoring it\n")
+}
+
+// runAttest writes a signed attestation of a block as seen by a running node. Returns the exit code.
+func runAttest(ctx context.Context, args []string) int {
+	config, err := parseAttestConfig(args)
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, printAttestUsage)
+	}
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(true)))
+	glogger.Verbosity(log.LvlWarn)
+	log.Root().SetHandler(glogger)
+
+	if err := attestMain(ctx, config); err != nil {
+		log.Error("attest failed", "err", err)
+		return 1
+	}
+	return 0
+}
+
+func attestMain(ctx context.Context, config *AttestConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+
+	_, dataSigner, err := util.OpenWallet("attest", &config.Wallet, nil)
+	if err != nil {
+		return fmt.Errorf("error opening wallet: %w", err)
+	}
+	if dataSigner == nil {
+		// only creating a key
+		return nil
+	}
+	rpcClient, err := rpc.DialContext(ctx, config.NodeURL)
+	if err != nil {
+		return fmt.Errorf("error connecting to node: %w", err)
+	}
+	defer rpcClient.Close()
+	client := ethclient.NewClient(rpcClient)
+
+	chainId, err := client.ChainID(ctx)
+	if err != nil {
+		return err
+	}
+	header, err := client.HeaderByNumber(ctx, big.NewInt(config.Block))
+	if err != nil {
+		return fmt.Errorf("error getting block %v: %w", config.Block, err)
+	}
+	var anchor arbnode.BlockAnchor
+	if err := rpcClient.CallContext(ctx, &anchor, "arbattest_anchor", hexutil.Uint64(config.Block)); err != nil {
+		return fmt.Errorf("error getting anchor of block %v: %w", config.Block, err)
+	}
+	// The node's execution and consensus views must agree, or it's mid reorg
+	extraInfo := types.DeserializeHeaderExtraInformation(header)
+	if anchor.BlockHash != header.Hash() || anchor.SendRoot != extraInfo.SendRoot {
+		return fmt.Errorf("node returned block hash %v and send root %v but anchored block hash %v and send root %v", header.Hash(), extraInfo.SendRoot, anchor.BlockHash, anchor.SendRoot)
+	}
+	if !anchor.Posted {
+		log.Warn("block hasn't been posted to the parent chain yet", "block", config.Block)
+	}
+
+	attestation, err := json.Marshal(&Attestation{
+		ChainId:     (*hexutil.Big)(chainId),
+		AttestedAt:  hexutil.Uint64(time.Now().Unix()),
+		StateRoot:   header.Root,
+		BlockAnchor: anchor,
+	})
+	if err != nil {
+		return err
+	}
+	sig, err := dataSigner(accounts.TextHash(attestation))
+	if err != nil {
+		return fmt.Errorf("error signing attestation: %w", err)
+	}
+	pubkey, err := crypto.SigToPub(accounts.TextHash(attestation), sig)
+	if err != nil {
+		return err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	signed, err := json.MarshalIndent(&SignedAttestation{
+		Attestation: attestation,
+		Signer:      crypto.PubkeyToAddress(*pubkey),
+		Signature:   sig,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	signed = append(signed, '\n')
+	if config.Output == "" {
+		_, err = os.Stdout.Write(signed)
+		return err
+	}
+	return os.WriteFile(config.Output, signed, 0o644)
+}
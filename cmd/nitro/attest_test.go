@@ -0,0 +1,124 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/cmd/genericconf"
+)
+
+type fakeAttestEth struct {
+	header *types.Header
+}
+
+func (e *fakeAttestEth) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(412346))
+}
+
+func (e *fakeAttestEth) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) *types.Header {
+	if number.Int64() != e.header.Number.Int64() {
+		return nil
+	}
+	return e.header
+}
+
+type fakeAttestAnchor struct {
+	anchor *arbnode.BlockAnchor
+}
+
+func (a *fakeAttestAnchor) Anchor(block hexutil.Uint64) *arbnode.BlockAnchor {
+	return a.anchor
+}
+
+func TestAttest(t *testing.T) {
+	sendRoot := common.HexToHash("0x5e4d")
+	header := &types.Header{
+		Number:     big.NewInt(100),
+		Root:       common.HexToHash("0x5747e"),
+		Difficulty: common.Big1,
+		BaseFee:    big.NewInt(1e8),
+		Extra:      sendRoot.Bytes(),
+		MixDigest:  common.HexToHash("0x01"),
+	}
+	anchor := &arbnode.BlockAnchor{
+		Block:                 100,
+		BlockHash:             header.Hash(),
+		SendRoot:              sendRoot,
+		Posted:                true,
+		Batch:                 7,
+		BatchAccumulator:      common.HexToHash("0xacc"),
+		BatchParentChainBlock: 1234,
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &fakeAttestEth{header}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("arbattest", &fakeAttestAnchor{anchor}); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultAttestConfig
+	config.Block = 100
+	config.NodeURL = httpServer.URL
+	config.Output = filepath.Join(t.TempDir(), "attestation.json")
+	config.Wallet = genericconf.WalletConfig{PrivateKey: common.Bytes2Hex(crypto.FromECDSA(key))}
+	if err := attestMain(context.Background(), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(config.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signed SignedAttestation
+	if err := json.Unmarshal(data, &signed); err != nil {
+		t.Fatal(err)
+	}
+	if signed.Signer != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatal("attestation claims signer", signed.Signer)
+	}
+	attestation, err := signed.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attestation.StateRoot != header.Root || attestation.BlockAnchor != *anchor || attestation.ChainId.ToInt().Uint64() != 412346 {
+		t.Fatal("unexpected attestation", string(signed.Attestation))
+	}
+	if time.Since(time.Unix(int64(attestation.AttestedAt), 0)) > time.Minute {
+		t.Fatal("unexpected attestation time", attestation.AttestedAt)
+	}
+
+	// A tampered attestation no longer matches its signer
+	signed.Attestation = json.RawMessage(string(signed.Attestation[:len(signed.Attestation)-1]) + " }")
+	if _, err := signed.Verify(); err == nil {
+		t.Fatal("expected tampered attestation to fail verification")
+	}
+
+	// The node's execution and consensus views have to agree
+	anchor.BlockHash = common.HexToHash("0xbad")
+	if err := attestMain(context.Background(), &config); err == nil {
+		t.Fatal("expected attesting to a mismatched block hash to fail")
+	}
+}
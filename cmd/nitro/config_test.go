@@ -62,6 +62,83 @@ func TestAggregatorConfig(t *testing.T) {
 	Require(t, err)
 }
 
+// chainInfoJsonWithParent returns the chain info of a test chain, settled to a parent chain that may be an Arbitrum chain
+func chainInfoJsonWithParent(parentChainIsArbitrum bool) string {
+	return fmt.Sprintf(`[{
+		"chain-name": "parent-test",
+		"parent-chain-id": 412346,
+		"parent-chain-is-arbitrum": %v,
+		"chain-config": {
+			"chainId": 333333,
+			"homesteadBlock": 0,
+			"daoForkBlock": null,
+			"daoForkSupport": true,
+			"eip150Block": 0,
+			"eip150Hash": "0x0000000000000000000000000000000000000000000000000000000000000000",
+			"eip155Block": 0,
+			"eip158Block": 0,
+			"byzantiumBlock": 0,
+			"constantinopleBlock": 0,
+			"petersburgBlock": 0,
+			"istanbulBlock": 0,
+			"muirGlacierBlock": 0,
+			"berlinBlock": 0,
+			"londonBlock": 0,
+			"clique": {"period": 0, "epoch": 0},
+			"arbitrum": {
+				"EnableArbOS": true,
+				"AllowDebugPrecompiles": true,
+				"DataAvailabilityCommittee": false,
+				"InitialArbOSVersion": 11,
+				"InitialChainOwner": "0x0000000000000000000000000000000000000000",
+				"GenesisBlockNum": 0
+			}
+		}
+	}]`, parentChainIsArbitrum)
+}
+
+func TestApplyChainParametersArbitrumParent(t *testing.T) {
+	ctx := context.Background()
+	for _, parentChainIsArbitrum := range []bool{false, true} {
+		f := flag.NewFlagSet("", flag.ContinueOnError)
+		NodeConfigAddOptions(f)
+		k, err := confighelpers.BeginCommonParse(f, []string{})
+		Require(t, err)
+		Require(t, applyChainParameters(ctx, k, 333333, "", nil, chainInfoJsonWithParent(parentChainIsArbitrum), "", ""))
+		var config NodeConfig
+		Require(t, confighelpers.EndCommonParse(k, &config))
+
+		if config.Chain.ID != 333333 || config.ParentChain.ID != 412346 {
+			Fail(t, "chain ids", config.Chain.ID, config.ParentChain.ID, "weren't applied")
+		}
+		inboxReader := config.Node.InboxReader
+		batchPoster := config.Node.BatchPoster
+		if !parentChainIsArbitrum {
+			if inboxReader.MinBlocksToRead != NodeConfigDefault.Node.InboxReader.MinBlocksToRead ||
+				inboxReader.DefaultBlocksToRead != NodeConfigDefault.Node.InboxReader.DefaultBlocksToRead ||
+				inboxReader.MaxBlocksToRead != NodeConfigDefault.Node.InboxReader.MaxBlocksToRead {
+				Fail(t, "inbox reader config changed for a non-Arbitrum parent chain", inboxReader)
+			}
+			if batchPoster.MaxSize != NodeConfigDefault.Node.BatchPoster.MaxSize {
+				Fail(t, "batch size changed for a non-Arbitrum parent chain", batchPoster.MaxSize)
+			}
+			continue
+		}
+		if inboxReader.MinBlocksToRead != 4 || inboxReader.DefaultBlocksToRead != 1_000 || inboxReader.MaxBlocksToRead != 10_000 {
+			Fail(t, "inbox reader isn't tuned for an Arbitrum parent chain", inboxReader)
+		}
+		// batches must fit in a transaction on the parent chain, and the chain's own transactions in a batch
+		parentMaxTxSize := NodeConfigDefault.Execution.Sequencer.MaxTxDataSize
+		if batchPoster.MaxSize >= parentMaxTxSize {
+			Fail(t, "batch size", batchPoster.MaxSize, "doesn't fit in a parent chain transaction of", parentMaxTxSize)
+		}
+		if config.Execution.Sequencer.MaxTxDataSize >= batchPoster.MaxSize {
+			Fail(t, "max tx size", config.Execution.Sequencer.MaxTxDataSize, "doesn't fit in a batch of", batchPoster.MaxSize)
+		}
+		Require(t, inboxReader.Validate())
+	}
+}
+
 func TestReloads(t *testing.T) {
 	var check func(node reflect.Value, cold bool, path string)
 	check = func(node reflect.Value, cold bool, path string) {
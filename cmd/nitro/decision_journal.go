@@ -0,0 +1,124 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/staker"
+)
+
+type DecisionJournalQueryConfig struct {
+	File  string `koanf:"file"`
+	Node  uint64 `koanf:"node"`
+	Kind  string `koanf:"kind"`
+	Since string `koanf:"since"`
+	Until string `koanf:"until"`
+
+	filter staker.DecisionJournalFilter
+}
+
+var DefaultDecisionJournalQueryConfig = DecisionJournalQueryConfig{
+	File:  "",
+	Node:  0,
+	Kind:  "",
+	Since: "",
+	Until: "",
+}
+
+func DecisionJournalQueryConfigAddOptions(f *flag.FlagSet) {
+	f.String("file", DefaultDecisionJournalQueryConfig.File, "staker decision journal to query, as written with node.staker.decision-journal")
+	f.Uint64("node", DefaultDecisionJournalQueryConfig.Node, "only show entries about this assertion node (0 = all nodes)")
+	f.String("kind", DefaultDecisionJournalQueryConfig.Kind, "only show entries of this kind, either assertion, action, tx or error (empty = all kinds)")
+	f.String("since", DefaultDecisionJournalQueryConfig.Since, "only show entries recorded at or after this RFC 3339 time")
+	f.String("until", DefaultDecisionJournalQueryConfig.Until, "only show entries recorded at or before this RFC 3339 time")
+}
+
+func (c *DecisionJournalQueryConfig) Validate() error {
+	if c.File == "" {
+		return errors.New("decision-journal requires a journal file")
+	}
+	switch c.Kind {
+	case "", staker.DecisionKindAssertion, staker.DecisionKindAction, staker.DecisionKindTx, staker.DecisionKindError:
+	default:
+		return fmt.Errorf("unknown decision journal entry kind \"%v\"", c.Kind)
+	}
+	c.filter = staker.DecisionJournalFilter{
+		Node: c.Node,
+		Kind: c.Kind,
+	}
+	var err error
+	if c.Since != "" {
+		c.filter.Since, err = time.Parse(time.RFC3339, c.Since)
+		if err != nil {
+			return fmt.Errorf("invalid since time: %w", err)
+		}
+	}
+	if c.Until != "" {
+		c.filter.Until, err = time.Parse(time.RFC3339, c.Until)
+		if err != nil {
+			return fmt.Errorf("invalid until time: %w", err)
+		}
+	}
+	return nil
+}
+
+func parseDecisionJournalQueryConfig(args []string) (*DecisionJournalQueryConfig, error) {
+	f := flag.NewFlagSet("decision-journal", flag.ContinueOnError)
+	DecisionJournalQueryConfigAddOptions(f)
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config DecisionJournalQueryConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	return &config, config.Validate()
+}
+
+func printDecisionJournalUsage(name string) {
+	fmt.Printf("Sample usage: %s decision-journal --file staker-decisions.jsonl --node 1234\n\n", name)
+	fmt.Printf("Prints the staker's recorded decisions about assertions, and the actions and transactions they led to, one JSON entry per line\n")
+}
+
+// runDecisionJournal prints the matching entries of a staker decision journal. Returns the exit code.
+func runDecisionJournal(args []string) int {
+	config, err := parseDecisionJournalQueryConfig(args)
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, printDecisionJournalUsage)
+	}
+	if err := queryDecisionJournal(config, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "decision-journal failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func queryDecisionJournal(config *DecisionJournalQueryConfig, output io.Writer) error {
+	file, err := os.Open(config.File)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	entries, err := staker.QueryDecisionJournal(file, &config.filter)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(output)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,488 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/catalyst"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/cmd/chaininfo"
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/deploy"
+	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
+	"github.com/offchainlabs/nitro/util/headerreader"
+	"github.com/offchainlabs/nitro/validator/server_common"
+)
+
+// The well known dev key, also used by --dev. It owns the chain, posts batches, and is funded on both chains.
+const devnetDevPrivateKey = "b6b15c8cb491557369f3c7d2c287b053eb229daa9c22138887752191c9520659"
+
+// The validator stakes with its own key so that it doesn't race the batch poster's nonces
+var devnetValidatorKeySeed = []byte("nitro devnet validator")
+
+var devnetRoles = []string{"sequencer", "validator", "replica"}
+
+type DevnetConfig struct {
+	Nodes          int           `koanf:"nodes"`
+	Dir            string        `koanf:"dir"`
+	BasePort       int           `koanf:"base-port"`
+	FeedPort       int           `koanf:"feed-port"`
+	L1BlockTime    time.Duration `koanf:"l1-block-time"`
+	WasmModuleRoot string        `koanf:"wasm-module-root"`
+	ReadyTimeout   time.Duration `koanf:"ready-timeout"`
+}
+
+var DefaultDevnetConfig = DevnetConfig{
+	Nodes:       3,
+	Dir:         "",
+	BasePort:    8545,
+	FeedPort:    9642,
+	L1BlockTime: time.Second,
+	// Usually found in the machines directory next to the binary
+	WasmModuleRoot: "",
+	ReadyTimeout:   time.Minute * 2,
+}
+
+func DevnetConfigAddOptions(f *flag.FlagSet) {
+	f.Int("nodes", DefaultDevnetConfig.Nodes, "number of L2 nodes to run: a sequencer, then a validator, then a replica")
+	f.String("dir", DefaultDevnetConfig.Dir, "directory for the devnet's chain data and logs (defaults to a new temporary directory)")
+	f.Int("base-port", DefaultDevnetConfig.BasePort, "the L1 serves HTTP and WS RPC on this port and the next, and each L2 node on the two after that")
+	f.Int("feed-port", DefaultDevnetConfig.FeedPort, "port of the sequencer's feed")
+	f.Duration("l1-block-time", DefaultDevnetConfig.L1BlockTime, "time between L1 blocks")
+	f.String("wasm-module-root", DefaultDevnetConfig.WasmModuleRoot, "WASM module root to deploy the rollup with (defaults to the latest found machine)")
+	f.Duration("ready-timeout", DefaultDevnetConfig.ReadyTimeout, "how long to wait for the nodes' RPC to come up")
+}
+
+func (c *DevnetConfig) Validate() error {
+	if c.Nodes < 1 || c.Nodes > len(devnetRoles) {
+		return fmt.Errorf("devnet nodes must be between 1 and %v", len(devnetRoles))
+	}
+	if c.L1BlockTime < time.Second {
+		return errors.New("devnet l1-block-time must be at least one second")
+	}
+	return nil
+}
+
+func (c *DevnetConfig) l1HTTPPort() int {
+	return c.BasePort
+}
+
+func (c *DevnetConfig) l1WSPort() int {
+	return c.BasePort + 1
+}
+
+func (c *DevnetConfig) nodeHTTPPort(index int) int {
+	return c.BasePort + 2 + 2*index
+}
+
+func (c *DevnetConfig) nodeWSPort(index int) int {
+	return c.BasePort + 3 + 2*index
+}
+
+func parseDevnetConfig(args []string) (*DevnetConfig, error) {
+	f := flag.NewFlagSet("devnet", flag.ContinueOnError)
+	DevnetConfigAddOptions(f)
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config DevnetConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	return &config, config.Validate()
+}
+
+func printDevnetUsage(name string) {
+	fmt.Printf("Sample usage: %s devnet --nodes 3\n\n", name)
+	fmt.Printf("Starts a local L1 with the rollup deployed, and a sequencer, a validator and a replica as subprocesses\n")
+}
+
+// runDevnet runs the L1 in this process, deploys the rollup to it, and runs the L2 nodes as subprocesses
+// of this binary until interrupted. Returns the exit code.
+func runDevnet(ctx context.Context, args []string) int {
+	config, err := parseDevnetConfig(args)
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, printDevnetUsage)
+	}
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(true)))
+	glogger.Verbosity(log.LvlInfo)
+	log.Root().SetHandler(glogger)
+
+	if err := devnetMain(ctx, config); err != nil {
+		log.Error("devnet failed", "err", err)
+		return 1
+	}
+	return 0
+}
+
+func devnetMain(ctx context.Context, config *DevnetConfig) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	dir := config.Dir
+	if dir == "" {
+		var err error
+		dir, err = os.MkdirTemp("", "nitro-devnet-")
+		if err != nil {
+			return err
+		}
+	}
+	devKey, err := crypto.HexToECDSA(devnetDevPrivateKey)
+	if err != nil {
+		return err
+	}
+	validatorKey, err := crypto.ToECDSA(crypto.Keccak256(devnetValidatorKeySeed))
+	if err != nil {
+		return err
+	}
+	devAddr := crypto.PubkeyToAddress(devKey.PublicKey)
+	validatorAddr := crypto.PubkeyToAddress(validatorKey.PublicKey)
+
+	l1Stack, l1ChainId, err := startDevnetL1(config, filepath.Join(dir, "l1"), devAddr, validatorAddr)
+	if err != nil {
+		return fmt.Errorf("error starting L1: %w", err)
+	}
+	defer l1Stack.Close()
+	l1Client := ethclient.NewClient(l1Stack.Attach())
+
+	chainConfig := params.ArbitrumDevTestChainConfig()
+	chainConfig.ArbitrumChainParams.InitialChainOwner = devAddr
+	addresses, err := deployDevnetRollup(ctx, config, l1Client, l1ChainId, devKey, chainConfig)
+	if err != nil {
+		return fmt.Errorf("error deploying rollup: %w", err)
+	}
+	parentChainIsArbitrum := false
+	chainInfo := []chaininfo.ChainInfo{{
+		ChainName:             "devnet",
+		ParentChainId:         l1ChainId.Uint64(),
+		ParentChainIsArbitrum: &parentChainIsArbitrum,
+		ChainConfig:           chainConfig,
+		RollupAddresses:       addresses,
+	}}
+	chainInfoJson, err := json.Marshal(chainInfo)
+	if err != nil {
+		return err
+	}
+	chainInfoFile := filepath.Join(dir, "chain-info.json")
+	if err := os.WriteFile(chainInfoFile, chainInfoJson, 0600); err != nil {
+		return err
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	exited := make(chan error, config.Nodes)
+	var nodes []*devnetNode
+	defer func() {
+		stopDevnetNodes(nodes)
+	}()
+	for i, role := range devnetRoles[:config.Nodes] {
+		nodeDir := filepath.Join(dir, role)
+		if err := os.MkdirAll(nodeDir, 0700); err != nil {
+			return err
+		}
+		args := devnetNodeArgs(config, i, role, nodeDir, chainInfoFile, l1ChainId.Uint64(), chainConfig.ChainID.Uint64(), devAddr, validatorKey)
+		n, err := startDevnetNode(executable, args, filepath.Join(nodeDir, "node.log"))
+		if err != nil {
+			return fmt.Errorf("error starting %v: %w", role, err)
+		}
+		nodes = append(nodes, n)
+		go func(role string) {
+			err := n.cmd.Wait()
+			close(n.done)
+			exited <- fmt.Errorf("%v exited: %w", role, err)
+		}(role)
+	}
+
+	if err := waitForDevnetNodes(ctx, config, exited); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nDevnet running in %v\n", dir)
+	fmt.Printf("  L1          http://127.0.0.1:%v  ws://127.0.0.1:%v  chain ID %v\n", config.l1HTTPPort(), config.l1WSPort(), l1ChainId)
+	for i, role := range devnetRoles[:config.Nodes] {
+		fmt.Printf("  %-11v http://127.0.0.1:%v  ws://127.0.0.1:%v  logs %v\n", role, config.nodeHTTPPort(i), config.nodeWSPort(i), filepath.Join(dir, role, "node.log"))
+	}
+	fmt.Printf("  L2 chain ID %v, feed ws://127.0.0.1:%v, chain info %v\n", chainConfig.ChainID, config.FeedPort, chainInfoFile)
+	fmt.Printf("  Funded on both chains: %v (private key %v)\n\n", devAddr, devnetDevPrivateKey)
+
+	select {
+	case <-ctx.Done():
+		log.Info("shutting down devnet")
+		return nil
+	case err := <-exited:
+		return err
+	}
+}
+
+func startDevnetL1(config *DevnetConfig, dataDir string, funded ...common.Address) (*node.Node, *big.Int, error) {
+	stackConf := node.DefaultConfig
+	stackConf.DataDir = dataDir
+	stackConf.HTTPHost = "127.0.0.1"
+	stackConf.HTTPPort = config.l1HTTPPort()
+	stackConf.HTTPModules = []string{"eth", "net", "web3", "debug"}
+	stackConf.WSHost = "127.0.0.1"
+	stackConf.WSPort = config.l1WSPort()
+	stackConf.WSModules = []string{"eth", "net", "web3", "debug"}
+	stackConf.P2P.NoDiscovery = true
+	stackConf.P2P.NoDial = true
+	stackConf.P2P.ListenAddr = ""
+	stackConf.P2P.NAT = nil
+	stack, err := node.New(&stackConf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	genesis := core.DeveloperGenesisBlock(30_000_000, funded[0])
+	balance, _ := new(big.Int).SetString("1000000000000000000000000", 10)
+	for _, addr := range funded {
+		genesis.Alloc[addr] = core.GenesisAccount{Balance: balance}
+	}
+	genesis.BaseFee = big.NewInt(params.GWei)
+	ethConf := ethconfig.Defaults
+	ethConf.NetworkId = genesis.Config.ChainID.Uint64()
+	ethConf.Genesis = genesis
+	ethConf.Miner.Etherbase = funded[0]
+	ethConf.SyncMode = downloader.FullSync
+	backend, err := eth.New(stack, &ethConf)
+	if err != nil {
+		stack.Close()
+		return nil, nil, err
+	}
+	simBeacon, err := catalyst.NewSimulatedBeacon(uint64(config.L1BlockTime/time.Second), backend)
+	if err != nil {
+		stack.Close()
+		return nil, nil, err
+	}
+	catalyst.RegisterSimulatedBeaconAPIs(stack, simBeacon)
+	stack.RegisterLifecycle(simBeacon)
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "eth",
+		Service:   filters.NewFilterAPI(filters.NewFilterSystem(backend.APIBackend, filters.Config{}), false),
+	}})
+	if err := stack.Start(); err != nil {
+		stack.Close()
+		return nil, nil, err
+	}
+	return stack, genesis.Config.ChainID, nil
+}
+
+func deployDevnetRollup(
+	ctx context.Context,
+	config *DevnetConfig,
+	l1Client *ethclient.Client,
+	l1ChainId *big.Int,
+	devKey *ecdsa.PrivateKey,
+	chainConfig *params.ChainConfig,
+) (*chaininfo.RollupAddresses, error) {
+	devAddr := crypto.PubkeyToAddress(devKey.PublicKey)
+	auth, err := bind.NewKeyedTransactorWithChainID(devKey, l1ChainId)
+	if err != nil {
+		return nil, err
+	}
+	auth.Context = ctx
+
+	var moduleRoot common.Hash
+	if config.WasmModuleRoot != "" {
+		moduleRoot = common.HexToHash(config.WasmModuleRoot)
+	} else if locator, err := server_common.NewMachineLocator(""); err == nil {
+		moduleRoot = locator.LatestWasmModuleRoot()
+	}
+	if moduleRoot == (common.Hash{}) {
+		// The validator runs without a block validator, so it never needs the machine
+		log.Warn("no machines found, deploying the rollup with a placeholder WASM module root; blocks can't be proven")
+		moduleRoot = common.HexToHash("0x01")
+	}
+	serializedChainConfig, err := json.Marshal(chainConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	arbSys, _ := precompilesgen.NewArbSys(types.ArbSysAddress, l1Client)
+	l1Reader, err := headerreader.New(ctx, l1Client, func() *headerreader.Config { return &headerreader.DefaultConfig }, arbSys)
+	if err != nil {
+		return nil, err
+	}
+	l1Reader.Start(ctx)
+	defer l1Reader.StopAndWait()
+
+	log.Info("deploying rollup to the devnet L1")
+	return deploy.DeployOnL1(
+		ctx,
+		l1Reader,
+		auth,
+		[]common.Address{devAddr},
+		devAddr,
+		// Authorizes the validator's smart contract wallet, the first one the wallet creator deploys
+		1,
+		arbnode.GenerateRollupConfig(false, moduleRoot, devAddr, chainConfig, serializedChainConfig, common.Address{}),
+		common.Address{},
+		big.NewInt(117964),
+		false,
+	)
+}
+
+func devnetNodeArgs(
+	config *DevnetConfig,
+	index int,
+	role string,
+	nodeDir string,
+	chainInfoFile string,
+	l1ChainId uint64,
+	l2ChainId uint64,
+	devAddr common.Address,
+	validatorKey *ecdsa.PrivateKey,
+) []string {
+	args := []string{
+		fmt.Sprintf("--parent-chain.id=%v", l1ChainId),
+		fmt.Sprintf("--parent-chain.connection.url=ws://127.0.0.1:%v", config.l1WSPort()),
+		fmt.Sprintf("--chain.id=%v", l2ChainId),
+		"--chain.info-files=" + chainInfoFile,
+		"--persistent.chain=" + nodeDir,
+		"--persistent.global-config=" + nodeDir,
+		"--init.dev-init",
+		"--init.dev-init-address=" + devAddr.Hex(),
+		"--http.addr=127.0.0.1",
+		fmt.Sprintf("--http.port=%v", config.nodeHTTPPort(index)),
+		"--http.api=net,web3,eth,arb,debug",
+		"--http.vhosts=*",
+		"--http.corsdomain=*",
+		"--ws.addr=127.0.0.1",
+		fmt.Sprintf("--ws.port=%v", config.nodeWSPort(index)),
+		"--ws.api=net,web3,eth,arb",
+		"--node.block-validator.enable=false",
+	}
+	sequencerHTTP := fmt.Sprintf("http://127.0.0.1:%v", config.nodeHTTPPort(0))
+	feed := fmt.Sprintf("ws://127.0.0.1:%v", config.FeedPort)
+	switch role {
+	case "sequencer":
+		args = append(args,
+			"--node.sequencer",
+			"--execution.sequencer.enable",
+			"--node.dangerous.no-sequencer-coordinator",
+			"--node.delayed-sequencer.enable",
+			"--node.batch-poster.enable",
+			"--node.batch-poster.parent-chain-wallet.private-key="+devnetDevPrivateKey,
+			"--node.feed.output.enable",
+			"--node.feed.output.addr=127.0.0.1",
+			fmt.Sprintf("--node.feed.output.port=%v", config.FeedPort),
+			"--node.staker.enable=false",
+		)
+	case "validator":
+		args = append(args,
+			"--node.feed.input.url="+feed,
+			"--execution.forwarding-target="+sequencerHTTP,
+			"--node.staker.enable",
+			"--node.staker.strategy=MakeNodes",
+			"--node.staker.use-smart-contract-wallet",
+			"--node.staker.parent-chain-wallet.private-key="+common.Bytes2Hex(crypto.FromECDSA(validatorKey)),
+			"--node.staker.dangerous.without-block-validator",
+		)
+	case "replica":
+		args = append(args,
+			"--node.feed.input.url="+feed,
+			"--execution.forwarding-target="+sequencerHTTP,
+			"--node.staker.enable=false",
+		)
+	}
+	return args
+}
+
+type devnetNode struct {
+	cmd *exec.Cmd
+	// Closed once the process has exited and been waited on
+	done chan struct{}
+}
+
+func startDevnetNode(executable string, args []string, logFile string) (*devnetNode, error) {
+	out, err := os.Create(logFile)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Start(); err != nil {
+		out.Close()
+		return nil, err
+	}
+	log.Info("started devnet node", "pid", cmd.Process.Pid, "args", strings.Join(args, " "))
+	return &devnetNode{cmd: cmd, done: make(chan struct{})}, nil
+}
+
+// waitForDevnetNodes waits for every node's RPC to answer, failing early if one exits
+func waitForDevnetNodes(ctx context.Context, config *DevnetConfig, exited chan error) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ReadyTimeout)
+	defer cancel()
+	for i, role := range devnetRoles[:config.Nodes] {
+		url := fmt.Sprintf("http://127.0.0.1:%v", config.nodeHTTPPort(i))
+		for {
+			client, err := ethclient.DialContext(ctx, url)
+			if err == nil {
+				_, err = client.ChainID(ctx)
+				client.Close()
+				if err == nil {
+					break
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%v didn't come up at %v: %w", role, url, ctx.Err())
+			case err := <-exited:
+				return err
+			case <-time.After(time.Second):
+			}
+		}
+	}
+	return nil
+}
+
+func stopDevnetNodes(nodes []*devnetNode) {
+	for _, n := range nodes {
+		_ = n.cmd.Process.Signal(os.Interrupt)
+	}
+	deadline := time.Now().Add(time.Second * 30)
+	for _, n := range nodes {
+		select {
+		case <-n.done:
+			continue
+		case <-time.After(time.Until(deadline)):
+		}
+		log.Warn("devnet node didn't stop in time, killing it", "pid", n.cmd.Process.Pid)
+		_ = n.cmd.Process.Kill()
+		<-n.done
+	}
+}
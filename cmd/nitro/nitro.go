@@ -52,6 +52,7 @@ import (
 	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
 	"github.com/offchainlabs/nitro/execution/gethexec"
 	_ "github.com/offchainlabs/nitro/nodeInterface"
+	"github.com/offchainlabs/nitro/precompiles"
 	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
 	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
 	"github.com/offchainlabs/nitro/solgen/go/rollupgen"
@@ -61,7 +62,6 @@ import (
 	"github.com/offchainlabs/nitro/util/headerreader"
 	"github.com/offchainlabs/nitro/util/rpcclient"
 	"github.com/offchainlabs/nitro/util/signature"
-	"github.com/offchainlabs/nitro/validator/server_common"
 	"github.com/offchainlabs/nitro/validator/valnode"
 )
 
@@ -164,6 +164,21 @@ func mainImpl() int {
 	defer cancelFunc()
 
 	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "devnet" {
+		return runDevnet(ctx, args[1:])
+	}
+	if len(args) > 0 && args[0] == "attest" {
+		return runAttest(ctx, args[1:])
+	}
+	if len(args) > 0 && args[0] == "decision-journal" {
+		return runDecisionJournal(args[1:])
+	}
+	if len(args) > 0 && args[0] == "stake" {
+		return runStake(ctx, args[1:])
+	}
+	if len(args) > 0 && args[0] == "revalidate" {
+		return runRevalidate(ctx, args[1:])
+	}
 	nodeConfig, l1Wallet, l2DevWallet, err := ParseNode(ctx, args)
 	if err != nil {
 		confighelpers.PrintErrorAndExit(err, printSampleUsage)
@@ -214,6 +229,9 @@ func mainImpl() int {
 	}
 
 	log.Info("Running Arbitrum nitro node", "revision", vcsRevision, "vcs.time", vcsTime)
+	if custom := precompiles.CustomPrecompileAddresses(); len(custom) > 0 {
+		log.Info("Custom precompiles registered", "addresses", custom, "hash", precompiles.CustomPrecompilesHash())
+	}
 
 	if nodeConfig.Node.Dangerous.NoL1Listener {
 		nodeConfig.Node.ParentChainReader.Enable = false
@@ -304,7 +322,8 @@ func mainImpl() int {
 		if err != nil {
 			log.Crit("couldn't parse staker strategy", "err", err)
 		}
-		if strategy != staker.WatchtowerStrategy && !nodeConfig.Node.Staker.Dangerous.WithoutBlockValidator {
+		validate := strategy != staker.WatchtowerStrategy || nodeConfig.Node.Staker.Watchtower.Validate
+		if validate && !nodeConfig.Node.Staker.Dangerous.WithoutBlockValidator {
 			nodeConfig.Node.BlockValidator.Enable = true
 		}
 	}
@@ -325,14 +344,19 @@ func mainImpl() int {
 	var l1Client *ethclient.Client
 	var l1Reader *headerreader.HeaderReader
 	var blobReader arbstate.BlobReader
+	// parentChainClient is l1Client, unless historical requests go to a separate archive endpoint
+	var parentChainClient arbutil.L1Interface = l1Client
 	if nodeConfig.Node.ParentChainReader.Enable {
 		confFetcher := func() *rpcclient.ClientConfig { return &liveNodeConfig.Get().ParentChain.Connection }
-		rpcClient := rpcclient.NewRpcClient(confFetcher, nil)
+		failoverConfFetcher := func() *rpcclient.FailoverConfig { return &liveNodeConfig.Get().ParentChain.ConnectionFailover }
+		// The connection url may list several endpoints to fail over between
+		rpcClient := rpcclient.NewFailoverClient(confFetcher, failoverConfFetcher, nil)
 		err := rpcClient.Start(ctx)
 		if err != nil {
 			log.Crit("couldn't connect to L1", "err", err)
 		}
 		l1Client = ethclient.NewClient(rpcClient)
+		parentChainClient = l1Client
 		l1ChainId, err := l1Client.ChainID(ctx)
 		if err != nil {
 			log.Crit("couldn't read L1 chainid", "err", err)
@@ -363,6 +387,42 @@ func mainImpl() int {
 			}
 			blobReader = blobClient
 		}
+
+		if nodeConfig.ParentChain.ArchiveConnection.URL != "" {
+			archiveConfFetcher := func() *rpcclient.ClientConfig { return &liveNodeConfig.Get().ParentChain.ArchiveConnection }
+			archiveRpcClient := rpcclient.NewRpcClient(archiveConfFetcher, nil)
+			if err := archiveRpcClient.Start(ctx); err != nil {
+				log.Crit("couldn't connect to parent chain archive", "err", err)
+			}
+			archiveClient := ethclient.NewClient(archiveRpcClient)
+			archiveChainId, err := archiveClient.ChainID(ctx)
+			if err != nil {
+				log.Crit("couldn't read parent chain archive chainid", "err", err)
+			}
+			if archiveChainId.Uint64() != nodeConfig.ParentChain.ID {
+				log.Crit("parent chain archive chainID doesn't fit config", "found", archiveChainId.Uint64(), "expected", nodeConfig.ParentChain.ID)
+			}
+			log.Info("connected to parent chain archive", "url", nodeConfig.ParentChain.ArchiveConnection.URL, "afterBlocks", nodeConfig.ParentChain.ArchiveAfterBlocks)
+			parentChainClient = arbnode.NewArchiveClient(l1Client, archiveClient, func() uint64 { return liveNodeConfig.Get().ParentChain.ArchiveAfterBlocks })
+		}
+
+		if nodeConfig.ParentChain.LightClient.URL != "" {
+			lightClientConfFetcher := func() *rpcclient.ClientConfig { return &liveNodeConfig.Get().ParentChain.LightClient }
+			lightClientRpcClient := rpcclient.NewRpcClient(lightClientConfFetcher, nil)
+			if err := lightClientRpcClient.Start(ctx); err != nil {
+				log.Crit("couldn't connect to parent chain light client", "err", err)
+			}
+			lightClient := ethclient.NewClient(lightClientRpcClient)
+			lightClientChainId, err := lightClient.ChainID(ctx)
+			if err != nil {
+				log.Crit("couldn't read parent chain light client chainid", "err", err)
+			}
+			if lightClientChainId.Uint64() != nodeConfig.ParentChain.ID {
+				log.Crit("parent chain light client chainID doesn't fit config", "found", lightClientChainId.Uint64(), "expected", nodeConfig.ParentChain.ID)
+			}
+			log.Info("verifying parent chain data with light client", "url", nodeConfig.ParentChain.LightClient.URL)
+			parentChainClient = arbnode.NewVerifiedClient(parentChainClient, lightClient)
+		}
 	}
 
 	if nodeConfig.Node.Staker.OnlyCreateWalletContract {
@@ -379,7 +439,7 @@ func mainImpl() int {
 		if err != nil {
 			log.Crit("error getting rollup addresses config", "err", err)
 		}
-		addr, err := validatorwallet.GetValidatorWalletContract(ctx, deployInfo.ValidatorWalletCreator, int64(deployInfo.DeployedAt), l1TransactionOptsValidator, l1Reader, true)
+		addr, err := validatorwallet.GetValidatorWalletContract(ctx, deployInfo.ValidatorWalletCreator, deployInfo.Rollup, int64(deployInfo.DeployedAt), l1TransactionOptsValidator, l1Reader, true)
 		if err != nil {
 			log.Crit("error creating validator wallet contract", "error", err, "address", l1TransactionOptsValidator.From.Hex())
 		}
@@ -462,13 +522,12 @@ func mainImpl() int {
 				return 1
 			}
 		} else {
-			// If no allowed module roots were provided in config, check if we have a validator machine directory for the on-chain WASM module root
-			locator, err := server_common.NewMachineLocator(nodeConfig.Validation.Wasm.RootPath)
+			// If no allowed module roots were provided in config, check if we have (or can download) a validator machine directory for the on-chain WASM module root
+			locator, err := valnode.CreateMachineLocator(&nodeConfig.Validation.Wasm)
 			if err != nil {
 				log.Warn("failed to create machine locator. Skipping the check for compatibility with on-chain WASM module root", "err", err)
 			} else {
-				path := locator.GetMachinePath(moduleRoot)
-				if _, err := os.Stat(path); err != nil {
+				if err := locator.EnsureMachine(ctx, moduleRoot); err != nil {
 					log.Error("unable to find validator machine directory for the on-chain WASM module root", "err", err)
 					return 1
 				}
@@ -487,7 +546,8 @@ func mainImpl() int {
 		return 1
 	}
 
-	arbDb, err := stack.OpenDatabase("arbitrumdata", 0, 0, "", false)
+	// Metrics are namespaced per database, as geth's database meters would otherwise collide
+	arbDb, err := stack.OpenDatabase("arbitrumdata", 0, 0, "arbitrumdata/", false)
 	deferFuncs = append(deferFuncs, func() { closeDb(arbDb, "arbDb") })
 	if err != nil {
 		log.Error("failed to open database", "err", err)
@@ -549,7 +609,7 @@ func mainImpl() int {
 		arbDb,
 		&NodeConfigFetcher{liveNodeConfig},
 		l2BlockChain.Config(),
-		l1Client,
+		parentChainClient,
 		&rollupAddrs,
 		l1TransactionOptsValidator,
 		l1TransactionOptsBatchPoster,
@@ -590,9 +650,14 @@ func mainImpl() int {
 	}
 	// If sequencer is enabled, validate MaxTxDataSize to be at least 5kB below the batch poster's MaxSize to allow space for headers and such.
 	// And since batchposter's MaxSize is to be at least 10kB below the sequencer inbox’s maxDataSize, this leads to another condition of atlest 15kB below the sequencer inbox’s maxDataSize.
+	// With the data availability service, batches are limited by MaxDASBatchSize if it's set, and needn't fit in the sequencer inbox.
 	if nodeConfig.Execution.Sequencer.Enable {
-		if nodeConfig.Execution.Sequencer.MaxTxDataSize > nodeConfig.Node.BatchPoster.MaxSize-5000 ||
-			nodeConfig.Execution.Sequencer.MaxTxDataSize > seqInboxMaxDataSize-15000 {
+		batchMaxSize := nodeConfig.Node.BatchPoster.MaxSize
+		if nodeConfig.Node.DataAvailability.Enable && nodeConfig.Node.BatchPoster.MaxDASBatchSize > 0 {
+			batchMaxSize = nodeConfig.Node.BatchPoster.MaxDASBatchSize
+		}
+		if nodeConfig.Execution.Sequencer.MaxTxDataSize > batchMaxSize-5000 ||
+			(!nodeConfig.Node.DataAvailability.Enable && nodeConfig.Execution.Sequencer.MaxTxDataSize > seqInboxMaxDataSize-15000) {
 			log.Error("sequencer's MaxTxDataSize too large")
 			return 1
 		}
@@ -644,6 +709,18 @@ func mainImpl() int {
 		// remove previous deferFuncs, StopAndWait closes database and blockchain.
 		deferFuncs = []func(){func() { currentNode.StopAndWait() }}
 	}
+	if err == nil && nodeConfig.OperatorAPI.Enable {
+		server, err := startOperatorAPI(&nodeConfig.OperatorAPI, &nodeConfig.Persistent, liveNodeConfig, currentNode, execNode, l2BlockChain)
+		if err != nil {
+			log.Error("failed to start operator API", "err", err)
+			return 1
+		}
+		deferFuncs = append(deferFuncs, func() {
+			if err := server.Close(); err != nil {
+				log.Warn("error closing operator API server", "err", err)
+			}
+		})
+	}
 	if nodeConfig.BlocksReExecutor.Enable && l2BlockChain != nil {
 		blocksReExecutor := blocksreexecutor.New(&nodeConfig.BlocksReExecutor, l2BlockChain, fatalErrChan)
 		blocksReExecutor.Start(ctx)
@@ -707,6 +784,7 @@ type NodeConfig struct {
 	Init             conf.InitConfig                 `koanf:"init"`
 	Rpc              genericconf.RpcConfig           `koanf:"rpc"`
 	BlocksReExecutor blocksreexecutor.Config         `koanf:"blocks-reexecutor"`
+	OperatorAPI      genericconf.OperatorAPIConfig   `koanf:"operator-api"`
 }
 
 var NodeConfigDefault = NodeConfig{
@@ -733,6 +811,7 @@ var NodeConfigDefault = NodeConfig{
 	PProf:            false,
 	PprofCfg:         genericconf.PProfDefault,
 	BlocksReExecutor: blocksreexecutor.DefaultConfig,
+	OperatorAPI:      genericconf.OperatorAPIConfigDefault,
 }
 
 func NodeConfigAddOptions(f *flag.FlagSet) {
@@ -760,6 +839,7 @@ func NodeConfigAddOptions(f *flag.FlagSet) {
 	conf.InitConfigAddOptions("init", f)
 	genericconf.RpcConfigAddOptions("rpc", f)
 	blocksreexecutor.ConfigAddOptions("blocks-reexecutor", f)
+	genericconf.OperatorAPIConfigAddOptions("operator-api", f)
 }
 
 func (c *NodeConfig) ResolveDirectoryNames() error {
@@ -961,6 +1041,9 @@ func applyChainParameters(ctx context.Context, k *koanf.Koanf, chainId uint64, c
 	if !chainInfo.HasGenesisState {
 		chainDefaults["init.empty"] = true
 	}
+	if chainInfo.ParentChainTagFallbackConfirmations > 0 {
+		chainDefaults["node.inbox-reader.tag-fallback-confirmations"] = chainInfo.ParentChainTagFallbackConfirmations
+	}
 	if parentChainIsArbitrum {
 		l2MaxTxSize := gethexec.DefaultSequencerConfig.MaxTxDataSize
 		bufferSpace := 5000
@@ -973,6 +1056,10 @@ func applyChainParameters(ctx context.Context, k *koanf.Koanf, chainId uint64, c
 		// Arbitrum chains produce blocks more quickly, so the inbox reader should read more blocks at once.
 		// Even if this is too large, on error the inbox reader will reset its query size down to the default.
 		chainDefaults["node.inbox-reader.max-blocks-to-read"] = 10_000
+		chainDefaults["node.inbox-reader.default-blocks-to-read"] = 1_000
+		// With blocks every quarter second, waiting for a few of them before reading costs little latency,
+		// and saves querying the parent chain for every single block.
+		chainDefaults["node.inbox-reader.min-blocks-to-read"] = 4
 	}
 	if chainInfo.DasIndexUrl != "" {
 		chainDefaults["node.batch-poster.max-size"] = 1_000_000
@@ -0,0 +1,346 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/cmd/conf"
+	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/execution/gethexec"
+)
+
+type operatorSequencer interface {
+	PauseSequencing()
+	ResumeSequencing()
+	SequencingPaused() bool
+}
+
+// operatorAPI is a small REST API for operational tasks, meant for dashboards and curl-based runbooks.
+// Every request must carry an "Authorization: Bearer <token>" header with the contents of the token file.
+type operatorAPI struct {
+	token string
+	// sequencer is nil if the node isn't sequencing
+	sequencer    operatorSequencer
+	syncProgress func() map[string]interface{}
+	headBlock    func() uint64
+	exportState  func(blockNum uint64, w io.Writer) (*types.Header, error)
+	snapshotDir  string
+	config       func() interface{}
+	rotateLog    func() error
+
+	snapshotting atomic.Bool
+}
+
+func startOperatorAPI(
+	config *genericconf.OperatorAPIConfig,
+	persistent *conf.PersistentConfig,
+	liveNodeConfig *genericconf.LiveConfig[*NodeConfig],
+	currentNode *arbnode.Node,
+	execNode *gethexec.ExecutionNode,
+	l2BlockChain *core.BlockChain,
+) (*http.Server, error) {
+	tokenFile := config.TokenFile
+	if tokenFile == "" {
+		tokenFile = "operator-token"
+		if err := genericconf.TryCreatingJWTSecret(filepath.Join(persistent.GlobalConfig, tokenFile)); err != nil {
+			return nil, err
+		}
+	}
+	if !filepath.IsAbs(tokenFile) {
+		tokenFile = filepath.Join(persistent.GlobalConfig, tokenFile)
+	}
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read operator API token: %w", err)
+	}
+	if len(strings.TrimSpace(string(token))) == 0 {
+		return nil, fmt.Errorf("operator API token file %v is empty", tokenFile)
+	}
+	snapshotDir := config.SnapshotDir
+	if snapshotDir == "" {
+		snapshotDir = "snapshots"
+	}
+	if !filepath.IsAbs(snapshotDir) {
+		snapshotDir = filepath.Join(persistent.Chain, snapshotDir)
+	}
+	api := &operatorAPI{
+		token:        strings.TrimSpace(string(token)),
+		syncProgress: currentNode.SyncMonitor.SyncProgressMap,
+		headBlock:    func() uint64 { return l2BlockChain.CurrentBlock().Number.Uint64() },
+		exportState:  execNode.ExportState,
+		snapshotDir:  snapshotDir,
+		config:       func() interface{} { return liveNodeConfig.Get() },
+		rotateLog:    genericconf.RotateLog,
+	}
+	if execNode.Sequencer != nil {
+		api.sequencer = execNode.Sequencer
+	}
+	server := &http.Server{
+		Addr:              fmt.Sprintf("%v:%v", config.Addr, config.Port),
+		Handler:           api.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("error serving operator API", "err", err)
+		}
+	}()
+	log.Info("operator API listening", "addr", listener.Addr(), "tokenFile", tokenFile)
+	return server, nil
+}
+
+func (a *operatorAPI) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", a.only(http.MethodGet, a.health))
+	mux.HandleFunc("/config", a.only(http.MethodGet, a.getConfig))
+	mux.HandleFunc("/sequencer", a.only(http.MethodGet, a.sequencerStatus))
+	mux.HandleFunc("/sequencer/pause", a.only(http.MethodPost, a.pauseSequencer))
+	mux.HandleFunc("/sequencer/resume", a.only(http.MethodPost, a.resumeSequencer))
+	mux.HandleFunc("/snapshot", a.only(http.MethodPost, a.snapshot))
+	mux.HandleFunc("/logs/rotate", a.only(http.MethodPost, a.rotateLogs))
+	return a.authenticated(mux)
+}
+
+func (a *operatorAPI) authenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(a.token)) != 1 {
+			writeOperatorError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *operatorAPI) only(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeOperatorError(w, http.StatusMethodNotAllowed, fmt.Errorf("%v requires %v", r.URL.Path, method))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func writeOperatorResponse(w http.ResponseWriter, status int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Warn("error writing operator API response", "err", err)
+	}
+}
+
+func writeOperatorError(w http.ResponseWriter, status int, err error) {
+	writeOperatorResponse(w, status, map[string]string{"error": err.Error()})
+}
+
+func (a *operatorAPI) health(w http.ResponseWriter, r *http.Request) {
+	progress := a.syncProgress()
+	status := http.StatusOK
+	if len(progress) > 0 {
+		status = http.StatusServiceUnavailable
+	}
+	writeOperatorResponse(w, status, map[string]interface{}{
+		"synced":   len(progress) == 0,
+		"progress": progress,
+	})
+}
+
+func (a *operatorAPI) getConfig(w http.ResponseWriter, r *http.Request) {
+	writeOperatorResponse(w, http.StatusOK, redactedConfigMap(reflect.ValueOf(a.config())))
+}
+
+func (a *operatorAPI) sequencerStatus(w http.ResponseWriter, r *http.Request) {
+	if a.sequencer == nil {
+		writeOperatorError(w, http.StatusNotFound, errors.New("node is not a sequencer"))
+		return
+	}
+	writeOperatorResponse(w, http.StatusOK, map[string]bool{"paused": a.sequencer.SequencingPaused()})
+}
+
+func (a *operatorAPI) pauseSequencer(w http.ResponseWriter, r *http.Request) {
+	if a.sequencer == nil {
+		writeOperatorError(w, http.StatusNotFound, errors.New("node is not a sequencer"))
+		return
+	}
+	log.Warn("pausing sequencer from the operator API")
+	a.sequencer.PauseSequencing()
+	writeOperatorResponse(w, http.StatusOK, map[string]bool{"paused": a.sequencer.SequencingPaused()})
+}
+
+func (a *operatorAPI) resumeSequencer(w http.ResponseWriter, r *http.Request) {
+	if a.sequencer == nil {
+		writeOperatorError(w, http.StatusNotFound, errors.New("node is not a sequencer"))
+		return
+	}
+	log.Warn("resuming sequencer from the operator API")
+	a.sequencer.ResumeSequencing()
+	writeOperatorResponse(w, http.StatusOK, map[string]bool{"paused": a.sequencer.SequencingPaused()})
+}
+
+// snapshot starts writing the state at the block given by the block query parameter, or the head block,
+// to a file in the snapshot directory. The file only appears once the whole state has been written.
+func (a *operatorAPI) snapshot(w http.ResponseWriter, r *http.Request) {
+	blockNum := a.headBlock()
+	if param := r.URL.Query().Get("block"); param != "" {
+		var err error
+		blockNum, err = strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			writeOperatorError(w, http.StatusBadRequest, fmt.Errorf("invalid block %q", param))
+			return
+		}
+	}
+	if !a.snapshotting.CompareAndSwap(false, true) {
+		writeOperatorError(w, http.StatusConflict, errors.New("a snapshot is already being written"))
+		return
+	}
+	if err := os.MkdirAll(a.snapshotDir, 0755); err != nil {
+		a.snapshotting.Store(false)
+		writeOperatorError(w, http.StatusInternalServerError, err)
+		return
+	}
+	path := filepath.Join(a.snapshotDir, fmt.Sprintf("state-%d.json", blockNum))
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		a.snapshotting.Store(false)
+		writeOperatorError(w, http.StatusInternalServerError, err)
+		return
+	}
+	go func() {
+		defer a.snapshotting.Store(false)
+		start := time.Now()
+		header, err := a.exportState(blockNum, file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(file.Name(), path)
+		}
+		if err != nil {
+			log.Error("failed writing state snapshot", "block", blockNum, "err", err)
+			_ = os.Remove(file.Name())
+			return
+		}
+		log.Info("wrote state snapshot", "block", blockNum, "hash", header.Hash(), "root", header.Root, "file", path, "elapsed", time.Since(start))
+	}()
+	writeOperatorResponse(w, http.StatusAccepted, map[string]interface{}{
+		"block": blockNum,
+		"file":  path,
+	})
+}
+
+func (a *operatorAPI) rotateLogs(w http.ResponseWriter, r *http.Request) {
+	if err := a.rotateLog(); err != nil {
+		writeOperatorError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeOperatorResponse(w, http.StatusOK, map[string]bool{"rotated": true})
+}
+
+// redactedConfigKeys are the config options whose values are never returned by the operator API
+var redactedConfigKeys = map[string]bool{
+	"password":                true,
+	"private-key":             true,
+	"priv-key":                true,
+	"client-private-key":      true,
+	"secret-key":              true,
+	"access-key":              true,
+	"signing-key":             true,
+	"session-key":             true,
+	"signing-wallet-password": true,
+}
+
+// redactURLs keeps only the scheme and host of each comma separated URL, since
+// provider URLs commonly include an API key in their path, query, or user info
+func redactURLs(urls string) string {
+	var redacted []string
+	for _, raw := range strings.Split(urls, ",") {
+		parsed, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			redacted = append(redacted, "<redacted>")
+			continue
+		}
+		if parsed.Host == "" {
+			// such as self or a file path
+			redacted = append(redacted, raw)
+			continue
+		}
+		redacted = append(redacted, parsed.Scheme+"://"+parsed.Host)
+	}
+	return strings.Join(redacted, ",")
+}
+
+// redactedConfigMap converts a config struct to a map keyed by option name, without secrets
+func redactedConfigMap(value reflect.Value) interface{} {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Type() == reflect.TypeOf(time.Duration(0)) {
+		return value.Interface().(time.Duration).String()
+	}
+	if value.Kind() != reflect.Struct {
+		return value.Interface()
+	}
+	res := make(map[string]interface{})
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key := field.Tag.Get("koanf")
+		if !field.IsExported() || key == "" || key == "-" {
+			continue
+		}
+		fieldValue := value.Field(i)
+		if redactedConfigKeys[key] {
+			if !fieldValue.IsZero() {
+				res[key] = "<redacted>"
+			} else {
+				res[key] = ""
+			}
+			continue
+		}
+		if strings.HasSuffix(key, "url") || strings.HasSuffix(key, "urls") || strings.HasSuffix(key, "url-list") {
+			if urls, ok := fieldValue.Interface().(string); ok {
+				res[key] = redactURLs(urls)
+				continue
+			}
+			if urls, ok := fieldValue.Interface().([]string); ok {
+				redacted := make([]string, len(urls))
+				for i, u := range urls {
+					redacted[i] = redactURLs(u)
+				}
+				res[key] = redacted
+				continue
+			}
+		}
+		res[key] = redactedConfigMap(fieldValue)
+	}
+	return res
+}
@@ -0,0 +1,134 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type fakeOperatorSequencer struct {
+	paused atomic.Bool
+}
+
+func (s *fakeOperatorSequencer) PauseSequencing()       { s.paused.Store(true) }
+func (s *fakeOperatorSequencer) ResumeSequencing()      { s.paused.Store(false) }
+func (s *fakeOperatorSequencer) SequencingPaused() bool { return s.paused.Load() }
+
+func TestOperatorAPI(t *testing.T) {
+	sequencer := &fakeOperatorSequencer{}
+	config := NodeConfigDefault
+	config.ParentChain.Connection.URL = "https://provider.example/v3/apikey"
+	config.ParentChain.Wallet.PrivateKey = "secret"
+	var rotations atomic.Int64
+	var syncing atomic.Bool
+	api := &operatorAPI{
+		token:     "token",
+		sequencer: sequencer,
+		syncProgress: func() map[string]interface{} {
+			if syncing.Load() {
+				return map[string]interface{}{"msgCount": 1}
+			}
+			return map[string]interface{}{}
+		},
+		headBlock: func() uint64 { return 7 },
+		exportState: func(blockNum uint64, w io.Writer) (*types.Header, error) {
+			_, err := w.Write([]byte("{\"root\":\"0x00\"}\n"))
+			return &types.Header{Number: new(big.Int).SetUint64(blockNum)}, err
+		},
+		snapshotDir: t.TempDir(),
+		config:      func() interface{} { return &config },
+		rotateLog: func() error {
+			rotations.Add(1)
+			return nil
+		},
+	}
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+
+	request := func(method, path, token string, expectedStatus int) map[string]interface{} {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+path, nil)
+		Require(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Require(t, err)
+		defer resp.Body.Close()
+		if resp.StatusCode != expectedStatus {
+			Fail(t, method, path, "returned status", resp.StatusCode, "but expected", expectedStatus)
+		}
+		var body map[string]interface{}
+		Require(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body
+	}
+
+	request(http.MethodGet, "/health", "", http.StatusUnauthorized)
+	request(http.MethodGet, "/health", "wrong", http.StatusUnauthorized)
+	if body := request(http.MethodGet, "/health", "token", http.StatusOK); body["synced"] != true {
+		Fail(t, "expected synced node to be healthy but got", body)
+	}
+	syncing.Store(true)
+	request(http.MethodGet, "/health", "token", http.StatusServiceUnavailable)
+
+	request(http.MethodGet, "/sequencer/pause", "token", http.StatusMethodNotAllowed)
+	if body := request(http.MethodPost, "/sequencer/pause", "token", http.StatusOK); body["paused"] != true || !sequencer.SequencingPaused() {
+		Fail(t, "expected sequencer to be paused but got", body)
+	}
+	if body := request(http.MethodPost, "/sequencer/resume", "token", http.StatusOK); body["paused"] != false || sequencer.SequencingPaused() {
+		Fail(t, "expected sequencer to be resumed but got", body)
+	}
+
+	request(http.MethodPost, "/logs/rotate", "token", http.StatusOK)
+	if rotations.Load() != 1 {
+		Fail(t, "expected logs to be rotated once but got", rotations.Load())
+	}
+
+	body := request(http.MethodGet, "/config", "token", http.StatusOK)
+	parentChain := body["parent-chain"].(map[string]interface{})
+	if url := parentChain["connection"].(map[string]interface{})["url"]; url != "https://provider.example" {
+		Fail(t, "expected parent chain url to be redacted but got", url)
+	}
+	if key := parentChain["wallet"].(map[string]interface{})["private-key"]; key != "<redacted>" {
+		Fail(t, "expected private key to be redacted but got", key)
+	}
+	if timeout := parentChain["connection"].(map[string]interface{})["timeout"]; timeout != "1m0s" {
+		Fail(t, "expected parent chain timeout to be a duration but got", timeout)
+	}
+
+	request(http.MethodPost, "/snapshot?block=abc", "token", http.StatusBadRequest)
+	body = request(http.MethodPost, "/snapshot", "token", http.StatusAccepted)
+	if body["block"] != float64(7) {
+		Fail(t, "expected a snapshot of the head block but got", body)
+	}
+	file := body["file"].(string)
+	if filepath.Dir(file) != api.snapshotDir {
+		Fail(t, "snapshot written to", file, "outside of", api.snapshotDir)
+	}
+	for i := 0; ; i++ {
+		data, err := os.ReadFile(file)
+		if err == nil {
+			if !strings.Contains(string(data), "root") {
+				Fail(t, "unexpected snapshot contents", string(data))
+			}
+			break
+		}
+		if i >= 100 {
+			Fail(t, "snapshot wasn't written", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+)
+
+type RevalidateConfig struct {
+	NodeURL    string        `koanf:"node-url"`
+	From       int64         `koanf:"from"`
+	To         int64         `koanf:"to"`
+	ModuleRoot string        `koanf:"module-root"`
+	Full       bool          `koanf:"full"`
+	ChunkSize  uint64        `koanf:"chunk-size"`
+	Timeout    time.Duration `koanf:"timeout"`
+
+	moduleRoot *common.Hash
+}
+
+var DefaultRevalidateConfig = RevalidateConfig{
+	NodeURL:    "http://127.0.0.1:8547",
+	From:       -1,
+	To:         -1,
+	ModuleRoot: "",
+	Full:       false,
+	ChunkSize:  16,
+	Timeout:    time.Hour,
+}
+
+func RevalidateConfigAddOptions(f *flag.FlagSet) {
+	f.String("node-url", DefaultRevalidateConfig.NodeURL, "RPC URL of the node to validate with; it must serve the arbvalidator API")
+	f.Int64("from", DefaultRevalidateConfig.From, "first block to validate")
+	f.Int64("to", DefaultRevalidateConfig.To, "last block to validate (-1 = the from block)")
+	f.String("module-root", DefaultRevalidateConfig.ModuleRoot, "wasm module root to validate against (empty = the node's current module root)")
+	f.Bool("full", DefaultRevalidateConfig.Full, "run the full arbitrator machine rather than the JIT validator")
+	f.Uint64("chunk-size", DefaultRevalidateConfig.ChunkSize, "number of blocks to validate per request to the node")
+	f.Duration("timeout", DefaultRevalidateConfig.Timeout, "timeout for validating the whole range")
+}
+
+func (c *RevalidateConfig) Validate() error {
+	if c.From < 0 {
+		return errors.New("revalidate requires a from block")
+	}
+	if c.To < 0 {
+		c.To = c.From
+	}
+	if c.To < c.From {
+		return fmt.Errorf("to block %v is before from block %v", c.To, c.From)
+	}
+	if c.ChunkSize == 0 || c.ChunkSize > 1024 {
+		return errors.New("chunk-size must be between 1 and 1024")
+	}
+	c.moduleRoot = nil
+	if c.ModuleRoot != "" {
+		root := common.HexToHash(c.ModuleRoot)
+		if (root == common.Hash{}) {
+			return fmt.Errorf("invalid module root \"%v\"", c.ModuleRoot)
+		}
+		c.moduleRoot = &root
+	}
+	return nil
+}
+
+func parseRevalidateConfig(args []string) (*RevalidateConfig, error) {
+	f := flag.NewFlagSet("revalidate", flag.ContinueOnError)
+	RevalidateConfigAddOptions(f)
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config RevalidateConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	return &config, config.Validate()
+}
+
+func printRevalidateUsage(name string) {
+	fmt.Printf("Sample usage: %s revalidate --node-url http://127.0.0.1:8547 --from 1000 --to 1100\n\n", name)
+	fmt.Printf("Has a node re-validate a range of blocks, and reports whether each one validated\n")
+}
+
+// runRevalidate has a node re-validate a range of blocks. Returns the exit code, which is non-zero if any block
+// failed to validate.
+func runRevalidate(ctx context.Context, args []string) int {
+	config, err := parseRevalidateConfig(args)
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, printRevalidateUsage)
+	}
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(true)))
+	glogger.Verbosity(log.LvlWarn)
+	log.Root().SetHandler(glogger)
+
+	failed, err := revalidateMain(ctx, config, os.Stdout)
+	if err != nil {
+		log.Error("revalidation failed", "err", err)
+		return 1
+	}
+	if failed > 0 {
+		log.Error("blocks failed validation", "failed", failed, "from", config.From, "to", config.To)
+		return 1
+	}
+	return 0
+}
+
+// revalidateMain validates the range a chunk at a time, writing each block's result as it's validated.
+// Returns the number of blocks that failed.
+func revalidateMain(ctx context.Context, config *RevalidateConfig, output io.Writer) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+
+	rpcClient, err := rpc.DialContext(ctx, config.NodeURL)
+	if err != nil {
+		return 0, fmt.Errorf("error connecting to node: %w", err)
+	}
+	defer rpcClient.Close()
+
+	failed := 0
+	for from := uint64(config.From); from <= uint64(config.To); from += config.ChunkSize {
+		to := from + config.ChunkSize - 1
+		if to > uint64(config.To) {
+			to = uint64(config.To)
+		}
+		var results []arbnode.BlockRangeValidationResult
+		err := rpcClient.CallContext(ctx, &results, "arbvalidator_validateBlockRange", hexutil.Uint64(from), hexutil.Uint64(to), config.Full, config.moduleRoot)
+		if err != nil {
+			return failed, fmt.Errorf("error validating blocks %v to %v: %w", from, to, err)
+		}
+		for _, result := range results {
+			var line string
+			if result.Error != "" {
+				line = fmt.Sprintf("block %v (message %v): error after %v: %v", uint64(result.Block), uint64(result.MessageNumber), result.Latency, result.Error)
+			} else if !result.Valid {
+				var got common.Hash
+				if result.GlobalState != nil {
+					got = result.GlobalState.BlockHash
+				}
+				line = fmt.Sprintf("block %v (message %v): INVALID after %v: expected block hash %v, got %v", uint64(result.Block), uint64(result.MessageNumber), result.Latency, result.Expected.BlockHash, got)
+			} else {
+				line = fmt.Sprintf("block %v (message %v): valid in %v", uint64(result.Block), uint64(result.MessageNumber), result.Latency)
+			}
+			if !result.Valid {
+				failed++
+			}
+			if _, err := fmt.Fprintln(output, line); err != nil {
+				return failed, err
+			}
+		}
+	}
+	return failed, nil
+}
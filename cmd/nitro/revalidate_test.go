@@ -0,0 +1,93 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/validator"
+)
+
+type fakeBlockValidatorAPI struct {
+	ranges      [][2]uint64
+	moduleRoots []*common.Hash
+}
+
+func (a *fakeBlockValidatorAPI) ValidateBlockRange(fromBlock, toBlock hexutil.Uint64, full bool, moduleRoot *common.Hash) []arbnode.BlockRangeValidationResult {
+	a.ranges = append(a.ranges, [2]uint64{uint64(fromBlock), uint64(toBlock)})
+	a.moduleRoots = append(a.moduleRoots, moduleRoot)
+	var results []arbnode.BlockRangeValidationResult
+	for block := fromBlock; block <= toBlock; block++ {
+		result := arbnode.BlockRangeValidationResult{
+			Block:         block,
+			MessageNumber: block,
+			Valid:         true,
+			Latency:       "1ms",
+		}
+		if block == 5 {
+			result.Valid = false
+			result.Expected = validator.GoGlobalState{BlockHash: common.HexToHash("0x05")}
+			result.GlobalState = &validator.GoGlobalState{BlockHash: common.HexToHash("0x06")}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func TestRevalidate(t *testing.T) {
+	api := &fakeBlockValidatorAPI{}
+	server := rpc.NewServer()
+	if err := server.RegisterName("arbvalidator", api); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	moduleRoot := common.HexToHash("0x1234")
+	config, err := parseRevalidateConfig([]string{"--node-url", httpServer.URL, "--from", "3", "--to", "7", "--chunk-size", "2", "--module-root", moduleRoot.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	failed, err := revalidateMain(context.Background(), config, &output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 1 {
+		t.Fatal("expected one block to fail but got", failed)
+	}
+	if len(api.ranges) != 3 || api.ranges[0] != [2]uint64{3, 4} || api.ranges[2] != [2]uint64{7, 7} {
+		t.Fatal("validated unexpected ranges", api.ranges)
+	}
+	if api.moduleRoots[0] == nil || *api.moduleRoots[0] != moduleRoot {
+		t.Fatal("validated against unexpected module root", api.moduleRoots[0])
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 5 || !strings.Contains(lines[2], "INVALID") || !strings.Contains(lines[0], "valid") {
+		t.Fatal("unexpected output", output.String())
+	}
+
+	config, err = parseRevalidateConfig([]string{"--from", "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.To != 3 || config.moduleRoot != nil {
+		t.Fatal("unexpected defaults", config.To, config.moduleRoot)
+	}
+	if _, err := parseRevalidateConfig([]string{"--to", "3"}); err == nil {
+		t.Fatal("expected a missing from block to be rejected")
+	}
+	if _, err := parseRevalidateConfig([]string{"--from", "5", "--to", "3"}); err == nil {
+		t.Fatal("expected a reversed range to be rejected")
+	}
+}
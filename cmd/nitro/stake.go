@@ -0,0 +1,155 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	gethnode "github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+// Stake operations, and the arbstaker RPC methods that carry them out
+var stakeOperations = map[string]string{
+	"withdraw":     "arbstaker_withdrawStake",
+	"refund":       "arbstaker_refundOldStakers",
+	"add-to-stake": "arbstaker_addToStake",
+}
+
+type StakeConfig struct {
+	NodeURL       string        `koanf:"node-url"`
+	NodeJWTSecret string        `koanf:"node-jwtsecret"`
+	Stakers       []string      `koanf:"stakers"`
+	Timeout       time.Duration `koanf:"timeout"`
+
+	operation string
+	stakers   []common.Address
+}
+
+var DefaultStakeConfig = StakeConfig{
+	NodeURL:       "http://127.0.0.1:8549",
+	NodeJWTSecret: "",
+	Stakers:       []string{},
+	Timeout:       10 * time.Minute,
+}
+
+func StakeConfigAddOptions(f *flag.FlagSet) {
+	f.String("node-url", DefaultStakeConfig.NodeURL, "URL of the staking node's authenticated RPC, which serves the arbstaker API (see the node's auth options)")
+	f.String("node-jwtsecret", DefaultStakeConfig.NodeJWTSecret, "path to the file with the JWT secret of the staking node's authenticated RPC")
+	f.StringSlice("stakers", DefaultStakeConfig.Stakers, "stakers whose deposits to refund (empty = all refundable stakers)")
+	f.Duration("timeout", DefaultStakeConfig.Timeout, "timeout for the node to send the transactions and have them approved")
+}
+
+func (c *StakeConfig) Validate() error {
+	if _, ok := stakeOperations[c.operation]; !ok {
+		return fmt.Errorf("unknown stake operation \"%v\", expected withdraw, refund or add-to-stake", c.operation)
+	}
+	if len(c.Stakers) > 0 && c.operation != "refund" {
+		return errors.New("stakers can only be given to refund")
+	}
+	c.stakers = nil
+	for _, staker := range c.Stakers {
+		if !common.IsHexAddress(staker) {
+			return fmt.Errorf("invalid staker address \"%v\"", staker)
+		}
+		c.stakers = append(c.stakers, common.HexToAddress(staker))
+	}
+	return nil
+}
+
+func parseStakeConfig(args []string) (*StakeConfig, error) {
+	if len(args) == 0 {
+		return nil, errors.New("stake requires an operation: withdraw, refund or add-to-stake")
+	}
+	f := flag.NewFlagSet("stake", flag.ContinueOnError)
+	StakeConfigAddOptions(f)
+	k, err := confighelpers.BeginCommonParse(f, args[1:])
+	if err != nil {
+		return nil, err
+	}
+	var config StakeConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	config.operation = args[0]
+	return &config, config.Validate()
+}
+
+func printStakeUsage(name string) {
+	fmt.Printf("Sample usage: %s stake withdraw --node-url http://127.0.0.1:8549 --node-jwtsecret /path/to/jwtsecret\n\n", name)
+	fmt.Printf("Has a running staker withdraw its stake once confirmed (withdraw), refund the deposits of old stakers (refund), or top up its stake to the current requirement (add-to-stake)\n")
+}
+
+// runStake has a running staker carry out a stake operation. Returns the exit code.
+func runStake(ctx context.Context, args []string) int {
+	config, err := parseStakeConfig(args)
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, printStakeUsage)
+	}
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(true)))
+	glogger.Verbosity(log.LvlWarn)
+	log.Root().SetHandler(glogger)
+
+	if err := stakeMain(ctx, config, os.Stdout); err != nil {
+		log.Error("stake failed", "operation", config.operation, "err", err)
+		return 1
+	}
+	return 0
+}
+
+// dialStakingNode connects to the staking node's authenticated RPC, where the arbstaker API is served
+func dialStakingNode(ctx context.Context, config *StakeConfig) (*rpc.Client, error) {
+	if config.NodeJWTSecret == "" {
+		return rpc.DialContext(ctx, config.NodeURL)
+	}
+	jwt, err := signature.LoadSigningKey(config.NodeJWTSecret)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.DialOptions(ctx, config.NodeURL, rpc.WithHTTPAuth(gethnode.NewJWTAuth([32]byte(*jwt))))
+}
+
+func stakeMain(ctx context.Context, config *StakeConfig, output io.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+
+	rpcClient, err := dialStakingNode(ctx, config)
+	if err != nil {
+		return fmt.Errorf("error connecting to node: %w", err)
+	}
+	defer rpcClient.Close()
+
+	var txs []common.Hash
+	method := stakeOperations[config.operation]
+	if config.operation == "refund" {
+		err = rpcClient.CallContext(ctx, &txs, method, config.stakers)
+	} else {
+		err = rpcClient.CallContext(ctx, &txs, method)
+	}
+	if err != nil {
+		return err
+	}
+	if len(txs) == 0 {
+		_, err = fmt.Fprintln(output, "nothing to do")
+		return err
+	}
+	for _, tx := range txs {
+		if _, err := fmt.Fprintln(output, tx.Hex()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type fakeStakerAPI struct {
+	refunded []common.Address
+}
+
+func (a *fakeStakerAPI) WithdrawStake() []common.Hash {
+	return []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x02")}
+}
+
+func (a *fakeStakerAPI) RefundOldStakers(stakers []common.Address) []common.Hash {
+	a.refunded = stakers
+	return []common.Hash{common.HexToHash("0x03")}
+}
+
+func (a *fakeStakerAPI) AddToStake() []common.Hash {
+	return []common.Hash{}
+}
+
+func TestStake(t *testing.T) {
+	api := &fakeStakerAPI{}
+	server := rpc.NewServer()
+	if err := server.RegisterName("arbstaker", api); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	run := func(args ...string) string {
+		config, err := parseStakeConfig(append(args, "--node-url", httpServer.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var output bytes.Buffer
+		if err := stakeMain(context.Background(), config, &output); err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(output.String())
+	}
+
+	if output := run("withdraw"); output != common.HexToHash("0x01").Hex()+"\n"+common.HexToHash("0x02").Hex() {
+		t.Fatal("unexpected withdraw output", output)
+	}
+	staker := common.HexToAddress("0x5ca1e")
+	if output := run("refund", "--stakers", staker.Hex()); output != common.HexToHash("0x03").Hex() {
+		t.Fatal("unexpected refund output", output)
+	}
+	if len(api.refunded) != 1 || api.refunded[0] != staker {
+		t.Fatal("refunded unexpected stakers", api.refunded)
+	}
+	if output := run("add-to-stake"); output != "nothing to do" {
+		t.Fatal("unexpected add-to-stake output", output)
+	}
+
+	if _, err := parseStakeConfig([]string{"unstake"}); err == nil {
+		t.Fatal("expected an unknown operation to be rejected")
+	}
+	if _, err := parseStakeConfig([]string{"withdraw", "--stakers", staker.Hex()}); err == nil {
+		t.Fatal("expected stakers to be rejected outside of refund")
+	}
+	if _, err := parseStakeConfig([]string{"refund", "--stakers", "0xnotanaddress"}); err == nil {
+		t.Fatal("expected an invalid staker address to be rejected")
+	}
+}
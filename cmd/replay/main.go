@@ -119,6 +119,15 @@ func (dasReader *PreimageDASReader) ExpirationPolicy(ctx context.Context) (arbst
 	return arbstate.DiscardImmediately, nil
 }
 
+// CelestiaPreimageReader resolves Celestia batch data from the keccak preimage of its data hash,
+// recorded when the batch was read before validation
+type CelestiaPreimageReader struct {
+}
+
+func (r *CelestiaPreimageReader) Read(ctx context.Context, pointer *arbstate.CelestiaBlobPointer) ([]byte, error) {
+	return wavmio.ResolveTypedPreimage(arbutil.Keccak256PreimageType, pointer.DataHash)
+}
+
 type BlobPreimageReader struct {
 }
 
@@ -215,6 +224,7 @@ func main() {
 			daProviders = append(daProviders, arbstate.NewDAProviderDAS(dasReader))
 		}
 		daProviders = append(daProviders, arbstate.NewDAProviderBlobReader(&BlobPreimageReader{}))
+		daProviders = append(daProviders, arbstate.NewDAProviderCelestia(&CelestiaPreimageReader{}))
 		inboxMultiplexer := arbstate.NewInboxMultiplexer(backend, delayedMessagesRead, daProviders, keysetValidationMode)
 		ctx := context.Background()
 		message, err := inboxMultiplexer.Pop(ctx)
@@ -0,0 +1,278 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	gethnode "github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/cmd/chaininfo"
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+func main() {
+	args := os.Args
+	if len(args) < 2 {
+		fmt.Fprintf(os.Stderr, "Usage: rotate-key [whitelist|unwhitelist|drain|resume|status] ...\n")
+		os.Exit(1)
+	}
+	var err error
+	switch strings.ToLower(args[1]) {
+	case "whitelist":
+		err = printCalldata(args[2:], true)
+	case "unwhitelist":
+		err = printCalldata(args[2:], false)
+	case "drain":
+		err = drain(args[2:])
+	case "resume":
+		err = resume(args[2:])
+	case "status":
+		err = status(args[2:])
+	default:
+		err = fmt.Errorf("unknown command '%s', valid commands are 'whitelist', 'unwhitelist', 'drain', 'resume' and 'status'", args[1])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+type RotateKeyConfig struct {
+	Role           string        `koanf:"role"`
+	ParentChainURL string        `koanf:"parent-chain-url"`
+	NodeURL        string        `koanf:"node-url"`
+	NodeJWTSecret  string        `koanf:"node-jwtsecret"`
+	SequencerInbox string        `koanf:"sequencer-inbox"`
+	Rollup         string        `koanf:"rollup"`
+	OldKey         string        `koanf:"old-key"`
+	NewKey         string        `koanf:"new-key"`
+	Wait           bool          `koanf:"wait"`
+	PollInterval   time.Duration `koanf:"poll-interval"`
+}
+
+func parseConfig(command string, args []string) (*RotateKeyConfig, error) {
+	f := flag.NewFlagSet("rotate-key "+command, flag.ContinueOnError)
+	f.String("role", string(arbnode.KeyRotationBatchPoster), "which key to rotate, batch-poster or validator")
+	f.String("parent-chain-url", "", "URL of a parent chain node")
+	f.String("node-url", "", "URL of the batch poster node's authenticated RPC, which must expose the arbbatchposter API (see the node's auth options)")
+	f.String("node-jwtsecret", "", "path to the file with the JWT secret of the batch poster node's authenticated RPC")
+	f.String("sequencer-inbox", "", "address of the sequencer inbox contract")
+	f.String("rollup", "", "address of the rollup contract")
+	f.String("old-key", "", "address of the key being rotated out")
+	f.String("new-key", "", "address of the key being rotated in")
+	f.Bool("wait", false, "wait until the old key is drained and the new key can take over (draining doesn't survive a restart of the batch poster node, which has to be drained again)")
+	f.Duration("poll-interval", time.Second*10, "how often to check progress when waiting")
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config RotateKeyConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *RotateKeyConfig) deployInfo() *chaininfo.RollupAddresses {
+	return &chaininfo.RollupAddresses{
+		SequencerInbox: common.HexToAddress(c.SequencerInbox),
+		Rollup:         common.HexToAddress(c.Rollup),
+	}
+}
+
+// dialNode connects to the batch poster node's authenticated RPC, where the arbbatchposter API is served
+func dialNode(ctx context.Context, config *RotateKeyConfig) (*rpc.Client, error) {
+	if config.NodeJWTSecret == "" {
+		return rpc.DialContext(ctx, config.NodeURL)
+	}
+	jwt, err := signature.LoadSigningKey(config.NodeJWTSecret)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.DialOptions(ctx, config.NodeURL, rpc.WithHTTPAuth(gethnode.NewJWTAuth([32]byte(*jwt))))
+}
+
+func parseAddress(name string, value string) (common.Address, error) {
+	if !common.IsHexAddress(value) {
+		return common.Address{}, fmt.Errorf("invalid or missing %v address \"%v\"", name, value)
+	}
+	return common.HexToAddress(value), nil
+}
+
+// rotate-key whitelist|unwhitelist --role ... --new-key|--old-key ...
+// prints the calls the rollup owner needs to make
+func printCalldata(args []string, whitelisted bool) error {
+	config, err := parseConfig("whitelist", args)
+	if err != nil {
+		return err
+	}
+	role, err := arbnode.ParseKeyRotationRole(config.Role)
+	if err != nil {
+		return err
+	}
+	keyName, keyValue := "new-key", config.NewKey
+	if !whitelisted {
+		keyName, keyValue = "old-key", config.OldKey
+	}
+	key, err := parseAddress(keyName, keyValue)
+	if err != nil {
+		return err
+	}
+	target, calldata, executorCalldata, err := arbnode.KeyRotationCalldata(role, config.deployInfo(), key, whitelisted)
+	if err != nil {
+		return err
+	}
+	if target == (common.Address{}) {
+		return errors.New("the address of the contract to call must be specified with --sequencer-inbox or --rollup")
+	}
+	fmt.Printf("contract:                    %v\n", target)
+	fmt.Printf("calldata:                    %v\n", hexutil.Encode(calldata))
+	fmt.Printf("upgrade executor calldata:   %v\n", hexutil.Encode(executorCalldata))
+	return nil
+}
+
+// rotate-key drain --node-url ... [--wait ...]
+func drain(args []string) error {
+	config, err := parseConfig("drain", args)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	node, err := dialNode(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer node.Close()
+	if err := node.CallContext(ctx, nil, "arbbatchposter_drain"); err != nil {
+		return err
+	}
+	fmt.Println("batch poster is draining and won't post new batches until it's resumed or restarted")
+	if !config.Wait {
+		return nil
+	}
+	return waitUntilReady(ctx, config, node)
+}
+
+// rotate-key resume --node-url ...
+func resume(args []string) error {
+	config, err := parseConfig("resume", args)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	node, err := dialNode(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer node.Close()
+	if err := node.CallContext(ctx, nil, "arbbatchposter_resume"); err != nil {
+		return err
+	}
+	fmt.Println("batch poster resumed")
+	return nil
+}
+
+// rotate-key status --role ... --parent-chain-url ... --old-key ... --new-key ... [--node-url ...] [--wait ...]
+func status(args []string) error {
+	config, err := parseConfig("status", args)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	var node *rpc.Client
+	if config.NodeURL != "" {
+		node, err = dialNode(ctx, config)
+		if err != nil {
+			return err
+		}
+		defer node.Close()
+	}
+	if config.Wait {
+		return waitUntilReady(ctx, config, node)
+	}
+	_, err = printStatus(ctx, config, node)
+	return err
+}
+
+func waitUntilReady(ctx context.Context, config *RotateKeyConfig, node *rpc.Client) error {
+	for {
+		ready, err := printStatus(ctx, config, node)
+		if err != nil {
+			return err
+		}
+		if ready {
+			fmt.Println("the new key is ready to take over")
+			return nil
+		}
+		time.Sleep(config.PollInterval)
+	}
+}
+
+// printStatus returns whether the new key is ready to take over
+func printStatus(ctx context.Context, config *RotateKeyConfig, node *rpc.Client) (bool, error) {
+	ready := true
+	if node != nil {
+		var drainStatus arbnode.BatchPosterDrainStatus
+		if err := node.CallContext(ctx, &drainStatus, "arbbatchposter_drainStatus"); err != nil {
+			return false, err
+		}
+		if err := printJSON("batch poster", drainStatus); err != nil {
+			return false, err
+		}
+		ready = drainStatus.Drained
+	}
+	if config.ParentChainURL == "" {
+		return ready, nil
+	}
+	role, err := arbnode.ParseKeyRotationRole(config.Role)
+	if err != nil {
+		return false, err
+	}
+	oldKey, err := parseAddress("old-key", config.OldKey)
+	if err != nil {
+		return false, err
+	}
+	newKey, err := parseAddress("new-key", config.NewKey)
+	if err != nil {
+		return false, err
+	}
+	client, err := ethclient.DialContext(ctx, config.ParentChainURL)
+	if err != nil {
+		return false, err
+	}
+	defer client.Close()
+	rotationStatus, err := arbnode.CheckKeyRotation(ctx, client, role, config.deployInfo(), oldKey, newKey)
+	if err != nil {
+		return false, err
+	}
+	if err := printJSON("parent chain", rotationStatus); err != nil {
+		return false, err
+	}
+	return ready && rotationStatus.ReadyToSwitch(), nil
+}
+
+func printJSON(name string, value interface{}) error {
+	encoded, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%v: %v\n", name, string(encoded))
+	return nil
+}
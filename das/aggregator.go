@@ -264,7 +264,13 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 
 			select {
 			case <-ctx.Done():
-				break
+				// Store is still waiting for a result if the threshold wasn't reached in time
+				if !returned {
+					certDetailsChan <- certDetails{
+						err: fmt.Errorf("aggregator only stored message to %d out of the %d DASes required before %w. %w", successfullyStoredCount, a.requiredServicesForStore, ctx.Err(), BatchToDasFailed),
+					}
+				}
+				return
 			case r := <-responses:
 				if r.err != nil {
 					storeFailures++
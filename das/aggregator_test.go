@@ -288,3 +288,59 @@ func TestDAS_AtLeastHStorageFailures(t *testing.T) {
 		})
 	}
 }
+
+type alwaysFail struct {
+	failure failureType
+}
+
+func (f alwaysFail) shouldFail() failureType {
+	return f.failure
+}
+
+func TestDAS_AggregatorStoreCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	numBackendDAS := 4
+	var backends []ServiceDetails
+	for i := 0; i < numBackendDAS; i++ {
+		privKey, err := blsSignatures.GeneratePrivKeyString()
+		Require(t, err)
+		config := DataAvailabilityConfig{
+			Enable: true,
+			Key: KeyConfig{
+				PrivKey: privKey,
+			},
+			ParentChainNodeURL: "none",
+		}
+		das, err := NewSignAfterStoreDASWriter(ctx, config, NewMemoryBackedStorageService(ctx))
+		Require(t, err)
+		details, err := NewServiceDetails(&WrapStore{t, alwaysFail{tooSlow}, das}, *das.pubKey, uint64(1<<i), "service"+strconv.Itoa(i))
+		Require(t, err)
+		backends = append(backends, *details)
+	}
+	aggregator, err := NewAggregator(
+		ctx,
+		DataAvailabilityConfig{
+			RPCAggregator:      AggregatorConfig{AssumedHonest: 1},
+			ParentChainNodeURL: "none",
+			RequestTimeout:     time.Hour,
+		}, backends)
+	Require(t, err)
+
+	storeCtx, storeCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer storeCancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := aggregator.Store(storeCtx, []byte("It's time for you to see the fnords."), 0, []byte{})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, BatchToDasFailed) {
+			Fail(t, "expected store to fail with", BatchToDasFailed, "but got", err)
+		}
+	case <-time.After(10 * time.Second):
+		Fail(t, "store didn't return after its context was canceled")
+	}
+}
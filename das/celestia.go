@@ -0,0 +1,162 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbstate"
+)
+
+type CelestiaConfig struct {
+	URL            string        `koanf:"url"`
+	AuthToken      string        `koanf:"auth-token"`
+	NamespaceID    string        `koanf:"namespace-id"`
+	GasPrice       float64       `koanf:"gas-price"`
+	RequestTimeout time.Duration `koanf:"request-timeout"`
+}
+
+var DefaultCelestiaConfig = CelestiaConfig{
+	URL:            "",
+	AuthToken:      "",
+	NamespaceID:    "",
+	GasPrice:       -1,
+	RequestTimeout: time.Minute,
+}
+
+func CelestiaConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.String(prefix+".url", DefaultCelestiaConfig.URL, "RPC URL of the Celestia node batch data is posted to and read from")
+	f.String(prefix+".auth-token", DefaultCelestiaConfig.AuthToken, "auth token for the Celestia node's RPC; needs write permission to post batch data")
+	f.String(prefix+".namespace-id", DefaultCelestiaConfig.NamespaceID, "hex encoded ID of the version 0 Celestia namespace batch data is posted to, at most 10 bytes")
+	f.Float64(prefix+".gas-price", DefaultCelestiaConfig.GasPrice, "gas price in utia for posting batch data to Celestia (-1 = let the Celestia node estimate it)")
+	f.Duration(prefix+".request-timeout", DefaultCelestiaConfig.RequestTimeout, "timeout for each request to the Celestia node, including waiting for a posted blob to be included")
+}
+
+// The largest namespace ID of a version 0 namespace, which must be prefixed with zeroes
+const celestiaNamespaceV0IDSize = 10
+
+func (c *CelestiaConfig) namespace() ([arbstate.CelestiaNamespaceSize]byte, error) {
+	var namespace [arbstate.CelestiaNamespaceSize]byte
+	id, err := hex.DecodeString(strings.TrimPrefix(c.NamespaceID, "0x"))
+	if err != nil {
+		return namespace, fmt.Errorf("invalid celestia namespace-id: %w", err)
+	}
+	if len(id) == 0 || len(id) > celestiaNamespaceV0IDSize {
+		return namespace, fmt.Errorf("celestia namespace-id must be between 1 and %v bytes", celestiaNamespaceV0IDSize)
+	}
+	// The version byte, 0, and the ID's zero prefix come first
+	copy(namespace[arbstate.CelestiaNamespaceSize-len(id):], id)
+	return namespace, nil
+}
+
+// celestiaBlob is the JSON encoding of a blob used by the Celestia node's blob API
+type celestiaBlob struct {
+	Namespace    []byte `json:"namespace"`
+	Data         []byte `json:"data"`
+	ShareVersion uint32 `json:"share_version"`
+	Commitment   []byte `json:"commitment"`
+}
+
+// CelestiaDA posts batch data to a Celestia namespace through a Celestia node's RPC API, and reads it back.
+// The node verifies that the blobs it returns match their commitments against Celestia's data roots.
+type CelestiaDA struct {
+	config    CelestiaConfig
+	namespace [arbstate.CelestiaNamespaceSize]byte
+	client    *rpc.Client
+}
+
+func NewCelestiaDA(ctx context.Context, config CelestiaConfig) (*CelestiaDA, error) {
+	if config.URL == "" {
+		return nil, errors.New("celestia data availability mode requires a celestia url")
+	}
+	namespace, err := config.namespace()
+	if err != nil {
+		return nil, err
+	}
+	var opts []rpc.ClientOption
+	if config.AuthToken != "" {
+		opts = append(opts, rpc.WithHeader("Authorization", "Bearer "+config.AuthToken))
+	}
+	client, err := rpc.DialOptions(ctx, config.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to celestia node: %w", err)
+	}
+	return &CelestiaDA{
+		config:    config,
+		namespace: namespace,
+		client:    client,
+	}, nil
+}
+
+// Store posts message as a blob and returns the sequencer message pointing to it. Errors wrap
+// BatchToDasFailed so the batch poster can fall back to posting the batch data on chain.
+func (c *CelestiaDA) Store(ctx context.Context, message []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.RequestTimeout)
+	defer cancel()
+
+	blob := &celestiaBlob{
+		Namespace: c.namespace[:],
+		Data:      message,
+	}
+	var height uint64
+	if err := c.client.CallContext(ctx, &height, "blob.Submit", []*celestiaBlob{blob}, c.config.GasPrice); err != nil {
+		return nil, fmt.Errorf("%w: error submitting blob to celestia: %w", BatchToDasFailed, err)
+	}
+	// The commitment is computed from the blob's share layout, so it's simplest to get it back from the node
+	var included []*celestiaBlob
+	if err := c.client.CallContext(ctx, &included, "blob.GetAll", height, [][]byte{c.namespace[:]}); err != nil {
+		return nil, fmt.Errorf("%w: error getting blob commitment from celestia at height %v: %w", BatchToDasFailed, height, err)
+	}
+	var commitment []byte
+	for _, b := range included {
+		if bytes.Equal(b.Data, message) {
+			commitment = b.Commitment
+			break
+		}
+	}
+	if len(commitment) != len(common.Hash{}) {
+		return nil, fmt.Errorf("%w: posted blob not found in celestia block %v", BatchToDasFailed, height)
+	}
+	pointer := &arbstate.CelestiaBlobPointer{
+		BlockHeight: height,
+		Namespace:   c.namespace,
+		Commitment:  common.BytesToHash(commitment),
+		DataHash:    crypto.Keccak256Hash(message),
+	}
+	log.Info("Posted batch data to celestia", "height", height, "commitment", pointer.Commitment, "size", len(message))
+	return pointer.Serialize(), nil
+}
+
+func (c *CelestiaDA) Read(ctx context.Context, pointer *arbstate.CelestiaBlobPointer) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.RequestTimeout)
+	defer cancel()
+
+	var blob celestiaBlob
+	err := c.client.CallContext(ctx, &blob, "blob.Get", pointer.BlockHeight, pointer.Namespace[:], pointer.Commitment[:])
+	if err != nil {
+		return nil, err
+	}
+	return blob.Data, nil
+}
+
+func (c *CelestiaDA) Close(ctx context.Context) error {
+	c.client.Close()
+	return nil
+}
+
+func (c *CelestiaDA) String() string {
+	return fmt.Sprintf("CelestiaDA{url:%v, namespace:%v}", c.config.URL, hex.EncodeToString(c.namespace[:]))
+}
@@ -0,0 +1,184 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+// newTestCelestiaNode serves the subset of a Celestia node's blob API that CelestiaDA uses,
+// keeping every submitted blob in memory. If corrupt is set, blobs are served with altered data.
+func newTestCelestiaNode(t *testing.T, corrupt bool) *httptest.Server {
+	var mutex sync.Mutex
+	blocks := make(map[uint64][]*celestiaBlob)
+	var height uint64
+	// Handlers run outside the test goroutine, so can't stop the test
+	check := func(err error) {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error("invalid request", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		var result interface{}
+		switch req.Method {
+		case "blob.Submit":
+			var submitted []*celestiaBlob
+			check(json.Unmarshal(req.Params[0], &submitted))
+			height++
+			for _, b := range submitted {
+				b.Commitment = crypto.Keccak256(b.Namespace, b.Data)
+				if corrupt {
+					b.Data = append([]byte{}, b.Data...)
+					b.Data[0]++
+				}
+			}
+			blocks[height] = submitted
+			result = height
+		case "blob.GetAll":
+			var h uint64
+			check(json.Unmarshal(req.Params[0], &h))
+			result = blocks[h]
+		case "blob.Get":
+			var h uint64
+			var commitment []byte
+			check(json.Unmarshal(req.Params[0], &h))
+			check(json.Unmarshal(req.Params[2], &commitment))
+			for _, b := range blocks[h] {
+				if bytes.Equal(b.Commitment, commitment) {
+					result = b
+				}
+			}
+		default:
+			t.Error("unexpected method", req.Method)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if result == nil {
+			resp["error"] = map[string]interface{}{"code": 1, "message": "blob: not found"}
+		} else {
+			resp["result"] = result
+		}
+		check(json.NewEncoder(w).Encode(resp))
+	}))
+}
+
+func TestCelestiaDA(t *testing.T) {
+	ctx := context.Background()
+	server := newTestCelestiaNode(t, false)
+	defer server.Close()
+
+	config := DefaultCelestiaConfig
+	config.URL = server.URL
+	config.AuthToken = "token"
+	config.NamespaceID = "0x0123"
+	config.RequestTimeout = time.Second * 5
+	celestia, err := NewCelestiaDA(ctx, config)
+	testhelpers.RequireImpl(t, err)
+	defer func() { _ = celestia.Close(ctx) }()
+
+	data := testhelpers.RandomizeSlice(make([]byte, 1000))
+	msg, err := celestia.Store(ctx, data)
+	testhelpers.RequireImpl(t, err)
+	pointer, err := arbstate.DeserializeCelestiaBlobPointer(msg)
+	testhelpers.RequireImpl(t, err)
+	if pointer.BlockHeight != 1 || pointer.DataHash != crypto.Keccak256Hash(data) {
+		testhelpers.FailImpl(t, "unexpected pointer", pointer)
+	}
+	if pointer.Namespace[0] != 0 || !bytes.Equal(pointer.Namespace[arbstate.CelestiaNamespaceSize-2:], []byte{0x01, 0x23}) {
+		testhelpers.FailImpl(t, "unexpected namespace", common.Bytes2Hex(pointer.Namespace[:]))
+	}
+
+	// The sequencer inbox prefixes the batch data with a 40 byte header
+	sequencerMsg := append(make([]byte, 40), msg...)
+	provider := arbstate.NewDAProviderCelestia(celestia)
+	preimages := make(map[arbutil.PreimageType]map[common.Hash][]byte)
+	payload, err := provider.RecoverPayloadFromBatch(ctx, 0, common.Hash{}, sequencerMsg, preimages, arbstate.KeysetValidate)
+	testhelpers.RequireImpl(t, err)
+	if !bytes.Equal(payload, data) {
+		testhelpers.FailImpl(t, "read back different data from celestia")
+	}
+	if !bytes.Equal(preimages[arbutil.Keccak256PreimageType][pointer.DataHash], data) {
+		testhelpers.FailImpl(t, "batch data wasn't recorded as a preimage")
+	}
+
+	// A malformed pointer makes for an empty batch rather than an error
+	payload, err = provider.RecoverPayloadFromBatch(ctx, 0, common.Hash{}, sequencerMsg[:len(sequencerMsg)-1], nil, arbstate.KeysetValidate)
+	testhelpers.RequireImpl(t, err)
+	if payload != nil {
+		testhelpers.FailImpl(t, "expected no payload for a malformed pointer")
+	}
+}
+
+func TestCelestiaDARejectsCorruptData(t *testing.T) {
+	ctx := context.Background()
+	server := newTestCelestiaNode(t, true)
+	defer server.Close()
+
+	config := DefaultCelestiaConfig
+	config.URL = server.URL
+	config.AuthToken = "token"
+	config.NamespaceID = "0x0123"
+	celestia, err := NewCelestiaDA(ctx, config)
+	testhelpers.RequireImpl(t, err)
+	defer func() { _ = celestia.Close(ctx) }()
+
+	// The corrupted blob can't be found by its data to get its commitment
+	data := testhelpers.RandomizeSlice(make([]byte, 1000))
+	_, err = celestia.Store(ctx, data)
+	if !errors.Is(err, BatchToDasFailed) {
+		testhelpers.FailImpl(t, "expected store to fail with", BatchToDasFailed, "but got", err)
+	}
+
+	pointer := &arbstate.CelestiaBlobPointer{
+		BlockHeight: 1,
+		Namespace:   celestia.namespace,
+		Commitment:  common.BytesToHash(crypto.Keccak256(celestia.namespace[:], data)),
+		DataHash:    crypto.Keccak256Hash(data),
+	}
+	sequencerMsg := append(make([]byte, 40), pointer.Serialize()...)
+	_, err = arbstate.NewDAProviderCelestia(celestia).RecoverPayloadFromBatch(ctx, 0, common.Hash{}, sequencerMsg, nil, arbstate.KeysetValidate)
+	if !errors.Is(err, arbstate.ErrHashMismatch) {
+		testhelpers.FailImpl(t, "expected read to fail with", arbstate.ErrHashMismatch, "but got", err)
+	}
+}
+
+func TestCelestiaNamespace(t *testing.T) {
+	for _, id := range []string{"", "0xzz", "0x0102030405060708090a0b"} {
+		config := CelestiaConfig{NamespaceID: id}
+		if _, err := config.namespace(); err == nil {
+			testhelpers.FailImpl(t, "expected invalid namespace-id to be rejected", id)
+		}
+	}
+}
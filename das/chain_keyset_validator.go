@@ -0,0 +1,74 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
+	"github.com/offchainlabs/nitro/util/pretty"
+)
+
+// ChainKeysetValidator checks keyset hashes against the SequencerInbox. A keyset's creation block is kept
+// when it's invalidated, so a keyset that was ever registered stays registered and can be cached.
+type ChainKeysetValidator struct {
+	keysetCreationBlock func(ctx context.Context, hash common.Hash) (uint64, error)
+
+	mutex      sync.RWMutex
+	registered map[common.Hash]struct{}
+}
+
+func NewChainKeysetValidator(l1client arbutil.L1Interface, seqInboxAddr common.Address) (*ChainKeysetValidator, error) {
+	seqInboxCaller, err := bridgegen.NewSequencerInboxCaller(seqInboxAddr, l1client)
+	if err != nil {
+		return nil, err
+	}
+	return newChainKeysetValidator(func(ctx context.Context, hash common.Hash) (uint64, error) {
+		info, err := seqInboxCaller.DasKeySetInfo(&bind.CallOpts{Context: ctx}, hash)
+		if err != nil {
+			return 0, err
+		}
+		return info.CreationBlock, nil
+	}), nil
+}
+
+func newChainKeysetValidator(keysetCreationBlock func(ctx context.Context, hash common.Hash) (uint64, error)) *ChainKeysetValidator {
+	return &ChainKeysetValidator{
+		keysetCreationBlock: keysetCreationBlock,
+		registered:          make(map[common.Hash]struct{}),
+	}
+}
+
+func (v *ChainKeysetValidator) IsKeysetHashRegistered(ctx context.Context, keysetHash common.Hash) (bool, error) {
+	v.mutex.RLock()
+	_, ok := v.registered[keysetHash]
+	v.mutex.RUnlock()
+	if ok {
+		return true, nil
+	}
+
+	creationBlock, err := v.keysetCreationBlock(ctx, keysetHash)
+	if err != nil {
+		return false, err
+	}
+	if creationBlock == 0 {
+		log.Warn("keyset hash not registered in the SequencerInbox", "keysetHash", pretty.PrettyHash(keysetHash))
+		return false, nil
+	}
+
+	v.mutex.Lock()
+	v.registered[keysetHash] = struct{}{}
+	v.mutex.Unlock()
+	return true, nil
+}
+
+func (v *ChainKeysetValidator) String() string {
+	return "ChainKeysetValidator"
+}
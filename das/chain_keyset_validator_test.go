@@ -0,0 +1,113 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/blsSignatures"
+	"github.com/offchainlabs/nitro/das/dastree"
+)
+
+func TestKeysetVerificationInReadPath(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var pubKeys []blsSignatures.PublicKey
+	var privKeys []blsSignatures.PrivateKey
+	for i := 0; i < 2; i++ {
+		pubKey, privKey, err := blsSignatures.GenerateKeys()
+		Require(t, err)
+		pubKeys = append(pubKeys, pubKey)
+		privKeys = append(privKeys, privKey)
+	}
+	keyset := &arbstate.DataAvailabilityKeyset{
+		AssumedHonest: 1,
+		PubKeys:       pubKeys,
+	}
+	keysetBuf := bytes.NewBuffer([]byte{})
+	Require(t, keyset.Serialize(keysetBuf))
+	keysetBytes := keysetBuf.Bytes()
+	keysetHash, err := keyset.Hash()
+	Require(t, err)
+
+	decoded, err := arbstate.DeserializeKeyset(bytes.NewReader(keysetBytes), false)
+	Require(t, err)
+	decodedHash, err := decoded.Hash()
+	Require(t, err)
+	if decodedHash != keysetHash || decoded.AssumedHonest != keyset.AssumedHonest || len(decoded.PubKeys) != len(pubKeys) {
+		t.Fatal("keyset changed after decoding")
+	}
+
+	storage := NewMemoryBackedStorageService(ctx)
+	payload := []byte("the batch data")
+	Require(t, storage.Put(ctx, keysetBytes, math.MaxUint64))
+	Require(t, storage.Put(ctx, payload, math.MaxUint64))
+
+	sequencerMsg := func(signers uint64) []byte {
+		cert := &arbstate.DataAvailabilityCertificate{
+			KeysetHash:  keysetHash,
+			DataHash:    dastree.Hash(payload),
+			Timeout:     math.MaxUint64,
+			SignersMask: signers,
+			Version:     1,
+		}
+		var sigs []blsSignatures.Signature
+		for i, privKey := range privKeys {
+			if signers&(1<<i) != 0 {
+				sig, err := blsSignatures.SignMessage(privKey, cert.SerializeSignableFields())
+				Require(t, err)
+				sigs = append(sigs, sig)
+			}
+		}
+		cert.Sig = blsSignatures.AggregateSignatures(sigs)
+		return append(make([]byte, 40), Serialize(cert)...)
+	}
+
+	lookups := 0
+	registered := map[common.Hash]bool{keysetHash: true}
+	validator := newChainKeysetValidator(func(ctx context.Context, hash common.Hash) (uint64, error) {
+		lookups++
+		if registered[hash] {
+			return 100, nil
+		}
+		return 0, nil
+	})
+	provider := arbstate.NewDAProviderDASWithKeysetValidator(storage, validator)
+
+	for i := 0; i < 2; i++ {
+		recovered, err := provider.RecoverPayloadFromBatch(ctx, 1, common.Hash{}, sequencerMsg(3), nil, arbstate.KeysetValidate)
+		Require(t, err)
+		if !bytes.Equal(recovered, payload) {
+			t.Fatal("recovered wrong payload", recovered)
+		}
+	}
+	if lookups != 1 {
+		t.Fatal("expected the registered keyset to be looked up once but was looked up", lookups, "times")
+	}
+
+	// A certificate missing a signer doesn't have enough signatures for the keyset
+	recovered, err := provider.RecoverPayloadFromBatch(ctx, 1, common.Hash{}, sequencerMsg(1), nil, arbstate.KeysetValidate)
+	Require(t, err)
+	if recovered != nil {
+		t.Fatal("recovered payload from certificate without enough signers")
+	}
+
+	// A keyset that was never registered isn't trusted, even with valid signatures
+	validator = newChainKeysetValidator(func(ctx context.Context, hash common.Hash) (uint64, error) {
+		return 0, nil
+	})
+	provider = arbstate.NewDAProviderDASWithKeysetValidator(storage, validator)
+	_, err = provider.RecoverPayloadFromBatch(ctx, 1, common.Hash{}, sequencerMsg(3), nil, arbstate.KeysetValidate)
+	if !errors.Is(err, arbstate.ErrUnregisteredKeyset) {
+		t.Fatal("expected unregistered keyset error but got", err)
+	}
+}
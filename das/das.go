@@ -26,6 +26,13 @@ type DataAvailabilityServiceWriter interface {
 	fmt.Stringer
 }
 
+// DAProviderWriter stores batch data with a data availability layer other than an AnyTrust committee,
+// returning the sequencer message that points to it.
+type DAProviderWriter interface {
+	Store(ctx context.Context, message []byte) ([]byte, error)
+	fmt.Stringer
+}
+
 type DataAvailabilityServiceReader interface {
 	arbstate.DataAvailabilityReader
 	fmt.Stringer
@@ -35,24 +42,36 @@ type DataAvailabilityServiceHealthChecker interface {
 	HealthCheck(ctx context.Context) error
 }
 
+const (
+	DataAvailabilityModeAnyTrust = "anytrust"
+	DataAvailabilityModeCelestia = "celestia"
+)
+
 type DataAvailabilityConfig struct {
-	Enable bool `koanf:"enable"`
+	Enable bool   `koanf:"enable"`
+	Mode   string `koanf:"mode"`
 
 	RequestTimeout time.Duration `koanf:"request-timeout"`
 
-	LocalCache CacheConfig `koanf:"local-cache"`
-	RedisCache RedisConfig `koanf:"redis-cache"`
+	LocalCache CacheConfig     `koanf:"local-cache"`
+	RedisCache RedisConfig     `koanf:"redis-cache"`
+	ReadCache  ReadCacheConfig `koanf:"read-cache"`
 
 	LocalDBStorage     LocalDBStorageConfig     `koanf:"local-db-storage"`
 	LocalFileStorage   LocalFileStorageConfig   `koanf:"local-file-storage"`
+	Retention          RetentionConfig          `koanf:"retention"`
 	S3Storage          S3StorageServiceConfig   `koanf:"s3-storage"`
 	IpfsStorage        IpfsStorageServiceConfig `koanf:"ipfs-storage"`
+	IpfsGateway        IpfsGatewayConfig        `koanf:"ipfs-gateway"`
 	RegularSyncStorage RegularSyncStorageConfig `koanf:"regular-sync-storage"`
 
 	Key KeyConfig `koanf:"key"`
 
 	RPCAggregator  AggregatorConfig              `koanf:"rpc-aggregator"`
 	RestAggregator RestfulClientAggregatorConfig `koanf:"rest-aggregator"`
+	RestServer     RestfulDasServerConfig        `koanf:"rest-server"`
+
+	Celestia CelestiaConfig `koanf:"celestia"`
 
 	ParentChainNodeURL              string `koanf:"parent-chain-node-url"`
 	ParentChainConnectionAttempts   int    `koanf:"parent-chain-connection-attempts"`
@@ -61,15 +80,29 @@ type DataAvailabilityConfig struct {
 
 	PanicOnError             bool `koanf:"panic-on-error"`
 	DisableSignatureChecking bool `koanf:"disable-signature-checking"`
+	VerifyKeysetsOnChain     bool `koanf:"verify-keysets-on-chain"`
 }
 
 var DefaultDataAvailabilityConfig = DataAvailabilityConfig{
 	RequestTimeout:                5 * time.Second,
 	Enable:                        false,
+	Mode:                          DataAvailabilityModeAnyTrust,
 	RestAggregator:                DefaultRestfulClientAggregatorConfig,
 	ParentChainConnectionAttempts: 15,
 	PanicOnError:                  false,
 	IpfsStorage:                   DefaultIpfsStorageServiceConfig,
+	IpfsGateway:                   DefaultIpfsGatewayConfig,
+	RestServer:                    DefaultRestfulDasServerConfig,
+	Retention:                     DefaultRetentionConfig,
+	Celestia:                      DefaultCelestiaConfig,
+	ReadCache:                     DefaultReadCacheConfig,
+}
+
+func (c *DataAvailabilityConfig) Validate() error {
+	if c.Mode != DataAvailabilityModeAnyTrust && c.Mode != DataAvailabilityModeCelestia {
+		return fmt.Errorf("invalid data availability mode %v, must be %v or %v", c.Mode, DataAvailabilityModeAnyTrust, DataAvailabilityModeCelestia)
+	}
+	return nil
 }
 
 func OptionalAddressFromString(s string) (*common.Address, error) {
@@ -114,7 +147,6 @@ func dataAvailabilityConfigAddOptions(prefix string, f *flag.FlagSet, r role) {
 
 		// Storage options
 		LocalDBStorageConfigAddOptions(prefix+".local-db-storage", f)
-		LocalFileStorageConfigAddOptions(prefix+".local-file-storage", f)
 		S3ConfigAddOptions(prefix+".s3-storage", f)
 		RegularSyncStorageConfigAddOptions(prefix+".regular-sync-storage", f)
 
@@ -124,12 +156,22 @@ func dataAvailabilityConfigAddOptions(prefix string, f *flag.FlagSet, r role) {
 		f.String(prefix+".extra-signature-checking-public-key", DefaultDataAvailabilityConfig.ExtraSignatureCheckingPublicKey, "public key to use to validate Data Availability Store requests in addition to the Sequencer's public key determined using sequencer-inbox-address, can be a file or the hex-encoded public key beginning with 0x; useful for testing")
 	}
 	if r == roleNode {
+		f.String(prefix+".mode", DefaultDataAvailabilityConfig.Mode, "data availability layer batch data is posted to and read from, either "+DataAvailabilityModeAnyTrust+" (a committee of DA servers) or "+DataAvailabilityModeCelestia)
+		CelestiaConfigAddOptions(prefix+".celestia", f)
+		ReadCacheConfigAddOptions(prefix+".read-cache", f)
+		IpfsGatewayConfigAddOptions(prefix+".ipfs-gateway", f)
+		RestfulDasServerConfigAddOptions(prefix+".rest-server", f)
+		f.Bool(prefix+".verify-keysets-on-chain", DefaultDataAvailabilityConfig.VerifyKeysetsOnChain, "check that the keyset of each DAS certificate read was registered in the parent chain's SequencerInbox before trusting its signatures")
+
 		// These are only for batch poster
 		AggregatorConfigAddOptions(prefix+".rpc-aggregator", f)
 		f.Duration(prefix+".request-timeout", DefaultDataAvailabilityConfig.RequestTimeout, "Data Availability Service timeout duration for Store requests")
 	}
 
 	// Both the Nitro node and daserver can use these options.
+	// A Nitro node uses local file storage as a read-through cache in front of the REST aggregator.
+	LocalFileStorageConfigAddOptions(prefix+".local-file-storage", f)
+	RetentionConfigAddOptions(prefix+".retention", f)
 	IpfsStorageServiceConfigAddOptions(prefix+".ipfs-storage", f)
 	RestfulClientAggregatorConfigAddOptions(prefix+".rest-aggregator", f)
 
@@ -24,6 +24,18 @@ func CreatePersistentStorageService(
 	config *DataAvailabilityConfig,
 	syncFromStorageServices *[]*IterableStorageService,
 	syncToStorageServices *[]StorageService,
+) (StorageService, *LifecycleManager, error) {
+	return createPersistentStorageService(ctx, config, syncFromStorageServices, syncToStorageServices, nil, nil)
+}
+
+// createPersistentStorageService also needs the parent chain for retention that waits for batches to be posted
+func createPersistentStorageService(
+	ctx context.Context,
+	config *DataAvailabilityConfig,
+	syncFromStorageServices *[]*IterableStorageService,
+	syncToStorageServices *[]StorageService,
+	l1Reader *headerreader.HeaderReader,
+	seqInboxAddress *common.Address,
 ) (StorageService, *LifecycleManager, error) {
 	storageServices := make([]StorageService, 0, 10)
 	var lifecycleManager LifecycleManager
@@ -45,10 +57,19 @@ func CreatePersistentStorageService(
 	}
 
 	if config.LocalFileStorage.Enable {
-		s, err := NewLocalFileStorageService(config.LocalFileStorage.DataDir)
+		fs, err := NewLocalFileStorageServiceWithConfig(&config.LocalFileStorage)
 		if err != nil {
 			return nil, nil, err
 		}
+		if config.Retention.Enable {
+			retention, err := NewStorageRetention(&config.Retention, fs, l1Reader, seqInboxAddress)
+			if err != nil {
+				return nil, nil, err
+			}
+			retention.Start(ctx)
+			lifecycleManager.Register(retention)
+		}
+		var s StorageService = fs
 		if config.LocalFileStorage.SyncFromStorageService {
 			iterableStorageService := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(s))
 			*syncFromStorageServices = append(*syncFromStorageServices, iterableStorageService)
@@ -207,7 +228,7 @@ func CreateDAComponentsForDaserver(
 
 	var syncFromStorageServices []*IterableStorageService
 	var syncToStorageServices []StorageService
-	storageService, dasLifecycleManager, err := CreatePersistentStorageService(ctx, config, &syncFromStorageServices, &syncToStorageServices)
+	storageService, dasLifecycleManager, err := createPersistentStorageService(ctx, config, &syncFromStorageServices, &syncToStorageServices, l1Reader, seqInboxAddress)
 	if err != nil {
 		return nil, nil, nil, nil, err
 	}
@@ -323,8 +344,12 @@ func CreateDAReaderForNode(
 		return nil, nil, errors.New("node.data-availability.rpc-aggregator is only for Batch Poster mode")
 	}
 
-	if !config.RestAggregator.Enable && !config.IpfsStorage.Enable {
-		return nil, nil, fmt.Errorf("--node.data-availability.enable was set but neither of --node.data-availability.(rest-aggregator|ipfs-storage) were enabled. When running a Nitro Anytrust node in non-Batch Poster mode, some way to get the batch data is required.")
+	if !config.RestAggregator.Enable && !config.IpfsStorage.Enable && !config.LocalFileStorage.Enable && !config.IpfsGateway.Enable {
+		return nil, nil, fmt.Errorf("--node.data-availability.enable was set but none of --node.data-availability.(rest-aggregator|ipfs-storage|local-file-storage|ipfs-gateway) were enabled. When running a Nitro Anytrust node in non-Batch Poster mode, some way to get the batch data is required.")
+	}
+
+	if config.LocalFileStorage.SyncFromStorageService || config.LocalFileStorage.SyncToStorageService {
+		return nil, nil, errors.New("--node.data-availability.local-file-storage.sync-(from|to)-storage-service can only be used with a daserver")
 	}
 
 	if config.RestAggregator.SyncToStorage.Eager {
@@ -332,7 +357,7 @@ func CreateDAReaderForNode(
 	}
 	// Done checking config requirements
 
-	storageService, dasLifecycleManager, err := CreatePersistentStorageService(ctx, config, nil, nil)
+	storageService, dasLifecycleManager, err := createPersistentStorageService(ctx, config, nil, nil, l1Reader, seqInboxAddress)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -356,7 +381,7 @@ func CreateDAReaderForNode(
 				retentionPeriodSeconds = uint64(syncConf.RetentionPeriod.Seconds())
 			}
 
-			// This falls back to REST and updates the local IPFS repo if the data is found.
+			// This falls back to REST and updates the local IPFS repo or files if the data is found.
 			storageService = NewFallbackStorageService(storageService, restAgg, restAgg,
 				retentionPeriodSeconds, syncConf.IgnoreWriteErrors, true)
 			dasLifecycleManager.Register(storageService)
@@ -365,6 +390,20 @@ func CreateDAReaderForNode(
 		} else {
 			daReader = restAgg
 		}
+	} else if storageService != nil {
+		daReader = storageService
+	}
+
+	if config.IpfsGateway.Enable {
+		gatewayReader, err := NewIpfsGatewayReader(config.IpfsGateway)
+		if err != nil {
+			return nil, nil, err
+		}
+		if daReader == nil {
+			daReader = gatewayReader
+		} else {
+			daReader = &readerWithFallback{primary: daReader, backup: gatewayReader}
+		}
 	}
 
 	if seqInboxAddress != nil {
@@ -378,5 +417,12 @@ func CreateDAReaderForNode(
 		}
 	}
 
+	if config.ReadCache.Enable {
+		daReader, err = NewReadCache(config.ReadCache, daReader)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	return daReader, dasLifecycleManager, nil
 }
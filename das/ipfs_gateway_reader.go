@@ -0,0 +1,182 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/das/dastree"
+	"github.com/offchainlabs/nitro/util/pretty"
+	flag "github.com/spf13/pflag"
+)
+
+type IpfsGatewayConfig struct {
+	Enable         bool          `koanf:"enable"`
+	URLs           []string      `koanf:"urls"`
+	RequestTimeout time.Duration `koanf:"request-timeout"`
+	ReadTimeout    time.Duration `koanf:"read-timeout"`
+}
+
+var DefaultIpfsGatewayConfig = IpfsGatewayConfig{
+	Enable:         false,
+	URLs:           []string{},
+	RequestTimeout: 10 * time.Second,
+	ReadTimeout:    time.Minute,
+}
+
+func IpfsGatewayConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultIpfsGatewayConfig.Enable, "enable retrieval of sequencer batch data from IPFS HTTP gateways, when other sources don't have it")
+	f.StringSlice(prefix+".urls", DefaultIpfsGatewayConfig.URLs, "list of IPFS HTTP gateway URLs to try in order, eg https://ipfs.io")
+	f.Duration(prefix+".request-timeout", DefaultIpfsGatewayConfig.RequestTimeout, "timeout for each request to a gateway, after which the next gateway is tried")
+	f.Duration(prefix+".read-timeout", DefaultIpfsGatewayConfig.ReadTimeout, "timeout for retrieving all of a batch's data from the gateways. Treat timeout as not found")
+}
+
+// maxIpfsGatewayBlockSize bounds the size of a block read from a gateway. dastree nodes and chunks are
+// at most 64 kB, but batches stored under old-style flat hashes are a single larger block.
+const maxIpfsGatewayBlockSize = 16 * 1024 * 1024
+
+// IpfsGatewayReader retrieves batch data stored in IPFS by the IpfsStorageService through HTTP gateways,
+// without running an IPFS node. Each block is addressed by a CID derived from the keccak256 hash of its
+// content, and is checked against that hash, so a gateway can withhold data but can't forge it.
+type IpfsGatewayReader struct {
+	config IpfsGatewayConfig
+	client *http.Client
+}
+
+func NewIpfsGatewayReader(config IpfsGatewayConfig) (*IpfsGatewayReader, error) {
+	if len(config.URLs) == 0 {
+		return nil, errors.New("IPFS gateway retrieval requires at least one gateway URL")
+	}
+	for _, gateway := range config.URLs {
+		if _, err := url.ParseRequestURI(gateway); err != nil {
+			return nil, fmt.Errorf("invalid IPFS gateway URL %v: %w", gateway, err)
+		}
+	}
+	return &IpfsGatewayReader{
+		config: config,
+		client: &http.Client{},
+	}, nil
+}
+
+func (r *IpfsGatewayReader) GetByHash(ctx context.Context, hash common.Hash) ([]byte, error) {
+	log.Trace("das.IpfsGatewayReader.GetByHash", "hash", pretty.PrettyHash(hash))
+	ctx, cancel := context.WithTimeout(ctx, r.config.ReadTimeout)
+	defer cancel()
+	data, err := dastree.Content(hash, func(h common.Hash) ([]byte, error) {
+		return r.getBlock(ctx, h)
+	})
+	if err != nil && ctx.Err() != nil {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// getBlock tries each gateway in turn for the block with the given keccak256 hash
+func (r *IpfsGatewayReader) getBlock(ctx context.Context, hash common.Hash) ([]byte, error) {
+	blockCid, err := hashToCid(hash)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, gateway := range r.config.URLs {
+		data, err := r.getBlockFromGateway(ctx, gateway, blockCid.String())
+		if err == nil && crypto.Keccak256Hash(data) != hash {
+			err = fmt.Errorf("gateway returned data with hash %v", crypto.Keccak256Hash(data))
+		}
+		if err == nil {
+			return data, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Debug("failed to get IPFS block from gateway", "gateway", gateway, "cid", blockCid, "err", err)
+		errs = append(errs, fmt.Errorf("%v: %w", gateway, err))
+	}
+	if len(errs) == len(r.config.URLs) && allNotFound(errs) {
+		return nil, ErrNotFound
+	}
+	return nil, errors.Join(errs...)
+}
+
+func allNotFound(errs []error) bool {
+	for _, err := range errs {
+		if !errors.Is(err, ErrNotFound) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *IpfsGatewayReader) getBlockFromGateway(ctx context.Context, gateway string, blockCid string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.config.RequestTimeout)
+	defer cancel()
+	// Ask for the raw block, rather than letting the gateway interpret it
+	blockURL := strings.TrimSuffix(gateway, "/") + "/ipfs/" + blockCid + "?format=raw"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, blockURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.ipld.raw")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gateway returned status %v", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIpfsGatewayBlockSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxIpfsGatewayBlockSize {
+		return nil, fmt.Errorf("gateway returned a block larger than %v bytes", maxIpfsGatewayBlockSize)
+	}
+	return data, nil
+}
+
+func (r *IpfsGatewayReader) ExpirationPolicy(ctx context.Context) (arbstate.ExpirationPolicy, error) {
+	return arbstate.KeepForever, nil
+}
+
+func (r *IpfsGatewayReader) String() string {
+	return fmt.Sprintf("IpfsGatewayReader(%v)", strings.Join(r.config.URLs, ","))
+}
+
+// readerWithFallback reads from the backup when the primary reader fails
+type readerWithFallback struct {
+	primary DataAvailabilityServiceReader
+	backup  DataAvailabilityServiceReader
+}
+
+func (r *readerWithFallback) GetByHash(ctx context.Context, hash common.Hash) ([]byte, error) {
+	data, err := r.primary.GetByHash(ctx, hash)
+	if err == nil {
+		return data, nil
+	}
+	log.Debug("das.readerWithFallback: trying backup", "hash", pretty.PrettyHash(hash), "primary", r.primary, "err", err)
+	return r.backup.GetByHash(ctx, hash)
+}
+
+func (r *readerWithFallback) ExpirationPolicy(ctx context.Context) (arbstate.ExpirationPolicy, error) {
+	return r.primary.ExpirationPolicy(ctx)
+}
+
+func (r *readerWithFallback) String() string {
+	return fmt.Sprintf("readerWithFallback(%v, %v)", r.primary, r.backup)
+}
@@ -0,0 +1,106 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/offchainlabs/nitro/das/dastree"
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+func newTestIpfsGateway(t *testing.T, blocks map[common.Hash][]byte, corrupt bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		blockCid, err := cid.Decode(strings.TrimPrefix(r.URL.Path, "/ipfs/"))
+		if err != nil {
+			t.Error("invalid CID requested", r.URL, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		decoded, err := multihash.Decode(blockCid.Hash())
+		if err != nil || decoded.Code != multihash.KECCAK_256 || r.URL.Query().Get("format") != "raw" {
+			t.Error("unexpected block request", r.URL, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		block, ok := blocks[common.BytesToHash(decoded.Digest)]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if corrupt {
+			block = append([]byte{}, block...)
+			block[len(block)-1]++
+		}
+		_, _ = w.Write(block)
+	}))
+}
+
+func TestIpfsGatewayReader(t *testing.T) {
+	ctx := context.Background()
+	data := testhelpers.RandomizeSlice(make([]byte, 200000))
+	blocks := make(map[common.Hash][]byte)
+	root := dastree.RecordHash(func(hash common.Hash, preimage []byte) {
+		blocks[hash] = preimage
+	}, data)
+
+	empty := newTestIpfsGateway(t, map[common.Hash][]byte{}, false)
+	defer empty.Close()
+	corrupt := newTestIpfsGateway(t, blocks, true)
+	defer corrupt.Close()
+	good := newTestIpfsGateway(t, blocks, false)
+	defer good.Close()
+
+	config := DefaultIpfsGatewayConfig
+	config.Enable = true
+	config.URLs = []string{empty.URL, corrupt.URL + "/", good.URL}
+	reader, err := NewIpfsGatewayReader(config)
+	Require(t, err)
+	res, err := reader.GetByHash(ctx, root)
+	Require(t, err)
+	if !bytes.Equal(res, data) {
+		t.Fatal("retrieved data doesn't match what was stored")
+	}
+
+	// Forged data is never returned, even if no other gateway has the block
+	config.URLs = []string{empty.URL, corrupt.URL}
+	reader, err = NewIpfsGatewayReader(config)
+	Require(t, err)
+	if _, err := reader.GetByHash(ctx, root); err == nil {
+		t.Fatal("expected forged data to be rejected")
+	}
+
+	config.URLs = []string{empty.URL}
+	reader, err = NewIpfsGatewayReader(config)
+	Require(t, err)
+	if _, err := reader.GetByHash(ctx, root); !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected missing data to be not found but got", err)
+	}
+
+	// Timing out is treated as not found
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+	config.URLs = []string{slow.URL}
+	config.ReadTimeout = 50 * time.Millisecond
+	reader, err = NewIpfsGatewayReader(config)
+	Require(t, err)
+	if _, err := reader.GetByHash(ctx, root); !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected timeout to be not found but got", err)
+	}
+}
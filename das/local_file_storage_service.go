@@ -10,6 +10,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -24,30 +27,214 @@ import (
 type LocalFileStorageConfig struct {
 	Enable                 bool   `koanf:"enable"`
 	DataDir                string `koanf:"data-dir"`
+	Fsync                  bool   `koanf:"fsync"`
 	SyncFromStorageService bool   `koanf:"sync-from-storage-service"`
 	SyncToStorageService   bool   `koanf:"sync-to-storage-service"`
 }
 
 var DefaultLocalFileStorageConfig = LocalFileStorageConfig{
 	DataDir: "",
+	Fsync:   true,
 }
 
 func LocalFileStorageConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultLocalFileStorageConfig.Enable, "enable storage/retrieval of sequencer batch data from a directory of files, one per batch")
 	f.String(prefix+".data-dir", DefaultLocalFileStorageConfig.DataDir, "local data directory")
+	f.Bool(prefix+".fsync", DefaultLocalFileStorageConfig.Fsync, "fsync each batch file and the data directory before acknowledging a store, so stored data survives a crash")
 	f.Bool(prefix+".sync-from-storage-service", DefaultLocalFileStorageConfig.SyncFromStorageService, "enable local storage to be used as a source for regular sync storage")
 	f.Bool(prefix+".sync-to-storage-service", DefaultLocalFileStorageConfig.SyncToStorageService, "enable local storage to be used as a sink for regular sync storage")
 }
 
+// localFileStorageIndexName is an append-only list of the keys stored in the data directory, one per line.
+// Removed keys are appended again with localFileStorageRemovedPrefix. It isn't a valid key, so it can't
+// collide with the files holding data.
+const localFileStorageIndexName = "index"
+const localFileStorageRemovedPrefix = "-"
+
 type LocalFileStorageService struct {
 	dataDir string
+	fsync   bool
+
+	// indexMutex guards index and indexFile, and orders appends to the index
+	indexMutex sync.Mutex
+	index      map[common.Hash]struct{}
+	indexFile  *os.File
 }
 
 func NewLocalFileStorageService(dataDir string) (StorageService, error) {
+	return NewLocalFileStorageServiceWithConfig(&LocalFileStorageConfig{DataDir: dataDir, Fsync: DefaultLocalFileStorageConfig.Fsync})
+}
+
+func NewLocalFileStorageServiceWithConfig(config *LocalFileStorageConfig) (*LocalFileStorageService, error) {
+	dataDir := config.DataDir
 	if unix.Access(dataDir, unix.W_OK|unix.R_OK) != nil {
 		return nil, fmt.Errorf("couldn't start LocalFileStorageService, directory '%s' must be readable and writeable", dataDir)
 	}
-	return &LocalFileStorageService{dataDir: dataDir}, nil
+	s := &LocalFileStorageService{
+		dataDir: dataDir,
+		fsync:   config.Fsync,
+		index:   make(map[common.Hash]struct{}),
+	}
+	if err := s.openIndex(); err != nil {
+		return nil, fmt.Errorf("couldn't open LocalFileStorageService index in '%s': %w", dataDir, err)
+	}
+	return s, nil
+}
+
+// openIndex loads the index, or builds it from the data directory if it doesn't exist yet,
+// such as for data directories written before the index was added.
+func (s *LocalFileStorageService) openIndex() error {
+	indexPath := filepath.Join(s.dataDir, localFileStorageIndexName)
+	data, err := os.ReadFile(indexPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return s.rebuildIndex()
+	}
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(data), "\n")
+	removals := 0
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		removed := strings.HasPrefix(line, localFileStorageRemovedPrefix)
+		key, err := decodeLocalFileStorageName(strings.TrimPrefix(line, localFileStorageRemovedPrefix))
+		if err != nil {
+			if i == len(lines)-1 {
+				// the last append was interrupted, and will be rewritten by the next Put or Remove of that key
+				log.Warn("ignoring truncated LocalFileStorageService index entry", "entry", line)
+				continue
+			}
+			return fmt.Errorf("invalid index entry %v: %w", line, err)
+		}
+		if removed {
+			delete(s.index, key)
+			removals++
+		} else {
+			s.index[key] = struct{}{}
+		}
+	}
+	if removals > 0 {
+		// drop the removed keys, so the index doesn't grow without bound
+		return s.writeIndex()
+	}
+	s.indexFile, err = os.OpenFile(indexPath, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		// terminate the truncated entry so the next one starts on its own line
+		if _, err := s.indexFile.Write([]byte{'\n'}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LocalFileStorageService) rebuildIndex() error {
+	entries, err := os.ReadDir(s.dataDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		key, err := decodeLocalFileStorageName(entry.Name())
+		if err != nil {
+			// temporary files from interrupted writes, or files from before keys were hex encoded
+			continue
+		}
+		s.index[key] = struct{}{}
+	}
+	if len(s.index) > 0 {
+		log.Info("built LocalFileStorageService index from data directory", "dataDir", s.dataDir, "entries", len(s.index))
+	}
+	return s.writeIndex()
+}
+
+// writeIndex replaces the index with the keys currently in it, and opens it for appending.
+// The whole index is written before it's used, so an interrupted rewrite is started again.
+func (s *LocalFileStorageService) writeIndex() error {
+	var index bytes.Buffer
+	for key := range s.index {
+		index.WriteString(EncodeStorageServiceKey(key) + "\n")
+	}
+	if err := s.writeFileAtomically(localFileStorageIndexName, index.Bytes()); err != nil {
+		return err
+	}
+	var err error
+	s.indexFile, err = os.OpenFile(filepath.Join(s.dataDir, localFileStorageIndexName), os.O_WRONLY|os.O_APPEND, 0o600)
+	return err
+}
+
+func decodeLocalFileStorageName(name string) (common.Hash, error) {
+	if len(name) != 2*common.HashLength {
+		return common.Hash{}, fmt.Errorf("unexpected length %v", len(name))
+	}
+	return DecodeStorageServiceKey(name)
+}
+
+// Has returns whether the key is in the index, without touching its file
+func (s *LocalFileStorageService) Has(key common.Hash) bool {
+	s.indexMutex.Lock()
+	defer s.indexMutex.Unlock()
+	_, has := s.index[key]
+	return has
+}
+
+// Keys returns every key in the index, in no particular order
+func (s *LocalFileStorageService) Keys() []common.Hash {
+	s.indexMutex.Lock()
+	defer s.indexMutex.Unlock()
+	keys := make([]common.Hash, 0, len(s.index))
+	for key := range s.index {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// StoredEntries returns the size and modification time of each stored batch, for retention
+func (s *LocalFileStorageService) StoredEntries() ([]StoredEntry, error) {
+	keys := s.Keys()
+	entries := make([]StoredEntry, 0, len(keys))
+	for _, key := range keys {
+		info, err := os.Stat(filepath.Join(s.dataDir, EncodeStorageServiceKey(key)))
+		if errors.Is(err, os.ErrNotExist) {
+			// removed since the keys were listed
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, StoredEntry{Key: key, Size: uint64(info.Size()), StoredAt: info.ModTime()})
+	}
+	return entries, nil
+}
+
+// Remove deletes a stored batch. It's dropped from the index first, so a crash can leave an
+// unindexed file behind but never an index entry without its file.
+func (s *LocalFileStorageService) Remove(ctx context.Context, key common.Hash) error {
+	s.indexMutex.Lock()
+	defer s.indexMutex.Unlock()
+	if _, has := s.index[key]; !has {
+		return ErrNotFound
+	}
+	if _, err := s.indexFile.Write([]byte(localFileStorageRemovedPrefix + EncodeStorageServiceKey(key) + "\n")); err != nil {
+		return err
+	}
+	if s.fsync {
+		if err := s.indexFile.Sync(); err != nil {
+			return err
+		}
+	}
+	delete(s.index, key)
+	err := os.Remove(filepath.Join(s.dataDir, EncodeStorageServiceKey(key)))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return s.syncDataDir()
 }
 
 func (s *LocalFileStorageService) GetByHash(ctx context.Context, key common.Hash) ([]byte, error) {
@@ -71,14 +258,32 @@ func (s *LocalFileStorageService) GetByHash(ctx context.Context, key common.Hash
 
 func (s *LocalFileStorageService) Put(ctx context.Context, data []byte, timeout uint64) error {
 	logPut("das.LocalFileStorageService.Store", data, timeout, s)
-	fileName := EncodeStorageServiceKey(dastree.Hash(data))
+	return s.putKeyValue(ctx, dastree.Hash(data), data)
+}
+
+func (s *LocalFileStorageService) putKeyValue(ctx context.Context, key common.Hash, value []byte) error {
+	if err := s.writeFileAtomically(EncodeStorageServiceKey(key), value); err != nil {
+		return err
+	}
+	return s.addToIndex(key)
+}
+
+// writeFileAtomically uses a temp file and rename to achieve atomic writes,
+// and with fsync enabled only returns once both are durable.
+func (s *LocalFileStorageService) writeFileAtomically(fileName string, data []byte) error {
 	finalPath := s.dataDir + "/" + fileName
 
-	// Use a temp file and rename to achieve atomic writes.
 	f, err := os.CreateTemp(s.dataDir, fileName)
 	if err != nil {
 		return err
 	}
+	renamed := false
+	defer func() {
+		if !renamed {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
 	err = f.Chmod(0o600)
 	if err != nil {
 		return err
@@ -87,39 +292,53 @@ func (s *LocalFileStorageService) Put(ctx context.Context, data []byte, timeout
 	if err != nil {
 		return err
 	}
+	if s.fsync {
+		err = f.Sync()
+		if err != nil {
+			return err
+		}
+	}
 	err = f.Close()
 	if err != nil {
 		return err
 	}
 
-	return os.Rename(f.Name(), finalPath)
-
-}
-
-func (s *LocalFileStorageService) putKeyValue(ctx context.Context, key common.Hash, value []byte) error {
-	fileName := EncodeStorageServiceKey(key)
-	finalPath := s.dataDir + "/" + fileName
-
-	// Use a temp file and rename to achieve atomic writes.
-	f, err := os.CreateTemp(s.dataDir, fileName)
+	err = os.Rename(f.Name(), finalPath)
 	if err != nil {
 		return err
 	}
-	err = f.Chmod(0o600)
-	if err != nil {
-		return err
+	renamed = true
+	return s.syncDataDir()
+}
+
+func (s *LocalFileStorageService) syncDataDir() error {
+	if !s.fsync {
+		return nil
 	}
-	_, err = f.Write(value)
+	dir, err := os.Open(s.dataDir)
 	if err != nil {
 		return err
 	}
-	err = f.Close()
-	if err != nil {
+	defer dir.Close()
+	return dir.Sync()
+}
+
+func (s *LocalFileStorageService) addToIndex(key common.Hash) error {
+	s.indexMutex.Lock()
+	defer s.indexMutex.Unlock()
+	if _, has := s.index[key]; has {
+		return nil
+	}
+	if _, err := s.indexFile.Write([]byte(EncodeStorageServiceKey(key) + "\n")); err != nil {
 		return err
 	}
-
-	return os.Rename(f.Name(), finalPath)
-
+	if s.fsync {
+		if err := s.indexFile.Sync(); err != nil {
+			return err
+		}
+	}
+	s.index[key] = struct{}{}
+	return nil
 }
 
 func (s *LocalFileStorageService) Sync(ctx context.Context) error {
@@ -127,7 +346,9 @@ func (s *LocalFileStorageService) Sync(ctx context.Context) error {
 }
 
 func (s *LocalFileStorageService) Close(ctx context.Context) error {
-	return nil
+	s.indexMutex.Lock()
+	defer s.indexMutex.Unlock()
+	return s.indexFile.Close()
 }
 
 func (s *LocalFileStorageService) ExpirationPolicy(ctx context.Context) (arbstate.ExpirationPolicy, error) {
@@ -0,0 +1,111 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/offchainlabs/nitro/das/dastree"
+)
+
+func TestLocalFileStorageService(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dataDir := t.TempDir()
+
+	val1 := []byte("First value")
+	hash1 := dastree.Hash(val1)
+	val2 := []byte("Second value")
+	hash2 := dastree.Hash(val2)
+
+	config := DefaultLocalFileStorageConfig
+	config.DataDir = dataDir
+	s, err := NewLocalFileStorageServiceWithConfig(&config)
+	Require(t, err)
+	Require(t, s.Put(ctx, val1, math.MaxUint64))
+	Require(t, s.Put(ctx, val1, math.MaxUint64))
+	res, err := s.GetByHash(ctx, hash1)
+	Require(t, err)
+	if !bytes.Equal(res, val1) {
+		t.Fatal("unexpected value", string(res))
+	}
+	if _, err := s.GetByHash(ctx, hash2); !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected not found but got", err)
+	}
+	if !s.Has(hash1) || s.Has(hash2) {
+		t.Fatal("unexpected index contents", s.Keys())
+	}
+	Require(t, s.Close(ctx))
+
+	entries, err := os.ReadDir(dataDir)
+	Require(t, err)
+	if len(entries) != 2 {
+		t.Fatal("expected only the data and index files but got", len(entries), "entries")
+	}
+
+	// The index survives restarts, and an interrupted append to it is ignored
+	indexFile, err := os.OpenFile(filepath.Join(dataDir, localFileStorageIndexName), os.O_WRONLY|os.O_APPEND, 0o600)
+	Require(t, err)
+	_, err = indexFile.Write([]byte(EncodeStorageServiceKey(hash2)[:10]))
+	Require(t, err)
+	Require(t, indexFile.Close())
+	s, err = NewLocalFileStorageServiceWithConfig(&config)
+	Require(t, err)
+	if !s.Has(hash1) || s.Has(hash2) || len(s.Keys()) != 1 {
+		t.Fatal("unexpected index contents after restart", s.Keys())
+	}
+	Require(t, s.Put(ctx, val2, math.MaxUint64))
+	Require(t, s.Close(ctx))
+
+	// A missing index is rebuilt from the data directory
+	Require(t, os.Remove(filepath.Join(dataDir, localFileStorageIndexName)))
+	s, err = NewLocalFileStorageServiceWithConfig(&config)
+	Require(t, err)
+	if !s.Has(hash1) || !s.Has(hash2) || len(s.Keys()) != 2 {
+		t.Fatal("unexpected index contents after rebuild", s.Keys())
+	}
+	res, err = s.GetByHash(ctx, hash2)
+	Require(t, err)
+	if !bytes.Equal(res, val2) {
+		t.Fatal("unexpected value", string(res))
+	}
+	Require(t, s.Close(ctx))
+}
+
+func TestLocalFileStorageServiceReadThrough(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	val := []byte("Remote value")
+	hash := dastree.Hash(val)
+	remote := NewMemoryBackedStorageService(ctx)
+	Require(t, remote.Put(ctx, val, math.MaxUint64))
+
+	config := DefaultLocalFileStorageConfig
+	config.DataDir = t.TempDir()
+	local, err := NewLocalFileStorageServiceWithConfig(&config)
+	Require(t, err)
+	defer local.Close(ctx)
+	fss := NewFallbackStorageService(local, remote, remote, math.MaxUint64, false, true)
+
+	res, err := fss.GetByHash(ctx, hash)
+	Require(t, err)
+	if !bytes.Equal(res, val) {
+		t.Fatal("unexpected value", string(res))
+	}
+	if !local.Has(hash) {
+		t.Fatal("data fetched from the backup wasn't stored locally")
+	}
+	res, err = local.GetByHash(ctx, hash)
+	Require(t, err)
+	if !bytes.Equal(res, val) {
+		t.Fatal("unexpected local value", string(res))
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	flag "github.com/spf13/pflag"
+	"golang.org/x/sys/unix"
+
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/das/dastree"
+	"github.com/offchainlabs/nitro/util/pretty"
+)
+
+var (
+	readCacheMemoryHitCounter = metrics.NewRegisteredCounter("arb/das/readcache/memory/hit", nil)
+	readCacheDiskHitCounter   = metrics.NewRegisteredCounter("arb/das/readcache/disk/hit", nil)
+	readCacheMissCounter      = metrics.NewRegisteredCounter("arb/das/readcache/miss", nil)
+	readCacheDiskBytesGauge   = metrics.NewRegisteredGauge("arb/das/readcache/disk/bytes", nil)
+)
+
+type ReadCacheConfig struct {
+	Enable        bool   `koanf:"enable"`
+	MemoryMaxSize uint64 `koanf:"memory-max-size"`
+	DiskDir       string `koanf:"disk-dir"`
+	DiskMaxSize   uint64 `koanf:"disk-max-size"`
+}
+
+var DefaultReadCacheConfig = ReadCacheConfig{
+	Enable:        false,
+	MemoryMaxSize: 256 * 1024 * 1024,
+	DiskDir:       "",
+	DiskMaxSize:   16 * 1024 * 1024 * 1024,
+}
+
+func ReadCacheConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultReadCacheConfig.Enable, "cache batch data read from the data availability service, so it isn't fetched again by validation or a resync")
+	f.Uint64(prefix+".memory-max-size", DefaultReadCacheConfig.MemoryMaxSize, "maximum bytes of batch data to cache in memory")
+	f.String(prefix+".disk-dir", DefaultReadCacheConfig.DiskDir, "directory to cache batch data evicted from memory in (empty = only cache in memory)")
+	f.Uint64(prefix+".disk-max-size", DefaultReadCacheConfig.DiskMaxSize, "maximum bytes of batch data to cache on disk, evicting the least recently used")
+}
+
+// ReadCache caches what a DataAvailabilityServiceReader returns, in a least recently used cache in
+// memory backed by a least recently used cache on disk. Everything read from the disk is checked
+// against its hash, so a corrupted cache file is refetched rather than trusted.
+type ReadCache struct {
+	base   DataAvailabilityServiceReader
+	config ReadCacheConfig
+	memory *lru.SizeConstrainedCache[common.Hash, []byte]
+
+	// The sizes of the files in the disk cache by key, least recently used first
+	diskMutex sync.Mutex
+	disk      lru.BasicLRU[common.Hash, uint64]
+	diskBytes uint64
+}
+
+func NewReadCache(config ReadCacheConfig, base DataAvailabilityServiceReader) (*ReadCache, error) {
+	c := &ReadCache{
+		base:   base,
+		config: config,
+		memory: lru.NewSizeConstrainedCache[common.Hash, []byte](config.MemoryMaxSize),
+		disk:   lru.NewBasicLRU[common.Hash, uint64](math.MaxInt32),
+	}
+	if config.DiskDir != "" {
+		if err := os.MkdirAll(config.DiskDir, 0o700); err != nil {
+			return nil, err
+		}
+		if unix.Access(config.DiskDir, unix.W_OK|unix.R_OK) != nil {
+			return nil, fmt.Errorf("data availability read cache directory '%s' must be readable and writeable", config.DiskDir)
+		}
+		if err := c.loadDisk(); err != nil {
+			return nil, fmt.Errorf("couldn't load data availability read cache from '%s': %w", config.DiskDir, err)
+		}
+	}
+	return c, nil
+}
+
+// loadDisk indexes the files already in the disk cache, treating the least recently modified as
+// the least recently used
+func (c *ReadCache) loadDisk() error {
+	entries, err := os.ReadDir(c.config.DiskDir)
+	if err != nil {
+		return err
+	}
+	type cached struct {
+		key  common.Hash
+		info os.FileInfo
+	}
+	var files []cached
+	for _, entry := range entries {
+		key, err := decodeLocalFileStorageName(entry.Name())
+		if err != nil {
+			// leftover temp files from an interrupted write
+			_ = os.Remove(filepath.Join(c.config.DiskDir, entry.Name()))
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, cached{key, info})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].info.ModTime().Before(files[j].info.ModTime())
+	})
+	c.diskMutex.Lock()
+	defer c.diskMutex.Unlock()
+	for _, file := range files {
+		c.addToDiskLocked(file.key, uint64(file.info.Size()))
+	}
+	return nil
+}
+
+func (c *ReadCache) diskPath(key common.Hash) string {
+	return filepath.Join(c.config.DiskDir, EncodeStorageServiceKey(key))
+}
+
+func (c *ReadCache) GetByHash(ctx context.Context, key common.Hash) ([]byte, error) {
+	log.Trace("das.ReadCache.GetByHash", "key", pretty.PrettyHash(key), "this", c)
+
+	if data, ok := c.memory.Get(key); ok {
+		readCacheMemoryHitCounter.Inc(1)
+		return data, nil
+	}
+	if data := c.getFromDisk(key); data != nil {
+		readCacheDiskHitCounter.Inc(1)
+		c.addToMemory(key, data)
+		return data, nil
+	}
+	readCacheMissCounter.Inc(1)
+
+	data, err := c.base.GetByHash(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !dastree.ValidHash(key, data) {
+		// leave it to the caller to reject
+		return data, nil
+	}
+	c.addToMemory(key, data)
+	if c.config.DiskDir != "" {
+		if err := c.putOnDisk(key, data); err != nil {
+			log.Warn("failed to cache batch data on disk", "key", pretty.PrettyHash(key), "err", err)
+		}
+	}
+	return data, nil
+}
+
+func (c *ReadCache) addToMemory(key common.Hash, data []byte) {
+	// The memory cache always keeps the latest entry, even if it's over the limit
+	if uint64(len(data)) <= c.config.MemoryMaxSize {
+		c.memory.Add(key, data)
+	}
+}
+
+func (c *ReadCache) getFromDisk(key common.Hash) []byte {
+	if c.config.DiskDir == "" {
+		return nil
+	}
+	c.diskMutex.Lock()
+	_, ok := c.disk.Get(key)
+	c.diskMutex.Unlock()
+	if !ok {
+		return nil
+	}
+	data, err := os.ReadFile(c.diskPath(key))
+	if err == nil && dastree.ValidHash(key, data) {
+		return data
+	}
+	log.Warn("dropping unreadable or corrupted batch data from the read cache", "key", pretty.PrettyHash(key), "err", err)
+	c.diskMutex.Lock()
+	defer c.diskMutex.Unlock()
+	if size, ok := c.disk.Peek(key); ok {
+		c.disk.Remove(key)
+		c.diskBytes -= size
+		_ = os.Remove(c.diskPath(key))
+	}
+	return nil
+}
+
+func (c *ReadCache) putOnDisk(key common.Hash, data []byte) error {
+	if uint64(len(data)) > c.config.DiskMaxSize {
+		return nil
+	}
+	f, err := os.CreateTemp(c.config.DiskDir, EncodeStorageServiceKey(key)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.diskPath(key))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	c.diskMutex.Lock()
+	defer c.diskMutex.Unlock()
+	if size, ok := c.disk.Peek(key); ok {
+		c.diskBytes -= size
+	}
+	c.addToDiskLocked(key, uint64(len(data)))
+	return nil
+}
+
+// addToDiskLocked must be called with the diskMutex held
+func (c *ReadCache) addToDiskLocked(key common.Hash, size uint64) {
+	c.disk.Add(key, size)
+	c.diskBytes += size
+	for c.diskBytes > c.config.DiskMaxSize {
+		evicted, evictedSize, ok := c.disk.RemoveOldest()
+		if !ok {
+			break
+		}
+		c.diskBytes -= evictedSize
+		if err := os.Remove(c.diskPath(evicted)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warn("failed to evict batch data from the read cache", "key", pretty.PrettyHash(evicted), "err", err)
+		}
+	}
+	readCacheDiskBytesGauge.Update(int64(c.diskBytes))
+}
+
+func (c *ReadCache) ExpirationPolicy(ctx context.Context) (arbstate.ExpirationPolicy, error) {
+	return c.base.ExpirationPolicy(ctx)
+}
+
+func (c *ReadCache) String() string {
+	return fmt.Sprintf("ReadCache(%v)", c.base)
+}
@@ -0,0 +1,139 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/das/dastree"
+)
+
+// countingReader counts how often each key is fetched from the underlying storage
+type countingReader struct {
+	StorageService
+	reads map[common.Hash]int
+}
+
+func (r *countingReader) GetByHash(ctx context.Context, key common.Hash) ([]byte, error) {
+	r.reads[key]++
+	return r.StorageService.GetByHash(ctx, key)
+}
+
+func newCountingReader(ctx context.Context) *countingReader {
+	return &countingReader{NewMemoryBackedStorageService(ctx), make(map[common.Hash]int)}
+}
+
+func TestReadCache(t *testing.T) {
+	ctx := context.Background()
+	base := newCountingReader(ctx)
+	config := ReadCacheConfig{
+		Enable:        true,
+		MemoryMaxSize: 1 << 20,
+		DiskDir:       t.TempDir(),
+		DiskMaxSize:   1 << 20,
+	}
+	cache, err := NewReadCache(config, base)
+	Require(t, err)
+
+	val := []byte("The first value")
+	key := dastree.Hash(val)
+	_, err = cache.GetByHash(ctx, key)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+	Require(t, base.Put(ctx, val, 1))
+	for i := 0; i < 3; i++ {
+		got, err := cache.GetByHash(ctx, key)
+		Require(t, err)
+		if !bytes.Equal(got, val) {
+			t.Fatal(got, val)
+		}
+	}
+	// Missing data isn't cached, so it's fetched once before and once after being stored
+	if base.reads[key] != 2 {
+		t.Fatal("expected 2 reads from the base but got", base.reads[key])
+	}
+
+	// A new cache over the same directory, as after a restart, serves the data from disk
+	cache, err = NewReadCache(config, base)
+	Require(t, err)
+	got, err := cache.GetByHash(ctx, key)
+	Require(t, err)
+	if !bytes.Equal(got, val) {
+		t.Fatal(got, val)
+	}
+	if base.reads[key] != 2 {
+		t.Fatal("expected the data to be read from disk, but it was read from the base")
+	}
+}
+
+func TestReadCacheDiskEviction(t *testing.T) {
+	ctx := context.Background()
+	base := newCountingReader(ctx)
+	config := ReadCacheConfig{
+		Enable:        true,
+		MemoryMaxSize: 0,
+		DiskDir:       t.TempDir(),
+		DiskMaxSize:   100,
+	}
+	cache, err := NewReadCache(config, base)
+	Require(t, err)
+
+	var keys []common.Hash
+	for i := 0; i < 5; i++ {
+		val := bytes.Repeat([]byte{byte(i)}, 40)
+		Require(t, base.Put(ctx, val, 1))
+		key := dastree.Hash(val)
+		keys = append(keys, key)
+		_, err := cache.GetByHash(ctx, key)
+		Require(t, err)
+	}
+	entries, err := os.ReadDir(config.DiskDir)
+	Require(t, err)
+	if len(entries) != 2 || cache.diskBytes != 80 {
+		t.Fatal("expected the disk cache to hold the 2 latest values but it has", len(entries), "files of", cache.diskBytes, "bytes")
+	}
+	for i, key := range keys {
+		_, err := os.Stat(filepath.Join(config.DiskDir, EncodeStorageServiceKey(key)))
+		if evicted := errors.Is(err, os.ErrNotExist); evicted != (i < 3) {
+			t.Fatal("unexpected disk cache state for value", i, err)
+		}
+	}
+}
+
+func TestReadCacheRefetchesCorruptData(t *testing.T) {
+	ctx := context.Background()
+	base := newCountingReader(ctx)
+	config := ReadCacheConfig{
+		Enable:        true,
+		MemoryMaxSize: 0,
+		DiskDir:       t.TempDir(),
+		DiskMaxSize:   1 << 20,
+	}
+	cache, err := NewReadCache(config, base)
+	Require(t, err)
+
+	val := []byte("The first value")
+	key := dastree.Hash(val)
+	Require(t, base.Put(ctx, val, 1))
+	_, err = cache.GetByHash(ctx, key)
+	Require(t, err)
+
+	Require(t, os.WriteFile(filepath.Join(config.DiskDir, EncodeStorageServiceKey(key)), []byte("corrupted"), 0o600))
+	got, err := cache.GetByHash(ctx, key)
+	Require(t, err)
+	if !bytes.Equal(got, val) {
+		t.Fatal(got, val)
+	}
+	if base.reads[key] != 2 {
+		t.Fatal("expected corrupted data to be refetched from the base, but it had", base.reads[key], "reads")
+	}
+}
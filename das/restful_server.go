@@ -4,6 +4,7 @@
 package das
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -20,6 +21,7 @@ import (
 	"github.com/offchainlabs/nitro/arbstate"
 	"github.com/offchainlabs/nitro/cmd/genericconf"
 	"github.com/offchainlabs/nitro/util/pretty"
+	flag "github.com/spf13/pflag"
 )
 
 var (
@@ -30,6 +32,46 @@ var (
 	restGetByHashDurationHistogram  = metrics.NewRegisteredHistogram("arb/das/rest/getbyhash/duration", nil, metrics.NewBoundedHistogramSample())
 )
 
+type RestfulDasServerConfig struct {
+	Enable         bool                                `koanf:"enable"`
+	Addr           string                              `koanf:"addr"`
+	Port           uint64                              `koanf:"port"`
+	ServerTimeouts genericconf.HTTPServerTimeoutConfig `koanf:"server-timeouts"`
+}
+
+var DefaultRestfulDasServerConfig = RestfulDasServerConfig{
+	Enable:         false,
+	Addr:           "localhost",
+	Port:           9877,
+	ServerTimeouts: genericconf.HTTPServerTimeoutConfigDefault,
+}
+
+func RestfulDasServerConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultRestfulDasServerConfig.Enable, "enable serving the batch data this node can retrieve over the DAS REST API, so it can act as a mirror for other nodes")
+	f.String(prefix+".addr", DefaultRestfulDasServerConfig.Addr, "DAS REST server listening interface")
+	f.Uint64(prefix+".port", DefaultRestfulDasServerConfig.Port, "DAS REST server listening port")
+	genericconf.HTTPServerTimeoutConfigAddOptions(prefix+".server-timeouts", f)
+}
+
+// readerHealthChecker reports a reader without its own health check as healthy while it responds
+type readerHealthChecker struct {
+	reader arbstate.DataAvailabilityReader
+}
+
+func (c readerHealthChecker) HealthCheck(ctx context.Context) error {
+	_, err := c.reader.ExpirationPolicy(ctx)
+	return err
+}
+
+// NewRestfulDasServerForReader serves the reader's data, using its health check if it has one
+func NewRestfulDasServerForReader(config *RestfulDasServerConfig, daReader arbstate.DataAvailabilityReader) (*RestfulDasServer, error) {
+	daHealthChecker, ok := daReader.(DataAvailabilityServiceHealthChecker)
+	if !ok {
+		daHealthChecker = readerHealthChecker{daReader}
+	}
+	return NewRestfulDasServer(config.Addr, config.Port, config.ServerTimeouts, daReader, daHealthChecker)
+}
+
 type RestfulDasServer struct {
 	server               *http.Server
 	daReader             arbstate.DataAvailabilityReader
@@ -204,3 +246,11 @@ func (rds *RestfulDasServer) Shutdown() error {
 	<-rds.httpServerExitedChan
 	return rds.httpServerError
 }
+
+func (rds *RestfulDasServer) Close(ctx context.Context) error {
+	return rds.Shutdown()
+}
+
+func (rds *RestfulDasServer) String() string {
+	return "RestfulDasServer"
+}
@@ -13,6 +13,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/offchainlabs/nitro/arbstate"
 	"github.com/offchainlabs/nitro/cmd/genericconf"
 	"github.com/offchainlabs/nitro/das/dastree"
 )
@@ -68,3 +69,42 @@ func TestRestfulClientServer(t *testing.T) {
 	err = server.Shutdown()
 	Require(t, err)
 }
+
+func TestRestfulDasServerForReader(t *testing.T) {
+	initTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage := NewMemoryBackedStorageService(ctx)
+	data := []byte("Testing a restful server mirroring a node's reader.")
+	err := storage.Put(ctx, data, uint64(time.Now().Add(time.Hour).Unix()))
+	Require(t, err)
+
+	listener, err := net.Listen("tcp", LocalServerAddressForTest+":0")
+	Require(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	Require(t, listener.Close())
+
+	// The node's reader is wrapped, so it doesn't have a health check of its own
+	config := DefaultRestfulDasServerConfig
+	config.Enable = true
+	config.Port = uint64(port)
+	server, err := NewRestfulDasServerForReader(&config, NewReaderTimeoutWrapper(storage, time.Second))
+	Require(t, err)
+
+	client := NewRestfulDasClient("http", LocalServerAddressForTest, port)
+	Require(t, client.HealthCheck(ctx))
+	returnedData, err := client.GetByHash(ctx, dastree.Hash(data))
+	Require(t, err)
+	if !bytes.Equal(data, returnedData) {
+		Fail(t, fmt.Sprintf("Returned data '%s' does not match expected '%s'", returnedData, data))
+	}
+	expirationPolicy, err := client.ExpirationPolicy(ctx)
+	Require(t, err)
+	if expirationPolicy != arbstate.KeepForever {
+		Fail(t, "unexpected expiration policy", expirationPolicy)
+	}
+
+	Require(t, server.Close(ctx))
+}
@@ -0,0 +1,388 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
+	"github.com/offchainlabs/nitro/util/headerreader"
+	"github.com/offchainlabs/nitro/util/pretty"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+	flag "github.com/spf13/pflag"
+)
+
+var (
+	retentionStoredBytesGauge    = metrics.NewRegisteredGauge("arb/das/retention/storedbytes", nil)
+	retentionStoredEntriesGauge  = metrics.NewRegisteredGauge("arb/das/retention/storedentries", nil)
+	retentionEvictedCounter      = metrics.NewRegisteredCounter("arb/das/retention/evicted", nil)
+	retentionEvictedBytesCounter = metrics.NewRegisteredCounter("arb/das/retention/evictedbytes", nil)
+	retentionUnconfirmedGauge    = metrics.NewRegisteredGauge("arb/das/retention/unconfirmed", nil)
+)
+
+type RetentionConfig struct {
+	Enable                         bool          `koanf:"enable"`
+	MaxAge                         time.Duration `koanf:"max-age"`
+	MaxBytes                       uint64        `koanf:"max-bytes"`
+	RequireParentChainConfirmation bool          `koanf:"require-parent-chain-confirmation"`
+	CheckInterval                  time.Duration `koanf:"check-interval"`
+	ParentChainStartBlock          uint64        `koanf:"parent-chain-start-block"`
+	ParentChainBlocksPerRead       uint64        `koanf:"parent-chain-blocks-per-read"`
+	StateDir                       string        `koanf:"state-dir"`
+}
+
+var DefaultRetentionConfig = RetentionConfig{
+	Enable:                         false,
+	MaxAge:                         0,
+	MaxBytes:                       0,
+	RequireParentChainConfirmation: true,
+	CheckInterval:                  time.Minute,
+	ParentChainStartBlock:          0,
+	ParentChainBlocksPerRead:       1000,
+	StateDir:                       "",
+}
+
+func RetentionConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultRetentionConfig.Enable, "enable evicting batch data from local file storage once it's too old or storage is too large")
+	f.Duration(prefix+".max-age", DefaultRetentionConfig.MaxAge, "evict batch data stored longer than this (0 = no age limit)")
+	f.Uint64(prefix+".max-bytes", DefaultRetentionConfig.MaxBytes, "evict the oldest batch data while more than this many bytes are stored (0 = no size limit)")
+	f.Bool(prefix+".require-parent-chain-confirmation", DefaultRetentionConfig.RequireParentChainConfirmation, "only evict batch data whose batch has been posted to the parent chain in a finalized block")
+	f.Duration(prefix+".check-interval", DefaultRetentionConfig.CheckInterval, "how often to check for batch data to evict")
+	f.Uint64(prefix+".parent-chain-start-block", DefaultRetentionConfig.ParentChainStartBlock, "parent chain block to start reading posted batches from, when there is no retention state")
+	f.Uint64(prefix+".parent-chain-blocks-per-read", DefaultRetentionConfig.ParentChainBlocksPerRead, "max parent chain blocks to read posted batches from per request")
+	f.String(prefix+".state-dir", DefaultRetentionConfig.StateDir, "directory to store which stored batches have been posted to the parent chain, so they aren't read again on restart")
+}
+
+func (c *RetentionConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.MaxAge == 0 && c.MaxBytes == 0 {
+		return errors.New("retention enabled but neither max-age nor max-bytes is set")
+	}
+	if c.CheckInterval <= 0 {
+		return errors.New("retention check-interval must be positive")
+	}
+	if c.RequireParentChainConfirmation && c.StateDir == "" {
+		return errors.New("retention requiring parent chain confirmation needs a state-dir")
+	}
+	return nil
+}
+
+// StoredEntry describes a stored batch, for deciding whether it's retained
+type StoredEntry struct {
+	Key      common.Hash
+	Size     uint64
+	StoredAt time.Time
+}
+
+// EvictableStorageService is a storage backend which retention can list and delete batches from
+type EvictableStorageService interface {
+	StoredEntries() ([]StoredEntry, error)
+	Remove(ctx context.Context, key common.Hash) error
+}
+
+// StorageRetention evicts stored batches which are older than the max age, and the oldest batches while
+// storage is larger than the max size. When parent chain confirmation is required, a batch is only
+// evicted once a finalized parent chain block has posted its certificate, so data is never dropped
+// before the chain has committed to it, however old it is.
+type StorageRetention struct {
+	stopwaiter.StopWaiter
+	config        RetentionConfig
+	storage       EvictableStorageService
+	confirmations *batchConfirmations
+}
+
+func NewStorageRetention(config *RetentionConfig, storage EvictableStorageService, l1Reader *headerreader.HeaderReader, seqInboxAddress *common.Address) (*StorageRetention, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	r := &StorageRetention{
+		config:  *config,
+		storage: storage,
+	}
+	if config.RequireParentChainConfirmation {
+		if l1Reader == nil || seqInboxAddress == nil {
+			return nil, errors.New("retention requiring parent chain confirmation needs parent-chain-node-url and sequencer-inbox-address")
+		}
+		confirmations, err := newBatchConfirmations(config, l1Reader, *seqInboxAddress)
+		if err != nil {
+			return nil, err
+		}
+		r.confirmations = confirmations
+	}
+	return r, nil
+}
+
+func (r *StorageRetention) Start(ctx context.Context) {
+	r.StopWaiter.Start(ctx, r)
+	r.CallIteratively(func(ctx context.Context) time.Duration {
+		if r.confirmations != nil {
+			for {
+				caughtUp, err := r.confirmations.readMore(ctx)
+				if err != nil {
+					log.Warn("failed to read posted batches for DAS retention", "err", err)
+					return r.config.CheckInterval
+				}
+				if caughtUp || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+		if err := r.evict(ctx, time.Now()); err != nil {
+			log.Warn("DAS retention failed to evict batch data", "err", err)
+		}
+		return r.config.CheckInterval
+	})
+}
+
+func (r *StorageRetention) Close(ctx context.Context) error {
+	r.StopOnly()
+	return nil
+}
+
+func (r *StorageRetention) String() string {
+	return fmt.Sprintf("StorageRetention(%v)", r.storage)
+}
+
+func (r *StorageRetention) evict(ctx context.Context, now time.Time) error {
+	entries, err := r.storage.StoredEntries()
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].StoredAt.Before(entries[j].StoredAt) })
+	var storedBytes uint64
+	for _, entry := range entries {
+		storedBytes += entry.Size
+	}
+
+	var unconfirmed int64
+	var evicted []common.Hash
+	for _, entry := range entries {
+		tooOld := r.config.MaxAge > 0 && now.Sub(entry.StoredAt) > r.config.MaxAge
+		tooLarge := r.config.MaxBytes > 0 && storedBytes > r.config.MaxBytes
+		if !tooOld && !tooLarge {
+			// entries are oldest first, so the rest are retained too
+			break
+		}
+		if r.confirmations != nil && !r.confirmations.isConfirmed(entry.Key) {
+			unconfirmed++
+			continue
+		}
+		if err := r.storage.Remove(ctx, entry.Key); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		log.Debug("evicted DAS batch data", "key", pretty.PrettyHash(entry.Key), "size", entry.Size, "storedAt", entry.StoredAt)
+		storedBytes -= entry.Size
+		evicted = append(evicted, entry.Key)
+		retentionEvictedCounter.Inc(1)
+		retentionEvictedBytesCounter.Inc(int64(entry.Size))
+	}
+	retentionStoredBytesGauge.Update(int64(storedBytes))
+	retentionStoredEntriesGauge.Update(int64(len(entries) - len(evicted)))
+	retentionUnconfirmedGauge.Update(unconfirmed)
+	if unconfirmed > 0 {
+		log.Info("retaining DAS batch data not yet posted in a finalized parent chain block", "entries", unconfirmed)
+	}
+	if r.confirmations != nil && len(evicted) > 0 {
+		return r.confirmations.forget(evicted)
+	}
+	return nil
+}
+
+const (
+	retentionNextBlockFilename = "retentionNextBlock"
+	retentionConfirmedFilename = "retentionConfirmed"
+)
+
+// batchConfirmations records the data hashes of the DAS certificates posted in finalized parent chain
+// blocks, persisting them so they're known after a restart
+type batchConfirmations struct {
+	config        *RetentionConfig
+	l1Reader      *headerreader.HeaderReader
+	inboxContract *bridgegen.SequencerInbox
+	inboxAddr     common.Address
+
+	mutex     sync.Mutex
+	confirmed map[common.Hash]struct{}
+	nextBlock uint64
+}
+
+func newBatchConfirmations(config *RetentionConfig, l1Reader *headerreader.HeaderReader, inboxAddr common.Address) (*batchConfirmations, error) {
+	inboxContract, err := bridgegen.NewSequencerInbox(inboxAddr, l1Reader.Client())
+	if err != nil {
+		return nil, err
+	}
+	c := &batchConfirmations{
+		config:        config,
+		l1Reader:      l1Reader,
+		inboxContract: inboxContract,
+		inboxAddr:     inboxAddr,
+		confirmed:     make(map[common.Hash]struct{}),
+		nextBlock:     config.ParentChainStartBlock,
+	}
+	if err := c.load(); err != nil {
+		return nil, fmt.Errorf("failed to load DAS retention state from %v: %w", config.StateDir, err)
+	}
+	return c, nil
+}
+
+func (c *batchConfirmations) load() error {
+	data, err := os.ReadFile(filepath.Join(c.config.StateDir, retentionNextBlockFilename))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.nextBlock, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return err
+	}
+	data, err = os.ReadFile(filepath.Join(c.config.StateDir, retentionConfirmedFilename))
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		key, err := DecodeStorageServiceKey(line)
+		if err != nil {
+			return err
+		}
+		c.confirmed[key] = struct{}{}
+	}
+	return nil
+}
+
+// saveLocked writes the confirmed hashes before the next block, so on restart the block range is
+// read again rather than confirmations being lost
+func (c *batchConfirmations) saveLocked() error {
+	var confirmed bytes.Buffer
+	for key := range c.confirmed {
+		confirmed.WriteString(EncodeStorageServiceKey(key) + "\n")
+	}
+	if err := writeRetentionState(c.config.StateDir, retentionConfirmedFilename, confirmed.Bytes()); err != nil {
+		return err
+	}
+	return writeRetentionState(c.config.StateDir, retentionNextBlockFilename, []byte(fmt.Sprintf("%d\n", c.nextBlock)))
+}
+
+func writeRetentionState(stateDir string, fileName string, data []byte) error {
+	f, err := os.CreateTemp(stateDir, fileName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(stateDir, fileName))
+}
+
+func (c *batchConfirmations) isConfirmed(key common.Hash) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, confirmed := c.confirmed[key]
+	return confirmed
+}
+
+// forget drops evicted hashes, so the confirmed set only covers what's still stored
+func (c *batchConfirmations) forget(keys []common.Hash) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, key := range keys {
+		delete(c.confirmed, key)
+	}
+	return c.saveLocked()
+}
+
+func (c *batchConfirmations) confirm(keys []common.Hash, nextBlock uint64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, key := range keys {
+		c.confirmed[key] = struct{}{}
+	}
+	c.nextBlock = nextBlock
+	return c.saveLocked()
+}
+
+// readMore reads the batches posted in the next range of finalized blocks, and returns whether
+// it has caught up to the latest finalized block
+func (c *batchConfirmations) readMore(ctx context.Context) (bool, error) {
+	finalized, err := c.l1Reader.LatestFinalizedBlockNr(ctx)
+	if err != nil {
+		return false, err
+	}
+	c.mutex.Lock()
+	from := c.nextBlock
+	c.mutex.Unlock()
+	if from > finalized {
+		return true, nil
+	}
+	to := finalized
+	if c.config.ParentChainBlocksPerRead > 0 && to-from+1 > c.config.ParentChainBlocksPerRead {
+		to = from + c.config.ParentChainBlocksPerRead - 1
+	}
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []common.Address{c.inboxAddr},
+		Topics:    [][]common.Hash{{BatchDeliveredID}},
+	}
+	logs, err := c.l1Reader.Client().FilterLogs(ctx, query)
+	if err != nil {
+		return false, err
+	}
+	var keys []common.Hash
+	for _, deliveredLog := range logs {
+		deliveredEvent, err := c.inboxContract.ParseSequencerBatchDelivered(deliveredLog)
+		if err != nil {
+			return false, err
+		}
+		data, err := FindDASDataFromLog(ctx, c.inboxContract, deliveredEvent, c.inboxAddr, c.l1Reader.Client(), deliveredLog)
+		if err != nil {
+			return false, err
+		}
+		if data == nil {
+			continue
+		}
+		cert, err := arbstate.DeserializeDASCertFrom(bytes.NewReader(data))
+		if err != nil {
+			log.Warn("failed to parse DAS certificate posted to the parent chain", "batch", deliveredEvent.BatchSequenceNumber, "err", err)
+			continue
+		}
+		keys = append(keys, cert.DataHash)
+	}
+	if err := c.confirm(keys, to+1); err != nil {
+		return false, err
+	}
+	return to == finalized, nil
+}
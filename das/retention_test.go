@@ -0,0 +1,96 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package das
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/offchainlabs/nitro/das/dastree"
+)
+
+func TestStorageRetention(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dataDir := t.TempDir()
+	stateDir := t.TempDir()
+
+	config := DefaultLocalFileStorageConfig
+	config.DataDir = dataDir
+	storage, err := NewLocalFileStorageServiceWithConfig(&config)
+	Require(t, err)
+
+	now := time.Now()
+	var hashes []common.Hash
+	for i, age := range []time.Duration{time.Hour * 3, time.Hour * 2, time.Minute} {
+		data := []byte{byte(i), 1, 2, 3, 4, 5, 6, 7, 8, 9}
+		Require(t, storage.Put(ctx, data, math.MaxUint64))
+		hash := dastree.Hash(data)
+		storedAt := now.Add(-age)
+		Require(t, os.Chtimes(filepath.Join(dataDir, EncodeStorageServiceKey(hash)), storedAt, storedAt))
+		hashes = append(hashes, hash)
+	}
+
+	retentionConfig := DefaultRetentionConfig
+	retentionConfig.Enable = true
+	retentionConfig.MaxAge = time.Hour
+	retentionConfig.StateDir = stateDir
+	Require(t, retentionConfig.Validate())
+	confirmations := &batchConfirmations{
+		config:    &retentionConfig,
+		confirmed: make(map[common.Hash]struct{}),
+	}
+	retention := &StorageRetention{
+		config:        retentionConfig,
+		storage:       storage,
+		confirmations: confirmations,
+	}
+
+	// Old data is kept until its batch has been posted
+	Require(t, confirmations.confirm([]common.Hash{hashes[1]}, 100))
+	Require(t, retention.evict(ctx, now))
+	if storage.Has(hashes[1]) || !storage.Has(hashes[0]) || !storage.Has(hashes[2]) {
+		t.Fatal("expected only the old confirmed data to be evicted but have", storage.Keys())
+	}
+	if confirmations.isConfirmed(hashes[1]) {
+		t.Fatal("evicted data still recorded as confirmed")
+	}
+
+	// Confirmations are persisted
+	Require(t, confirmations.confirm([]common.Hash{hashes[0], hashes[2]}, 200))
+	reloaded := &batchConfirmations{
+		config:    &retentionConfig,
+		confirmed: make(map[common.Hash]struct{}),
+	}
+	Require(t, reloaded.load())
+	if reloaded.nextBlock != 200 || len(reloaded.confirmed) != 2 || !reloaded.isConfirmed(hashes[2]) {
+		t.Fatal("unexpected reloaded confirmations", reloaded.nextBlock, reloaded.confirmed)
+	}
+
+	// Recent data is evicted, oldest first, only while storage is too large
+	retention.config.MaxAge = 0
+	retention.config.MaxBytes = 15
+	Require(t, retention.evict(ctx, now))
+	if storage.Has(hashes[0]) || !storage.Has(hashes[2]) {
+		t.Fatal("expected the oldest data to be evicted but have", storage.Keys())
+	}
+	if _, err := storage.GetByHash(ctx, hashes[0]); !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected evicted data to be not found but got", err)
+	}
+
+	// Removals survive restarts
+	Require(t, storage.Close(ctx))
+	storage, err = NewLocalFileStorageServiceWithConfig(&config)
+	Require(t, err)
+	if keys := storage.Keys(); len(keys) != 1 || keys[0] != hashes[2] {
+		t.Fatal("unexpected index after restart", keys)
+	}
+	Require(t, storage.Close(ctx))
+}
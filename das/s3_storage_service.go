@@ -6,8 +6,10 @@ package das
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -15,6 +17,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/offchainlabs/nitro/arbstate"
 	"github.com/offchainlabs/nitro/das/dastree"
 	"github.com/offchainlabs/nitro/util/pretty"
@@ -40,12 +43,56 @@ type S3StorageServiceConfig struct {
 	ObjectPrefix           string `koanf:"object-prefix"`
 	Region                 string `koanf:"region"`
 	SecretKey              string `koanf:"secret-key"`
+	CredentialsSource      string `koanf:"credentials-source"`
+	Profile                string `koanf:"profile"`
+	Endpoint               string `koanf:"endpoint"`
+	UsePathStyle           bool   `koanf:"use-path-style"`
 	DiscardAfterTimeout    bool   `koanf:"discard-after-timeout"`
 	SyncFromStorageService bool   `koanf:"sync-from-storage-service"`
 	SyncToStorageService   bool   `koanf:"sync-to-storage-service"`
 }
 
-var DefaultS3StorageServiceConfig = S3StorageServiceConfig{}
+const (
+	// s3CredentialsAuto uses the access and secret keys if both are set, and otherwise the default chain
+	s3CredentialsAuto = "auto"
+	// s3CredentialsStatic only uses the access and secret keys
+	s3CredentialsStatic = "static"
+	// s3CredentialsDefault uses the AWS SDK's default chain: the environment, shared config files
+	// (optionally using a named profile), web identity tokens, and the container or instance role
+	s3CredentialsDefault = "default"
+)
+
+var DefaultS3StorageServiceConfig = S3StorageServiceConfig{
+	CredentialsSource: s3CredentialsAuto,
+}
+
+func (c *S3StorageServiceConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.Bucket == "" {
+		return errors.New("s3 storage requires a bucket")
+	}
+	switch c.CredentialsSource {
+	case s3CredentialsAuto, "":
+	case s3CredentialsStatic:
+		if c.AccessKey == "" || c.SecretKey == "" {
+			return errors.New("s3 storage with static credentials requires an access key and secret key")
+		}
+	case s3CredentialsDefault:
+		if c.AccessKey != "" || c.SecretKey != "" {
+			return errors.New("s3 storage access and secret keys can't be used with the default credentials source")
+		}
+	default:
+		return fmt.Errorf("invalid s3 storage credentials source \"%v\", must be one of %v, %v, or %v", c.CredentialsSource, s3CredentialsAuto, s3CredentialsStatic, s3CredentialsDefault)
+	}
+	if c.Endpoint != "" {
+		if _, err := url.ParseRequestURI(c.Endpoint); err != nil {
+			return fmt.Errorf("invalid s3 storage endpoint: %w", err)
+		}
+	}
+	return nil
+}
 
 func S3ConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultS3StorageServiceConfig.Enable, "enable storage/retrieval of sequencer batch data from an AWS S3 bucket")
@@ -54,7 +101,11 @@ func S3ConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.String(prefix+".object-prefix", DefaultS3StorageServiceConfig.ObjectPrefix, "prefix to add to S3 objects")
 	f.String(prefix+".region", DefaultS3StorageServiceConfig.Region, "S3 region")
 	f.String(prefix+".secret-key", DefaultS3StorageServiceConfig.SecretKey, "S3 secret key")
-	f.Bool(prefix+".discard-after-timeout", DefaultS3StorageServiceConfig.DiscardAfterTimeout, "discard data after its expiry timeout")
+	f.String(prefix+".credentials-source", DefaultS3StorageServiceConfig.CredentialsSource, "where to get S3 credentials from: \"static\" for the access and secret keys, \"default\" for the AWS default chain (environment, shared config, web identity, or instance role), or \"auto\" for the keys if set and otherwise the default chain")
+	f.String(prefix+".profile", DefaultS3StorageServiceConfig.Profile, "shared config profile to get S3 credentials and settings from, when not using static credentials")
+	f.String(prefix+".endpoint", DefaultS3StorageServiceConfig.Endpoint, "URL of an S3-compatible service such as MinIO to use instead of AWS S3")
+	f.Bool(prefix+".use-path-style", DefaultS3StorageServiceConfig.UsePathStyle, "address the bucket in the URL path rather than the hostname, as most S3-compatible services require")
+	f.Bool(prefix+".discard-after-timeout", DefaultS3StorageServiceConfig.DiscardAfterTimeout, "discard data after its expiry timeout, by setting it as objects' expiry (a bucket lifecycle rule is needed to delete them)")
 	f.Bool(prefix+".sync-from-storage-service", DefaultRedisConfig.SyncFromStorageService, "enable s3 to be used as a source for regular sync storage")
 	f.Bool(prefix+".sync-to-storage-service", DefaultRedisConfig.SyncToStorageService, "enable s3 to be used as a sink for regular sync storage")
 }
@@ -69,7 +120,10 @@ type S3StorageService struct {
 }
 
 func NewS3StorageService(config S3StorageServiceConfig) (StorageService, error) {
-	client, err := buildS3Client(config.AccessKey, config.SecretKey, config.Region)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	client, err := buildS3Client(&config)
 	if err != nil {
 		return nil, err
 	}
@@ -83,29 +137,45 @@ func NewS3StorageService(config S3StorageServiceConfig) (StorageService, error)
 	}, nil
 }
 
-func buildS3Client(accessKey, secretKey, region string) (*s3.Client, error) {
-	cfg, err := awsConfig.LoadDefaultConfig(context.TODO(), awsConfig.WithRegion(region), func(options *awsConfig.LoadOptions) error {
+func buildS3Client(config *S3StorageServiceConfig) (*s3.Client, error) {
+	loadOptions := []func(*awsConfig.LoadOptions) error{awsConfig.WithRegion(config.Region)}
+	useStatic := config.CredentialsSource == s3CredentialsStatic ||
+		(config.CredentialsSource != s3CredentialsDefault && config.AccessKey != "" && config.SecretKey != "")
+	if useStatic {
 		// remain backward compatible with accessKey and secretKey credentials provided via cli flags
-		if accessKey != "" && secretKey != "" {
-			options.Credentials = credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")
-		}
-		return nil
-	})
+		loadOptions = append(loadOptions, awsConfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(config.AccessKey, config.SecretKey, "")))
+	} else if config.Profile != "" {
+		loadOptions = append(loadOptions, awsConfig.WithSharedConfigProfile(config.Profile))
+	}
+	cfg, err := awsConfig.LoadDefaultConfig(context.TODO(), loadOptions...)
 	if err != nil {
 		return nil, err
 	}
-	return s3.NewFromConfig(cfg), nil
+	return s3.NewFromConfig(cfg, func(options *s3.Options) {
+		if config.Endpoint != "" {
+			options.EndpointResolver = s3.EndpointResolverFromURL(config.Endpoint)
+		}
+		options.UsePathStyle = config.UsePathStyle
+	}), nil
 }
 
 func (s3s *S3StorageService) GetByHash(ctx context.Context, key common.Hash) ([]byte, error) {
 	log.Trace("das.S3StorageService.GetByHash", "key", pretty.PrettyHash(key), "this", s3s)
 
 	buf := manager.NewWriteAtBuffer([]byte{})
-	_, err := s3s.downloader.Download(ctx, buf, &s3.GetObjectInput{
+	input := &s3.GetObjectInput{
 		Bucket: aws.String(s3s.bucket),
 		Key:    aws.String(s3s.objectPrefix + EncodeStorageServiceKey(key)),
-	})
-	return buf.Bytes(), err
+	}
+	_, err := s3s.downloader.Download(ctx, buf, input)
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 func (s3s *S3StorageService) Put(ctx context.Context, value []byte, timeout uint64) error {
@@ -114,7 +184,7 @@ func (s3s *S3StorageService) Put(ctx context.Context, value []byte, timeout uint
 		Bucket: aws.String(s3s.bucket),
 		Key:    aws.String(s3s.objectPrefix + EncodeStorageServiceKey(dastree.Hash(value))),
 		Body:   bytes.NewReader(value)}
-	if !s3s.discardAfterTimeout {
+	if s3s.discardAfterTimeout {
 		expires := time.Unix(int64(timeout), 0)
 		putObjectInput.Expires = &expires
 	}
@@ -90,3 +90,43 @@ func TestS3StorageService(t *testing.T) {
 		t.Fatal(val, val1)
 	}
 }
+
+func TestS3StorageServiceConfig(t *testing.T) {
+	valid := func(modify func(*S3StorageServiceConfig)) bool {
+		config := DefaultS3StorageServiceConfig
+		config.Enable = true
+		config.Bucket = "das"
+		modify(&config)
+		return config.Validate() == nil
+	}
+	if !valid(func(c *S3StorageServiceConfig) {}) {
+		t.Fatal("expected default credentials to be valid")
+	}
+	if valid(func(c *S3StorageServiceConfig) { c.Bucket = "" }) {
+		t.Fatal("expected a bucket to be required")
+	}
+	if valid(func(c *S3StorageServiceConfig) { c.CredentialsSource = s3CredentialsStatic }) {
+		t.Fatal("expected static credentials to require keys")
+	}
+	if !valid(func(c *S3StorageServiceConfig) {
+		c.CredentialsSource = s3CredentialsStatic
+		c.AccessKey = "access"
+		c.SecretKey = "secret"
+		c.Endpoint = "http://localhost:9000"
+		c.UsePathStyle = true
+	}) {
+		t.Fatal("expected static credentials for a MinIO endpoint to be valid")
+	}
+	if valid(func(c *S3StorageServiceConfig) {
+		c.CredentialsSource = s3CredentialsDefault
+		c.AccessKey = "access"
+	}) {
+		t.Fatal("expected keys to be rejected with default credentials")
+	}
+	if valid(func(c *S3StorageServiceConfig) { c.CredentialsSource = "vault" }) {
+		t.Fatal("expected an unknown credentials source to be rejected")
+	}
+	if valid(func(c *S3StorageServiceConfig) { c.Endpoint = "localhost" }) {
+		t.Fatal("expected an endpoint without a scheme to be rejected")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/offchainlabs/nitro/arbos/arbosState"
 	"github.com/offchainlabs/nitro/arbos/retryables"
 	"github.com/offchainlabs/nitro/util/arbmath"
+	"github.com/offchainlabs/nitro/util/latencytracker"
 )
 
 type ArbAPI struct {
@@ -35,6 +36,49 @@ func (a *ArbAPI) CheckPublisherHealth(ctx context.Context) error {
 	return a.txPublisher.CheckHealth(ctx)
 }
 
+// MessageLatencies returns percentiles of the time, in milliseconds, messages spent between pipeline stages
+func (a *ArbAPI) MessageLatencies(ctx context.Context) map[string]latencytracker.Percentiles {
+	return latencytracker.Summary()
+}
+
+type ArbReplicaBusAPI struct {
+	replicaBus *ReplicaBus
+}
+
+func NewArbReplicaBusAPI(replicaBus *ReplicaBus) *ArbReplicaBusAPI {
+	return &ArbReplicaBusAPI{replicaBus}
+}
+
+func (a *ArbReplicaBusAPI) ReplicaBusStatus() ReplicaBusStatus {
+	return a.replicaBus.Status()
+}
+
+// WaitForFleetHead lets a load balancer hold a request until this replica has caught up to the fleet
+func (a *ArbReplicaBusAPI) WaitForFleetHead(ctx context.Context) bool {
+	return a.replicaBus.WaitForFleetHead(ctx)
+}
+
+type ArbSequencerAPI struct {
+	sequencer *Sequencer
+}
+
+func NewArbSequencerAPI(sequencer *Sequencer) *ArbSequencerAPI {
+	return &ArbSequencerAPI{sequencer}
+}
+
+// PauseSequencer stops block production while still accepting transactions into the queue
+func (a *ArbSequencerAPI) PauseSequencer() {
+	a.sequencer.PauseSequencing()
+}
+
+func (a *ArbSequencerAPI) ResumeSequencer() {
+	a.sequencer.ResumeSequencing()
+}
+
+func (a *ArbSequencerAPI) SequencerPaused() bool {
+	return a.sequencer.SequencingPaused()
+}
+
 type ArbDebugAPI struct {
 	blockchain        *core.BlockChain
 	blockRangeBound   uint64
@@ -263,8 +307,37 @@ func (api *ArbDebugAPI) TimeoutQueue(ctx context.Context, blockNum rpc.BlockNumb
 	return queue, err
 }
 
+type ScheduledArbOSUpgrade struct {
+	BlockNumber    uint64 `json:"blockNumber"`
+	CurrentVersion uint64 `json:"currentVersion"`
+	Version        uint64 `json:"version"`
+	Timestamp      uint64 `json:"timestamp"`
+	Pending        bool   `json:"pending"` // whether the upgrade is yet to take effect
+}
+
+// ScheduledArbOSUpgrade returns the ArbOS upgrade scheduled by the chain owner as of the block
+func (api *ArbDebugAPI) ScheduledArbOSUpgrade(ctx context.Context, blockNum rpc.BlockNumber) (ScheduledArbOSUpgrade, error) {
+	blockNum, _ = api.blockchain.ClipToPostNitroGenesis(blockNum)
+
+	upgrade := ScheduledArbOSUpgrade{BlockNumber: uint64(blockNum)}
+	state, _, err := stateAndHeader(api.blockchain, uint64(blockNum))
+	if err != nil {
+		return upgrade, err
+	}
+	upgrade.CurrentVersion = state.ArbOSVersion()
+	upgrade.Version, upgrade.Timestamp, err = state.GetScheduledUpgrade()
+	if err != nil {
+		return upgrade, err
+	}
+	upgrade.Pending = upgrade.Version > upgrade.CurrentVersion
+	return upgrade, nil
+}
+
 func stateAndHeader(blockchain *core.BlockChain, block uint64) (*arbosState.ArbosState, *types.Header, error) {
 	header := blockchain.GetHeaderByNumber(block)
+	if header == nil {
+		return nil, nil, fmt.Errorf("block %v not found", block)
+	}
 	if !blockchain.Config().IsArbitrumNitro(header.Number) {
 		return nil, nil, types.ErrUseFallback
 	}
@@ -0,0 +1,192 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+
+	"github.com/offchainlabs/nitro/arbos/util"
+)
+
+// BalanceTransferTracerName is the tracer to pass to debug_traceTransaction and related methods
+// to get every balance transfer ArbOS made in a transaction, such as deposits, fee payments,
+// refunds, and retryable escrow, with its purpose.
+const BalanceTransferTracerName = "arbBalanceTransferTracer"
+
+func init() {
+	tracers.DefaultDirectory.Register(BalanceTransferTracerName, newBalanceTransferTracer, false)
+}
+
+var _ util.BalanceTransferTracer = (*balanceTransferTracer)(nil)
+
+type BalanceTransfer struct {
+	// From is unset when funds are minted, and To when they're burnt
+	From    *common.Address `json:"from,omitempty"`
+	To      *common.Address `json:"to,omitempty"`
+	Value   *hexutil.Big    `json:"value"`
+	Purpose string          `json:"purpose"`
+	// Phase is whether the transfer was made before, during, or after EVM execution
+	Phase string `json:"phase"`
+	// Reverted is set when the transfer was made during EVM execution in a call that later reverted
+	Reverted bool `json:"reverted,omitempty"`
+}
+
+type BalanceTransferTrace struct {
+	Transfers []*BalanceTransfer `json:"transfers"`
+}
+
+type balanceTransferTracerConfig struct {
+	// Purposes are the transfer purposes to trace, or all if empty
+	Purposes []string `json:"purposes"`
+	// ExcludeReverted leaves out transfers undone by a revert
+	ExcludeReverted bool `json:"excludeReverted"`
+}
+
+type balanceTransferTracer struct {
+	config    balanceTransferTracerConfig
+	purposes  map[string]bool
+	transfers []*BalanceTransfer
+	// frames holds the indices of the transfers made in each open call frame
+	frames    [][]int
+	interrupt atomic.Bool
+	reason    error
+}
+
+func newBalanceTransferTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config balanceTransferTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	purposes := make(map[string]bool, len(config.Purposes))
+	for _, purpose := range config.Purposes {
+		purposes[purpose] = true
+	}
+	return &balanceTransferTracer{
+		config:   config,
+		purposes: purposes,
+	}, nil
+}
+
+func tracingScenarioPhase(scenario util.TracingScenario) string {
+	switch scenario {
+	case util.TracingBeforeEVM:
+		return "beforeEVM"
+	case util.TracingDuringEVM:
+		return "duringEVM"
+	default:
+		return "afterEVM"
+	}
+}
+
+func (t *balanceTransferTracer) CaptureArbOSBalanceTransfer(from, to *common.Address, amount *big.Int, scenario util.TracingScenario, purpose string) {
+	if t.interrupt.Load() {
+		return
+	}
+	if len(t.purposes) > 0 && !t.purposes[purpose] {
+		return
+	}
+	transfer := &BalanceTransfer{
+		Value:   (*hexutil.Big)(new(big.Int).Set(amount)),
+		Purpose: purpose,
+		Phase:   tracingScenarioPhase(scenario),
+	}
+	if from != nil {
+		fromCopy := *from
+		transfer.From = &fromCopy
+	}
+	if to != nil {
+		toCopy := *to
+		transfer.To = &toCopy
+	}
+	if scenario == util.TracingDuringEVM && len(t.frames) > 0 {
+		last := len(t.frames) - 1
+		t.frames[last] = append(t.frames[last], len(t.transfers))
+	}
+	t.transfers = append(t.transfers, transfer)
+}
+
+func (t *balanceTransferTracer) enterFrame() {
+	t.frames = append(t.frames, nil)
+}
+
+// exitFrame marks the transfers made in the frame as reverted if it failed, or passes them on to its parent
+func (t *balanceTransferTracer) exitFrame(err error) {
+	if len(t.frames) == 0 {
+		return
+	}
+	last := len(t.frames) - 1
+	frame := t.frames[last]
+	t.frames = t.frames[:last]
+	if err != nil {
+		for _, index := range frame {
+			t.transfers[index].Reverted = true
+		}
+	} else if last > 0 {
+		t.frames[last-1] = append(t.frames[last-1], frame...)
+	}
+}
+
+func (t *balanceTransferTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *balanceTransferTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *balanceTransferTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.enterFrame()
+}
+
+func (t *balanceTransferTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.exitFrame(err)
+}
+
+func (t *balanceTransferTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.enterFrame()
+}
+
+func (t *balanceTransferTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.exitFrame(err)
+}
+
+func (t *balanceTransferTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+func (t *balanceTransferTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// ArbOS transfers outside of EVM execution are recorded by CaptureArbOSBalanceTransfer instead
+func (t *balanceTransferTracer) CaptureArbitrumTransfer(env *vm.EVM, from, to *common.Address, value *big.Int, before bool, purpose string) {
+}
+
+func (t *balanceTransferTracer) CaptureArbitrumStorageGet(key common.Hash, depth int, before bool) {}
+
+func (t *balanceTransferTracer) CaptureArbitrumStorageSet(key, value common.Hash, depth int, before bool) {
+}
+
+func (t *balanceTransferTracer) GetResult() (json.RawMessage, error) {
+	trace := BalanceTransferTrace{Transfers: []*BalanceTransfer{}}
+	for _, transfer := range t.transfers {
+		if t.config.ExcludeReverted && transfer.Reverted {
+			continue
+		}
+		trace.Transfers = append(trace.Transfers, transfer)
+	}
+	result, err := json.Marshal(trace)
+	if err != nil {
+		return nil, err
+	}
+	return result, t.reason
+}
+
+func (t *balanceTransferTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
@@ -0,0 +1,181 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbos"
+	"github.com/offchainlabs/nitro/arbos/arbosState"
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbos/l1pricing"
+)
+
+// BlockParams are the inputs to a block besides its transactions, as a batch poster would set them.
+// Any left unset default to the parent block's values, and the current L1 price for the base fee.
+type BlockParams struct {
+	Timestamp     *uint64
+	L1BlockNumber *uint64
+	L1BaseFee     *big.Int
+	Poster        *common.Address
+}
+
+// BuildBlock executes the given transactions, in order, in a block on top of parent, without changing
+// the chain. Invalid transactions are left out of the block, and the returned errors hold the reason
+// each transaction was left out, or nil if it was included.
+func BuildBlock(bc *core.BlockChain, parent *types.Header, blockParams BlockParams, txes types.Transactions) (*types.Block, types.Receipts, []error, error) {
+	statedb, err := bc.StateAt(parent.Root)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	l1Header := &arbostypes.L1IncomingMessageHeader{
+		Kind:        arbostypes.L1MessageType_L2Message,
+		Poster:      l1pricing.BatchPosterAddress,
+		BlockNumber: types.DeserializeHeaderExtraInformation(parent).L1BlockNumber,
+		Timestamp:   parent.Time,
+		L1BaseFee:   blockParams.L1BaseFee,
+	}
+	if blockParams.Poster != nil {
+		l1Header.Poster = *blockParams.Poster
+	}
+	if blockParams.L1BlockNumber != nil {
+		l1Header.BlockNumber = *blockParams.L1BlockNumber
+	}
+	if blockParams.Timestamp != nil {
+		l1Header.Timestamp = *blockParams.Timestamp
+	}
+	if l1Header.L1BaseFee == nil {
+		state, err := arbosState.OpenSystemArbosState(statedb, nil, true)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		l1Header.L1BaseFee, err = state.L1PricingState().PricePerUnit()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	hooks := arbos.NoopSequencingHooks()
+	block, receipts, err := arbos.ProduceBlockAdvanced(l1Header, txes, parent.Nonce.Uint64(), parent, statedb, bc, bc.Config(), hooks)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return block, receipts, hooks.TxErrors, nil
+}
+
+type BuildBlockArgs struct {
+	// Parent defaults to the latest block
+	Parent *rpc.BlockNumberOrHash `json:"parent"`
+	// Transactions are signed and binary encoded, as for eth_sendRawTransaction
+	Transactions  []hexutil.Bytes `json:"transactions"`
+	Timestamp     *hexutil.Uint64 `json:"timestamp"`
+	L1BlockNumber *hexutil.Uint64 `json:"l1BlockNumber"`
+	L1BaseFee     *hexutil.Big    `json:"l1BaseFee"`
+	Poster        *common.Address `json:"poster"`
+}
+
+type BuiltTransaction struct {
+	Hash     common.Hash `json:"hash"`
+	Included bool        `json:"included"`
+	// Error is why the transaction was left out of the block
+	Error   string         `json:"error,omitempty"`
+	Status  hexutil.Uint64 `json:"status"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Logs    []*types.Log   `json:"logs"`
+}
+
+type BuildBlockResult struct {
+	Hash      common.Hash    `json:"hash"`
+	Number    hexutil.Uint64 `json:"number"`
+	Timestamp hexutil.Uint64 `json:"timestamp"`
+	StateRoot common.Hash    `json:"stateRoot"`
+	GasUsed   hexutil.Uint64 `json:"gasUsed"`
+	// Transactions are in the order given, and don't include the block's internal transactions or redeems
+	Transactions []*BuiltTransaction `json:"transactions"`
+}
+
+func (a *ArbSimulationAPI) parentHeader(parent *rpc.BlockNumberOrHash) (*types.Header, error) {
+	bc := a.execEngine.bc
+	var header *types.Header
+	if parent == nil {
+		header = bc.CurrentBlock()
+	} else if hash, ok := parent.Hash(); ok {
+		header = bc.GetHeaderByHash(hash)
+	} else if number, ok := parent.Number(); ok {
+		if number < 0 {
+			header = bc.CurrentBlock()
+		} else {
+			header = bc.GetHeaderByNumber(uint64(number))
+		}
+	}
+	if header == nil {
+		return nil, errors.New("parent block not found")
+	}
+	return header, nil
+}
+
+// BuildBlock executes the given transactions, in order, in a block on top of the parent block, without
+// changing the chain, for deterministic tests of transaction ordering.
+func (a *ArbSimulationAPI) BuildBlock(ctx context.Context, args BuildBlockArgs) (*BuildBlockResult, error) {
+	if maxTxs := a.config().MaxBuildBlockTxs; len(args.Transactions) > maxTxs {
+		return nil, fmt.Errorf("%v transactions exceeds the limit of %v", len(args.Transactions), maxTxs)
+	}
+	parent, err := a.parentHeader(args.Parent)
+	if err != nil {
+		return nil, err
+	}
+	var txes types.Transactions
+	for i, encoded := range args.Transactions {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(encoded); err != nil {
+			return nil, fmt.Errorf("failed to decode transaction %v: %w", i, err)
+		}
+		txes = append(txes, tx)
+	}
+	blockParams := BlockParams{
+		Timestamp:     (*uint64)(args.Timestamp),
+		L1BlockNumber: (*uint64)(args.L1BlockNumber),
+		L1BaseFee:     (*big.Int)(args.L1BaseFee),
+		Poster:        args.Poster,
+	}
+	block, receipts, txErrors, err := BuildBlock(a.execEngine.bc, parent, blockParams, txes)
+	if err != nil {
+		return nil, err
+	}
+	if len(txErrors) != len(txes) {
+		return nil, fmt.Errorf("got %v transaction results for %v transactions", len(txErrors), len(txes))
+	}
+	receiptsByHash := make(map[common.Hash]*types.Receipt, len(receipts))
+	for _, receipt := range receipts {
+		receiptsByHash[receipt.TxHash] = receipt
+	}
+	result := &BuildBlockResult{
+		Hash:      block.Hash(),
+		Number:    hexutil.Uint64(block.NumberU64()),
+		Timestamp: hexutil.Uint64(block.Time()),
+		StateRoot: block.Root(),
+		GasUsed:   hexutil.Uint64(block.GasUsed()),
+	}
+	for i, tx := range txes {
+		built := &BuiltTransaction{Hash: tx.Hash()}
+		if txErrors[i] != nil {
+			built.Error = txErrors[i].Error()
+		} else if receipt, ok := receiptsByHash[tx.Hash()]; ok {
+			built.Included = true
+			built.Status = hexutil.Uint64(receipt.Status)
+			built.GasUsed = hexutil.Uint64(receipt.GasUsed)
+			built.Logs = receipt.Logs
+		}
+		result.Transactions = append(result.Transactions, built)
+	}
+	return result, nil
+}
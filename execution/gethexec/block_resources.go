@@ -0,0 +1,227 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	blockResourcesExecutionTimer = metrics.NewRegisteredTimer("arb/block/resources/execution", nil)
+	blockResourcesStateWrites    = metrics.NewRegisteredHistogram("arb/block/resources/statewrites", nil, metrics.NewBoundedHistogramSample())
+	blockResourcesL1DataBytes    = metrics.NewRegisteredHistogram("arb/block/resources/l1databytes", nil, metrics.NewBoundedHistogramSample())
+)
+
+// maps a block number to the BlockResourceUsage recorded when this node executed it
+var blockResourcesPrefix = []byte("arbBlockResources")
+
+const maxBlockResourcesRange = 10_000
+
+type BlockResourcesConfig struct {
+	Enable    bool   `koanf:"enable"`
+	Retention uint64 `koanf:"retention"`
+}
+
+var DefaultBlockResourcesConfig = BlockResourcesConfig{
+	Enable:    false,
+	Retention: 1_000_000,
+}
+
+func BlockResourcesConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultBlockResourcesConfig.Enable, "record the execution time, state accesses and L1 data size of each block this node executes, queryable with arb_blockResourceUsage")
+	f.Uint64(prefix+".retention", DefaultBlockResourcesConfig.Retention, "number of recent blocks to keep the resource usage of (0 = all blocks)")
+}
+
+// BlockResourceUsage is what executing a block cost this node. It's measured locally rather than being part
+// of the chain, so it depends on the node's hardware and load, and is only recorded for blocks the node executed.
+type BlockResourceUsage struct {
+	BlockNumber   hexutil.Uint64 `json:"blockNumber"`
+	BlockHash     common.Hash    `json:"blockHash"`
+	ExecutionTime hexutil.Uint64 `json:"executionTimeNs"`
+	TxCount       hexutil.Uint64 `json:"txCount"`
+	GasUsed       hexutil.Uint64 `json:"gasUsed"`
+	GasUsedForL1  hexutil.Uint64 `json:"gasUsedForL1"`
+	// L1DataBytes is the size of the block's transactions as posted to the parent chain, before compression
+	L1DataBytes    hexutil.Uint64 `json:"l1DataBytes"`
+	AccountWrites  hexutil.Uint64 `json:"accountWrites"`
+	StorageWrites  hexutil.Uint64 `json:"storageWrites"`
+	AccountDeletes hexutil.Uint64 `json:"accountDeletes"`
+	StorageDeletes hexutil.Uint64 `json:"storageDeletes"`
+	// StateReadTime is the time spent reading accounts and storage, which geth only measures with expensive metrics enabled
+	StateReadTime hexutil.Uint64 `json:"stateReadTimeNs"`
+}
+
+func measureBlockResources(block *types.Block, statedb *state.StateDB, receipts types.Receipts, duration time.Duration) *BlockResourceUsage {
+	usage := &BlockResourceUsage{
+		BlockNumber:    hexutil.Uint64(block.NumberU64()),
+		BlockHash:      block.Hash(),
+		ExecutionTime:  hexutil.Uint64(duration.Nanoseconds()),
+		TxCount:        hexutil.Uint64(len(block.Transactions())),
+		GasUsed:        hexutil.Uint64(block.GasUsed()),
+		AccountWrites:  hexutil.Uint64(statedb.AccountUpdated),
+		StorageWrites:  hexutil.Uint64(statedb.StorageUpdated),
+		AccountDeletes: hexutil.Uint64(statedb.AccountDeleted),
+		StorageDeletes: hexutil.Uint64(statedb.StorageDeleted),
+		StateReadTime:  hexutil.Uint64((statedb.AccountReads + statedb.StorageReads).Nanoseconds()),
+	}
+	for _, receipt := range receipts {
+		usage.GasUsedForL1 += hexutil.Uint64(receipt.GasUsedForL1)
+	}
+	for _, tx := range block.Transactions() {
+		// Arbitrum's own transaction types come from the parent chain or ArbOS, and aren't posted in batches
+		if tx.Type() < types.ArbitrumDepositTxType {
+			usage.L1DataBytes += hexutil.Uint64(tx.Size())
+		}
+	}
+	return usage
+}
+
+type blockResourcesIndex struct {
+	db        ethdb.KeyValueStore
+	retention uint64
+}
+
+func newBlockResourcesIndex(db ethdb.KeyValueStore, config *BlockResourcesConfig) *blockResourcesIndex {
+	return &blockResourcesIndex{
+		db:        db,
+		retention: config.Retention,
+	}
+}
+
+func blockResourcesKey(number uint64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, blockResourcesPrefix...), number)
+}
+
+// record stores the block's usage, replacing that of any block it reorged out, and prunes past the retention
+func (i *blockResourcesIndex) record(usage *BlockResourceUsage) {
+	blockResourcesExecutionTimer.Update(time.Duration(usage.ExecutionTime))
+	blockResourcesStateWrites.Update(int64(usage.AccountWrites + usage.StorageWrites + usage.AccountDeletes + usage.StorageDeletes))
+	blockResourcesL1DataBytes.Update(int64(usage.L1DataBytes))
+
+	data, err := rlp.EncodeToBytes(usage)
+	if err != nil {
+		log.Warn("error encoding block resource usage", "block", usage.BlockNumber, "err", err)
+		return
+	}
+	batch := i.db.NewBatch()
+	if err := batch.Put(blockResourcesKey(uint64(usage.BlockNumber)), data); err != nil {
+		log.Warn("error recording block resource usage", "block", usage.BlockNumber, "err", err)
+		return
+	}
+	if i.retention > 0 && uint64(usage.BlockNumber) >= i.retention {
+		if err := batch.Delete(blockResourcesKey(uint64(usage.BlockNumber) - i.retention)); err != nil {
+			log.Warn("error pruning block resource usage", "block", usage.BlockNumber, "err", err)
+			return
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Warn("error recording block resource usage", "block", usage.BlockNumber, "err", err)
+	}
+}
+
+func (i *blockResourcesIndex) get(number uint64) (*BlockResourceUsage, error) {
+	key := blockResourcesKey(number)
+	has, err := i.db.Has(key)
+	if err != nil || !has {
+		return nil, err
+	}
+	data, err := i.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	var usage BlockResourceUsage
+	if err := rlp.DecodeBytes(data, &usage); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+type ArbBlockResourcesAPI struct {
+	blockchain *core.BlockChain
+	index      *blockResourcesIndex
+}
+
+func NewArbBlockResourcesAPI(blockchain *core.BlockChain, index *blockResourcesIndex) *ArbBlockResourcesAPI {
+	return &ArbBlockResourcesAPI{blockchain, index}
+}
+
+func (a *ArbBlockResourcesAPI) resolveBlockNumber(number rpc.BlockNumber) (uint64, error) {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return a.blockchain.CurrentBlock().Number.Uint64(), nil
+	}
+	if number < 0 {
+		return 0, fmt.Errorf("unsupported block number %v", number)
+	}
+	return uint64(number), nil
+}
+
+// canonicalUsage returns the usage recorded for a block, or nil if it wasn't recorded for the canonical block
+func (a *ArbBlockResourcesAPI) canonicalUsage(number uint64) (*BlockResourceUsage, error) {
+	usage, err := a.index.get(number)
+	if err != nil || usage == nil {
+		return nil, err
+	}
+	if a.blockchain.GetCanonicalHash(number) != usage.BlockHash {
+		return nil, nil
+	}
+	return usage, nil
+}
+
+// BlockResourceUsage returns what executing a block cost this node, or nil if the node didn't record it
+func (a *ArbBlockResourcesAPI) BlockResourceUsage(ctx context.Context, number rpc.BlockNumber) (*BlockResourceUsage, error) {
+	block, err := a.resolveBlockNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	return a.canonicalUsage(block)
+}
+
+// BlockResourceUsageRange returns the recorded usage of the blocks in a range, skipping those without a record
+func (a *ArbBlockResourcesAPI) BlockResourceUsageRange(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) ([]*BlockResourceUsage, error) {
+	from, err := a.resolveBlockNumber(fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := a.resolveBlockNumber(toBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("fromBlock %v is after toBlock %v", from, to)
+	}
+	if to-from >= maxBlockResourcesRange {
+		return nil, errors.New("block range too large")
+	}
+	usages := []*BlockResourceUsage{}
+	for number := from; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		usage, err := a.canonicalUsage(number)
+		if err != nil {
+			return nil, err
+		}
+		if usage != nil {
+			usages = append(usages, usage)
+		}
+	}
+	return usages, nil
+}
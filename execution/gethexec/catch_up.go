@@ -0,0 +1,188 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+var (
+	catchUpSpeculativeCommittedCounter = metrics.NewRegisteredCounter("arb/execution/catchup/speculative/committed", nil)
+	catchUpSpeculativeDiscardedCounter = metrics.NewRegisteredCounter("arb/execution/catchup/speculative/discarded", nil)
+	catchUpSpeculativeFailedCounter    = metrics.NewRegisteredCounter("arb/execution/catchup/speculative/failed", nil)
+)
+
+type CatchUpConfig struct {
+	Enable    bool `koanf:"enable"`
+	Lookahead int  `koanf:"lookahead"`
+	Workers   int  `koanf:"workers"`
+}
+
+var DefaultCatchUpConfig = CatchUpConfig{
+	Enable:    false,
+	Lookahead: 8,
+	Workers:   4,
+}
+
+func CatchUpConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultCatchUpConfig.Enable, "when behind on messages, execute the next block speculatively while the current one is written, and prefetch the state of those after it in parallel")
+	f.Int(prefix+".lookahead", DefaultCatchUpConfig.Lookahead, "number of upcoming messages to read ahead of the one being executed")
+	f.Int(prefix+".workers", DefaultCatchUpConfig.Workers, "number of parallel workers prefetching the state of upcoming messages")
+}
+
+func (c *CatchUpConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.Lookahead < 1 {
+		return errors.New("catch-up lookahead must be at least 1")
+	}
+	if c.Workers < 0 {
+		return errors.New("catch-up workers must not be negative")
+	}
+	return nil
+}
+
+// speculativeBlock is the next block, executed on top of the in-memory result of the block being
+// written before that block was committed. It's only used if the block it was built on became the
+// head, and the message at its position is still the one it executed.
+type speculativeBlock struct {
+	num        arbutil.MessageIndex
+	msgHash    common.Hash
+	parentHash common.Hash
+
+	done     chan struct{}
+	block    *types.Block
+	statedb  *state.StateDB
+	receipts types.Receipts
+	duration time.Duration
+	err      error
+}
+
+// speculativeChainContext serves the header of the uncommitted parent block, so the speculative
+// block sees the same block hashes as it would once the parent is written
+type speculativeChainContext struct {
+	bc     *core.BlockChain
+	parent *types.Header
+}
+
+func (c *speculativeChainContext) Engine() consensus.Engine {
+	return c.bc.Engine()
+}
+
+func (c *speculativeChainContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if hash == c.parent.Hash() && number == c.parent.Number.Uint64() {
+		return c.parent
+	}
+	return c.bc.GetHeader(hash, number)
+}
+
+func (s *ExecutionEngine) EnableCatchUp(config CatchUpConfig) {
+	if s.Started() {
+		panic("trying to enable catch-up after start")
+	}
+	if s.catchUp != nil {
+		panic("trying to enable catch-up when already set")
+	}
+	s.catchUp = &config
+	s.catchUpWorkers = make(chan struct{}, config.Workers)
+}
+
+// CatchUpLookahead returns how many upcoming messages are useful to DigestMessage
+func (s *ExecutionEngine) CatchUpLookahead() int {
+	if s.catchUp == nil {
+		if s.prefetchBlock {
+			return 1
+		}
+		return 0
+	}
+	return s.catchUp.Lookahead
+}
+
+func (s *ExecutionEngine) messageHash(num arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata) (common.Hash, error) {
+	return msg.Hash(num, s.bc.Config().ChainID.Uint64())
+}
+
+// takeSpeculativeBlock returns the speculative block for the message if it's still valid, waiting for it to
+// finish executing. Must hold createBlocksMutex.
+func (s *ExecutionEngine) takeSpeculativeBlock(num arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata, head *types.Header) *speculativeBlock {
+	spec := s.speculative
+	s.speculative = nil
+	if spec == nil {
+		return nil
+	}
+	msgHash, err := s.messageHash(num, msg)
+	if err != nil || spec.num != num || spec.msgHash != msgHash || spec.parentHash != head.Hash() {
+		log.Debug("discarding speculative block", "num", num, "speculativeNum", spec.num)
+		catchUpSpeculativeDiscardedCounter.Inc(1)
+		return nil
+	}
+	<-spec.done
+	if spec.err != nil {
+		log.Debug("speculative block failed, executing serially", "num", num, "err", spec.err)
+		catchUpSpeculativeFailedCounter.Inc(1)
+		return nil
+	}
+	catchUpSpeculativeCommittedCounter.Inc(1)
+	return spec
+}
+
+// speculate starts executing the message after the block about to be written, on a copy of its state, and
+// prefetches the state of the messages after that. Must hold createBlocksMutex, and be called before the
+// block's state is committed.
+func (s *ExecutionEngine) speculate(num arbutil.MessageIndex, parent *types.Block, parentState *state.StateDB, lookahead []*arbostypes.MessageWithMetadata) {
+	if len(lookahead) == 0 {
+		return
+	}
+	msgHash, err := s.messageHash(num+1, lookahead[0])
+	if err != nil {
+		return
+	}
+	spec := &speculativeBlock{
+		num:        num + 1,
+		msgHash:    msgHash,
+		parentHash: parent.Hash(),
+		done:       make(chan struct{}),
+	}
+	s.speculative = spec
+	specState := parentState.Copy()
+	chainContext := &speculativeChainContext{s.bc, parent.Header()}
+	go func() {
+		defer close(spec.done)
+		startTime := time.Now()
+		spec.block, spec.receipts, spec.err = s.produceBlock(lookahead[0], parent.Header(), specState, chainContext)
+		spec.statedb = specState
+		spec.duration = time.Since(startTime)
+	}()
+
+	// The messages after the next can't be executed on the right state yet, but executing them on the
+	// speculative parent's state loads most of what they'll touch into the caches
+	for _, msg := range lookahead[1:] {
+		select {
+		case s.catchUpWorkers <- struct{}{}:
+		default:
+			// all workers are still busy with earlier messages
+			return
+		}
+		msg := msg
+		prefetchState := parentState.Copy()
+		go func() {
+			defer func() { <-s.catchUpWorkers }()
+			_, _, _ = s.produceBlock(msg, parent.Header(), prefetchState, chainContext)
+		}()
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbos"
+	"github.com/offchainlabs/nitro/arbos/arbosState"
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbos/retryables"
+	"github.com/offchainlabs/nitro/arbos/util"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+const (
+	DelayedMessageDeposit   = "deposit"
+	DelayedMessageRetryable = "retryable"
+)
+
+// DelayedMessageSimulationArgs describes an L1 to L2 message as it would be sent to the parent chain's inbox
+type DelayedMessageSimulationArgs struct {
+	Kind string `json:"kind"`
+	// From is the L1 sender, which is aliased as the inbox does
+	From common.Address  `json:"from"`
+	To   *common.Address `json:"to"`
+	// Value is the amount deposited for a deposit, or the L2 call value for a retryable
+	Value *hexutil.Big `json:"value"`
+	// The following only apply to retryables, and default to what's needed for the retryable to be auto-redeemed
+	Deposit                *hexutil.Big    `json:"deposit"`
+	MaxSubmissionFee       *hexutil.Big    `json:"maxSubmissionFee"`
+	ExcessFeeRefundAddress *common.Address `json:"excessFeeRefundAddress"`
+	CallValueRefundAddress *common.Address `json:"callValueRefundAddress"`
+	GasLimit               hexutil.Uint64  `json:"gasLimit"`
+	MaxFeePerGas           *hexutil.Big    `json:"maxFeePerGas"`
+	Data                   hexutil.Bytes   `json:"data"`
+}
+
+type SimulatedTransaction struct {
+	Hash    common.Hash     `json:"hash"`
+	Type    hexutil.Uint64  `json:"type"`
+	From    common.Address  `json:"from"`
+	To      *common.Address `json:"to"`
+	Value   *hexutil.Big    `json:"value"`
+	Status  hexutil.Uint64  `json:"status"`
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Logs    []*types.Log    `json:"logs"`
+}
+
+type BalanceChange struct {
+	Before *hexutil.Big `json:"before"`
+	After  *hexutil.Big `json:"after"`
+}
+
+type DelayedMessageSimulationResult struct {
+	// Sender is the aliased L2 address the message is sent from
+	Sender       common.Address          `json:"sender"`
+	Transactions []*SimulatedTransaction `json:"transactions"`
+	// TicketId is the retryable's id, assuming the message is the next one read from the delayed inbox
+	TicketId *common.Hash `json:"ticketId,omitempty"`
+	// AutoRedeem is the retryable's automatic redeem attempt, if one was scheduled
+	AutoRedeem *SimulatedTransaction `json:"autoRedeem,omitempty"`
+	// TicketOutstanding is set if the retryable still has to be redeemed manually after the auto-redeem
+	TicketOutstanding bool                             `json:"ticketOutstanding"`
+	Balances          map[common.Address]BalanceChange `json:"balances"`
+}
+
+type SimulationConfig struct {
+	Enable           bool `koanf:"enable"`
+	MaxBuildBlockTxs int  `koanf:"max-build-block-txs" reload:"hot"`
+}
+
+var DefaultSimulationConfig = SimulationConfig{
+	Enable:           false,
+	MaxBuildBlockTxs: 100,
+}
+
+func SimulationConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultSimulationConfig.Enable, "serve arb_simulateDelayedMessage and arb_buildBlock, which execute messages and transactions on top of the chain without changing it")
+	f.Int(prefix+".max-build-block-txs", DefaultSimulationConfig.MaxBuildBlockTxs, "maximum number of transactions in a block built by arb_buildBlock")
+}
+
+func (c *SimulationConfig) Validate() error {
+	if c.MaxBuildBlockTxs <= 0 {
+		return errors.New("simulation max-build-block-txs must be positive")
+	}
+	return nil
+}
+
+type ArbSimulationAPI struct {
+	execEngine *ExecutionEngine
+	config     func() *SimulationConfig
+}
+
+func NewArbSimulationAPI(execEngine *ExecutionEngine, config func() *SimulationConfig) *ArbSimulationAPI {
+	return &ArbSimulationAPI{execEngine, config}
+}
+
+// SimulateDelayedMessage applies a hypothetical L1 to L2 message on top of the current head,
+// without changing the chain, and reports its effects on the L2.
+func (a *ArbSimulationAPI) SimulateDelayedMessage(ctx context.Context, args DelayedMessageSimulationArgs) (*DelayedMessageSimulationResult, error) {
+	bc := a.execEngine.bc
+	header := bc.CurrentBlock()
+	if header == nil {
+		return nil, errors.New("failed to get current block header")
+	}
+	statedb, err := bc.StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	state, err := arbosState.OpenSystemArbosState(statedb, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	l1BaseFee, err := state.L1PricingState().PricePerUnit()
+	if err != nil {
+		return nil, err
+	}
+
+	sender := util.RemapL1Address(args.From)
+	delayedMessagesRead := header.Nonce.Uint64()
+	requestId := common.BigToHash(new(big.Int).SetUint64(delayedMessagesRead))
+	message := &arbostypes.L1IncomingMessage{
+		Header: &arbostypes.L1IncomingMessageHeader{
+			Poster:      sender,
+			BlockNumber: types.DeserializeHeaderExtraInformation(header).L1BlockNumber,
+			Timestamp:   header.Time,
+			RequestId:   &requestId,
+			L1BaseFee:   l1BaseFee,
+		},
+	}
+	watched := []common.Address{sender}
+	value := (*big.Int)(args.Value)
+	if value == nil {
+		value = new(big.Int)
+	}
+	switch args.Kind {
+	case DelayedMessageDeposit:
+		// the inbox only aliases the destination of deposits from contracts, so it's taken as given
+		to := args.From
+		if args.To != nil {
+			to = *args.To
+		}
+		message.Header.Kind = arbostypes.L1MessageType_EthDeposit
+		message.L2msg = append(to.Bytes(), arbmath.U256Bytes(value)...)
+		watched = append(watched, to)
+	case DelayedMessageRetryable:
+		var retryTo common.Address
+		if args.To != nil {
+			retryTo = *args.To
+		}
+		excessFeeRefund := args.From
+		if args.ExcessFeeRefundAddress != nil {
+			excessFeeRefund = *args.ExcessFeeRefundAddress
+		}
+		callValueRefund := args.From
+		if args.CallValueRefundAddress != nil {
+			callValueRefund = *args.CallValueRefundAddress
+		}
+		maxSubmissionFee := (*big.Int)(args.MaxSubmissionFee)
+		if maxSubmissionFee == nil {
+			maxSubmissionFee = retryables.RetryableSubmissionFee(len(args.Data), l1BaseFee)
+		}
+		maxFeePerGas := (*big.Int)(args.MaxFeePerGas)
+		if maxFeePerGas == nil {
+			maxFeePerGas = header.BaseFee
+		}
+		deposit := (*big.Int)(args.Deposit)
+		if deposit == nil {
+			deposit = arbmath.BigAdd(arbmath.BigAdd(maxSubmissionFee, value), arbmath.BigMulByUint(maxFeePerGas, uint64(args.GasLimit)))
+		}
+		var l2msg []byte
+		l2msg = append(l2msg, common.LeftPadBytes(retryTo.Bytes(), 32)...)
+		l2msg = append(l2msg, arbmath.U256Bytes(value)...)
+		l2msg = append(l2msg, arbmath.U256Bytes(deposit)...)
+		l2msg = append(l2msg, arbmath.U256Bytes(maxSubmissionFee)...)
+		l2msg = append(l2msg, common.LeftPadBytes(excessFeeRefund.Bytes(), 32)...)
+		l2msg = append(l2msg, common.LeftPadBytes(callValueRefund.Bytes(), 32)...)
+		l2msg = append(l2msg, arbmath.Uint64ToU256Bytes(uint64(args.GasLimit))...)
+		l2msg = append(l2msg, arbmath.U256Bytes(maxFeePerGas)...)
+		l2msg = append(l2msg, arbmath.Uint64ToU256Bytes(uint64(len(args.Data)))...)
+		l2msg = append(l2msg, args.Data...)
+		message.Header.Kind = arbostypes.L1MessageType_SubmitRetryable
+		message.L2msg = l2msg
+		watched = append(watched, retryTo, excessFeeRefund, callValueRefund)
+	default:
+		return nil, fmt.Errorf("unknown delayed message kind \"%v\" (must be %v or %v)", args.Kind, DelayedMessageDeposit, DelayedMessageRetryable)
+	}
+
+	balancesBefore := make(map[common.Address]*big.Int)
+	for _, addr := range watched {
+		balancesBefore[addr] = new(big.Int).Set(statedb.GetBalance(addr))
+	}
+
+	block, receipts, err := arbos.ProduceBlock(
+		message,
+		delayedMessagesRead+1,
+		header,
+		statedb,
+		bc,
+		bc.Config(),
+		func(uint64) ([]byte, error) {
+			return nil, errors.New("simulated delayed messages can't read batches")
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DelayedMessageSimulationResult{
+		Sender:   sender,
+		Balances: make(map[common.Address]BalanceChange),
+	}
+	for i, tx := range block.Transactions() {
+		if tx.Type() == types.ArbitrumInternalTxType {
+			continue
+		}
+		simulated := &SimulatedTransaction{
+			Hash:    tx.Hash(),
+			Type:    hexutil.Uint64(tx.Type()),
+			To:      tx.To(),
+			Value:   (*hexutil.Big)(tx.Value()),
+			Status:  hexutil.Uint64(receipts[i].Status),
+			GasUsed: hexutil.Uint64(receipts[i].GasUsed),
+			Logs:    receipts[i].Logs,
+		}
+		switch inner := tx.GetInner().(type) {
+		case *types.ArbitrumDepositTx:
+			simulated.From = inner.From
+		case *types.ArbitrumSubmitRetryableTx:
+			simulated.From = inner.From
+			ticketId := tx.Hash()
+			result.TicketId = &ticketId
+		case *types.ArbitrumRetryTx:
+			simulated.From = inner.From
+			result.AutoRedeem = simulated
+		}
+		result.Transactions = append(result.Transactions, simulated)
+	}
+	if result.TicketId != nil {
+		state, err := arbosState.OpenSystemArbosState(statedb, nil, true)
+		if err != nil {
+			return nil, err
+		}
+		retryable, err := state.RetryableState().OpenRetryable(*result.TicketId, block.Time())
+		if err != nil {
+			return nil, err
+		}
+		result.TicketOutstanding = retryable != nil
+	}
+	for addr, before := range balancesBefore {
+		result.Balances[addr] = BalanceChange{
+			Before: (*hexutil.Big)(before),
+			After:  (*hexutil.Big)(statedb.GetBalance(addr)),
+		}
+	}
+	return result, nil
+}
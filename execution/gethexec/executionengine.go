@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -43,6 +44,18 @@ type ExecutionEngine struct {
 	reorgSequencing bool
 
 	prefetchBlock bool
+
+	catchUp        *CatchUpConfig
+	catchUpWorkers chan struct{}
+	speculative    *speculativeBlock // protected by the createBlocksMutex
+
+	// freezeAtBlock is the last block to sequence, or 0 to sequence indefinitely
+	freezeAtBlock atomic.Uint64
+
+	sequencedTxSubscriptions sequencedTxSubscriptions
+	retryableEvents          retryableEvents
+
+	blockResources *blockResourcesIndex // nil unless block resource recording is enabled
 }
 
 func NewExecutionEngine(bc *core.BlockChain) (*ExecutionEngine, error) {
@@ -83,6 +96,16 @@ func (s *ExecutionEngine) EnablePrefetchBlock() {
 	s.prefetchBlock = true
 }
 
+func (s *ExecutionEngine) EnableBlockResources(index *blockResourcesIndex) {
+	if s.Started() {
+		panic("trying to enable block resources after start")
+	}
+	if s.blockResources != nil {
+		panic("trying to enable block resources when already set")
+	}
+	s.blockResources = index
+}
+
 func (s *ExecutionEngine) SetTransactionStreamer(streamer execution.TransactionStreamer) {
 	if s.Started() {
 		panic("trying to set transaction streamer after start")
@@ -118,12 +141,13 @@ func (s *ExecutionEngine) Reorg(count arbutil.MessageIndex, newMessages []arbost
 	if err != nil {
 		return err
 	}
+	lookahead := s.CatchUpLookahead()
 	for i := range newMessages {
-		var msgForPrefetch *arbostypes.MessageWithMetadata
-		if i < len(newMessages)-1 {
-			msgForPrefetch = &newMessages[i]
+		var msgsForPrefetch []*arbostypes.MessageWithMetadata
+		for j := i + 1; j < len(newMessages) && j <= i+lookahead; j++ {
+			msgsForPrefetch = append(msgsForPrefetch, &newMessages[j])
 		}
-		err := s.digestMessageWithBlockMutex(count+arbutil.MessageIndex(i), &newMessages[i], msgForPrefetch)
+		err := s.digestMessageWithBlockMutex(count+arbutil.MessageIndex(i), &newMessages[i], msgsForPrefetch)
 		if err != nil {
 			return err
 		}
@@ -255,10 +279,26 @@ func (s *ExecutionEngine) resequenceReorgedMessages(messages []*arbostypes.Messa
 	}
 }
 
+// SetFreezeAtBlock stops sequencing once the chain reaches the given block, or never if it's 0.
+// Blocks from a sequencer feed or the parent chain are still digested past it.
+func (s *ExecutionEngine) SetFreezeAtBlock(blockNum uint64) {
+	s.freezeAtBlock.Store(blockNum)
+}
+
+// Frozen returns whether the chain has reached the block sequencing is frozen at
+func (s *ExecutionEngine) Frozen() bool {
+	freezeAt := s.freezeAtBlock.Load()
+	return freezeAt != 0 && s.bc.CurrentBlock().Number.Uint64() >= freezeAt
+}
+
 func (s *ExecutionEngine) sequencerWrapper(sequencerFunc func() (*types.Block, error)) (*types.Block, error) {
 	attempts := 0
 	for {
 		s.createBlocksMutex.Lock()
+		if s.Frozen() {
+			s.createBlocksMutex.Unlock()
+			return nil, execution.ErrChainFrozen
+		}
 		block, err := sequencerFunc()
 		s.createBlocksMutex.Unlock()
 		if !errors.Is(err, execution.ErrSequencerInsertLockTaken) {
@@ -455,23 +495,26 @@ func (s *ExecutionEngine) createBlockFromNextMessage(msg *arbostypes.MessageWith
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	block, receipts, err := s.produceBlock(msg, currentHeader, statedb, s.bc)
+	return block, statedb, receipts, err
+}
+
+func (s *ExecutionEngine) produceBlock(msg *arbostypes.MessageWithMetadata, parent *types.Header, statedb *state.StateDB, chainContext core.ChainContext) (*types.Block, types.Receipts, error) {
 	statedb.StartPrefetcher("TransactionStreamer")
 	defer statedb.StopPrefetcher()
 
-	block, receipts, err := arbos.ProduceBlock(
+	return arbos.ProduceBlock(
 		msg.Message,
 		msg.DelayedMessagesRead,
-		currentHeader,
+		parent,
 		statedb,
-		s.bc,
+		chainContext,
 		s.bc.Config(),
 		func(batchNum uint64) ([]byte, error) {
 			data, _, err := s.streamer.FetchBatch(batchNum)
 			return data, err
 		},
 	)
-
-	return block, statedb, receipts, err
 }
 
 // must hold createBlockMutex
@@ -480,6 +523,11 @@ func (s *ExecutionEngine) appendBlock(block *types.Block, statedb *state.StateDB
 	for _, receipt := range receipts {
 		logs = append(logs, receipt.Logs...)
 	}
+	// Measured before writing the block, as committing the state resets its counters
+	var usage *BlockResourceUsage
+	if s.blockResources != nil {
+		usage = measureBlockResources(block, statedb, receipts, duration)
+	}
 	status, err := s.bc.WriteBlockAndSetHeadWithTime(block, receipts, logs, statedb, true, duration)
 	if err != nil {
 		return err
@@ -487,9 +535,27 @@ func (s *ExecutionEngine) appendBlock(block *types.Block, statedb *state.StateDB
 	if status == core.SideStatTy {
 		return errors.New("geth rejected block as non-canonical")
 	}
+	if usage != nil {
+		s.blockResources.record(usage)
+	}
+	s.sequencedTxSubscriptions.notify(block, receipts)
+	s.retryableEvents.notify(block, statedb, receipts)
 	return nil
 }
 
+// SubscribeSequencedTransactions returns a channel receiving every transaction added to the chain as its block is written,
+// and every transaction dropped from the sequencer's queue.
+// The returned function must be called to unsubscribe.
+func (s *ExecutionEngine) SubscribeSequencedTransactions() (<-chan *SequencedTransaction, func()) {
+	return s.sequencedTxSubscriptions.subscribe()
+}
+
+// SubscribeRetryableEvents returns a channel receiving the lifecycle events of retryable tickets as blocks are written.
+// The returned function must be called to unsubscribe.
+func (s *ExecutionEngine) SubscribeRetryableEvents() (<-chan *RetryableEvent, func()) {
+	return s.retryableEvents.subscribe()
+}
+
 func (s *ExecutionEngine) resultFromHeader(header *types.Header) (*execution.MessageResult, error) {
 	if header == nil {
 		return nil, fmt.Errorf("result not found")
@@ -507,18 +573,20 @@ func (s *ExecutionEngine) ResultAtPos(pos arbutil.MessageIndex) (*execution.Mess
 
 // DigestMessage is used to create a block by executing msg against the latest state and storing it.
 // Also, while creating a block by executing msg against the latest state,
-// in parallel, creates a block by executing msgForPrefetch (msg+1) against the latest state
+// in parallel, creates a block by executing msgsForPrefetch[0] (msg+1) against the latest state
 // but does not store the block.
 // This helps in filling the cache, so that the next block creation is faster.
-func (s *ExecutionEngine) DigestMessage(num arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata, msgForPrefetch *arbostypes.MessageWithMetadata) error {
+// With catch-up enabled, the next block is instead executed speculatively on top of this one while
+// it's stored, and the blocks after are prefetched, up to CatchUpLookahead messages ahead.
+func (s *ExecutionEngine) DigestMessage(num arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata, msgsForPrefetch []*arbostypes.MessageWithMetadata) error {
 	if !s.createBlocksMutex.TryLock() {
 		return errors.New("createBlock mutex held")
 	}
 	defer s.createBlocksMutex.Unlock()
-	return s.digestMessageWithBlockMutex(num, msg, msgForPrefetch)
+	return s.digestMessageWithBlockMutex(num, msg, msgsForPrefetch)
 }
 
-func (s *ExecutionEngine) digestMessageWithBlockMutex(num arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata, msgForPrefetch *arbostypes.MessageWithMetadata) error {
+func (s *ExecutionEngine) digestMessageWithBlockMutex(num arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata, msgsForPrefetch []*arbostypes.MessageWithMetadata) error {
 	currentHeader, err := s.getCurrentHeader()
 	if err != nil {
 		return err
@@ -532,24 +600,36 @@ func (s *ExecutionEngine) digestMessageWithBlockMutex(num arbutil.MessageIndex,
 	}
 
 	startTime := time.Now()
-	var wg sync.WaitGroup
-	if s.prefetchBlock && msgForPrefetch != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			_, _, _, err := s.createBlockFromNextMessage(msgForPrefetch)
-			if err != nil {
-				return
-			}
-		}()
-	}
+	var block *types.Block
+	var statedb *state.StateDB
+	var receipts types.Receipts
+	var duration time.Duration
+	if spec := s.takeSpeculativeBlock(num, msg, currentHeader); spec != nil {
+		block, statedb, receipts, duration = spec.block, spec.statedb, spec.receipts, spec.duration
+	} else {
+		var wg sync.WaitGroup
+		if s.prefetchBlock && s.catchUp == nil && len(msgsForPrefetch) > 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _, _, err := s.createBlockFromNextMessage(msgsForPrefetch[0])
+				if err != nil {
+					return
+				}
+			}()
+		}
 
-	block, statedb, receipts, err := s.createBlockFromNextMessage(msg)
-	if err != nil {
-		return err
+		block, statedb, receipts, err = s.createBlockFromNextMessage(msg)
+		if err != nil {
+			return err
+		}
+		wg.Wait()
+		duration = time.Since(startTime)
 	}
-	wg.Wait()
-	err = s.appendBlock(block, statedb, receipts, time.Since(startTime))
+	if s.catchUp != nil {
+		s.speculate(num, block, statedb, msgsForPrefetch)
+	}
+	err = s.appendBlock(block, statedb, receipts, duration)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,348 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/util/containers"
+)
+
+var (
+	logsPagesCounter          = metrics.NewRegisteredCounter("arb/rpc/logspages/pages", nil)
+	logsPagesTimeoutCounter   = metrics.NewRegisteredCounter("arb/rpc/logspages/timeout", nil)
+	logsPagesCacheHitCounter  = metrics.NewRegisteredCounter("arb/rpc/logspages/cache/hit", nil)
+	logsPagesCacheDropCounter = metrics.NewRegisteredCounter("arb/rpc/logspages/cache/dropped", nil)
+)
+
+// LogsPagesCacheHint is the replica bus hint which drops every cached page of arb_getLogsPage
+const LogsPagesCacheHint = "logspages"
+
+type LogsPagesConfig struct {
+	MaxLogs        uint64        `koanf:"max-logs" reload:"hot"`
+	MaxBlocks      uint64        `koanf:"max-blocks" reload:"hot"`
+	BlocksPerQuery uint64        `koanf:"blocks-per-query" reload:"hot"`
+	Timeout        time.Duration `koanf:"timeout" reload:"hot"`
+	CacheSize      int           `koanf:"cache-size"`
+}
+
+var DefaultLogsPagesConfig = LogsPagesConfig{
+	MaxLogs:        10_000,
+	MaxBlocks:      1_000_000,
+	BlocksPerQuery: 10_000,
+	Timeout:        time.Second * 5,
+	CacheSize:      1024,
+}
+
+func LogsPagesConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Uint64(prefix+".max-logs", DefaultLogsPagesConfig.MaxLogs, "maximum number of logs in a page returned by arb_getLogsPage")
+	f.Uint64(prefix+".max-blocks", DefaultLogsPagesConfig.MaxBlocks, "maximum number of blocks searched for a page of arb_getLogsPage")
+	f.Uint64(prefix+".blocks-per-query", DefaultLogsPagesConfig.BlocksPerQuery, "number of blocks arb_getLogsPage searches at a time, between checks of the page's time and log limits")
+	f.Duration(prefix+".timeout", DefaultLogsPagesConfig.Timeout, "time arb_getLogsPage spends searching before returning the logs found so far")
+	f.Int(prefix+".cache-size", DefaultLogsPagesConfig.CacheSize, "number of pages of arb_getLogsPage to cache when the replica bus is enabled, which drops pages that go stale (0 = disable)")
+}
+
+func (c *LogsPagesConfig) Validate() error {
+	if c.MaxLogs == 0 || c.MaxBlocks == 0 || c.BlocksPerQuery == 0 {
+		return errors.New("logs pages max-logs, max-blocks and blocks-per-query must be positive")
+	}
+	if c.Timeout <= 0 {
+		return errors.New("logs pages timeout must be positive")
+	}
+	if c.CacheSize < 0 {
+		return errors.New("logs pages cache size must not be negative")
+	}
+	return nil
+}
+
+type LogsPageQuery struct {
+	FromBlock *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock   *rpc.BlockNumber `json:"toBlock"`
+	Addresses []common.Address `json:"address"`
+	Topics    [][]common.Hash  `json:"topics"`
+	// Limit lowers the maximum number of logs in the page below the node's limit
+	Limit hexutil.Uint64 `json:"limit"`
+	// Cursor continues from where the previous page stopped, and must come with the same filter
+	Cursor hexutil.Bytes `json:"cursor"`
+}
+
+type LogsPage struct {
+	Logs []*types.Log `json:"logs"`
+	// Every block before NextBlock has been searched, except for logs skipped by Cursor
+	NextBlock hexutil.Uint64 `json:"nextBlock"`
+	ToBlock   hexutil.Uint64 `json:"toBlock"`
+	// Cursor is nil once the range has been searched
+	Cursor hexutil.Bytes `json:"cursor"`
+}
+
+// logsCursor is where a paged query continues: the block to search next, how many logs matching the filter
+// were already returned from it, and the end of the range, resolved when the first page was requested.
+// The filter's hash binds a cursor to the query it came from.
+type logsCursor struct {
+	toBlock    uint64
+	nextBlock  uint64
+	skipLogs   uint64
+	filterHash common.Hash
+}
+
+const logsCursorSize = 8 + 8 + 8 + 32
+
+func (c *logsCursor) encode() hexutil.Bytes {
+	data := make([]byte, 0, logsCursorSize)
+	data = binary.BigEndian.AppendUint64(data, c.toBlock)
+	data = binary.BigEndian.AppendUint64(data, c.nextBlock)
+	data = binary.BigEndian.AppendUint64(data, c.skipLogs)
+	return append(data, c.filterHash[:]...)
+}
+
+func decodeLogsCursor(data []byte) (*logsCursor, error) {
+	if len(data) != logsCursorSize {
+		return nil, errors.New("invalid logs cursor")
+	}
+	return &logsCursor{
+		toBlock:    binary.BigEndian.Uint64(data[0:8]),
+		nextBlock:  binary.BigEndian.Uint64(data[8:16]),
+		skipLogs:   binary.BigEndian.Uint64(data[16:24]),
+		filterHash: common.BytesToHash(data[24:]),
+	}, nil
+}
+
+func logsFilterHash(addresses []common.Address, topics [][]common.Hash) common.Hash {
+	var data []byte
+	data = binary.BigEndian.AppendUint64(data, uint64(len(addresses)))
+	for _, addr := range addresses {
+		data = append(data, addr[:]...)
+	}
+	for _, position := range topics {
+		// Each position is prefixed with its length, so an empty position differs from a missing one
+		data = binary.BigEndian.AppendUint64(data, uint64(len(position)))
+		for _, topic := range position {
+			data = append(data, topic[:]...)
+		}
+	}
+	return crypto.Keccak256Hash(data)
+}
+
+// logsPageKey identifies a page by where its search started and its log limit
+type logsPageKey struct {
+	cursor  string
+	maxLogs uint64
+}
+
+// logsPagesCache holds recently returned pages. Its invalidation relies on the replica bus, which announces
+// both local and remote reorgs, so it's only used on nodes running one.
+type logsPagesCache struct {
+	mutex sync.Mutex
+	pages *containers.LruCache[logsPageKey, *LogsPage]
+}
+
+func newLogsPagesCache(size int) *logsPagesCache {
+	return &logsPagesCache{pages: containers.NewLruCache[logsPageKey, *LogsPage](size)}
+}
+
+func (c *logsPagesCache) get(key logsPageKey) (*LogsPage, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.pages.Get(key)
+}
+
+func (c *logsPagesCache) add(key logsPageKey, page *LogsPage) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.pages.Add(key, page)
+}
+
+// invalidate is registered with the replica bus, and drops the pages which searched a reorged block
+func (c *logsPagesCache) invalidate(msg *ReplicaBusMessage) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, hint := range msg.Hints {
+		if hint == LogsPagesCacheHint {
+			logsPagesCacheDropCounter.Inc(int64(c.pages.Len()))
+			c.pages.Clear()
+			return
+		}
+	}
+	if msg.InvalidateFrom == nil {
+		return
+	}
+	for _, key := range c.pages.Keys() {
+		page, ok := c.pages.Get(key)
+		// a page which filled up partway through a block has also searched its NextBlock
+		if ok && uint64(page.NextBlock) >= *msg.InvalidateFrom {
+			c.pages.Remove(key)
+			logsPagesCacheDropCounter.Inc(1)
+		}
+	}
+}
+
+type ArbLogsPagesAPI struct {
+	blockchain   *core.BlockChain
+	filterSystem *filters.FilterSystem
+	config       func() *LogsPagesConfig
+	cache        *logsPagesCache
+}
+
+func NewArbLogsPagesAPI(blockchain *core.BlockChain, filterSystem *filters.FilterSystem, config func() *LogsPagesConfig) *ArbLogsPagesAPI {
+	return &ArbLogsPagesAPI{blockchain: blockchain, filterSystem: filterSystem, config: config}
+}
+
+// CacheWith caches pages, dropping them when the replica bus announces they're stale
+func (a *ArbLogsPagesAPI) CacheWith(replicaBus *ReplicaBus, size int) {
+	a.cache = newLogsPagesCache(size)
+	replicaBus.OnInvalidate(a.cache.invalidate)
+}
+
+func (a *ArbLogsPagesAPI) resolveBlockNumber(number *rpc.BlockNumber) (uint64, error) {
+	if number == nil || *number == rpc.LatestBlockNumber || *number == rpc.PendingBlockNumber {
+		return a.blockchain.CurrentBlock().Number.Uint64(), nil
+	}
+	var header *types.Header
+	switch *number {
+	case rpc.EarliestBlockNumber:
+		return 0, nil
+	case rpc.SafeBlockNumber:
+		header = a.blockchain.CurrentSafeBlock()
+	case rpc.FinalizedBlockNumber:
+		header = a.blockchain.CurrentFinalBlock()
+	default:
+		if *number < 0 {
+			return 0, fmt.Errorf("invalid block number %v", *number)
+		}
+		return uint64(*number), nil
+	}
+	if header == nil {
+		return 0, fmt.Errorf("%v block not found", number)
+	}
+	return header.Number.Uint64(), nil
+}
+
+// GetLogsPage returns a page of the logs matching the query, stopping at the node's limits on the number of logs,
+// blocks searched, and time spent searching. While the returned cursor isn't nil, passing it back with the same
+// query returns the next page, so a client can walk a range of any size without the request timing out.
+func (a *ArbLogsPagesAPI) GetLogsPage(ctx context.Context, query LogsPageQuery) (*LogsPage, error) {
+	config := a.config()
+	logsPagesCounter.Inc(1)
+	filterHash := logsFilterHash(query.Addresses, query.Topics)
+
+	var cursor *logsCursor
+	if len(query.Cursor) > 0 {
+		var err error
+		cursor, err = decodeLogsCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.filterHash != filterHash {
+			return nil, errors.New("logs cursor was returned for a different filter")
+		}
+	} else {
+		fromBlock, err := a.resolveBlockNumber(query.FromBlock)
+		if err != nil {
+			return nil, err
+		}
+		toBlock, err := a.resolveBlockNumber(query.ToBlock)
+		if err != nil {
+			return nil, err
+		}
+		if fromBlock > toBlock {
+			return nil, fmt.Errorf("fromBlock %v is after toBlock %v", fromBlock, toBlock)
+		}
+		cursor = &logsCursor{
+			toBlock:    toBlock,
+			nextBlock:  fromBlock,
+			filterHash: filterHash,
+		}
+	}
+	maxLogs := config.MaxLogs
+	if query.Limit > 0 && uint64(query.Limit) < maxLogs {
+		maxLogs = uint64(query.Limit)
+	}
+	key := logsPageKey{cursor: string(cursor.encode()), maxLogs: maxLogs}
+	if a.cache != nil {
+		if page, ok := a.cache.get(key); ok {
+			logsPagesCacheHitCounter.Inc(1)
+			return page, nil
+		}
+	}
+	lastBlock := cursor.toBlock
+	if cursor.nextBlock+config.MaxBlocks-1 < lastBlock {
+		lastBlock = cursor.nextBlock + config.MaxBlocks - 1
+	}
+
+	page := &LogsPage{
+		Logs:    []*types.Log{},
+		ToBlock: hexutil.Uint64(cursor.toBlock),
+	}
+	deadline := time.Now().Add(config.Timeout)
+	for cursor.nextBlock <= lastBlock {
+		if time.Now().After(deadline) {
+			logsPagesTimeoutCounter.Inc(1)
+			break
+		}
+		end := cursor.nextBlock + config.BlocksPerQuery - 1
+		if end > lastBlock {
+			end = lastBlock
+		}
+		found, err := a.filterSystem.NewRangeFilter(int64(cursor.nextBlock), int64(end), query.Addresses, query.Topics).Logs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// Drop the logs of the first block that earlier pages returned
+		skipped := uint64(0)
+		for len(found) > 0 && skipped < cursor.skipLogs && found[0].BlockNumber == cursor.nextBlock {
+			found = found[1:]
+			skipped++
+		}
+		room := maxLogs - uint64(len(page.Logs))
+		if uint64(len(found)) <= room {
+			page.Logs = append(page.Logs, found...)
+			cursor.nextBlock = end + 1
+			cursor.skipLogs = 0
+			if uint64(len(found)) == room {
+				break
+			}
+			continue
+		}
+		// The page is full partway through the range searched, so continue from the first log left out
+		page.Logs = append(page.Logs, found[:room]...)
+		next := found[room].BlockNumber
+		returned := uint64(0)
+		if next == cursor.nextBlock {
+			returned = cursor.skipLogs
+		}
+		for _, log := range found[:room] {
+			if log.BlockNumber == next {
+				returned++
+			}
+		}
+		cursor.nextBlock = next
+		cursor.skipLogs = returned
+		break
+	}
+	page.NextBlock = hexutil.Uint64(cursor.nextBlock)
+	if cursor.nextBlock <= cursor.toBlock {
+		page.Cursor = cursor.encode()
+	}
+	// a page which searched past the head would miss the logs of blocks added later
+	if a.cache != nil && uint64(page.NextBlock) <= a.blockchain.CurrentBlock().Number.Uint64() {
+		a.cache.add(key, page)
+	}
+	return page, nil
+}
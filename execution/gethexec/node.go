@@ -51,6 +51,12 @@ type Config struct {
 	TxLookupLimit             uint64                           `koanf:"tx-lookup-limit"`
 	Dangerous                 DangerousConfig                  `koanf:"dangerous"`
 	EnablePrefetchBlock       bool                             `koanf:"enable-prefetch-block"`
+	ReplicaBus                ReplicaBusConfig                 `koanf:"replica-bus" reload:"hot"`
+	SnapshotSessions          SnapshotSessionsConfig           `koanf:"snapshot-sessions" reload:"hot"`
+	CatchUp                   CatchUpConfig                    `koanf:"catch-up"`
+	LogsPages                 LogsPagesConfig                  `koanf:"logs-pages" reload:"hot"`
+	BlockResources            BlockResourcesConfig             `koanf:"block-resources"`
+	Simulation                SimulationConfig                 `koanf:"simulation" reload:"hot"`
 
 	forwardingTarget string
 }
@@ -59,6 +65,21 @@ func (c *Config) Validate() error {
 	if err := c.Sequencer.Validate(); err != nil {
 		return err
 	}
+	if err := c.ReplicaBus.Validate(); err != nil {
+		return err
+	}
+	if err := c.SnapshotSessions.Validate(); err != nil {
+		return err
+	}
+	if err := c.CatchUp.Validate(); err != nil {
+		return err
+	}
+	if err := c.LogsPages.Validate(); err != nil {
+		return err
+	}
+	if err := c.Simulation.Validate(); err != nil {
+		return err
+	}
 	if !c.Sequencer.Enable && c.ForwardingTarget == "" {
 		return errors.New("ForwardingTarget not set and not sequencer (can use \"null\")")
 	}
@@ -86,6 +107,12 @@ func ConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Uint64(prefix+".tx-lookup-limit", ConfigDefault.TxLookupLimit, "retain the ability to lookup transactions by hash for the past N blocks (0 = all blocks)")
 	DangerousConfigAddOptions(prefix+".dangerous", f)
 	f.Bool(prefix+".enable-prefetch-block", ConfigDefault.EnablePrefetchBlock, "enable prefetching of blocks")
+	ReplicaBusConfigAddOptions(prefix+".replica-bus", f)
+	SnapshotSessionsConfigAddOptions(prefix+".snapshot-sessions", f)
+	CatchUpConfigAddOptions(prefix+".catch-up", f)
+	LogsPagesConfigAddOptions(prefix+".logs-pages", f)
+	BlockResourcesConfigAddOptions(prefix+".block-resources", f)
+	SimulationConfigAddOptions(prefix+".simulation", f)
 }
 
 var ConfigDefault = Config{
@@ -101,6 +128,12 @@ var ConfigDefault = Config{
 	Dangerous:                 DefaultDangerousConfig,
 	Forwarder:                 DefaultNodeForwarderConfig,
 	EnablePrefetchBlock:       true,
+	ReplicaBus:                DefaultReplicaBusConfig,
+	SnapshotSessions:          DefaultSnapshotSessionsConfig,
+	CatchUp:                   DefaultCatchUpConfig,
+	LogsPages:                 DefaultLogsPagesConfig,
+	BlockResources:            DefaultBlockResourcesConfig,
+	Simulation:                DefaultSimulationConfig,
 }
 
 func ConfigDefaultNonSequencerTest() *Config {
@@ -139,6 +172,8 @@ type ExecutionNode struct {
 	TxPublisher       TransactionPublisher
 	ConfigFetcher     ConfigFetcher
 	ParentChainReader *headerreader.HeaderReader
+	ReplicaBus        *ReplicaBus
+	SnapshotSessions  *SnapshotSessions
 	started           atomic.Bool
 }
 
@@ -155,6 +190,14 @@ func CreateExecutionNode(
 	if config.EnablePrefetchBlock {
 		execEngine.EnablePrefetchBlock()
 	}
+	if config.CatchUp.Enable {
+		execEngine.EnableCatchUp(config.CatchUp)
+	}
+	var blockResources *blockResourcesIndex
+	if config.BlockResources.Enable {
+		blockResources = newBlockResourcesIndex(chainDB, &config.BlockResources)
+		execEngine.EnableBlockResources(blockResources)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -205,12 +248,90 @@ func CreateExecutionNode(
 		return nil, err
 	}
 
+	var replicaBus *ReplicaBus
+	if config.ReplicaBus.Enable {
+		replicaBus, err = NewReplicaBus(l2BlockChain, func() *ReplicaBusConfig { return &configFetcher().ReplicaBus })
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var snapshotSessions *SnapshotSessions
+	if config.SnapshotSessions.Enable {
+		snapshotSessions = NewSnapshotSessions(l2BlockChain, stack.Attach(), func() *SnapshotSessionsConfig { return &configFetcher().SnapshotSessions })
+	}
+
 	apis := []rpc.API{{
 		Namespace: "arb",
 		Version:   "1.0",
 		Service:   NewArbAPI(txPublisher),
 		Public:    false,
 	}}
+	apis = append(apis, rpc.API{
+		Namespace: "arb",
+		Version:   "1.0",
+		Service:   NewArbSubscriptionAPI(execEngine),
+		Public:    false,
+	})
+	if config.Simulation.Enable {
+		// executing arbitrary messages is expensive, so it's only served if asked for
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   NewArbSimulationAPI(execEngine, func() *SimulationConfig { return &configFetcher().Simulation }),
+			Public:    false,
+		})
+	}
+	logsPagesAPI := NewArbLogsPagesAPI(l2BlockChain, filterSystem, func() *LogsPagesConfig { return &configFetcher().LogsPages })
+	if replicaBus != nil && config.LogsPages.CacheSize > 0 {
+		logsPagesAPI.CacheWith(replicaBus, config.LogsPages.CacheSize)
+	}
+	apis = append(apis, rpc.API{
+		Namespace: "arb",
+		Version:   "1.0",
+		Service:   logsPagesAPI,
+		Public:    false,
+	})
+	apis = append(apis, rpc.API{
+		Namespace: "eth",
+		Version:   "1.0",
+		Service:   NewRetryableEventsAPI(execEngine),
+		Public:    true,
+	})
+	if sequencer != nil {
+		// only served over the authenticated RPC endpoint, see the auth.api option
+		apis = append(apis, rpc.API{
+			Namespace:     "arb",
+			Version:       "1.0",
+			Service:       NewArbSequencerAPI(sequencer),
+			Public:        false,
+			Authenticated: true,
+		})
+	}
+	if replicaBus != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   NewArbReplicaBusAPI(replicaBus),
+			Public:    false,
+		})
+	}
+	if blockResources != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   NewArbBlockResourcesAPI(l2BlockChain, blockResources),
+			Public:    false,
+		})
+	}
+	if snapshotSessions != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   NewArbSnapshotAPI(snapshotSessions),
+			Public:    false,
+		})
+	}
 	apis = append(apis, rpc.API{
 		Namespace: "arbdebug",
 		Version:   "1.0",
@@ -221,6 +342,12 @@ func CreateExecutionNode(
 		),
 		Public: false,
 	})
+	apis = append(apis, rpc.API{
+		Namespace: "arbdebug",
+		Version:   "1.0",
+		Service:   NewArbSequencerJournalAPI(l2BlockChain),
+		Public:    false,
+	})
 	apis = append(apis, rpc.API{
 		Namespace: "arbtrace",
 		Version:   "1.0",
@@ -249,6 +376,8 @@ func CreateExecutionNode(
 		TxPublisher:       txPublisher,
 		ConfigFetcher:     configFetcher,
 		ParentChainReader: parentChainReader,
+		ReplicaBus:        replicaBus,
+		SnapshotSessions:  snapshotSessions,
 	}, nil
 
 }
@@ -288,6 +417,12 @@ func (n *ExecutionNode) Start(ctx context.Context) error {
 	if n.ParentChainReader != nil {
 		n.ParentChainReader.Start(ctx)
 	}
+	if n.ReplicaBus != nil {
+		n.ReplicaBus.Start(ctx)
+	}
+	if n.SnapshotSessions != nil {
+		n.SnapshotSessions.Start(ctx)
+	}
 	return nil
 }
 
@@ -301,6 +436,12 @@ func (n *ExecutionNode) StopAndWait() {
 		n.TxPublisher.StopAndWait()
 	}
 	n.Recorder.OrderlyShutdown()
+	if n.ReplicaBus != nil && n.ReplicaBus.Started() {
+		n.ReplicaBus.StopAndWait()
+	}
+	if n.SnapshotSessions != nil && n.SnapshotSessions.Started() {
+		n.SnapshotSessions.StopAndWait()
+	}
 	if n.ParentChainReader != nil && n.ParentChainReader.Started() {
 		n.ParentChainReader.StopAndWait()
 	}
@@ -317,8 +458,11 @@ func (n *ExecutionNode) StopAndWait() {
 	// }
 }
 
-func (n *ExecutionNode) DigestMessage(num arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata, msgForPrefetch *arbostypes.MessageWithMetadata) error {
-	return n.ExecEngine.DigestMessage(num, msg, msgForPrefetch)
+func (n *ExecutionNode) DigestMessage(num arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata, msgsForPrefetch []*arbostypes.MessageWithMetadata) error {
+	return n.ExecEngine.DigestMessage(num, msg, msgsForPrefetch)
+}
+func (n *ExecutionNode) CatchUpLookahead() int {
+	return n.ExecEngine.CatchUpLookahead()
 }
 func (n *ExecutionNode) Reorg(count arbutil.MessageIndex, newMessages []arbostypes.MessageWithMetadata, oldMessages []*arbostypes.MessageWithMetadata) error {
 	return n.ExecEngine.Reorg(count, newMessages, oldMessages)
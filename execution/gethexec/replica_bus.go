@@ -0,0 +1,317 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/util/redisutil"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	replicaBusPublishedCounter    = metrics.NewRegisteredCounter("arb/replicabus/published", nil)
+	replicaBusReceivedCounter     = metrics.NewRegisteredCounter("arb/replicabus/received", nil)
+	replicaBusInvalidationCounter = metrics.NewRegisteredCounter("arb/replicabus/invalidations", nil)
+	replicaBusLagGauge            = metrics.NewRegisteredGauge("arb/replicabus/lag", nil)
+)
+
+type ReplicaBusConfig struct {
+	Enable     bool          `koanf:"enable"`
+	RedisUrl   string        `koanf:"redis-url"`
+	SessionKey string        `koanf:"session-key"`
+	MaxWait    time.Duration `koanf:"max-wait" reload:"hot"`
+}
+
+var DefaultReplicaBusConfig = ReplicaBusConfig{
+	Enable:     false,
+	RedisUrl:   "",
+	SessionKey: "default",
+	MaxWait:    time.Millisecond * 250,
+}
+
+func ReplicaBusConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultReplicaBusConfig.Enable, "share head updates and cache invalidation hints with the other RPC replicas of the fleet")
+	f.String(prefix+".redis-url", DefaultReplicaBusConfig.RedisUrl, "the Redis URL to publish and receive replica updates through")
+	f.String(prefix+".session-key", DefaultReplicaBusConfig.SessionKey, "key identifying the fleet of replicas, only replicas with the same key see each other's updates")
+	f.Duration(prefix+".max-wait", DefaultReplicaBusConfig.MaxWait, "maximum time to wait for this replica to catch up to the fleet's head before serving a request")
+}
+
+func (c *ReplicaBusConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.RedisUrl == "" {
+		return errors.New("replica bus enabled but no redis url set")
+	}
+	if c.SessionKey == "" {
+		return errors.New("replica bus session key must not be empty")
+	}
+	return nil
+}
+
+// ReplicaBusMessage is published by a replica whenever its head changes
+type ReplicaBusMessage struct {
+	Origin     string      `json:"origin"`
+	Number     uint64      `json:"number"`
+	Hash       common.Hash `json:"hash"`
+	ParentHash common.Hash `json:"parentHash"`
+	// InvalidateFrom is set when the head was reorged, and cached results for this block and above are stale
+	InvalidateFrom *uint64 `json:"invalidateFrom,omitempty"`
+	// Hints name additional replica-local caches which should be dropped, e.g. LogsPagesCacheHint
+	Hints []string `json:"hints,omitempty"`
+}
+
+// ReplicaBus connects the RPC replicas of a fleet over a redis pub/sub channel keyed by the session key.
+// Each replica publishes its head as it advances, so the others know the fleet's head and can wait to catch
+// up to it before serving a read, and replica-local caches are told when their entries go stale.
+type ReplicaBus struct {
+	stopwaiter.StopWaiter
+	config     func() *ReplicaBusConfig
+	blockchain *core.BlockChain
+	client     redis.UniversalClient
+	channel    string
+	origin     string
+
+	mutex      sync.Mutex
+	localHead  *types.Header
+	fleetHead  uint64
+	headNotify chan struct{} // closed and replaced whenever the local head advances
+	handlers   []func(*ReplicaBusMessage)
+}
+
+func NewReplicaBus(blockchain *core.BlockChain, config func() *ReplicaBusConfig) (*ReplicaBus, error) {
+	client, err := redisutil.RedisClientFromURL(config().RedisUrl)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errors.New("replica bus requires a redis url")
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	return &ReplicaBus{
+		config:     config,
+		blockchain: blockchain,
+		client:     client,
+		channel:    "replica-bus:" + config().SessionKey,
+		origin:     hex.EncodeToString(id[:]),
+		headNotify: make(chan struct{}),
+	}, nil
+}
+
+// OnInvalidate registers a handler to be called with every message carrying an invalidation
+func (b *ReplicaBus) OnInvalidate(handler func(*ReplicaBusMessage)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// FleetHead returns the highest head any replica, including this one, has announced
+func (b *ReplicaBus) FleetHead() uint64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.fleetHead
+}
+
+func (b *ReplicaBus) localHeadNumber() uint64 {
+	if b.localHead == nil {
+		return 0
+	}
+	return b.localHead.Number.Uint64()
+}
+
+// WaitForFleetHead blocks until this replica has caught up to the fleet's head, up to the max-wait
+// timeout, and returns whether it caught up.
+func (b *ReplicaBus) WaitForFleetHead(ctx context.Context) bool {
+	timer := time.NewTimer(b.config().MaxWait)
+	defer timer.Stop()
+	for {
+		b.mutex.Lock()
+		caughtUp := b.localHeadNumber() >= b.fleetHead
+		notify := b.headNotify
+		b.mutex.Unlock()
+		if caughtUp {
+			return true
+		}
+		select {
+		case <-notify:
+		case <-timer.C:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// PublishInvalidation announces hints for replica-local caches, which are also applied to this replica
+func (b *ReplicaBus) PublishInvalidation(ctx context.Context, hints ...string) error {
+	b.mutex.Lock()
+	msg := &ReplicaBusMessage{Origin: b.origin, Hints: hints}
+	if b.localHead != nil {
+		msg.Number = b.localHead.Number.Uint64()
+		msg.Hash = b.localHead.Hash()
+		msg.ParentHash = b.localHead.ParentHash
+	}
+	b.mutex.Unlock()
+	b.invalidate(msg)
+	return b.publish(ctx, msg)
+}
+
+func (b *ReplicaBus) publish(ctx context.Context, msg *ReplicaBusMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := b.client.Publish(ctx, b.channel, data).Err(); err != nil {
+		return err
+	}
+	replicaBusPublishedCounter.Inc(1)
+	return nil
+}
+
+func (b *ReplicaBus) invalidate(msg *ReplicaBusMessage) {
+	if msg.InvalidateFrom == nil && len(msg.Hints) == 0 {
+		return
+	}
+	replicaBusInvalidationCounter.Inc(1)
+	b.mutex.Lock()
+	handlers := b.handlers
+	b.mutex.Unlock()
+	for _, handler := range handlers {
+		handler(msg)
+	}
+}
+
+// onLocalHead records a new local head and announces it to the fleet
+func (b *ReplicaBus) onLocalHead(ctx context.Context, header *types.Header) error {
+	msg := &ReplicaBusMessage{
+		Origin:     b.origin,
+		Number:     header.Number.Uint64(),
+		Hash:       header.Hash(),
+		ParentHash: header.ParentHash,
+	}
+	b.mutex.Lock()
+	prev := b.localHead
+	if prev != nil && prev.Hash() != header.ParentHash {
+		// the new head doesn't extend the previous one, so everything from the fork onward may have changed
+		from := header.Number.Uint64()
+		if prevNumber := prev.Number.Uint64(); prevNumber < from {
+			from = prevNumber
+		}
+		msg.InvalidateFrom = &from
+	}
+	b.localHead = header
+	if msg.Number > b.fleetHead || msg.InvalidateFrom != nil {
+		b.fleetHead = msg.Number
+	}
+	close(b.headNotify)
+	b.headNotify = make(chan struct{})
+	replicaBusLagGauge.Update(int64(b.fleetHead) - int64(msg.Number))
+	b.mutex.Unlock()
+	b.invalidate(msg)
+	return b.publish(ctx, msg)
+}
+
+// onRemoteMessage handles a message published by another replica
+func (b *ReplicaBus) onRemoteMessage(msg *ReplicaBusMessage) {
+	if msg.Origin == b.origin {
+		return
+	}
+	replicaBusReceivedCounter.Inc(1)
+	b.mutex.Lock()
+	if msg.Number > b.fleetHead || msg.InvalidateFrom != nil {
+		b.fleetHead = msg.Number
+	}
+	replicaBusLagGauge.Update(int64(b.fleetHead) - int64(b.localHeadNumber()))
+	b.mutex.Unlock()
+	b.invalidate(msg)
+}
+
+func (b *ReplicaBus) Start(ctxIn context.Context) {
+	b.StopWaiter.Start(ctxIn, b)
+	pubsub := b.client.Subscribe(b.GetContext(), b.channel)
+	b.LaunchThread(func(ctx context.Context) {
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case redisMsg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var msg ReplicaBusMessage
+				if err := json.Unmarshal([]byte(redisMsg.Payload), &msg); err != nil {
+					log.Warn("failed to parse replica bus message", "err", err)
+					continue
+				}
+				b.onRemoteMessage(&msg)
+			}
+		}
+	})
+	if b.blockchain == nil {
+		return
+	}
+	headEvents := make(chan core.ChainHeadEvent, 16)
+	headSub := b.blockchain.SubscribeChainHeadEvent(headEvents)
+	b.LaunchThread(func(ctx context.Context) {
+		defer headSub.Unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-headSub.Err():
+				if err != nil {
+					log.Error("replica bus chain head subscription failed", "err", err)
+				}
+				return
+			case ev := <-headEvents:
+				if err := b.onLocalHead(ctx, ev.Block.Header()); err != nil {
+					log.Warn("failed to publish head to replica bus", "err", err)
+				}
+			}
+		}
+	})
+}
+
+func (b *ReplicaBus) StopAndWait() {
+	b.StopWaiter.StopAndWait()
+	if err := b.client.Close(); err != nil {
+		log.Warn("error closing replica bus redis client", "err", err)
+	}
+}
+
+type ReplicaBusStatus struct {
+	LocalHead uint64 `json:"localHead"`
+	FleetHead uint64 `json:"fleetHead"`
+}
+
+// Status returns this replica's head and the fleet's head
+func (b *ReplicaBus) Status() ReplicaBusStatus {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return ReplicaBusStatus{
+		LocalHead: b.localHeadNumber(),
+		FleetHead: b.fleetHead,
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/nitro/util/redisutil"
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+func TestReplicaBus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := DefaultReplicaBusConfig
+	config.Enable = true
+	config.RedisUrl = redisutil.CreateTestRedis(ctx, t)
+	config.MaxWait = time.Millisecond * 20
+	configFetcher := func() *ReplicaBusConfig { return &config }
+
+	newBus := func() *ReplicaBus {
+		bus, err := NewReplicaBus(nil, configFetcher)
+		Require(t, err)
+		bus.Start(ctx)
+		return bus
+	}
+	busA := newBus()
+	defer busA.StopAndWait()
+	busB := newBus()
+	defer busB.StopAndWait()
+
+	invalidations := make(chan *ReplicaBusMessage, 8)
+	busB.OnInvalidate(func(msg *ReplicaBusMessage) { invalidations <- msg })
+
+	// wait for the subscriptions to be established before publishing
+	time.Sleep(time.Millisecond * 100)
+
+	waitForFleetHead := func(bus *ReplicaBus, expected uint64) {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			if bus.FleetHead() == expected {
+				return
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		Fail(t, "fleet head", bus.FleetHead(), "expected", expected)
+	}
+
+	var headers []*types.Header
+	parent := common.Hash{}
+	for i := int64(1); i <= 3; i++ {
+		header := &types.Header{Number: big.NewInt(i), ParentHash: parent}
+		Require(t, busA.onLocalHead(ctx, header))
+		headers = append(headers, header)
+		parent = header.Hash()
+	}
+	waitForFleetHead(busB, 3)
+	if busB.WaitForFleetHead(ctx) {
+		Fail(t, "replica without a head caught up to the fleet")
+	}
+	if !busA.WaitForFleetHead(ctx) {
+		Fail(t, "replica at the fleet head didn't catch up")
+	}
+
+	// replica B catches up while waiting
+	go func() {
+		time.Sleep(time.Millisecond * 5)
+		for _, header := range headers {
+			_ = busB.onLocalHead(ctx, header)
+		}
+	}()
+	config.MaxWait = time.Second
+	if !busB.WaitForFleetHead(ctx) {
+		Fail(t, "replica didn't catch up to the fleet head")
+	}
+
+	// a reorg on replica A is announced as an invalidation
+	reorged := &types.Header{Number: big.NewInt(3), ParentHash: headers[1].Hash(), Extra: []byte{1}}
+	Require(t, busA.onLocalHead(ctx, reorged))
+	select {
+	case msg := <-invalidations:
+		if msg.InvalidateFrom == nil || *msg.InvalidateFrom != 3 {
+			Fail(t, "unexpected invalidation", msg)
+		}
+		if msg.Hash != reorged.Hash() {
+			Fail(t, "invalidation for block", msg.Hash, "expected", reorged.Hash())
+		}
+	case <-time.After(time.Second * 5):
+		Fail(t, "replica didn't receive the reorg invalidation")
+	}
+
+	Require(t, busA.PublishInvalidation(ctx, "feehistory"))
+	select {
+	case msg := <-invalidations:
+		if len(msg.Hints) != 1 || msg.Hints[0] != "feehistory" {
+			Fail(t, "unexpected invalidation hints", msg.Hints)
+		}
+	case <-time.After(time.Second * 5):
+		Fail(t, "replica didn't receive the invalidation hint")
+	}
+}
+
+func TestReplicaBusInvalidatesLogsPages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := DefaultReplicaBusConfig
+	config.Enable = true
+	config.RedisUrl = redisutil.CreateTestRedis(ctx, t)
+	configFetcher := func() *ReplicaBusConfig { return &config }
+	newBus := func() *ReplicaBus {
+		bus, err := NewReplicaBus(nil, configFetcher)
+		Require(t, err)
+		bus.Start(ctx)
+		return bus
+	}
+	busA := newBus()
+	defer busA.StopAndWait()
+	busB := newBus()
+	defer busB.StopAndWait()
+
+	api := NewArbLogsPagesAPI(nil, nil, nil)
+	api.CacheWith(busB, 8)
+	cache := api.cache
+	keys := make(map[uint64]logsPageKey)
+	for i, nextBlock := range []uint64{2, 3, 9} {
+		start := &logsCursor{nextBlock: uint64(i), toBlock: 10}
+		keys[nextBlock] = logsPageKey{cursor: string(start.encode()), maxLogs: 10}
+		cache.add(keys[nextBlock], &LogsPage{NextBlock: hexutil.Uint64(nextBlock)})
+	}
+	waitForPages := func(expected ...uint64) {
+		t.Helper()
+		for i := 0; i < 500 && cache.pages.Len() != len(expected); i++ {
+			time.Sleep(time.Millisecond * 10)
+		}
+		if cache.pages.Len() != len(expected) {
+			Fail(t, "cache has", cache.pages.Len(), "pages, expected", expected)
+		}
+		for _, nextBlock := range expected {
+			if _, ok := cache.get(keys[nextBlock]); !ok {
+				Fail(t, "page up to block", nextBlock, "was dropped")
+			}
+		}
+	}
+
+	// wait for the subscriptions to be established before publishing
+	time.Sleep(time.Millisecond * 100)
+
+	var headers []*types.Header
+	parent := common.Hash{}
+	for i := int64(1); i <= 3; i++ {
+		header := &types.Header{Number: big.NewInt(i), ParentHash: parent}
+		Require(t, busA.onLocalHead(ctx, header))
+		headers = append(headers, header)
+		parent = header.Hash()
+	}
+	waitForPages(2, 3, 9)
+
+	// a reorg of block 3 on another replica drops the pages which searched it
+	reorged := &types.Header{Number: big.NewInt(3), ParentHash: headers[1].Hash(), Extra: []byte{1}}
+	Require(t, busA.onLocalHead(ctx, reorged))
+	waitForPages(2)
+
+	// other hints leave the pages alone, and the logs pages hint drops them all
+	Require(t, busB.PublishInvalidation(ctx, "feehistory"))
+	waitForPages(2)
+	Require(t, busA.PublishInvalidation(ctx, LogsPagesCacheHint))
+	waitForPages()
+}
+
+func Require(t *testing.T, err error, printables ...interface{}) {
+	t.Helper()
+	testhelpers.RequireImpl(t, err, printables...)
+}
+
+func Fail(t *testing.T, printables ...interface{}) {
+	t.Helper()
+	testhelpers.FailImpl(t, printables...)
+}
@@ -0,0 +1,332 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"container/heap"
+	"context"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbos/arbosState"
+	"github.com/offchainlabs/nitro/arbos/util"
+	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
+)
+
+const (
+	RetryableCreated               = "created"
+	RetryableAutoRedeemSucceeded   = "autoRedeemSucceeded"
+	RetryableAutoRedeemFailed      = "autoRedeemFailed"
+	RetryableManualRedeemSucceeded = "manualRedeemSucceeded"
+	RetryableManualRedeemFailed    = "manualRedeemFailed"
+	RetryableCanceled              = "canceled"
+	RetryableExpired               = "expired"
+)
+
+// How many open retryables are tracked to notice them expiring. Retryables created beyond that are
+// still reported when created or redeemed, but not when they expire.
+const maxTrackedRetryables = 1 << 20
+
+var untrackedRetryablesCounter = metrics.NewRegisteredCounter("arb/retryables/events/untracked", nil)
+
+var retryableRedeemScheduledID common.Hash
+var retryableCanceledID common.Hash
+
+func init() {
+	parsed, err := abi.JSON(strings.NewReader(precompilesgen.ArbRetryableTxABI))
+	if err != nil {
+		panic(err)
+	}
+	retryableRedeemScheduledID = parsed.Events["RedeemScheduled"].ID
+	retryableCanceledID = parsed.Events["Canceled"].ID
+}
+
+// RetryableEvent notifies of a step in a retryable ticket's lifecycle
+type RetryableEvent struct {
+	Type     string      `json:"type"`
+	TicketId common.Hash `json:"ticketId"`
+	// The transaction that created, redeemed or canceled the ticket; absent for expiry, which has none
+	TxHash      *common.Hash   `json:"transactionHash,omitempty"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	Beneficiary common.Address `json:"beneficiary"`
+	// Nil if the retryable deploys a contract
+	Destination *common.Address `json:"destination"`
+}
+
+// RetryableEventsFilter selects the events of retryables with any of the given beneficiaries and
+// any of the given destinations. An empty list matches every retryable.
+type RetryableEventsFilter struct {
+	Beneficiaries []common.Address `json:"beneficiaries"`
+	Destinations  []common.Address `json:"destinations"`
+}
+
+func (f *RetryableEventsFilter) matches(event *RetryableEvent) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Beneficiaries) > 0 && !containsAddress(f.Beneficiaries, event.Beneficiary) {
+		return false
+	}
+	if len(f.Destinations) > 0 && (event.Destination == nil || !containsAddress(f.Destinations, *event.Destination)) {
+		return false
+	}
+	return true
+}
+
+func containsAddress(addrs []common.Address, addr common.Address) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+type openRetryable struct {
+	beneficiary common.Address
+	destination *common.Address
+	timeout     uint64
+}
+
+type retryableTimeout struct {
+	timeout  uint64
+	ticketId common.Hash
+}
+
+// retryableTimeouts is a min-heap of tracked retryables by timeout
+type retryableTimeouts []retryableTimeout
+
+func (h retryableTimeouts) Len() int           { return len(h) }
+func (h retryableTimeouts) Less(i, j int) bool { return h[i].timeout < h[j].timeout }
+func (h retryableTimeouts) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *retryableTimeouts) Push(x any)        { *h = append(*h, x.(retryableTimeout)) }
+func (h *retryableTimeouts) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// retryableEvents derives retryable lifecycle events from the blocks the execution engine writes.
+// ArbOS reaps expired retryables without a log, so expiry is noticed by tracking open retryables
+// created since the node started and checking their state once their timeout passes.
+// Must only be used while holding the execution engine's createBlockMutex.
+type retryableEvents struct {
+	txSubscriptions[*RetryableEvent]
+	open     map[common.Hash]*openRetryable
+	timeouts retryableTimeouts
+}
+
+func (r *retryableEvents) notify(block *types.Block, statedb *state.StateDB, receipts types.Receipts) {
+	if r.open == nil {
+		r.open = make(map[common.Hash]*openRetryable)
+	}
+	var arbState *arbosState.ArbosState
+	openState := func() *arbosState.ArbosState {
+		if arbState == nil {
+			var err error
+			arbState, err = arbosState.OpenSystemArbosState(statedb, nil, true)
+			if err != nil {
+				log.Warn("failed to open ArbOS state for retryable events", "block", block.NumberU64(), "err", err)
+			}
+		}
+		return arbState
+	}
+	newEvent := func(eventType string, ticketId common.Hash, txHash *common.Hash, info *openRetryable) *RetryableEvent {
+		return &RetryableEvent{
+			Type:        eventType,
+			TicketId:    ticketId,
+			TxHash:      txHash,
+			BlockHash:   block.Hash(),
+			BlockNumber: hexutil.Uint64(block.NumberU64()),
+			Beneficiary: info.beneficiary,
+			Destination: info.destination,
+		}
+	}
+	// lookup finds what's known about a retryable, from its creation or failing that its current state
+	lookup := func(ticketId common.Hash) *openRetryable {
+		if info, ok := r.open[ticketId]; ok {
+			return info
+		}
+		info := &openRetryable{}
+		if arbState := openState(); arbState != nil {
+			retryable, err := arbState.RetryableState().OpenRetryable(ticketId, 0)
+			if err == nil && retryable != nil {
+				info.beneficiary, _ = retryable.Beneficiary()
+				info.destination, _ = retryable.To()
+			}
+		}
+		return info
+	}
+
+	var events []*RetryableEvent
+	var created []common.Hash
+	// Whether each scheduled redeem was scheduled by the retryable's submission, making it an auto-redeem
+	autoRedeems := make(map[common.Hash]bool)
+	for i, tx := range block.Transactions() {
+		if i >= len(receipts) {
+			break
+		}
+		receipt := receipts[i]
+		txHash := tx.Hash()
+		switch inner := tx.GetInner().(type) {
+		case *types.ArbitrumSubmitRetryableTx:
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				break
+			}
+			info := &openRetryable{beneficiary: inner.Beneficiary, destination: inner.RetryTo}
+			r.open[txHash] = info
+			created = append(created, txHash)
+			events = append(events, newEvent(RetryableCreated, txHash, &txHash, info))
+		case *types.ArbitrumRetryTx:
+			succeeded := receipt.Status == types.ReceiptStatusSuccessful
+			var eventType string
+			switch {
+			case autoRedeems[txHash] && succeeded:
+				eventType = RetryableAutoRedeemSucceeded
+			case autoRedeems[txHash]:
+				eventType = RetryableAutoRedeemFailed
+			case succeeded:
+				eventType = RetryableManualRedeemSucceeded
+			default:
+				eventType = RetryableManualRedeemFailed
+			}
+			info := lookup(inner.TicketId)
+			info.destination = inner.To
+			events = append(events, newEvent(eventType, inner.TicketId, &txHash, info))
+			if succeeded {
+				delete(r.open, inner.TicketId)
+			}
+		}
+		for _, txLog := range receipt.Logs {
+			if txLog.Address != types.ArbRetryableTxAddress || len(txLog.Topics) < 2 {
+				continue
+			}
+			switch txLog.Topics[0] {
+			case retryableRedeemScheduledID:
+				event := &precompilesgen.ArbRetryableTxRedeemScheduled{}
+				if err := util.ParseRedeemScheduledLog(event, txLog); err != nil {
+					log.Warn("failed to parse RedeemScheduled log", "err", err)
+					continue
+				}
+				autoRedeems[event.RetryTxHash] = tx.Type() == types.ArbitrumSubmitRetryableTxType
+			case retryableCanceledID:
+				ticketId := txLog.Topics[1]
+				info, ok := r.open[ticketId]
+				if !ok {
+					info = &openRetryable{}
+				}
+				events = append(events, newEvent(RetryableCanceled, ticketId, &txHash, info))
+				delete(r.open, ticketId)
+			}
+		}
+	}
+
+	// Track the timeouts of retryables that are still open at the end of the block
+	for _, ticketId := range created {
+		info, ok := r.open[ticketId]
+		if !ok {
+			continue
+		}
+		if len(r.open) > maxTrackedRetryables {
+			delete(r.open, ticketId)
+			untrackedRetryablesCounter.Inc(1)
+			continue
+		}
+		r.updateTimeout(openState(), ticketId, info)
+	}
+
+	// Check on the retryables whose timeouts have passed, which may have had their lifetimes extended
+	for len(r.timeouts) > 0 && r.timeouts[0].timeout < block.Time() {
+		next := heap.Pop(&r.timeouts).(retryableTimeout)
+		info, ok := r.open[next.ticketId]
+		if !ok || info.timeout != next.timeout {
+			continue
+		}
+		arbState := openState()
+		if arbState == nil {
+			break
+		}
+		retryable, err := arbState.RetryableState().OpenRetryable(next.ticketId, block.Time())
+		if err != nil {
+			log.Warn("failed to open retryable", "ticketId", next.ticketId, "err", err)
+			continue
+		}
+		if retryable == nil {
+			events = append(events, newEvent(RetryableExpired, next.ticketId, nil, info))
+			delete(r.open, next.ticketId)
+			continue
+		}
+		r.updateTimeout(arbState, next.ticketId, info)
+	}
+
+	for _, event := range events {
+		r.send(event)
+	}
+}
+
+func (r *retryableEvents) updateTimeout(arbState *arbosState.ArbosState, ticketId common.Hash, info *openRetryable) {
+	if arbState == nil {
+		delete(r.open, ticketId)
+		return
+	}
+	retryable, err := arbState.RetryableState().OpenRetryable(ticketId, 0)
+	if err != nil || retryable == nil {
+		delete(r.open, ticketId)
+		return
+	}
+	timeout, err := retryable.CalculateTimeout()
+	if err != nil {
+		delete(r.open, ticketId)
+		return
+	}
+	info.timeout = timeout
+	heap.Push(&r.timeouts, retryableTimeout{timeout: timeout, ticketId: ticketId})
+}
+
+type RetryableEventsAPI struct {
+	execEngine *ExecutionEngine
+}
+
+func NewRetryableEventsAPI(execEngine *ExecutionEngine) *RetryableEventsAPI {
+	return &RetryableEventsAPI{execEngine}
+}
+
+// RetryableEvents notifies the subscriber as retryable tickets are created, redeemed, canceled or expire,
+// available as eth_subscribe("retryableEvents", filter).
+func (a *RetryableEventsAPI) RetryableEvents(ctx context.Context, filter *RetryableEventsFilter) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+	events, unsubscribe := a.execEngine.SubscribeRetryableEvents()
+	go func() {
+		defer unsubscribe()
+		for {
+			select {
+			case event := <-events:
+				if !filter.matches(event) {
+					continue
+				}
+				if err := notifier.Notify(rpcSub.ID, event); err != nil {
+					log.Debug("failed to notify retryable events subscriber", "err", err)
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var sequencedTxNotificationsDroppedCounter = metrics.NewRegisteredCounter("arb/sequencer/subscriptions/dropped", nil)
+
+// How many notifications a subscriber may fall behind before notifications are dropped for it.
+// Sequencing must never wait on a slow subscriber.
+const sequencedTxSubscriberBuffer = 4096
+
+// SequencedTransaction is a soft confirmation that a transaction made it into an L2 block,
+// sent before the block has been posted to the parent chain.
+// With Dropped set, it's instead notice that a transaction accepted into the sequencer's queue
+// will never be sequenced, and only the transaction hash, Reason and QueuedAt are set.
+type SequencedTransaction struct {
+	TxHash           common.Hash    `json:"transactionHash"`
+	BlockHash        common.Hash    `json:"blockHash"`
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	TransactionIndex hexutil.Uint   `json:"transactionIndex"`
+	Status           hexutil.Uint64 `json:"status"`
+	GasUsed          hexutil.Uint64 `json:"gasUsed"`
+
+	Dropped  bool           `json:"dropped,omitempty"`
+	Reason   string         `json:"reason,omitempty"`
+	QueuedAt hexutil.Uint64 `json:"queuedAt,omitempty"` // unix milliseconds
+}
+
+type txSubscriptions[T any] struct {
+	mutex       sync.Mutex
+	nextId      uint64
+	subscribers map[uint64]chan T
+}
+
+func (s *txSubscriptions[T]) subscribe() (<-chan T, func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.subscribers == nil {
+		s.subscribers = make(map[uint64]chan T)
+	}
+	id := s.nextId
+	s.nextId++
+	ch := make(chan T, sequencedTxSubscriberBuffer)
+	s.subscribers[id] = ch
+	unsubscribe := func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		delete(s.subscribers, id)
+	}
+	return ch, unsubscribe
+}
+
+func (s *txSubscriptions[T]) hasSubscribers() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.subscribers) > 0
+}
+
+// send never blocks; notifications are dropped for subscribers that have fallen too far behind
+func (s *txSubscriptions[T]) send(notification T) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, ch := range s.subscribers {
+		select {
+		case ch <- notification:
+		default:
+			sequencedTxNotificationsDroppedCounter.Inc(1)
+		}
+	}
+}
+
+type sequencedTxSubscriptions struct {
+	txSubscriptions[*SequencedTransaction]
+}
+
+func (s *sequencedTxSubscriptions) notify(block *types.Block, receipts types.Receipts) {
+	if !s.hasSubscribers() {
+		return
+	}
+	for i, tx := range block.Transactions() {
+		if tx.Type() == types.ArbitrumInternalTxType || i >= len(receipts) {
+			continue
+		}
+		receipt := receipts[i]
+		s.send(&SequencedTransaction{
+			TxHash:           tx.Hash(),
+			BlockHash:        block.Hash(),
+			BlockNumber:      hexutil.Uint64(block.NumberU64()),
+			TransactionIndex: hexutil.Uint(i),
+			Status:           hexutil.Uint64(receipt.Status),
+			GasUsed:          hexutil.Uint64(receipt.GasUsed),
+		})
+	}
+}
+
+func (s *sequencedTxSubscriptions) notifyDropped(tx *types.Transaction, queuedAt time.Time, reason error) {
+	if !s.hasSubscribers() {
+		return
+	}
+	s.send(&SequencedTransaction{
+		TxHash:   tx.Hash(),
+		Dropped:  true,
+		Reason:   reason.Error(),
+		QueuedAt: hexutil.Uint64(queuedAt.UnixMilli()),
+	})
+}
+
+type ArbSubscriptionAPI struct {
+	execEngine *ExecutionEngine
+}
+
+func NewArbSubscriptionAPI(execEngine *ExecutionEngine) *ArbSubscriptionAPI {
+	return &ArbSubscriptionAPI{execEngine}
+}
+
+// SequencedTransactions notifies the subscriber of every transaction as soon as it's sequenced into a block,
+// and of every transaction the sequencer accepted into its queue but dropped without sequencing,
+// e.g. because it sat in the queue for too long, available as arb_subscribe("sequencedTransactions").
+func (a *ArbSubscriptionAPI) SequencedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+	sequenced, unsubscribe := a.execEngine.SubscribeSequencedTransactions()
+	go forwardNotifications(notifier, rpcSub, sequenced, unsubscribe)
+	return rpcSub, nil
+}
+
+func forwardNotifications[T any](notifier *rpc.Notifier, rpcSub *rpc.Subscription, notifications <-chan T, unsubscribe func()) {
+	defer unsubscribe()
+	for {
+		select {
+		case notification := <-notifications:
+			if err := notifier.Notify(rpcSub.ID, notification); err != nil {
+				log.Debug("failed to notify transaction subscriber", "err", err)
+				return
+			}
+		case <-rpcSub.Err():
+			return
+		}
+	}
+}
@@ -4,6 +4,7 @@
 package gethexec
 
 import (
+	"container/heap"
 	"context"
 	"errors"
 	"fmt"
@@ -20,11 +21,13 @@ import (
 	"github.com/offchainlabs/nitro/util/arbmath"
 	"github.com/offchainlabs/nitro/util/containers"
 	"github.com/offchainlabs/nitro/util/headerreader"
+	"github.com/offchainlabs/nitro/util/latencytracker"
 	flag "github.com/spf13/pflag"
 
 	"github.com/ethereum/go-ethereum/arbitrum"
 	"github.com/ethereum/go-ethereum/arbitrum_types"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/txpool"
@@ -51,23 +54,42 @@ var (
 	successfulBlocksCounter                 = metrics.NewRegisteredCounter("arb/sequencer/block/successful", nil)
 	conditionalTxRejectedBySequencerCounter = metrics.NewRegisteredCounter("arb/sequencer/condtionaltx/rejected", nil)
 	conditionalTxAcceptedBySequencerCounter = metrics.NewRegisteredCounter("arb/sequencer/condtionaltx/accepted", nil)
+	sequencingPausedGauge                   = metrics.NewRegisteredGauge("arb/sequencer/paused", nil)
+	chainFrozenGauge                        = metrics.NewRegisteredGauge("arb/sequencer/frozen", nil)
+	expiredTxCounter                        = metrics.NewRegisteredCounter("arb/sequencer/queue/expired", nil)
+	underpricedTxRejectedCounter            = metrics.NewRegisteredCounter("arb/sequencer/underpriced/rejected", nil)
+	oversizedTxRejectedCounter              = metrics.NewRegisteredCounter("arb/sequencer/oversized/rejected", nil)
 )
 
 type SequencerConfig struct {
-	Enable                      bool            `koanf:"enable"`
-	MaxBlockSpeed               time.Duration   `koanf:"max-block-speed" reload:"hot"`
-	MaxRevertGasReject          uint64          `koanf:"max-revert-gas-reject" reload:"hot"`
-	MaxAcceptableTimestampDelta time.Duration   `koanf:"max-acceptable-timestamp-delta" reload:"hot"`
-	SenderWhitelist             string          `koanf:"sender-whitelist"`
-	Forwarder                   ForwarderConfig `koanf:"forwarder"`
-	QueueSize                   int             `koanf:"queue-size"`
-	QueueTimeout                time.Duration   `koanf:"queue-timeout" reload:"hot"`
-	NonceCacheSize              int             `koanf:"nonce-cache-size" reload:"hot"`
-	MaxTxDataSize               int             `koanf:"max-tx-data-size" reload:"hot"`
-	NonceFailureCacheSize       int             `koanf:"nonce-failure-cache-size" reload:"hot"`
-	NonceFailureCacheExpiry     time.Duration   `koanf:"nonce-failure-cache-expiry" reload:"hot"`
+	Enable                      bool                   `koanf:"enable"`
+	MaxBlockSpeed               time.Duration          `koanf:"max-block-speed" reload:"hot"`
+	MaxRevertGasReject          uint64                 `koanf:"max-revert-gas-reject" reload:"hot"`
+	MaxAcceptableTimestampDelta time.Duration          `koanf:"max-acceptable-timestamp-delta" reload:"hot"`
+	SenderWhitelist             string                 `koanf:"sender-whitelist"`
+	Forwarder                   ForwarderConfig        `koanf:"forwarder"`
+	QueueSize                   int                    `koanf:"queue-size"`
+	QueueTimeout                time.Duration          `koanf:"queue-timeout" reload:"hot"`
+	MaxTxAge                    time.Duration          `koanf:"max-tx-age" reload:"hot"`
+	MinBaseFeeMultipleBips      arbmath.Bips           `koanf:"min-base-fee-multiple-bips" reload:"hot"`
+	NonceCacheSize              int                    `koanf:"nonce-cache-size" reload:"hot"`
+	MaxTxDataSize               int                    `koanf:"max-tx-data-size" reload:"hot"`
+	MaxTxCalldataSize           int                    `koanf:"max-tx-calldata-size" reload:"hot"`
+	NonceFailureCacheSize       int                    `koanf:"nonce-failure-cache-size" reload:"hot"`
+	NonceFailureCacheExpiry     time.Duration          `koanf:"nonce-failure-cache-expiry" reload:"hot"`
+	OrderingPolicy              string                 `koanf:"ordering-policy" reload:"hot"`
+	Journal                     SequencerJournalConfig `koanf:"journal"`
+	FreezeAtBlock               uint64                 `koanf:"freeze-at-block" reload:"hot"`
 }
 
+const (
+	// OrderingPolicyFCFS sequences transactions in the order they arrived
+	OrderingPolicyFCFS = "fcfs"
+	// OrderingPolicyEffectiveTip sequences the transactions collected for a block by descending effective tip,
+	// while keeping each sender's transactions in nonce order
+	OrderingPolicyEffectiveTip = "effective-tip"
+)
+
 func (c *SequencerConfig) Validate() error {
 	entries := strings.Split(c.SenderWhitelist, ",")
 	for _, address := range entries {
@@ -78,7 +100,16 @@ func (c *SequencerConfig) Validate() error {
 			return fmt.Errorf("sequencer sender whitelist entry \"%v\" is not a valid address", address)
 		}
 	}
-	return nil
+	if c.OrderingPolicy != OrderingPolicyFCFS && c.OrderingPolicy != OrderingPolicyEffectiveTip {
+		return fmt.Errorf("invalid sequencer ordering policy \"%v\"", c.OrderingPolicy)
+	}
+	if c.MaxTxDataSize > arbostypes.MaxL2MessageSize {
+		return fmt.Errorf("sequencer max-tx-data-size %v exceeds the maximum L2 message size %v", c.MaxTxDataSize, arbostypes.MaxL2MessageSize)
+	}
+	if c.MaxTxCalldataSize < 0 || c.MaxTxCalldataSize > c.MaxTxDataSize {
+		return fmt.Errorf("sequencer max-tx-calldata-size %v must be between 0 and max-tx-data-size %v", c.MaxTxCalldataSize, c.MaxTxDataSize)
+	}
+	return c.Journal.Validate()
 }
 
 type SequencerConfigFetcher func() *SequencerConfig
@@ -91,12 +122,18 @@ var DefaultSequencerConfig = SequencerConfig{
 	Forwarder:                   DefaultSequencerForwarderConfig,
 	QueueSize:                   1024,
 	QueueTimeout:                time.Second * 12,
+	MaxTxAge:                    0,
+	MinBaseFeeMultipleBips:      0,
 	NonceCacheSize:              1024,
 	// 95% of the default batch poster limit, leaving 5KB for headers and such
 	// This default is overridden for L3 chains in applyChainParameters in cmd/nitro/nitro.go
 	MaxTxDataSize:           95000,
+	MaxTxCalldataSize:       0,
 	NonceFailureCacheSize:   1024,
 	NonceFailureCacheExpiry: time.Second,
+	OrderingPolicy:          OrderingPolicyFCFS,
+	Journal:                 DefaultSequencerJournalConfig,
+	FreezeAtBlock:           0,
 }
 
 var TestSequencerConfig = SequencerConfig{
@@ -108,10 +145,16 @@ var TestSequencerConfig = SequencerConfig{
 	Forwarder:                   DefaultTestForwarderConfig,
 	QueueSize:                   128,
 	QueueTimeout:                time.Second * 5,
+	MaxTxAge:                    0,
+	MinBaseFeeMultipleBips:      0,
 	NonceCacheSize:              4,
 	MaxTxDataSize:               95000,
+	MaxTxCalldataSize:           0,
 	NonceFailureCacheSize:       1024,
 	NonceFailureCacheExpiry:     time.Second,
+	OrderingPolicy:              OrderingPolicyFCFS,
+	Journal:                     DefaultSequencerJournalConfig,
+	FreezeAtBlock:               0,
 }
 
 func SequencerConfigAddOptions(prefix string, f *flag.FlagSet) {
@@ -123,10 +166,16 @@ func SequencerConfigAddOptions(prefix string, f *flag.FlagSet) {
 	AddOptionsForSequencerForwarderConfig(prefix+".forwarder", f)
 	f.Int(prefix+".queue-size", DefaultSequencerConfig.QueueSize, "size of the pending tx queue")
 	f.Duration(prefix+".queue-timeout", DefaultSequencerConfig.QueueTimeout, "maximum amount of time transaction can wait in queue")
+	f.Uint64(prefix+".min-base-fee-multiple-bips", uint64(DefaultSequencerConfig.MinBaseFeeMultipleBips), "reject transactions whose max fee per gas is below this multiple of the next block's L2 base fee (measured in basis points) when they're submitted (0 to disable)")
+	f.Duration(prefix+".max-tx-age", DefaultSequencerConfig.MaxTxAge, "maximum amount of time since a transaction was first queued before it's dropped instead of sequenced, even if its submitter is still waiting (0 to disable)")
 	f.Int(prefix+".nonce-cache-size", DefaultSequencerConfig.NonceCacheSize, "size of the tx sender nonce cache")
 	f.Int(prefix+".max-tx-data-size", DefaultSequencerConfig.MaxTxDataSize, "maximum transaction size the sequencer will accept")
+	f.Int(prefix+".max-tx-calldata-size", DefaultSequencerConfig.MaxTxCalldataSize, "maximum transaction calldata size the sequencer will accept (0 = only limited by max-tx-data-size)")
 	f.Int(prefix+".nonce-failure-cache-size", DefaultSequencerConfig.NonceFailureCacheSize, "number of transactions with too high of a nonce to keep in memory while waiting for their predecessor")
 	f.Duration(prefix+".nonce-failure-cache-expiry", DefaultSequencerConfig.NonceFailureCacheExpiry, "maximum amount of time to wait for a predecessor before rejecting a tx with nonce too high")
+	f.String(prefix+".ordering-policy", DefaultSequencerConfig.OrderingPolicy, "how to order the transactions in a block (\""+OrderingPolicyFCFS+"\" for arrival order or \""+OrderingPolicyEffectiveTip+"\" to prefer higher tips)")
+	SequencerJournalConfigAddOptions(prefix+".journal", f)
+	f.Uint64(prefix+".freeze-at-block", DefaultSequencerConfig.FreezeAtBlock, "stop sequencing once the chain reaches this block, to freeze the chain for a migration announced in advance (0 to disable)")
 }
 
 type txQueueItem struct {
@@ -138,6 +187,41 @@ type txQueueItem struct {
 	firstAppearance time.Time
 }
 
+var ErrTxExpiredInQueue = errors.New("transaction expired in the sequencer queue")
+
+// UnderpricedError is returned when a transaction's max fee per gas is too low to be sequenced,
+// and tells the submitter the minimum fee that'd currently be accepted.
+type UnderpricedError struct {
+	sender       common.Address
+	maxFeePerGas *big.Int
+	minFeePerGas *big.Int
+}
+
+func (e UnderpricedError) Error() string {
+	return fmt.Sprintf("%v: address %v, maxFeePerGas: %s minimum: %s", core.ErrFeeCapTooLow, e.sender, e.maxFeePerGas, e.minFeePerGas)
+}
+
+func (e UnderpricedError) Unwrap() error {
+	return core.ErrFeeCapTooLow
+}
+
+// ErrorCode and ErrorData expose the minimum fee to RPC clients
+func (e UnderpricedError) ErrorCode() int {
+	return -32000
+}
+
+func (e UnderpricedError) ErrorData() interface{} {
+	return map[string]*hexutil.Big{
+		"maxFeePerGas": (*hexutil.Big)(e.maxFeePerGas),
+		"minFeePerGas": (*hexutil.Big)(e.minFeePerGas),
+	}
+}
+
+// MinFeePerGas is the lowest max fee per gas the sequencer would have accepted
+func (e UnderpricedError) MinFeePerGas() *big.Int {
+	return new(big.Int).Set(e.minFeePerGas)
+}
+
 func (i *txQueueItem) returnResult(err error) {
 	if i.returnedResult {
 		log.Error("attempting to return result to already finished queue item", "err", err)
@@ -148,6 +232,20 @@ func (i *txQueueItem) returnResult(err error) {
 	close(i.resultChan)
 }
 
+// queueItemErr returns why the queue item should be dropped instead of sequenced, if it should be
+func (s *Sequencer) queueItemErr(queueItem *txQueueItem) error {
+	if err := queueItem.ctx.Err(); err != nil {
+		return err
+	}
+	maxAge := s.config().MaxTxAge
+	if maxAge > 0 && time.Since(queueItem.firstAppearance) > maxAge {
+		expiredTxCounter.Inc(1)
+		s.execEngine.sequencedTxSubscriptions.notifyDropped(queueItem.tx, queueItem.firstAppearance, ErrTxExpiredInQueue)
+		return ErrTxExpiredInQueue
+	}
+	return nil
+}
+
 type nonceCache struct {
 	cache *containers.LruCache[common.Address, uint64]
 	block common.Hash
@@ -291,6 +389,18 @@ type Sequencer struct {
 	activeMutex sync.Mutex
 	pauseChan   chan struct{}
 	forwarder   *TxForwarder
+
+	// haltChan is non-nil while an operator has paused sequencing
+	// unlike pauseChan, it's independent of the role assigned by the coordinator
+	haltMutex sync.Mutex
+	haltChan  chan struct{}
+
+	journal *sequencerJournal // nil unless the journal is enabled
+
+	// nextBaseFee caches projectedBaseFee for the head block nextBaseFeeBlock
+	nextBaseFeeMutex sync.Mutex
+	nextBaseFeeBlock common.Hash
+	nextBaseFee      *big.Int
 }
 
 func NewSequencer(execEngine *ExecutionEngine, l1Reader *headerreader.HeaderReader, configFetcher SequencerConfigFetcher) (*Sequencer, error) {
@@ -322,6 +432,13 @@ func NewSequencer(execEngine *ExecutionEngine, l1Reader *headerreader.HeaderRead
 		containers.NewLruCacheWithOnEvict(config.NonceCacheSize, s.onNonceFailureEvict),
 		func() time.Duration { return configFetcher().NonceFailureCacheExpiry },
 	}
+	if config.Journal.Enable {
+		journal, err := openSequencerJournal(config.Journal.File)
+		if err != nil {
+			return nil, err
+		}
+		s.journal = journal
+	}
 	s.Pause()
 	execEngine.EnableReorgSequencing()
 	return s, nil
@@ -355,12 +472,124 @@ func (s *Sequencer) onNonceFailureEvict(_ addressAndNonce, failure *nonceFailure
 	}
 }
 
-// ctxWithTimeout is like context.WithTimeout except a timeout of 0 means unlimited instead of instantly expired.
-func ctxWithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+// projectedBaseFee is the base fee of the next block, which ArbOS already set when it finished the latest one.
+// It's cached per head block, so only the first transaction published after each block opens its state.
+func (s *Sequencer) projectedBaseFee() (*big.Int, error) {
+	bc := s.execEngine.bc
+	header := bc.CurrentBlock()
+	if header == nil {
+		return nil, nil
+	}
+	s.nextBaseFeeMutex.Lock()
+	defer s.nextBaseFeeMutex.Unlock()
+	blockHash := header.Hash()
+	if s.nextBaseFee != nil && s.nextBaseFeeBlock == blockHash {
+		return s.nextBaseFee, nil
+	}
+	statedb, err := bc.StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	arbState, err := arbosState.OpenSystemArbosState(statedb, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	baseFee, err := arbState.L2PricingState().BaseFeeWei()
+	if err != nil {
+		return nil, err
+	}
+	s.nextBaseFeeBlock = blockHash
+	s.nextBaseFee = baseFee
+	return baseFee, nil
+}
+
+// checkBaseFee rejects a transaction that can't pay the projected base fee up front, rather than queueing it to fail
+func (s *Sequencer) checkBaseFee(tx *types.Transaction) error {
+	multiple := s.config().MinBaseFeeMultipleBips
+	if multiple == 0 {
+		return nil
+	}
+	baseFee, err := s.projectedBaseFee()
+	if err != nil || baseFee == nil {
+		return err
+	}
+	minFeePerGas := arbmath.BigMulByBips(baseFee, multiple)
+	if !arbmath.BigLessThan(tx.GasFeeCap(), minFeePerGas) {
+		return nil
+	}
+	underpricedTxRejectedCounter.Inc(1)
+	signer := types.LatestSigner(s.execEngine.bc.Config())
+	sender, err := types.Sender(signer, tx)
+	if err != nil {
+		return err
+	}
+	return UnderpricedError{
+		sender:       sender,
+		maxFeePerGas: tx.GasFeeCap(),
+		minFeePerGas: minFeePerGas,
+	}
+}
+
+// queueTimeoutCtx reports a deadline exceeded error once its queue timeout has passed
+type queueTimeoutCtx struct {
+	context.Context
+	expired atomic.Bool
+}
+
+func (c *queueTimeoutCtx) Err() error {
+	if c.expired.Load() {
+		return context.DeadlineExceeded
+	}
+	return c.Context.Err()
+}
+
+// ctxWithQueueTimeout is like context.WithTimeout except a timeout of 0 means unlimited, and time spent with sequencing
+// paused by the operator doesn't count, so transactions queued while paused are still sequenced once it's resumed.
+// If the timeout runs out while paused, the transaction gets the full timeout again on resuming. MaxTxAge still applies.
+func (s *Sequencer) ctxWithQueueTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
 	if timeout == time.Duration(0) {
-		return context.WithCancel(ctx)
+		return context.WithCancel(parent)
 	}
-	return context.WithTimeout(ctx, timeout)
+	inner, cancel := context.WithCancel(parent)
+	ctx := &queueTimeoutCtx{Context: inner}
+	go func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		for {
+			select {
+			case <-inner.Done():
+				return
+			case <-timer.C:
+			}
+			halt := s.getHaltChan()
+			if halt == nil {
+				ctx.expired.Store(true)
+				cancel()
+				return
+			}
+			select {
+			case <-inner.Done():
+				return
+			case <-halt:
+			}
+			timer.Reset(timeout)
+		}
+	}()
+	return ctx, cancel
+}
+
+// checkTxSize rejects a transaction too large to sequence when it's submitted, rather than once it's dequeued
+func (s *Sequencer) checkTxSize(tx *types.Transaction) error {
+	config := s.config()
+	if size := tx.Size(); size > uint64(config.MaxTxDataSize) {
+		oversizedTxRejectedCounter.Inc(1)
+		return fmt.Errorf("%w: transaction size %v exceeds the sequencer's limit of %v bytes", txpool.ErrOversizedData, size, config.MaxTxDataSize)
+	}
+	if config.MaxTxCalldataSize > 0 && len(tx.Data()) > config.MaxTxCalldataSize {
+		oversizedTxRejectedCounter.Inc(1)
+		return fmt.Errorf("%w: transaction calldata size %v exceeds the sequencer's limit of %v bytes", txpool.ErrOversizedData, len(tx.Data()), config.MaxTxCalldataSize)
+	}
+	return nil
 }
 
 func (s *Sequencer) PublishTransaction(parentCtx context.Context, tx *types.Transaction, options *arbitrum_types.ConditionalOptions) error {
@@ -374,6 +603,9 @@ func (s *Sequencer) PublishTransaction(parentCtx context.Context, tx *types.Tran
 			return err
 		}
 	}
+	if s.execEngine.Frozen() {
+		return execution.ErrChainFrozen
+	}
 
 	if len(s.senderWhitelist) > 0 {
 		signer := types.LatestSigner(s.execEngine.bc.Config())
@@ -392,12 +624,19 @@ func (s *Sequencer) PublishTransaction(parentCtx context.Context, tx *types.Tran
 		return types.ErrTxTypeNotSupported
 	}
 
+	if err := s.checkTxSize(tx); err != nil {
+		return err
+	}
+	if err := s.checkBaseFee(tx); err != nil {
+		return err
+	}
+
 	queueTimeout := s.config().QueueTimeout
-	queueCtx, cancelFunc := ctxWithTimeout(parentCtx, queueTimeout)
+	queueCtx, cancelFunc := s.ctxWithQueueTimeout(parentCtx, queueTimeout)
 	defer cancelFunc()
 
 	// Just to be safe, make sure we don't run over twice the queue timeout
-	abortCtx, cancel := ctxWithTimeout(parentCtx, queueTimeout*2)
+	abortCtx, cancel := s.ctxWithQueueTimeout(parentCtx, queueTimeout*2)
 	defer cancel()
 
 	resultChan := make(chan error, 1)
@@ -462,7 +701,7 @@ func (s *Sequencer) postTxFilter(header *types.Header, _ *arbosState.ArbosState,
 		nonceFailure.revived = true // prevent the expiry hook from taking effect
 		s.nonceFailures.Remove(newAddrAndNonce)
 		// Immediately check if the transaction submission has been canceled
-		err := nonceFailure.queueItem.ctx.Err()
+		err := s.queueItemErr(&nonceFailure.queueItem)
 		if err != nil {
 			nonceFailure.queueItem.returnResult(err)
 		} else {
@@ -548,6 +787,52 @@ func (s *Sequencer) Pause() {
 	}
 }
 
+// PauseSequencing stops block production until ResumeSequencing is called.
+// Transactions are still accepted and wait in the queue, subject to the queue timeout.
+func (s *Sequencer) PauseSequencing() {
+	s.haltMutex.Lock()
+	defer s.haltMutex.Unlock()
+	if s.haltChan == nil {
+		log.Warn("sequencing paused by operator")
+		s.haltChan = make(chan struct{})
+		sequencingPausedGauge.Update(1)
+	}
+}
+
+func (s *Sequencer) ResumeSequencing() {
+	s.haltMutex.Lock()
+	defer s.haltMutex.Unlock()
+	if s.haltChan != nil {
+		log.Info("sequencing resumed by operator")
+		close(s.haltChan)
+		s.haltChan = nil
+		sequencingPausedGauge.Update(0)
+	}
+}
+
+func (s *Sequencer) SequencingPaused() bool {
+	s.haltMutex.Lock()
+	defer s.haltMutex.Unlock()
+	return s.haltChan != nil
+}
+
+func (s *Sequencer) getHaltChan() chan struct{} {
+	s.haltMutex.Lock()
+	defer s.haltMutex.Unlock()
+	return s.haltChan
+}
+
+// updateFreezeAtBlock passes the configured freeze block on to the execution engine, which also stops
+// sequencing delayed messages past it
+func (s *Sequencer) updateFreezeAtBlock() {
+	s.execEngine.SetFreezeAtBlock(s.config().FreezeAtBlock)
+	if s.execEngine.Frozen() {
+		chainFrozenGauge.Update(1)
+	} else {
+		chainFrozenGauge.Update(0)
+	}
+}
+
 var ErrNoSequencer = errors.New("sequencer temporarily not available")
 
 func (s *Sequencer) GetPauseAndForwarder() (chan struct{}, *TxForwarder) {
@@ -674,7 +959,7 @@ func (s *Sequencer) precheckNonces(queueItems []txQueueItem) []txQueueItem {
 				// Re-enqueue the tx whose nonce should now be correct, unless it expired
 				revivingFailure.revived = true
 				s.nonceFailures.Remove(nextKey)
-				err := revivingFailure.queueItem.ctx.Err()
+				err := s.queueItemErr(&revivingFailure.queueItem)
 				if err != nil {
 					revivingFailure.queueItem.returnResult(err)
 				} else {
@@ -712,7 +997,98 @@ func (s *Sequencer) precheckNonces(queueItems []txQueueItem) []txQueueItem {
 	return outputQueueItems
 }
 
+type tipOrderedItem struct {
+	queueItem txQueueItem
+	sender    common.Address
+	tip       *big.Int
+	arrival   int
+}
+
+// tipOrderedHeads is a max-heap over the next pending transaction of each sender
+type tipOrderedHeads []*tipOrderedItem
+
+func (h tipOrderedHeads) Len() int { return len(h) }
+func (h tipOrderedHeads) Less(i, j int) bool {
+	cmp := h[i].tip.Cmp(h[j].tip)
+	if cmp == 0 {
+		// break ties by arrival to remain FCFS among equal tips
+		return h[i].arrival < h[j].arrival
+	}
+	return cmp > 0
+}
+func (h tipOrderedHeads) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *tipOrderedHeads) Push(x any)   { *h = append(*h, x.(*tipOrderedItem)) }
+func (h *tipOrderedHeads) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// orderQueueItems orders the transactions collected for a block according to the ordering policy
+func (s *Sequencer) orderQueueItems(policy string, queueItems []txQueueItem) []txQueueItem {
+	if policy != OrderingPolicyEffectiveTip {
+		return queueItems
+	}
+	bc := s.execEngine.bc
+	latestHeader := bc.CurrentBlock()
+	signer := types.MakeSigner(bc.Config(), arbmath.BigAdd(latestHeader.Number, common.Big1), latestHeader.Time)
+	return orderByEffectiveTip(queueItems, signer, latestHeader.BaseFee)
+}
+
+// orderByEffectiveTip reorders the queue items by descending effective tip against the base fee.
+// A sender's transactions keep their relative order so that nonces remain sequential.
+func orderByEffectiveTip(queueItems []txQueueItem, signer types.Signer, baseFee *big.Int) []txQueueItem {
+	bySender := make(map[common.Address][]*tipOrderedItem)
+	var senders []common.Address
+	for i, queueItem := range queueItems {
+		sender, err := types.Sender(signer, queueItem.tx)
+		if err != nil {
+			// precheckNonces already filtered these out; leave the ordering as is to be safe
+			log.Warn("failed to get sender while ordering transactions by tip", "err", err)
+			return queueItems
+		}
+		tip, err := queueItem.tx.EffectiveGasTip(baseFee)
+		if err != nil {
+			// the tx can't pay the base fee, so it has no tip to speak of
+			tip = new(big.Int)
+		}
+		if _, seen := bySender[sender]; !seen {
+			senders = append(senders, sender)
+		}
+		bySender[sender] = append(bySender[sender], &tipOrderedItem{queueItem, sender, tip, i})
+	}
+
+	heads := make(tipOrderedHeads, 0, len(senders))
+	for _, sender := range senders {
+		heads = append(heads, bySender[sender][0])
+		bySender[sender] = bySender[sender][1:]
+	}
+	heap.Init(&heads)
+
+	ordered := make([]txQueueItem, 0, len(queueItems))
+	for heads.Len() > 0 {
+		item := heap.Pop(&heads).(*tipOrderedItem) //nolint:errcheck
+		ordered = append(ordered, item.queueItem)
+		if rest := bySender[item.sender]; len(rest) > 0 {
+			heap.Push(&heads, rest[0])
+			bySender[item.sender] = rest[1:]
+		}
+	}
+	return ordered
+}
+
 func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
+	if halt := s.getHaltChan(); halt != nil {
+		// leave transactions in the queue until the operator resumes sequencing
+		select {
+		case <-halt:
+		case <-ctx.Done():
+		}
+		return false
+	}
+	s.updateFreezeAtBlock()
+
 	var queueItems []txQueueItem
 	var totalBatchSize int
 
@@ -780,7 +1156,7 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 				break
 			}
 		}
-		err := queueItem.ctx.Err()
+		err := s.queueItemErr(&queueItem)
 		if err != nil {
 			queueItem.returnResult(err)
 			continue
@@ -808,6 +1184,7 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 	s.nonceCache.Resize(config.NonceCacheSize) // Would probably be better in a config hook but this is basically free
 	s.nonceCache.BeginNewBlock()
 	queueItems = s.precheckNonces(queueItems)
+	queueItems = s.orderQueueItems(config.OrderingPolicy, queueItems)
 	txes := make([]*types.Transaction, len(queueItems))
 	hooks := s.makeSequencingHooks()
 	hooks.ConditionalOptionsForTx = make([]*arbitrum_types.ConditionalOptions, len(queueItems))
@@ -815,6 +1192,10 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 		txes[i] = queueItem.tx
 		hooks.ConditionalOptionsForTx[i] = queueItem.options
 	}
+	var filtered map[common.Hash]string
+	if s.journal != nil {
+		filtered = s.journal.trackFilters(hooks)
+	}
 
 	if s.handleInactive(ctx, queueItems) {
 		return false
@@ -872,6 +1253,13 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 		}
 		return false
 	}
+	if errors.Is(err, execution.ErrChainFrozen) {
+		// the chain reached the freeze block while these transactions were queued
+		for _, queueItem := range queueItems {
+			queueItem.returnResult(err)
+		}
+		return true
+	}
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			// thread closed. We'll later try to forward these messages.
@@ -890,6 +1278,11 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 	if block != nil {
 		successfulBlocksCounter.Inc(1)
 		s.nonceCache.Finalize(block)
+		if s.journal != nil {
+			if err := s.journal.record(block, header, config.OrderingPolicy, queueItems, hooks.TxErrors, filtered); err != nil {
+				log.Error("error recording block in sequencer journal", "block", block.Number(), "err", err)
+			}
+		}
 	}
 
 	madeBlock := false
@@ -915,6 +1308,9 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 			s.nonceFailures.Add(nonceError, queueItem)
 			continue
 		}
+		if err == nil {
+			latencytracker.TransactionSequenced(queueItem.firstAppearance)
+		}
 		queueItem.returnResult(err)
 	}
 	return madeBlock
@@ -946,6 +1342,7 @@ func (s *Sequencer) Initialize(ctx context.Context) error {
 
 func (s *Sequencer) Start(ctxIn context.Context) error {
 	s.StopWaiter.Start(ctxIn, s)
+	s.updateFreezeAtBlock()
 	if s.l1Reader != nil {
 		initialBlockNr := atomic.LoadUint64(&s.l1BlockNumber)
 		if initialBlockNr == 0 {
@@ -987,6 +1384,9 @@ func (s *Sequencer) Start(ctxIn context.Context) error {
 
 func (s *Sequencer) StopAndWait() {
 	s.StopWaiter.StopAndWait()
+	if s.journal != nil {
+		s.journal.close()
+	}
 	if s.txRetryQueue.Len() == 0 && len(s.txQueue) == 0 && s.nonceFailures.Len() == 0 {
 		return
 	}
@@ -0,0 +1,258 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/arbitrum_types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbos"
+	"github.com/offchainlabs/nitro/arbos/arbosState"
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+)
+
+type SequencerJournalConfig struct {
+	Enable bool   `koanf:"enable"`
+	File   string `koanf:"file"`
+}
+
+var DefaultSequencerJournalConfig = SequencerJournalConfig{
+	Enable: false,
+	File:   "",
+}
+
+func SequencerJournalConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultSequencerJournalConfig.Enable, "record the sequencer's inputs and ordering decisions for each block it creates, so the block can be replayed with arbdebug_replaySequencerJournalEntry")
+	f.String(prefix+".file", DefaultSequencerJournalConfig.File, "file to append the sequencer journal to, one JSON entry per line")
+}
+
+func (c *SequencerJournalConfig) Validate() error {
+	if c.Enable && c.File == "" {
+		return errors.New("sequencer journal enabled but no file set")
+	}
+	return nil
+}
+
+// Which of the sequencer's filters rejected a transaction
+const (
+	journalFilterPre  = "pre"
+	journalFilterPost = "post"
+)
+
+type SequencerJournalTx struct {
+	Hash    common.Hash                        `json:"hash"`
+	Tx      hexutil.Bytes                      `json:"tx"`
+	Arrival time.Time                          `json:"arrival"`
+	Options *arbitrum_types.ConditionalOptions `json:"options,omitempty"`
+	// Filter is set if the transaction was rejected by the sequencer's filters rather than by executing it
+	Filter string `json:"filter,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SequencerJournalEntry records everything the sequencer decided in creating a block: the header it chose,
+// and every transaction it considered in the order it tried them, with whether and why each was rejected.
+type SequencerJournalEntry struct {
+	BlockNumber    uint64                              `json:"blockNumber"`
+	BlockHash      common.Hash                         `json:"blockHash"`
+	ParentHash     common.Hash                         `json:"parentHash"`
+	OrderingPolicy string                              `json:"orderingPolicy"`
+	Header         *arbostypes.L1IncomingMessageHeader `json:"header"`
+	Txs            []SequencerJournalTx                `json:"txs"`
+}
+
+type sequencerJournal struct {
+	mutex   sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func openSequencerJournal(path string) (*sequencerJournal, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening sequencer journal: %w", err)
+	}
+	return &sequencerJournal{
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+// trackFilters wraps the hooks' filters to note which transactions they reject
+func (j *sequencerJournal) trackFilters(hooks *arbos.SequencingHooks) map[common.Hash]string {
+	filtered := make(map[common.Hash]string)
+	preTxFilter, postTxFilter := hooks.PreTxFilter, hooks.PostTxFilter
+	hooks.PreTxFilter = func(config *params.ChainConfig, header *types.Header, statedb *state.StateDB, state *arbosState.ArbosState, tx *types.Transaction, options *arbitrum_types.ConditionalOptions, sender common.Address, l1Info *arbos.L1Info) error {
+		err := preTxFilter(config, header, statedb, state, tx, options, sender, l1Info)
+		if err != nil {
+			filtered[tx.Hash()] = journalFilterPre
+		}
+		return err
+	}
+	hooks.PostTxFilter = func(header *types.Header, state *arbosState.ArbosState, tx *types.Transaction, sender common.Address, dataGas uint64, result *core.ExecutionResult) error {
+		err := postTxFilter(header, state, tx, sender, dataGas, result)
+		if err != nil {
+			filtered[tx.Hash()] = journalFilterPost
+		}
+		return err
+	}
+	return filtered
+}
+
+func (j *sequencerJournal) record(block *types.Block, header *arbostypes.L1IncomingMessageHeader, orderingPolicy string, queueItems []txQueueItem, txErrors []error, filtered map[common.Hash]string) error {
+	entry := &SequencerJournalEntry{
+		BlockNumber:    block.NumberU64(),
+		BlockHash:      block.Hash(),
+		ParentHash:     block.ParentHash(),
+		OrderingPolicy: orderingPolicy,
+		Header:         header,
+		Txs:            make([]SequencerJournalTx, 0, len(queueItems)),
+	}
+	for i, queueItem := range queueItems {
+		txBytes, err := queueItem.tx.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		journalTx := SequencerJournalTx{
+			Hash:    queueItem.tx.Hash(),
+			Tx:      txBytes,
+			Arrival: queueItem.firstAppearance,
+			Options: queueItem.options,
+		}
+		if i < len(txErrors) && txErrors[i] != nil {
+			journalTx.Error = txErrors[i].Error()
+			journalTx.Filter = filtered[journalTx.Hash]
+		}
+		entry.Txs = append(entry.Txs, journalTx)
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.encoder.Encode(entry)
+}
+
+func (j *sequencerJournal) close() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if err := j.file.Close(); err != nil {
+		log.Warn("error closing sequencer journal", "err", err)
+	}
+}
+
+type SequencerReplayMismatch struct {
+	Index    int         `json:"index"`
+	Hash     common.Hash `json:"hash"`
+	Recorded string      `json:"recorded"`
+	Replayed string      `json:"replayed"`
+}
+
+type SequencerReplayResult struct {
+	BlockHash common.Hash `json:"blockHash"`
+	// Reproduced is set if replaying the entry created the recorded block
+	Reproduced bool                      `json:"reproduced"`
+	Mismatches []SequencerReplayMismatch `json:"mismatches,omitempty"`
+}
+
+// ReplaySequencerJournalEntry re-creates a journaled block on top of its parent, applying the recorded filter
+// decisions, and reports whether the same block results and which transactions' outcomes differ.
+func ReplaySequencerJournalEntry(bc *core.BlockChain, entry *SequencerJournalEntry) (*SequencerReplayResult, error) {
+	if entry.Header == nil {
+		return nil, errors.New("sequencer journal entry has no header")
+	}
+	parent := bc.GetHeaderByHash(entry.ParentHash)
+	if parent == nil {
+		return nil, fmt.Errorf("parent block %v not found", entry.ParentHash)
+	}
+	statedb, err := bc.StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	txes := make(types.Transactions, len(entry.Txs))
+	filterErrors := make(map[common.Hash]error)
+	postFilterErrors := make(map[common.Hash]error)
+	for i, journalTx := range entry.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(journalTx.Tx); err != nil {
+			return nil, fmt.Errorf("decoding journaled transaction %v: %w", i, err)
+		}
+		txes[i] = tx
+		switch journalTx.Filter {
+		case journalFilterPre:
+			filterErrors[tx.Hash()] = errors.New(journalTx.Error)
+		case journalFilterPost:
+			postFilterErrors[tx.Hash()] = errors.New(journalTx.Error)
+		}
+	}
+	hooks := &arbos.SequencingHooks{
+		TxErrors:               []error{},
+		DiscardInvalidTxsEarly: true,
+		PreTxFilter: func(_ *params.ChainConfig, _ *types.Header, _ *state.StateDB, _ *arbosState.ArbosState, tx *types.Transaction, _ *arbitrum_types.ConditionalOptions, _ common.Address, _ *arbos.L1Info) error {
+			return filterErrors[tx.Hash()]
+		},
+		PostTxFilter: func(_ *types.Header, _ *arbosState.ArbosState, tx *types.Transaction, _ common.Address, _ uint64, _ *core.ExecutionResult) error {
+			return postFilterErrors[tx.Hash()]
+		},
+	}
+	block, _, err := arbos.ProduceBlockAdvanced(
+		entry.Header,
+		txes,
+		parent.Nonce.Uint64(),
+		parent,
+		statedb,
+		bc,
+		bc.Config(),
+		hooks,
+	)
+	if err != nil {
+		return nil, err
+	}
+	result := &SequencerReplayResult{
+		BlockHash:  block.Hash(),
+		Reproduced: block.Hash() == entry.BlockHash,
+	}
+	for i, journalTx := range entry.Txs {
+		var replayed string
+		if i < len(hooks.TxErrors) && hooks.TxErrors[i] != nil {
+			replayed = hooks.TxErrors[i].Error()
+		}
+		if replayed != journalTx.Error {
+			result.Mismatches = append(result.Mismatches, SequencerReplayMismatch{
+				Index:    i,
+				Hash:     journalTx.Hash,
+				Recorded: journalTx.Error,
+				Replayed: replayed,
+			})
+		}
+	}
+	return result, nil
+}
+
+type ArbSequencerJournalAPI struct {
+	blockchain *core.BlockChain
+}
+
+func NewArbSequencerJournalAPI(blockchain *core.BlockChain) *ArbSequencerJournalAPI {
+	return &ArbSequencerJournalAPI{blockchain}
+}
+
+// ReplaySequencerJournalEntry replays a block recorded in the sequencer journal without changing the chain
+func (a *ArbSequencerJournalAPI) ReplaySequencerJournalEntry(ctx context.Context, entry *SequencerJournalEntry) (*SequencerReplayResult, error) {
+	if entry == nil {
+		return nil, errors.New("no sequencer journal entry given")
+	}
+	return ReplaySequencerJournalEntry(a.blockchain, entry)
+}
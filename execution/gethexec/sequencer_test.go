@@ -0,0 +1,68 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func makeOrderingTestTx(t *testing.T, signer types.Signer, key *ecdsa.PrivateKey, nonce uint64, tip int64, feeCap int64) txQueueItem {
+	t.Helper()
+	tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+		ChainID:   signer.ChainID(),
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(tip),
+		GasFeeCap: big.NewInt(feeCap),
+		Gas:       21000,
+	})
+	Require(t, err)
+	return txQueueItem{tx: tx}
+}
+
+func TestOrderByEffectiveTip(t *testing.T) {
+	signer := types.LatestSignerForChainID(big.NewInt(412346))
+	baseFee := big.NewInt(100)
+	newKey := func() *ecdsa.PrivateKey {
+		key, err := crypto.GenerateKey()
+		Require(t, err)
+		return key
+	}
+	keyA, keyB, keyC, keyD := newKey(), newKey(), newKey(), newKey()
+
+	a0 := makeOrderingTestTx(t, signer, keyA, 0, 1, 1000)
+	a1 := makeOrderingTestTx(t, signer, keyA, 1, 50, 1000)
+	b0 := makeOrderingTestTx(t, signer, keyB, 0, 10, 1000)
+	c0 := makeOrderingTestTx(t, signer, keyC, 0, 10, 1000)
+	// can't pay the base fee, so it has no tip
+	d0 := makeOrderingTestTx(t, signer, keyD, 0, 10, 50)
+	arrived := []txQueueItem{a0, a1, b0, c0, d0}
+
+	// equal tips stay in arrival order, and a1 can't go before a0 despite its higher tip
+	expected := []txQueueItem{b0, c0, a0, a1, d0}
+	ordered := orderByEffectiveTip(arrived, signer, baseFee)
+	if len(ordered) != len(expected) {
+		Fail(t, "ordered", len(ordered), "transactions, expected", len(expected))
+	}
+	for i := range expected {
+		if ordered[i].tx.Hash() != expected[i].tx.Hash() {
+			Fail(t, "unexpected transaction at position", i)
+		}
+	}
+
+	if DefaultSequencerConfig.OrderingPolicy != OrderingPolicyFCFS {
+		Fail(t, "sequencer doesn't order by arrival by default")
+	}
+	sequencer := &Sequencer{}
+	unchanged := sequencer.orderQueueItems(OrderingPolicyFCFS, arrived)
+	for i := range arrived {
+		if unchanged[i].tx.Hash() != arrived[i].tx.Hash() {
+			Fail(t, "fcfs policy reordered transaction at position", i)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	snapshotSessionsOpenGauge      = metrics.NewRegisteredGauge("arb/rpc/snapshots/open", nil)
+	snapshotSessionsExpiredCounter = metrics.NewRegisteredCounter("arb/rpc/snapshots/expired", nil)
+)
+
+type SnapshotSessionsConfig struct {
+	Enable      bool          `koanf:"enable"`
+	MaxSessions int           `koanf:"max-sessions" reload:"hot"`
+	IdleTimeout time.Duration `koanf:"idle-timeout" reload:"hot"`
+	MaxLifetime time.Duration `koanf:"max-lifetime" reload:"hot"`
+}
+
+var DefaultSnapshotSessionsConfig = SnapshotSessionsConfig{
+	Enable:      false,
+	MaxSessions: 1000,
+	IdleTimeout: time.Minute * 5,
+	MaxLifetime: time.Hour,
+}
+
+func SnapshotSessionsConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultSnapshotSessionsConfig.Enable, "enable the arb_openSnapshot RPC API, which pins a block's state so a client can make several consistent reads against it")
+	f.Int(prefix+".max-sessions", DefaultSnapshotSessionsConfig.MaxSessions, "maximum number of snapshot sessions open at once")
+	f.Duration(prefix+".idle-timeout", DefaultSnapshotSessionsConfig.IdleTimeout, "close a snapshot session that hasn't been read from for this long")
+	f.Duration(prefix+".max-lifetime", DefaultSnapshotSessionsConfig.MaxLifetime, "close a snapshot session this long after it was opened, releasing its state")
+}
+
+func (c *SnapshotSessionsConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.MaxSessions <= 0 {
+		return errors.New("snapshot sessions max-sessions must be positive")
+	}
+	if c.IdleTimeout <= 0 || c.MaxLifetime <= 0 {
+		return errors.New("snapshot sessions idle-timeout and max-lifetime must be positive")
+	}
+	return nil
+}
+
+type SnapshotSessionInfo struct {
+	Id          string         `json:"id"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	StateRoot   common.Hash    `json:"stateRoot"`
+	ExpiresAt   time.Time      `json:"expiresAt"`
+}
+
+type snapshotSession struct {
+	id       string
+	header   *types.Header
+	opened   time.Time
+	lastUsed time.Time
+}
+
+// SnapshotSessions pins the state of blocks on behalf of RPC clients. While a session is open its state
+// root is referenced in the trie database, so it isn't garbage collected as the chain advances, and every
+// read made through the session sees the same state, even if the block is later reorged out.
+type SnapshotSessions struct {
+	stopwaiter.StopWaiter
+	config     func() *SnapshotSessionsConfig
+	blockchain *core.BlockChain
+	rpcClient  *rpc.Client
+
+	mutex    sync.Mutex
+	sessions map[string]*snapshotSession
+	pinned   map[common.Hash]int
+}
+
+// NewSnapshotSessions creates the session manager. Calls are made through the rpc client, which should be
+// attached in-process to the node serving eth_call.
+func NewSnapshotSessions(blockchain *core.BlockChain, rpcClient *rpc.Client, config func() *SnapshotSessionsConfig) *SnapshotSessions {
+	return &SnapshotSessions{
+		config:     config,
+		blockchain: blockchain,
+		rpcClient:  rpcClient,
+		sessions:   make(map[string]*snapshotSession),
+		pinned:     make(map[common.Hash]int),
+	}
+}
+
+func (s *SnapshotSessions) Start(ctx context.Context) {
+	s.StopWaiter.Start(ctx, s)
+	s.CallIteratively(func(ctx context.Context) time.Duration {
+		s.expireSessions(time.Now())
+		return time.Second * 10
+	})
+}
+
+func (s *SnapshotSessions) StopAndWait() {
+	s.StopWaiter.StopAndWait()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for id, session := range s.sessions {
+		s.closeLocked(id, session)
+	}
+}
+
+func (s *SnapshotSessions) resolveHeader(blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		header := s.blockchain.GetHeaderByHash(hash)
+		if header == nil {
+			return nil, fmt.Errorf("block %v not found", hash)
+		}
+		return header, nil
+	}
+	number, ok := blockNrOrHash.Number()
+	if !ok {
+		return nil, errors.New("invalid block number or hash")
+	}
+	if number < 0 {
+		// latest, safe and finalized are all pinned at the current head
+		return s.blockchain.CurrentBlock(), nil
+	}
+	header := s.blockchain.GetHeaderByNumber(uint64(number))
+	if header == nil {
+		return nil, fmt.Errorf("block %v not found", number)
+	}
+	return header, nil
+}
+
+// Open pins the state of the given block in a new session
+func (s *SnapshotSessions) Open(blockNrOrHash rpc.BlockNumberOrHash) (*SnapshotSessionInfo, error) {
+	config := s.config()
+	header, err := s.resolveHeader(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	var rawId [16]byte
+	if _, err := rand.Read(rawId[:]); err != nil {
+		return nil, err
+	}
+	session := &snapshotSession{
+		id:       hex.EncodeToString(rawId[:]),
+		header:   header,
+		opened:   time.Now(),
+		lastUsed: time.Now(),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.sessions) >= config.MaxSessions {
+		return nil, fmt.Errorf("too many snapshot sessions open (limit %v)", config.MaxSessions)
+	}
+	// Reference the root before checking it's available, so it can't be collected in between
+	if s.pinned[header.Root] == 0 {
+		if err := s.blockchain.StateCache().TrieDB().Reference(header.Root, common.Hash{}); err != nil {
+			return nil, err
+		}
+	}
+	s.pinned[header.Root]++
+	if _, err := s.blockchain.StateAt(header.Root); err != nil {
+		s.unpinLocked(header.Root)
+		return nil, fmt.Errorf("state of block %v is not available: %w", header.Number, err)
+	}
+	s.sessions[session.id] = session
+	snapshotSessionsOpenGauge.Update(int64(len(s.sessions)))
+	return s.infoLocked(config, session), nil
+}
+
+func (s *SnapshotSessions) infoLocked(config *SnapshotSessionsConfig, session *snapshotSession) *SnapshotSessionInfo {
+	expiresAt := session.lastUsed.Add(config.IdleTimeout)
+	if lifetimeEnd := session.opened.Add(config.MaxLifetime); lifetimeEnd.Before(expiresAt) {
+		expiresAt = lifetimeEnd
+	}
+	return &SnapshotSessionInfo{
+		Id:          session.id,
+		BlockNumber: hexutil.Uint64(session.header.Number.Uint64()),
+		BlockHash:   session.header.Hash(),
+		StateRoot:   session.header.Root,
+		ExpiresAt:   expiresAt,
+	}
+}
+
+func (s *SnapshotSessions) unpinLocked(root common.Hash) {
+	s.pinned[root]--
+	if s.pinned[root] > 0 {
+		return
+	}
+	delete(s.pinned, root)
+	if err := s.blockchain.StateCache().TrieDB().Dereference(root); err != nil {
+		log.Warn("failed to release snapshot state", "root", root, "err", err)
+	}
+}
+
+func (s *SnapshotSessions) closeLocked(id string, session *snapshotSession) {
+	delete(s.sessions, id)
+	s.unpinLocked(session.header.Root)
+	snapshotSessionsOpenGauge.Update(int64(len(s.sessions)))
+}
+
+// Close ends the session and releases its state, returning false if it wasn't open
+func (s *SnapshotSessions) Close(id string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return false
+	}
+	s.closeLocked(id, session)
+	return true
+}
+
+func (s *SnapshotSessions) expireSessions(now time.Time) {
+	config := s.config()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for id, session := range s.sessions {
+		if now.Sub(session.lastUsed) > config.IdleTimeout || now.Sub(session.opened) > config.MaxLifetime {
+			log.Debug("snapshot session expired", "id", id, "block", session.header.Number)
+			s.closeLocked(id, session)
+			snapshotSessionsExpiredCounter.Inc(1)
+		}
+	}
+}
+
+// touch looks up an open session and marks it as used
+func (s *SnapshotSessions) touch(id string) (*types.Header, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	session, err := s.touchLocked(id)
+	if err != nil {
+		return nil, err
+	}
+	return session.header, nil
+}
+
+func (s *SnapshotSessions) touchLocked(id string) (*snapshotSession, error) {
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("snapshot session %v is not open", id)
+	}
+	session.lastUsed = time.Now()
+	return session, nil
+}
+
+// Info returns the session's block and when it expires if left unused
+func (s *SnapshotSessions) Info(id string) (*SnapshotSessionInfo, error) {
+	config := s.config()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	session, err := s.touchLocked(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.infoLocked(config, session), nil
+}
+
+// State opens a fresh state database at the session's pinned root for a single read
+func (s *SnapshotSessions) State(id string) (*state.StateDB, *types.Header, error) {
+	header, err := s.touch(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	statedb, err := s.blockchain.StateAt(header.Root)
+	if err != nil {
+		return nil, nil, err
+	}
+	return statedb, header, nil
+}
+
+// Call runs eth_call with the given arguments against the session's block
+func (s *SnapshotSessions) Call(ctx context.Context, id string, args json.RawMessage) (hexutil.Bytes, error) {
+	header, err := s.touch(id)
+	if err != nil {
+		return nil, err
+	}
+	var result hexutil.Bytes
+	err = s.rpcClient.CallContext(ctx, &result, "eth_call", args, rpc.BlockNumberOrHashWithHash(header.Hash(), false))
+	return result, err
+}
+
+// ArbSnapshotAPI serves reads against pinned state, so indexers making several calls see one consistent
+// view of the chain. The pinned block hash can also be passed to the standard eth methods.
+type ArbSnapshotAPI struct {
+	sessions *SnapshotSessions
+}
+
+func NewArbSnapshotAPI(sessions *SnapshotSessions) *ArbSnapshotAPI {
+	return &ArbSnapshotAPI{sessions}
+}
+
+func (a *ArbSnapshotAPI) OpenSnapshot(blockNrOrHash *rpc.BlockNumberOrHash) (*SnapshotSessionInfo, error) {
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	return a.sessions.Open(*blockNrOrHash)
+}
+
+func (a *ArbSnapshotAPI) CloseSnapshot(id string) bool {
+	return a.sessions.Close(id)
+}
+
+func (a *ArbSnapshotAPI) SnapshotInfo(id string) (*SnapshotSessionInfo, error) {
+	return a.sessions.Info(id)
+}
+
+func (a *ArbSnapshotAPI) SnapshotBalance(id string, address common.Address) (*hexutil.Big, error) {
+	statedb, _, err := a.sessions.State(id)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(statedb.GetBalance(address)), nil
+}
+
+func (a *ArbSnapshotAPI) SnapshotNonce(id string, address common.Address) (hexutil.Uint64, error) {
+	statedb, _, err := a.sessions.State(id)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(statedb.GetNonce(address)), nil
+}
+
+func (a *ArbSnapshotAPI) SnapshotCode(id string, address common.Address) (hexutil.Bytes, error) {
+	statedb, _, err := a.sessions.State(id)
+	if err != nil {
+		return nil, err
+	}
+	return statedb.GetCode(address), nil
+}
+
+func (a *ArbSnapshotAPI) SnapshotStorageAt(id string, address common.Address, key common.Hash) (hexutil.Bytes, error) {
+	statedb, _, err := a.sessions.State(id)
+	if err != nil {
+		return nil, err
+	}
+	value := statedb.GetState(address, key)
+	return value[:], nil
+}
+
+// SnapshotCall takes the same call arguments as eth_call
+func (a *ArbSnapshotAPI) SnapshotCall(ctx context.Context, id string, args json.RawMessage) (hexutil.Bytes, error) {
+	return a.sessions.Call(ctx, id, args)
+}
@@ -0,0 +1,30 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ExportState writes the state at the given block, as a line with the state root followed by a line
+// per account in address hash order, with its code and storage. Accounts are only given with their
+// address if the node has recorded its preimage, and with its address hash otherwise.
+func (n *ExecutionNode) ExportState(blockNum uint64, w io.Writer) (*types.Header, error) {
+	bc := n.ExecEngine.bc
+	header := bc.GetHeaderByNumber(blockNum)
+	if header == nil {
+		return nil, fmt.Errorf("block %v not found", blockNum)
+	}
+	statedb, err := bc.StateAt(header.Root)
+	if err != nil {
+		return nil, fmt.Errorf("state of block %v not available: %w", blockNum, err)
+	}
+	statedb.IterativeDump(&state.DumpConfig{}, json.NewEncoder(w))
+	return header, nil
+}
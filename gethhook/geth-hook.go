@@ -7,6 +7,7 @@ import (
 	"errors"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/log"
@@ -37,6 +38,12 @@ func (p ArbosPrecompileWrapper) RunAdvanced(
 	info.Evm.IncrementDepth()
 	defer info.Evm.DecrementDepth()
 
+	if processor, ok := info.Evm.ProcessingHook.(*arbos.TxProcessor); ok {
+		if err := processor.PrecompileCallHook(info.PrecompileAddress, info.Caller, input); err != nil {
+			return nil, 0, err
+		}
+	}
+
 	return p.inner.Call(
 		input, info.PrecompileAddress, info.ActingAsAddress,
 		info.Caller, info.Value, info.ReadOnly, gasSupplied, info.Evm,
@@ -56,16 +63,24 @@ func init() {
 	}
 
 	precompileErrors := make(map[[4]byte]abi.Error)
-	for addr, precompile := range precompiles.Precompiles() {
+	install := func(addr common.Address, precompile precompiles.ArbosPrecompile) {
 		for _, errABI := range precompile.Precompile().GetErrorABIs() {
 			var id [4]byte
 			copy(id[:], errABI.ID[:4])
 			precompileErrors[id] = errABI
 		}
+		if _, exists := vm.PrecompiledContractsArbitrum[addr]; !exists {
+			vm.PrecompiledAddressesArbitrum = append(vm.PrecompiledAddressesArbitrum, addr)
+		}
 		var wrapped vm.AdvancedPrecompile = ArbosPrecompileWrapper{precompile}
 		vm.PrecompiledContractsArbitrum[addr] = wrapped
-		vm.PrecompiledAddressesArbitrum = append(vm.PrecompiledAddressesArbitrum, addr)
 	}
+	for addr, precompile := range precompiles.Precompiles() {
+		install(addr, precompile)
+	}
+
+	// forks may register custom precompiles after this package initializes
+	precompiles.SetCustomPrecompileInstaller(install)
 
 	core.RenderRPCError = func(data []byte) error {
 		if len(data) < 4 {
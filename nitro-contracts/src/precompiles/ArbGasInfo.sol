@@ -149,4 +149,8 @@ interface ArbGasInfo {
     /// @notice Returns the L1 pricing surplus as of the last update (may be negative).
     /// Available in ArbOS version 20
     function getLastL1PricingSurplus() external view returns (int256);
+
+    /// @notice Returns the L1 calldata payments due to all batch posters for their reported spending.
+    /// Available in ArbOS version 21
+    function getL1PricingBatchPosterFundsDue() external view returns (uint256);
 }
@@ -93,6 +93,23 @@ interface ArbOwner {
     /// @notice Sets serialized chain config in ArbOS state
     function setChainConfig(string calldata chainConfig) external;
 
+    /**
+     * @notice Sets how retryables are auto-redeemed: how many times a failed auto-redeem is attempted in all (1 to 8),
+     * the most gas given to an auto-redeem (0 for no ceiling), and whether refunds go to the beneficiary rather than
+     * the fee refund address. Available in ArbOS version 21
+     */
+    function setRetryableAutoRedeemConfig(
+        uint64 attempts,
+        uint64 gasCeiling,
+        bool refundToBeneficiary
+    ) external;
+
     // Emitted when a successful call is made to this precompile
     event OwnerActs(bytes4 indexed method, address indexed owner, bytes data);
+
+    // Emitted when an ArbOS upgrade is scheduled, replacing any upgrade scheduled before (available in ArbOS version 21 and above)
+    event ArbOSUpgradeScheduled(uint64 indexed newVersion, uint64 timestamp);
+
+    // Emitted when a scheduled ArbOS upgrade takes effect (available in ArbOS version 21 and above)
+    event ArbOSUpgraded(uint64 indexed oldVersion, uint64 indexed newVersion);
 }
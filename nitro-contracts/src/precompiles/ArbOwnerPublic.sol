@@ -37,5 +37,17 @@ interface ArbOwnerPublic {
         view
         returns (uint64 arbosVersion, uint64 scheduledForTimestamp);
 
+    /// @notice Get how retryables are auto-redeemed: the number of attempts, the gas ceiling (0 if none), and
+    /// whether refunds go to the beneficiary.
+    /// Available in ArbOS version 21.
+    function getRetryableAutoRedeemConfig()
+        external
+        view
+        returns (
+            uint64 attempts,
+            uint64 gasCeiling,
+            bool refundToBeneficiary
+        );
+
     event ChainOwnerRectified(address rectifiedOwner);
 }
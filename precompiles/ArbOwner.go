@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/arbos/l1pricing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -21,9 +22,13 @@ import (
 // which ensures only a chain owner can access these methods. For methods that
 // are safe for non-owners to call, see ArbOwnerOld
 type ArbOwner struct {
-	Address          addr // 0x70
-	OwnerActs        func(ctx, mech, bytes4, addr, []byte) error
-	OwnerActsGasCost func(bytes4, addr, []byte) (uint64, error)
+	Address                      addr // 0x70
+	OwnerActs                    func(ctx, mech, bytes4, addr, []byte) error
+	OwnerActsGasCost             func(bytes4, addr, []byte) (uint64, error)
+	ArbOSUpgradeScheduled        func(ctx, mech, uint64, uint64) error
+	ArbOSUpgradeScheduledGasCost func(uint64, uint64) (uint64, error)
+	ArbOSUpgraded                func(ctx, mech, uint64, uint64) error
+	ArbOSUpgradedGasCost         func(uint64, uint64) (uint64, error)
 }
 
 var (
@@ -111,7 +116,13 @@ func (con ArbOwner) SetInfraFeeAccount(c ctx, evm mech, newNetworkFeeAccount add
 
 // ScheduleArbOSUpgrade to the requested version at the requested timestamp
 func (con ArbOwner) ScheduleArbOSUpgrade(c ctx, evm mech, newVersion uint64, timestamp uint64) error {
-	return c.State.ScheduleArbOSUpgrade(newVersion, timestamp)
+	if err := c.State.ScheduleArbOSUpgrade(newVersion, timestamp); err != nil {
+		return err
+	}
+	if c.State.ArbOSVersion() < arbostypes.ArbosVersion_UpgradeEvents {
+		return nil
+	}
+	return con.ArbOSUpgradeScheduled(c, evm, newVersion, timestamp)
 }
 
 func (con ArbOwner) SetL1PricingEquilibrationUnits(c ctx, evm mech, equilibrationUnits huge) error {
@@ -146,6 +157,11 @@ func (con ArbOwner) SetBrotliCompressionLevel(c ctx, evm mech, level uint64) err
 	return c.State.SetBrotliCompressionLevel(level)
 }
 
+// SetRetryableAutoRedeemConfig sets how many times an auto-redeem is attempted, the most gas it's given, and who it refunds
+func (con ArbOwner) SetRetryableAutoRedeemConfig(c ctx, evm mech, attempts uint64, gasCeiling uint64, refundToBeneficiary bool) error {
+	return c.State.RetryableState().SetAutoRedeemConfig(attempts, gasCeiling, refundToBeneficiary)
+}
+
 func (con ArbOwner) ReleaseL1PricerSurplusFunds(c ctx, evm mech, maxWeiToRelease huge) (huge, error) {
 	balance := evm.StateDB.GetBalance(l1pricing.L1PricerFundsPoolAddress)
 	l1p := c.State.L1PricingState()
@@ -65,3 +65,9 @@ func (con ArbOwnerPublic) GetScheduledUpgrade(c ctx, evm mech) (uint64, uint64,
 	}
 	return version, timestamp, nil
 }
+
+// GetRetryableAutoRedeemConfig gets how many times an auto-redeem is attempted, the most gas it's given (0 if unlimited),
+// and whether it refunds the beneficiary
+func (con ArbOwnerPublic) GetRetryableAutoRedeemConfig(c ctx, evm mech) (uint64, uint64, bool, error) {
+	return c.State.RetryableState().AutoRedeemConfig()
+}
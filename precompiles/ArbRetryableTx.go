@@ -99,10 +99,10 @@ func (con ArbRetryableTx) Redeem(c ctx, evm mech, ticketId bytes32) (bytes32, er
 	gasCostToReturnResult := params.CopyGas
 	gasPoolUpdateCost := storage.StorageReadCost + storage.StorageWriteCost
 	futureGasCosts := eventCost + gasCostToReturnResult + gasPoolUpdateCost
-	if c.gasLeft < futureGasCosts {
+	if c.GasLeft() < futureGasCosts {
 		return hash{}, c.Burn(futureGasCosts) // this will error
 	}
-	gasToDonate := c.gasLeft - futureGasCosts
+	gasToDonate := c.GasLeft() - futureGasCosts
 	if gasToDonate < params.TxGas {
 		return hash{}, errors.New("not enough gas to run redeem attempt")
 	}
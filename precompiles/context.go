@@ -25,55 +25,24 @@ type bytes4 = [4]byte
 type bytes32 = [32]byte
 type ctx = *Context
 
+// Context is the burner for a precompile call, which meters the call's work against the gas supplied to it
 type Context struct {
+	*burn.GasPoolBurner
 	caller      addr
-	gasSupplied uint64
-	gasLeft     uint64
 	txProcessor *arbos.TxProcessor
 	State       *arbosState.ArbosState
-	tracingInfo *util.TracingInfo
-	readOnly    bool
 }
 
-func (c *Context) Burn(amount uint64) error {
-	if c.gasLeft < amount {
-		c.gasLeft = 0
-		return vm.ErrOutOfGas
+func newContext(caller addr, gasSupplied uint64, tracingInfo *util.TracingInfo, readOnly bool) *Context {
+	return &Context{
+		GasPoolBurner: burn.NewGasPoolBurner(gasSupplied, tracingInfo, readOnly),
+		caller:        caller,
 	}
-	c.gasLeft -= amount
-	return nil
-}
-
-//nolint:unused
-func (c *Context) Burned() uint64 {
-	return c.gasSupplied - c.gasLeft
-}
-
-func (c *Context) Restrict(err error) {
-	log.Crit("A metered burner was used for access-controlled work", "error", err)
-}
-
-func (c *Context) HandleError(err error) error {
-	return err
-}
-
-func (c *Context) ReadOnly() bool {
-	return c.readOnly
-}
-
-func (c *Context) TracingInfo() *util.TracingInfo {
-	return c.tracingInfo
 }
 
 func testContext(caller addr, evm mech) *Context {
 	tracingInfo := util.NewTracingInfo(evm, common.Address{}, types.ArbosAddress, util.TracingDuringEVM)
-	ctx := &Context{
-		caller:      caller,
-		gasSupplied: ^uint64(0),
-		gasLeft:     ^uint64(0),
-		tracingInfo: tracingInfo,
-		readOnly:    false,
-	}
+	ctx := newContext(caller, ^uint64(0), tracingInfo, false)
 	state, err := arbosState.OpenArbosState(evm.StateDB, burn.NewSystemBurner(tracingInfo, false))
 	if err != nil {
 		log.Crit("unable to open arbos state", "error", err)
@@ -0,0 +1,149 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package precompiles
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// CustomPrecompile describes a precompile provided by a chain fork rather than by ArbOS itself.
+// Forks register these at startup so that the core dispatch table in Precompiles() never needs editing.
+//
+// The implementer follows the same conventions as the built-in precompiles (see MakePrecompile):
+// it must have an Address field, implement every solidity method, and provide fields for its events
+// and errors. Impure methods can reach their own ArbOS storage via Context.State.CustomPrecompileStorage.
+type CustomPrecompile struct {
+	Metadata       *bind.MetaData    // hardhat-to-geth bindings for the precompile's solidity interface
+	Implementer    interface{}       // a pointer to the struct implementing the precompile
+	ArbosVersion   uint64            // the first ArbOS version at which the precompile exists
+	MethodVersions map[string]uint64 // optional per-method activation versions, keyed by method name
+}
+
+// Addresses below this bound are reserved for Ethereum and ArbOS precompiles
+var reservedPrecompileRangeEnd = new(big.Int).SetUint64(0x10000)
+
+var (
+	ErrReservedPrecompileAddress  = errors.New("custom precompile address is in a reserved range")
+	ErrDuplicatePrecompileAddress = errors.New("custom precompile address is already taken")
+	ErrUnknownPrecompileMethod    = errors.New("custom precompile has no method with that name")
+)
+
+var customPrecompiles = struct {
+	mutex     sync.Mutex
+	contracts map[addr]ArbosPrecompile
+	installer func(addr, ArbosPrecompile)
+}{
+	contracts: make(map[addr]ArbosPrecompile),
+}
+
+// IsReservedPrecompileAddress reports whether the address belongs to a range that custom precompiles may not use
+func IsReservedPrecompileAddress(address addr) bool {
+	if address.Big().Cmp(reservedPrecompileRangeEnd) < 0 {
+		return true
+	}
+	return address == types.ArbosAddress || address == types.NodeInterfaceAddress || address == types.NodeInterfaceDebugAddress
+}
+
+// RegisterCustomPrecompile validates a fork's precompile and adds it to the set returned by Precompiles().
+// Every binary that executes blocks (the node and the replay machine alike) must register the same
+// precompiles in the same way, otherwise validation will disagree with execution.
+// See CustomPrecompilesHash for a fingerprint that can be compared between the two.
+func RegisterCustomPrecompile(custom CustomPrecompile) (*Precompile, error) {
+	if custom.Metadata == nil || custom.Implementer == nil {
+		return nil, errors.New("custom precompile is missing its metadata or implementer")
+	}
+	address, precompile := MakePrecompile(custom.Metadata, custom.Implementer)
+	if IsReservedPrecompileAddress(address) {
+		return nil, fmt.Errorf("%w: %v (%v)", ErrReservedPrecompileAddress, precompile.name, address)
+	}
+	precompile.arbosVersion = custom.ArbosVersion
+	for name, version := range custom.MethodVersions {
+		method, ok := precompile.methodsByName[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %v.%v", ErrUnknownPrecompileMethod, precompile.name, name)
+		}
+		method.arbosVersion = version
+	}
+
+	customPrecompiles.mutex.Lock()
+	defer customPrecompiles.mutex.Unlock()
+	if _, exists := customPrecompiles.contracts[address]; exists {
+		return nil, fmt.Errorf("%w: %v (%v)", ErrDuplicatePrecompileAddress, precompile.name, address)
+	}
+	customPrecompiles.contracts[address] = precompile
+	if customPrecompiles.installer != nil {
+		customPrecompiles.installer(address, precompile)
+	}
+	return precompile, nil
+}
+
+// SetCustomPrecompileInstaller lets the geth hook learn of custom precompiles regardless of whether
+// they're registered before or after it initializes. Already-registered precompiles are installed immediately.
+func SetCustomPrecompileInstaller(installer func(addr, ArbosPrecompile)) {
+	customPrecompiles.mutex.Lock()
+	defer customPrecompiles.mutex.Unlock()
+	customPrecompiles.installer = installer
+	for _, address := range sortedCustomPrecompileAddresses() {
+		installer(address, customPrecompiles.contracts[address])
+	}
+}
+
+// CustomPrecompileAddresses returns the addresses of all registered custom precompiles in ascending order
+func CustomPrecompileAddresses() []addr {
+	customPrecompiles.mutex.Lock()
+	defer customPrecompiles.mutex.Unlock()
+	return sortedCustomPrecompileAddresses()
+}
+
+// CustomPrecompilesHash fingerprints the registered custom precompiles' addresses, methods, and activation versions.
+// The node and replay machine can log or compare this value to detect mismatched fork builds.
+func CustomPrecompilesHash() common.Hash {
+	customPrecompiles.mutex.Lock()
+	defer customPrecompiles.mutex.Unlock()
+
+	var data []byte
+	for _, address := range sortedCustomPrecompileAddresses() {
+		precompile := customPrecompiles.contracts[address].Precompile()
+		data = append(data, address.Bytes()...)
+		data = append(data, common.BigToHash(new(big.Int).SetUint64(precompile.arbosVersion)).Bytes()...)
+		for _, id := range precompile.Get4ByteMethodSignatures() {
+			data = append(data, id[:]...)
+			version := precompile.methods[id].arbosVersion
+			data = append(data, common.BigToHash(new(big.Int).SetUint64(version)).Bytes()...)
+		}
+	}
+	return crypto.Keccak256Hash(data)
+}
+
+// the caller must hold the custom precompiles mutex
+func sortedCustomPrecompileAddresses() []addr {
+	addresses := make([]addr, 0, len(customPrecompiles.contracts))
+	for address := range customPrecompiles.contracts {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].Big().Cmp(addresses[j].Big()) < 0
+	})
+	return addresses
+}
+
+func insertCustomPrecompiles(contracts map[addr]ArbosPrecompile) {
+	customPrecompiles.mutex.Lock()
+	defer customPrecompiles.mutex.Unlock()
+	for address, precompile := range customPrecompiles.contracts {
+		if _, exists := contracts[address]; exists {
+			panic(fmt.Sprintf("custom precompile %v collides with a core precompile", address))
+		}
+		contracts[address] = precompile
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package precompiles
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	templates "github.com/offchainlabs/nitro/solgen/go/precompilesgen"
+)
+
+func TestCustomPrecompileRegistration(t *testing.T) {
+	for _, reserved := range []common.Address{
+		common.HexToAddress("01"), types.ArbSysAddress, common.HexToAddress("ffff"), types.ArbosAddress,
+	} {
+		if !IsReservedPrecompileAddress(reserved) {
+			Fail(t, "address should be reserved", reserved)
+		}
+	}
+
+	_, err := RegisterCustomPrecompile(CustomPrecompile{
+		Metadata:    templates.ArbInfoMetaData,
+		Implementer: &ArbInfo{Address: common.HexToAddress("65")},
+	})
+	if !errors.Is(err, ErrReservedPrecompileAddress) {
+		Fail(t, "expected reserved address error, got", err)
+	}
+
+	address := common.HexToAddress("0x10000")
+	before := CustomPrecompilesHash()
+	defer func() {
+		customPrecompiles.mutex.Lock()
+		delete(customPrecompiles.contracts, address)
+		customPrecompiles.mutex.Unlock()
+	}()
+
+	_, err = RegisterCustomPrecompile(CustomPrecompile{
+		Metadata:       templates.ArbInfoMetaData,
+		Implementer:    &ArbInfo{Address: address},
+		MethodVersions: map[string]uint64{"Missing": 1},
+	})
+	if !errors.Is(err, ErrUnknownPrecompileMethod) {
+		Fail(t, "expected unknown method error, got", err)
+	}
+
+	precompile, err := RegisterCustomPrecompile(CustomPrecompile{
+		Metadata:       templates.ArbInfoMetaData,
+		Implementer:    &ArbInfo{Address: address},
+		ArbosVersion:   20,
+		MethodVersions: map[string]uint64{"GetBalance": 21},
+	})
+	Require(t, err)
+	if precompile.arbosVersion != 20 || precompile.methodsByName["GetBalance"].arbosVersion != 21 {
+		Fail(t, "activation versions weren't applied")
+	}
+	if _, ok := Precompiles()[address]; !ok {
+		Fail(t, "custom precompile missing from the precompile set")
+	}
+	if before == CustomPrecompilesHash() {
+		Fail(t, "custom precompiles hash didn't change")
+	}
+
+	_, err = RegisterCustomPrecompile(CustomPrecompile{
+		Metadata:    templates.ArbInfoMetaData,
+		Implementer: &ArbInfo{Address: address},
+	})
+	if !errors.Is(err, ErrDuplicatePrecompileAddress) {
+		Fail(t, "expected duplicate address error, got", err)
+	}
+}
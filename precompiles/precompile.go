@@ -357,7 +357,7 @@ func MakePrecompile(metadata *bind.MetaData, implementer interface{}) (addr, *Pr
 			args = args[2:]
 
 			version := arbosState.ArbOSVersion(state)
-			if callerCtx.readOnly && version >= 11 {
+			if callerCtx.ReadOnly() && version >= 11 {
 				return []reflect.Value{reflect.ValueOf(vm.ErrWriteProtection)}
 			}
 
@@ -541,6 +541,7 @@ func Precompiles() map[addr]ArbosPrecompile {
 	ArbGasInfo.methodsByName["GetL1PricingFundsDueForRewards"].arbosVersion = 20
 	ArbGasInfo.methodsByName["GetL1PricingUnitsSinceUpdate"].arbosVersion = 20
 	ArbGasInfo.methodsByName["GetLastL1PricingSurplus"].arbosVersion = 20
+	ArbGasInfo.methodsByName["GetL1PricingBatchPosterFundsDue"].arbosVersion = 21
 	insert(MakePrecompile(templates.ArbAggregatorMetaData, &ArbAggregator{Address: hex("6d")}))
 	insert(MakePrecompile(templates.ArbStatisticsMetaData, &ArbStatistics{Address: hex("6f")}))
 
@@ -548,10 +549,7 @@ func Precompiles() map[addr]ArbosPrecompile {
 		if err != nil {
 			glog.Error("call to event's GasCost field failed", "err", err)
 		}
-		return &Context{
-			gasSupplied: gasLimit,
-			gasLeft:     gasLimit,
-		}
+		return newContext(addr{}, gasLimit, nil, false)
 	}
 
 	ArbOwnerPublic := insert(MakePrecompile(templates.ArbOwnerPublicMetaData, &ArbOwnerPublic{Address: hex("6b")}))
@@ -559,6 +557,7 @@ func Precompiles() map[addr]ArbosPrecompile {
 	ArbOwnerPublic.methodsByName["RectifyChainOwner"].arbosVersion = 11
 	ArbOwnerPublic.methodsByName["GetBrotliCompressionLevel"].arbosVersion = 20
 	ArbOwnerPublic.methodsByName["GetScheduledUpgrade"].arbosVersion = 20
+	ArbOwnerPublic.methodsByName["GetRetryableAutoRedeemConfig"].arbosVersion = 21
 
 	ArbRetryableImpl := &ArbRetryableTx{Address: types.ArbRetryableTxAddress}
 	ArbRetryable := insert(MakePrecompile(templates.ArbRetryableTxMetaData, ArbRetryableImpl))
@@ -589,12 +588,17 @@ func Precompiles() map[addr]ArbosPrecompile {
 		context := eventCtx(ArbOwnerImpl.OwnerActsGasCost(method, owner, data))
 		return ArbOwnerImpl.OwnerActs(context, evm, method, owner, data)
 	}
+	arbos.EmitArbOSUpgradedEvent = func(evm mech, oldVersion, newVersion uint64) error {
+		context := eventCtx(ArbOwnerImpl.ArbOSUpgradedGasCost(oldVersion, newVersion))
+		return ArbOwnerImpl.ArbOSUpgraded(context, evm, oldVersion, newVersion)
+	}
 	_, ArbOwner := MakePrecompile(templates.ArbOwnerMetaData, ArbOwnerImpl)
 	ArbOwner.methodsByName["GetInfraFeeAccount"].arbosVersion = 5
 	ArbOwner.methodsByName["SetInfraFeeAccount"].arbosVersion = 5
 	ArbOwner.methodsByName["ReleaseL1PricerSurplusFunds"].arbosVersion = 10
 	ArbOwner.methodsByName["SetChainConfig"].arbosVersion = 11
 	ArbOwner.methodsByName["SetBrotliCompressionLevel"].arbosVersion = 20
+	ArbOwner.methodsByName["SetRetryableAutoRedeemConfig"].arbosVersion = 21
 
 	insert(ownerOnly(ArbOwnerImpl.Address, ArbOwner, emitOwnerActs))
 	insert(debugOnly(MakePrecompile(templates.ArbDebugMetaData, &ArbDebug{Address: hex("ff")})))
@@ -603,6 +607,8 @@ func Precompiles() map[addr]ArbosPrecompile {
 	arbos.InternalTxStartBlockMethodID = ArbosActs.GetMethodID("StartBlock")
 	arbos.InternalTxBatchPostingReportMethodID = ArbosActs.GetMethodID("BatchPostingReport")
 
+	insertCustomPrecompiles(contracts)
+
 	return contracts
 }
 
@@ -664,13 +670,8 @@ func (p *Precompile) Call(
 		return nil, 0, vm.ErrExecutionReverted
 	}
 
-	callerCtx := &Context{
-		caller:      caller,
-		gasSupplied: gasSupplied,
-		gasLeft:     gasSupplied,
-		readOnly:    method.purity <= view,
-		tracingInfo: util.NewTracingInfo(evm, caller, precompileAddress, util.TracingDuringEVM),
-	}
+	tracingInfo := util.NewTracingInfo(evm, caller, precompileAddress, util.TracingDuringEVM)
+	callerCtx := newContext(caller, gasSupplied, tracingInfo, method.purity <= view)
 
 	argsCost := params.CopyGas * arbmath.WordsForBytes(uint64(len(input)-4))
 	if err := callerCtx.Burn(argsCost); err != nil {
@@ -733,7 +734,7 @@ func (p *Precompile) Call(
 		errRet, ok := reflectResult[resultCount].Interface().(error)
 		if !ok {
 			log.Error("final precompile return value must be error")
-			return nil, callerCtx.gasLeft, vm.ErrExecutionReverted
+			return nil, callerCtx.GasLeft(), vm.ErrExecutionReverted
 		}
 		var solErr *SolError
 		isSolErr := errors.As(errRet, &solErr)
@@ -743,14 +744,14 @@ func (p *Precompile) Call(
 				// user cannot afford the result data returned
 				return nil, 0, vm.ErrExecutionReverted
 			}
-			return solErr.data, callerCtx.gasLeft, vm.ErrExecutionReverted
+			return solErr.data, callerCtx.GasLeft(), vm.ErrExecutionReverted
 		}
 		if !errors.Is(errRet, vm.ErrOutOfGas) {
 			log.Debug("precompile reverted with non-solidity error", "precompile", precompileAddress, "input", input, "err", errRet)
 		}
 		// nolint:errorlint
 		if arbosVersion >= 11 || errRet == vm.ErrExecutionReverted {
-			return nil, callerCtx.gasLeft, vm.ErrExecutionReverted
+			return nil, callerCtx.GasLeft(), vm.ErrExecutionReverted
 		}
 		// Preserve behavior with old versions which would zero out gas on this type of error
 		return nil, 0, errRet
@@ -763,7 +764,7 @@ func (p *Precompile) Call(
 	encoded, err := method.template.Outputs.PackValues(result)
 	if err != nil {
 		log.Error("could not encode precompile result", "err", err)
-		return nil, callerCtx.gasLeft, vm.ErrExecutionReverted
+		return nil, callerCtx.GasLeft(), vm.ErrExecutionReverted
 	}
 
 	resultCost := params.CopyGas * arbmath.WordsForBytes(uint64(len(encoded)))
@@ -772,7 +773,7 @@ func (p *Precompile) Call(
 		return nil, 0, vm.ErrExecutionReverted
 	}
 
-	return encoded, callerCtx.gasLeft, nil
+	return encoded, callerCtx.GasLeft(), nil
 }
 
 func (p *Precompile) Precompile() *Precompile {
@@ -791,6 +792,24 @@ func (p *Precompile) Get4ByteMethodSignatures() [][4]byte {
 	return ret
 }
 
+// ViewMethods returns the methods that don't modify state and are active at the given ArbOS version,
+// which may be queried with eth_call at any block running that version
+func (p *Precompile) ViewMethods(arbosVersion uint64) []abi.Method {
+	if arbosVersion < p.arbosVersion {
+		return nil
+	}
+	ret := []abi.Method{}
+	for _, method := range p.methods {
+		if method.purity <= view && arbosVersion >= method.arbosVersion {
+			ret = append(ret, method.template)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
 func (p *Precompile) GetErrorABIs() []abi.Error {
 	ret := make([]abi.Error, 0, len(p.errors))
 	for _, solErr := range p.errors {
@@ -75,24 +75,20 @@ func (wrapper *OwnerPrecompile) Call(
 ) ([]byte, uint64, error) {
 	con := wrapper.precompile
 
-	burner := &Context{
-		gasSupplied: gasSupplied,
-		gasLeft:     gasSupplied,
-		tracingInfo: util.NewTracingInfo(evm, caller, precompileAddress, util.TracingDuringEVM),
-	}
+	burner := newContext(caller, gasSupplied, util.NewTracingInfo(evm, caller, precompileAddress, util.TracingDuringEVM), false)
 	state, err := arbosState.OpenArbosState(evm.StateDB, burner)
 	if err != nil {
-		return nil, burner.gasLeft, err
+		return nil, burner.GasLeft(), err
 	}
 
 	owners := state.ChainOwners()
 	isOwner, err := owners.IsMember(caller)
 	if err != nil {
-		return nil, burner.gasLeft, err
+		return nil, burner.GasLeft(), err
 	}
 
 	if !isOwner {
-		return nil, burner.gasLeft, errors.New("unauthorized caller to access-controlled method")
+		return nil, burner.GasLeft(), errors.New("unauthorized caller to access-controlled method")
 	}
 
 	output, _, err := con.Call(input, precompileAddress, actingAsAddress, caller, value, readOnly, gasSupplied, evm)
@@ -74,6 +74,14 @@ type NodeInfo struct {
 	WasmModuleRoot           common.Hash
 }
 
+// number returns the node's number, or zero for a nil node
+func (n *NodeInfo) number() uint64 {
+	if n == nil {
+		return 0
+	}
+	return n.NodeNum
+}
+
 func (n *NodeInfo) AfterState() *validator.ExecutionState {
 	return n.Assertion.AfterState
 }
@@ -24,6 +24,7 @@ import (
 	"github.com/offchainlabs/nitro/arbnode/resourcemanager"
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/util/containers"
+	"github.com/offchainlabs/nitro/util/latencytracker"
 	"github.com/offchainlabs/nitro/util/rpcclient"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
 	"github.com/offchainlabs/nitro/validator"
@@ -35,6 +36,12 @@ var (
 	validatorFailedValidationsCounter = metrics.NewRegisteredCounter("arb/validator/validations/failed", nil)
 	validatorMsgCountCurrentBatch     = metrics.NewRegisteredGauge("arb/validator/msg_count_current_batch", nil)
 	validatorMsgCountValidatedGauge   = metrics.NewRegisteredGauge("arb/validator/msg_count_validated", nil)
+	validatorInFlightValidationsGauge = metrics.NewRegisteredGauge("arb/validator/validations/inflight", nil)
+	validatorRecordedBytesGauge       = metrics.NewRegisteredGauge("arb/validator/recorded_bytes", nil)
+	validatorValidatedMeter           = metrics.NewRegisteredMeter("arb/validator/validations/rate", nil)
+	validatorQueueDepthGauge          = metrics.NewRegisteredGauge("arb/validator/validations/queue", nil)
+	validatorValidationDurationHist   = metrics.NewRegisteredHistogram("arb/validator/validations/duration", nil, metrics.NewBoundedHistogramSample())
+	validatorMsgCountLagGauge         = metrics.NewRegisteredGauge("arb/validator/msg_count_lag", nil)
 )
 
 type BlockValidator struct {
@@ -68,11 +75,21 @@ type BlockValidator struct {
 	validatedA  uint64
 	validations containers.SyncMap[arbutil.MessageIndex, *validationStatus]
 
+	// atomic: validations launched and not yet finished or cancelled
+	inFlightValidations int64
+
+	// messages to validate ahead of their turn, and the results of doing so
+	priorityMutex sync.Mutex
+	priorityStart arbutil.MessageIndex
+	priorityEnd   arbutil.MessageIndex
+	prioritized   map[arbutil.MessageIndex]*prioritizedValidation
+
 	config BlockValidatorConfigFetcher
 
 	createNodesChan         chan struct{}
 	sendRecordChan          chan struct{}
 	progressValidationsChan chan struct{}
+	prioritizeChan          chan struct{}
 
 	// for testing only
 	testingProgressMadeChan chan struct{}
@@ -95,8 +112,15 @@ type BlockValidatorConfig struct {
 	Dangerous                   BlockValidatorDangerousConfig `koanf:"dangerous"`
 	MemoryFreeLimit             string                        `koanf:"memory-free-limit" reload:"hot"`
 	ValidationServerConfigsList string                        `koanf:"validation-server-configs-list" reload:"hot"`
-
-	memoryFreeLimit int
+	MaxConcurrentValidations    uint64                        `koanf:"max-concurrent-validations" reload:"hot"`
+	MemoryBudget                string                        `koanf:"memory-budget" reload:"hot"`
+	PersistRecordedEntries      bool                          `koanf:"persist-recorded-entries"`
+	FailureDumpDir              string                        `koanf:"failure-dump-dir" reload:"hot"`
+	SpillRecordedSize           string                        `koanf:"spill-recorded-size" reload:"hot"`
+
+	memoryFreeLimit   int
+	memoryBudget      int
+	spillRecordedSize int
 }
 
 func (c *BlockValidatorConfig) Validate() error {
@@ -109,6 +133,22 @@ func (c *BlockValidatorConfig) Validate() error {
 		}
 		c.memoryFreeLimit = limit
 	}
+	c.memoryBudget = 0
+	if c.MemoryBudget != "" {
+		budget, err := resourcemanager.ParseMemLimit(c.MemoryBudget)
+		if err != nil {
+			return fmt.Errorf("failed to parse block-validator config memory-budget string: %w", err)
+		}
+		c.memoryBudget = budget
+	}
+	c.spillRecordedSize = 0
+	if c.SpillRecordedSize != "" {
+		size, err := resourcemanager.ParseMemLimit(c.SpillRecordedSize)
+		if err != nil {
+			return fmt.Errorf("failed to parse block-validator config spill-recorded-size string: %w", err)
+		}
+		c.spillRecordedSize = size
+	}
 	if c.ValidationServerConfigs == nil {
 		if c.ValidationServerConfigsList == "default" {
 			c.ValidationServerConfigs = []rpcclient.ClientConfig{c.ValidationServer}
@@ -145,10 +185,15 @@ func BlockValidatorConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Uint64(prefix+".forward-blocks", DefaultBlockValidatorConfig.ForwardBlocks, "prepare entries for up to that many blocks ahead of validation (small footprint)")
 	f.Uint64(prefix+".prerecorded-blocks", DefaultBlockValidatorConfig.PrerecordedBlocks, "record that many blocks ahead of validation (larger footprint)")
 	f.String(prefix+".current-module-root", DefaultBlockValidatorConfig.CurrentModuleRoot, "current wasm module root ('current' read from chain, 'latest' from machines/latest dir, or provide hash)")
-	f.String(prefix+".pending-upgrade-module-root", DefaultBlockValidatorConfig.PendingUpgradeModuleRoot, "pending upgrade wasm module root to additionally validate (hash, 'latest' or empty)")
+	f.String(prefix+".pending-upgrade-module-root", DefaultBlockValidatorConfig.PendingUpgradeModuleRoot, "comma separated list of pending upgrade wasm module roots to additionally validate, so validation continues when the rollup is upgraded to any of them (hashes, 'latest' or empty)")
 	f.Bool(prefix+".failure-is-fatal", DefaultBlockValidatorConfig.FailureIsFatal, "failing a validation is treated as a fatal error")
 	BlockValidatorDangerousConfigAddOptions(prefix+".dangerous", f)
 	f.String(prefix+".memory-free-limit", DefaultBlockValidatorConfig.MemoryFreeLimit, "minimum free-memory limit after reaching which the blockvalidator pauses validation. Enabled by default as 1GB, to disable provide empty string")
+	f.Uint64(prefix+".max-concurrent-validations", DefaultBlockValidatorConfig.MaxConcurrentValidations, "maximum number of blocks being validated at once across all validation servers (0 = as many as the validation servers have room for)")
+	f.Bool(prefix+".persist-recorded-entries", DefaultBlockValidatorConfig.PersistRecordedEntries, "store blocks recorded for validation in the database until they're validated, so a restarted node validates them without recording them again")
+	f.String(prefix+".memory-budget", DefaultBlockValidatorConfig.MemoryBudget, "maximum memory held by blocks recorded for validation but not yet validated, such as \"4GB\"; recording pauses while it's exceeded (empty = no budget)")
+	f.String(prefix+".spill-recorded-size", DefaultBlockValidatorConfig.SpillRecordedSize, "recorded blocks at least this size, such as \"256MB\", are written to the database and only read back into memory when they're validated, so large blocks don't exhaust memory while they wait (empty = keep all in memory)")
+	f.String(prefix+".failure-dump-dir", DefaultBlockValidatorConfig.FailureDumpDir, "directory to write the full validation input of blocks that fail validation to, as JSON, so they can be reproduced offline (empty = don't dump)")
 }
 
 func BlockValidatorDangerousConfigAddOptions(prefix string, f *flag.FlagSet) {
@@ -167,6 +212,11 @@ var DefaultBlockValidatorConfig = BlockValidatorConfig{
 	FailureIsFatal:              true,
 	Dangerous:                   DefaultBlockValidatorDangerousConfig,
 	MemoryFreeLimit:             "default",
+	MaxConcurrentValidations:    0,
+	MemoryBudget:                "",
+	PersistRecordedEntries:      false,
+	FailureDumpDir:              "",
+	SpillRecordedSize:           "",
 }
 
 var TestBlockValidatorConfig = BlockValidatorConfig{
@@ -181,6 +231,11 @@ var TestBlockValidatorConfig = BlockValidatorConfig{
 	FailureIsFatal:           true,
 	Dangerous:                DefaultBlockValidatorDangerousConfig,
 	MemoryFreeLimit:          "default",
+	MaxConcurrentValidations: 0,
+	MemoryBudget:             "",
+	PersistRecordedEntries:   false,
+	FailureDumpDir:           "",
+	SpillRecordedSize:        "",
 }
 
 var DefaultBlockValidatorDangerousConfig = BlockValidatorDangerousConfig{
@@ -203,6 +258,7 @@ type validationStatus struct {
 	Cancel func()                    // non-atomic: only read/written to with reorg mutex
 	Entry  *validationEntry          // non-atomic: only read if Status >= validationStatusPrepared
 	Runs   []validator.ValidationRun // if status >= ValidationSent
+	Sent   time.Time                 // if status >= ValidationSent
 }
 
 func (s *validationStatus) getStatus() valStatusField {
@@ -226,6 +282,8 @@ func NewBlockValidator(
 		createNodesChan:         make(chan struct{}, 1),
 		sendRecordChan:          make(chan struct{}, 1),
 		progressValidationsChan: make(chan struct{}, 1),
+		prioritizeChan:          make(chan struct{}, 1),
+		prioritized:             make(map[arbutil.MessageIndex]*prioritizedValidation),
 		config:                  config,
 		fatalErr:                fatalErr,
 	}
@@ -434,6 +492,7 @@ func (v *BlockValidator) sendRecord(s *validationStatus) error {
 			log.Error("Error while recording", "err", err, "status", s.getStatus())
 			return
 		}
+		v.storeRecordedEntry(s.Entry)
 		if !s.replaceStatus(RecordSent, Prepared) {
 			log.Error("Fault trying to update validation with recording", "entry", s.Entry, "status", s.getStatus())
 			return
@@ -453,32 +512,57 @@ func (v *BlockValidator) writeToFile(validationEntry *validationEntry, moduleRoo
 	return err
 }
 
-func (v *BlockValidator) SetCurrentWasmModuleRoot(hash common.Hash) error {
+// setKnownWasmModuleRoot makes the module root current if it's already current or pending, and returns whether it was
+func (v *BlockValidator) setKnownWasmModuleRoot(hash common.Hash) bool {
 	v.moduleMutex.Lock()
 	defer v.moduleMutex.Unlock()
 
-	if (hash == common.Hash{}) {
-		return errors.New("trying to set zero as wasmModuleRoot")
-	}
 	if hash == v.currentWasmModuleRoot {
-		return nil
+		return true
 	}
 	if (v.currentWasmModuleRoot == common.Hash{}) {
 		v.currentWasmModuleRoot = hash
-		return nil
+		return true
 	}
-	if v.pendingWasmModuleRoot == hash {
-		log.Info("Block validator: detected progressing to pending machine", "hash", hash)
+	for i, pending := range v.pendingWasmModuleRoots {
+		if pending != hash {
+			continue
+		}
+		log.Info("Block validator: detected progressing to pending machine", "hash", hash, "previous", v.currentWasmModuleRoot)
 		v.currentWasmModuleRoot = hash
+		// later upgrades stay pending, but there's no going back to the previous machine
+		remaining := make([]common.Hash, 0, len(v.pendingWasmModuleRoots)-1)
+		remaining = append(remaining, v.pendingWasmModuleRoots[:i]...)
+		v.pendingWasmModuleRoots = append(remaining, v.pendingWasmModuleRoots[i+1:]...)
+		return true
+	}
+	return false
+}
+
+func (v *BlockValidator) SetCurrentWasmModuleRoot(ctx context.Context, hash common.Hash) error {
+	if (hash == common.Hash{}) {
+		return errors.New("trying to set zero as wasmModuleRoot")
+	}
+	if v.setKnownWasmModuleRoot(hash) {
 		return nil
 	}
 	if v.config().CurrentModuleRoot != "current" {
 		return nil
 	}
-	return fmt.Errorf(
-		"unexpected wasmModuleRoot! cannot validate! found %v , current %v, pending %v",
-		hash, v.currentWasmModuleRoot, v.pendingWasmModuleRoot,
-	)
+	// The rollup moved to a module root that wasn't pending. Rather than stop validating until a restart, load its
+	// machine from the machine root path, where it may have been put since the node started, and switch to it.
+	_, err := v.execSpawner.LoadWasmModuleRoot(hash).Await(ctx)
+	if err != nil {
+		return fmt.Errorf(
+			"unexpected wasmModuleRoot! cannot validate! found %v , validating %v: %w",
+			hash, v.GetModuleRootsToValidate(), err,
+		)
+	}
+	v.moduleMutex.Lock()
+	defer v.moduleMutex.Unlock()
+	log.Warn("Block validator: loaded machine for new wasmModuleRoot", "hash", hash, "previous", v.currentWasmModuleRoot)
+	v.currentWasmModuleRoot = hash
+	return nil
 }
 
 func (v *BlockValidator) readBatch(ctx context.Context, batchNum uint64) (bool, []byte, common.Hash, arbutil.MessageIndex, error) {
@@ -588,6 +672,30 @@ func (v *BlockValidator) isMemoryLimitExceeded() bool {
 	return exceeded
 }
 
+// recordedBytes estimates the memory held by recorded entries that haven't yet been validated.
+// Must be called holding reorg-read.
+func (v *BlockValidator) recordedBytes() int {
+	total := 0
+	for pos := v.validated(); pos < v.recordSent(); pos++ {
+		validationStatus, found := v.validations.Load(pos)
+		if !found || validationStatus.getStatus() < Prepared {
+			continue
+		}
+		total += validationStatus.Entry.size()
+	}
+	validatorRecordedBytesGauge.Update(int64(total))
+	return total
+}
+
+// Must be called holding reorg-read
+func (v *BlockValidator) isMemoryBudgetExceeded() bool {
+	budget := v.config().memoryBudget
+	if budget <= 0 {
+		return false
+	}
+	return v.recordedBytes() >= budget
+}
+
 func (v *BlockValidator) sendNextRecordRequests(ctx context.Context) (bool, error) {
 	if v.isMemoryLimitExceeded() {
 		log.Warn("sendNextRecordRequests: aborting due to running low on memory")
@@ -626,6 +734,11 @@ func (v *BlockValidator) sendNextRecordRequests(ctx context.Context) (bool, erro
 			log.Warn("sendNextRecordRequests: aborting due to running low on memory")
 			return false, nil
 		}
+		if v.isMemoryBudgetExceeded() {
+			// resumes once validations complete and release their recordings
+			log.Debug("sendNextRecordRequests: pausing as recorded blocks exceed the memory budget", "pos", pos)
+			return false, nil
+		}
 		validationStatus, found := v.validations.Load(pos)
 		if !found {
 			return false, fmt.Errorf("not found entry for pos %d", pos)
@@ -745,6 +858,9 @@ validationsLoop:
 					if writeErr != nil {
 						log.Warn("failed to write debug results file", "err", writeErr)
 					}
+					v.dumpFailedValidation(validationStatus.Entry, run.WasmModuleRoot(), &runEnd, err)
+				} else if err != nil {
+					v.dumpFailedValidation(validationStatus.Entry, run.WasmModuleRoot(), nil, err)
 				}
 				if err != nil {
 					validatorFailedValidationsCounter.Inc(1)
@@ -760,15 +876,32 @@ validationsLoop:
 			go v.recorder.MarkValid(pos, v.lastValidGS.BlockHash)
 			atomicStorePos(&v.validatedA, pos+1)
 			v.validations.Delete(pos)
+			v.deleteRecordedEntries(pos, pos+1)
 			nonBlockingTrigger(v.createNodesChan)
 			nonBlockingTrigger(v.sendRecordChan)
 			validatorMsgCountValidatedGauge.Update(int64(pos + 1))
+			validatorValidatedMeter.Mark(1)
+			validatorValidationDurationHist.Update(time.Since(validationStatus.Sent).Milliseconds())
+			latencytracker.MessagesValidated(pos + 1)
 			if v.testingProgressMadeChan != nil {
 				nonBlockingTrigger(v.testingProgressMadeChan)
 			}
 			log.Trace("result validated", "count", v.validated(), "blockHash", v.lastValidGS.BlockHash)
 			continue
 		}
+		if currentStatus == Prepared {
+			if runs := v.takePrioritized(validationStatus.Entry, wasmRoots); runs != nil {
+				// already validated ahead of its turn, so it needs no room
+				if !validationStatus.replaceStatus(Prepared, ValidationSent) {
+					v.possiblyFatal(errors.New("failed to set status to ValidationSent"))
+				}
+				validationStatus.Runs = runs
+				validationStatus.Sent = time.Now()
+				validationStatus.Cancel = func() {}
+				nonBlockingTrigger(v.progressValidationsChan)
+				continue
+			}
+		}
 		for currentSpawnerIndex < len(rooms) {
 			if rooms[currentSpawnerIndex] > 0 {
 				break
@@ -783,7 +916,17 @@ validationsLoop:
 			log.Warn("advanceValidations: aborting due to running low on memory")
 			return nil, nil
 		}
+		maxConcurrent := v.config().MaxConcurrentValidations
+		if maxConcurrent > 0 && atomic.LoadInt64(&v.inFlightValidations) >= int64(maxConcurrent) {
+			log.Trace("advanceValidations: max concurrent validations reached", "pos", pos)
+			return nil, nil
+		}
 		if currentStatus == Prepared {
+			if err := v.unspillRecordedEntry(validationStatus.Entry); err != nil {
+				log.Error("failed reading spilled validation entry, recording again", "pos", pos, "err", err)
+				validationStatus.replaceStatus(Prepared, RecordFailed)
+				return &pos, nil
+			}
 			input, err := validationStatus.Entry.ToInput()
 			if err != nil && ctx.Err() == nil {
 				v.possiblyFatal(fmt.Errorf("%w: error preparing validation", err))
@@ -803,9 +946,14 @@ validationsLoop:
 			}
 			validationCtx, cancel := context.WithCancel(ctx)
 			validationStatus.Runs = runs
+			validationStatus.Sent = time.Now()
 			validationStatus.Cancel = cancel
+			validatorInFlightValidationsGauge.Update(atomic.AddInt64(&v.inFlightValidations, 1))
 			v.LaunchUntrackedThread(func() {
 				defer cancel()
+				defer func() {
+					validatorInFlightValidationsGauge.Update(atomic.AddInt64(&v.inFlightValidations, -1))
+				}()
 				replaced = validationStatus.replaceStatus(SendingValidation, ValidationSent)
 				if !replaced {
 					v.possiblyFatal(errors.New("failed to set status to ValidationSent"))
@@ -829,7 +977,28 @@ validationsLoop:
 	}
 }
 
+// updateProgressMetrics reports how many messages are waiting to be validated, and how far validation lags
+// behind the messages processed
+func (v *BlockValidator) updateProgressMetrics() {
+	validated := v.validated()
+	if created := v.created(); created > validated {
+		validatorQueueDepthGauge.Update(int64(created - validated))
+	} else {
+		validatorQueueDepthGauge.Update(0)
+	}
+	processed, err := v.streamer.GetProcessedMessageCount()
+	if err != nil {
+		return
+	}
+	if processed > validated {
+		validatorMsgCountLagGauge.Update(int64(processed - validated))
+	} else {
+		validatorMsgCountLagGauge.Update(0)
+	}
+}
+
 func (v *BlockValidator) iterativeValidationProgress(ctx context.Context, ignored struct{}) time.Duration {
+	defer v.updateProgressMetrics()
 	reorg, err := v.advanceValidations(ctx)
 	if err != nil {
 		log.Error("error trying to record for validation node", "err", err)
@@ -934,6 +1103,11 @@ func (v *BlockValidator) UpdateLatestStaked(count arbutil.MessageIndex, globalSt
 		return
 	}
 	// delete no-longer relevant entries
+	if v.created() < count {
+		v.deleteRecordedEntries(v.validated(), v.created())
+	} else {
+		v.deleteRecordedEntries(v.validated(), count)
+	}
 	for iPos := v.validated(); iPos < count && iPos < v.created(); iPos++ {
 		status, found := v.validations.Load(iPos)
 		if found && status != nil && status.Cancel != nil {
@@ -999,6 +1173,8 @@ func (v *BlockValidator) Reorg(ctx context.Context, count arbutil.MessageIndex)
 		v.possiblyFatal(err)
 		return err
 	}
+	v.deleteRecordedEntries(count, v.created())
+	v.dropPrioritized(count)
 	for iPos := count; iPos < v.created(); iPos++ {
 		status, found := v.validations.Load(iPos)
 		if found && status != nil && status.Cancel != nil {
@@ -1048,7 +1224,7 @@ func (v *BlockValidator) Initialize(ctx context.Context) error {
 			return errors.New("current-module-root config value illegal")
 		}
 	}
-	log.Info("BlockValidator initialized", "current", v.currentWasmModuleRoot, "pending", v.pendingWasmModuleRoot)
+	log.Info("BlockValidator initialized", "current", v.currentWasmModuleRoot, "pending", v.pendingWasmModuleRoots)
 	return nil
 }
 
@@ -1162,6 +1338,7 @@ func (v *BlockValidator) checkValidatedGSCaughtUp() (bool, error) {
 	atomicStorePos(&v.createdA, count)
 	atomicStorePos(&v.recordSentA, count)
 	atomicStorePos(&v.validatedA, count)
+	v.loadRecordedEntries(count)
 	validatorMsgCountValidatedGauge.Update(int64(count))
 	v.chainCaughtUp = true
 	return true, nil
@@ -1198,6 +1375,10 @@ func (v *BlockValidator) LaunchWorkthreadsWhenCaughtUp(ctx context.Context) {
 	if err != nil {
 		v.possiblyFatal(err)
 	}
+	err = stopwaiter.CallIterativelyWith[struct{}](&v.StopWaiterSafe, v.iterativePriorityValidation, v.prioritizeChan)
+	if err != nil {
+		v.possiblyFatal(err)
+	}
 }
 
 func (v *BlockValidator) Start(ctxIn context.Context) error {
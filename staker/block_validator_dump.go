@@ -0,0 +1,76 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/validator"
+	"github.com/offchainlabs/nitro/validator/server_api"
+)
+
+// FailedValidationDump is written to the failure dump directory when a block fails validation. Input is in the
+// validation server API's format, so it can be decoded with server_api.ValidationInputFromJson and run again offline.
+type FailedValidationDump struct {
+	MessageIndex   uint64                          `json:"messageIndex"`
+	WasmModuleRoot common.Hash                     `json:"wasmModuleRoot"`
+	ExpectedEnd    validator.GoGlobalState         `json:"expectedEnd"`
+	ActualEnd      *validator.GoGlobalState        `json:"actualEnd,omitempty"`
+	Error          string                          `json:"error"`
+	Input          *server_api.ValidationInputJson `json:"input"`
+}
+
+func failedValidationDumpPath(dir string, entry *validationEntry, moduleRoot common.Hash) string {
+	return filepath.Join(dir, fmt.Sprintf("message_%d_%x.json", entry.Pos, moduleRoot[:4]))
+}
+
+// dumpFailedValidation writes the failed entry's full validation input to the failure dump directory, if one is
+// configured. A failure that's retried overwrites its earlier dump. actualEnd is nil if validation didn't finish.
+func (v *BlockValidator) dumpFailedValidation(entry *validationEntry, moduleRoot common.Hash, actualEnd *validator.GoGlobalState, failure error) {
+	dir := v.config().FailureDumpDir
+	if dir == "" || v.GetContext().Err() != nil {
+		return
+	}
+	input, err := entry.ToInput()
+	if err != nil {
+		log.Warn("failed to get input of failed validation to dump", "pos", entry.Pos, "err", err)
+		return
+	}
+	dump := &FailedValidationDump{
+		MessageIndex:   uint64(entry.Pos),
+		WasmModuleRoot: moduleRoot,
+		ExpectedEnd:    entry.End,
+		ActualEnd:      actualEnd,
+		Error:          failure.Error(),
+		Input:          server_api.ValidationInputToJson(input),
+	}
+	path := failedValidationDumpPath(dir, entry, moduleRoot)
+	if err := writeFailedValidationDump(path, dump); err != nil {
+		log.Warn("failed to dump failed validation", "pos", entry.Pos, "path", path, "err", err)
+		return
+	}
+	log.Warn("dumped failed validation input", "pos", entry.Pos, "path", path)
+}
+
+// writeFailedValidationDump writes the dump through a temporary file, so a partial dump is never left at path
+func writeFailedValidationDump(path string, dump *FailedValidationDump) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(dump)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, encoded, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
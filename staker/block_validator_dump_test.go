@@ -0,0 +1,70 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/validator"
+	"github.com/offchainlabs/nitro/validator/server_api"
+)
+
+func TestFailedValidationDump(t *testing.T) {
+	preimage := []byte("preimage")
+	entry := &validationEntry{
+		Stage: Ready,
+		Pos:   41,
+		Start: validator.GoGlobalState{BlockHash: common.HexToHash("0x5747"), Batch: 2, PosInBatch: 3},
+		End:   validator.GoGlobalState{BlockHash: common.HexToHash("0xe4d"), Batch: 2, PosInBatch: 4},
+		Preimages: map[arbutil.PreimageType]map[common.Hash][]byte{
+			arbutil.Keccak256PreimageType: {common.HexToHash("0x1"): preimage},
+		},
+		BatchInfo:     []validator.BatchInfo{{Number: 2, Data: []byte("batch")}},
+		HasDelayedMsg: true,
+		DelayedMsgNr:  5,
+		DelayedMsg:    []byte("delayed"),
+	}
+	input, err := entry.ToInput()
+	Require(t, err)
+	moduleRoot := common.HexToHash("0xabcdef01")
+	actualEnd := validator.GoGlobalState{BlockHash: common.HexToHash("0xbad"), Batch: 2, PosInBatch: 4}
+	path := failedValidationDumpPath(t.TempDir(), entry, moduleRoot)
+	Require(t, writeFailedValidationDump(path, &FailedValidationDump{
+		MessageIndex:   uint64(entry.Pos),
+		WasmModuleRoot: moduleRoot,
+		ExpectedEnd:    entry.End,
+		ActualEnd:      &actualEnd,
+		Error:          "validation failed",
+		Input:          server_api.ValidationInputToJson(input),
+	}))
+
+	encoded, err := os.ReadFile(path)
+	Require(t, err)
+	var dump FailedValidationDump
+	Require(t, json.Unmarshal(encoded, &dump))
+	if dump.MessageIndex != 41 || dump.WasmModuleRoot != moduleRoot || dump.ExpectedEnd != entry.End || dump.ActualEnd == nil || *dump.ActualEnd != actualEnd {
+		Fail(t, "unexpected failed validation dump", string(encoded))
+	}
+	// The dumped input can be decoded back into what was validated
+	decoded, err := server_api.ValidationInputFromJson(dump.Input)
+	Require(t, err)
+	if decoded.Id != input.Id || decoded.StartState != input.StartState || !decoded.HasDelayedMsg || decoded.DelayedMsgNr != 5 || !bytes.Equal(decoded.DelayedMsg, input.DelayedMsg) {
+		Fail(t, "dumped input doesn't match validation input", decoded)
+	}
+	if len(decoded.BatchInfo) != 1 || !bytes.Equal(decoded.BatchInfo[0].Data, []byte("batch")) {
+		Fail(t, "dumped input has wrong batch info", decoded.BatchInfo)
+	}
+	if !bytes.Equal(decoded.Preimages[arbutil.Keccak256PreimageType][common.HexToHash("0x1")], preimage) {
+		Fail(t, "dumped input is missing preimages", decoded.Preimages)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		Fail(t, "temporary dump file left behind", err)
+	}
+}
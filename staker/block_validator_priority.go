@@ -0,0 +1,167 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/containers"
+	"github.com/offchainlabs/nitro/validator"
+	"github.com/offchainlabs/nitro/validator/server_common"
+)
+
+var validatorPrioritizedValidationsCounter = metrics.NewRegisteredCounter("arb/validator/validations/prioritized", nil)
+
+// prioritizedValidation is the result of validating a message ahead of its turn
+type prioritizedValidation struct {
+	start validator.GoGlobalState
+	end   validator.GoGlobalState
+	roots []common.Hash
+}
+
+// PrioritizeValidation has the validator validate the messages in [start, end), such as those disputed in a challenge,
+// ahead of their turn rather than strictly in order. Each is validated on its own as soon as the validation servers
+// have room, and its result is used once in-order validation reaches it, if it's still the same entry. Replaces any
+// range previously prioritized.
+func (v *BlockValidator) PrioritizeValidation(start, end arbutil.MessageIndex) {
+	v.priorityMutex.Lock()
+	changed := v.priorityStart != start || v.priorityEnd != end
+	v.priorityStart = start
+	v.priorityEnd = end
+	v.priorityMutex.Unlock()
+	if changed {
+		log.Info("prioritizing validation", "start", start, "end", end)
+		nonBlockingTrigger(v.prioritizeChan)
+	}
+}
+
+// ClearValidationPriority stops validating messages ahead of their turn. Results already found are still used.
+func (v *BlockValidator) ClearValidationPriority() {
+	v.PrioritizeValidation(0, 0)
+}
+
+// nextPrioritized returns the next message in the prioritized range that in-order validation hasn't started recording,
+// and that hasn't already been validated ahead of its turn
+func (v *BlockValidator) nextPrioritized() (arbutil.MessageIndex, bool) {
+	v.reorgMutex.RLock()
+	recordSent := v.recordSent()
+	v.reorgMutex.RUnlock()
+	v.priorityMutex.Lock()
+	defer v.priorityMutex.Unlock()
+	pos := v.priorityStart
+	if pos < recordSent {
+		pos = recordSent
+	}
+	for ; pos < v.priorityEnd; pos++ {
+		if _, done := v.prioritized[pos]; !done {
+			return pos, true
+		}
+	}
+	return 0, false
+}
+
+func (v *BlockValidator) iterativePriorityValidation(ctx context.Context, ignored struct{}) time.Duration {
+	pos, ok := v.nextPrioritized()
+	if !ok || v.isMemoryLimitExceeded() {
+		return v.config().ValidationPoll
+	}
+	var spawner validator.ValidationSpawner
+	for _, candidate := range v.validationSpawners {
+		if spawner == nil || candidate.Room() > spawner.Room() {
+			spawner = candidate
+		}
+	}
+	if spawner == nil || spawner.Room() == 0 {
+		return v.config().ValidationPoll
+	}
+	entry, err := v.CreateReadyValidationEntry(ctx, pos)
+	if err != nil {
+		log.Warn("failed recording prioritized validation", "pos", pos, "err", err)
+		return v.config().ValidationPoll
+	}
+	input, err := entry.ToInput()
+	if err != nil {
+		log.Warn("failed preparing prioritized validation", "pos", pos, "err", err)
+		return v.config().ValidationPoll
+	}
+	wasmRoots := v.GetModuleRootsToValidate()
+	var runs []validator.ValidationRun
+	for _, moduleRoot := range wasmRoots {
+		runs = append(runs, spawner.Launch(input, moduleRoot))
+	}
+	defer func() {
+		for _, run := range runs {
+			run.Cancel()
+		}
+	}()
+	for _, run := range runs {
+		runEnd, err := run.Await(ctx)
+		if ctx.Err() != nil {
+			return 0
+		}
+		if err != nil {
+			log.Warn("error running prioritized validation", "pos", pos, "moduleRoot", run.WasmModuleRoot(), "err", err)
+			return v.config().ValidationPoll
+		}
+		if runEnd != entry.End {
+			err = fmt.Errorf("prioritized validation failed: expected %v got %v", entry.End, runEnd)
+			v.dumpFailedValidation(entry, run.WasmModuleRoot(), &runEnd, err)
+			validatorFailedValidationsCounter.Inc(1)
+			v.possiblyFatal(err)
+			return v.config().ValidationPoll
+		}
+	}
+	v.priorityMutex.Lock()
+	v.prioritized[pos] = &prioritizedValidation{
+		start: entry.Start,
+		end:   entry.End,
+		roots: wasmRoots,
+	}
+	v.priorityMutex.Unlock()
+	validatorPrioritizedValidationsCounter.Inc(1)
+	log.Debug("validated message ahead of its turn", "pos", pos)
+	return 0
+}
+
+// takePrioritized removes and returns the ready runs of the entry's validation, if it was validated ahead of its turn
+// from the same start and end states with the given module roots, and otherwise returns nil
+func (v *BlockValidator) takePrioritized(entry *validationEntry, wasmRoots []common.Hash) []validator.ValidationRun {
+	v.priorityMutex.Lock()
+	defer v.priorityMutex.Unlock()
+	result, ok := v.prioritized[entry.Pos]
+	if !ok {
+		return nil
+	}
+	delete(v.prioritized, entry.Pos)
+	if result.start != entry.Start || result.end != entry.End || len(result.roots) != len(wasmRoots) {
+		return nil
+	}
+	var runs []validator.ValidationRun
+	for i, root := range wasmRoots {
+		if result.roots[i] != root {
+			return nil
+		}
+		runs = append(runs, server_common.NewValRun(containers.NewReadyPromise(result.end, nil), root))
+	}
+	return runs
+}
+
+// dropPrioritized forgets the results of validating messages at or after count ahead of their turn, as they've been
+// reorged out
+func (v *BlockValidator) dropPrioritized(count arbutil.MessageIndex) {
+	v.priorityMutex.Lock()
+	defer v.priorityMutex.Unlock()
+	for pos := range v.prioritized {
+		if pos >= count {
+			delete(v.prioritized, pos)
+		}
+	}
+}
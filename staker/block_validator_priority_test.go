@@ -0,0 +1,130 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/containers"
+	"github.com/offchainlabs/nitro/validator"
+	"github.com/offchainlabs/nitro/validator/server_common"
+)
+
+// priorityTestExecution records and validates the messages of a recordedTestChain
+type priorityTestExecution struct {
+	chain    *recordedTestChain
+	launched []uint64
+}
+
+func (e *priorityTestExecution) GetSequencerMessageBytes(context.Context, uint64) ([]byte, common.Hash, error) {
+	return []byte{}, common.Hash{}, nil
+}
+
+func (e *priorityTestExecution) RecordBlockCreation(_ context.Context, pos arbutil.MessageIndex, _ *arbostypes.MessageWithMetadata) (*execution.RecordResult, error) {
+	return &execution.RecordResult{Pos: pos, BlockHash: e.chain.blockHashes[pos]}, nil
+}
+
+func (e *priorityTestExecution) MarkValid(arbutil.MessageIndex, common.Hash) {}
+
+func (e *priorityTestExecution) PrepareForRecord(context.Context, arbutil.MessageIndex, arbutil.MessageIndex) error {
+	return nil
+}
+
+func (e *priorityTestExecution) Launch(input *validator.ValidationInput, moduleRoot common.Hash) validator.ValidationRun {
+	e.launched = append(e.launched, input.Id)
+	end := e.chain.entry(arbutil.MessageIndex(input.Id)).End
+	return server_common.NewValRun(containers.NewReadyPromise(end, nil), moduleRoot)
+}
+
+func (e *priorityTestExecution) Start(context.Context) error { return nil }
+func (e *priorityTestExecution) Stop()                       {}
+func (e *priorityTestExecution) Name() string                { return "priority-test" }
+func (e *priorityTestExecution) Room() int                   { return 1 }
+
+func TestPrioritizedValidation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	config := TestBlockValidatorConfig
+	chain := newRecordedTestChain(10)
+	exec := &priorityTestExecution{chain: chain}
+	moduleRoot := common.HexToHash("0x01")
+	v := &BlockValidator{
+		StatelessBlockValidator: &StatelessBlockValidator{
+			inboxReader:           exec,
+			inboxTracker:          chain,
+			streamer:              chain,
+			recorder:              exec,
+			validationSpawners:    []validator.ValidationSpawner{exec},
+			currentWasmModuleRoot: moduleRoot,
+		},
+		config:      func() *BlockValidatorConfig { return &config },
+		prioritized: make(map[arbutil.MessageIndex]*prioritizedValidation),
+	}
+
+	// in-order validation has started recording message 3, so only the rest of the range is validated ahead
+	atomicStorePos(&v.recordSentA, 3)
+	v.PrioritizeValidation(2, 6)
+	for i := 0; i < 3; i++ {
+		if delay := v.iterativePriorityValidation(ctx, struct{}{}); delay != 0 {
+			Fail(t, "prioritized validation", i, "didn't run")
+		}
+	}
+	if delay := v.iterativePriorityValidation(ctx, struct{}{}); delay != config.ValidationPoll {
+		Fail(t, "validated past the prioritized range")
+	}
+	if len(exec.launched) != 3 || exec.launched[0] != 3 || exec.launched[2] != 5 {
+		Fail(t, "validated messages", exec.launched, "ahead of their turn, expected 3 to 5")
+	}
+	for pos := arbutil.MessageIndex(2); pos < 7; pos++ {
+		if _, found := v.prioritized[pos]; found != (pos >= 3 && pos < 6) {
+			Fail(t, "message", pos, "validated ahead of its turn", found)
+		}
+	}
+
+	// in-order validation uses the result rather than validating again, and only once
+	roots := v.GetModuleRootsToValidate()
+	runs := v.takePrioritized(chain.entry(3), roots)
+	if len(runs) != 1 || runs[0].WasmModuleRoot() != moduleRoot {
+		Fail(t, "prioritized result wasn't used")
+	}
+	end, err := runs[0].Await(ctx)
+	Require(t, err)
+	if end != chain.entry(3).End {
+		Fail(t, "prioritized result has end state", end, "expected", chain.entry(3).End)
+	}
+	if v.takePrioritized(chain.entry(3), roots) != nil {
+		Fail(t, "prioritized result was used twice")
+	}
+
+	// a reorg drops the results from the first reorged message
+	v.dropPrioritized(5)
+	if _, found := v.prioritized[5]; found {
+		Fail(t, "reorged prioritized result was kept")
+	}
+	if _, found := v.prioritized[4]; !found {
+		Fail(t, "prioritized result before the reorg was dropped")
+	}
+
+	// an entry that no longer matches the result, or is validated against other roots, doesn't use it
+	chain.blockHashes[4] = common.HexToHash("0x4444")
+	if v.takePrioritized(chain.entry(4), roots) != nil {
+		Fail(t, "prioritized result was used for an entry with a different end state")
+	}
+	if _, found := v.prioritized[4]; found {
+		Fail(t, "mismatched prioritized result was kept")
+	}
+	v.PrioritizeValidation(6, 7)
+	if delay := v.iterativePriorityValidation(ctx, struct{}{}); delay != 0 {
+		Fail(t, "prioritized validation of message 6 didn't run")
+	}
+	if v.takePrioritized(chain.entry(6), []common.Hash{moduleRoot, common.HexToHash("0x02")}) != nil {
+		Fail(t, "prioritized result was used for other module roots")
+	}
+}
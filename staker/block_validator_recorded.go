@@ -0,0 +1,200 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+func recordedValidationEntryKey(pos arbutil.MessageIndex) []byte {
+	return binary.BigEndian.AppendUint64(common.CopyBytes(recordedValidationEntryPrefix), uint64(pos))
+}
+
+// persistRecordedEntry stores a recorded entry so that it needn't be recorded again if the node restarts before
+// validating it. An entry reorged out while it was being recorded may still be stored, but loadRecordedEntries
+// checks entries against the chain before using them.
+func (v *BlockValidator) persistRecordedEntry(e *validationEntry) error {
+	recorded := recordedValidationEntry{
+		Pos:           uint64(e.Pos),
+		Start:         e.Start,
+		End:           e.End,
+		HasDelayedMsg: e.HasDelayedMsg,
+		DelayedMsgNr:  e.DelayedMsgNr,
+		BatchInfo:     e.BatchInfo,
+		DelayedMsg:    e.DelayedMsg,
+	}
+	for ty, preimages := range e.Preimages {
+		for hash, data := range preimages {
+			recorded.Preimages = append(recorded.Preimages, recordedPreimage{
+				Type: ty,
+				Hash: hash,
+				Data: data,
+			})
+		}
+	}
+	encoded, err := rlp.EncodeToBytes(&recorded)
+	if err != nil {
+		return fmt.Errorf("failed encoding recorded validation entry: %w", err)
+	}
+	return v.db.Put(recordedValidationEntryKey(e.Pos), encoded)
+}
+
+// storeRecordedEntry persists a recorded entry if configured to, and spills it if it's at least the spill size:
+// once stored its preimages are released, and unspillRecordedEntry reads them back when it's about to be validated.
+// Entries that can't be stored stay in memory.
+func (v *BlockValidator) storeRecordedEntry(e *validationEntry) {
+	config := v.config()
+	spill := config.spillRecordedSize > 0 && e.size() >= config.spillRecordedSize
+	if !config.PersistRecordedEntries && !spill {
+		return
+	}
+	if err := v.persistRecordedEntry(e); err != nil {
+		log.Warn("failed storing recorded validation entry", "pos", e.Pos, "err", err)
+		return
+	}
+	if spill {
+		log.Debug("spilled recorded validation entry to database", "pos", e.Pos, "size", e.size())
+		e.Preimages = nil
+		e.spilled = true
+	}
+}
+
+// unspillRecordedEntry reads back the preimages of an entry spilled to the database
+func (v *BlockValidator) unspillRecordedEntry(e *validationEntry) error {
+	if !e.spilled {
+		return nil
+	}
+	encoded, err := v.db.Get(recordedValidationEntryKey(e.Pos))
+	if err != nil {
+		return err
+	}
+	var recorded recordedValidationEntry
+	if err := rlp.DecodeBytes(encoded, &recorded); err != nil {
+		return err
+	}
+	if recorded.Pos != uint64(e.Pos) || recorded.Start != e.Start || recorded.End != e.End {
+		return errors.New("spilled entry doesn't match the validation entry")
+	}
+	e.Preimages = make(map[arbutil.PreimageType]map[common.Hash][]byte)
+	for _, preimage := range recorded.Preimages {
+		if e.Preimages[preimage.Type] == nil {
+			e.Preimages[preimage.Type] = make(map[common.Hash][]byte)
+		}
+		e.Preimages[preimage.Type][preimage.Hash] = preimage.Data
+	}
+	e.spilled = false
+	return nil
+}
+
+// deleteRecordedEntries removes any stored entries for positions in [from, to)
+func (v *BlockValidator) deleteRecordedEntries(from, to arbutil.MessageIndex) {
+	if !v.config().PersistRecordedEntries && v.config().spillRecordedSize <= 0 {
+		return
+	}
+	batch := v.db.NewBatch()
+	for pos := from; pos < to; pos++ {
+		if err := batch.Delete(recordedValidationEntryKey(pos)); err != nil {
+			log.Warn("failed deleting recorded validation entry", "pos", pos, "err", err)
+			return
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Warn("failed deleting recorded validation entries", "from", from, "to", to, "err", err)
+	}
+}
+
+// loadRecordedEntry decodes the stored entry at pos, if it follows on from the next entry to create and still matches the chain
+func (v *BlockValidator) loadRecordedEntry(encoded []byte, pos arbutil.MessageIndex) (*validationEntry, error) {
+	var recorded recordedValidationEntry
+	if err := rlp.DecodeBytes(encoded, &recorded); err != nil {
+		return nil, err
+	}
+	if recorded.Pos != uint64(pos) || recorded.Start != v.nextCreateStartGS {
+		return nil, errors.New("doesn't follow on from the last entry")
+	}
+	caughtUp, count, err := GlobalStateToMsgCount(v.inboxTracker, v.streamer, recorded.End)
+	if err != nil {
+		return nil, err
+	}
+	if !caughtUp || count != pos+1 {
+		return nil, fmt.Errorf("end state %v is at count %v (caught up %v)", recorded.End, count, caughtUp)
+	}
+	entry := &validationEntry{
+		Stage:         Ready,
+		Pos:           pos,
+		Start:         recorded.Start,
+		End:           recorded.End,
+		HasDelayedMsg: recorded.HasDelayedMsg,
+		DelayedMsgNr:  recorded.DelayedMsgNr,
+		BatchInfo:     recorded.BatchInfo,
+		Preimages:     make(map[arbutil.PreimageType]map[common.Hash][]byte),
+		DelayedMsg:    recorded.DelayedMsg,
+	}
+	for _, preimage := range recorded.Preimages {
+		if entry.Preimages[preimage.Type] == nil {
+			entry.Preimages[preimage.Type] = make(map[common.Hash][]byte)
+		}
+		entry.Preimages[preimage.Type][preimage.Hash] = preimage.Data
+	}
+	return entry, nil
+}
+
+// loadRecordedEntries restores the entries recorded but not validated before the node last stopped, starting at
+// the validated count, so that they're validated without being recorded again. Entries that don't follow on or no
+// longer match the chain are deleted, along with all of them if persisting them is disabled.
+// Must be called before the validation threads start, with the created position at the validated count.
+func (v *BlockValidator) loadRecordedEntries(count arbutil.MessageIndex) {
+	loading := v.config().PersistRecordedEntries
+	pos := count
+	var prevDelayed uint64
+	batch := v.db.NewBatch()
+	iter := v.db.NewIterator(recordedValidationEntryPrefix, nil)
+	defer iter.Release()
+	for iter.Next() {
+		key := iter.Key()
+		if loading && len(key) == len(recordedValidationEntryPrefix)+8 && arbutil.MessageIndex(binary.BigEndian.Uint64(key[len(recordedValidationEntryPrefix):])) == pos {
+			entry, err := v.loadRecordedEntry(iter.Value(), pos)
+			if err == nil {
+				msg, msgErr := v.streamer.GetMessage(pos)
+				if msgErr == nil {
+					prevDelayed = msg.DelayedMessagesRead
+					v.validations.Store(pos, &validationStatus{
+						Status: uint32(Prepared),
+						Entry:  entry,
+					})
+					v.nextCreateStartGS = entry.End
+					pos++
+					continue
+				}
+				err = msgErr
+			}
+			log.Info("not restoring recorded validation entry", "pos", pos, "err", err)
+			loading = false
+		}
+		if err := batch.Delete(common.CopyBytes(key)); err != nil {
+			log.Warn("failed deleting stale recorded validation entry", "err", err)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		log.Warn("failed reading recorded validation entries", "err", err)
+	}
+	if err := batch.Write(); err != nil {
+		log.Warn("failed deleting stale recorded validation entries", "err", err)
+	}
+	if pos > count {
+		v.nextCreatePrevDelayed = prevDelayed
+		v.nextCreateBatchReread = true
+		atomicStorePos(&v.createdA, pos)
+		atomicStorePos(&v.recordSentA, pos)
+		log.Info("restored recorded validation entries", "from", count, "to", pos)
+	}
+}
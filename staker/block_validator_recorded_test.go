@@ -0,0 +1,194 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/validator"
+)
+
+func TestSpillRecordedEntry(t *testing.T) {
+	config := TestBlockValidatorConfig
+	config.SpillRecordedSize = "1KB"
+	Require(t, config.Validate())
+	v := &BlockValidator{
+		StatelessBlockValidator: &StatelessBlockValidator{db: rawdb.NewMemoryDatabase()},
+		config:                  func() *BlockValidatorConfig { return &config },
+	}
+	newEntry := func(pos arbutil.MessageIndex, preimageSize int) *validationEntry {
+		return &validationEntry{
+			Stage: Ready,
+			Pos:   pos,
+			Start: validator.GoGlobalState{Batch: 1, PosInBatch: uint64(pos)},
+			End:   validator.GoGlobalState{Batch: 1, PosInBatch: uint64(pos) + 1},
+			Preimages: map[arbutil.PreimageType]map[common.Hash][]byte{
+				arbutil.Keccak256PreimageType: {common.HexToHash("0x1"): make([]byte, preimageSize)},
+			},
+		}
+	}
+
+	small := newEntry(1, 10)
+	v.storeRecordedEntry(small)
+	if small.spilled || small.Preimages == nil {
+		Fail(t, "spilled an entry below the spill size")
+	}
+
+	large := newEntry(2, 2048)
+	v.storeRecordedEntry(large)
+	if !large.spilled || large.Preimages != nil || large.size() >= 2048 {
+		Fail(t, "didn't spill an entry past the spill size")
+	}
+	if _, err := large.ToInput(); err != nil {
+		Fail(t, "spilled entry isn't ready", err)
+	}
+	Require(t, v.unspillRecordedEntry(large))
+	if large.spilled || !bytes.Equal(large.Preimages[arbutil.Keccak256PreimageType][common.HexToHash("0x1")], make([]byte, 2048)) {
+		Fail(t, "spilled entry's preimages weren't restored")
+	}
+
+	v.deleteRecordedEntries(2, 3)
+	reused := newEntry(2, 0)
+	reused.spilled = true
+	if v.unspillRecordedEntry(reused) == nil {
+		Fail(t, "read back a deleted spilled entry")
+	}
+}
+
+// recordedTestChain has the init message in batch 0 and the rest of its messages in batch 1, which read one delayed message
+type recordedTestChain struct {
+	blockHashes []common.Hash
+}
+
+func newRecordedTestChain(messages uint64) *recordedTestChain {
+	chain := &recordedTestChain{}
+	for pos := uint64(0); pos < messages; pos++ {
+		chain.blockHashes = append(chain.blockHashes, common.BigToHash(new(big.Int).SetUint64(pos+1)))
+	}
+	return chain
+}
+
+func (c *recordedTestChain) SetBlockValidator(*BlockValidator) {}
+
+func (c *recordedTestChain) GetDelayedMessageBytes(uint64) ([]byte, error) {
+	return nil, errors.New("no delayed messages")
+}
+
+func (c *recordedTestChain) GetBatchMessageCount(seqNum uint64) (arbutil.MessageIndex, error) {
+	switch seqNum {
+	case 0:
+		return 1, nil
+	case 1:
+		return arbutil.MessageIndex(len(c.blockHashes)), nil
+	}
+	return 0, errors.New("batch not found")
+}
+
+func (c *recordedTestChain) GetBatchAcc(uint64) (common.Hash, error) {
+	return common.Hash{}, nil
+}
+
+func (c *recordedTestChain) GetBatchCount() (uint64, error) {
+	return 2, nil
+}
+
+func (c *recordedTestChain) GetProcessedMessageCount() (arbutil.MessageIndex, error) {
+	return arbutil.MessageIndex(len(c.blockHashes)), nil
+}
+
+func (c *recordedTestChain) GetMessage(pos arbutil.MessageIndex) (*arbostypes.MessageWithMetadata, error) {
+	return &arbostypes.MessageWithMetadata{DelayedMessagesRead: 1}, nil
+}
+
+func (c *recordedTestChain) ResultAtCount(count arbutil.MessageIndex) (*execution.MessageResult, error) {
+	if count == 0 || int(count) > len(c.blockHashes) {
+		return nil, errors.New("message not found")
+	}
+	return &execution.MessageResult{BlockHash: c.blockHashes[count-1]}, nil
+}
+
+func (c *recordedTestChain) PauseReorgs()  {}
+func (c *recordedTestChain) ResumeReorgs() {}
+
+// entry returns the validation entry of a message before the last, as it would be recorded on the chain now
+func (c *recordedTestChain) entry(pos arbutil.MessageIndex) *validationEntry {
+	return &validationEntry{
+		Stage: Ready,
+		Pos:   pos,
+		Start: validator.GoGlobalState{Batch: 1, PosInBatch: uint64(pos) - 1, BlockHash: c.blockHashes[pos-1]},
+		End:   validator.GoGlobalState{Batch: 1, PosInBatch: uint64(pos), BlockHash: c.blockHashes[pos]},
+		Preimages: map[arbutil.PreimageType]map[common.Hash][]byte{
+			arbutil.Keccak256PreimageType: {common.BigToHash(big.NewInt(int64(pos))): {byte(pos)}},
+		},
+	}
+}
+
+func TestLoadRecordedEntries(t *testing.T) {
+	for _, persist := range []bool{true, false} {
+		config := TestBlockValidatorConfig
+		config.PersistRecordedEntries = true
+		Require(t, config.Validate())
+		chain := newRecordedTestChain(8)
+		db := rawdb.NewMemoryDatabase()
+		newValidator := func() *BlockValidator {
+			return &BlockValidator{
+				StatelessBlockValidator: &StatelessBlockValidator{db: db, inboxTracker: chain, streamer: chain},
+				config:                  func() *BlockValidatorConfig { return &config },
+			}
+		}
+
+		// messages up to 2 were validated when the node stopped, and 2 to 5 and 7 were recorded
+		v := newValidator()
+		for _, pos := range []arbutil.MessageIndex{2, 3, 4, 5, 7} {
+			Require(t, v.persistRecordedEntry(chain.entry(pos)))
+		}
+		// message 4 was reorged while the node was stopped
+		chain.blockHashes[4] = common.HexToHash("0x4444")
+
+		config.PersistRecordedEntries = persist
+		v = newValidator()
+		v.nextCreateStartGS = chain.entry(2).Start
+		v.loadRecordedEntries(2)
+
+		restored := arbutil.MessageIndex(2)
+		if persist {
+			restored = 4
+		}
+		for pos := arbutil.MessageIndex(2); pos < 8; pos++ {
+			status, found := v.validations.Load(pos)
+			if found != (pos < restored) {
+				Fail(t, "persisting", persist, "message", pos, "restored", found)
+			}
+			if found && (status.getStatus() != Prepared || !bytes.Equal(status.Entry.Preimages[arbutil.Keccak256PreimageType][common.BigToHash(big.NewInt(int64(pos)))], []byte{byte(pos)})) {
+				Fail(t, "message", pos, "wasn't restored with its preimages")
+			}
+			_, err := db.Get(recordedValidationEntryKey(pos))
+			if stored := err == nil; stored != (pos < restored) {
+				Fail(t, "persisting", persist, "message", pos, "still stored", stored)
+			}
+		}
+		if !persist {
+			if v.created() != 0 || v.recordSent() != 0 || v.nextCreateStartGS != chain.entry(2).Start {
+				Fail(t, "validator moved on without restoring any entries")
+			}
+			continue
+		}
+		// the restored entries won't be created or recorded again
+		if v.created() != restored || v.recordSent() != restored {
+			Fail(t, "validator resumes creating at", v.created(), "and recording at", v.recordSent(), "expected", restored)
+		}
+		if v.nextCreateStartGS != chain.entry(restored-1).End || v.nextCreatePrevDelayed != 1 || !v.nextCreateBatchReread {
+			Fail(t, "validator doesn't resume creating after the last restored entry")
+		}
+	}
+}
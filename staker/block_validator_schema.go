@@ -5,6 +5,7 @@ package staker
 
 import (
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/validator"
 )
 
@@ -22,4 +23,23 @@ type GlobalStateValidatedInfo struct {
 var (
 	lastGlobalStateValidatedInfoKey = []byte("_lastGlobalStateValidatedInfo") // contains a rlp encoded lastBlockValidatedDbInfo
 	legacyLastBlockValidatedInfoKey = []byte("_lastBlockValidatedInfo")       // LEGACY - contains a rlp encoded lastBlockValidatedDbInfo
+	recordedValidationEntryPrefix   = []byte("_recordedValidationEntry")      // maps a message index to a rlp encoded recordedValidationEntry
 )
+
+type recordedPreimage struct {
+	Type arbutil.PreimageType
+	Hash common.Hash
+	Data []byte
+}
+
+// recordedValidationEntry is a validation entry that was recorded but not yet validated before the node stopped
+type recordedValidationEntry struct {
+	Pos           uint64
+	Start         validator.GoGlobalState
+	End           validator.GoGlobalState
+	HasDelayedMsg bool
+	DelayedMsgNr  uint64
+	BatchInfo     []validator.BatchInfo
+	Preimages     []recordedPreimage
+	DelayedMsg    []byte
+}
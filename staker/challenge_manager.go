@@ -16,14 +16,22 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/solgen/go/challengegen"
+	"github.com/offchainlabs/nitro/util/headerreader"
 	"github.com/offchainlabs/nitro/validator"
 )
 
 const maxBisectionDegree uint64 = 40
 
+var (
+	challengeBisectionsCounter    = metrics.NewRegisteredCounter("arb/staker/challenge/bisections", nil)
+	challengeExecChallengeCounter = metrics.NewRegisteredCounter("arb/staker/challenge/execution_challenges", nil)
+	challengeOneStepProofsCounter = metrics.NewRegisteredCounter("arb/staker/challenge/one_step_proofs", nil)
+)
+
 const challengeModeExecution = 2
 
 var initiatedChallengeID common.Hash
@@ -205,7 +213,9 @@ func (m *ChallengeManager) latestConfirmedBlock(ctx context.Context) (*big.Int,
 	if err != nil {
 		return nil, fmt.Errorf("error getting latest header from client: %w", err)
 	}
-	if latestBlock.Difficulty.Sign() == 0 {
+	// A parent chain with finality, either Ethereum PoS or an Arbitrum chain, says which blocks are final.
+	// Otherwise, count confirmations, which would be far too few on an Arbitrum parent chain's fast blocks.
+	if headerreader.HeaderIndicatesFinalitySupport(latestBlock) {
 		latestConfirmed, err := m.client.HeaderByNumber(ctx, big.NewInt(int64(rpc.FinalizedBlockNumber)))
 		if err != nil {
 			return nil, fmt.Errorf("error getting finalized block from client: %w", err)
@@ -223,6 +233,12 @@ func (m *ChallengeManager) ChallengeIndex() uint64 {
 	return m.challengeIndex
 }
 
+// DisputedMessages returns the range [start, end) of messages whose execution is disputed in the challenge
+func (m *ChallengeManager) DisputedMessages() (arbutil.MessageIndex, arbutil.MessageIndex) {
+	backend := m.blockChallengeBackend
+	return backend.startMsgCount, backend.startMsgCount + arbutil.MessageIndex(backend.tooFarStartsAtPosition) - 1
+}
+
 func uint64ToIndex(val uint64) common.Hash {
 	var challengeIndex common.Hash
 	binary.BigEndian.PutUint64(challengeIndex[(32-8):], val)
@@ -508,6 +524,11 @@ func (m *ChallengeManager) createExecutionBackend(ctx context.Context, step uint
 	return nil
 }
 
+// Act makes our next move in the challenge if it's our turn, which the staker calls every time it acts, so that
+// challenges are played out to the end without intervention. The move is found by comparing the opponent's latest
+// segments against our own: a block challenge is bisected over block hashes until a single block is disputed,
+// then becomes an execution challenge bisected over machine hashes from the replay binary, until a single step
+// is disputed and proven on the parent chain with a one-step proof.
 func (m *ChallengeManager) Act(ctx context.Context) (*types.Transaction, error) {
 	err := m.LoadExecChallengeIfExists(ctx)
 	if err != nil {
@@ -545,10 +566,12 @@ func (m *ChallengeManager) Act(ctx context.Context) (*types.Transaction, error)
 	endPosition := state.Segments[nextMovePos+1].Position
 	if startPosition+1 != endPosition {
 		log.Info("bisecting execution", "challenge", m.challengeIndex, "startPosition", startPosition, "endPosition", endPosition)
+		challengeBisectionsCounter.Inc(1)
 		return m.bisect(ctx, backend, state, nextMovePos)
 	}
 	if m.executionChallengeBackend != nil {
 		log.Info("sending onestepproof", "challenge", m.challengeIndex, "startPosition", startPosition, "endPosition", endPosition)
+		challengeOneStepProofsCounter.Inc(1)
 		return m.IssueOneStepProof(
 			ctx,
 			state,
@@ -561,6 +584,7 @@ func (m *ChallengeManager) Act(ctx context.Context) (*types.Transaction, error)
 	}
 	machineStepCount := m.machineFinalStepCount
 	log.Info("issuing one step proof", "challenge", m.challengeIndex, "machineStepCount", machineStepCount, "initialCount", m.initialMachineMessageCount)
+	challengeExecChallengeCounter.Inc(1)
 	return m.blockChallengeBackend.IssueExecChallenge(
 		m.challengeCore,
 		state,
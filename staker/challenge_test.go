@@ -160,7 +160,7 @@ func runChallengeTest(
 
 	asserterRun, err := server_arb.NewExecutionRun(ctx,
 		func(context.Context) (server_arb.MachineInterface, error) { return asserterMachine, nil },
-		&server_arb.DefaultMachineCacheConfig)
+		&server_arb.DefaultMachineCacheConfig, nil, server_arb.MachineStateKey{})
 	Require(t, err)
 
 	asserterManager, err := NewExecutionChallengeManager(
@@ -176,7 +176,7 @@ func runChallengeTest(
 
 	challengerRun, err := server_arb.NewExecutionRun(ctx,
 		func(context.Context) (server_arb.MachineInterface, error) { return challengerMachine, nil },
-		&server_arb.DefaultMachineCacheConfig)
+		&server_arb.DefaultMachineCacheConfig, nil, server_arb.MachineStateKey{})
 	Require(t, err)
 	challengerManager, err := NewExecutionChallengeManager(
 		backend,
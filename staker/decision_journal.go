@@ -0,0 +1,235 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/validator"
+)
+
+type DecisionJournalConfig struct {
+	Enable bool   `koanf:"enable"`
+	File   string `koanf:"file"`
+}
+
+var DefaultDecisionJournalConfig = DecisionJournalConfig{
+	Enable: false,
+	File:   "",
+}
+
+func DecisionJournalConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultDecisionJournalConfig.Enable, "record every assertion the staker examines and every action it takes, before it's taken, so they can be queried with the decision-journal command")
+	f.String(prefix+".file", DefaultDecisionJournalConfig.File, "file to append the staker decision journal to, one JSON entry per line")
+}
+
+func (c *DecisionJournalConfig) Validate() error {
+	if c.Enable && c.File == "" {
+		return errors.New("staker decision journal enabled but no file set")
+	}
+	return nil
+}
+
+// Kinds of decision journal entries
+const (
+	// An assertion on the parent chain, and whether it agrees with the locally validated chain
+	DecisionKindAssertion = "assertion"
+	// Something the staker decided to do, or decided against doing, about the assertions it examined
+	DecisionKindAction = "action"
+	// A transaction the staker sent to carry out its actions
+	DecisionKindTx = "tx"
+	// A failure that stopped the staker from acting
+	DecisionKindError = "error"
+)
+
+// Verdicts on an assertion
+const (
+	decisionVerdictCorrect   = "correct"
+	decisionVerdictIncorrect = "incorrect"
+)
+
+// DecisionJournalEntry is one line of the staker decision journal. Only the fields relevant to its kind are set.
+type DecisionJournalEntry struct {
+	Time     time.Time   `json:"time"`
+	Kind     string      `json:"kind"`
+	Strategy string      `json:"strategy,omitempty"`
+	Node     uint64      `json:"node,omitempty"`
+	NodeHash common.Hash `json:"nodeHash,omitempty"`
+	// Asserted is the global state the assertion claims, or that the staker is about to assert
+	Asserted *validator.GoGlobalState `json:"asserted,omitempty"`
+	// Local is the latest global state this node had validated when it made the decision
+	Local        *validator.GoGlobalState `json:"local,omitempty"`
+	Verdict      string                   `json:"verdict,omitempty"`
+	Action       string                   `json:"action,omitempty"`
+	Challenge    uint64                   `json:"challenge,omitempty"`
+	Counterparty *common.Address          `json:"counterparty,omitempty"`
+	Reason       string                   `json:"reason,omitempty"`
+	TxHash       *common.Hash             `json:"txHash,omitempty"`
+	Error        string                   `json:"error,omitempty"`
+}
+
+// decisionJournal appends staker decisions to a file, syncing each so that it survives the node crashing
+// while acting on it. The staker examines the same assertions every interval, so an assertion is only
+// recorded again if its verdict changes, and a repeated error only once until the staker next succeeds.
+// A nil journal records nothing.
+type decisionJournal struct {
+	mutex     sync.Mutex
+	file      *os.File
+	encoder   *json.Encoder
+	verdicts  map[common.Hash]string
+	lastError string
+}
+
+func openDecisionJournal(path string) (*decisionJournal, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening staker decision journal: %w", err)
+	}
+	return &decisionJournal{
+		file:     file,
+		encoder:  json.NewEncoder(file),
+		verdicts: make(map[common.Hash]string),
+	}, nil
+}
+
+// write must be called with the mutex held
+func (j *decisionJournal) write(entry *DecisionJournalEntry) {
+	entry.Time = time.Now().UTC()
+	if err := j.encoder.Encode(entry); err != nil {
+		log.Warn("error writing staker decision journal", "kind", entry.Kind, "err", err)
+		return
+	}
+	if err := j.file.Sync(); err != nil {
+		log.Warn("error syncing staker decision journal", "err", err)
+	}
+}
+
+func (j *decisionJournal) assertion(nd *NodeInfo, local validator.GoGlobalState, verdict string, reason string) {
+	if j == nil {
+		return
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.verdicts[nd.NodeHash] == verdict {
+		return
+	}
+	j.verdicts[nd.NodeHash] = verdict
+	asserted := nd.AfterState().GlobalState
+	j.write(&DecisionJournalEntry{
+		Kind:     DecisionKindAssertion,
+		Node:     nd.NodeNum,
+		NodeHash: nd.NodeHash,
+		Asserted: &asserted,
+		Local:    &local,
+		Verdict:  verdict,
+		Reason:   reason,
+	})
+}
+
+func (j *decisionJournal) action(strategy StakerStrategy, entry *DecisionJournalEntry) {
+	if j == nil {
+		return
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	entry.Kind = DecisionKindAction
+	entry.Strategy = strategy.String()
+	j.write(entry)
+}
+
+// actResult records the transaction the staker sent, if any, and whether it succeeded
+func (j *decisionJournal) actResult(tx *types.Transaction, err error) {
+	if j == nil {
+		return
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if tx != nil {
+		txHash := tx.Hash()
+		entry := &DecisionJournalEntry{
+			Kind:   DecisionKindTx,
+			TxHash: &txHash,
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		j.write(entry)
+	} else if err != nil && err.Error() != j.lastError {
+		j.write(&DecisionJournalEntry{
+			Kind:  DecisionKindError,
+			Error: err.Error(),
+		})
+	}
+	if err != nil {
+		j.lastError = err.Error()
+	} else {
+		j.lastError = ""
+	}
+}
+
+func (j *decisionJournal) close() {
+	if j == nil {
+		return
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if err := j.file.Close(); err != nil {
+		log.Warn("error closing staker decision journal", "err", err)
+	}
+}
+
+// DecisionJournalFilter selects decision journal entries. Zero values match everything.
+type DecisionJournalFilter struct {
+	Node  uint64
+	Kind  string
+	Since time.Time
+	Until time.Time
+}
+
+func (f *DecisionJournalFilter) matches(entry *DecisionJournalEntry) bool {
+	if f.Node != 0 && entry.Node != f.Node {
+		return false
+	}
+	if f.Kind != "" && entry.Kind != f.Kind {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// QueryDecisionJournal reads the journal's entries matching the filter, in the order they were recorded.
+// An entry cut short by the node stopping while writing it is ignored.
+func QueryDecisionJournal(reader io.Reader, filter *DecisionJournalFilter) ([]*DecisionJournalEntry, error) {
+	decoder := json.NewDecoder(reader)
+	entries := []*DecisionJournalEntry{}
+	for line := 1; ; line++ {
+		var entry DecisionJournalEntry
+		err := decoder.Decode(&entry)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading staker decision journal entry %v: %w", line, err)
+		}
+		if filter.matches(&entry) {
+			entries = append(entries, &entry)
+		}
+	}
+}
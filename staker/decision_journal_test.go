@@ -0,0 +1,89 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/nitro/validator"
+)
+
+func TestDecisionJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	journal, err := openDecisionJournal(path)
+	Require(t, err)
+
+	local := validator.GoGlobalState{BlockHash: common.HexToHash("0x10ca1"), Batch: 3}
+	node := &NodeInfo{
+		NodeNum:  7,
+		NodeHash: common.HexToHash("0x7"),
+		Assertion: &Assertion{
+			AfterState: &validator.ExecutionState{
+				GlobalState:   validator.GoGlobalState{BlockHash: common.HexToHash("0xbad"), Batch: 3},
+				MachineStatus: validator.MachineStatusFinished,
+			},
+		},
+	}
+	// The staker examines the same assertion every interval, but it's only recorded once per verdict
+	for i := 0; i < 3; i++ {
+		journal.assertion(node, local, decisionVerdictIncorrect, "global state not in chain")
+	}
+	journal.action(DefensiveStrategy, &DecisionJournalEntry{
+		Action: "activate-defensive",
+		Node:   node.NodeNum,
+	})
+	tx := types.NewTransaction(0, common.Address{}, common.Big0, 0, common.Big0, nil)
+	journal.actResult(tx, nil)
+	for i := 0; i < 3; i++ {
+		journal.actResult(nil, errors.New("parent chain unavailable"))
+	}
+	journal.actResult(nil, nil)
+	journal.close()
+
+	// A node stopping mid-write leaves a partial last entry, which is ignored
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	Require(t, err)
+	_, err = file.WriteString(`{"time":"2023-`)
+	Require(t, err)
+	Require(t, file.Close())
+
+	file, err = os.Open(path)
+	Require(t, err)
+	defer file.Close()
+	entries, err := QueryDecisionJournal(file, &DecisionJournalFilter{})
+	Require(t, err)
+	if len(entries) != 4 {
+		Fail(t, "expected 4 journal entries but got", len(entries))
+	}
+	seen := entries[0]
+	if seen.Kind != DecisionKindAssertion || seen.Verdict != decisionVerdictIncorrect || seen.Local == nil || *seen.Local != local {
+		Fail(t, "unexpected assertion entry", seen)
+	}
+	if seen.Asserted == nil || *seen.Asserted != node.AfterState().GlobalState {
+		Fail(t, "assertion entry has wrong asserted state", seen.Asserted)
+	}
+	if entries[1].Action != "activate-defensive" || entries[1].Strategy != "Defensive" {
+		Fail(t, "unexpected action entry", entries[1])
+	}
+	if entries[2].TxHash == nil || *entries[2].TxHash != tx.Hash() {
+		Fail(t, "unexpected tx entry", entries[2])
+	}
+	if entries[3].Kind != DecisionKindError || entries[3].Error != "parent chain unavailable" {
+		Fail(t, "unexpected error entry", entries[3])
+	}
+
+	_, err = file.Seek(0, 0)
+	Require(t, err)
+	entries, err = QueryDecisionJournal(file, &DecisionJournalFilter{Node: node.NodeNum})
+	Require(t, err)
+	if len(entries) != 2 || entries[0].Kind != DecisionKindAssertion || entries[1].Kind != DecisionKindAction {
+		Fail(t, "unexpected entries about node", node.NodeNum, entries)
+	}
+}
@@ -55,6 +55,9 @@ type L1Validator struct {
 	txStreamer         TransactionStreamerInterface
 	blockValidator     *BlockValidator
 	lastWasmModuleRoot common.Hash
+	divergences        *divergenceTracker
+	// May be nil
+	journal *decisionJournal
 }
 
 func NewL1Validator(
@@ -94,6 +97,7 @@ func NewL1Validator(
 		inboxTracker:   inboxTracker,
 		txStreamer:     txStreamer,
 		blockValidator: blockValidator,
+		divergences:    newDivergenceTracker(),
 	}, nil
 }
 
@@ -120,7 +124,7 @@ func (v *L1Validator) updateBlockValidatorModuleRoot(ctx context.Context) error
 		return err
 	}
 	if moduleRoot != v.lastWasmModuleRoot {
-		err := v.blockValidator.SetCurrentWasmModuleRoot(moduleRoot)
+		err := v.blockValidator.SetCurrentWasmModuleRoot(ctx, moduleRoot)
 		if err != nil {
 			return err
 		}
@@ -393,6 +397,8 @@ func (v *L1Validator) generateNodeAction(
 		}
 		if correctNode != nil {
 			log.Error("found younger sibling to correct assertion (implicitly invalid)", "node", nd.NodeNum)
+			v.divergences.report(nd, "younger sibling of the correct assertion")
+			v.journal.assertion(nd, validatedGlobalState, decisionVerdictIncorrect, "younger sibling of the correct assertion")
 			wrongNodesExist = true
 			continue
 		}
@@ -416,12 +422,17 @@ func (v *L1Validator) generateNodeAction(
 		if nd.Assertion.AfterState.MachineStatus != validator.MachineStatusFinished {
 			wrongNodesExist = true
 			log.Error("Found incorrect assertion: Machine status not finished", "node", nd.NodeNum, "machineStatus", nd.Assertion.AfterState.MachineStatus)
+			reason := fmt.Sprintf("machine status %v isn't finished", nd.Assertion.AfterState.MachineStatus)
+			v.divergences.report(nd, reason)
+			v.journal.assertion(nd, validatedGlobalState, decisionVerdictIncorrect, reason)
 			continue
 		}
 		caughtUp, nodeMsgCount, err := GlobalStateToMsgCount(v.inboxTracker, v.txStreamer, afterGS)
 		if errors.Is(err, ErrGlobalStateNotInChain) {
 			wrongNodesExist = true
 			log.Error("Found incorrect assertion", "node", nd.NodeNum, "afterGS", afterGS, "err", err)
+			v.divergences.report(nd, err.Error())
+			v.journal.assertion(nd, validatedGlobalState, decisionVerdictIncorrect, err.Error())
 			continue
 		}
 		if err != nil {
@@ -436,6 +447,7 @@ func (v *L1Validator) generateNodeAction(
 			"count", nodeMsgCount,
 			"blockHash", afterGS.BlockHash,
 		)
+		v.journal.assertion(nd, validatedGlobalState, decisionVerdictCorrect, "")
 		correctNode = existingNodeAction{
 			number: nd.NodeNum,
 			hash:   nd.NodeHash,
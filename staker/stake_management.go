@@ -0,0 +1,249 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var errNoStakerWallet = errors.New("staker has no wallet to act with")
+
+// operatorRequested is the decision journal reason for stake operations requested through the admin API
+const operatorRequested = "requested by operator"
+
+func (s *Staker) getStakers(callOpts *bind.CallOpts) ([]common.Address, error) {
+	stakers, moreStakers, err := s.validatorUtils.GetStakers(callOpts, s.rollupAddress, 0, 1024)
+	if err != nil {
+		return nil, fmt.Errorf("error getting stakers list: %w", err)
+	}
+	for moreStakers {
+		var newStakers []common.Address
+		newStakers, moreStakers, err = s.validatorUtils.GetStakers(callOpts, s.rollupAddress, uint64(len(stakers)), 1024)
+		if err != nil {
+			return nil, fmt.Errorf("error getting more stakers: %w", err)
+		}
+		stakers = append(stakers, newStakers...)
+	}
+	return stakers, nil
+}
+
+// checkStakeRefundable returns an error unless the rollup would return the staker's deposit:
+// it must not be in a challenge, and the latest node it's staked on must have been confirmed.
+func (s *Staker) checkStakeRefundable(callOpts *bind.CallOpts, staker common.Address, info *StakerInfo) error {
+	if info.CurrentChallenge != nil {
+		return fmt.Errorf("staker %v is in challenge %v", staker, *info.CurrentChallenge)
+	}
+	latestStaked, _, err := s.validatorUtils.LatestStaked(callOpts, s.rollupAddress, staker)
+	if err != nil {
+		return fmt.Errorf("error getting latest staked node of staker %v: %w", staker, err)
+	}
+	latestConfirmed, err := s.rollup.LatestConfirmed(callOpts)
+	if err != nil {
+		return fmt.Errorf("error getting latest confirmed node: %w", err)
+	}
+	if latestStaked > latestConfirmed {
+		return fmt.Errorf("staker %v is staked on node %v, which isn't confirmed yet (latest confirmed %v)", staker, latestStaked, latestConfirmed)
+	}
+	return nil
+}
+
+// sendStakeOperation builds each step's transaction and sends them, batched if the wallet can batch them
+// and otherwise one at a time, waiting for each to be approved. Returns the hashes of the transactions sent.
+// Must be called with the act mutex held.
+func (s *Staker) sendStakeOperation(ctx context.Context, steps []func(context.Context) error) ([]common.Hash, error) {
+	s.builder.ClearTransactions()
+	defer s.builder.ClearTransactions()
+	sent := []common.Hash{}
+	send := func() error {
+		tx, err := s.wallet.ExecuteTransactions(ctx, s.builder, s.config.gasRefunder)
+		s.builder.ClearTransactions()
+		s.journal.actResult(tx, err)
+		if err != nil || tx == nil {
+			return err
+		}
+		sent = append(sent, tx.Hash())
+		if _, err := s.l1Reader.WaitForTxApproval(ctx, tx); err != nil {
+			return fmt.Errorf("error waiting for tx receipt: %w", err)
+		}
+		return nil
+	}
+	for _, step := range steps {
+		if err := step(ctx); err != nil {
+			return sent, err
+		}
+		if !s.wallet.CanBatchTxs() {
+			if err := send(); err != nil {
+				return sent, err
+			}
+		}
+	}
+	if err := send(); err != nil {
+		return sent, err
+	}
+	return sent, nil
+}
+
+// WithdrawStake returns the staker's deposit once the latest node it's staked on is confirmed, and withdraws it
+// along with any other funds the rollup holds for it. A staker with the stake-latest strategy or above stakes
+// again the next time it acts, so its strategy should be lowered first if the stake is to stay withdrawn.
+func (s *Staker) WithdrawStake(ctx context.Context) ([]common.Hash, error) {
+	s.actMutex.Lock()
+	defer s.actMutex.Unlock()
+	walletAddr := s.wallet.Address()
+	if walletAddr == nil {
+		return nil, errNoStakerWallet
+	}
+	callOpts := s.getCallOpts(ctx)
+	info, err := s.rollup.StakerInfo(ctx, *walletAddr)
+	if err != nil {
+		return nil, fmt.Errorf("error getting own staker (%v) info: %w", *walletAddr, err)
+	}
+	var steps []func(context.Context) error
+	if info != nil {
+		if err := s.checkStakeRefundable(callOpts, *walletAddr, info); err != nil {
+			return nil, err
+		}
+		steps = append(steps, func(ctx context.Context) error {
+			auth, err := s.builder.Auth(ctx)
+			if err != nil {
+				return err
+			}
+			_, err = s.rollup.ReturnOldDeposit(auth, *walletAddr)
+			if err != nil {
+				return fmt.Errorf("error returning old deposit (from our staker %v): %w", *walletAddr, err)
+			}
+			return nil
+		})
+	} else {
+		withdrawable, err := s.rollup.WithdrawableFunds(callOpts, *walletAddr)
+		if err != nil {
+			return nil, fmt.Errorf("error checking withdrawable funds of our staker %v: %w", *walletAddr, err)
+		}
+		if withdrawable.Sign() == 0 {
+			return nil, fmt.Errorf("staker %v has no stake or funds to withdraw", *walletAddr)
+		}
+	}
+	steps = append(steps, func(ctx context.Context) error {
+		auth, err := s.builder.Auth(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = s.rollup.WithdrawStakerFunds(auth)
+		if err != nil {
+			return fmt.Errorf("error withdrawing staker funds from our staker %v: %w", *walletAddr, err)
+		}
+		return nil
+	})
+	s.journal.action(s.config.strategy, &DecisionJournalEntry{
+		Action: "withdraw-stake",
+		Reason: operatorRequested,
+	})
+	return s.sendStakeOperation(ctx, steps)
+}
+
+// RefundOldStakers returns the deposits of the given stakers, which must all be refundable, to their withdrawable
+// funds on the rollup. If no stakers are given, every other staker whose deposit can be returned is refunded.
+func (s *Staker) RefundOldStakers(ctx context.Context, stakers []common.Address) ([]common.Hash, error) {
+	s.actMutex.Lock()
+	defer s.actMutex.Unlock()
+	if s.wallet.Address() == nil {
+		return nil, errNoStakerWallet
+	}
+	callOpts := s.getCallOpts(ctx)
+	requested := len(stakers) > 0
+	if !requested {
+		var err error
+		stakers, err = s.getStakers(callOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var steps []func(context.Context) error
+	for _, staker := range stakers {
+		staker := staker
+		if !requested && staker == *s.wallet.Address() {
+			continue
+		}
+		info, err := s.rollup.StakerInfo(ctx, staker)
+		if err != nil {
+			return nil, fmt.Errorf("error getting staker %v info: %w", staker, err)
+		}
+		if info == nil {
+			if requested {
+				return nil, fmt.Errorf("%v isn't staked", staker)
+			}
+			continue
+		}
+		if err := s.checkStakeRefundable(callOpts, staker, info); err != nil {
+			if requested {
+				return nil, err
+			}
+			continue
+		}
+		s.journal.action(s.config.strategy, &DecisionJournalEntry{
+			Action:       "refund-stake",
+			Counterparty: &staker,
+			Reason:       operatorRequested,
+		})
+		steps = append(steps, func(ctx context.Context) error {
+			auth, err := s.builder.Auth(ctx)
+			if err != nil {
+				return err
+			}
+			_, err = s.rollup.ReturnOldDeposit(auth, staker)
+			if err != nil {
+				return fmt.Errorf("error returning old deposit of staker %v: %w", staker, err)
+			}
+			return nil
+		})
+	}
+	return s.sendStakeOperation(ctx, steps)
+}
+
+// AddToStake tops up the staker's deposit to the rollup's current required stake, which rises while
+// unconfirmed nodes pile up. Sends nothing if the deposit already meets it.
+func (s *Staker) AddToStake(ctx context.Context) ([]common.Hash, error) {
+	s.actMutex.Lock()
+	defer s.actMutex.Unlock()
+	walletAddr := s.wallet.Address()
+	if walletAddr == nil {
+		return nil, errNoStakerWallet
+	}
+	info, err := s.rollup.StakerInfo(ctx, *walletAddr)
+	if err != nil {
+		return nil, fmt.Errorf("error getting own staker (%v) info: %w", *walletAddr, err)
+	}
+	if info == nil {
+		return nil, fmt.Errorf("staker %v isn't staked", *walletAddr)
+	}
+	requiredStake, err := s.rollup.CurrentRequiredStake(s.getCallOpts(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("error getting current required stake: %w", err)
+	}
+	if info.AmountStaked.Cmp(requiredStake) >= 0 {
+		return []common.Hash{}, nil
+	}
+	amount := new(big.Int).Sub(requiredStake, info.AmountStaked)
+	s.journal.action(s.config.strategy, &DecisionJournalEntry{
+		Action: "add-to-stake",
+		Reason: fmt.Sprintf("%v: staked %v of required %v", operatorRequested, info.AmountStaked, requiredStake),
+	})
+	return s.sendStakeOperation(ctx, []func(context.Context) error{func(ctx context.Context) error {
+		auth, err := s.builder.AuthWithAmount(ctx, amount)
+		if err != nil {
+			return err
+		}
+		_, err = s.rollup.AddToDeposit(auth, *walletAddr)
+		if err != nil {
+			return fmt.Errorf("error adding %v to our staker %v deposit: %w", amount, *walletAddr, err)
+		}
+		return nil
+	}})
+}
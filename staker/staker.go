@@ -10,6 +10,8 @@ import (
 	"math/big"
 	"runtime/debug"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -39,6 +41,7 @@ var (
 	stakerLastSuccessfulActionGauge = metrics.NewRegisteredGauge("arb/staker/action/last_success", nil)
 	stakerActionSuccessCounter      = metrics.NewRegisteredCounter("arb/staker/action/success", nil)
 	stakerActionFailureCounter      = metrics.NewRegisteredCounter("arb/staker/action/failure", nil)
+	stakerDefensiveActiveGauge      = metrics.NewRegisteredGauge("arb/staker/defensive/active", nil)
 	validatorGasRefunderBalance     = metrics.NewRegisteredGaugeFloat64("arb/validator/gasrefunder/balanceether", nil)
 )
 
@@ -57,6 +60,23 @@ const (
 	MakeNodesStrategy
 )
 
+func (s StakerStrategy) String() string {
+	switch s {
+	case WatchtowerStrategy:
+		return "Watchtower"
+	case DefensiveStrategy:
+		return "Defensive"
+	case StakeLatestStrategy:
+		return "StakeLatest"
+	case ResolveNodesStrategy:
+		return "ResolveNodes"
+	case MakeNodesStrategy:
+		return "MakeNodes"
+	default:
+		return fmt.Sprintf("StakerStrategy(%d)", uint8(s))
+	}
+}
+
 type L1PostingStrategy struct {
 	HighGasThreshold   float64 `koanf:"high-gas-threshold"`
 	HighGasDelayBlocks int64   `koanf:"high-gas-delay-blocks"`
@@ -90,6 +110,8 @@ type L1ValidatorConfig struct {
 	ExtraGas                  uint64                      `koanf:"extra-gas" reload:"hot"`
 	Dangerous                 DangerousConfig             `koanf:"dangerous"`
 	ParentChainWallet         genericconf.WalletConfig    `koanf:"parent-chain-wallet"`
+	Watchtower                WatchtowerConfig            `koanf:"watchtower"`
+	DecisionJournal           DecisionJournalConfig       `koanf:"decision-journal"`
 
 	strategy    StakerStrategy
 	gasRefunder common.Address
@@ -120,7 +142,7 @@ func (c *L1ValidatorConfig) ValidatorRequired() bool {
 		return false
 	}
 	if c.strategy == WatchtowerStrategy {
-		return false
+		return c.Watchtower.Validate
 	}
 	return true
 }
@@ -135,7 +157,13 @@ func (c *L1ValidatorConfig) Validate() error {
 		return errors.New("invalid validator gas refunder address")
 	}
 	c.gasRefunder = common.HexToAddress(c.GasRefunderAddress)
-	return nil
+	if err := c.Watchtower.Validate(); err != nil {
+		return err
+	}
+	if err := c.DecisionJournal.Validate(); err != nil {
+		return err
+	}
+	return c.DataPoster.Validate()
 }
 
 var DefaultL1ValidatorConfig = L1ValidatorConfig{
@@ -156,6 +184,8 @@ var DefaultL1ValidatorConfig = L1ValidatorConfig{
 	ExtraGas:                  50000,
 	Dangerous:                 DefaultDangerousConfig,
 	ParentChainWallet:         DefaultValidatorL1WalletConfig,
+	Watchtower:                DefaultWatchtowerConfig,
+	DecisionJournal:           DefaultDecisionJournalConfig,
 }
 
 var TestL1ValidatorConfig = L1ValidatorConfig{
@@ -176,6 +206,8 @@ var TestL1ValidatorConfig = L1ValidatorConfig{
 	ExtraGas:                  50000,
 	Dangerous:                 DefaultDangerousConfig,
 	ParentChainWallet:         DefaultValidatorL1WalletConfig,
+	Watchtower:                DefaultWatchtowerConfig,
+	DecisionJournal:           DefaultDecisionJournalConfig,
 }
 
 var DefaultValidatorL1WalletConfig = genericconf.WalletConfig{
@@ -188,7 +220,7 @@ var DefaultValidatorL1WalletConfig = genericconf.WalletConfig{
 
 func L1ValidatorConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultL1ValidatorConfig.Enable, "enable validator")
-	f.String(prefix+".strategy", DefaultL1ValidatorConfig.Strategy, "L1 validator strategy, either watchtower, defensive, stakeLatest, or makeNodes")
+	f.String(prefix+".strategy", DefaultL1ValidatorConfig.Strategy, "L1 validator strategy, either watchtower, defensive (only stake to challenge an incorrect assertion), stakeLatest, resolveNodes, or makeNodes")
 	f.Duration(prefix+".staker-interval", DefaultL1ValidatorConfig.StakerInterval, "how often the L1 validator should check the status of the L1 rollup and maybe take action with its stake")
 	f.Duration(prefix+".make-assertion-interval", DefaultL1ValidatorConfig.MakeAssertionInterval, "if configured with the makeNodes strategy, how often to create new assertions (bypassed in case of a dispute)")
 	L1PostingStrategyAddOptions(prefix+".posting-strategy", f)
@@ -204,6 +236,8 @@ func L1ValidatorConfigAddOptions(prefix string, f *flag.FlagSet) {
 	dataposter.DataPosterConfigAddOptions(prefix+".data-poster", f, dataposter.DefaultDataPosterConfigForValidator)
 	DangerousConfigAddOptions(prefix+".dangerous", f)
 	genericconf.WalletConfigAddOptions(prefix+".parent-chain-wallet", f, DefaultL1ValidatorConfig.ParentChainWallet.Pathname)
+	WatchtowerConfigAddOptions(prefix+".watchtower", f)
+	DecisionJournalConfigAddOptions(prefix+".decision-journal", f)
 }
 
 type DangerousConfig struct {
@@ -250,6 +284,10 @@ type Staker struct {
 	inboxReader             InboxReaderInterface
 	statelessBlockValidator *StatelessBlockValidator
 	fatalErr                chan<- error
+	latestStaked            atomic.Pointer[AssertionProgress]
+	latestConfirmed         atomic.Pointer[AssertionProgress]
+	// actMutex stops stake operations requested through the admin API from building transactions while the staker acts
+	actMutex sync.Mutex
 }
 
 type ValidatorWalletInterface interface {
@@ -299,6 +337,12 @@ func NewStaker(
 	if config.StartValidationFromStaked && blockValidator != nil {
 		stakedNotifiers = append(stakedNotifiers, blockValidator)
 	}
+	if config.DecisionJournal.Enable {
+		val.journal, err = openDecisionJournal(config.DecisionJournal.File)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &Staker{
 		L1Validator:             val,
 		l1Reader:                l1Reader,
@@ -343,22 +387,22 @@ func (s *Staker) Initialize(ctx context.Context) error {
 	return nil
 }
 
-func (s *Staker) getLatestStakedState(ctx context.Context, staker common.Address) (uint64, arbutil.MessageIndex, *validator.GoGlobalState, error) {
+func (s *Staker) getLatestStakedState(ctx context.Context, staker common.Address) (*NodeInfo, arbutil.MessageIndex, *validator.GoGlobalState, error) {
 	callOpts := s.getCallOpts(ctx)
 	if s.l1Reader.UseFinalityData() {
 		callOpts.BlockNumber = big.NewInt(int64(rpc.FinalizedBlockNumber))
 	}
 	latestStaked, _, err := s.validatorUtils.LatestStaked(s.getCallOpts(ctx), s.rollupAddress, staker)
 	if err != nil {
-		return 0, 0, nil, fmt.Errorf("couldn't get LatestStaked(%v): %w", staker, err)
+		return nil, 0, nil, fmt.Errorf("couldn't get LatestStaked(%v): %w", staker, err)
 	}
 	if latestStaked == 0 {
-		return latestStaked, 0, nil, nil
+		return nil, 0, nil, nil
 	}
 
 	stakedInfo, err := s.rollup.LookupNode(ctx, latestStaked)
 	if err != nil {
-		return 0, 0, nil, fmt.Errorf("couldn't look up latest assertion of %v (%v): %w", staker, latestStaked, err)
+		return nil, 0, nil, fmt.Errorf("couldn't look up latest assertion of %v (%v): %w", staker, latestStaked, err)
 	}
 
 	globalState := stakedInfo.AfterState().GlobalState
@@ -368,25 +412,25 @@ func (s *Staker) getLatestStakedState(ctx context.Context, staker common.Address
 			fatal := fmt.Errorf("latest assertion of %v (%v) not in chain: %w", staker, latestStaked, err)
 			s.fatalErr <- fatal
 		}
-		return 0, 0, nil, fmt.Errorf("latest assertion of %v (%v): %w", staker, latestStaked, err)
+		return nil, 0, nil, fmt.Errorf("latest assertion of %v (%v): %w", staker, latestStaked, err)
 	}
 
 	if !caughtUp {
 		log.Info("latest assertion not yet in our node", "staker", staker, "assertion", latestStaked, "state", globalState)
-		return latestStaked, 0, nil, nil
+		return stakedInfo, 0, nil, nil
 	}
 
 	processedCount, err := s.txStreamer.GetProcessedMessageCount()
 	if err != nil {
-		return 0, 0, nil, err
+		return nil, 0, nil, err
 	}
 
 	if processedCount < count {
 		log.Info("execution catching up to rollup", "staker", staker, "rollupCount", count, "processedCount", processedCount)
-		return latestStaked, 0, nil, nil
+		return stakedInfo, 0, nil, nil
 	}
 
-	return latestStaked, count, &globalState, nil
+	return stakedInfo, count, &globalState, nil
 }
 
 func (s *Staker) StopAndWait() {
@@ -394,6 +438,7 @@ func (s *Staker) StopAndWait() {
 	if s.Strategy() != WatchtowerStrategy {
 		s.wallet.StopAndWait()
 	}
+	s.journal.close()
 }
 
 func (s *Staker) Start(ctxIn context.Context) {
@@ -404,6 +449,8 @@ func (s *Staker) Start(ctxIn context.Context) {
 	backoff := time.Second
 	ephemeralErrorHandler := util.NewEphemeralErrorHandler(10*time.Minute, "is ahead of on-chain nonce", 0)
 	s.CallIteratively(func(ctx context.Context) (returningWait time.Duration) {
+		s.actMutex.Lock()
+		defer s.actMutex.Unlock()
 		defer func() {
 			panicErr := recover()
 			if panicErr != nil {
@@ -434,6 +481,7 @@ func (s *Staker) Start(ctxIn context.Context) {
 				err = fmt.Errorf("error waiting for tx receipt: %w", err)
 			}
 		}
+		s.journal.actResult(arbTx, err)
 		if err == nil {
 			ephemeralErrorHandler.Reset()
 			backoff = time.Second
@@ -463,7 +511,10 @@ func (s *Staker) Start(ctxIn context.Context) {
 		if err != nil && ctx.Err() == nil {
 			log.Error("staker: error checking latest staked", "err", err)
 		}
-		stakerLatestStakedNodeGauge.Update(int64(staked))
+		if err == nil {
+			s.latestStaked.Store(&AssertionProgress{Node: staked, MessageCount: stakedMsgCount})
+		}
+		stakerLatestStakedNodeGauge.Update(int64(staked.number()))
 		if stakedGlobalState != nil {
 			for _, notifier := range s.stakedNotifiers {
 				notifier.UpdateLatestStaked(stakedMsgCount, *stakedGlobalState)
@@ -478,7 +529,10 @@ func (s *Staker) Start(ctxIn context.Context) {
 				log.Error("staker: error checking latest confirmed", "err", err)
 			}
 		}
-		stakerLatestConfirmedNodeGauge.Update(int64(confirmed))
+		if err == nil {
+			s.latestConfirmed.Store(&AssertionProgress{Node: confirmed, MessageCount: confirmedMsgCount})
+		}
+		stakerLatestConfirmedNodeGauge.Update(int64(confirmed.number()))
 		if confirmedGlobalState != nil {
 			for _, notifier := range s.confirmedNotifiers {
 				notifier.UpdateLatestConfirmed(confirmedMsgCount, *confirmedGlobalState)
@@ -507,20 +561,22 @@ func (s *Staker) IsWhitelisted(ctx context.Context) (bool, error) {
 func (s *Staker) shouldAct(ctx context.Context) bool {
 	var gasPriceHigh = false
 	var gasPriceFloat float64
-	gasPrice, err := s.client.SuggestGasPrice(ctx)
+	latestBlockInfo, err := s.client.HeaderByNumber(ctx, nil)
 	if err != nil {
-		log.Warn("error getting gas price", "err", err)
-	} else {
+		log.Warn("error getting latest block", "err", err)
+		return true
+	}
+	// The price a dynamic fee transaction pays is the base fee plus the priority fee
+	gasTipCap, err := s.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		log.Warn("error getting gas tip cap", "err", err)
+	} else if latestBlockInfo.BaseFee != nil {
+		gasPrice := arbmath.BigAdd(latestBlockInfo.BaseFee, gasTipCap)
 		gasPriceFloat = float64(gasPrice.Int64()) / 1e9
 		if gasPriceFloat >= s.config.PostingStrategy.HighGasThreshold {
 			gasPriceHigh = true
 		}
 	}
-	latestBlockInfo, err := s.client.HeaderByNumber(ctx, nil)
-	if err != nil {
-		log.Warn("error getting latest block", "err", err)
-		return true
-	}
 	latestBlockNum := latestBlockInfo.Number
 	if s.lastActCalledBlock == nil {
 		s.lastActCalledBlock = latestBlockNum
@@ -649,6 +705,7 @@ func (s *Staker) Act(ctx context.Context) (*types.Transaction, error) {
 		} else {
 			log.Info("defensive validator staked past incorrect node; waiting here")
 			s.bringActiveUntilNode = 0
+			stakerDefensiveActiveGauge.Update(0)
 		}
 		s.inactiveLastCheckedNode = nil
 	}
@@ -785,6 +842,9 @@ func (s *Staker) Act(ctx context.Context) (*types.Transaction, error) {
 
 func (s *Staker) handleConflict(ctx context.Context, info *StakerInfo) error {
 	if info.CurrentChallenge == nil {
+		if s.activeChallenge != nil && s.blockValidator != nil {
+			s.blockValidator.ClearValidationPriority()
+		}
 		s.activeChallenge = nil
 		return nil
 	}
@@ -813,9 +873,18 @@ func (s *Staker) handleConflict(ctx context.Context, info *StakerInfo) error {
 		}
 
 		s.activeChallenge = newChallengeManager
+		if s.blockValidator != nil {
+			s.blockValidator.PrioritizeValidation(newChallengeManager.DisputedMessages())
+		}
 	}
 
-	_, err := s.activeChallenge.Act(ctx)
+	tx, err := s.activeChallenge.Act(ctx)
+	if err == nil && tx != nil {
+		s.journal.action(s.Strategy(), &DecisionJournalEntry{
+			Action:    "challenge-move",
+			Challenge: *info.CurrentChallenge,
+		})
+	}
 	return err
 }
 
@@ -828,6 +897,9 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 	if wrongNodesExist && effectiveStrategy == WatchtowerStrategy {
 		log.Error("found incorrect assertion in watchtower mode")
 	}
+	if wrongNodesExist && s.Started() {
+		s.LaunchThread(s.alertDivergences)
+	}
 	if action == nil {
 		info.CanProgress = false
 		return nil
@@ -837,13 +909,23 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 	case createNodeAction:
 		if wrongNodesExist && s.config.DisableChallenge {
 			log.Error("refusing to challenge assertion as config disables challenges")
+			s.journal.action(effectiveStrategy, &DecisionJournalEntry{
+				Action: "refuse-challenge",
+				Reason: "challenges are disabled by config",
+			})
 			info.CanProgress = false
 			return nil
 		}
 		if !active {
 			if wrongNodesExist && effectiveStrategy >= DefensiveStrategy {
 				log.Error("bringing defensive validator online because of incorrect assertion")
+				s.journal.action(effectiveStrategy, &DecisionJournalEntry{
+					Action: "activate-defensive",
+					Node:   info.LatestStakedNode + 1,
+					Reason: "no correct successor to the staked node",
+				})
 				s.bringActiveUntilNode = info.LatestStakedNode + 1
+				stakerDefensiveActiveGauge.Update(1)
 			}
 			info.CanProgress = false
 			return nil
@@ -854,8 +936,19 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 		info.LatestStakedNode = 0
 		info.LatestStakedNodeHash = action.hash
 
+		asserted := action.assertion.AfterState.GlobalState
+		newNodeDecision := &DecisionJournalEntry{
+			Action:   "stake-on-new-node",
+			NodeHash: action.hash,
+			Asserted: &asserted,
+		}
+		if wrongNodesExist {
+			newNodeDecision.Reason = "no correct successor to the staked node"
+		}
+
 		// We'll return early if we already have a stake
 		if info.StakeExists {
+			s.journal.action(effectiveStrategy, newNodeDecision)
 			auth, err := s.builder.Auth(ctx)
 			if err != nil {
 				return err
@@ -872,6 +965,8 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 		if err != nil {
 			return fmt.Errorf("error getting current required stake: %w", err)
 		}
+		newNodeDecision.Action = "new-stake-on-new-node"
+		s.journal.action(effectiveStrategy, newNodeDecision)
 		auth, err := s.builder.AuthWithAmount(ctx, stakeAmount)
 		if err != nil {
 			return err
@@ -893,7 +988,14 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 		if !active {
 			if wrongNodesExist && effectiveStrategy >= DefensiveStrategy {
 				log.Error("bringing defensive validator online because of incorrect assertion")
+				s.journal.action(effectiveStrategy, &DecisionJournalEntry{
+					Action:   "activate-defensive",
+					Node:     action.number,
+					NodeHash: action.hash,
+					Reason:   "incorrect sibling of the correct assertion",
+				})
 				s.bringActiveUntilNode = action.number
+				stakerDefensiveActiveGauge.Update(1)
 				info.CanProgress = false
 			} else {
 				s.inactiveLastCheckedNode = &nodeAndHash{
@@ -904,8 +1006,14 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 			return nil
 		}
 		log.Info("staking on existing node", "node", action.number)
+		existingNodeDecision := &DecisionJournalEntry{
+			Action:   "stake-on-existing-node",
+			Node:     action.number,
+			NodeHash: action.hash,
+		}
 		// We'll return early if we already havea stake
 		if info.StakeExists {
+			s.journal.action(effectiveStrategy, existingNodeDecision)
 			auth, err := s.builder.Auth(ctx)
 			if err != nil {
 				return err
@@ -922,6 +1030,8 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 		if err != nil {
 			return fmt.Errorf("error getting current required stake: %w", err)
 		}
+		existingNodeDecision.Action = "new-stake-on-existing-node"
+		s.journal.action(effectiveStrategy, existingNodeDecision)
 		auth, err := s.builder.AuthWithAmount(ctx, stakeAmount)
 		if err != nil {
 			return err
@@ -947,17 +1057,9 @@ func (s *Staker) createConflict(ctx context.Context, info *StakerInfo) error {
 	}
 
 	callOpts := s.getCallOpts(ctx)
-	stakers, moreStakers, err := s.validatorUtils.GetStakers(callOpts, s.rollupAddress, 0, 1024)
+	stakers, err := s.getStakers(callOpts)
 	if err != nil {
-		return fmt.Errorf("error getting stakers list: %w", err)
-	}
-	for moreStakers {
-		var newStakers []common.Address
-		newStakers, moreStakers, err = s.validatorUtils.GetStakers(callOpts, s.rollupAddress, uint64(len(stakers)), 1024)
-		if err != nil {
-			return fmt.Errorf("error getting more stakers: %w", err)
-		}
-		stakers = append(stakers, newStakers...)
+		return err
 	}
 	latestNode, err := s.rollup.LatestConfirmed(callOpts)
 	if err != nil {
@@ -1003,6 +1105,12 @@ func (s *Staker) createConflict(ctx context.Context, info *StakerInfo) error {
 			return fmt.Errorf("error looking up node %v: %w", conflictInfo.Node2, err)
 		}
 		log.Warn("creating challenge", "node1", conflictInfo.Node1, "node2", conflictInfo.Node2, "otherStaker", staker)
+		s.journal.action(s.Strategy(), &DecisionJournalEntry{
+			Action:       "create-challenge",
+			Node:         conflictInfo.Node1,
+			Counterparty: &staker,
+			Reason:       fmt.Sprintf("conflicts with node %v", conflictInfo.Node2),
+		})
 		auth, err := s.builder.Auth(ctx)
 		if err != nil {
 			return err
@@ -1026,6 +1134,25 @@ func (s *Staker) createConflict(ctx context.Context, info *StakerInfo) error {
 	return nil
 }
 
+// AssertionProgress is the latest assertion staked on or confirmed, as last checked by the staker
+type AssertionProgress struct {
+	// Node is nil if there's no such assertion
+	Node *NodeInfo
+	// MessageCount is the number of messages the assertion covers, or zero if this node hasn't processed them all yet
+	MessageCount arbutil.MessageIndex
+}
+
+// LatestStakedAssertion returns the latest assertion the staker's wallet is staked on, or the latest confirmed one
+// if it has no stake. Returns nil if the staker hasn't checked yet.
+func (s *Staker) LatestStakedAssertion() *AssertionProgress {
+	return s.latestStaked.Load()
+}
+
+// LatestConfirmedAssertion returns the latest confirmed assertion, or nil if the staker hasn't checked yet
+func (s *Staker) LatestConfirmedAssertion() *AssertionProgress {
+	return s.latestConfirmed.Load()
+}
+
 func (s *Staker) Strategy() StakerStrategy {
 	return s.config.strategy
 }
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"sync"
 	"testing"
 
@@ -21,11 +22,14 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/arbstate"
 )
 
+var recordedPreimagesCacheHitCounter = metrics.NewRegisteredCounter("arb/validator/preimages/cache/hit", nil)
+
 type StatelessBlockValidator struct {
 	config *BlockValidatorConfig
 
@@ -40,10 +44,16 @@ type StatelessBlockValidator struct {
 	db           ethdb.Database
 	daService    arbstate.DataAvailabilityReader
 	blobReader   arbstate.BlobReader
+	celestia     arbstate.CelestiaReader
 
 	moduleMutex           sync.Mutex
 	currentWasmModuleRoot common.Hash
-	pendingWasmModuleRoot common.Hash
+	// upcoming module roots the rollup may be upgraded to, which are validated alongside the current one
+	pendingWasmModuleRoots []common.Hash
+
+	// the last entry recorded for its preimages, as a prover fetches them one at a time
+	recordedMutex sync.Mutex
+	lastRecorded  *validationEntry
 }
 
 type BlockValidatorRegistrer interface {
@@ -108,10 +118,37 @@ func GlobalStatePositionsAtCount(
 	return startPos, GlobalStatePosition{batch, posInBatch + 1}, nil
 }
 
+// BatchMetadataPruner is implemented by inbox trackers which may have pruned the metadata of old batches
+type BatchMetadataPruner interface {
+	GetFirstRetainedBatch() (uint64, error)
+}
+
+var ErrBatchMetadataPruned = errors.New("batch metadata pruned")
+
 func FindBatchContainingMessageIndex(
 	tracker InboxTrackerInterface, pos arbutil.MessageIndex, high uint64,
 ) (uint64, error) {
 	var low uint64
+	if pruner, ok := tracker.(BatchMetadataPruner); ok {
+		firstRetained, err := pruner.GetFirstRetainedBatch()
+		if err != nil {
+			return 0, err
+		}
+		if firstRetained > 0 {
+			// Only search the batches after the first retained one, as the search needs the previous batch's metadata
+			var count arbutil.MessageIndex
+			if firstRetained < high {
+				count, err = tracker.GetBatchMessageCount(firstRetained)
+				if err != nil {
+					return 0, err
+				}
+			}
+			if firstRetained >= high || count > pos {
+				return 0, fmt.Errorf("%w: message %v may be in batch %v or earlier", ErrBatchMetadataPruned, pos, firstRetained)
+			}
+			low = firstRetained + 1
+		}
+	}
 	// Iteration preconditions:
 	// - high >= low
 	// - msgCount(low - 1) <= pos implies low <= target
@@ -161,9 +198,24 @@ type validationEntry struct {
 	msg *arbostypes.MessageWithMetadata
 	// Has batch when created - others could be added on record
 	BatchInfo []validator.BatchInfo
-	// Valid since Ready
+	// Valid since Ready, unless spilled to the database until it's validated
 	Preimages  map[arbutil.PreimageType]map[common.Hash][]byte
 	DelayedMsg []byte
+	spilled    bool
+}
+
+// size estimates the memory held by the entry's recorded data
+func (e *validationEntry) size() int {
+	size := len(e.DelayedMsg)
+	for _, batch := range e.BatchInfo {
+		size += len(batch.Data)
+	}
+	for _, preimages := range e.Preimages {
+		for _, preimage := range preimages {
+			size += len(preimage) + common.HashLength
+		}
+	}
+	return size
 }
 
 func (e *validationEntry) ToInput() (*validator.ValidationInput, error) {
@@ -248,13 +300,20 @@ func NewStatelessBlockValidator(
 	return validator, nil
 }
 
+// SetCelestiaReader lets validation read batch data posted to Celestia
+func (v *StatelessBlockValidator) SetCelestiaReader(celestia arbstate.CelestiaReader) {
+	v.celestia = celestia
+}
+
 func (v *StatelessBlockValidator) GetModuleRootsToValidate() []common.Hash {
 	v.moduleMutex.Lock()
 	defer v.moduleMutex.Unlock()
 
 	validatingModuleRoots := []common.Hash{v.currentWasmModuleRoot}
-	if (v.currentWasmModuleRoot != v.pendingWasmModuleRoot && v.pendingWasmModuleRoot != common.Hash{}) {
-		validatingModuleRoots = append(validatingModuleRoots, v.pendingWasmModuleRoot)
+	for _, pending := range v.pendingWasmModuleRoots {
+		if pending != v.currentWasmModuleRoot {
+			validatingModuleRoots = append(validatingModuleRoots, pending)
+		}
 	}
 	return validatingModuleRoots
 }
@@ -316,6 +375,17 @@ func (v *StatelessBlockValidator) ValidationEntryRecord(ctx context.Context, e *
 				e.Preimages[arbutil.EthVersionedHashPreimageType][versionedHashes[i]] = b[:]
 			}
 		}
+		if arbstate.IsCelestiaMessageHeaderByte(batch.Data[40]) {
+			if v.celestia == nil {
+				return arbstate.ErrNoCelestiaReader
+			}
+			_, err := arbstate.NewDAProviderCelestia(v.celestia).RecoverPayloadFromBatch(
+				ctx, batch.Number, batch.BlockHash, batch.Data, e.Preimages, arbstate.KeysetValidate,
+			)
+			if err != nil {
+				return err
+			}
+		}
 		if arbstate.IsDASMessageHeaderByte(batch.Data[40]) {
 			if v.daService == nil {
 				log.Warn("No DAS configured, but sequencer message found with DAS header")
@@ -406,6 +476,48 @@ func (v *StatelessBlockValidator) CreateReadyValidationEntry(ctx context.Context
 	return entry, nil
 }
 
+// RecordedPreimages records the message at pos, and returns the preimages the recording resolved,
+// which are all a prover needs to execute the message besides its batches and delayed message
+func (v *StatelessBlockValidator) RecordedPreimages(ctx context.Context, pos arbutil.MessageIndex) (map[arbutil.PreimageType]map[common.Hash][]byte, error) {
+	v.recordedMutex.Lock()
+	defer v.recordedMutex.Unlock()
+	result, err := v.streamer.ResultAtCount(pos + 1)
+	if err != nil {
+		return nil, err
+	}
+	// the block hash changes if the message was reorged since it was recorded
+	if last := v.lastRecorded; last != nil && last.Pos == pos && last.End.BlockHash == result.BlockHash {
+		recordedPreimagesCacheHitCounter.Inc(1)
+		return last.Preimages, nil
+	}
+	entry, err := v.CreateReadyValidationEntry(ctx, pos)
+	if err != nil {
+		return nil, err
+	}
+	v.lastRecorded = entry
+	return entry.Preimages, nil
+}
+
+// RecordedPreimage records the message at pos, and returns the preimage of the hash the recording resolved, along
+// with its type. If preimageType is nil, the preimage is looked up under every type.
+func (v *StatelessBlockValidator) RecordedPreimage(
+	ctx context.Context, pos arbutil.MessageIndex, hash common.Hash, preimageType *arbutil.PreimageType,
+) (arbutil.PreimageType, []byte, error) {
+	preimages, err := v.RecordedPreimages(ctx, pos)
+	if err != nil {
+		return 0, nil, err
+	}
+	for ty, typePreimages := range preimages {
+		if preimageType != nil && ty != *preimageType {
+			continue
+		}
+		if preimage, ok := typePreimages[hash]; ok {
+			return ty, preimage, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("preimage of %v wasn't resolved recording message %v", hash, pos)
+}
+
 func (v *StatelessBlockValidator) ValidateResult(
 	ctx context.Context, pos arbutil.MessageIndex, useExec bool, moduleRoot common.Hash,
 ) (bool, *validator.GoGlobalState, error) {
@@ -413,6 +525,28 @@ func (v *StatelessBlockValidator) ValidateResult(
 	if err != nil {
 		return false, nil, err
 	}
+	return v.validateEntry(ctx, entry, useExec, moduleRoot)
+}
+
+// ValidateMessage validates the message at pos like ValidateResult, but also returns the end state this node
+// expects, which is nil if the message couldn't be recorded. The validated end state is nil on error.
+func (v *StatelessBlockValidator) ValidateMessage(
+	ctx context.Context, pos arbutil.MessageIndex, useExec bool, moduleRoot common.Hash,
+) (bool, *validator.GoGlobalState, *validator.GoGlobalState, error) {
+	entry, err := v.CreateReadyValidationEntry(ctx, pos)
+	if err != nil {
+		return false, nil, nil, err
+	}
+	valid, gs, err := v.validateEntry(ctx, entry, useExec, moduleRoot)
+	if err != nil {
+		gs = nil
+	}
+	return valid, &entry.End, gs, err
+}
+
+func (v *StatelessBlockValidator) validateEntry(
+	ctx context.Context, entry *validationEntry, useExec bool, moduleRoot common.Hash,
+) (bool, *validator.GoGlobalState, error) {
 	input, err := entry.ToInput()
 	if err != nil {
 		return false, nil, err
@@ -449,6 +583,37 @@ func (v *StatelessBlockValidator) OverrideRecorder(t *testing.T, recorder execut
 	v.recorder = recorder
 }
 
+// parsePendingModuleRoots parses the comma-separated pending-upgrade-module-root option, resolving "latest" with
+// the given function, and dropping duplicates while keeping the roots in the order given
+func parsePendingModuleRoots(config string, latest func() (common.Hash, error)) ([]common.Hash, error) {
+	var roots []common.Hash
+	for _, rootStr := range strings.Split(config, ",") {
+		rootStr = strings.TrimSpace(rootStr)
+		var root common.Hash
+		if rootStr == "latest" {
+			var err error
+			root, err = latest()
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			valid, _ := regexp.MatchString("(0x)?[0-9a-fA-F]{64}", rootStr)
+			root = common.HexToHash(rootStr)
+			if (!valid || root == common.Hash{}) {
+				return nil, fmt.Errorf("pending-upgrade-module-root config value %v illegal", rootStr)
+			}
+		}
+		duplicate := false
+		for _, pending := range roots {
+			duplicate = duplicate || pending == root
+		}
+		if !duplicate {
+			roots = append(roots, root)
+		}
+	}
+	return roots, nil
+}
+
 func (v *StatelessBlockValidator) Start(ctx_in context.Context) error {
 	err := v.execSpawner.Start(ctx_in)
 	if err != nil {
@@ -460,18 +625,12 @@ func (v *StatelessBlockValidator) Start(ctx_in context.Context) error {
 		}
 	}
 	if v.config.PendingUpgradeModuleRoot != "" {
-		if v.config.PendingUpgradeModuleRoot == "latest" {
-			latest, err := v.execSpawner.LatestWasmModuleRoot().Await(ctx_in)
-			if err != nil {
-				return err
-			}
-			v.pendingWasmModuleRoot = latest
-		} else {
-			valid, _ := regexp.MatchString("(0x)?[0-9a-fA-F]{64}", v.config.PendingUpgradeModuleRoot)
-			v.pendingWasmModuleRoot = common.HexToHash(v.config.PendingUpgradeModuleRoot)
-			if (!valid || v.pendingWasmModuleRoot == common.Hash{}) {
-				return errors.New("pending-upgrade-module-root config value illegal")
-			}
+		latest := func() (common.Hash, error) {
+			return v.execSpawner.LatestWasmModuleRoot().Await(ctx_in)
+		}
+		v.pendingWasmModuleRoots, err = parsePendingModuleRoots(v.config.PendingUpgradeModuleRoot, latest)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
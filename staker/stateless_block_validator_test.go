@@ -0,0 +1,98 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func requireModuleRoots(t *testing.T, have []common.Hash, expected ...common.Hash) {
+	t.Helper()
+	if len(have) != len(expected) {
+		Fail(t, "have module roots", have, "expected", expected)
+	}
+	for i := range expected {
+		if have[i] != expected[i] {
+			Fail(t, "have module roots", have, "expected", expected)
+		}
+	}
+}
+
+func TestParsePendingModuleRoots(t *testing.T) {
+	rootA := common.HexToHash("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	rootB := common.HexToHash("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	latestRoot := common.HexToHash("0xcccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc")
+	latestCalls := 0
+	latest := func() (common.Hash, error) {
+		latestCalls++
+		return latestRoot, nil
+	}
+
+	roots, err := parsePendingModuleRoots(rootA.Hex(), latest)
+	Require(t, err)
+	requireModuleRoots(t, roots, rootA)
+
+	roots, err = parsePendingModuleRoots(" "+rootB.Hex()+", latest ,"+rootA.Hex(), latest)
+	Require(t, err)
+	requireModuleRoots(t, roots, rootB, latestRoot, rootA)
+	if latestCalls != 1 {
+		Fail(t, "resolved latest", latestCalls, "times")
+	}
+
+	// duplicates are dropped, including latest resolving to a root listed explicitly
+	roots, err = parsePendingModuleRoots(rootA.Hex()+","+latestRoot.Hex()+","+rootA.Hex()+",latest", latest)
+	Require(t, err)
+	requireModuleRoots(t, roots, rootA, latestRoot)
+
+	for _, illegal := range []string{"", "0x1234", rootA.Hex() + ",", rootA.Hex() + ",current", common.Hash{}.Hex()} {
+		if _, err := parsePendingModuleRoots(illegal, latest); err == nil {
+			Fail(t, "accepted illegal pending module roots", illegal)
+		}
+	}
+
+	errNoMachine := errors.New("no latest machine")
+	_, err = parsePendingModuleRoots(rootA.Hex()+",latest", func() (common.Hash, error) { return common.Hash{}, errNoMachine })
+	if !errors.Is(err, errNoMachine) {
+		Fail(t, "expected the error resolving latest, got", err)
+	}
+}
+
+func TestSetCurrentWasmModuleRoot(t *testing.T) {
+	current := common.HexToHash("0x01")
+	first := common.HexToHash("0x02")
+	second := common.HexToHash("0x03")
+	third := common.HexToHash("0x04")
+	config := TestBlockValidatorConfig
+	config.CurrentModuleRoot = current.Hex()
+	v := &BlockValidator{
+		StatelessBlockValidator: &StatelessBlockValidator{
+			currentWasmModuleRoot:  current,
+			pendingWasmModuleRoots: []common.Hash{first, second, third},
+		},
+		config: func() *BlockValidatorConfig { return &config },
+	}
+	ctx := context.Background()
+
+	Require(t, v.SetCurrentWasmModuleRoot(ctx, current))
+	requireModuleRoots(t, v.GetModuleRootsToValidate(), current, first, second, third)
+
+	// promoting a pending root keeps the others pending, but not the previous root
+	Require(t, v.SetCurrentWasmModuleRoot(ctx, second))
+	requireModuleRoots(t, v.GetModuleRootsToValidate(), second, first, third)
+
+	Require(t, v.SetCurrentWasmModuleRoot(ctx, third))
+	requireModuleRoots(t, v.GetModuleRootsToValidate(), third, first)
+
+	// an unknown root is ignored unless following the rollup's current root
+	Require(t, v.SetCurrentWasmModuleRoot(ctx, common.HexToHash("0x05")))
+	requireModuleRoots(t, v.GetModuleRootsToValidate(), third, first)
+
+	if v.SetCurrentWasmModuleRoot(ctx, common.Hash{}) == nil {
+		Fail(t, "set the zero hash as the module root")
+	}
+}
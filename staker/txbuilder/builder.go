@@ -95,13 +95,14 @@ func (b *Builder) AuthWithAmount(ctx context.Context, amount *big.Int) (*bind.Tr
 		return nil, err
 	}
 	return &bind.TransactOpts{
-		From:     b.builderAuth.From,
-		Nonce:    new(big.Int).SetUint64(nonce),
-		Signer:   b.builderAuth.Signer,
-		Value:    amount,
-		GasPrice: b.builderAuth.GasPrice,
-		GasLimit: b.builderAuth.GasLimit,
-		Context:  ctx,
+		From:      b.builderAuth.From,
+		Nonce:     new(big.Int).SetUint64(nonce),
+		Signer:    b.builderAuth.Signer,
+		Value:     amount,
+		GasFeeCap: b.builderAuth.GasFeeCap,
+		GasTipCap: b.builderAuth.GasTipCap,
+		GasLimit:  b.builderAuth.GasLimit,
+		Context:   ctx,
 	}, nil
 }
 
@@ -56,6 +56,9 @@ type Contract struct {
 	challengeManagerAddress common.Address
 	dataPoster              *dataposter.DataPoster
 	getExtraGas             func() uint64
+	// executorOnly is set if the key we send with is one of the wallet's executors rather than its owner,
+	// so that it may only call the destinations the owner allowed
+	executorOnly atomic.Bool
 }
 
 func NewContract(dp *dataposter.DataPoster, address *common.Address, walletFactoryAddr, rollupAddress common.Address, l1Reader *headerreader.HeaderReader, auth *bind.TransactOpts, rollupFromBlock int64, onWalletCreated func(common.Address),
@@ -105,6 +108,30 @@ func (v *Contract) validateWallet(ctx context.Context) error {
 	if v.auth.From != owner && !isExecutor {
 		return errors.New("specified unauthorized smart contract wallet")
 	}
+	v.executorOnly.Store(v.auth.From != owner)
+	return nil
+}
+
+// checkDestinations refuses to have the wallet call anything but the rollup and its challenge manager,
+// and if we're only an executor of the wallet, anything its owner hasn't allowed executors to call.
+func (v *Contract) checkDestinations(ctx context.Context, txes []*types.Transaction) error {
+	callOpts := &bind.CallOpts{Context: ctx}
+	for _, tx := range txes {
+		dest := *tx.To()
+		if dest != v.rollupAddress && dest != v.challengeManagerAddress {
+			return fmt.Errorf("validator smart contract wallet refusing to call %v, which is neither the rollup nor its challenge manager", dest)
+		}
+		if !v.executorOnly.Load() {
+			continue
+		}
+		allowed, err := v.con.AllowedExecutorDestinations(callOpts, dest)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("validator smart contract wallet %v doesn't allow its executor %v to call %v; the wallet's owner must allow it with setAllowedExecutorDestinations", v.AddressOrZero(), v.auth.From, dest)
+		}
+	}
 	return nil
 }
 
@@ -194,7 +221,7 @@ func (v *Contract) populateWallet(ctx context.Context, createIfMissing bool) err
 		if err != nil {
 			return err
 		}
-		addr, err := GetValidatorWalletContract(ctx, v.walletFactoryAddr, v.rollupFromBlock, auth, v.l1Reader, createIfMissing)
+		addr, err := GetValidatorWalletContract(ctx, v.walletFactoryAddr, v.rollupAddress, v.rollupFromBlock, auth, v.l1Reader, createIfMissing)
 		if err != nil {
 			return err
 		}
@@ -244,6 +271,9 @@ func (v *Contract) ExecuteTransactions(ctx context.Context, builder *txbuilder.B
 	if err != nil {
 		return nil, err
 	}
+	if err := v.checkDestinations(ctx, txes); err != nil {
+		return nil, err
+	}
 
 	if len(txes) == 1 {
 		arbTx, err := v.executeTransaction(ctx, txes[0], gasRefunder)
@@ -399,9 +429,13 @@ func (b *Contract) DataPoster() *dataposter.DataPoster {
 	return b.dataPoster
 }
 
+// GetValidatorWalletContract finds the validator smart contract wallet created for the transacting key, creating
+// it if it's missing and createIfMissing is set. A created wallet only lets its executors call the rollup and the
+// rollup's challenge manager; its owner, the transacting key, can call anything.
 func GetValidatorWalletContract(
 	ctx context.Context,
 	validatorWalletFactoryAddr common.Address,
+	rollupAddress common.Address,
 	fromBlock int64,
 	transactAuth *bind.TransactOpts,
 	l1Reader *headerreader.HeaderReader,
@@ -442,7 +476,15 @@ func GetValidatorWalletContract(
 		return nil, nil
 	}
 
-	var initialExecutorAllowedDests []common.Address
+	rollup, err := rollupgen.NewRollupUserLogic(rollupAddress, client)
+	if err != nil {
+		return nil, err
+	}
+	challengeManagerAddress, err := rollup.ChallengeManager(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("error getting rollup challenge manager: %w", err)
+	}
+	initialExecutorAllowedDests := []common.Address{rollupAddress, challengeManagerAddress}
 	tx, err := walletCreator.CreateWallet(transactAuth, initialExecutorAllowedDests)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,173 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/validator"
+)
+
+var (
+	watchtowerDivergencesGauge     = metrics.NewRegisteredGauge("arb/staker/watchtower/divergences", nil)
+	watchtowerLastDivergenceGauge  = metrics.NewRegisteredGauge("arb/staker/watchtower/last_divergence_node", nil)
+	watchtowerWebhookErrorsCounter = metrics.NewRegisteredCounter("arb/staker/watchtower/webhook/errors", nil)
+)
+
+const (
+	watchtowerWebhookPayloadKind    = "assertion-divergence"
+	maxRecordedAssertionDivergences = 1000
+)
+
+type WatchtowerConfig struct {
+	Validate       bool          `koanf:"validate"`
+	WebhookURL     string        `koanf:"webhook-url"`
+	WebhookTimeout time.Duration `koanf:"webhook-timeout"`
+}
+
+var DefaultWatchtowerConfig = WatchtowerConfig{
+	Validate:       false,
+	WebhookURL:     "",
+	WebhookTimeout: time.Second * 10,
+}
+
+func WatchtowerConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".validate", DefaultWatchtowerConfig.Validate, "with the watchtower strategy, run the block validator and only accept assertions matching fully validated blocks")
+	f.String(prefix+".webhook-url", DefaultWatchtowerConfig.WebhookURL, "URL to POST a JSON alert to when an incorrect assertion is found on the parent chain (optional)")
+	f.Duration(prefix+".webhook-timeout", DefaultWatchtowerConfig.WebhookTimeout, "timeout for requests to the incorrect assertion webhook")
+}
+
+func (c *WatchtowerConfig) Validate() error {
+	if c.WebhookURL != "" {
+		if _, err := url.ParseRequestURI(c.WebhookURL); err != nil {
+			return fmt.Errorf("invalid watchtower webhook URL: %w", err)
+		}
+	}
+	return nil
+}
+
+// AssertionDivergence is an assertion on the parent chain that disagrees with this node's view of the chain.
+// It's also the JSON body POSTed to the webhook.
+type AssertionDivergence struct {
+	Kind             string                  `json:"kind"`
+	Node             uint64                  `json:"node"`
+	NodeHash         common.Hash             `json:"nodeHash"`
+	ParentChainBlock uint64                  `json:"parentChainBlock"`
+	Asserted         validator.GoGlobalState `json:"asserted"`
+	Reason           string                  `json:"reason"`
+	DetectedAt       time.Time               `json:"detectedAt"`
+}
+
+// divergenceTracker records each incorrect assertion once, however many times the staker comes across it
+type divergenceTracker struct {
+	mutex       sync.Mutex
+	seen        map[uint64]bool
+	divergences []*AssertionDivergence
+	unalerted   []*AssertionDivergence
+}
+
+func newDivergenceTracker() *divergenceTracker {
+	return &divergenceTracker{seen: make(map[uint64]bool)}
+}
+
+func (t *divergenceTracker) report(node *NodeInfo, reason string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.seen[node.NodeNum] {
+		return
+	}
+	t.seen[node.NodeNum] = true
+	divergence := &AssertionDivergence{
+		Kind:             watchtowerWebhookPayloadKind,
+		Node:             node.NodeNum,
+		NodeHash:         node.NodeHash,
+		ParentChainBlock: node.ParentChainBlockProposed,
+		Asserted:         node.AfterState().GlobalState,
+		Reason:           reason,
+		DetectedAt:       time.Now(),
+	}
+	log.Error(
+		"INCORRECT ASSERTION on the parent chain disagrees with this node",
+		"node", divergence.Node,
+		"nodeHash", divergence.NodeHash,
+		"parentChainBlock", divergence.ParentChainBlock,
+		"asserted", divergence.Asserted,
+		"reason", reason,
+	)
+	t.divergences = append(t.divergences, divergence)
+	if len(t.divergences) > maxRecordedAssertionDivergences {
+		t.divergences = t.divergences[1:]
+	}
+	t.unalerted = append(t.unalerted, divergence)
+	watchtowerDivergencesGauge.Update(int64(len(t.seen)))
+	watchtowerLastDivergenceGauge.Update(int64(node.NodeNum))
+}
+
+// takeUnalerted returns the divergences found since it was last called
+func (t *divergenceTracker) takeUnalerted() []*AssertionDivergence {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	unalerted := t.unalerted
+	t.unalerted = nil
+	return unalerted
+}
+
+func (t *divergenceTracker) list() []*AssertionDivergence {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]*AssertionDivergence{}, t.divergences...)
+}
+
+// AssertionDivergences returns the incorrect assertions found since the node started, oldest first
+func (v *L1Validator) AssertionDivergences() []*AssertionDivergence {
+	return v.divergences.list()
+}
+
+func (s *Staker) alertDivergences(ctx context.Context) {
+	config := &s.config.Watchtower
+	for _, divergence := range s.divergences.takeUnalerted() {
+		if config.WebhookURL == "" {
+			continue
+		}
+		if err := postDivergenceAlert(ctx, config, divergence); err != nil {
+			log.Warn("error sending incorrect assertion alert to webhook", "node", divergence.Node, "err", err)
+			watchtowerWebhookErrorsCounter.Inc(1)
+		}
+	}
+}
+
+func postDivergenceAlert(ctx context.Context, config *WatchtowerConfig, divergence *AssertionDivergence) error {
+	body, err := json.Marshal(divergence)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, config.WebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %v", resp.StatusCode)
+	}
+	return nil
+}
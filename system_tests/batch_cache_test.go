@@ -0,0 +1,60 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbnode"
+)
+
+func TestInboxReaderBatchCache(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	nodeConfig := arbnode.ConfigDefaultL1NonSequencerTest()
+	nodeConfig.InboxReader.BatchCacheSize = 0
+	testClientB, cleanupB := builder.Build2ndNode(t, &SecondNodeParams{nodeConfig: nodeConfig})
+	defer cleanupB()
+
+	builder.L2Info.GenerateAccount("User2")
+	tx := builder.L2Info.PrepareTx("Owner", "User2", builder.L2Info.TransferGas, big.NewInt(params.Ether), nil)
+	Require(t, builder.L2.Client.SendTransaction(ctx, tx))
+	_, err := builder.L2.EnsureTxSucceeded(tx)
+	Require(t, err)
+
+	for i := 0; ; i++ {
+		if i >= 200 {
+			Fatal(t, "batch wasn't read by both nodes")
+		}
+		countA, err := builder.L2.ConsensusNode.InboxTracker.GetBatchCount()
+		Require(t, err)
+		countB, err := testClientB.ConsensusNode.InboxTracker.GetBatchCount()
+		Require(t, err)
+		if countA >= 2 && countB >= 2 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// The first node serves the batch from its cache, the second fetches it from the parent chain
+	cached, cachedBlockHash, err := builder.L2.ConsensusNode.InboxReader.GetSequencerMessageBytes(ctx, 1)
+	Require(t, err)
+	fetched, fetchedBlockHash, err := testClientB.ConsensusNode.InboxReader.GetSequencerMessageBytes(ctx, 1)
+	Require(t, err)
+	if !bytes.Equal(cached, fetched) || cachedBlockHash != fetchedBlockHash {
+		Fatal(t, "cached batch differs from the batch fetched from the parent chain")
+	}
+}
@@ -231,6 +231,101 @@ func testBatchPosterParallel(t *testing.T, useRedis bool) {
 	}
 }
 
+func TestRedundantBatchPostersTakeOver(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	redisUrl := redisutil.CreateTestRedis(ctx, t)
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	builder.nodeConfig.BatchPoster.Enable = false
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	builder.L1Info.GenerateAccount("BackupBatchPoster")
+	backupAddr := builder.L1Info.GetAddress("BackupBatchPoster")
+	addNewBatchPoster(ctx, t, builder, backupAddr)
+	builder.L1.SendWaitTestTransactions(t, []*types.Transaction{
+		builder.L1Info.PrepareTxTo("Faucet", &backupAddr, 30000, big.NewInt(1e18), nil)})
+
+	parentChainID, err := builder.L1.Client.ChainID(ctx)
+	Require(t, err)
+	newBatchPoster := func(account string) *arbnode.BatchPoster {
+		t.Helper()
+		batchPosterConfig := builder.nodeConfig.BatchPoster
+		batchPosterConfig.Enable = true
+		batchPosterConfig.RedisUrl = redisUrl
+		batchPosterConfig.ExclusivePosting = true
+		txOpts := builder.L1Info.GetDefaultTransactOpts(account, ctx)
+		batchPoster, err := arbnode.NewBatchPoster(ctx,
+			&arbnode.BatchPosterOpts{
+				DataPosterDB:  nil,
+				L1Reader:      builder.L2.ConsensusNode.L1Reader,
+				Inbox:         builder.L2.ConsensusNode.InboxTracker,
+				Streamer:      builder.L2.ConsensusNode.TxStreamer,
+				VersionGetter: builder.L2.ExecNode,
+				SyncMonitor:   builder.L2.ConsensusNode.SyncMonitor,
+				Config:        func() *arbnode.BatchPosterConfig { return &batchPosterConfig },
+				DeployInfo:    builder.L2.ConsensusNode.DeployInfo,
+				TransactOpts:  &txOpts,
+				DAWriter:      nil,
+				ParentChainID: parentChainID,
+			},
+		)
+		Require(t, err)
+		return batchPoster
+	}
+
+	builder.L2Info.GenerateAccount("User2")
+	waitForPosted := func() {
+		t.Helper()
+		tx := builder.L2Info.PrepareTx("Owner", "User2", builder.L2Info.TransferGas, common.Big1, nil)
+		err := builder.L2.Client.SendTransaction(ctx, tx)
+		Require(t, err)
+		_, err = builder.L2.EnsureTxSucceeded(tx)
+		Require(t, err)
+		msgCount, err := builder.L2.ConsensusNode.TxStreamer.GetMessageCount()
+		Require(t, err)
+		tracker := builder.L2.ConsensusNode.InboxTracker
+		for i := 0; ; i++ {
+			batches, err := tracker.GetBatchCount()
+			Require(t, err)
+			posted, err := tracker.GetBatchMessageCount(batches - 1)
+			Require(t, err)
+			if posted >= msgCount {
+				return
+			}
+			if i >= 200 {
+				Fatal(t, "timed out waiting for messages to be posted, only", posted, "of", msgCount, "posted")
+			}
+			builder.L1.TransferBalance(t, "Faucet", "Faucet", common.Big1, builder.L1Info) // generate l1 traffic
+			time.Sleep(time.Millisecond * 50)
+		}
+	}
+
+	primary := newBatchPoster("Sequencer")
+	primary.Start(ctx)
+	waitForPosted()
+
+	backup := newBatchPoster("BackupBatchPoster")
+	backup.Start(ctx)
+	defer backup.StopAndWait()
+	waitForPosted()
+	backupNonce, err := builder.L1.Client.NonceAt(ctx, backupAddr, nil)
+	Require(t, err)
+	if backupNonce != 0 {
+		Fatal(t, "backup batch poster posted", backupNonce, "transactions while the primary held the lock")
+	}
+
+	primary.StopAndWait()
+	waitForPosted()
+	backupNonce, err = builder.L1.Client.NonceAt(ctx, backupAddr, nil)
+	Require(t, err)
+	if backupNonce == 0 {
+		Fatal(t, "backup batch poster didn't take over posting")
+	}
+}
+
 func TestBatchPosterLargeTx(t *testing.T) {
 	t.Parallel()
 	ctx, cancel := context.WithCancel(context.Background())
@@ -261,6 +356,87 @@ func TestBatchPosterLargeTx(t *testing.T) {
 	}
 }
 
+func TestBatchPosterMaxMessagesPerBatch(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	builder.nodeConfig.BatchPoster.MaxMessagesPerBatch = 2
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	builder.L2Info.GenerateAccount("User")
+	for i := 0; i < 6; i++ {
+		tx := builder.L2Info.PrepareTx("Owner", "User", builder.L2Info.TransferGas, big.NewInt(1e12), nil)
+		err := builder.L2.Client.SendTransaction(ctx, tx)
+		Require(t, err)
+		_, err = builder.L2.EnsureTxSucceeded(tx)
+		Require(t, err)
+	}
+
+	tracker := builder.L2.ConsensusNode.InboxTracker
+	msgCount, err := builder.L2.ConsensusNode.TxStreamer.GetMessageCount()
+	Require(t, err)
+	for i := 0; ; i++ {
+		batches, err := tracker.GetBatchCount()
+		Require(t, err)
+		posted, err := tracker.GetBatchMessageCount(batches - 1)
+		Require(t, err)
+		if posted >= msgCount {
+			break
+		}
+		if i >= 200 {
+			Fatal(t, "timed out waiting for messages to be posted, only", posted, "of", msgCount, "posted")
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+
+	batches, err := tracker.GetBatchCount()
+	Require(t, err)
+	for seqNum := uint64(1); seqNum < batches; seqNum++ {
+		prev, err := tracker.GetBatchMessageCount(seqNum - 1)
+		Require(t, err)
+		count, err := tracker.GetBatchMessageCount(seqNum)
+		Require(t, err)
+		if count-prev > 2 {
+			Fatal(t, "batch", seqNum, "has", count-prev, "messages but the limit is 2")
+		}
+	}
+}
+
+func TestBatchPosterWaitsForParentChainFees(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	builder.nodeConfig.BatchPoster.MaxL1BaseFeeGwei = 1e-9 // 1 wei, below any base fee the parent chain will have
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	tracker := builder.L2.ConsensusNode.InboxTracker
+	batchesBefore, err := tracker.GetBatchCount()
+	Require(t, err)
+
+	builder.L2Info.GenerateAccount("User")
+	tx := builder.L2Info.PrepareTx("Owner", "User", builder.L2Info.TransferGas, big.NewInt(1e12), nil)
+	err = builder.L2.Client.SendTransaction(ctx, tx)
+	Require(t, err)
+	_, err = builder.L2.EnsureTxSucceeded(tx)
+	Require(t, err)
+
+	for i := 0; i < 10; i++ {
+		builder.L1.TransferBalance(t, "Faucet", "Faucet", common.Big1, builder.L1Info) // generate l1 traffic
+		time.Sleep(time.Millisecond * 100)
+	}
+	batchesAfter, err := tracker.GetBatchCount()
+	Require(t, err)
+	if batchesAfter != batchesBefore {
+		Fatal(t, "batch poster posted", batchesAfter-batchesBefore, "batches while the parent chain base fee was above the maximum")
+	}
+}
+
 func TestBatchPosterKeepsUp(t *testing.T) {
 	t.Skip("This test is for manual inspection and would be unreliable in CI even if automated")
 	ctx, cancel := context.WithCancel(context.Background())
@@ -303,3 +479,93 @@ func TestBatchPosterKeepsUp(t *testing.T) {
 		fmt.Printf("backlog: %v message\n", haveMessages-postedMessages)
 	}
 }
+
+func TestBatchPosterRecoversAfterRestart(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	builder.nodeConfig.BatchPoster.Enable = false
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	parentChainID, err := builder.L1.Client.ChainID(ctx)
+	Require(t, err)
+	newBatchPoster := func() *arbnode.BatchPoster {
+		t.Helper()
+		batchPosterConfig := builder.nodeConfig.BatchPoster
+		batchPosterConfig.Enable = true
+		txOpts := builder.L1Info.GetDefaultTransactOpts("Sequencer", ctx)
+		batchPoster, err := arbnode.NewBatchPoster(ctx,
+			&arbnode.BatchPosterOpts{
+				DataPosterDB:  nil,
+				L1Reader:      builder.L2.ConsensusNode.L1Reader,
+				Inbox:         builder.L2.ConsensusNode.InboxTracker,
+				Streamer:      builder.L2.ConsensusNode.TxStreamer,
+				VersionGetter: builder.L2.ExecNode,
+				SyncMonitor:   builder.L2.ConsensusNode.SyncMonitor,
+				Config:        func() *arbnode.BatchPosterConfig { return &batchPosterConfig },
+				DeployInfo:    builder.L2.ConsensusNode.DeployInfo,
+				TransactOpts:  &txOpts,
+				DAWriter:      nil,
+				ParentChainID: parentChainID,
+			},
+		)
+		Require(t, err)
+		return batchPoster
+	}
+
+	builder.L2Info.GenerateAccount("User")
+	sendTxs := func(count int) {
+		t.Helper()
+		for i := 0; i < count; i++ {
+			tx := builder.L2Info.PrepareTx("Owner", "User", builder.L2Info.TransferGas, big.NewInt(1e12), nil)
+			err := builder.L2.Client.SendTransaction(ctx, tx)
+			Require(t, err)
+			_, err = builder.L2.EnsureTxSucceeded(tx)
+			Require(t, err)
+		}
+	}
+	tracker := builder.L2.ConsensusNode.InboxTracker
+	waitForPosted := func() {
+		t.Helper()
+		msgCount, err := builder.L2.ConsensusNode.TxStreamer.GetMessageCount()
+		Require(t, err)
+		for i := 0; ; i++ {
+			batches, err := tracker.GetBatchCount()
+			Require(t, err)
+			posted, err := tracker.GetBatchMessageCount(batches - 1)
+			Require(t, err)
+			if posted >= msgCount {
+				return
+			}
+			if i >= 200 {
+				Fatal(t, "timed out waiting for messages to be posted, only", posted, "of", msgCount, "posted")
+			}
+			builder.L1.TransferBalance(t, "Faucet", "Faucet", common.Big1, builder.L1Info) // generate l1 traffic
+			time.Sleep(time.Millisecond * 50)
+		}
+	}
+
+	batchPoster := newBatchPoster()
+	batchPoster.Start(ctx)
+	sendTxs(2)
+	waitForPosted()
+	batchPoster.StopAndWait()
+
+	// messages created while the batch poster is down leave a gap to the sequencer inbox,
+	// which the restarted batch poster has to fill without the previous one's queue
+	sendTxs(3)
+	batchesBefore, err := tracker.GetBatchCount()
+	Require(t, err)
+	batchPoster = newBatchPoster()
+	batchPoster.Start(ctx)
+	defer batchPoster.StopAndWait()
+	waitForPosted()
+	batchesAfter, err := tracker.GetBatchCount()
+	Require(t, err)
+	if batchesAfter <= batchesBefore {
+		Fatal(t, "restarted batch poster didn't post the missing batches")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/execution/gethexec"
+)
+
+func TestBuildBlock(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	builder.execConfig.Simulation.Enable = true
+	builder.execConfig.Simulation.MaxBuildBlockTxs = 2
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	l2rpc := builder.L2.Stack.Attach()
+	builder.L2Info.GenerateAccount("User")
+	user := builder.L2Info.GetAddress("User")
+	value := big.NewInt(params.Ether)
+	first := builder.L2Info.PrepareTx("Owner", "User", builder.L2Info.TransferGas, value, nil)
+	second := builder.L2Info.PrepareTx("Owner", "User", builder.L2Info.TransferGas, value, nil)
+	encode := func(txes ...*types.Transaction) []hexutil.Bytes {
+		t.Helper()
+		var encoded []hexutil.Bytes
+		for _, tx := range txes {
+			data, err := tx.MarshalBinary()
+			Require(t, err)
+			encoded = append(encoded, data)
+		}
+		return encoded
+	}
+	buildBlock := func(args gethexec.BuildBlockArgs) *gethexec.BuildBlockResult {
+		t.Helper()
+		var result gethexec.BuildBlockResult
+		err := l2rpc.CallContext(ctx, &result, "arb_buildBlock", args)
+		Require(t, err)
+		return &result
+	}
+
+	head, err := builder.L2.Client.HeaderByNumber(ctx, nil)
+	Require(t, err)
+	timestamp := hexutil.Uint64(head.Time + 100)
+	args := gethexec.BuildBlockArgs{
+		Transactions: encode(first, second),
+		Timestamp:    &timestamp,
+	}
+	result := buildBlock(args)
+	if uint64(result.Number) != head.Number.Uint64()+1 || result.Timestamp != timestamp {
+		Fatal(t, "unexpected block number", result.Number, "or timestamp", result.Timestamp)
+	}
+	for _, built := range result.Transactions {
+		if !built.Included || built.Status != types.ReceiptStatusSuccessful {
+			Fatal(t, "transaction", built.Hash, "wasn't included successfully:", built.Error)
+		}
+	}
+	if again := buildBlock(args); again.Hash != result.Hash || again.StateRoot != result.StateRoot {
+		Fatal(t, "building the same block twice gave different results")
+	}
+
+	// Out of order, the second transaction's nonce is too high
+	result = buildBlock(gethexec.BuildBlockArgs{Transactions: encode(second, first)})
+	if result.Transactions[0].Included || result.Transactions[0].Error == "" {
+		Fatal(t, "transaction with a nonce too high was included")
+	}
+	if !result.Transactions[1].Included {
+		Fatal(t, "transaction", result.Transactions[1].Hash, "wasn't included:", result.Transactions[1].Error)
+	}
+
+	third := builder.L2Info.PrepareTx("Owner", "User", builder.L2Info.TransferGas, value, nil)
+	var tooMany gethexec.BuildBlockResult
+	err = l2rpc.CallContext(ctx, &tooMany, "arb_buildBlock", gethexec.BuildBlockArgs{Transactions: encode(first, second, third)})
+	if err == nil {
+		Fatal(t, "built a block with more transactions than the limit")
+	}
+
+	balance, err := builder.L2.Client.BalanceAt(ctx, user, nil)
+	Require(t, err)
+	if balance.Sign() != 0 {
+		Fatal(t, "building a block changed the chain's state")
+	}
+	newHead, err := builder.L2.Client.HeaderByNumber(ctx, nil)
+	Require(t, err)
+	if newHead.Hash() != head.Hash() {
+		Fatal(t, "building a block changed the chain's head")
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/execution/gethexec"
+)
+
+func TestBlockResourceUsage(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	builder.execConfig.BlockResources.Enable = true
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	builder.L2Info.GenerateAccount("User2")
+	tx, receipt := builder.L2.TransferBalance(t, "Owner", "User2", common.Big1, builder.L2Info)
+
+	l2rpc := builder.L2.Stack.Attach()
+	var usage *gethexec.BlockResourceUsage
+	err := l2rpc.CallContext(ctx, &usage, "arb_blockResourceUsage", rpc.BlockNumber(receipt.BlockNumber.Int64()))
+	Require(t, err)
+	if usage == nil {
+		Fatal(t, "no resource usage recorded for block", receipt.BlockNumber)
+	}
+	if usage.BlockHash != receipt.BlockHash {
+		Fatal(t, "resource usage recorded for block", usage.BlockHash, "but expected", receipt.BlockHash)
+	}
+	if usage.TxCount != 2 || uint64(usage.GasUsed) < receipt.GasUsed {
+		Fatal(t, "unexpected transactions or gas in resource usage", usage.TxCount, usage.GasUsed)
+	}
+	if uint64(usage.L1DataBytes) != tx.Size() {
+		Fatal(t, "resource usage has", usage.L1DataBytes, "L1 data bytes but the transfer is", tx.Size())
+	}
+	if usage.ExecutionTime == 0 || usage.AccountWrites == 0 {
+		Fatal(t, "resource usage is missing execution time or state writes", usage.ExecutionTime, usage.AccountWrites)
+	}
+
+	var usages []*gethexec.BlockResourceUsage
+	err = l2rpc.CallContext(ctx, &usages, "arb_blockResourceUsageRange", rpc.BlockNumber(1), rpc.LatestBlockNumber)
+	Require(t, err)
+	found := false
+	for _, u := range usages {
+		found = found || u.BlockHash == receipt.BlockHash
+	}
+	if !found {
+		Fatal(t, "block", receipt.BlockNumber, "missing from the range of resource usage")
+	}
+}
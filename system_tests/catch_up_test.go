@@ -0,0 +1,61 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/execution/gethexec"
+)
+
+// A node far behind the sequencer catches up with speculative execution of the next block, and ends up with the same blocks
+func TestSpeculativeCatchUp(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	builder.L2Info.GenerateAccount("User2")
+	var lastTx *types.Transaction
+	for i := 0; i < 30; i++ {
+		lastTx = builder.L2Info.PrepareTx("Owner", "User2", builder.L2Info.TransferGas, big.NewInt(1e12), nil)
+		err := builder.L2.Client.SendTransaction(ctx, lastTx)
+		Require(t, err)
+		_, err = builder.L2.EnsureTxSucceeded(lastTx)
+		Require(t, err)
+	}
+
+	execConfig := gethexec.ConfigDefaultNonSequencerTest()
+	execConfig.CatchUp.Enable = true
+	execConfig.CatchUp.Lookahead = 4
+	execConfig.CatchUp.Workers = 2
+	Require(t, execConfig.Validate())
+	testClientB, cleanupB := builder.Build2ndNode(t, &SecondNodeParams{execConfig: execConfig})
+	defer cleanupB()
+
+	receipt, err := WaitForTx(ctx, testClientB.Client, lastTx.Hash(), time.Second*30)
+	Require(t, err)
+	for number := uint64(1); number <= receipt.BlockNumber.Uint64(); number++ {
+		headerA, err := builder.L2.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		Require(t, err)
+		headerB, err := testClientB.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		Require(t, err)
+		if headerA.Hash() != headerB.Hash() {
+			Fatal(t, "block", number, "differs after catching up: sequencer has", headerA.Hash(), "but catching up node has", headerB.Hash())
+		}
+	}
+	// only the catching up node speculates, so the blocks it committed were its own
+	if committed := metrics.GetOrRegisterCounter("arb/execution/catchup/speculative/committed", nil).Count(); committed <= 0 {
+		Fatal(t, "no speculatively executed blocks were committed")
+	}
+}
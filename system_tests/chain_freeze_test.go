@@ -0,0 +1,102 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/execution"
+)
+
+func TestChainFreeze(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	freezeAtBlock := uint64(10)
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	builder.nodeConfig.BlockValidator.Enable = true
+	builder.execConfig.Sequencer.FreezeAtBlock = freezeAtBlock
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	builder.L2Info.GenerateAccount("User2")
+	for {
+		head, err := builder.L2.Client.BlockNumber(ctx)
+		Require(t, err)
+		if head >= freezeAtBlock {
+			if head != freezeAtBlock {
+				Fatal(t, "sequenced past the freeze block to block", head)
+			}
+			break
+		}
+		tx := builder.L2Info.PrepareTx("Owner", "User2", builder.L2Info.TransferGas, big.NewInt(params.Ether), nil)
+		Require(t, builder.L2.Client.SendTransaction(ctx, tx))
+		_, err = builder.L2.EnsureTxSucceeded(tx)
+		Require(t, err)
+	}
+	tx := builder.L2Info.PrepareTx("Owner", "User2", builder.L2Info.TransferGas, big.NewInt(params.Ether), nil)
+	err := builder.L2.Client.SendTransaction(ctx, tx)
+	if err == nil || !strings.Contains(err.Error(), execution.ErrChainFrozen.Error()) {
+		Fatal(t, "expected the frozen chain to reject a transaction but got", err)
+	}
+
+	l2rpc := builder.L2.Stack.Attach()
+	var status arbnode.ChainFreezeStatus
+	for i := 0; ; i++ {
+		Require(t, l2rpc.CallContext(ctx, &status, "arbfreeze_status", hexutil.Uint64(freezeAtBlock)))
+		if status.Final {
+			break
+		}
+		if i >= 300 {
+			Fatal(t, "chain wasn't posted and validated up to the freeze block", status)
+		}
+		// Advance the parent chain so the batches are read back and validated
+		builder.L1.TransferBalance(t, "Faucet", "Faucet", common.Big1, builder.L1Info)
+		time.Sleep(100 * time.Millisecond)
+	}
+	header, err := builder.L2.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(freezeAtBlock))
+	Require(t, err)
+	if status.BlockHash != header.Hash() {
+		Fatal(t, "frozen at block", status.BlockHash, "but expected", header.Hash())
+	}
+	batchCount, err := builder.L2.ConsensusNode.InboxTracker.GetBatchCount()
+	Require(t, err)
+	if uint64(status.BatchCount) != batchCount {
+		Fatal(t, "final batch count", status.BatchCount, "but the inbox has", batchCount)
+	}
+	batchAcc, err := builder.L2.ConsensusNode.InboxTracker.GetBatchAcc(batchCount - 1)
+	Require(t, err)
+	if status.InboxAccumulator != batchAcc {
+		Fatal(t, "final inbox accumulator", status.InboxAccumulator, "but the inbox has", batchAcc)
+	}
+
+	var export arbnode.ChainFreezeExport
+	Require(t, l2rpc.CallContext(ctx, &export, "arbfreeze_exportState", hexutil.Uint64(freezeAtBlock)))
+	defer os.Remove(export.File)
+	if export.BlockHash != header.Hash() || export.StateRoot != header.Root {
+		Fatal(t, "exported block", export.BlockHash, "with root", export.StateRoot, "but expected", header.Hash(), "with root", header.Root)
+	}
+	file, err := os.Open(export.File)
+	Require(t, err)
+	defer file.Close()
+	var exportedRoot struct {
+		Root common.Hash `json:"root"`
+	}
+	Require(t, json.NewDecoder(file).Decode(&exportedRoot))
+	if exportedRoot.Root != header.Root {
+		Fatal(t, "export has state root", exportedRoot.Root, "but expected", header.Root)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestDAAuditorCalldataBatches(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	builder.nodeConfig.BatchPoster.Post4844Blobs = false
+	builder.nodeConfig.DAAuditor.Enable = true
+	builder.nodeConfig.DAAuditor.Interval = time.Millisecond * 100
+	builder.nodeConfig.DAAuditor.BatchesPerAudit = 2
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	builder.L2Info.GenerateAccount("User")
+	for i := 0; i < 3; i++ {
+		tx := builder.L2Info.PrepareTx("Owner", "User", builder.L2Info.TransferGas, big.NewInt(1e12), nil)
+		err := builder.L2.Client.SendTransaction(ctx, tx)
+		Require(t, err)
+		_, err = builder.L2.EnsureTxSucceeded(tx)
+		Require(t, err)
+	}
+
+	auditor := builder.L2.ConsensusNode.DAAuditor
+	for i := 0; ; i++ {
+		stats := auditor.Stats()["calldata"]
+		if stats.Audited >= 4 {
+			if stats.Available != stats.Audited {
+				Fatal(t, "audited", stats.Audited, "calldata batches but only", stats.Available, "were available")
+			}
+			break
+		}
+		if i >= 100 {
+			Fatal(t, "timed out waiting for calldata batches to be audited, stats:", stats)
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+}
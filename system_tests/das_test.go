@@ -200,6 +200,60 @@ func TestDASRekey(t *testing.T) {
 	nodeB.StopAndWait()
 }
 
+// With the DAS committee unreachable, the batch poster stores batch data on chain and nodes still sync
+func TestDASFallbackToOnChainData(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	chainConfig := params.ArbitrumDevTestDASChainConfig()
+	l1info, l1client, _, l1stack := createTestL1BlockChain(t, nil)
+	defer requireClose(t, l1stack)
+	feedErrChan := make(chan error, 10)
+	addresses, initMessage := DeployOnTestL1(t, ctx, l1info, l1client, chainConfig)
+
+	dasRpcServer, pubkey, backendConfig, restServer, restServerUrl := startLocalDASServer(t, ctx, t.TempDir(), l1client, addresses.SequencerInbox)
+	defer func() {
+		err := restServer.Shutdown()
+		Require(t, err)
+	}()
+	authorizeDASKeyset(t, ctx, pubkey, l1info, l1client)
+	// The committee goes down before any batch is posted
+	Require(t, dasRpcServer.Shutdown(ctx))
+
+	l2info := NewArbTestInfo(t, chainConfig.ChainID)
+	_, l2stackA, l2chainDb, l2arbDb, l2blockchain := createL2BlockChainWithStackConfig(t, l2info, t.TempDir(), chainConfig, initMessage, nil, nil)
+	l2info.GenerateAccount("User2")
+
+	l1NodeConfigA := arbnode.ConfigDefaultL1Test()
+	l1NodeConfigA.DataAvailability.Enable = true
+	l1NodeConfigA.DataAvailability.RPCAggregator = aggConfigForBackend(t, backendConfig)
+	l1NodeConfigA.DataAvailability.RestAggregator = das.DefaultRestfulClientAggregatorConfig
+	l1NodeConfigA.DataAvailability.RestAggregator.Enable = true
+	l1NodeConfigA.DataAvailability.RestAggregator.Urls = []string{restServerUrl}
+	l1NodeConfigA.DataAvailability.ParentChainNodeURL = "none"
+	l1NodeConfigA.BatchPoster.DasFallbackRetryInterval = time.Second
+	sequencerTxOpts := l1info.GetDefaultTransactOpts("Sequencer", ctx)
+	execA, err := gethexec.CreateExecutionNode(ctx, l2stackA, l2chainDb, l2blockchain, l1client, gethexec.ConfigDefaultTest)
+	Require(t, err)
+	nodeA, err := arbnode.CreateNode(ctx, l2stackA, execA, l2arbDb, NewFetcherFromConfig(l1NodeConfigA), l2blockchain.Config(), l1client, addresses, &sequencerTxOpts, &sequencerTxOpts, nil, feedErrChan, big.NewInt(1337), nil)
+	Require(t, err)
+	Require(t, nodeA.Start(ctx))
+	defer nodeA.StopAndWait()
+	l2clientA := ClientForStack(t, l2stackA)
+
+	l1NodeConfigB := arbnode.ConfigDefaultL1NonSequencerTest()
+	l1NodeConfigB.BlockValidator.Enable = false
+	l1NodeConfigB.DataAvailability.Enable = true
+	l1NodeConfigB.DataAvailability.RestAggregator = das.DefaultRestfulClientAggregatorConfig
+	l1NodeConfigB.DataAvailability.RestAggregator.Enable = true
+	l1NodeConfigB.DataAvailability.RestAggregator.Urls = []string{restServerUrl}
+	l1NodeConfigB.DataAvailability.ParentChainNodeURL = "none"
+	l2clientB, nodeB := Create2ndNodeWithConfig(t, ctx, nodeA, l1stack, l1info, &l2info.ArbInitData, l1NodeConfigB, nil, nil)
+	defer nodeB.StopAndWait()
+
+	checkBatchPosting(t, ctx, l1client, l2clientA, l1info, l2info, big.NewInt(1e12), l2clientB)
+}
+
 func checkBatchPosting(t *testing.T, ctx context.Context, l1client, l2clientA *ethclient.Client, l1info, l2info info, expectedBalance *big.Int, l2ClientsToCheck ...*ethclient.Client) {
 	tx := l2info.PrepareTx("Owner", "User2", l2info.TransferGas, big.NewInt(1e12), nil)
 	err := l2clientA.SendTransaction(ctx, tx)
@@ -12,7 +12,9 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/offchainlabs/nitro/execution/gethexec"
 	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
+	"math/big"
 )
 
 func TestDebugAPI(t *testing.T) {
@@ -56,3 +58,47 @@ func TestDebugAPI(t *testing.T) {
 	err = l2rpc.CallContext(ctx, &result, "debug_traceTransaction", tx.Hash(), &tracers.TraceConfig{Tracer: &flatCallTracer})
 	Require(t, err)
 }
+
+func TestBalanceTransferTracer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	l2rpc := builder.L2.Stack.Attach()
+	builder.L2Info.GenerateAccount("User2")
+	tx := builder.L2Info.PrepareTx("Owner", "User2", builder.L2Info.TransferGas, big.NewInt(1e12), nil)
+	Require(t, builder.L2.Client.SendTransaction(ctx, tx))
+	receipt, err := builder.L2.EnsureTxSucceeded(tx)
+	Require(t, err)
+
+	traceTransfers := func(tracerConfig string) gethexec.BalanceTransferTrace {
+		t.Helper()
+		tracer := gethexec.BalanceTransferTracerName
+		config := &tracers.TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(tracerConfig)}
+		var trace gethexec.BalanceTransferTrace
+		Require(t, l2rpc.CallContext(ctx, &trace, "debug_traceTransaction", tx.Hash(), config))
+		return trace
+	}
+
+	// The fees collected must add up to what the sender paid for gas
+	collected := big.NewInt(0)
+	for _, transfer := range traceTransfers("{}").Transfers {
+		if transfer.Purpose != "feeCollection" {
+			continue
+		}
+		if transfer.From != nil || transfer.To == nil || transfer.Phase != "afterEVM" || transfer.Reverted {
+			Fatal(t, "unexpected fee collection transfer", transfer)
+		}
+		collected.Add(collected, transfer.Value.ToInt())
+	}
+	paid := new(big.Int).Mul(receipt.EffectiveGasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+	if collected.Cmp(paid) != 0 {
+		Fatal(t, "collected", collected, "in fees but the sender paid", paid)
+	}
+
+	if transfers := traceTransfers(`{"purposes":["refund"]}`).Transfers; len(transfers) != 0 {
+		Fatal(t, "expected no refunds but got", transfers)
+	}
+}
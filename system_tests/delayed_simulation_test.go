@@ -0,0 +1,106 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbos/util"
+	"github.com/offchainlabs/nitro/execution/gethexec"
+)
+
+func TestSimulateDelayedMessage(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	builder.execConfig.Simulation.Enable = true
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	l2rpc := builder.L2.Stack.Attach()
+	builder.L2Info.GenerateAccount("User")
+	user := builder.L2Info.GetAddress("User")
+	l1Sender := common.HexToAddress("0x1234")
+	simulate := func(args gethexec.DelayedMessageSimulationArgs) *gethexec.DelayedMessageSimulationResult {
+		t.Helper()
+		var result gethexec.DelayedMessageSimulationResult
+		err := l2rpc.CallContext(ctx, &result, "arb_simulateDelayedMessage", args)
+		Require(t, err)
+		return &result
+	}
+	balanceDelta := func(result *gethexec.DelayedMessageSimulationResult, addr common.Address) *big.Int {
+		t.Helper()
+		change, ok := result.Balances[addr]
+		if !ok {
+			Fatal(t, "no balance change reported for", addr)
+		}
+		return new(big.Int).Sub(change.After.ToInt(), change.Before.ToInt())
+	}
+
+	value := big.NewInt(params.Ether)
+	result := simulate(gethexec.DelayedMessageSimulationArgs{
+		Kind:  gethexec.DelayedMessageDeposit,
+		From:  l1Sender,
+		To:    &user,
+		Value: (*hexutil.Big)(value),
+	})
+	if result.Sender != util.RemapL1Address(l1Sender) {
+		Fatal(t, "deposit sender", result.Sender, "isn't the aliased L1 sender")
+	}
+	if len(result.Transactions) != 1 || result.Transactions[0].Type != types.ArbitrumDepositTxType {
+		Fatal(t, "unexpected deposit transactions", result.Transactions)
+	}
+	if balanceDelta(result, user).Cmp(value) != 0 {
+		Fatal(t, "deposit simulated a balance change of", balanceDelta(result, user), "expected", value)
+	}
+	balance, err := builder.L2.Client.BalanceAt(ctx, user, nil)
+	Require(t, err)
+	if balance.Sign() != 0 {
+		Fatal(t, "simulating a deposit changed the chain's state")
+	}
+
+	header, err := builder.L2.Client.HeaderByNumber(ctx, nil)
+	Require(t, err)
+	maxFeePerGas := new(big.Int).Mul(header.BaseFee, big.NewInt(2))
+	result = simulate(gethexec.DelayedMessageSimulationArgs{
+		Kind:         gethexec.DelayedMessageRetryable,
+		From:         l1Sender,
+		To:           &user,
+		Value:        (*hexutil.Big)(value),
+		GasLimit:     100_000,
+		MaxFeePerGas: (*hexutil.Big)(maxFeePerGas),
+	})
+	if result.TicketId == nil || result.AutoRedeem == nil {
+		Fatal(t, "retryable wasn't auto-redeemed", result)
+	}
+	if result.AutoRedeem.Status != types.ReceiptStatusSuccessful {
+		Fatal(t, "auto-redeem failed", result.AutoRedeem)
+	}
+	if result.TicketOutstanding {
+		Fatal(t, "ticket outstanding after a successful auto-redeem")
+	}
+	if balanceDelta(result, user).Cmp(value) != 0 {
+		Fatal(t, "retryable simulated a balance change of", balanceDelta(result, user), "expected", value)
+	}
+
+	// without gas for the auto-redeem, the ticket is left to be redeemed manually
+	result = simulate(gethexec.DelayedMessageSimulationArgs{
+		Kind:  gethexec.DelayedMessageRetryable,
+		From:  l1Sender,
+		To:    &user,
+		Value: (*hexutil.Big)(value),
+	})
+	if result.TicketId == nil || result.AutoRedeem != nil || !result.TicketOutstanding {
+		Fatal(t, "expected an outstanding ticket without an auto-redeem", result)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/arbos"
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+type gasDiscrepancy struct {
+	block   uint64
+	txIndex int
+	txHash  common.Hash
+	field   string
+	geth    uint64
+	replay  uint64
+}
+
+func (d gasDiscrepancy) String() string {
+	return fmt.Sprintf(
+		"block %v tx %v (%v): %v is %v when executed by geth but %v when replayed",
+		d.block, d.txIndex, d.txHash, d.field, d.geth, d.replay,
+	)
+}
+
+// diffBlockGasAgainstReplay re-executes a block in-process with arbos.ProduceBlock, as the replay binary does, from
+// the block's inbox message on top of a deterministic StateDB, and reports every difference in gas usage from the
+// block geth produced. Gas is compared per transaction, split into the L1 poster charge and the L2 execution done by
+// ArbOS and the EVM. This only explains a mismatch: the replay machine itself is checked by validating the blocks.
+func diffBlockGasAgainstReplay(t *testing.T, testClient *TestClient, blockNum uint64) []gasDiscrepancy {
+	t.Helper()
+	bc := testClient.ExecNode.ArbInterface.BlockChain()
+	streamer := testClient.ConsensusNode.TxStreamer
+
+	block := bc.GetBlockByNumber(blockNum)
+	if block == nil {
+		Fatal(t, "block", blockNum, "not found")
+	}
+	parent := bc.GetHeaderByHash(block.ParentHash())
+	msgIdx, err := testClient.ExecNode.ExecEngine.BlockNumberToMessageIndex(blockNum)
+	Require(t, err)
+	msg, err := streamer.GetMessage(msgIdx)
+	Require(t, err)
+
+	statedb, err := state.NewDeterministic(parent.Root, bc.StateCache())
+	Require(t, err)
+	batchFetcher := func(batchNum uint64) ([]byte, error) {
+		data, _, err := streamer.FetchBatch(batchNum)
+		return data, err
+	}
+
+	// the replay binary never has the batch gas cost cached
+	message := *msg.Message
+	message.BatchGasCost = nil
+	replayBlock, replayReceipts, err := arbos.ProduceBlock(
+		&message, msg.DelayedMessagesRead, parent, statedb, bc, bc.Config(), batchFetcher,
+	)
+	Require(t, err)
+	gethReceipts := bc.GetReceiptsByHash(block.Hash())
+
+	var discrepancies []gasDiscrepancy
+	record := func(txIndex int, txHash common.Hash, field string, geth, replay uint64) {
+		if geth != replay {
+			discrepancies = append(discrepancies, gasDiscrepancy{blockNum, txIndex, txHash, field, geth, replay})
+		}
+	}
+	record(-1, common.Hash{}, "block gas used", block.GasUsed(), replayBlock.GasUsed())
+	record(-1, common.Hash{}, "transaction count", uint64(len(gethReceipts)), uint64(len(replayReceipts)))
+
+	for i := 0; i < len(gethReceipts) && i < len(replayReceipts); i++ {
+		geth := gethReceipts[i]
+		replay := replayReceipts[i]
+		record(i, geth.TxHash, "gas used", geth.GasUsed, replay.GasUsed)
+		record(i, geth.TxHash, "l1 poster gas", geth.GasUsedForL1, replay.GasUsedForL1)
+		record(i, geth.TxHash, "l2 execution gas", geth.GasUsed-geth.GasUsedForL1, replay.GasUsed-replay.GasUsedForL1)
+		record(i, geth.TxHash, "cumulative gas used", geth.CumulativeGasUsed, replay.CumulativeGasUsed)
+		record(i, geth.TxHash, "status", geth.Status, replay.Status)
+	}
+	if len(discrepancies) == 0 && replayBlock.Hash() != block.Hash() {
+		Fatal(t, "block", blockNum, "has hash", block.Hash(), "but replayed to", replayBlock.Hash(), "with identical gas usage")
+	}
+	return discrepancies
+}
+
+func TestGasDifferentialAgainstReplay(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	validatorConfig := arbnode.ConfigDefaultL1NonSequencerTest()
+	validatorConfig.BlockValidator.Enable = true
+	AddDefaultValNode(t, ctx, validatorConfig, true)
+	testClientB, cleanupB := builder.Build2ndNode(t, &SecondNodeParams{nodeConfig: validatorConfig})
+	defer cleanupB()
+
+	auth := builder.L2Info.GetDefaultTransactOpts("Owner", ctx)
+	_, simple := builder.L2.DeploySimple(t, auth)
+	for i := 0; i < 3; i++ {
+		tx, err := simple.Increment(&auth)
+		Require(t, err)
+		_, err = builder.L2.EnsureTxSucceeded(tx)
+		Require(t, err)
+	}
+
+	builder.L2Info.GenerateAccount("User")
+	var txs types.Transactions
+	for i := 0; i < 3; i++ {
+		// calldata makes the poster fee non-trivial
+		data := make([]byte, 1024*(i+1))
+		txs = append(txs, builder.L2Info.PrepareTx("Owner", "User", builder.L2Info.TransferGas*20, big.NewInt(1e12), data))
+	}
+	builder.L2.SendWaitTestTransactions(t, txs)
+
+	latest, err := builder.L2.Client.BlockNumber(ctx)
+	Require(t, err)
+
+	// the replay machine, run by the JIT validation node, must reach the same block hashes, which commit to each
+	// block's gas used and the cumulative gas used by each of its transactions through the receipts root
+	_, err = WaitForTx(ctx, testClientB.Client, txs[len(txs)-1].Hash(), time.Second*30)
+	Require(t, err)
+	// message index is the same as the block number here
+	if !testClientB.ConsensusNode.BlockValidator.WaitForPos(t, ctx, arbutil.MessageIndex(latest), getDeadlineTimeout(t, time.Minute*5)) {
+		Fatal(t, "replay machine didn't validate block", latest)
+	}
+
+	for blockNum := uint64(1); blockNum <= latest; blockNum++ {
+		for _, discrepancy := range diffBlockGasAgainstReplay(t, builder.L2, blockNum) {
+			t.Error(discrepancy)
+		}
+	}
+}
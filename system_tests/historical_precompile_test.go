@@ -0,0 +1,129 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/nitro/precompiles"
+	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
+)
+
+// Precompile getters called at a past block on an archive node see ArbOS as it was at that block,
+// including which ArbOS version was running
+func TestHistoricalPrecompileCalls(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	builder.chainConfig.ArbitrumChainParams.InitialArbOSVersion = 11
+	builder.execConfig.Caching.Archive = true
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	auth := builder.L2Info.GetDefaultTransactOpts("Owner", ctx)
+	arbOwner, err := precompilesgen.NewArbOwner(common.HexToAddress("0x70"), builder.L2.Client)
+	Require(t, err)
+	arbOwnerPublic, err := precompilesgen.NewArbOwnerPublic(common.HexToAddress("0x6b"), builder.L2.Client)
+	Require(t, err)
+	arbGasInfo, err := precompilesgen.NewArbGasInfo(common.HexToAddress("0x6c"), builder.L2.Client)
+	Require(t, err)
+	arbSys, err := precompilesgen.NewArbSys(common.HexToAddress("0x64"), builder.L2.Client)
+	Require(t, err)
+
+	ensure := func(tx *types.Transaction, err error) *types.Receipt {
+		t.Helper()
+		Require(t, err)
+		receipt, err := builder.L2.EnsureTxSucceeded(tx)
+		Require(t, err)
+		return receipt
+	}
+
+	oldBlock := ensure(arbOwner.SetL1PricingRewardRate(&auth, 7)).BlockNumber
+	oldViews := callPrecompileViews(t, ctx, builder, oldBlock, 11)
+
+	ensure(arbOwner.ScheduleArbOSUpgrade(&auth, 20, 0))
+	newBlock := ensure(arbOwner.SetL1PricingRewardRate(&auth, 9)).BlockNumber
+	for i := 0; i < 10; i++ {
+		builder.L2.TransferBalance(t, "Owner", "Owner", big.NewInt(1), builder.L2Info)
+	}
+
+	oldOpts := &bind.CallOpts{Context: ctx, BlockNumber: oldBlock}
+	newOpts := &bind.CallOpts{Context: ctx, BlockNumber: newBlock}
+	rate, err := arbGasInfo.GetL1RewardRate(oldOpts)
+	Require(t, err)
+	if rate != 7 {
+		Fatal(t, "expected historical reward rate 7 but got", rate)
+	}
+	rate, err = arbGasInfo.GetL1RewardRate(newOpts)
+	Require(t, err)
+	if rate != 9 {
+		Fatal(t, "expected reward rate 9 but got", rate)
+	}
+
+	version, err := arbSys.ArbOSVersion(oldOpts)
+	Require(t, err)
+	if version.Uint64() != 55+11 {
+		Fatal(t, "expected historical ArbOS version 11 but got", version.Uint64()-55)
+	}
+	version, err = arbSys.ArbOSVersion(newOpts)
+	Require(t, err)
+	if version.Uint64() != 55+20 {
+		Fatal(t, "expected ArbOS version 20 but got", version.Uint64()-55)
+	}
+
+	// Methods added by later ArbOS versions don't exist at blocks before the upgrade
+	_, err = arbOwnerPublic.GetScheduledUpgrade(oldOpts)
+	if err == nil {
+		Fatal(t, "GetScheduledUpgrade succeeded at a block running ArbOS 11")
+	}
+	_, err = arbOwnerPublic.GetScheduledUpgrade(newOpts)
+	Require(t, err)
+
+	// Every getter returns the same result now as when its block was the latest
+	for name, output := range callPrecompileViews(t, ctx, builder, oldBlock, 11) {
+		if !bytes.Equal(output, oldViews[name]) {
+			Fatal(t, "historical call to", name, "returned", output, "but returned", oldViews[name], "at the time")
+		}
+	}
+	callPrecompileViews(t, ctx, builder, newBlock, 20)
+}
+
+// callPrecompileViews calls every argument-free precompile view method active at the ArbOS version
+func callPrecompileViews(t *testing.T, ctx context.Context, builder *NodeBuilder, block *big.Int, arbosVersion uint64) map[string][]byte {
+	t.Helper()
+	outputs := make(map[string][]byte)
+	for address, precompile := range precompiles.Precompiles() {
+		if address == common.HexToAddress("0xff") {
+			// ArbDebug's views deliberately revert or panic
+			continue
+		}
+		for _, method := range precompile.Precompile().ViewMethods(arbosVersion) {
+			if len(method.Inputs) != 0 {
+				continue
+			}
+			address := address
+			msg := ethereum.CallMsg{
+				From: builder.L2Info.GetAddress("Owner"),
+				To:   &address,
+				Data: method.ID,
+			}
+			output, err := builder.L2.Client.CallContract(ctx, msg, block)
+			if err != nil {
+				Fatal(t, "calling", method.Name, "on", address, "at block", block, "failed:", err)
+			}
+			outputs[address.Hex()+"."+method.Name] = output
+		}
+	}
+	return outputs
+}
@@ -0,0 +1,62 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbnode"
+)
+
+func TestInboxReaderSafeModeFallsBackToConfirmations(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	// The test parent chain reader doesn't use finality data, so the safe block tag isn't supported
+	confirmations := uint64(5)
+	nodeConfig := arbnode.ConfigDefaultL1NonSequencerTest()
+	nodeConfig.InboxReader.ReadMode = "safe"
+	nodeConfig.InboxReader.TagFallbackConfirmations = confirmations
+	testClientB, cleanupB := builder.Build2ndNode(t, &SecondNodeParams{nodeConfig: nodeConfig})
+	defer cleanupB()
+
+	builder.L2Info.GenerateAccount("User2")
+	tx := builder.L2Info.PrepareTx("Owner", "User2", builder.L2Info.TransferGas, big.NewInt(params.Ether), nil)
+	err := builder.L2.Client.SendTransaction(ctx, tx)
+	Require(t, err)
+	_, err = builder.L2.EnsureTxSucceeded(tx)
+	Require(t, err)
+
+	for i := 0; ; i++ {
+		if i >= 200 {
+			Fatal(t, "safe mode node didn't read the batch")
+		}
+		// Advance the parent chain so the batch gets enough confirmations
+		builder.L1.TransferBalance(t, "Faucet", "Faucet", common.Big1, builder.L1Info)
+		balance, err := testClientB.Client.BalanceAt(ctx, builder.L2Info.GetAddress("User2"), nil)
+		Require(t, err)
+		if balance.Cmp(big.NewInt(params.Ether)) == 0 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	lastReadBlock, _ := testClientB.ConsensusNode.InboxReader.GetLastReadBlockAndBatchCount()
+	head, err := builder.L1.Client.BlockNumber(ctx)
+	Require(t, err)
+	if lastReadBlock+confirmations > head {
+		Fatal(t, "safe mode node read parent chain block", lastReadBlock, "with fewer than", confirmations, "confirmations at head", head)
+	}
+}
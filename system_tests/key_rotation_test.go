@@ -0,0 +1,115 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/solgen/go/upgrade_executorgen"
+)
+
+func TestBatchPosterKeyRotation(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	batchPoster := builder.L2.ConsensusNode.BatchPoster
+	tracker := builder.L2.ConsensusNode.InboxTracker
+	builder.L2Info.GenerateAccount("User")
+	sendTx := func() {
+		t.Helper()
+		tx := builder.L2Info.PrepareTx("Owner", "User", builder.L2Info.TransferGas, big.NewInt(1e12), nil)
+		err := builder.L2.Client.SendTransaction(ctx, tx)
+		Require(t, err)
+		_, err = builder.L2.EnsureTxSucceeded(tx)
+		Require(t, err)
+	}
+	waitForDrained := func() *arbnode.BatchPosterDrainStatus {
+		t.Helper()
+		for i := 0; i < 200; i++ {
+			status, err := batchPoster.DrainStatus(ctx)
+			Require(t, err)
+			if status.Drained {
+				return status
+			}
+			builder.L1.TransferBalance(t, "Faucet", "Faucet", common.Big1, builder.L1Info) // generate l1 traffic
+			time.Sleep(time.Millisecond * 50)
+		}
+		Fatal(t, "timed out waiting for batch poster to drain")
+		return nil
+	}
+
+	sendTx()
+	batchPoster.Drain()
+	status := waitForDrained()
+	if status.PendingTransactions != 0 {
+		Fatal(t, "batch poster drained with", status.PendingTransactions, "pending transactions")
+	}
+	if status.Sender != builder.L1Info.GetAddress("Sequencer") {
+		Fatal(t, "unexpected batch poster sender", status.Sender)
+	}
+
+	batchesDrained, err := tracker.GetBatchCount()
+	Require(t, err)
+	sendTx()
+	for i := 0; i < 10; i++ {
+		builder.L1.TransferBalance(t, "Faucet", "Faucet", common.Big1, builder.L1Info)
+		time.Sleep(time.Millisecond * 50)
+	}
+	batches, err := tracker.GetBatchCount()
+	Require(t, err)
+	if batches != batchesDrained {
+		Fatal(t, "drained batch poster posted", batches-batchesDrained, "batches")
+	}
+
+	deployInfo := builder.L2.ConsensusNode.DeployInfo
+	builder.L1Info.GenerateAccount("NewBatchPoster")
+	newKey := builder.L1Info.GetAddress("NewBatchPoster")
+	rotation, err := arbnode.CheckKeyRotation(ctx, builder.L1.Client, arbnode.KeyRotationBatchPoster, deployInfo, status.Sender, newKey)
+	Require(t, err)
+	if !rotation.Old.Whitelisted || !rotation.Old.Drained() {
+		Fatal(t, "old key should be whitelisted and drained", rotation.Old)
+	}
+	if rotation.ReadyToSwitch() {
+		Fatal(t, "ready to switch to a key that isn't whitelisted")
+	}
+	target, calldata, _, err := arbnode.KeyRotationCalldata(arbnode.KeyRotationBatchPoster, deployInfo, newKey, true)
+	Require(t, err)
+	upgradeExecutor, err := upgrade_executorgen.NewUpgradeExecutor(deployInfo.UpgradeExecutor, builder.L1.Client)
+	Require(t, err)
+	ownerOpts := builder.L1Info.GetDefaultTransactOpts("RollupOwner", ctx)
+	tx, err := upgradeExecutor.ExecuteCall(&ownerOpts, target, calldata)
+	Require(t, err)
+	_, err = builder.L1.EnsureTxSucceeded(tx)
+	Require(t, err)
+	rotation, err = arbnode.CheckKeyRotation(ctx, builder.L1.Client, arbnode.KeyRotationBatchPoster, deployInfo, status.Sender, newKey)
+	Require(t, err)
+	if !rotation.ReadyToSwitch() {
+		Fatal(t, "not ready to switch to whitelisted key", rotation)
+	}
+
+	// the old key can pick back up where it left off
+	batchPoster.Resume()
+	for i := 0; ; i++ {
+		batches, err = tracker.GetBatchCount()
+		Require(t, err)
+		if batches > batchesDrained {
+			break
+		}
+		if i >= 200 {
+			Fatal(t, "resumed batch poster didn't post a batch")
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/execution/gethexec"
+	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
+)
+
+func TestLogsPages(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	builder.execConfig.LogsPages.MaxBlocks = 3
+	builder.execConfig.LogsPages.BlocksPerQuery = 2
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	auth := builder.L2Info.GetDefaultTransactOpts("Owner", ctx)
+	arbSys, err := precompilesgen.NewArbSys(types.ArbSysAddress, builder.L2.Client)
+	Require(t, err)
+	startBlock, err := builder.L2.Client.BlockNumber(ctx)
+	Require(t, err)
+	for i := 0; i < 5; i++ {
+		tx, err := arbSys.WithdrawEth(&auth, common.Address{})
+		Require(t, err)
+		_, err = builder.L2.EnsureTxSucceeded(tx)
+		Require(t, err)
+		builder.L2.TransferBalance(t, "Owner", "Owner", common.Big1, builder.L2Info)
+	}
+	endBlock, err := builder.L2.Client.BlockNumber(ctx)
+	Require(t, err)
+
+	filter := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(startBlock),
+		ToBlock:   new(big.Int).SetUint64(endBlock),
+		Addresses: []common.Address{types.ArbSysAddress},
+	}
+	expected, err := builder.L2.Client.FilterLogs(ctx, filter)
+	Require(t, err)
+	if len(expected) < 10 {
+		Fatal(t, "expected at least 2 logs per withdrawal but got", len(expected))
+	}
+
+	l2rpc := builder.L2.Stack.Attach()
+	fromBlock := rpc.BlockNumber(startBlock)
+	toBlock := rpc.BlockNumber(endBlock)
+	query := gethexec.LogsPageQuery{
+		FromBlock: &fromBlock,
+		ToBlock:   &toBlock,
+		Addresses: filter.Addresses,
+		Limit:     3,
+	}
+	var logs []types.Log
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			Fatal(t, "logs pages never reached the end of the range")
+		}
+		var page gethexec.LogsPage
+		err := l2rpc.CallContext(ctx, &page, "arb_getLogsPage", query)
+		Require(t, err)
+		if len(page.Logs) > 3 {
+			Fatal(t, "page of", len(page.Logs), "logs exceeds the limit")
+		}
+		for _, log := range page.Logs {
+			logs = append(logs, *log)
+		}
+		if page.Cursor == nil {
+			break
+		}
+		query.Cursor = page.Cursor
+	}
+	if !reflect.DeepEqual(logs, expected) {
+		Fatal(t, "paged logs", logs, "differ from eth_getLogs", expected)
+	}
+
+	// A cursor can't be reused with another filter
+	query.Addresses = nil
+	var page gethexec.LogsPage
+	if err := l2rpc.CallContext(ctx, &page, "arb_getLogsPage", query); err == nil {
+		Fatal(t, "expected a cursor with a different filter to be rejected")
+	}
+}
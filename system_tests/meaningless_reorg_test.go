@@ -105,3 +105,71 @@ func TestMeaninglessBatchReorg(t *testing.T) {
 		Fatal(t, "L2 block hash changed")
 	}
 }
+
+func TestParentChainReorgRollsBackBatch(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	builder.nodeConfig.BatchPoster.Enable = false
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	seqInbox, err := bridgegen.NewSequencerInbox(builder.L1Info.GetAddress("SequencerInbox"), builder.L1.Client)
+	Require(t, err)
+	seqOpts := builder.L1Info.GetDefaultTransactOpts("Sequencer", ctx)
+
+	tx, err := seqInbox.AddSequencerL2BatchFromOrigin8f111f3c(&seqOpts, big.NewInt(1), nil, big.NewInt(1), common.Address{}, common.Big0, common.Big0)
+	Require(t, err)
+	batchReceipt, err := builder.L1.EnsureTxSucceeded(tx)
+	Require(t, err)
+
+	tracker := builder.L2.ConsensusNode.InboxTracker
+	for i := 0; ; i++ {
+		if i >= 500 {
+			Fatal(t, "Failed to read batch from L1")
+		}
+		batchCount, err := tracker.GetBatchCount()
+		Require(t, err)
+		if batchCount == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Make the reorg larger than 64 blocks so the miner doesn't put the batch back in the mempool
+	for j := uint64(0); j < 70; j++ {
+		builder.L1.TransferBalance(t, "Faucet", "Faucet", common.Big1, builder.L1Info)
+	}
+	parentBlock := builder.L1.L1Backend.BlockChain().GetBlockByNumber(batchReceipt.BlockNumber.Uint64() - 1)
+	err = builder.L1.L1Backend.BlockChain().ReorgToOldBlock(parentBlock)
+	Require(t, err)
+	for j := uint64(0); j < 3; j++ {
+		builder.L1.TransferBalance(t, "User", "User", common.Big1, builder.L1Info)
+	}
+
+	// The sequencer inbox's batch count dropped without a hard reorg, so only the block hash check rolls the batch back
+	for i := 0; ; i++ {
+		if i >= 500 {
+			Fatal(t, "Batch reorged out of the parent chain wasn't rolled back")
+		}
+		batchCount, err := tracker.GetBatchCount()
+		Require(t, err)
+		if batchCount == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, err = tracker.GetBatchMetadata(1)
+	if err == nil {
+		Fatal(t, "metadata for the reorged batch wasn't deleted")
+	}
+	msgCount, err := builder.L2.ConsensusNode.TxStreamer.GetMessageCount()
+	Require(t, err)
+	batchMessageCount, err := tracker.GetBatchMessageCount(0)
+	Require(t, err)
+	if msgCount != batchMessageCount {
+		Fatal(t, "transaction streamer has", msgCount, "messages after the reorg but expected", batchMessageCount)
+	}
+}
@@ -11,8 +11,11 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/offchainlabs/nitro/arbos"
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/execution/gethexec"
 	"github.com/offchainlabs/nitro/solgen/go/mocksgen"
 	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
 	"github.com/offchainlabs/nitro/util/arbmath"
@@ -130,6 +133,8 @@ func TestScheduleArbosUpgrade(t *testing.T) {
 	defer cancel()
 
 	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	// pinned so the upgrade scheduled event is always emitted, whatever version the dev chain defaults to
+	builder.chainConfig.ArbitrumChainParams.InitialArbOSVersion = arbostypes.ArbosVersion_UpgradeEvents
 	cleanup := builder.Build(t)
 	defer cleanup()
 
@@ -166,7 +171,7 @@ func TestScheduleArbosUpgrade(t *testing.T) {
 	var testTimestamp uint64 = 1 << 62
 	tx, err = arbOwner.ScheduleArbOSUpgrade(&auth, 100, 1<<62)
 	Require(t, err)
-	_, err = builder.L2.EnsureTxSucceeded(tx)
+	receipt, err := builder.L2.EnsureTxSucceeded(tx)
 	Require(t, err)
 
 	scheduled, err = arbOwnerPublic.GetScheduledUpgrade(callOpts)
@@ -174,4 +179,89 @@ func TestScheduleArbosUpgrade(t *testing.T) {
 	if scheduled.ArbosVersion != testVersion || scheduled.ScheduledForTimestamp != testTimestamp {
 		t.Errorf("expected upgrade to be scheduled for version %v timestamp %v, got version %v timestamp %v", testVersion, testTimestamp, scheduled.ArbosVersion, scheduled.ScheduledForTimestamp)
 	}
+
+	found := false
+	for _, eventLog := range receipt.Logs {
+		event, err := arbOwner.ParseArbOSUpgradeScheduled(*eventLog)
+		if err != nil {
+			continue
+		}
+		found = true
+		if event.NewVersion != testVersion || event.Timestamp != testTimestamp {
+			t.Errorf("expected upgrade scheduled event for version %v timestamp %v, got version %v timestamp %v", testVersion, testTimestamp, event.NewVersion, event.Timestamp)
+		}
+	}
+	if !found {
+		t.Error("expected an upgrade scheduled event")
+	}
+
+	var pending gethexec.ScheduledArbOSUpgrade
+	err = builder.L2.Stack.Attach().CallContext(ctx, &pending, "arbdebug_scheduledArbOSUpgrade", "latest")
+	Require(t, err)
+	if !pending.Pending || pending.Version != testVersion || pending.Timestamp != testTimestamp || pending.CurrentVersion != arbostypes.ArbosVersion_UpgradeEvents {
+		t.Errorf("unexpected scheduled upgrade from rpc %+v", pending)
+	}
+}
+
+func TestArbOSUpgradedEvent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	builder.chainConfig.ArbitrumChainParams.InitialArbOSVersion = arbostypes.ArbosVersion_UpgradeEvents - 1
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	auth := builder.L2Info.GetDefaultTransactOpts("Owner", ctx)
+	arbOwner, err := precompilesgen.NewArbOwner(common.HexToAddress("0x70"), builder.L2.Client)
+	Require(t, err)
+	arbSys, err := precompilesgen.NewArbSys(types.ArbSysAddress, builder.L2.Client)
+	Require(t, err)
+
+	// an upgrade scheduled in the past takes effect in the internal tx starting the next block
+	oldVersion := arbostypes.ArbosVersion_UpgradeEvents - 1
+	newVersion := arbostypes.ArbosVersion_UpgradeEvents
+	tx, err := arbOwner.ScheduleArbOSUpgrade(&auth, newVersion, 1)
+	Require(t, err)
+	receipt, err := builder.L2.EnsureTxSucceeded(tx)
+	Require(t, err)
+	for _, eventLog := range receipt.Logs {
+		if _, err := arbOwner.ParseArbOSUpgradeScheduled(*eventLog); err == nil {
+			Fatal(t, "upgrade scheduled event emitted before ArbOS version", newVersion)
+		}
+	}
+
+	builder.L2Info.GenerateAccount("User2")
+	tx = builder.L2Info.PrepareTx("Owner", "User2", builder.L2Info.TransferGas, big.NewInt(1e12), nil)
+	Require(t, builder.L2.Client.SendTransaction(ctx, tx))
+	receipt, err = builder.L2.EnsureTxSucceeded(tx)
+	Require(t, err)
+
+	block, err := builder.L2.Client.BlockByHash(ctx, receipt.BlockHash)
+	Require(t, err)
+	internalReceipt, err := builder.L2.Client.TransactionReceipt(ctx, block.Transactions()[0].Hash())
+	Require(t, err)
+	found := false
+	for _, eventLog := range internalReceipt.Logs {
+		event, err := arbOwner.ParseArbOSUpgraded(*eventLog)
+		if err != nil {
+			continue
+		}
+		if found {
+			Fatal(t, "upgraded event emitted twice")
+		}
+		found = true
+		if event.OldVersion != oldVersion || event.NewVersion != newVersion {
+			Fatal(t, "upgraded event from version", event.OldVersion, "to", event.NewVersion, "expected", oldVersion, "to", newVersion)
+		}
+	}
+	if !found {
+		Fatal(t, "no upgraded event in the internal tx of block", block.Number())
+	}
+
+	arbosVersion, err := arbSys.ArbOSVersion(&bind.CallOpts{Context: ctx})
+	Require(t, err)
+	if arbosVersion.Uint64() != 55+newVersion {
+		Fatal(t, "ArbOS version is", arbosVersion.Uint64()-55, "after the upgrade, expected", newVersion)
+	}
 }
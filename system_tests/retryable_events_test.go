@@ -0,0 +1,109 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbos/l2pricing"
+	"github.com/offchainlabs/nitro/execution/gethexec"
+	"github.com/offchainlabs/nitro/solgen/go/mocksgen"
+	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+func TestRetryableEventsSubscription(t *testing.T) {
+	t.Parallel()
+	builder, delayedInbox, lookupL2Tx, ctx, teardown := retryableSetup(t)
+	defer teardown()
+
+	ownerTxOpts := builder.L2Info.GetDefaultTransactOpts("Owner", ctx)
+	usertxopts := builder.L1Info.GetDefaultTransactOpts("Faucet", ctx)
+	usertxopts.Value = arbmath.BigMul(big.NewInt(1e12), big.NewInt(1e12))
+
+	simpleAddr, _ := builder.L2.DeploySimple(t, ownerTxOpts)
+	simpleABI, err := mocksgen.SimpleMetaData.GetAbi()
+	Require(t, err)
+	beneficiaryAddress := builder.L2Info.GetAddress("Beneficiary")
+
+	rpcClient := builder.L2.ConsensusNode.Stack.Attach()
+	events := make(chan *gethexec.RetryableEvent, 16)
+	filter := &gethexec.RetryableEventsFilter{Beneficiaries: []common.Address{beneficiaryAddress}}
+	sub, err := rpcClient.EthSubscribe(ctx, events, "retryableEvents", filter)
+	Require(t, err)
+	defer sub.Unsubscribe()
+	// Events for other beneficiaries are filtered out
+	otherEvents := make(chan *gethexec.RetryableEvent, 16)
+	otherFilter := &gethexec.RetryableEventsFilter{Beneficiaries: []common.Address{builder.L2Info.GetAddress("User2")}}
+	otherSub, err := rpcClient.EthSubscribe(ctx, otherEvents, "retryableEvents", otherFilter)
+	Require(t, err)
+	defer otherSub.Unsubscribe()
+
+	l1tx, err := delayedInbox.CreateRetryableTicket(
+		&usertxopts,
+		simpleAddr,
+		common.Big0,
+		big.NewInt(1e16),
+		beneficiaryAddress,
+		beneficiaryAddress,
+		// send enough L2 gas for intrinsic but not compute, so the auto-redeem fails
+		big.NewInt(int64(params.TxGas+params.TxDataNonZeroGasEIP2028*4)),
+		big.NewInt(l2pricing.InitialBaseFeeWei*2),
+		simpleABI.Methods["incrementRedeem"].ID,
+	)
+	Require(t, err)
+	l1Receipt, err := builder.L1.EnsureTxSucceeded(l1tx)
+	Require(t, err)
+	waitForL1DelayBlocks(t, ctx, builder)
+	receipt, err := builder.L2.EnsureTxSucceeded(lookupL2Tx(l1Receipt))
+	Require(t, err)
+	ticketId := receipt.Logs[0].Topics[1]
+	autoRedeemTxId := receipt.Logs[1].Topics[2]
+
+	arbRetryableTx, err := precompilesgen.NewArbRetryableTx(types.ArbRetryableTxAddress, builder.L2.Client)
+	Require(t, err)
+	tx, err := arbRetryableTx.Redeem(&ownerTxOpts, ticketId)
+	Require(t, err)
+	receipt, err = builder.L2.EnsureTxSucceeded(tx)
+	Require(t, err)
+	manualRedeemTxId := receipt.Logs[0].Topics[2]
+
+	expected := []struct {
+		eventType string
+		txHash    common.Hash
+	}{
+		{gethexec.RetryableCreated, ticketId},
+		{gethexec.RetryableAutoRedeemFailed, autoRedeemTxId},
+		{gethexec.RetryableManualRedeemSucceeded, manualRedeemTxId},
+	}
+	for _, want := range expected {
+		select {
+		case event := <-events:
+			if event.Type != want.eventType || event.TicketId != ticketId {
+				Fatal(t, "expected", want.eventType, "event for ticket", ticketId, "but got", event.Type, "for", event.TicketId)
+			}
+			if event.TxHash == nil || *event.TxHash != want.txHash {
+				Fatal(t, "event", event.Type, "has transaction", event.TxHash, "but expected", want.txHash)
+			}
+			if event.Beneficiary != beneficiaryAddress || event.Destination == nil || *event.Destination != simpleAddr {
+				Fatal(t, "event", event.Type, "has beneficiary", event.Beneficiary, "and destination", event.Destination)
+			}
+		case err := <-sub.Err():
+			Fatal(t, "subscription failed", err)
+		case <-time.After(time.Second * 5):
+			Fatal(t, "timed out waiting for", want.eventType, "event")
+		}
+	}
+	select {
+	case event := <-otherEvents:
+		Fatal(t, "filtered subscription got event", event.Type, "for beneficiary", event.Beneficiary)
+	default:
+	}
+}
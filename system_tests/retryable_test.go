@@ -31,7 +31,7 @@ import (
 	"github.com/offchainlabs/nitro/util/colors"
 )
 
-func retryableSetup(t *testing.T) (
+func retryableSetup(t *testing.T, modifyNodeConfig ...func(*NodeBuilder)) (
 	*NodeBuilder,
 	*bridgegen.Inbox,
 	func(*types.Receipt) *types.Transaction,
@@ -40,6 +40,9 @@ func retryableSetup(t *testing.T) (
 ) {
 	ctx, cancel := context.WithCancel(context.Background())
 	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	for _, f := range modifyNodeConfig {
+		f(builder)
+	}
 	builder.Build(t)
 
 	builder.L2Info.GenerateAccount("User2")
@@ -944,3 +947,356 @@ func setupFeeAddresses(t *testing.T, ctx context.Context, builder *NodeBuilder)
 	t.Log("Network fee account: ", networkFeeAccount)
 	return infraFeeAddr, networkFeeAddr
 }
+
+// withAutoRedeemConfig runs the chain at the first ArbOS version the chain owner can configure auto-redeems in
+func withAutoRedeemConfig(builder *NodeBuilder) {
+	builder.chainConfig.ArbitrumChainParams.InitialArbOSVersion = arbostypes.ArbosVersion_AutoRedeemConfig
+}
+
+func setAutoRedeemConfig(t *testing.T, ctx context.Context, builder *NodeBuilder, attempts uint64, gasCeiling uint64, refundToBeneficiary bool) {
+	ownerTxOpts := builder.L2Info.GetDefaultTransactOpts("Owner", ctx)
+	arbOwner, err := precompilesgen.NewArbOwner(common.HexToAddress("70"), builder.L2.Client)
+	Require(t, err)
+	tx, err := arbOwner.SetRetryableAutoRedeemConfig(&ownerTxOpts, attempts, gasCeiling, refundToBeneficiary)
+	Require(t, err)
+	_, err = builder.L2.EnsureTxSucceeded(tx)
+	Require(t, err)
+}
+
+// submitAutoRedeemed submits a retryable whose deposit exactly covers its max submission fee and gas,
+// refunding "FeeRefund" and with "Beneficiary" as its beneficiary
+func submitAutoRedeemed(
+	t *testing.T,
+	ctx context.Context,
+	builder *NodeBuilder,
+	delayedInbox *bridgegen.Inbox,
+	lookupL2Tx func(*types.Receipt) *types.Transaction,
+	to common.Address,
+	gas uint64,
+	data []byte,
+) (*types.ArbitrumSubmitRetryableTx, *types.Receipt) {
+	maxSubmissionFee := big.NewInt(1e16)
+	maxFeePerGas := arbmath.BigMulByUint(builder.L2.GetBaseFee(t), 2)
+	usertxopts := builder.L1Info.GetDefaultTransactOpts("Faucet", ctx)
+	usertxopts.Value = arbmath.BigAdd(maxSubmissionFee, arbmath.BigMulByUint(maxFeePerGas, gas))
+	l1tx, err := delayedInbox.CreateRetryableTicket(
+		&usertxopts,
+		to,
+		common.Big0,
+		maxSubmissionFee,
+		builder.L2Info.GetAddress("FeeRefund"),
+		builder.L2Info.GetAddress("Beneficiary"),
+		arbmath.UintToBig(gas),
+		maxFeePerGas,
+		data,
+	)
+	Require(t, err)
+	l1Receipt, err := builder.L1.EnsureTxSucceeded(l1tx)
+	Require(t, err)
+	if l1Receipt.Status != types.ReceiptStatusSuccessful {
+		Fatal(t, "l1Receipt indicated failure")
+	}
+
+	waitForL1DelayBlocks(t, ctx, builder)
+
+	submissionTxOuter := lookupL2Tx(l1Receipt)
+	submissionReceipt, err := builder.L2.EnsureTxSucceeded(submissionTxOuter)
+	Require(t, err)
+	submissionTx, ok := submissionTxOuter.GetInner().(*types.ArbitrumSubmitRetryableTx)
+	if !ok {
+		Fatal(t, "inner tx isn't ArbitrumSubmitRetryableTx")
+	}
+	return submissionTx, submissionReceipt
+}
+
+type autoRedeemAttempt struct {
+	scheduled *precompilesgen.ArbRetryableTxRedeemScheduled
+	tx        *types.ArbitrumRetryTx
+	receipt   *types.Receipt
+}
+
+// autoRedeemAttempts follows the RedeemScheduled events from the submission through every attempt at its auto-redeem
+func autoRedeemAttempts(t *testing.T, ctx context.Context, builder *NodeBuilder, submissionReceipt *types.Receipt) []autoRedeemAttempt {
+	arbRetryableTx, err := precompilesgen.NewArbRetryableTx(types.ArbRetryableTxAddress, builder.L2.Client)
+	Require(t, err)
+	arbRetryableABI, err := precompilesgen.ArbRetryableTxMetaData.GetAbi()
+	Require(t, err)
+	redeemScheduledID := arbRetryableABI.Events["RedeemScheduled"].ID
+
+	var attempts []autoRedeemAttempt
+	logs := submissionReceipt.Logs
+	for {
+		var scheduled *precompilesgen.ArbRetryableTxRedeemScheduled
+		for _, eventLog := range logs {
+			if eventLog.Address != types.ArbRetryableTxAddress || eventLog.Topics[0] != redeemScheduledID {
+				continue
+			}
+			if scheduled != nil {
+				Fatal(t, "more than one redeem scheduled by tx", eventLog.TxHash)
+			}
+			scheduled, err = arbRetryableTx.ParseRedeemScheduled(*eventLog)
+			Require(t, err)
+		}
+		if scheduled == nil {
+			return attempts
+		}
+		receipt, err := WaitForTx(ctx, builder.L2.Client, scheduled.RetryTxHash, time.Second*5)
+		Require(t, err)
+		txOuter, _, err := builder.L2.Client.TransactionByHash(ctx, scheduled.RetryTxHash)
+		Require(t, err)
+		tx, ok := txOuter.GetInner().(*types.ArbitrumRetryTx)
+		if !ok {
+			Fatal(t, "inner tx isn't ArbitrumRetryTx")
+		}
+		attempts = append(attempts, autoRedeemAttempt{scheduled, tx, receipt})
+		logs = receipt.Logs
+	}
+}
+
+func balancesAt(t *testing.T, ctx context.Context, builder *NodeBuilder, addresses ...common.Address) []*big.Int {
+	balances := make([]*big.Int, 0, len(addresses))
+	for _, address := range addresses {
+		balance, err := builder.L2.Client.BalanceAt(ctx, address, nil)
+		Require(t, err)
+		balances = append(balances, balance)
+	}
+	return balances
+}
+
+// requireAutoRedeemFees checks the fee accounts kept the gas used by every attempt at the submission's base fee,
+// and the submission fee unless the auto-redeem succeeded, and that the rest of the deposit was refunded
+func requireAutoRedeemFees(
+	t *testing.T,
+	ctx context.Context,
+	builder *NodeBuilder,
+	submissionTx *types.ArbitrumSubmitRetryableTx,
+	submissionReceipt *types.Receipt,
+	attempts []autoRedeemAttempt,
+	infraFeeAddr, networkFeeAddr, refundAddr common.Address,
+	before []*big.Int,
+) {
+	after := balancesAt(t, ctx, builder, infraFeeAddr, networkFeeAddr, refundAddr, submissionTx.From)
+	infraFee := arbmath.BigSub(after[0], before[0])
+	networkFee := arbmath.BigSub(after[1], before[1])
+	refunded := arbmath.BigSub(after[2], before[2])
+	if !arbmath.BigEquals(after[3], before[3]) {
+		Fatal(t, "retryable sender kept", arbmath.BigSub(after[3], before[3]), "of the deposit")
+	}
+
+	arbGasInfo, err := precompilesgen.NewArbGasInfo(common.HexToAddress("0x6c"), builder.L2.Client)
+	Require(t, err)
+	minimumBaseFee, err := arbGasInfo.GetMinimumGasPrice(&bind.CallOpts{Context: ctx})
+	Require(t, err)
+	baseFee := builder.L2.GetBaseFeeAt(t, submissionReceipt.BlockNumber)
+	var gasUsed uint64
+	for _, attempt := range attempts {
+		gasUsed += attempt.receipt.GasUsed
+	}
+	expectedInfraFee := arbmath.BigMulByUint(arbmath.BigMin(minimumBaseFee, baseFee), gasUsed)
+	expectedNetworkFee := arbmath.BigSub(arbmath.BigMulByUint(baseFee, gasUsed), expectedInfraFee)
+	if attempts[len(attempts)-1].receipt.Status != types.ReceiptStatusSuccessful {
+		submissionFee := retryables.RetryableSubmissionFee(len(submissionTx.RetryData), submissionTx.L1BaseFee)
+		expectedNetworkFee.Add(expectedNetworkFee, submissionFee)
+	}
+	expectedRefund := arbmath.BigSub(submissionTx.DepositValue, arbmath.BigAdd(expectedInfraFee, expectedNetworkFee))
+
+	if !arbmath.BigEquals(infraFee, expectedInfraFee) {
+		Fatal(t, "Unexpected infra fee paid by the auto-redeem, want:", expectedInfraFee, "have:", infraFee)
+	}
+	if !arbmath.BigEquals(networkFee, expectedNetworkFee) {
+		Fatal(t, "Unexpected network fee paid by the auto-redeem, want:", expectedNetworkFee, "have:", networkFee)
+	}
+	if !arbmath.BigEquals(refunded, expectedRefund) {
+		Fatal(t, "Unexpected refund of the auto-redeem, want:", expectedRefund, "have:", refunded)
+	}
+}
+
+func TestRetryableAutoRedeemConfigArbOSVersion(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, arbosVersion := range []uint64{arbostypes.ArbosVersion_AutoRedeemConfig - 1, arbostypes.ArbosVersion_AutoRedeemConfig} {
+		builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+		builder.chainConfig.ArbitrumChainParams.InitialArbOSVersion = arbosVersion
+		cleanup := builder.Build(t)
+
+		ownerTxOpts := builder.L2Info.GetDefaultTransactOpts("Owner", ctx)
+		callOpts := &bind.CallOpts{Context: ctx}
+		arbOwner, err := precompilesgen.NewArbOwner(common.HexToAddress("70"), builder.L2.Client)
+		Require(t, err)
+		arbOwnerPublic, err := precompilesgen.NewArbOwnerPublic(common.HexToAddress("6b"), builder.L2.Client)
+		Require(t, err)
+
+		if arbosVersion < arbostypes.ArbosVersion_AutoRedeemConfig {
+			if _, err := arbOwner.SetRetryableAutoRedeemConfig(&ownerTxOpts, 2, 0, false); err == nil {
+				Fatal(t, "configured auto-redeems in ArbOS version", arbosVersion)
+			}
+			if _, _, _, err := arbOwnerPublic.GetRetryableAutoRedeemConfig(callOpts); err == nil {
+				Fatal(t, "read the auto-redeem config in ArbOS version", arbosVersion)
+			}
+			cleanup()
+			continue
+		}
+
+		attempts, gasCeiling, refundToBeneficiary, err := arbOwnerPublic.GetRetryableAutoRedeemConfig(callOpts)
+		Require(t, err)
+		if attempts != 1 || gasCeiling != 0 || refundToBeneficiary {
+			Fatal(t, "unexpected default auto-redeem config", attempts, gasCeiling, refundToBeneficiary)
+		}
+		// a ceiling below the intrinsic gas would skip every auto-redeem
+		if _, err := arbOwner.SetRetryableAutoRedeemConfig(&ownerTxOpts, 1, params.TxGas-1, false); err == nil {
+			Fatal(t, "set an auto-redeem gas ceiling below the intrinsic gas")
+		}
+		if _, err := arbOwner.SetRetryableAutoRedeemConfig(&ownerTxOpts, retryables.MaxAutoRedeemAttempts+1, 0, false); err == nil {
+			Fatal(t, "set more auto-redeem attempts than the maximum")
+		}
+		tx, err := arbOwner.SetRetryableAutoRedeemConfig(&ownerTxOpts, 3, params.TxGas, true)
+		Require(t, err)
+		_, err = builder.L2.EnsureTxSucceeded(tx)
+		Require(t, err)
+		attempts, gasCeiling, refundToBeneficiary, err = arbOwnerPublic.GetRetryableAutoRedeemConfig(callOpts)
+		Require(t, err)
+		if attempts != 3 || gasCeiling != params.TxGas || !refundToBeneficiary {
+			Fatal(t, "unexpected auto-redeem config", attempts, gasCeiling, refundToBeneficiary)
+		}
+		cleanup()
+	}
+}
+
+func TestRetryableAutoRedeemGasCeiling(t *testing.T) {
+	t.Parallel()
+	builder, delayedInbox, lookupL2Tx, ctx, teardown := retryableSetup(t, withAutoRedeemConfig)
+	defer teardown()
+	infraFeeAddr, networkFeeAddr := setupFeeAddresses(t, ctx, builder)
+	builder.L2Info.GenerateAccount("FeeRefund")
+	feeRefundAddr := builder.L2Info.GetAddress("FeeRefund")
+
+	ownerTxOpts := builder.L2Info.GetDefaultTransactOpts("Owner", ctx)
+	simpleAddr, simple := builder.L2.DeploySimple(t, ownerTxOpts)
+	simpleABI, err := mocksgen.SimpleMetaData.GetAbi()
+	Require(t, err)
+
+	gasCeiling := uint64(200_000)
+	setAutoRedeemConfig(t, ctx, builder, 1, gasCeiling, false)
+
+	before := balancesAt(t, ctx, builder, infraFeeAddr, networkFeeAddr, feeRefundAddr, util.RemapL1Address(builder.L1Info.GetAddress("Faucet")))
+	submissionTx, submissionReceipt := submitAutoRedeemed(t, ctx, builder, delayedInbox, lookupL2Tx, simpleAddr, 5*gasCeiling, simpleABI.Methods["incrementRedeem"].ID)
+
+	attempts := autoRedeemAttempts(t, ctx, builder, submissionReceipt)
+	if len(attempts) != 1 {
+		Fatal(t, "auto-redeem attempted", len(attempts), "times")
+	}
+	attempt := attempts[0]
+	if attempt.receipt.Status != types.ReceiptStatusSuccessful {
+		Fatal(t, "auto-redeem failed")
+	}
+	if attempt.scheduled.DonatedGas != gasCeiling || attempt.tx.Gas != gasCeiling {
+		Fatal(t, "auto-redeem was given", attempt.tx.Gas, "gas, with", attempt.scheduled.DonatedGas, "donated, expected the ceiling of", gasCeiling)
+	}
+	if attempt.scheduled.GasDonor != feeRefundAddr || attempt.tx.RefundTo != feeRefundAddr {
+		Fatal(t, "auto-redeem refunds", attempt.tx.RefundTo, "expected the fee refund address", feeRefundAddr)
+	}
+	counter, err := simple.Counter(&bind.CallOpts{})
+	Require(t, err)
+	if counter != 1 {
+		Fatal(t, "Unexpected counter:", counter)
+	}
+
+	// the gas above the ceiling is refunded along with the gas the auto-redeem didn't use
+	requireAutoRedeemFees(t, ctx, builder, submissionTx, submissionReceipt, attempts, infraFeeAddr, networkFeeAddr, feeRefundAddr, before)
+}
+
+func TestRetryableAutoRedeemRefundToBeneficiary(t *testing.T) {
+	t.Parallel()
+	builder, delayedInbox, lookupL2Tx, ctx, teardown := retryableSetup(t, withAutoRedeemConfig)
+	defer teardown()
+	infraFeeAddr, networkFeeAddr := setupFeeAddresses(t, ctx, builder)
+	builder.L2Info.GenerateAccount("FeeRefund")
+	feeRefundAddr := builder.L2Info.GetAddress("FeeRefund")
+	beneficiaryAddr := builder.L2Info.GetAddress("Beneficiary")
+
+	ownerTxOpts := builder.L2Info.GetDefaultTransactOpts("Owner", ctx)
+	simpleAddr, _ := builder.L2.DeploySimple(t, ownerTxOpts)
+	simpleABI, err := mocksgen.SimpleMetaData.GetAbi()
+	Require(t, err)
+
+	setAutoRedeemConfig(t, ctx, builder, 1, 0, true)
+
+	feeRefundBefore := balancesAt(t, ctx, builder, feeRefundAddr)[0]
+	before := balancesAt(t, ctx, builder, infraFeeAddr, networkFeeAddr, beneficiaryAddr, util.RemapL1Address(builder.L1Info.GetAddress("Faucet")))
+	submissionTx, submissionReceipt := submitAutoRedeemed(t, ctx, builder, delayedInbox, lookupL2Tx, simpleAddr, 1_000_000, simpleABI.Methods["incrementRedeem"].ID)
+
+	attempts := autoRedeemAttempts(t, ctx, builder, submissionReceipt)
+	if len(attempts) != 1 {
+		Fatal(t, "auto-redeem attempted", len(attempts), "times")
+	}
+	attempt := attempts[0]
+	if attempt.receipt.Status != types.ReceiptStatusSuccessful {
+		Fatal(t, "auto-redeem failed")
+	}
+	if attempt.scheduled.GasDonor != beneficiaryAddr || attempt.tx.RefundTo != beneficiaryAddr {
+		Fatal(t, "auto-redeem refunds", attempt.tx.RefundTo, "expected the beneficiary", beneficiaryAddr)
+	}
+
+	// only the excess submission fee is still refunded to the fee refund address, as it's refunded before the auto-redeem
+	submissionFee := retryables.RetryableSubmissionFee(len(submissionTx.RetryData), submissionTx.L1BaseFee)
+	submissionFeeRefund := arbmath.BigSub(submissionTx.MaxSubmissionFee, submissionFee)
+	feeRefunded := arbmath.BigSub(balancesAt(t, ctx, builder, feeRefundAddr)[0], feeRefundBefore)
+	if !arbmath.BigEquals(feeRefunded, submissionFeeRefund) {
+		Fatal(t, "fee refund address was refunded", feeRefunded, "expected the excess submission fee", submissionFeeRefund)
+	}
+	before[2].Add(before[2], submissionFeeRefund)
+	requireAutoRedeemFees(t, ctx, builder, submissionTx, submissionReceipt, attempts, infraFeeAddr, networkFeeAddr, beneficiaryAddr, before)
+}
+
+func TestRetryableAutoRedeemAttempts(t *testing.T) {
+	t.Parallel()
+	builder, delayedInbox, lookupL2Tx, ctx, teardown := retryableSetup(t, withAutoRedeemConfig)
+	defer teardown()
+	infraFeeAddr, networkFeeAddr := setupFeeAddresses(t, ctx, builder)
+	builder.L2Info.GenerateAccount("FeeRefund")
+	feeRefundAddr := builder.L2Info.GetAddress("FeeRefund")
+
+	ownerTxOpts := builder.L2Info.GetDefaultTransactOpts("Owner", ctx)
+	simpleAddr, _ := builder.L2.DeploySimple(t, ownerTxOpts)
+	simpleABI, err := mocksgen.SimpleMetaData.GetAbi()
+	Require(t, err)
+
+	configuredAttempts := 3
+	setAutoRedeemConfig(t, ctx, builder, uint64(configuredAttempts), 0, false)
+
+	before := balancesAt(t, ctx, builder, infraFeeAddr, networkFeeAddr, feeRefundAddr, util.RemapL1Address(builder.L1Info.GetAddress("Faucet")))
+	gas := uint64(1_000_000)
+	submissionTx, submissionReceipt := submitAutoRedeemed(t, ctx, builder, delayedInbox, lookupL2Tx, simpleAddr, gas, simpleABI.Methods["pleaseRevert"].ID)
+
+	// every attempt reverts with gas left, so each is attempted again with the gas the one before left
+	attempts := autoRedeemAttempts(t, ctx, builder, submissionReceipt)
+	if len(attempts) != configuredAttempts {
+		Fatal(t, "auto-redeem attempted", len(attempts), "times, expected", configuredAttempts)
+	}
+	ticketId := attempts[0].scheduled.TicketId
+	for i, attempt := range attempts {
+		if attempt.receipt.Status != types.ReceiptStatusFailed {
+			Fatal(t, "reverting auto-redeem attempt", i, "succeeded")
+		}
+		if attempt.scheduled.TicketId != ticketId || attempt.scheduled.SequenceNum != uint64(i) || attempt.tx.Nonce != uint64(i) {
+			Fatal(t, "attempt", i, "redeems ticket", attempt.scheduled.TicketId, "with sequence number", attempt.scheduled.SequenceNum)
+		}
+		if attempt.scheduled.DonatedGas != gas || attempt.tx.Gas != gas {
+			Fatal(t, "attempt", i, "was given", attempt.tx.Gas, "gas, with", attempt.scheduled.DonatedGas, "donated, expected", gas)
+		}
+		if attempt.tx.RefundTo != feeRefundAddr {
+			Fatal(t, "attempt", i, "refunds", attempt.tx.RefundTo, "expected the fee refund address", feeRefundAddr)
+		}
+		gas -= attempt.receipt.GasUsed
+	}
+
+	// the retryable outlives its failed auto-redeem attempts
+	arbRetryableTx, err := precompilesgen.NewArbRetryableTx(types.ArbRetryableTxAddress, builder.L2.Client)
+	Require(t, err)
+	_, err = arbRetryableTx.GetTimeout(&bind.CallOpts{Context: ctx}, ticketId)
+	Require(t, err)
+
+	requireAutoRedeemFees(t, ctx, builder, submissionTx, submissionReceipt, attempts, infraFeeAddr, networkFeeAddr, feeRefundAddr, before)
+}
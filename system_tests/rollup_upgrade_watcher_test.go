@@ -0,0 +1,87 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/solgen/go/rollupgen"
+	"github.com/offchainlabs/nitro/solgen/go/upgrade_executorgen"
+)
+
+func TestRollupUpgradeWatcher(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	builder.nodeConfig.UpgradeWatcher.Enable = true
+	builder.nodeConfig.UpgradeWatcher.PollInterval = time.Millisecond * 50
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	watcher := builder.L2.ConsensusNode.RollupUpgradeWatcher
+	deployInfo := builder.L2.ConsensusNode.DeployInfo
+	waitFor := func(cond func(*arbnode.RollupParameters) bool) *arbnode.RollupParameters {
+		t.Helper()
+		for i := 0; i < 200; i++ {
+			params := watcher.Parameters()
+			if params != nil && cond(params) {
+				return params
+			}
+			time.Sleep(time.Millisecond * 50)
+		}
+		Fatal(t, "timed out waiting for rollup upgrade watcher")
+		return nil
+	}
+
+	params := waitFor(func(*arbnode.RollupParameters) bool { return true })
+	if params.SequencerInbox != deployInfo.SequencerInbox || params.Bridge != deployInfo.Bridge {
+		Fatal(t, "unexpected rollup parameters", params)
+	}
+	if watcher.RestartRequired() {
+		Fatal(t, "restart required without an upgrade")
+	}
+
+	changed := make(chan *arbnode.RollupParameters, 1)
+	watcher.OnChange(func(_, new *arbnode.RollupParameters) {
+		select {
+		case changed <- new:
+		default:
+		}
+	})
+
+	newRoot := common.HexToHash("0x1234")
+	rollupABI, err := abi.JSON(strings.NewReader(rollupgen.RollupAdminLogicABI))
+	Require(t, err)
+	calldata, err := rollupABI.Pack("setWasmModuleRoot", newRoot)
+	Require(t, err)
+	upgradeExecutor, err := upgrade_executorgen.NewUpgradeExecutor(deployInfo.UpgradeExecutor, builder.L1.Client)
+	Require(t, err)
+	ownerOpts := builder.L1Info.GetDefaultTransactOpts("RollupOwner", ctx)
+	tx, err := upgradeExecutor.ExecuteCall(&ownerOpts, deployInfo.Rollup, calldata)
+	Require(t, err)
+	_, err = builder.L1.EnsureTxSucceeded(tx)
+	Require(t, err)
+
+	waitFor(func(p *arbnode.RollupParameters) bool { return p.WasmModuleRoot == newRoot })
+	select {
+	case params = <-changed:
+		if params.WasmModuleRoot != newRoot {
+			Fatal(t, "change handler got wasm module root", params.WasmModuleRoot, "expected", newRoot)
+		}
+	case <-time.After(time.Second * 10):
+		Fatal(t, "change handler wasn't called")
+	}
+	if watcher.RestartRequired() {
+		Fatal(t, "restart required after a wasm module root change")
+	}
+}
@@ -75,3 +75,76 @@ func TestSequencerPause(t *testing.T) {
 		Require(t, err)
 	}
 }
+
+func TestSequencerOperatorPause(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	sequencer := builder.L2.ExecNode.Sequencer
+	if sequencer == nil {
+		t.Fatal("sequencer not found on node")
+	}
+
+	builder.L2Info.GenerateAccount("User")
+	sequencerApi := gethexec.NewArbSequencerAPI(sequencer)
+	sequencerApi.PauseSequencer()
+	if !sequencerApi.SequencerPaused() {
+		t.Fatal("sequencer should report being paused")
+	}
+
+	tx := builder.L2Info.PrepareTx("Owner", "User", builder.L2Info.TransferGas, big.NewInt(1e16), nil)
+	go func() {
+		err := sequencer.PublishTransaction(ctx, tx, nil)
+		Require(t, err)
+	}()
+
+	_, err := builder.L2.EnsureTxSucceededWithTimeout(tx, time.Second)
+	if err == nil {
+		t.Error("tx passed while sequencing paused")
+	}
+
+	sequencerApi.ResumeSequencer()
+	if sequencerApi.SequencerPaused() {
+		t.Fatal("sequencer should report being resumed")
+	}
+	_, err = builder.L2.EnsureTxSucceeded(tx)
+	Require(t, err)
+}
+
+func TestSequencerOperatorPauseOutlastsQueueTimeout(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	builder.execConfig.Sequencer.QueueTimeout = 100 * time.Millisecond
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	sequencer := builder.L2.ExecNode.Sequencer
+	builder.L2Info.GenerateAccount("User")
+	sequencer.PauseSequencing()
+
+	tx := builder.L2Info.PrepareTx("Owner", "User", builder.L2Info.TransferGas, big.NewInt(1e16), nil)
+	published := make(chan error, 1)
+	go func() {
+		published <- sequencer.PublishTransaction(ctx, tx, nil)
+	}()
+
+	// stay paused for several times the queue timeout; the transaction must still be queued afterwards
+	select {
+	case err := <-published:
+		Fatal(t, "transaction returned while sequencing was paused:", err)
+	case <-time.After(time.Second):
+	}
+
+	sequencer.ResumeSequencing()
+	Require(t, <-published)
+	_, err := builder.L2.EnsureTxSucceeded(tx)
+	Require(t, err)
+}
@@ -146,3 +146,42 @@ func TestSequencerRejection(t *testing.T) {
 		}
 	}
 }
+
+func TestSequencerRejectsOversizedTx(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	builder.execConfig.Sequencer.MaxTxDataSize = 2000
+	builder.execConfig.Sequencer.MaxTxCalldataSize = 1000
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	builder.L2Info.GenerateAccount("User")
+	send := func(dataSize int) error {
+		data := make([]byte, dataSize)
+		gas := builder.L2Info.TransferGas + uint64(dataSize)*params.TxDataNonZeroGasEIP2028
+		tx := builder.L2Info.PrepareTx("Owner", "User", gas, common.Big1, data)
+		err := builder.L2.Client.SendTransaction(ctx, tx)
+		if err == nil {
+			_, err = builder.L2.EnsureTxSucceeded(tx)
+			Require(t, err)
+		} else {
+			// the transaction wasn't sequenced, so reuse its nonce
+			builder.L2Info.GetInfoWithPrivKey("Owner").Nonce--
+		}
+		return err
+	}
+
+	Require(t, send(1000))
+	err := send(1001)
+	if err == nil || !strings.Contains(err.Error(), "calldata size 1001 exceeds the sequencer's limit of 1000 bytes") {
+		Fatal(t, "expected the sequencer to reject oversized calldata but got", err)
+	}
+	err = send(3000)
+	if err == nil || !strings.Contains(err.Error(), "exceeds the sequencer's limit of 2000 bytes") {
+		Fatal(t, "expected the sequencer to reject an oversized transaction but got", err)
+	}
+	Require(t, send(500))
+}
@@ -0,0 +1,104 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/execution/gethexec"
+)
+
+func TestSequencedTransactionsSubscription(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	rpcClient := builder.L2.ConsensusNode.Stack.Attach()
+	sequenced := make(chan *gethexec.SequencedTransaction, 16)
+	sub, err := rpcClient.Subscribe(ctx, "arb", sequenced, "sequencedTransactions")
+	Require(t, err)
+	defer sub.Unsubscribe()
+
+	builder.L2Info.GenerateAccount("User")
+	tx := builder.L2Info.PrepareTx("Owner", "User", builder.L2Info.TransferGas, big.NewInt(1e12), nil)
+	err = builder.L2.Client.SendTransaction(ctx, tx)
+	Require(t, err)
+	receipt, err := builder.L2.EnsureTxSucceeded(tx)
+	Require(t, err)
+
+	select {
+	case notification := <-sequenced:
+		if notification.TxHash != tx.Hash() {
+			Fatal(t, "notified of transaction", notification.TxHash, "but sent", tx.Hash())
+		}
+		if uint64(notification.BlockNumber) != receipt.BlockNumber.Uint64() || notification.BlockHash != receipt.BlockHash {
+			Fatal(t, "notification has the wrong block", notification.BlockNumber, notification.BlockHash)
+		}
+		if uint64(notification.Status) != receipt.Status || uint64(notification.GasUsed) != receipt.GasUsed {
+			Fatal(t, "notification doesn't match the receipt", notification.Status, notification.GasUsed)
+		}
+	case err := <-sub.Err():
+		Fatal(t, "subscription failed", err)
+	case <-time.After(time.Second * 5):
+		Fatal(t, "timed out waiting for sequenced transaction notification")
+	}
+}
+
+func TestSequencedTransactionsSubscriptionDropped(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	builder.execConfig.Sequencer.MaxTxAge = time.Millisecond * 200
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	rpcClient := builder.L2.ConsensusNode.Stack.Attach()
+	notifications := make(chan *gethexec.SequencedTransaction, 16)
+	sub, err := rpcClient.Subscribe(ctx, "arb", notifications, "sequencedTransactions")
+	Require(t, err)
+	defer sub.Unsubscribe()
+
+	sequencer := builder.L2.ExecNode.Sequencer
+	sequencer.PauseSequencing()
+	builder.L2Info.GenerateAccount("User")
+	tx := builder.L2Info.PrepareTx("Owner", "User", builder.L2Info.TransferGas, big.NewInt(1e12), nil)
+	result := make(chan error, 1)
+	go func() {
+		result <- sequencer.PublishTransaction(ctx, tx, nil)
+	}()
+	time.Sleep(time.Millisecond * 500)
+	sequencer.ResumeSequencing()
+
+	if err := <-result; !errors.Is(err, gethexec.ErrTxExpiredInQueue) {
+		Fatal(t, "expected the transaction to expire, got", err)
+	}
+	select {
+	case notification := <-notifications:
+		if notification.TxHash != tx.Hash() {
+			Fatal(t, "notified of transaction", notification.TxHash, "but sent", tx.Hash())
+		}
+		if !notification.Dropped || notification.Reason != gethexec.ErrTxExpiredInQueue.Error() {
+			Fatal(t, "expected notice of the transaction being dropped, got", notification.Dropped, notification.Reason)
+		}
+		if notification.BlockHash != (common.Hash{}) {
+			Fatal(t, "dropped transaction notification has a block", notification.BlockHash)
+		}
+	case err := <-sub.Err():
+		Fatal(t, "subscription failed", err)
+	case <-time.After(time.Second * 5):
+		Fatal(t, "timed out waiting for dropped transaction notification")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/offchainlabs/nitro/execution/gethexec"
+)
+
+func TestSequencerJournalReplay(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	journalFile := filepath.Join(t.TempDir(), "journal.jsonl")
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	builder.execConfig.Sequencer.Journal.Enable = true
+	builder.execConfig.Sequencer.Journal.File = journalFile
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	builder.L2Info.GenerateAccount("User")
+	for i := 0; i < 3; i++ {
+		tx := builder.L2Info.PrepareTx("Owner", "User", builder.L2Info.TransferGas, big.NewInt(1e12), nil)
+		err := builder.L2.Client.SendTransaction(ctx, tx)
+		Require(t, err)
+		_, err = builder.L2.EnsureTxSucceeded(tx)
+		Require(t, err)
+	}
+
+	file, err := os.Open(journalFile)
+	Require(t, err)
+	defer file.Close()
+	var entries []*gethexec.SequencerJournalEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		var entry gethexec.SequencerJournalEntry
+		Require(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, &entry)
+	}
+	Require(t, scanner.Err())
+	if len(entries) < 3 {
+		Fatal(t, "expected at least 3 journaled blocks but got", len(entries))
+	}
+
+	l2rpc := builder.L2.Stack.Attach()
+	for _, entry := range entries {
+		header, err := builder.L2.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(entry.BlockNumber))
+		Require(t, err)
+		if header.Hash() != entry.BlockHash {
+			Fatal(t, "journaled block", entry.BlockNumber, "hash", entry.BlockHash, "doesn't match the chain's", header.Hash())
+		}
+		if entry.OrderingPolicy != gethexec.OrderingPolicyFCFS || len(entry.Txs) == 0 {
+			Fatal(t, "unexpected journal entry", entry)
+		}
+		var result gethexec.SequencerReplayResult
+		err = l2rpc.CallContext(ctx, &result, "arbdebug_replaySequencerJournalEntry", entry)
+		Require(t, err)
+		if !result.Reproduced || len(result.Mismatches) != 0 {
+			Fatal(t, "replaying block", entry.BlockNumber, "got", result.BlockHash, "with mismatches", result.Mismatches)
+		}
+	}
+
+	entry := entries[len(entries)-1]
+	entry.Header.Timestamp++
+	var result gethexec.SequencerReplayResult
+	err = l2rpc.CallContext(ctx, &result, "arbdebug_replaySequencerJournalEntry", entry)
+	Require(t, err)
+	if result.Reproduced {
+		Fatal(t, "replaying a block with a different timestamp reproduced it")
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/execution/gethexec"
+	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
+)
+
+func TestSnapshotSessions(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	builder.execConfig.SnapshotSessions.Enable = true
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	l2rpc := builder.L2.Stack.Attach()
+	builder.L2Info.GenerateAccount("User")
+	user := builder.L2Info.GetAddress("User")
+	builder.L2.TransferBalance(t, "Owner", "User", big.NewInt(1e12), builder.L2Info)
+
+	var session gethexec.SnapshotSessionInfo
+	err := l2rpc.CallContext(ctx, &session, "arb_openSnapshot", rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	Require(t, err)
+
+	// The chain advancing doesn't change what the session sees
+	builder.L2.TransferBalance(t, "Owner", "User", big.NewInt(1e12), builder.L2Info)
+	builder.L2.TransferBalance(t, "Owner", "User", big.NewInt(1e12), builder.L2Info)
+
+	var balance hexutil.Big
+	err = l2rpc.CallContext(ctx, &balance, "arb_snapshotBalance", session.Id, user)
+	Require(t, err)
+	if balance.ToInt().Cmp(big.NewInt(1e12)) != 0 {
+		Fatal(t, "snapshot balance", balance.ToInt(), "expected", 1e12)
+	}
+	var nonce hexutil.Uint64
+	err = l2rpc.CallContext(ctx, &nonce, "arb_snapshotNonce", session.Id, builder.L2Info.GetAddress("Owner"))
+	Require(t, err)
+	latestNonce, err := builder.L2.Client.NonceAt(ctx, builder.L2Info.GetAddress("Owner"), nil)
+	Require(t, err)
+	if uint64(nonce)+2 != latestNonce {
+		Fatal(t, "snapshot nonce", nonce, "but latest nonce is", latestNonce)
+	}
+
+	arbSysAbi, err := precompilesgen.ArbSysMetaData.GetAbi()
+	Require(t, err)
+	calldata, err := arbSysAbi.Pack("arbBlockNumber")
+	Require(t, err)
+	var result hexutil.Bytes
+	err = l2rpc.CallContext(ctx, &result, "arb_snapshotCall", session.Id, map[string]interface{}{
+		"to":   types.ArbSysAddress,
+		"data": hexutil.Bytes(calldata),
+	})
+	Require(t, err)
+	if new(big.Int).SetBytes(result).Uint64() != uint64(session.BlockNumber) {
+		Fatal(t, "snapshot call saw block", new(big.Int).SetBytes(result), "expected", session.BlockNumber)
+	}
+
+	var closed bool
+	err = l2rpc.CallContext(ctx, &closed, "arb_closeSnapshot", session.Id)
+	Require(t, err)
+	if !closed {
+		Fatal(t, "snapshot session wasn't open")
+	}
+	err = l2rpc.CallContext(ctx, &balance, "arb_snapshotBalance", session.Id, common.Address{})
+	if err == nil {
+		Fatal(t, "read from a closed snapshot session")
+	}
+}
@@ -140,14 +140,21 @@ func stakerTestImpl(t *testing.T, faultyStaker bool, honestStakerInactive bool)
 	builder.L1.TransferBalance(t, "Faucet", "ValidatorB", balance, builder.L1Info)
 	l1authB := builder.L1Info.GetDefaultTransactOpts("ValidatorB", ctx)
 
-	valWalletAddrAPtr, err := validatorwallet.GetValidatorWalletContract(ctx, l2nodeA.DeployInfo.ValidatorWalletCreator, 0, &l1authA, l2nodeA.L1Reader, true)
+	valWalletAddrAPtr, err := validatorwallet.GetValidatorWalletContract(ctx, l2nodeA.DeployInfo.ValidatorWalletCreator, l2nodeA.DeployInfo.Rollup, 0, &l1authA, l2nodeA.L1Reader, true)
 	Require(t, err)
 	valWalletAddrA := *valWalletAddrAPtr
-	valWalletAddrCheck, err := validatorwallet.GetValidatorWalletContract(ctx, l2nodeA.DeployInfo.ValidatorWalletCreator, 0, &l1authA, l2nodeA.L1Reader, true)
+	valWalletAddrCheck, err := validatorwallet.GetValidatorWalletContract(ctx, l2nodeA.DeployInfo.ValidatorWalletCreator, l2nodeA.DeployInfo.Rollup, 0, &l1authA, l2nodeA.L1Reader, true)
 	Require(t, err)
 	if valWalletAddrA == *valWalletAddrCheck {
 		Require(t, err, "didn't cache validator wallet address", valWalletAddrA.String(), "vs", valWalletAddrCheck.String())
 	}
+	valWalletContractA, err := rollupgen.NewValidatorWallet(valWalletAddrA, builder.L1.Client)
+	Require(t, err)
+	rollupAllowed, err := valWalletContractA.AllowedExecutorDestinations(&bind.CallOpts{Context: ctx}, l2nodeA.DeployInfo.Rollup)
+	Require(t, err)
+	if !rollupAllowed {
+		Fatal(t, "created validator wallet doesn't allow its executors to call the rollup")
+	}
 
 	rollup, err := rollupgen.NewRollupAdminLogic(l2nodeA.DeployInfo.Rollup, builder.L1.Client)
 	Require(t, err)
@@ -452,6 +459,13 @@ func stakerTestImpl(t *testing.T, faultyStaker bool, honestStakerInactive bool)
 	if stakerATxs == 0 || stakerBTxs == 0 {
 		Fatal(t, "staker didn't make txs: staker A made", stakerATxs, "staker B made", stakerBTxs)
 	}
+	divergences := stakerC.AssertionDivergences()
+	if faultyStaker && len(divergences) == 0 {
+		Fatal(t, "watchtower staker didn't report the faulty staker's assertion")
+	}
+	if !faultyStaker && len(divergences) > 0 {
+		Fatal(t, "watchtower staker reported a correct assertion as incorrect", divergences[0].Node, divergences[0].Reason)
+	}
 
 	latestConfirmedNode, err := rollup.LatestConfirmed(&bind.CallOpts{})
 	Require(t, err)
@@ -0,0 +1,59 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+func TestSequencerRejectsUnderpricedTx(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	builder.execConfig.Sequencer.MinBaseFeeMultipleBips = arbmath.OneInBips
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	header, err := builder.L2.Client.HeaderByNumber(ctx, nil)
+	Require(t, err)
+	builder.L2Info.GenerateAccount("User")
+	to := builder.L2Info.GetAddress("User")
+	tx := builder.L2Info.SignTxAs("Owner", &types.DynamicFeeTx{
+		To:        &to,
+		Gas:       builder.L2Info.TransferGas,
+		GasFeeCap: new(big.Int).Sub(header.BaseFee, big.NewInt(1)),
+		Value:     big.NewInt(1),
+		Nonce:     builder.L2Info.GetInfoWithPrivKey("Owner").Nonce,
+	})
+
+	err = builder.L2.Client.SendTransaction(ctx, tx)
+	if err == nil {
+		Fatal(t, "underpriced transaction was accepted")
+	}
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		Fatal(t, "expected a structured error, got", err)
+	}
+	data, ok := dataErr.ErrorData().(map[string]interface{})
+	if !ok || data["minFeePerGas"] == nil {
+		Fatal(t, "error data is missing the minimum fee", dataErr.ErrorData())
+	}
+
+	// the sequencer itself reports the underlying geth error
+	err = builder.L2.ExecNode.Sequencer.PublishTransaction(ctx, tx, nil)
+	if !errors.Is(err, core.ErrFeeCapTooLow) {
+		Fatal(t, "expected fee cap too low error, got", err)
+	}
+}
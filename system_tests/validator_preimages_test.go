@@ -0,0 +1,86 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+func TestValidatorRecordedPreimages(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, true)
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	validatorConfig := arbnode.ConfigDefaultL1NonSequencerTest()
+	validatorConfig.BlockValidator.Enable = true
+	AddDefaultValNode(t, ctx, validatorConfig, true)
+	testClientB, cleanupB := builder.Build2ndNode(t, &SecondNodeParams{nodeConfig: validatorConfig})
+	defer cleanupB()
+
+	builder.L2Info.GenerateAccount("User2")
+	tx := builder.L2Info.PrepareTx("Owner", "User2", builder.L2Info.TransferGas, big.NewInt(1e12), nil)
+	Require(t, builder.L2.Client.SendTransaction(ctx, tx))
+	receipt, err := WaitForTx(ctx, testClientB.Client, tx.Hash(), time.Second*30)
+	Require(t, err)
+	// messageindex is same as block number here
+	msgNum := receipt.BlockNumber.Uint64()
+	if !testClientB.ConsensusNode.BlockValidator.WaitForPos(t, ctx, arbutil.MessageIndex(msgNum), getDeadlineTimeout(t, time.Minute*5)) {
+		Fatal(t, "did not validate the transfer's block")
+	}
+
+	l2rpc := testClientB.Stack.Attach()
+	var preimages []arbnode.RecordedPreimage
+	Require(t, l2rpc.CallContext(ctx, &preimages, "arbvalidator_recordedPreimages", hexutil.Uint64(msgNum)))
+	if len(preimages) == 0 {
+		Fatal(t, "no preimages were recorded for message", msgNum)
+	}
+	var keccak *arbnode.RecordedPreimage
+	for i := range preimages {
+		if preimages[i].Type == arbutil.Keccak256PreimageType {
+			keccak = &preimages[i]
+			if crypto.Keccak256Hash(preimages[i].Data) != preimages[i].Hash {
+				Fatal(t, "recorded preimage doesn't hash to", preimages[i].Hash)
+			}
+		}
+	}
+	if keccak == nil {
+		Fatal(t, "no keccak preimages were recorded")
+	}
+
+	// the preimages are all served from the entry recorded for the first request
+	cacheHits := metrics.GetOrRegisterCounter("arb/validator/preimages/cache/hit", nil)
+	hitsBefore := cacheHits.Count()
+	keccakType := uint8(arbutil.Keccak256PreimageType)
+	for _, preimageType := range []*uint8{nil, &keccakType} {
+		var preimage arbnode.RecordedPreimage
+		Require(t, l2rpc.CallContext(ctx, &preimage, "arbvalidator_preimage", hexutil.Uint64(msgNum), keccak.Hash, preimageType))
+		if preimage.Type != arbutil.Keccak256PreimageType || !bytes.Equal(preimage.Data, keccak.Data) {
+			Fatal(t, "served the wrong preimage for", keccak.Hash)
+		}
+	}
+	if hits := cacheHits.Count() - hitsBefore; hits < 2 {
+		Fatal(t, "message was recorded again, only", hits, "requests were served from the recorded entry")
+	}
+
+	var missing arbnode.RecordedPreimage
+	if l2rpc.CallContext(ctx, &missing, "arbvalidator_preimage", hexutil.Uint64(msgNum), common.Hash{}, nil) == nil {
+		Fatal(t, "served a preimage that wasn't recorded")
+	}
+}
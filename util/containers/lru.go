@@ -78,6 +78,14 @@ func (c *LruCache[K, V]) RemoveOldest() {
 	c.inner.RemoveOldest()
 }
 
+// Keys returns the keys in the cache, from oldest to newest
+func (c *LruCache[K, V]) Keys() []K {
+	if c.inner == nil {
+		return nil
+	}
+	return c.inner.Keys()
+}
+
 func (c *LruCache[K, V]) Len() int {
 	if c.inner == nil {
 		return 0
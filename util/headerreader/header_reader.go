@@ -17,12 +17,18 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
 	flag "github.com/spf13/pflag"
 )
 
+var (
+	subscribedGauge            = metrics.NewRegisteredGauge("arb/headerreader/subscribed", nil)
+	subscriptionDroppedCounter = metrics.NewRegisteredCounter("arb/headerreader/subscription/dropped", nil)
+)
+
 // A regexp matching "execution reverted" errors returned from the parent chain RPC.
 var ExecutionRevertedRegexp = regexp.MustCompile(`(?i)execution reverted|VM execution error\.?`)
 
@@ -93,7 +99,7 @@ func AddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultConfig.Enable, "enable reader connection")
 	f.Bool(prefix+".poll-only", DefaultConfig.PollOnly, "do not attempt to subscribe to header events")
 	f.Bool(prefix+".use-finality-data", DefaultConfig.UseFinalityData, "use l1 data about finalized/safe blocks")
-	f.Duration(prefix+".poll-interval", DefaultConfig.PollInterval, "interval when polling endpoint")
+	f.Duration(prefix+".poll-interval", DefaultConfig.PollInterval, "interval when polling endpoint, which is done when not subscribed to new headers, or when the subscription hasn't sent a header for this long")
 	f.Duration(prefix+".subscribe-err-interval", DefaultConfig.SubscribeErrInterval, "interval for subscribe error")
 	f.Duration(prefix+".tx-timeout", DefaultConfig.TxTimeout, "timeout when waiting for a transaction")
 	f.Duration(prefix+".old-header-timeout", DefaultConfig.OldHeaderTimeout, "warns if the latest l1 block is at least this old")
@@ -274,13 +280,26 @@ func (s *HeaderReader) broadcastLoop(ctx context.Context) {
 	nextSubscribeErr := time.Now().Add(-time.Second)
 	var errChannel <-chan error
 	pollOnlyOverride := false
+	// Set when the subscription drops, to poll right away instead of waiting for the poll interval
+	pollNow := false
 	for {
+		if clientSubscription != nil && s.config().PollOnly {
+			log.Info("poll-only set, unsubscribing from parent chain headers")
+			clientSubscription.Unsubscribe()
+			clientSubscription = nil
+			subscribedGauge.Update(0)
+		}
 		if clientSubscription != nil {
 			errChannel = clientSubscription.Err()
 		} else {
 			errChannel = nil
 		}
-		timer := time.NewTimer(s.config().PollInterval)
+		pollInterval := s.config().PollInterval
+		if pollNow {
+			pollInterval = 0
+			pollNow = false
+		}
+		timer := time.NewTimer(pollInterval)
 		select {
 		case h := <-inputChannel:
 			log.Trace("got new header from L1", "number", h.Number, "hash", h.Hash(), "header", h)
@@ -298,7 +317,10 @@ func (s *HeaderReader) broadcastLoop(ctx context.Context) {
 			}
 			if !(s.config().PollOnly || pollOnlyOverride) && clientSubscription == nil {
 				clientSubscription, err = s.client.SubscribeNewHead(ctx, inputChannel)
-				if err != nil {
+				if err == nil {
+					log.Info("subscribed to parent chain headers")
+					subscribedGauge.Update(1)
+				} else {
 					clientSubscription = nil
 					if errors.Is(err, rpc.ErrNotificationsUnsupported) {
 						pollOnlyOverride = true
@@ -315,8 +337,11 @@ func (s *HeaderReader) broadcastLoop(ctx context.Context) {
 				return
 			}
 			clientSubscription = nil
+			subscribedGauge.Update(0)
+			subscriptionDroppedCounter.Inc(1)
 			s.setError(fmt.Errorf("error in subscription to headers: %w", err))
-			log.Warn("error in subscription to headers", "err", err)
+			log.Warn("error in subscription to headers, polling until resubscribed", "err", err, "pollInterval", s.config().PollInterval)
+			pollNow = true
 			timer.Stop()
 		case <-ctx.Done():
 			timer.Stop()
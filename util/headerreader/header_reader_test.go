@@ -0,0 +1,37 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package headerreader
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestHeaderIndicatesFinalitySupport(t *testing.T) {
+	posHeader := &types.Header{Difficulty: big.NewInt(0)}
+	if !HeaderIndicatesFinalitySupport(posHeader) {
+		t.Fatal("Ethereum PoS header doesn't indicate finality support")
+	}
+
+	// Arbitrum blocks have a difficulty of 1, so a chain settling to an Arbitrum chain relies on the header info
+	arbitrumHeader := &types.Header{Difficulty: big.NewInt(1), BaseFee: big.NewInt(100_000_000)}
+	headerInfo := types.HeaderInfo{
+		SendRoot:           common.HexToHash("0x01"),
+		SendCount:          1,
+		L1BlockNumber:      1,
+		ArbOSFormatVersion: 11,
+	}
+	headerInfo.UpdateHeaderWithInfo(arbitrumHeader)
+	if !HeaderIndicatesFinalitySupport(arbitrumHeader) {
+		t.Fatal("Arbitrum header doesn't indicate finality support")
+	}
+
+	cliqueHeader := &types.Header{Difficulty: big.NewInt(1), BaseFee: big.NewInt(100_000_000)}
+	if HeaderIndicatesFinalitySupport(cliqueHeader) {
+		t.Fatal("Clique header indicates finality support")
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// Package latencytracker follows messages through the node's pipeline,
+// from a transaction arriving over RPC until its message is validated,
+// and records how long each stage takes.
+package latencytracker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+type Stage int
+
+const (
+	Received  Stage = iota // a transaction reached the sequencer
+	Sequenced              // a message was added to the transaction streamer
+	Broadcast              // a message was sent out over the feed
+	Posted                 // a message's batch was posted to the parent chain
+	Validated              // a message was validated by the block validator
+	numStages
+)
+
+var stageNames = [numStages]string{"received", "sequenced", "broadcast", "posted", "validated"}
+
+func (s Stage) String() string {
+	if s < 0 || s >= numStages {
+		return "unknown"
+	}
+	return stageNames[s]
+}
+
+// Intervals are measured from the previous stage, except for validation which is measured from sequencing
+// since a validator doesn't necessarily wait for its messages to be posted.
+var intervals = []struct{ from, to Stage }{
+	{Received, Sequenced},
+	{Sequenced, Broadcast},
+	{Sequenced, Posted},
+	{Sequenced, Validated},
+}
+
+// how many messages' timestamps to remember before forgetting the oldest
+const maxTrackedMessages = 1 << 16
+
+var histograms = make(map[[2]Stage]metrics.Histogram)
+
+func init() {
+	for _, interval := range intervals {
+		name := "arb/latency/" + interval.from.String() + "_to_" + interval.to.String()
+		histograms[[2]Stage{interval.from, interval.to}] = metrics.NewRegisteredHistogram(name, nil, metrics.NewBoundedHistogramSample())
+	}
+}
+
+type stamps [numStages]time.Time
+
+type trackedMessage struct {
+	pos    arbutil.MessageIndex
+	stamps stamps
+}
+
+type messageTracker struct {
+	mutex    sync.Mutex
+	messages []*trackedMessage // ordered by position, with at most limit entries
+	limit    int
+	next     arbutil.MessageIndex
+	posted   arbutil.MessageIndex
+	valid    arbutil.MessageIndex
+}
+
+func newMessageTracker(limit int) *messageTracker {
+	return &messageTracker{limit: limit}
+}
+
+var tracker = newMessageTracker(maxTrackedMessages)
+
+func observe(from, to Stage, start, end time.Time) {
+	histogram, ok := histograms[[2]Stage{from, to}]
+	if !ok || start.IsZero() {
+		return
+	}
+	histogram.Update(end.Sub(start).Milliseconds())
+}
+
+// search returns the index of the first tracked message at or after pos
+func (t *messageTracker) search(pos arbutil.MessageIndex) int {
+	return sort.Search(len(t.messages), func(i int) bool {
+		return t.messages[i].pos >= pos
+	})
+}
+
+// stamps returns the times recorded for the message, if it's still tracked
+func (t *messageTracker) stamps(pos arbutil.MessageIndex) (stamps, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	i := t.search(pos)
+	if i == len(t.messages) || t.messages[i].pos != pos {
+		return stamps{}, false
+	}
+	return t.messages[i].stamps, true
+}
+
+// TransactionSequenced records a transaction that arrived at the given time making it into a block
+func TransactionSequenced(received time.Time) {
+	observe(Received, Sequenced, received, time.Now())
+}
+
+// MessagesSequenced stamps messages [pos, pos+count) as having been added to the transaction streamer
+func MessagesSequenced(pos arbutil.MessageIndex, count int) {
+	tracker.sequenced(pos, count, time.Now())
+}
+
+func (t *messageTracker) sequenced(pos arbutil.MessageIndex, count int, now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	// forget what we knew about messages replaced by a reorg
+	t.messages = t.messages[:t.search(pos)]
+	if count > t.limit {
+		// only the latest messages would be kept anyway
+		pos += arbutil.MessageIndex(count - t.limit)
+		count = t.limit
+	}
+	for i := 0; i < count; i++ {
+		message := &trackedMessage{pos: pos + arbutil.MessageIndex(i)}
+		message.stamps[Sequenced] = now
+		t.messages = append(t.messages, message)
+	}
+	t.next = pos + arbutil.MessageIndex(count)
+	if excess := len(t.messages) - t.limit; excess > 0 {
+		t.messages = t.messages[excess:]
+	}
+}
+
+// MessagesBroadcast stamps messages [pos, pos+count) as having been sent to feed clients
+func MessagesBroadcast(pos arbutil.MessageIndex, count int) {
+	tracker.broadcast(pos, count, time.Now())
+}
+
+func (t *messageTracker) broadcast(pos arbutil.MessageIndex, count int, now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	end := pos + arbutil.MessageIndex(count)
+	for i := t.search(pos); i < len(t.messages) && t.messages[i].pos < end; i++ {
+		message := &t.messages[i].stamps
+		if message[Broadcast].IsZero() {
+			message[Broadcast] = now
+			observe(Sequenced, Broadcast, message[Sequenced], now)
+		}
+	}
+}
+
+// MessagesPosted stamps all messages before the count as having been posted to the parent chain
+func MessagesPosted(count arbutil.MessageIndex) {
+	tracker.advance(Posted, &tracker.posted, count, time.Now())
+}
+
+// MessagesValidated stamps all messages before the count as having been validated
+func MessagesValidated(count arbutil.MessageIndex) {
+	tracker.advance(Validated, &tracker.valid, count, time.Now())
+}
+
+func (t *messageTracker) advance(stage Stage, progress *arbutil.MessageIndex, count arbutil.MessageIndex, now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for i := t.search(*progress); i < len(t.messages) && t.messages[i].pos < count; i++ {
+		message := &t.messages[i].stamps
+		if message[stage].IsZero() {
+			message[stage] = now
+			observe(Sequenced, stage, message[Sequenced], now)
+		}
+	}
+	if count > *progress {
+		*progress = count
+	}
+}
+
+// Percentiles summarizes the latency between two stages, in milliseconds
+type Percentiles struct {
+	Count int64   `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   int64   `json:"max"`
+}
+
+// Summary returns the latency percentiles for each pair of stages, keyed like "sequenced_to_posted"
+func Summary() map[string]Percentiles {
+	summary := make(map[string]Percentiles)
+	for _, interval := range intervals {
+		snapshot := histograms[[2]Stage{interval.from, interval.to}].Snapshot()
+		values := snapshot.Percentiles([]float64{0.5, 0.9, 0.99})
+		summary[interval.from.String()+"_to_"+interval.to.String()] = Percentiles{
+			Count: snapshot.Count(),
+			Mean:  snapshot.Mean(),
+			P50:   values[0],
+			P90:   values[1],
+			P99:   values[2],
+			Max:   snapshot.Max(),
+		}
+	}
+	return summary
+}
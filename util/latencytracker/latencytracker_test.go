@@ -0,0 +1,103 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package latencytracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+func TestMessageTrackerStages(t *testing.T) {
+	tr := newMessageTracker(16)
+	start := time.Unix(1000, 0)
+	tr.sequenced(0, 4, start)
+	tr.broadcast(1, 2, start.Add(time.Second))
+	tr.advance(Posted, &tr.posted, 3, start.Add(2*time.Second))
+
+	for pos := arbutil.MessageIndex(0); pos < 4; pos++ {
+		stamps, ok := tr.stamps(pos)
+		if !ok {
+			t.Fatal("message", pos, "not tracked")
+		}
+		if !stamps[Sequenced].Equal(start) {
+			t.Fatal("message", pos, "has wrong sequenced time", stamps[Sequenced])
+		}
+		if broadcast := pos >= 1 && pos < 3; stamps[Broadcast].IsZero() == broadcast {
+			t.Fatal("message", pos, "has wrong broadcast time", stamps[Broadcast])
+		}
+		if posted := pos < 3; stamps[Posted].IsZero() == posted {
+			t.Fatal("message", pos, "has wrong posted time", stamps[Posted])
+		}
+	}
+
+	// stamps aren't overwritten by later progress
+	tr.advance(Posted, &tr.posted, 4, start.Add(time.Hour))
+	stamps, _ := tr.stamps(0)
+	if !stamps[Posted].Equal(start.Add(2 * time.Second)) {
+		t.Fatal("posted time was overwritten", stamps[Posted])
+	}
+	if tr.posted != 4 {
+		t.Fatal("posted progress is", tr.posted)
+	}
+}
+
+func TestMessageTrackerReorg(t *testing.T) {
+	tr := newMessageTracker(16)
+	first := time.Unix(1000, 0)
+	tr.sequenced(0, 8, first)
+	second := first.Add(time.Minute)
+	tr.sequenced(5, 1, second)
+
+	if tr.next != 6 {
+		t.Fatal("next message is", tr.next)
+	}
+	if _, ok := tr.stamps(6); ok {
+		t.Fatal("reorged out message is still tracked")
+	}
+	stamps, ok := tr.stamps(5)
+	if !ok || !stamps[Sequenced].Equal(second) {
+		t.Fatal("replacement message not tracked with its own time")
+	}
+	stamps, ok = tr.stamps(4)
+	if !ok || !stamps[Sequenced].Equal(first) {
+		t.Fatal("message before the reorg lost")
+	}
+}
+
+func TestMessageTrackerEviction(t *testing.T) {
+	tr := newMessageTracker(4)
+	now := time.Unix(1000, 0)
+	tr.sequenced(0, 3, now)
+	tr.sequenced(3, 3, now)
+	if len(tr.messages) != 4 {
+		t.Fatal("tracking", len(tr.messages), "messages")
+	}
+	if _, ok := tr.stamps(1); ok {
+		t.Fatal("oldest messages weren't evicted")
+	}
+	if _, ok := tr.stamps(2); !ok {
+		t.Fatal("message within the limit was evicted")
+	}
+
+	// a jump far ahead evicts everything before it without walking the gap
+	far := arbutil.MessageIndex(1 << 62)
+	tr.sequenced(far, 2, now)
+	if len(tr.messages) != 4 {
+		t.Fatal("tracking", len(tr.messages), "messages after a jump")
+	}
+	tr.sequenced(far+2, 10, now)
+	if len(tr.messages) != 4 || tr.messages[0].pos != far+8 || tr.next != far+12 {
+		t.Fatal("unexpected tracked messages after a large batch", len(tr.messages), tr.messages[0].pos, tr.next)
+	}
+
+	// progress past the tracked messages only stamps what's tracked
+	tr.advance(Validated, &tr.valid, far+100, now)
+	for _, message := range tr.messages {
+		if message.stamps[Validated].IsZero() {
+			t.Fatal("tracked message", message.pos, "not stamped validated")
+		}
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright 2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package rpcclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	failoverCounter            = metrics.NewRegisteredCounter("arb/rpcclient/failover", nil)
+	healthyEndpointsGauge      = metrics.NewRegisteredGauge("arb/rpcclient/failover/healthy", nil)
+	crossVerifyMismatchCounter = metrics.NewRegisteredCounter("arb/rpcclient/failover/crossverify/mismatch", nil)
+)
+
+type FailoverConfig struct {
+	HealthCheckInterval time.Duration `koanf:"health-check-interval" reload:"hot"`
+	MaxBlockLag         uint64        `koanf:"max-block-lag" reload:"hot"`
+	CrossVerifyMethods  []string      `koanf:"cross-verify-methods" reload:"hot"`
+}
+
+type FailoverConfigFetcher func() *FailoverConfig
+
+var DefaultFailoverConfig = FailoverConfig{
+	HealthCheckInterval: 10 * time.Second,
+	MaxBlockLag:         10,
+	CrossVerifyMethods:  []string{},
+}
+
+var TestFailoverConfig = FailoverConfig{
+	HealthCheckInterval: 100 * time.Millisecond,
+	MaxBlockLag:         10,
+	CrossVerifyMethods:  []string{},
+}
+
+func FailoverConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Duration(prefix+".health-check-interval", DefaultFailoverConfig.HealthCheckInterval, "how often to check the health of each endpoint, when the connection url is a comma separated list of endpoints to fail over between")
+	f.Uint64(prefix+".max-block-lag", DefaultFailoverConfig.MaxBlockLag, "consider an endpoint unhealthy if its latest block is this many blocks behind the most up to date endpoint (0 = disabled)")
+	f.StringSlice(prefix+".cross-verify-methods", DefaultFailoverConfig.CrossVerifyMethods, "RPC methods whose results are verified against a second healthy endpoint, such as eth_getLogs and eth_getTransactionByHash for batch data")
+}
+
+func (c *FailoverConfig) Validate() error {
+	if c.HealthCheckInterval <= 0 {
+		return errors.New("failover health-check-interval must be positive")
+	}
+	return nil
+}
+
+type failoverEndpoint struct {
+	url       string
+	client    *RpcClient
+	connected atomic.Bool
+	healthy   atomic.Bool
+}
+
+// FailoverClient spreads a connection over several endpoints, given as a comma separated list in the
+// connection url. Calls go to the first healthy endpoint in the order they're listed, and fail over to
+// the next one if the endpoint can't be reached or times out. Errors returned by the server itself, such
+// as reverts, aren't failed over. Endpoints are health checked in the background, so an endpoint which
+// recovers is preferred again.
+type FailoverClient struct {
+	stopwaiter.StopWaiter
+	config    FailoverConfigFetcher
+	endpoints []*failoverEndpoint
+}
+
+func NewFailoverClient(config ClientConfigFetcher, failoverConfig FailoverConfigFetcher, stack *node.Node) *FailoverClient {
+	var endpoints []*failoverEndpoint
+	for _, url := range strings.Split(config().URL, ",") {
+		url := strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+		endpointConfig := func() *ClientConfig {
+			c := *config()
+			c.URL = url
+			return &c
+		}
+		endpoints = append(endpoints, &failoverEndpoint{
+			url:    url,
+			client: NewRpcClient(endpointConfig, stack),
+		})
+	}
+	return &FailoverClient{
+		config:    failoverConfig,
+		endpoints: endpoints,
+	}
+}
+
+// Start connects to every endpoint, and succeeds if any of them can be reached
+func (c *FailoverClient) Start(ctx context.Context) error {
+	if len(c.endpoints) == 0 {
+		return errors.New("no url provided for this connection")
+	}
+	errs := make([]error, len(c.endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range c.endpoints {
+		wg.Add(1)
+		go func(i int, endpoint *failoverEndpoint) {
+			defer wg.Done()
+			errs[i] = endpoint.client.Start(ctx)
+			endpoint.connected.Store(errs[i] == nil)
+			endpoint.healthy.Store(errs[i] == nil)
+		}(i, endpoint)
+	}
+	wg.Wait()
+	connected := false
+	for i, endpoint := range c.endpoints {
+		if errs[i] != nil {
+			log.Warn("failed to connect to endpoint", "url", endpoint.url, "err", errs[i])
+		} else {
+			connected = true
+		}
+	}
+	if !connected {
+		return errors.Join(errs...)
+	}
+	c.StopWaiter.Start(ctx, c)
+	c.CallIteratively(func(ctx context.Context) time.Duration {
+		c.checkHealth(ctx)
+		return c.config().HealthCheckInterval
+	})
+	return nil
+}
+
+func (c *FailoverClient) checkHealth(ctx context.Context) {
+	config := c.config()
+	blockNumbers := make([]uint64, len(c.endpoints))
+	reachable := make([]bool, len(c.endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range c.endpoints {
+		wg.Add(1)
+		go func(i int, endpoint *failoverEndpoint) {
+			defer wg.Done()
+			if !endpoint.connected.Load() {
+				// Never connected, try again
+				if err := endpoint.client.Start(ctx); err != nil {
+					return
+				}
+				endpoint.connected.Store(true)
+			}
+			checkCtx, cancel := context.WithTimeout(ctx, config.HealthCheckInterval)
+			defer cancel()
+			var blockNumber hexutil.Uint64
+			err := endpoint.client.client.CallContext(checkCtx, &blockNumber, "eth_blockNumber")
+			if err != nil {
+				log.Debug("endpoint failed health check", "url", endpoint.url, "err", err)
+				return
+			}
+			blockNumbers[i] = uint64(blockNumber)
+			reachable[i] = true
+		}(i, endpoint)
+	}
+	wg.Wait()
+	var best uint64
+	for i := range c.endpoints {
+		if reachable[i] && blockNumbers[i] > best {
+			best = blockNumbers[i]
+		}
+	}
+	healthyCount := 0
+	for i, endpoint := range c.endpoints {
+		healthy := reachable[i] && (config.MaxBlockLag == 0 || blockNumbers[i]+config.MaxBlockLag >= best)
+		if endpoint.healthy.Swap(healthy) != healthy {
+			log.Info("endpoint health changed", "url", endpoint.url, "healthy", healthy, "blockNumber", blockNumbers[i], "bestBlockNumber", best)
+		}
+		if healthy {
+			healthyCount++
+		}
+	}
+	healthyEndpointsGauge.Update(int64(healthyCount))
+}
+
+// candidates returns the endpoints to try in order: the healthy ones, then the rest as a last resort
+func (c *FailoverClient) candidates() []*failoverEndpoint {
+	candidates := make([]*failoverEndpoint, 0, len(c.endpoints))
+	for _, endpoint := range c.endpoints {
+		if endpoint.healthy.Load() {
+			candidates = append(candidates, endpoint)
+		}
+	}
+	for _, endpoint := range c.endpoints {
+		if !endpoint.healthy.Load() && endpoint.connected.Load() {
+			candidates = append(candidates, endpoint)
+		}
+	}
+	return candidates
+}
+
+// shouldFailOver returns whether an error is from failing to reach the endpoint rather than from the server
+func shouldFailOver(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var rpcErr rpc.Error
+	return !errors.As(err, &rpcErr)
+}
+
+func (c *FailoverClient) call(ctx context.Context, call func(*failoverEndpoint) error) (*failoverEndpoint, error) {
+	candidates := c.candidates()
+	if len(candidates) == 0 {
+		return nil, errors.New("not connected")
+	}
+	var err error
+	for i, endpoint := range candidates {
+		err = call(endpoint)
+		if !shouldFailOver(ctx, err) {
+			return endpoint, err
+		}
+		if endpoint.healthy.Swap(false) {
+			log.Warn("endpoint failed, failing over", "url", endpoint.url, "err", err)
+		}
+		if i < len(candidates)-1 {
+			failoverCounter.Inc(1)
+		}
+	}
+	return nil, err
+}
+
+func (c *FailoverClient) crossVerified(method string) bool {
+	for _, verified := range c.config().CrossVerifyMethods {
+		if verified == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *FailoverClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if result == nil || !c.crossVerified(method) {
+		_, err := c.call(ctx, func(endpoint *failoverEndpoint) error {
+			return endpoint.client.CallContext(ctx, result, method, args...)
+		})
+		return err
+	}
+	var raw json.RawMessage
+	used, err := c.call(ctx, func(endpoint *failoverEndpoint) error {
+		return endpoint.client.CallContext(ctx, &raw, method, args...)
+	})
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, result); err != nil {
+		return err
+	}
+	for _, endpoint := range c.endpoints {
+		if endpoint == used || !endpoint.healthy.Load() {
+			continue
+		}
+		var otherRaw json.RawMessage
+		err := endpoint.client.CallContext(ctx, &otherRaw, method, args...)
+		if err != nil {
+			return fmt.Errorf("failed to cross verify %v with %v: %w", method, endpoint.url, err)
+		}
+		return compareResults(result, otherRaw, method, used.url, endpoint.url)
+	}
+	log.Debug("no second healthy endpoint to cross verify with", "method", method)
+	return nil
+}
+
+// compareResults checks that another endpoint's raw result decodes to the same value as the result,
+// ignoring fields the result's type doesn't have, which may differ between client implementations
+func compareResults(result interface{}, otherRaw json.RawMessage, method string, url string, otherUrl string) error {
+	other := reflect.New(reflect.TypeOf(result).Elem()).Interface()
+	if err := json.Unmarshal(otherRaw, other); err != nil {
+		return fmt.Errorf("failed to decode %v result from %v: %w", method, otherUrl, err)
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	otherEncoded, err := json.Marshal(other)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(encoded, otherEncoded) {
+		crossVerifyMismatchCounter.Inc(1)
+		return fmt.Errorf("%v results from %v and %v differ", method, url, otherUrl)
+	}
+	return nil
+}
+
+func (c *FailoverClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	_, err := c.call(ctx, func(endpoint *failoverEndpoint) error {
+		return endpoint.client.BatchCallContext(ctx, b)
+	})
+	return err
+}
+
+func (c *FailoverClient) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*rpc.ClientSubscription, error) {
+	var sub *rpc.ClientSubscription
+	_, err := c.call(ctx, func(endpoint *failoverEndpoint) error {
+		var err error
+		sub, err = endpoint.client.EthSubscribe(ctx, channel, args...)
+		return err
+	})
+	return sub, err
+}
+
+func (c *FailoverClient) Close() {
+	c.StopAndWait()
+	for _, endpoint := range c.endpoints {
+		if endpoint.connected.Load() {
+			endpoint.client.Close()
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package rpcclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type failoverTestEthAPI struct {
+	blockNumber uint64
+}
+
+func (a *failoverTestEthAPI) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(a.blockNumber)
+}
+
+type failoverTestAPI struct {
+	value int
+}
+
+func (a *failoverTestAPI) Value() int {
+	return a.value
+}
+
+func (a *failoverTestAPI) Fail() error {
+	return errors.New("execution reverted")
+}
+
+func createFailoverTestNode(t *testing.T, ctx context.Context, value int, blockNumber uint64) *node.Node {
+	stackConf := node.DefaultConfig
+	stackConf.HTTPPort = 0
+	stackConf.DataDir = ""
+	stackConf.WSHost = "127.0.0.1"
+	stackConf.WSPort = 0
+	stackConf.WSModules = []string{"test", "eth"}
+	stackConf.P2P.NoDiscovery = true
+	stackConf.P2P.ListenAddr = ""
+
+	stack, err := node.New(&stackConf)
+	Require(t, err)
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "test",
+		Version:   "1.0",
+		Service:   &failoverTestAPI{value},
+		Public:    true,
+	}, {
+		Namespace: "eth",
+		Version:   "1.0",
+		Service:   &failoverTestEthAPI{blockNumber},
+		Public:    true,
+	}})
+	Require(t, stack.Start())
+	go func() {
+		<-ctx.Done()
+		stack.Close()
+	}()
+	return stack
+}
+
+func newTestFailoverClient(t *testing.T, ctx context.Context, failoverConfig *FailoverConfig, stacks ...*node.Node) *FailoverClient {
+	var urls []string
+	for _, stack := range stacks {
+		urls = append(urls, stack.WSEndpoint())
+	}
+	config := &ClientConfig{
+		URL:     strings.Join(urls, ","),
+		Timeout: time.Second,
+	}
+	client := NewFailoverClient(func() *ClientConfig { return config }, func() *FailoverConfig { return failoverConfig }, nil)
+	Require(t, client.Start(ctx))
+	t.Cleanup(client.Close)
+	return client
+}
+
+func expectValue(t *testing.T, ctx context.Context, client *FailoverClient, expected int) {
+	t.Helper()
+	var value int
+	Require(t, client.CallContext(ctx, &value, "test_value"))
+	if value != expected {
+		Fail(t, "got value", value, "but expected", expected)
+	}
+}
+
+func TestFailoverClient(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stackA := createFailoverTestNode(t, ctx, 1, 100)
+	stackB := createFailoverTestNode(t, ctx, 2, 100)
+	config := TestFailoverConfig
+	client := newTestFailoverClient(t, ctx, &config, stackA, stackB)
+
+	expectValue(t, ctx, client, 1)
+	// An error from the server isn't failed over
+	err := client.CallContext(ctx, nil, "test_fail")
+	if err == nil || !strings.Contains(err.Error(), "execution reverted") {
+		Fail(t, "expected the server's error but got", err)
+	}
+	expectValue(t, ctx, client, 1)
+
+	stackA.Close()
+	expectValue(t, ctx, client, 2)
+}
+
+func TestFailoverClientBlockLag(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stackA := createFailoverTestNode(t, ctx, 1, 50)
+	stackB := createFailoverTestNode(t, ctx, 2, 100)
+	config := TestFailoverConfig
+	client := newTestFailoverClient(t, ctx, &config, stackA, stackB)
+
+	for i := 0; ; i++ {
+		if i >= 100 {
+			Fail(t, "lagging endpoint was never marked unhealthy")
+		}
+		var value int
+		Require(t, client.CallContext(ctx, &value, "test_value"))
+		if value == 2 {
+			break
+		}
+		time.Sleep(config.HealthCheckInterval / 2)
+	}
+}
+
+func TestFailoverClientCrossVerify(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := TestFailoverConfig
+	config.CrossVerifyMethods = []string{"test_value"}
+	agreeing := newTestFailoverClient(t, ctx, &config, createFailoverTestNode(t, ctx, 1, 100), createFailoverTestNode(t, ctx, 1, 100))
+	expectValue(t, ctx, agreeing, 1)
+
+	disagreeing := newTestFailoverClient(t, ctx, &config, createFailoverTestNode(t, ctx, 1, 100), createFailoverTestNode(t, ctx, 2, 100))
+	var value int
+	err := disagreeing.CallContext(ctx, &value, "test_value")
+	if err == nil || !strings.Contains(err.Error(), "differ") {
+		Fail(t, "expected cross verification to fail but got", err)
+	}
+}
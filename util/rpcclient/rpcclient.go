@@ -11,29 +11,38 @@ import (
 	"time"
 
 	flag "github.com/spf13/pflag"
+	"golang.org/x/time/rate"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/offchainlabs/nitro/util/signature"
 )
 
+var rateLimitedCounter = metrics.NewRegisteredCounter("arb/rpcclient/ratelimited", nil)
+
 type ClientConfig struct {
-	URL            string        `json:"url,omitempty" koanf:"url"`
-	JWTSecret      string        `json:"jwtsecret,omitempty" koanf:"jwtsecret"`
-	Timeout        time.Duration `json:"timeout,omitempty" koanf:"timeout" reload:"hot"`
-	Retries        uint          `json:"retries,omitempty" koanf:"retries" reload:"hot"`
-	ConnectionWait time.Duration `json:"connection-wait,omitempty" koanf:"connection-wait"`
-	ArgLogLimit    uint          `json:"arg-log-limit,omitempty" koanf:"arg-log-limit" reload:"hot"`
-	RetryErrors    string        `json:"retry-errors,omitempty" koanf:"retry-errors" reload:"hot"`
-	RetryDelay     time.Duration `json:"retry-delay,omitempty" koanf:"retry-delay"`
+	URL               string        `json:"url,omitempty" koanf:"url"`
+	JWTSecret         string        `json:"jwtsecret,omitempty" koanf:"jwtsecret"`
+	Timeout           time.Duration `json:"timeout,omitempty" koanf:"timeout" reload:"hot"`
+	Retries           uint          `json:"retries,omitempty" koanf:"retries" reload:"hot"`
+	ConnectionWait    time.Duration `json:"connection-wait,omitempty" koanf:"connection-wait"`
+	ArgLogLimit       uint          `json:"arg-log-limit,omitempty" koanf:"arg-log-limit" reload:"hot"`
+	RetryErrors       string        `json:"retry-errors,omitempty" koanf:"retry-errors" reload:"hot"`
+	RetryDelay        time.Duration `json:"retry-delay,omitempty" koanf:"retry-delay"`
+	RequestsPerSecond float64       `json:"requests-per-second,omitempty" koanf:"requests-per-second" reload:"hot"`
+	RequestsBurst     uint          `json:"requests-burst,omitempty" koanf:"requests-burst" reload:"hot"`
 
 	retryErrors *regexp.Regexp
 }
 
 func (c *ClientConfig) Validate() error {
+	if c.RequestsPerSecond < 0 {
+		return errors.New("requests-per-second must not be negative")
+	}
 	if c.RetryErrors == "" {
 		c.retryErrors = nil
 		return nil
@@ -67,6 +76,8 @@ func RPCClientAddOptions(prefix string, f *flag.FlagSet, defaultConfig *ClientCo
 	f.Uint(prefix+".retries", defaultConfig.Retries, "number of retries in case of failure(0 mean one attempt)")
 	f.String(prefix+".retry-errors", defaultConfig.RetryErrors, "Errors matching this regular expression are automatically retried")
 	f.Duration(prefix+".retry-delay", defaultConfig.RetryDelay, "delay between retries")
+	f.Float64(prefix+".requests-per-second", defaultConfig.RequestsPerSecond, "maximum rate of requests sent to the server, including retries (0 = unlimited)")
+	f.Uint(prefix+".requests-burst", defaultConfig.RequestsBurst, "number of requests which may be sent at once before requests-per-second applies (0 = 1)")
 }
 
 type RpcClient struct {
@@ -74,12 +85,52 @@ type RpcClient struct {
 	client    *rpc.Client
 	autoStack *node.Node
 	logId     uint64
+	limiter   *rate.Limiter
 }
 
 func NewRpcClient(config ClientConfigFetcher, stack *node.Node) *RpcClient {
 	return &RpcClient{
 		config:    config,
 		autoStack: stack,
+		limiter:   rate.NewLimiter(rate.Inf, 1),
+	}
+}
+
+// waitForRateLimit blocks until n more requests may be sent under the configured rate limit
+func (c *RpcClient) waitForRateLimit(ctx context.Context, n int) error {
+	config := c.config()
+	if config.RequestsPerSecond <= 0 {
+		return nil
+	}
+	limit := rate.Limit(config.RequestsPerSecond)
+	burst := int(config.RequestsBurst)
+	if burst < 1 {
+		burst = 1
+	}
+	if c.limiter.Limit() != limit {
+		c.limiter.SetLimit(limit)
+	}
+	if c.limiter.Burst() != burst {
+		c.limiter.SetBurst(burst)
+	}
+	// A batch larger than the burst would never be allowed, so it takes up the whole burst instead
+	if n > burst {
+		n = burst
+	}
+	reservation := c.limiter.ReserveN(time.Now(), n)
+	delay := reservation.Delay()
+	if delay == 0 {
+		return nil
+	}
+	rateLimitedCounter.Inc(1)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		reservation.Cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
 }
 
@@ -165,6 +216,9 @@ func (c *RpcClient) CallContext(ctx_in context.Context, result interface{}, meth
 		if ctx_in.Err() != nil {
 			return ctx_in.Err()
 		}
+		if err := c.waitForRateLimit(ctx_in, 1); err != nil {
+			return err
+		}
 		var ctx context.Context
 		var cancelCtx context.CancelFunc
 		timeout := c.config().Timeout
@@ -210,10 +264,16 @@ func (c *RpcClient) CallContext(ctx_in context.Context, result interface{}, meth
 }
 
 func (c *RpcClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	if err := c.waitForRateLimit(ctx, len(b)); err != nil {
+		return err
+	}
 	return c.client.BatchCallContext(ctx, b)
 }
 
 func (c *RpcClient) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*rpc.ClientSubscription, error) {
+	if err := c.waitForRateLimit(ctx, 1); err != nil {
+		return nil, err
+	}
 	return c.client.EthSubscribe(ctx, channel, args...)
 }
 
@@ -182,6 +182,46 @@ func TestRpcClientRetry(t *testing.T) {
 	}
 }
 
+func TestRpcClientRateLimit(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	config := &ClientConfig{
+		URL:               "self",
+		Timeout:           time.Second * 5,
+		RequestsPerSecond: 20,
+		RequestsBurst:     2,
+	}
+	Require(t, config.Validate())
+	server := createTestNode(t, ctx, 0)
+	client := NewRpcClient(func() *ClientConfig { return config }, server)
+	Require(t, client.Start(ctx))
+	defer client.Close()
+
+	start := time.Now()
+	for i := 0; i < 12; i++ {
+		Require(t, client.CallContext(ctx, nil, "test_failAtFirst"))
+	}
+	// The burst of 2 goes through immediately, and the other 10 requests are spaced 50ms apart
+	if elapsed := time.Since(start); elapsed < 450*time.Millisecond {
+		Fail(t, "requests weren't rate limited, took", elapsed)
+	}
+
+	// With the burst used up, a call that can't wait long enough gives up
+	config.RequestsPerSecond = 0.001
+	shortCtx, cancelShort := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancelShort()
+	if err := client.CallContext(shortCtx, nil, "test_failAtFirst"); !errors.Is(err, context.DeadlineExceeded) {
+		Fail(t, "expected rate limited call to time out but got", err)
+	}
+
+	config.RequestsPerSecond = -1
+	if err := config.Validate(); err == nil {
+		Fail(t, "negative requests-per-second passed validation")
+	}
+}
+
 func TestIsAlreadyKnownError(t *testing.T) {
 	for _, testCase := range []struct {
 		input    string
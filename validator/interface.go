@@ -24,6 +24,8 @@ type ExecutionSpawner interface {
 	ValidationSpawner
 	CreateExecutionRun(wasmModuleRoot common.Hash, input *ValidationInput) containers.PromiseInterface[ExecutionRun]
 	LatestWasmModuleRoot() containers.PromiseInterface[common.Hash]
+	// LoadWasmModuleRoot loads the machine for the module root, returning an error if it can't be found
+	LoadWasmModuleRoot(moduleRoot common.Hash) containers.PromiseInterface[struct{}]
 	WriteToFile(input *ValidationInput, expOut GoGlobalState, moduleRoot common.Hash) containers.PromiseInterface[struct{}]
 }
 
@@ -17,6 +17,8 @@ import (
 
 const Namespace string = "validation"
 
+// ValidationServerAPI serves a validation spawner to ValidationClients, as the validation namespace of a
+// validation server. Whether it requires JWT authentication is set by the validation node's api-auth option.
 type ValidationServerAPI struct {
 	spawner validator.ValidationSpawner
 }
@@ -90,6 +92,11 @@ func (a *ExecServerAPI) LatestWasmModuleRoot(ctx context.Context) (common.Hash,
 	return a.execSpawner.LatestWasmModuleRoot().Await(ctx)
 }
 
+func (a *ExecServerAPI) LoadWasmModuleRoot(ctx context.Context, moduleRoot common.Hash) error {
+	_, err := a.execSpawner.LoadWasmModuleRoot(moduleRoot).Await(ctx)
+	return err
+}
+
 func (a *ExecServerAPI) removeOldRuns(ctx context.Context) time.Duration {
 	oldestKept := time.Now().Add(-1 * a.config().ExecutionRunTimeout)
 	a.runIdLock.Lock()
@@ -20,6 +20,9 @@ import (
 	"github.com/ethereum/go-ethereum/node"
 )
 
+// ValidationClient runs validations on a separate validation server (nitro-val) over its validation API, so that
+// machine loading and execution can run in another process or on other hardware. The server's room, read
+// when connecting, bounds how many validations the client has in flight on it at once.
 type ValidationClient struct {
 	stopwaiter.StopWaiter
 	client *rpcclient.RpcClient
@@ -141,6 +144,13 @@ func (c *ExecutionClient) LatestWasmModuleRoot() containers.PromiseInterface[com
 	})
 }
 
+func (c *ExecutionClient) LoadWasmModuleRoot(moduleRoot common.Hash) containers.PromiseInterface[struct{}] {
+	return stopwaiter.LaunchPromiseThread[struct{}](c, func(ctx context.Context) (struct{}, error) {
+		err := c.client.CallContext(ctx, nil, Namespace+"_loadWasmModuleRoot", moduleRoot)
+		return struct{}{}, err
+	})
+}
+
 func (c *ExecutionClient) WriteToFile(input *validator.ValidationInput, expOut validator.GoGlobalState, moduleRoot common.Hash) containers.PromiseInterface[struct{}] {
 	jsonInput := ValidationInputToJson(input)
 	return stopwaiter.LaunchPromiseThread[struct{}](c, func(ctx context.Context) (struct{}, error) {
@@ -21,14 +21,17 @@ type executionRun struct {
 
 // NewExecutionChallengeBackend creates a backend with the given arguments.
 // Note: machineCache may be nil, but if present, it must not have a restricted range.
+// The execution shares machine states under the key through the shared cache, which may be nil.
 func NewExecutionRun(
 	ctxIn context.Context,
 	initialMachineGetter func(context.Context) (MachineInterface, error),
 	config *MachineCacheConfig,
+	shared *MachineStateCache,
+	key MachineStateKey,
 ) (*executionRun, error) {
 	exec := &executionRun{}
 	exec.Start(ctxIn, exec)
-	exec.cache = NewMachineCache(exec.GetContext(), initialMachineGetter, config)
+	exec.cache = NewMachineCache(exec.GetContext(), initialMachineGetter, config, shared, key)
 	return exec, nil
 }
 
@@ -29,6 +29,7 @@ import (
 type MachineInterface interface {
 	CloneMachineInterface() MachineInterface
 	GetStepCount() uint64
+	MemorySize() uint64
 	IsRunning() bool
 	ValidForStep(uint64) bool
 	Status() uint8
@@ -138,6 +139,14 @@ func (m *ArbitratorMachine) GetStepCount() uint64 {
 	return uint64(C.arbitrator_get_num_steps(m.ptr))
 }
 
+// MemorySize returns the bytes of wasm memory the machine holds
+func (m *ArbitratorMachine) MemorySize() uint64 {
+	defer runtime.KeepAlive(m)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return uint64(C.arbitrator_memory_size(m.ptr))
+}
+
 func (m *ArbitratorMachine) IsRunning() bool {
 	defer runtime.KeepAlive(m)
 	m.mutex.Lock()
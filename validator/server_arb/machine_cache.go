@@ -25,6 +25,8 @@ type MachineCache struct {
 	firstMachineStep    uint64
 	machineStepInterval uint64
 	config              *MachineCacheConfig
+	shared              *MachineStateCache
+	key                 MachineStateKey
 
 	lastMachine     MachineInterface
 	lastMachineLock sync.Mutex
@@ -33,23 +35,32 @@ type MachineCache struct {
 type MachineCacheConfig struct {
 	CachedChallengeMachines int    `koanf:"cached-challenge-machines"`
 	InitialSteps            uint64 `koanf:"initial-steps"`
+	MaxEntries              int    `koanf:"max-entries"`
+	MemoryLimit             int    `koanf:"memory-limit"`
 }
 
 var DefaultMachineCacheConfig = MachineCacheConfig{
 	CachedChallengeMachines: 4,
 	InitialSteps:            100000,
+	MaxEntries:              16,
+	MemoryLimit:             2048,
 }
 
 func MachineCacheConfigConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Uint64(prefix+".initial-steps", DefaultMachineCacheConfig.InitialSteps, "initial steps between machines")
 	f.Int(prefix+".cached-challenge-machines", DefaultMachineCacheConfig.CachedChallengeMachines, "how many machines to store in cache while working on a challenge (should be even)")
+	f.Int(prefix+".max-entries", DefaultMachineCacheConfig.MaxEntries, "maximum number of machine states kept across executions, keyed by module root, input and step, so repeated executions of the same input start from the closest one; least recently used are evicted first (0 = disabled)")
+	f.Int(prefix+".memory-limit", DefaultMachineCacheConfig.MemoryLimit, "maximum wasm memory in megabytes held by machine states kept across executions (0 = no limit)")
 }
 
 // `initialMachine` won't be mutated by this function.
-func NewMachineCache(ctx context.Context, initialMachineGetter func(context.Context) (MachineInterface, error), config *MachineCacheConfig) *MachineCache {
+// Machines are shared with other executions of the same key through the shared cache, which may be nil.
+func NewMachineCache(ctx context.Context, initialMachineGetter func(context.Context) (MachineInterface, error), config *MachineCacheConfig, shared *MachineStateCache, key MachineStateKey) *MachineCache {
 	cache := &MachineCache{
 		buildingLock: make(chan struct{}, 1), // locked on init
 		config:       config,
+		shared:       shared,
+		key:          key,
 	}
 	go func() {
 		zeroStepMachine, err := initialMachineGetter(ctx)
@@ -169,8 +180,8 @@ func (c *MachineCache) setRangeLocked(ctx context.Context, start uint64, end uin
 	}
 	var initial MachineInterface
 	if closestStep < start {
-		initial = closest.CloneMachineInterface()
-		err := initial.Step(ctx, start-closestStep)
+		var err error
+		initial, err = c.stepTo(ctx, closest, start)
 		if err != nil {
 			return err
 		}
@@ -215,8 +226,8 @@ func (c *MachineCache) populateCache(ctx context.Context) error {
 		if len(c.machines) >= c.config.CachedChallengeMachines {
 			break
 		}
-		nextMachine = nextMachine.CloneMachineInterface()
-		err := nextMachine.Step(ctx, c.machineStepInterval)
+		var err error
+		nextMachine, err = c.stepTo(ctx, nextMachine, nextMachine.GetStepCount()+c.machineStepInterval)
 		if err != nil {
 			return err
 		}
@@ -226,6 +237,19 @@ func (c *MachineCache) populateCache(ctx context.Context) error {
 	return nil
 }
 
+// stepTo returns a new machine at the given step, starting from the given machine or from a closer one in the
+// shared cache, and shares the new machine. The given machine isn't mutated.
+func (c *MachineCache) stepTo(ctx context.Context, from MachineInterface, step uint64) (MachineInterface, error) {
+	machine := c.shared.cloneClosest(c.key, step, from)
+	err := machine.Step(ctx, step-machine.GetStepCount())
+	if err != nil {
+		machine.Destroy()
+		return nil, err
+	}
+	c.shared.add(c.key, machine)
+	return machine, nil
+}
+
 // Warning: don't mutate the result of this!
 func (c *MachineCache) getClosestMachine(stepCount uint64) (int, MachineInterface) {
 	if stepCount < c.firstMachineStep {
@@ -273,7 +297,7 @@ func (c *MachineCache) GetMachineAt(ctx context.Context, stepCount uint64) (Mach
 	if lastMachine != nil && lastMachine.GetStepCount() >= closestMachine.GetStepCount() && lastMachine.GetStepCount() <= stepCount {
 		closestMachine = lastMachine
 	} else {
-		closestMachine = closestMachine.CloneMachineInterface()
+		closestMachine = c.shared.cloneClosest(c.key, stepCount, closestMachine)
 	}
 	c.unlockBuild(nil)
 
@@ -281,6 +305,7 @@ func (c *MachineCache) GetMachineAt(ctx context.Context, stepCount uint64) (Mach
 	if err != nil {
 		return nil, err
 	}
+	c.shared.add(c.key, closestMachine)
 	if !closestMachine.ValidForStep(stepCount) {
 		return nil, fmt.Errorf("internal error: got machine with wrong step count %v looking for step count %v", closestMachine.GetStepCount(), stepCount)
 	}
@@ -0,0 +1,171 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package server_arb
+
+import (
+	"container/list"
+	"encoding/binary"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/validator"
+)
+
+var (
+	machineStateCacheHitCounter   = metrics.NewRegisteredCounter("arbitrator/machinecache/hit", nil)
+	machineStateCacheMissCounter  = metrics.NewRegisteredCounter("arbitrator/machinecache/miss", nil)
+	machineStateCacheEntriesGauge = metrics.NewRegisteredGauge("arbitrator/machinecache/entries", nil)
+	machineStateCacheBytesGauge   = metrics.NewRegisteredGauge("arbitrator/machinecache/bytes", nil)
+	machineStateCacheHitRateGauge = metrics.NewRegisteredGaugeFloat64("arbitrator/machinecache/hitrate", nil)
+)
+
+// MachineStateKey identifies the execution of a validation input by a machine, whose states the cache holds
+type MachineStateKey struct {
+	ModuleRoot common.Hash
+	Input      common.Hash
+}
+
+func NewMachineStateKey(moduleRoot common.Hash, input *validator.ValidationInput) MachineStateKey {
+	var buf []byte
+	buf = binary.BigEndian.AppendUint64(buf, input.Id)
+	buf = append(buf, input.StartState.BlockHash[:]...)
+	buf = append(buf, input.StartState.SendRoot[:]...)
+	buf = binary.BigEndian.AppendUint64(buf, input.StartState.Batch)
+	buf = binary.BigEndian.AppendUint64(buf, input.StartState.PosInBatch)
+	for _, batch := range input.BatchInfo {
+		buf = binary.BigEndian.AppendUint64(buf, batch.Number)
+		buf = append(buf, crypto.Keccak256(batch.Data)...)
+	}
+	if input.HasDelayedMsg {
+		buf = binary.BigEndian.AppendUint64(buf, input.DelayedMsgNr)
+		buf = append(buf, crypto.Keccak256(input.DelayedMsg)...)
+	}
+	return MachineStateKey{
+		ModuleRoot: moduleRoot,
+		Input:      crypto.Keccak256Hash(buf),
+	}
+}
+
+type machineStateEntry struct {
+	key     MachineStateKey
+	step    uint64
+	size    uint64
+	machine MachineInterface
+}
+
+// MachineStateCache holds frozen machines at various steps of the executions recently worked on, so that executions
+// of the same input, such as a challenge's repeated bisections, can start from the closest one rather than stepping
+// from the start. The least recently used machines are evicted once it holds too many or they hold too much memory.
+// A nil cache holds nothing.
+type MachineStateCache struct {
+	config func() *MachineCacheConfig
+
+	mutex   sync.Mutex
+	lru     *list.List // of *machineStateEntry, most recently used first
+	entries map[MachineStateKey]map[uint64]*list.Element
+	bytes   uint64
+}
+
+func NewMachineStateCache(config func() *MachineCacheConfig) *MachineStateCache {
+	return &MachineStateCache{
+		config:  config,
+		lru:     list.New(),
+		entries: make(map[MachineStateKey]map[uint64]*list.Element),
+	}
+}
+
+// cloneClosest returns a clone of the cached machine with the greatest step not past the given step, if it's ahead
+// of the fallback machine, and otherwise a clone of the fallback.
+func (c *MachineStateCache) cloneClosest(key MachineStateKey, step uint64, fallback MachineInterface) MachineInterface {
+	if c == nil {
+		return fallback.CloneMachineInterface()
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var closest *list.Element
+	closestStep := fallback.GetStepCount()
+	for entryStep, elem := range c.entries[key] {
+		if entryStep > closestStep && entryStep <= step {
+			closest = elem
+			closestStep = entryStep
+		}
+	}
+	if closest == nil {
+		machineStateCacheMissCounter.Inc(1)
+		updateMachineStateCacheHitRate()
+		return fallback.CloneMachineInterface()
+	}
+	machineStateCacheHitCounter.Inc(1)
+	updateMachineStateCacheHitRate()
+	c.lru.MoveToFront(closest)
+	// Cloned with the lock held, as the machine may be evicted and destroyed as soon as it's released
+	return closest.Value.(*machineStateEntry).machine.CloneMachineInterface()
+}
+
+func updateMachineStateCacheHitRate() {
+	hits := machineStateCacheHitCounter.Count()
+	total := hits + machineStateCacheMissCounter.Count()
+	if total > 0 {
+		machineStateCacheHitRateGauge.Update(float64(hits) / float64(total))
+	}
+}
+
+// add caches a frozen clone of the machine, unless the cache is disabled or already has the machine's step
+func (c *MachineStateCache) add(key MachineStateKey, machine MachineInterface) {
+	if c == nil {
+		return
+	}
+	config := c.config()
+	if config.MaxEntries <= 0 {
+		return
+	}
+	step := machine.GetStepCount()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if elem, ok := c.entries[key][step]; ok {
+		c.lru.MoveToFront(elem)
+		return
+	}
+	size := machine.MemorySize()
+	limit := uint64(config.MemoryLimit) * 1024 * 1024
+	if limit > 0 && size > limit {
+		return
+	}
+	clone := machine.CloneMachineInterface()
+	clone.Freeze()
+	entry := &machineStateEntry{
+		key:     key,
+		step:    step,
+		size:    size,
+		machine: clone,
+	}
+	if c.entries[key] == nil {
+		c.entries[key] = make(map[uint64]*list.Element)
+	}
+	c.entries[key][step] = c.lru.PushFront(entry)
+	c.bytes += size
+	for c.lru.Len() > config.MaxEntries || (limit > 0 && c.bytes > limit) {
+		c.evictOldest()
+	}
+	machineStateCacheEntriesGauge.Update(int64(c.lru.Len()))
+	machineStateCacheBytesGauge.Update(int64(c.bytes))
+}
+
+// evictOldest must be called with the mutex held
+func (c *MachineStateCache) evictOldest() {
+	elem := c.lru.Back()
+	if elem == nil {
+		return
+	}
+	entry := c.lru.Remove(elem).(*machineStateEntry)
+	delete(c.entries[entry.key], entry.step)
+	if len(c.entries[entry.key]) == 0 {
+		delete(c.entries, entry.key)
+	}
+	c.bytes -= entry.size
+	entry.machine.Destroy()
+}
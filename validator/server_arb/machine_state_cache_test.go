@@ -0,0 +1,101 @@
+// Copyright 2021-2023, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package server_arb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/validator"
+)
+
+type fakeMachine struct {
+	step      uint64
+	size      uint64
+	destroyed bool
+}
+
+func (m *fakeMachine) CloneMachineInterface() MachineInterface {
+	return &fakeMachine{step: m.step, size: m.size}
+}
+func (m *fakeMachine) GetStepCount() uint64                    { return m.step }
+func (m *fakeMachine) MemorySize() uint64                      { return m.size }
+func (m *fakeMachine) IsRunning() bool                         { return true }
+func (m *fakeMachine) ValidForStep(step uint64) bool           { return step == m.step }
+func (m *fakeMachine) Status() uint8                           { return uint8(validator.MachineStatusRunning) }
+func (m *fakeMachine) Hash() common.Hash                       { return common.Hash{} }
+func (m *fakeMachine) GetGlobalState() validator.GoGlobalState { return validator.GoGlobalState{} }
+func (m *fakeMachine) ProveNextStep() []byte                   { return nil }
+func (m *fakeMachine) Freeze()                                 {}
+func (m *fakeMachine) Destroy()                                { m.destroyed = true }
+
+func (m *fakeMachine) Step(ctx context.Context, count uint64) error {
+	m.step += count
+	return nil
+}
+
+func TestMachineStateCache(t *testing.T) {
+	config := DefaultMachineCacheConfig
+	config.MaxEntries = 3
+	config.MemoryLimit = 1
+	cache := NewMachineStateCache(func() *MachineCacheConfig { return &config })
+	input := &validator.ValidationInput{Id: 7, BatchInfo: []validator.BatchInfo{{Number: 1, Data: []byte("batch")}}}
+	key := NewMachineStateKey(common.HexToHash("0x1"), input)
+	otherKey := NewMachineStateKey(common.HexToHash("0x2"), input)
+	zero := &fakeMachine{size: 1000}
+
+	for _, step := range []uint64{100, 200, 300} {
+		cache.add(key, &fakeMachine{step: step, size: 1000})
+	}
+	if machine := cache.cloneClosest(key, 250, zero); machine.GetStepCount() != 200 {
+		t.Fatal("expected the cached machine at step 200 but got step", machine.GetStepCount())
+	}
+	if machine := cache.cloneClosest(otherKey, 250, zero); machine.GetStepCount() != 0 {
+		t.Fatal("machine cached for another module root was used")
+	}
+	if machine := cache.cloneClosest(key, 50, zero); machine.GetStepCount() != 0 {
+		t.Fatal("machine cached past the requested step was used")
+	}
+
+	// Step 200 was just used, so step 100 is the least recently used
+	cache.add(key, &fakeMachine{step: 400, size: 1000})
+	if machine := cache.cloneClosest(key, 150, zero); machine.GetStepCount() != 0 {
+		t.Fatal("least recently used machine wasn't evicted")
+	}
+	if machine := cache.cloneClosest(key, 250, zero); machine.GetStepCount() != 200 {
+		t.Fatal("recently used machine was evicted")
+	}
+
+	// Machines are also evicted to stay within the memory limit
+	cache.add(otherKey, &fakeMachine{step: 100, size: 1024*1024 - 1500})
+	if len(cache.entries[key]) != 1 || cache.bytes > 1024*1024 {
+		t.Fatal("memory limit not enforced", len(cache.entries[key]), cache.bytes)
+	}
+	// A machine larger than the limit is never cached
+	cache.add(otherKey, &fakeMachine{step: 500, size: 2 * 1024 * 1024})
+	if _, ok := cache.entries[otherKey][500]; ok {
+		t.Fatal("cached a machine past the memory limit")
+	}
+
+	// The per-execution cache starts from shared machines
+	machineCache := &MachineCache{config: &config, shared: cache, key: key}
+	machine, err := machineCache.stepTo(context.Background(), zero, 450)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if machine.GetStepCount() != 450 || zero.GetStepCount() != 0 {
+		t.Fatal("unexpected machine steps", machine.GetStepCount(), zero.GetStepCount())
+	}
+	if _, ok := cache.entries[key][450]; !ok {
+		t.Fatal("stepped machine wasn't shared")
+	}
+
+	var nilCache *MachineStateCache
+	nilCache.add(key, zero)
+	if machine := nilCache.cloneClosest(key, 450, zero); machine.GetStepCount() != 0 {
+		t.Fatal("nil cache returned a machine")
+	}
+}
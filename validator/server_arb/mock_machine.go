@@ -53,6 +53,10 @@ func (m *IncorrectMachine) GetStepCount() uint64 {
 	return m.stepCount
 }
 
+func (m *IncorrectMachine) MemorySize() uint64 {
+	return m.inner.MemorySize()
+}
+
 func (m *IncorrectMachine) IsRunning() bool {
 	return m.inner.IsRunning() || m.stepCount < m.incorrectStep
 }
@@ -22,6 +22,23 @@ import (
 	"github.com/offchainlabs/nitro/validator/server_common"
 )
 
+// VerifyMachineModuleRoot loads the machine in dir to check it has the module root
+func VerifyMachineModuleRoot(dir string, moduleRoot common.Hash) error {
+	binPath := filepath.Join(dir, DefaultArbitratorMachineConfig.WavmBinaryPath)
+	cBinPath := C.CString(binPath)
+	defer C.free(unsafe.Pointer(cBinPath))
+	baseMachine := C.arbitrator_load_wavm_binary(cBinPath)
+	if baseMachine == nil {
+		return fmt.Errorf("failed to load machine %v", binPath)
+	}
+	machine := machineFromPointer(baseMachine)
+	defer machine.Destroy()
+	if machineModuleRoot := machine.GetModuleRoot(); machineModuleRoot != moduleRoot {
+		return fmt.Errorf("machine has module root %v, expected %v", machineModuleRoot, moduleRoot)
+	}
+	return nil
+}
+
 func createArbMachine(ctx context.Context, locator *server_common.MachineLocator, config *ArbitratorMachineConfig, moduleRoot common.Hash) (*arbMachines, error) {
 	binPath := filepath.Join(locator.GetMachinePath(moduleRoot), config.WavmBinaryPath)
 	cBinPath := C.CString(binPath)
@@ -58,6 +58,7 @@ type ArbitratorSpawner struct {
 	count         int32
 	locator       *server_common.MachineLocator
 	machineLoader *ArbMachineLoader
+	machineStates *MachineStateCache
 	config        ArbitratorSpawnerConfigFecher
 }
 
@@ -66,6 +67,7 @@ func NewArbitratorSpawner(locator *server_common.MachineLocator, config Arbitrat
 	spawner := &ArbitratorSpawner{
 		locator:       locator,
 		machineLoader: NewArbMachineLoader(&DefaultArbitratorMachineConfig, locator),
+		machineStates: NewMachineStateCache(func() *MachineCacheConfig { return &config().Execution }),
 		config:        config,
 	}
 	return spawner, nil
@@ -80,6 +82,13 @@ func (s *ArbitratorSpawner) LatestWasmModuleRoot() containers.PromiseInterface[c
 	return containers.NewReadyPromise(s.locator.LatestWasmModuleRoot(), nil)
 }
 
+func (s *ArbitratorSpawner) LoadWasmModuleRoot(moduleRoot common.Hash) containers.PromiseInterface[struct{}] {
+	return stopwaiter.LaunchPromiseThread[struct{}](s, func(ctx context.Context) (struct{}, error) {
+		_, err := s.machineLoader.GetZeroStepMachine(ctx, moduleRoot)
+		return struct{}{}, err
+	})
+}
+
 func (s *ArbitratorSpawner) Name() string {
 	return "arbitrator"
 }
@@ -322,8 +331,9 @@ func (v *ArbitratorSpawner) CreateExecutionRun(wasmModuleRoot common.Hash, input
 		return machine, nil
 	}
 	currentExecConfig := v.config().Execution
+	key := NewMachineStateKey(wasmModuleRoot, input)
 	return stopwaiter.LaunchPromiseThread[validator.ExecutionRun](v, func(ctx context.Context) (validator.ExecutionRun, error) {
-		return NewExecutionRun(v.GetContext(), getMachine, &currentExecConfig)
+		return NewExecutionRun(v.GetContext(), getMachine, &currentExecConfig, v.machineStates, key)
 	})
 }
 
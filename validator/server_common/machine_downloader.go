@@ -0,0 +1,116 @@
+package server_common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// MachineFiles are the files of a machine folder that are downloaded
+var MachineFiles = []string{"module-root.txt", "machine.wavm.br", "replay.wasm"}
+
+// MachineVerifier checks the machine in a folder has the module root
+type MachineVerifier func(dir string, moduleRoot common.Hash) error
+
+// MachineDownloader fetches machines missing from the root path from an HTTPS mirror laid out like the root path,
+// with each machine's files at <url>/<module root>/<file>
+type MachineDownloader struct {
+	url    *url.URL
+	client *http.Client
+	verify MachineVerifier
+	mutex  sync.Mutex // held while downloading
+}
+
+func NewMachineDownloader(mirror string, timeout time.Duration, verify MachineVerifier) (*MachineDownloader, error) {
+	parsed, err := url.Parse(mirror)
+	if err != nil {
+		return nil, fmt.Errorf("invalid machine download url \"%v\": %w", mirror, err)
+	}
+	if parsed.Scheme != "https" {
+		return nil, fmt.Errorf("machine download url \"%v\" must use https", mirror)
+	}
+	return &MachineDownloader{
+		url:    parsed,
+		client: &http.Client{Timeout: timeout},
+		verify: verify,
+	}, nil
+}
+
+// Download fetches the machine into a temporary folder under the root path, checks it has the module root, and
+// only then moves it to dir, so a partial or bad download is never found there.
+func (d *MachineDownloader) Download(ctx context.Context, rootPath string, moduleRoot common.Hash, dir string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if _, err := os.Stat(dir); err == nil {
+		// downloaded while waiting for the lock
+		return nil
+	}
+	tmpDir, err := os.MkdirTemp(rootPath, ".download-"+moduleRoot.String()+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	for _, file := range MachineFiles {
+		if err := d.downloadFile(ctx, moduleRoot, file, filepath.Join(tmpDir, file)); err != nil {
+			return err
+		}
+	}
+	fileBytes, err := os.ReadFile(filepath.Join(tmpDir, "module-root.txt"))
+	if err != nil {
+		return err
+	}
+	if listed := common.HexToHash(strings.TrimSpace(string(fileBytes))); listed != moduleRoot {
+		return fmt.Errorf("downloaded machine lists module root %v, expected %v", listed, moduleRoot)
+	}
+	if d.verify != nil {
+		if err := d.verify(tmpDir, moduleRoot); err != nil {
+			return fmt.Errorf("downloaded machine failed verification: %w", err)
+		}
+	}
+	if err := os.Chmod(tmpDir, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return err
+	}
+	log.Info("downloaded machine", "moduleRoot", moduleRoot, "path", dir)
+	return nil
+}
+
+func (d *MachineDownloader) downloadFile(ctx context.Context, moduleRoot common.Hash, file string, path string) error {
+	fileURL := d.url.JoinPath(moduleRoot.String(), file).String()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading %v: %w", fileURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error downloading %v: %v", fileURL, resp.Status)
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error downloading %v: %w", fileURL, err)
+	}
+	return nil
+}
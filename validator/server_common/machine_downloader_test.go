@@ -0,0 +1,94 @@
+package server_common
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestMachineDownloader(t *testing.T) {
+	goodRoot := common.HexToHash("0x01")
+	badRoot := common.HexToHash("0x02")
+	missingRoot := common.HexToHash("0x03")
+	served := map[string]string{
+		"/machines/" + goodRoot.String() + "/module-root.txt": goodRoot.String() + "\n",
+		"/machines/" + goodRoot.String() + "/machine.wavm.br": "wavm",
+		"/machines/" + goodRoot.String() + "/replay.wasm":     "wasm",
+		"/machines/" + badRoot.String() + "/module-root.txt":  goodRoot.String(),
+		"/machines/" + badRoot.String() + "/machine.wavm.br":  "wavm",
+		"/machines/" + badRoot.String() + "/replay.wasm":      "wasm",
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := served[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	if _, err := NewMachineDownloader("http://example.com/machines", time.Minute, nil); err == nil {
+		t.Fatal("expected a plain http mirror to be rejected")
+	}
+	verified := 0
+	downloader, err := NewMachineDownloader(server.URL+"/machines", time.Minute, func(dir string, moduleRoot common.Hash) error {
+		verified++
+		if moduleRoot != goodRoot {
+			return errors.New("bad machine")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloader.client = server.Client()
+
+	rootPath := t.TempDir()
+	locator, err := NewMachineLocator(rootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := locator.EnsureMachine(ctx, goodRoot); err == nil {
+		t.Fatal("expected a missing machine without downloads to fail")
+	}
+	locator.SetDownloader(downloader)
+	if err := locator.EnsureMachine(ctx, goodRoot); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(locator.GetMachinePath(goodRoot), "replay.wasm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "wasm" {
+		t.Fatalf("downloaded replay.wasm has %q", data)
+	}
+	if err := locator.EnsureMachine(ctx, goodRoot); err != nil {
+		t.Fatal(err)
+	}
+	if verified != 1 {
+		t.Fatalf("machine verified %v times, expected once", verified)
+	}
+
+	if err := locator.EnsureMachine(ctx, badRoot); err == nil {
+		t.Fatal("expected a machine listing the wrong module root to fail")
+	}
+	if err := locator.EnsureMachine(ctx, missingRoot); err == nil {
+		t.Fatal("expected a machine missing from the mirror to fail")
+	}
+	entries, err := os.ReadDir(rootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != goodRoot.String() {
+		t.Fatalf("expected only the good machine under the root path, found %v entries", len(entries))
+	}
+}
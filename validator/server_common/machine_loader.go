@@ -50,8 +50,18 @@ func (l *MachineLoader[M]) GetMachine(ctx context.Context, moduleRoot common.Has
 		status = newMachineStatus[M]()
 		l.machines[moduleRoot] = status
 		go func() {
-			machine, err := l.createMachine(context.Background(), moduleRoot)
+			err := l.locator.EnsureMachine(context.Background(), moduleRoot)
+			var machine *M
+			if err == nil {
+				machine, err = l.createMachine(context.Background(), moduleRoot)
+			}
 			if err != nil {
+				// forget the failure, so the machine is loaded once it's put under the root path
+				l.mapMutex.Lock()
+				if l.machines[moduleRoot] == status {
+					delete(l.machines, moduleRoot)
+				}
+				l.mapMutex.Unlock()
 				status.ProduceError(err)
 				return
 			}
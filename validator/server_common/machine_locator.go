@@ -1,6 +1,7 @@
 package server_common
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
@@ -11,8 +12,9 @@ import (
 )
 
 type MachineLocator struct {
-	rootPath string
-	latest   common.Hash
+	rootPath   string
+	latest     common.Hash
+	downloader *MachineDownloader
 }
 
 var ErrMachineNotFound = errors.New("machine not found")
@@ -60,7 +62,7 @@ func NewMachineLocator(rootPath string) (*MachineLocator, error) {
 				s := strings.TrimSpace(string(fileBytes))
 				latestModuleRoot = common.HexToHash(s)
 			}
-			return &MachineLocator{place, latestModuleRoot}, nil
+			return &MachineLocator{rootPath: place, latest: latestModuleRoot}, nil
 		}
 	}
 	return nil, ErrMachineNotFound
@@ -81,3 +83,19 @@ func (l MachineLocator) LatestWasmModuleRoot() common.Hash {
 func (l MachineLocator) RootPath() string {
 	return l.rootPath
 }
+
+// SetDownloader has machines missing from the root path downloaded when they're needed
+func (l *MachineLocator) SetDownloader(downloader *MachineDownloader) {
+	l.downloader = downloader
+}
+
+// EnsureMachine returns an error if the machine for the module root isn't under the root path, after trying to
+// download it if downloads are enabled
+func (l MachineLocator) EnsureMachine(ctx context.Context, moduleRoot common.Hash) error {
+	path := l.GetMachinePath(moduleRoot)
+	_, err := os.Stat(path)
+	if err == nil || l.downloader == nil || !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return l.downloader.Download(ctx, l.rootPath, moduleRoot, path)
+}
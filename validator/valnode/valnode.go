@@ -2,9 +2,12 @@ package valnode
 
 import (
 	"context"
+	"os"
+	"time"
 
 	"github.com/offchainlabs/nitro/validator"
 
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/rpc"
 	flag "github.com/spf13/pflag"
@@ -16,21 +19,49 @@ import (
 )
 
 type WasmConfig struct {
-	RootPath               string   `koanf:"root-path"`
-	EnableWasmrootsCheck   bool     `koanf:"enable-wasmroots-check"`
-	AllowedWasmModuleRoots []string `koanf:"allowed-wasm-module-roots"`
+	RootPath               string        `koanf:"root-path"`
+	EnableWasmrootsCheck   bool          `koanf:"enable-wasmroots-check"`
+	AllowedWasmModuleRoots []string      `koanf:"allowed-wasm-module-roots"`
+	DownloadURL            string        `koanf:"download-url"`
+	DownloadTimeout        time.Duration `koanf:"download-timeout"`
 }
 
 func WasmConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.String(prefix+".root-path", DefaultWasmConfig.RootPath, "path to machine folders, each containing wasm files (machine.wavm.br, replay.wasm)")
 	f.Bool(prefix+".enable-wasmroots-check", DefaultWasmConfig.EnableWasmrootsCheck, "enable check for compatibility of on-chain WASM module root with node")
 	f.StringSlice(prefix+".allowed-wasm-module-roots", DefaultWasmConfig.AllowedWasmModuleRoots, "list of WASM module roots to check if the on-chain WASM module root belongs to on node startup")
+	f.String(prefix+".download-url", DefaultWasmConfig.DownloadURL, "HTTPS mirror to download machine folders missing from the root path from, as <url>/<module root>/<file> (empty = don't download)")
+	f.Duration(prefix+".download-timeout", DefaultWasmConfig.DownloadTimeout, "timeout for downloading each machine file")
 }
 
 var DefaultWasmConfig = WasmConfig{
 	RootPath:               "",
 	EnableWasmrootsCheck:   true,
 	AllowedWasmModuleRoots: []string{},
+	DownloadURL:            "",
+	DownloadTimeout:        10 * time.Minute,
+}
+
+// CreateMachineLocator finds the machine folders, creating the root path if machines are to be downloaded into it
+func CreateMachineLocator(config *WasmConfig) (*server_common.MachineLocator, error) {
+	if config.DownloadURL == "" {
+		return server_common.NewMachineLocator(config.RootPath)
+	}
+	downloader, err := server_common.NewMachineDownloader(config.DownloadURL, config.DownloadTimeout, server_arb.VerifyMachineModuleRoot)
+	if err != nil {
+		return nil, err
+	}
+	if config.RootPath != "" {
+		if err := os.MkdirAll(config.RootPath, 0755); err != nil {
+			return nil, err
+		}
+	}
+	locator, err := server_common.NewMachineLocator(config.RootPath)
+	if err != nil {
+		return nil, err
+	}
+	locator.SetDownloader(downloader)
+	return locator, nil
 }
 
 type Config struct {
@@ -63,7 +94,7 @@ var TestValidationConfig = Config{
 }
 
 func ValidationConfigAddOptions(prefix string, f *flag.FlagSet) {
-	f.Bool(prefix+".use-jit", DefaultValidationConfig.UseJit, "use jit for validation")
+	f.Bool(prefix+".use-jit", DefaultValidationConfig.UseJit, "validate blocks by running the JIT-compiled replay binary rather than interpreting the machine in the arbitrator (challenges always use the arbitrator)")
 	f.Bool(prefix+".api-auth", DefaultValidationConfig.ApiAuth, "validate is an authenticated API")
 	f.Bool(prefix+".api-public", DefaultValidationConfig.ApiPublic, "validate is a public API")
 	server_arb.ArbitratorSpawnerConfigAddOptions(prefix+".arbitrator", f)
@@ -92,7 +123,7 @@ func EnsureValidationExposedViaAuthRPC(stackConf *node.Config) {
 
 func CreateValidationNode(configFetcher ValidationConfigFetcher, stack *node.Node, fatalErrChan chan error) (*ValidationNode, error) {
 	config := configFetcher()
-	locator, err := server_common.NewMachineLocator(config.Wasm.RootPath)
+	locator, err := CreateMachineLocator(&config.Wasm)
 	if err != nil {
 		return nil, err
 	}
@@ -116,6 +147,7 @@ func CreateValidationNode(configFetcher ValidationConfigFetcher, stack *node.Nod
 	} else {
 		serverAPI = server_api.NewExecutionServerAPI(arbSpawner, arbSpawner, arbConfigFetcher)
 	}
+	log.Info("validation node created", "validationBackend", serverAPI.Name(), "challengeBackend", arbSpawner.Name())
 	valAPIs := []rpc.API{{
 		Namespace:     server_api.Namespace,
 		Version:       "1.0",